@@ -26,6 +26,7 @@ func main() {
 	tool := flag.String("tool", "", "tool name to call (required)")
 	args := flag.String("args", "{}", "tool arguments as JSON object")
 	binary := flag.String("binary", "", "path to typescript-mcp binary (default: build from source)")
+	cwd := flag.String("cwd", "", "directory to start the server in, passing the project with --project (default: start it in the project)")
 	flag.Parse()
 
 	if *project == "" || *tool == "" {
@@ -45,13 +46,20 @@ func main() {
 
 	ctx := context.Background()
 
+	serverArgs := []string{}
+	serverDir := *project
+	if *cwd != "" {
+		serverArgs = append(serverArgs, "--project", *project)
+		serverDir = *cwd
+	}
+
 	c, err := client.NewStdioMCPClientWithOptions(
 		bin,
 		nil,
-		nil,
+		serverArgs,
 		transport.WithCommandFunc(func(ctx context.Context, command string, env []string, cmdArgs []string) (*exec.Cmd, error) {
 			cmd := exec.CommandContext(ctx, command, cmdArgs...)
-			cmd.Dir = *project
+			cmd.Dir = serverDir
 			cmd.Env = append(os.Environ(), env...)
 			return cmd, nil
 		}),
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
 
@@ -22,14 +24,26 @@ func main() {
 }
 
 func run() error {
+	var project string
+	flag.StringVar(&project, "project", "", "workspace root: the directory of the TypeScript project (default: $TYPESCRIPT_MCP_PROJECT, else the current directory)")
+	flag.StringVar(&project, "root", "", "alias of -project")
+	flag.Parse()
+	root, err := projectRoot(project, os.Getenv("TYPESCRIPT_MCP_PROJECT"))
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	// Spawn tsgo LSP server
-	lspClient, err := lsp.NewClient(ctx, "")
-	if err != nil {
-		return fmt.Errorf("starting LSP client: %w", err)
+	// Spawn tsgo LSP server in the background so the MCP handshake and the
+	// tools that do not need tsgo are not held up by it. A failed start is
+	// logged and reported by ts_server_status and the tools that need tsgo.
+	rootURI := ""
+	if root != "" {
+		rootURI = docsync.FileToURI(root)
 	}
+	lspClient := lsp.StartClient(ctx, rootURI, lsp.StartTsgo)
 	var closeOnce sync.Once
 	closeLSP := func() { closeOnce.Do(func() { lspClient.Close() }) }
 	defer closeLSP()
@@ -44,33 +58,52 @@ func run() error {
 	docMgr := docsync.NewManager()
 
 	// Create MCP server
+	hooks := &server.Hooks{}
 	s := server.NewMCPServer(
 		"typescript-mcp",
 		"0.1.0",
-		server.WithInstructions(serverInstructions),
+		server.WithInstructions(tools.Instructions(tools.RegisterOptions{})),
+		server.WithHooks(hooks),
 	)
 
-	// Register all tools
-	tools.Register(s, lspClient, docMgr)
+	// Register all tools. Projects outside the root get a tsgo of their
+	// own, stopped with ctx; the hooks let clients cancel tool calls.
+	if err := tools.RegisterWithOptions(s, lspClient, docMgr, tools.RegisterOptions{Context: ctx, Hooks: hooks}); err != nil {
+		return fmt.Errorf("registering tools: %w", err)
+	}
 
 	// Serve over stdio
 	return server.ServeStdio(s)
 }
 
-const serverInstructions = `TypeScript type-checking and code navigation tools powered by tsgo.
-
-Available tools:
-- ts_diagnostics: Get TypeScript errors and warnings for a file
-- ts_definition: Go to the definition of a symbol
-- ts_hover: Get type information and documentation for a symbol
-- ts_references: Find all references to a symbol across the project
-- ts_rename: Rename a symbol across the project (writes changes to disk)
-- ts_document_symbols: Get the symbol outline of a file
-- ts_project_info: Get TypeScript project configuration info
-
-Workflow:
-1. After editing TypeScript files, use ts_diagnostics to check for type errors
-2. Use ts_hover to understand types and ts_definition to navigate code
-3. Use ts_references before renaming or refactoring to find all usages
-4. Use ts_rename to rename symbols — it applies all changes across the project
-5. Use ts_document_symbols to get a file overview without reading the full source`
+// projectRoot returns the workspace root set by the -project flag, or else
+// by TYPESCRIPT_MCP_PROJECT, made absolute. It fails unless the directory
+// exists and holds a tsconfig.json or package.json, so a host launching
+// the server from elsewhere learns of a wrong path at once. It returns ""
+// when neither is set, for the current directory.
+func projectRoot(flagValue, envValue string) (string, error) {
+	dir, source := flagValue, "-project"
+	if dir == "" {
+		dir, source = envValue, "TYPESCRIPT_MCP_PROJECT"
+	}
+	if dir == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", source, dir, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", source, dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s %s is not a directory; pass the directory of the TypeScript project", source, dir)
+	}
+	for _, marker := range []string{"tsconfig.json", "package.json"} {
+		if _, err := os.Stat(filepath.Join(abs, marker)); err == nil {
+			return abs, nil
+		}
+	}
+	return "", fmt.Errorf("%s %s has no tsconfig.json or package.json; pass the directory of the TypeScript project", source, dir)
+}
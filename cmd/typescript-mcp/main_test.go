@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectRoot(t *testing.T) {
+	dir := t.TempDir()
+	withConfig := filepath.Join(dir, "app")
+	withPackage := filepath.Join(dir, "lib")
+	empty := filepath.Join(dir, "empty")
+	for _, d := range []string{withConfig, withPackage, empty} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(withConfig, "tsconfig.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(withPackage, "package.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		flag, env string
+		want      string
+		wantErr   string
+	}{
+		{name: "unset"},
+		{name: "flag", flag: withConfig, want: withConfig},
+		{name: "env", env: withPackage, want: withPackage},
+		{name: "flag wins", flag: withConfig, env: withPackage, want: withConfig},
+		{name: "missing", flag: filepath.Join(dir, "nope"), wantErr: "-project " + filepath.Join(dir, "nope")},
+		{name: "file", env: filepath.Join(withConfig, "tsconfig.json"), wantErr: "is not a directory"},
+		{name: "no markers", env: empty, wantErr: "TYPESCRIPT_MCP_PROJECT " + empty + " has no tsconfig.json or package.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := projectRoot(tt.flag, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("projectRoot = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	t.Chdir(dir)
+	if got, err := projectRoot("app", ""); err != nil || got != withConfig {
+		t.Errorf("relative flag: projectRoot = %q, %v; want %q", got, err, withConfig)
+	}
+}
@@ -0,0 +1,107 @@
+package docsync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"go.lsp.dev/protocol"
+)
+
+// applyEdits applies the non-overlapping text edits of one document to
+// text, as a WorkspaceEdit means them: every range refers to text before
+// any edit, and insertions at one position keep their order. It returns
+// the new text and the changes of a didChange that make the same edit.
+// The changes are in reverse document order, so that each range still
+// refers to the text the previous changes left.
+func applyEdits(text string, edits []protocol.TextEdit) (string, []protocol.TextDocumentContentChangeEvent, error) {
+	type span struct {
+		start, end int
+		edit       protocol.TextEdit
+	}
+	lines := lineStarts(text)
+	spans := make([]span, len(edits))
+	for i, e := range edits {
+		start, err := offsetOf(text, lines, e.Range.Start)
+		if err != nil {
+			return "", nil, err
+		}
+		end, err := offsetOf(text, lines, e.Range.End)
+		if err != nil {
+			return "", nil, err
+		}
+		if end < start {
+			return "", nil, fmt.Errorf("edit range %v ends before it starts", e.Range)
+		}
+		spans[i] = span{start: start, end: end, edit: e}
+	}
+	// Last first. Edits starting at one offset are applied in reverse
+	// input order, each in front of the previous, so that they end up in
+	// input order; only the last of them may replace text.
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+	changes := make([]protocol.TextDocumentContentChangeEvent, 0, len(spans))
+	next := len(text)
+	for i := 0; i < len(spans); {
+		j := i
+		for j < len(spans) && spans[j].start == spans[i].start {
+			j++
+		}
+		for k := j - 1; k >= i; k-- {
+			s := spans[k]
+			if s.end > next {
+				return "", nil, fmt.Errorf("edit range %v overlaps another edit", s.edit.Range)
+			}
+			text = text[:s.start] + s.edit.NewText + text[s.end:]
+			changes = append(changes, protocol.TextDocumentContentChangeEvent{Range: s.edit.Range, Text: s.edit.NewText})
+			next = s.start
+		}
+		i = j
+	}
+	return text, changes, nil
+}
+
+// lineStarts returns the byte offset of the start of every line of text.
+// Lines end at "\n", "\r\n" or a lone "\r", as LSP positions count them.
+func lineStarts(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i++
+			}
+			starts = append(starts, i+1)
+		case '\n':
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// offsetOf converts an LSP position, whose character counts UTF-16 code
+// units, to a byte offset into text. A character past the end of its line
+// means the end of the line's text, and the line past the last the end of
+// text.
+func offsetOf(text string, lines []int, pos protocol.Position) (int, error) {
+	if int(pos.Line) == len(lines) {
+		return len(text), nil
+	}
+	if int(pos.Line) > len(lines) {
+		return 0, fmt.Errorf("position %d:%d is past the last line %d", pos.Line, pos.Character, len(lines)-1)
+	}
+	start := lines[pos.Line]
+	end := len(text)
+	if int(pos.Line)+1 < len(lines) {
+		end = lines[pos.Line+1]
+	}
+	line := strings.TrimRight(text[start:end], "\r\n")
+	units := uint32(0)
+	for i, r := range line {
+		if units >= pos.Character {
+			return start + i, nil
+		}
+		units += uint32(utf16.RuneLen(r))
+	}
+	return start + len(line), nil
+}
@@ -0,0 +1,142 @@
+package docsync
+
+import (
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func edit(startLine, startChar, endLine, endChar uint32, text string) protocol.TextEdit {
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startChar},
+			End:   protocol.Position{Line: endLine, Character: endChar},
+		},
+		NewText: text,
+	}
+}
+
+// replayChanges applies didChange content changes to text one after the
+// other, as a server taking incremental changes does.
+func replayChanges(t *testing.T, text string, changes []protocol.TextDocumentContentChangeEvent) string {
+	t.Helper()
+	for _, c := range changes {
+		lines := lineStarts(text)
+		start, err := offsetOf(text, lines, c.Range.Start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		end, err := offsetOf(text, lines, c.Range.End)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text = text[:start] + c.Text + text[end:]
+	}
+	return text
+}
+
+func TestApplyEdits(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		edits []protocol.TextEdit
+		want  string
+	}{
+		{
+			name:  "rename on several lines",
+			text:  "const foo = 1;\nfoo + foo;\n",
+			edits: []protocol.TextEdit{edit(0, 6, 0, 9, "bar"), edit(1, 0, 1, 3, "bar"), edit(1, 6, 1, 9, "bar")},
+			want:  "const bar = 1;\nbar + bar;\n",
+		},
+		{
+			name:  "edits out of order",
+			text:  "a b c\n",
+			edits: []protocol.TextEdit{edit(0, 4, 0, 5, "z"), edit(0, 0, 0, 1, "x")},
+			want:  "x b z\n",
+		},
+		{
+			name:  "insertions at one position keep their order",
+			text:  "x\n",
+			edits: []protocol.TextEdit{edit(0, 0, 0, 0, "1"), edit(0, 0, 0, 0, "2"), edit(0, 0, 0, 1, "3")},
+			want:  "123\n",
+		},
+		{
+			name:  "adjacent edits",
+			text:  "abcdef",
+			edits: []protocol.TextEdit{edit(0, 0, 0, 3, "X"), edit(0, 3, 0, 6, "Y")},
+			want:  "XY",
+		},
+		{
+			name:  "multi-line edit",
+			text:  "one\ntwo\nthree\n",
+			edits: []protocol.TextEdit{edit(0, 1, 2, 2, "-")},
+			want:  "o-ree\n",
+		},
+		{
+			name:  "utf-16 columns",
+			text:  "const s = \"😀\"; foo;\n",
+			edits: []protocol.TextEdit{edit(0, 16, 0, 19, "bar")},
+			want:  "const s = \"😀\"; bar;\n",
+		},
+		{
+			name:  "crlf lines",
+			text:  "foo\r\nfoo\r\n",
+			edits: []protocol.TextEdit{edit(1, 0, 1, 3, "bar"), edit(0, 3, 1, 0, "\r\n\r\n")},
+			want:  "foo\r\n\r\nbar\r\n",
+		},
+		{
+			name:  "column past the end of the line",
+			text:  "ab\ncd\n",
+			edits: []protocol.TextEdit{edit(0, 9, 0, 9, ";")},
+			want:  "ab;\ncd\n",
+		},
+		{
+			name:  "insert at the end",
+			text:  "ab",
+			edits: []protocol.TextEdit{edit(0, 2, 0, 2, "\n")},
+			want:  "ab\n",
+		},
+		{
+			name:  "deletion through the line past the last",
+			text:  "ab\ncd",
+			edits: []protocol.TextEdit{edit(1, 0, 2, 0, "")},
+			want:  "ab\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes, err := applyEdits(tt.text, tt.edits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+			if replayed := replayChanges(t, tt.text, changes); replayed != tt.want {
+				t.Errorf("changes replayed = %q, want %q", replayed, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEditsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []protocol.TextEdit
+		want  string
+	}{
+		{name: "overlap", edits: []protocol.TextEdit{edit(0, 0, 0, 4, "x"), edit(0, 2, 0, 6, "y")}, want: "overlaps"},
+		{name: "replacement before an insertion at its start", edits: []protocol.TextEdit{edit(0, 0, 0, 2, "x"), edit(0, 0, 0, 0, "y")}, want: "overlaps"},
+		{name: "past the last line", edits: []protocol.TextEdit{edit(4, 0, 4, 0, "x")}, want: "past the last line"},
+		{name: "reversed range", edits: []protocol.TextEdit{edit(1, 0, 0, 0, "x")}, want: "ends before it starts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := applyEdits("abcdef\nghi\n", tt.edits)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
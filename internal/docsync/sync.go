@@ -2,45 +2,303 @@ package docsync
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 )
 
+// ErrOpenLimit is returned when opening a document would exceed the
+// manager's open-document cap.
+var ErrOpenLimit = errors.New("open document limit reached")
+
 // trackedDoc holds the state for a document that has been opened with the LSP server.
 type trackedDoc struct {
-	version int32
-	content string
+	version  int32
+	content  string
+	openedAt time.Time
+	syncedAt time.Time
+	// checkedAt is when the content was last compared with the disk, and
+	// stamp what os.Stat reported of the file then.
+	checkedAt time.Time
+	stamp     fileStamp
+}
+
+// fileStamp is the size and modification time of a file, which a rewrite
+// changes.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// statFile returns the stamp of path, or the zero stamp when it cannot be
+// stat'd.
+func statFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{size: fi.Size(), modTime: fi.ModTime()}
+}
+
+func (s fileStamp) equal(o fileStamp) bool {
+	return s.size == o.size && s.modTime.Equal(o.modTime)
+}
+
+// DefaultFreshness is how long a synced document is trusted without
+// reading it again, as long as its size and modification time are those
+// seen when it was read.
+const DefaultFreshness = 200 * time.Millisecond
+
+// syncCall is a SyncFile in progress that concurrent callers for the same
+// document wait on instead of repeating it.
+type syncCall struct {
+	done chan struct{}
+	err  error
 }
 
 // Manager tracks open documents and synchronizes them with the LSP server.
 type Manager struct {
-	mu   sync.Mutex
-	docs map[string]*trackedDoc // URI -> tracked state
+	mu      sync.Mutex
+	docs    map[string]*trackedDoc // URI -> tracked state
+	pins    map[string]int         // URI -> in-flight operations using it
+	maxOpen int
+	// closed remembers the last version of closed documents so a reopened
+	// document never reuses a version number; caches keyed by version
+	// stay valid.
+	closed    map[string]int32
+	inflight  map[string]*syncCall // URI -> sync in progress
+	freshness time.Duration
+	readFile  func(string) ([]byte, error)
+	// onChange are called when a tracked document gets a new version.
+	onChange []func(filePath string, version int32)
+	// incremental reports whether the server takes ranged changes.
+	incremental func() bool
 }
 
-// NewManager creates a new document manager.
+// NewManager creates a new document manager with no open-document cap and
+// the default freshness window.
 func NewManager() *Manager {
 	return &Manager{
-		docs: make(map[string]*trackedDoc),
+		docs:      make(map[string]*trackedDoc),
+		pins:      make(map[string]int),
+		closed:    make(map[string]int32),
+		inflight:  make(map[string]*syncCall),
+		freshness: DefaultFreshness,
+		readFile:  os.ReadFile,
 	}
 }
 
+// SetFreshness sets how long SyncFile trusts a document it synced without
+// reading it from disk again while its size and modification time stay
+// the same. d <= 0 makes every SyncFile read the file.
+func (m *Manager) SetFreshness(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.freshness = max(d, 0)
+}
+
+// SetMaxOpen caps the number of documents held open with the server;
+// opening more fails with ErrOpenLimit. n <= 0 removes the cap.
+func (m *Manager) SetMaxOpen(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxOpen = max(n, 0)
+}
+
+// MaxOpen returns the open-document cap, or 0 when there is none.
+func (m *Manager) MaxOpen() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.maxOpen
+}
+
+// OnChange registers fn to be called after SyncFile gives a document a new
+// version: its content changed, or it was reopened after being closed.
+// fn must not call back into the manager.
+func (m *Manager) OnChange(fn func(filePath string, version int32)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = append(m.onChange, fn)
+}
+
+// SetIncremental sets how ApplyEdits learns whether the server takes
+// didChange notifications with ranged changes. Without it, ApplyEdits
+// always sends the full text.
+func (m *Manager) SetIncremental(fn func() bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.incremental = fn
+}
+
 // SyncFile ensures the LSP server has the current content for the given file path.
 // It reads the file from disk and sends textDocument/didOpen if the file is new,
 // or textDocument/didChange if the content has changed.
+//
+// Concurrent calls for the same file share one read and one notification,
+// and a file checked within the freshness window is only stat'd, so
+// parallel tool calls on one file cost a single round trip. Callers that
+// just wrote the file use ResyncFile instead.
 func (m *Manager) SyncFile(ctx context.Context, conn jsonrpc2.Conn, filePath string) error {
-	content, err := os.ReadFile(filePath)
+	return m.sync(ctx, conn, filePath, false, m.readFile)
+}
+
+// ResyncFile is SyncFile for callers that have just written filePath: it
+// ignores the freshness window and does not join a sync that started
+// before the write, so the server is guaranteed to see the new content.
+func (m *Manager) ResyncFile(ctx context.Context, conn jsonrpc2.Conn, filePath string) error {
+	return m.sync(ctx, conn, filePath, true, m.readFile)
+}
+
+// SyncContent sends content to the server as the text of filePath without
+// it being on disk, to see how the server would treat it. The overlay
+// stays until the next sync reads the file: SyncFile does so immediately,
+// as the freshness window does not apply to it.
+func (m *Manager) SyncContent(ctx context.Context, conn jsonrpc2.Conn, filePath string, content []byte) error {
+	err := m.sync(ctx, conn, filePath, true, func(string) ([]byte, error) { return content, nil })
+	m.Invalidate(filePath)
+	return err
+}
+
+// ApplyEdits brings the server up to date with text edits the caller
+// has just written to filePath, without reading the file: the edits are
+// applied to the content last synced and sent as the ranged changes of a
+// didChange. They are the edits of one document of a WorkspaceEdit, whose
+// ranges refer to the content before any of them and do not overlap. When
+// the document is not open, the server takes only the full text, or the
+// edits do not apply to the content last synced, the file is read and
+// synced as ResyncFile does.
+func (m *Manager) ApplyEdits(ctx context.Context, conn jsonrpc2.Conn, filePath string, edits []protocol.TextEdit) error {
+	m.mu.Lock()
+	incremental := m.incremental
+	m.mu.Unlock()
+	if incremental == nil || !incremental() {
+		return m.ResyncFile(ctx, conn, filePath)
+	}
+
+	// The caller wrote the file; its stamp is that of the content the
+	// edits produce.
+	stamp := statFile(filePath)
+	docURI := FileToURI(filePath)
+	m.mu.Lock()
+	for m.inflight[docURI] != nil {
+		// A sync in progress may have read the file before the edit.
+		call := m.inflight[docURI]
+		m.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		m.mu.Lock()
+	}
+	tracked := m.docs[docURI]
+	if tracked == nil {
+		m.mu.Unlock()
+		return m.ResyncFile(ctx, conn, filePath)
+	}
+	text, changes, err := applyEdits(tracked.content, edits)
 	if err != nil {
-		return fmt.Errorf("reading %s: %w", filePath, err)
+		m.mu.Unlock()
+		return m.ResyncFile(ctx, conn, filePath)
+	}
+	if text == tracked.content {
+		m.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	tracked.version++
+	version := tracked.version
+	tracked.content, tracked.syncedAt, tracked.checkedAt = text, now, now
+	tracked.stamp = stamp
+	listeners := m.onChange
+	m.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(filePath, version)
 	}
+	return conn.Notify(ctx, protocol.MethodTextDocumentDidChange, &protocol.DidChangeTextDocumentParams{
+		TextDocument: protocol.VersionedTextDocumentIdentifier{
+			TextDocumentIdentifier: protocol.TextDocumentIdentifier{
+				URI: protocol.DocumentURI(docURI),
+			},
+			Version: version,
+		},
+		ContentChanges: changes,
+	})
+}
 
+// Invalidate makes the next SyncFile of filePath read it from disk even
+// within the freshness window, for callers that learn of a change from a
+// file watcher.
+func (m *Manager) Invalidate(filePath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if tracked, ok := m.docs[FileToURI(filePath)]; ok {
+		tracked.checkedAt = time.Time{}
+	}
+}
+
+func (m *Manager) sync(ctx context.Context, conn jsonrpc2.Conn, filePath string, force bool, read func(string) ([]byte, error)) error {
 	docURI := FileToURI(filePath)
+	trustFresh := !force
+	for {
+		m.mu.Lock()
+		if call := m.inflight[docURI]; call != nil {
+			m.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !force {
+				return call.err
+			}
+			// The sync we waited for may have read the file before the
+			// caller wrote it; run another.
+			continue
+		}
+		if tracked := m.docs[docURI]; trustFresh && tracked != nil && time.Since(tracked.checkedAt) < m.freshness {
+			stamp := tracked.stamp
+			m.mu.Unlock()
+			if statFile(filePath).equal(stamp) {
+				return nil
+			}
+			// Rewritten since it was read; sync it.
+			trustFresh = false
+			continue
+		}
+		call := &syncCall{done: make(chan struct{})}
+		m.inflight[docURI] = call
+		m.mu.Unlock()
+
+		call.err = m.syncNow(ctx, conn, filePath, docURI, read)
+		m.mu.Lock()
+		delete(m.inflight, docURI)
+		m.mu.Unlock()
+		close(call.done)
+		return call.err
+	}
+}
+
+// syncNow reads filePath with read and sends the notification that brings
+// the server up to date, if any.
+func (m *Manager) syncNow(ctx context.Context, conn jsonrpc2.Conn, filePath, docURI string, read func(string) ([]byte, error)) error {
+	checked := time.Now()
+	stamp := statFile(filePath)
+	content, err := read(filePath)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", filePath, err)
+	}
 	text := string(content)
 
 	// Determine what notification to send while holding the lock,
@@ -51,25 +309,38 @@ func (m *Manager) SyncFile(ctx context.Context, conn jsonrpc2.Conn, filePath str
 	}
 
 	var notif *notification
+	var changed int32 // new version of a previously seen document
 
+	now := time.Now()
 	m.mu.Lock()
 	tracked, exists := m.docs[docURI]
 	if !exists {
-		m.docs[docURI] = &trackedDoc{version: 1, content: text}
+		if m.maxOpen > 0 && len(m.docs) >= m.maxOpen {
+			m.mu.Unlock()
+			return fmt.Errorf("opening %s: %w (%d documents open)", filePath, ErrOpenLimit, m.maxOpen)
+		}
+		version := m.closed[docURI] + 1
+		if version > 1 {
+			changed = version
+		}
+		delete(m.closed, docURI)
+		m.docs[docURI] = &trackedDoc{version: version, content: text, openedAt: now, syncedAt: now, checkedAt: checked, stamp: stamp}
 		notif = &notification{
 			method: protocol.MethodTextDocumentDidOpen,
 			params: &protocol.DidOpenTextDocumentParams{
 				TextDocument: protocol.TextDocumentItem{
 					URI:        protocol.DocumentURI(docURI),
 					LanguageID: languageIDFromPath(filePath),
-					Version:    1,
+					Version:    version,
 					Text:       text,
 				},
 			},
 		}
 	} else if tracked.content != text {
 		tracked.version++
+		changed = tracked.version
 		tracked.content = text
+		tracked.syncedAt = now
 		notif = &notification{
 			method: protocol.MethodTextDocumentDidChange,
 			params: &protocol.DidChangeTextDocumentParams{
@@ -85,8 +356,18 @@ func (m *Manager) SyncFile(ctx context.Context, conn jsonrpc2.Conn, filePath str
 			},
 		}
 	}
+	if exists {
+		tracked.checkedAt, tracked.stamp = checked, stamp
+	}
+	var listeners []func(string, int32)
+	if changed > 0 {
+		listeners = m.onChange
+	}
 	m.mu.Unlock()
 
+	for _, fn := range listeners {
+		fn(filePath, changed)
+	}
 	if notif == nil {
 		return nil
 	}
@@ -97,9 +378,11 @@ func (m *Manager) SyncFile(ctx context.Context, conn jsonrpc2.Conn, filePath str
 func languageIDFromPath(filePath string) protocol.LanguageIdentifier {
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
+	case ".ts", ".mts", ".cts":
+		return protocol.TypeScriptLanguage
 	case ".tsx":
 		return protocol.TypeScriptReactLanguage
-	case ".js":
+	case ".js", ".mjs", ".cjs":
 		return protocol.JavaScriptLanguage
 	case ".jsx":
 		return protocol.JavaScriptReactLanguage
@@ -118,24 +401,209 @@ func (m *Manager) SyncFiles(ctx context.Context, conn jsonrpc2.Conn, paths []str
 	return nil
 }
 
+// Version returns the version last sent to the server for filePath, and
+// false when the file is not open.
+func (m *Manager) Version(filePath string) (int32, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if tracked, ok := m.docs[FileToURI(filePath)]; ok {
+		return tracked.version, true
+	}
+	return 0, false
+}
+
+// ContentHash returns the SHA-256 of the content last sent to the server
+// for filePath, hex encoded, and false when the file is not open. The
+// content on disk differs from what the server analyzes when the hashes
+// differ.
+func (m *Manager) ContentHash(filePath string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if tracked, ok := m.docs[FileToURI(filePath)]; ok {
+		sum := sha256.Sum256([]byte(tracked.content))
+		return hex.EncodeToString(sum[:]), true
+	}
+	return "", false
+}
+
+// OpenFiles returns the paths of all documents currently open with the
+// server, sorted.
+func (m *Manager) OpenFiles() []string {
+	m.mu.Lock()
+	files := make([]string, 0, len(m.docs))
+	for u := range m.docs {
+		files = append(files, URIToFile(u))
+	}
+	m.mu.Unlock()
+	sort.Strings(files)
+	return files
+}
+
+// DocumentInfo describes a document open with the server.
+type DocumentInfo struct {
+	Path       string
+	LanguageID protocol.LanguageIdentifier
+	Version    int32
+	// OpenedAt is when didOpen was sent; SyncedAt when the content last
+	// changed.
+	OpenedAt time.Time
+	SyncedAt time.Time
+	// Pinned is true while an in-flight operation uses the document.
+	Pinned bool
+}
+
+// Document returns the state of filePath, and false when it is not open.
+func (m *Manager) Document(filePath string) (DocumentInfo, bool) {
+	u := FileToURI(filePath)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d, ok := m.docs[u]
+	if !ok {
+		return DocumentInfo{}, false
+	}
+	return m.info(u, d), true
+}
+
+// Documents returns all open documents sorted by path.
+func (m *Manager) Documents() []DocumentInfo {
+	m.mu.Lock()
+	out := make([]DocumentInfo, 0, len(m.docs))
+	for u, d := range m.docs {
+		out = append(out, m.info(u, d))
+	}
+	m.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// info builds the DocumentInfo of a tracked document; m.mu must be held.
+func (m *Manager) info(docURI string, d *trackedDoc) DocumentInfo {
+	p := URIToFile(docURI)
+	return DocumentInfo{
+		Path:       p,
+		LanguageID: languageIDFromPath(p),
+		Version:    d.version,
+		OpenedAt:   d.openedAt,
+		SyncedAt:   d.syncedAt,
+		Pinned:     m.pins[docURI] > 0,
+	}
+}
+
+// Pin marks filePath as in use by an in-flight operation, so CloseFiles
+// leaves it open until the returned release function is called. Pins
+// nest, and filePath need not be open yet.
+func (m *Manager) Pin(filePath string) (release func()) {
+	u := FileToURI(filePath)
+	m.mu.Lock()
+	m.pins[u]++
+	m.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			if m.pins[u]--; m.pins[u] <= 0 {
+				delete(m.pins, u)
+			}
+			m.mu.Unlock()
+		})
+	}
+}
+
+// Reasons a path is skipped by CloseFiles.
+const (
+	SkipNotOpen = "not open"
+	SkipPinned  = "pinned by an in-flight operation"
+)
+
+// SkippedClose is a path CloseFiles left alone.
+type SkippedClose struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// CloseFiles sends textDocument/didClose for the given paths and stops
+// tracking them. Paths that are not open or are pinned are skipped and
+// reported. A notification error, which means the connection is gone, is
+// returned with the paths closed before it.
+func (m *Manager) CloseFiles(ctx context.Context, conn jsonrpc2.Conn, paths []string) (closed []string, skipped []SkippedClose, err error) {
+	var uris []string
+	m.mu.Lock()
+	for _, p := range paths {
+		u := FileToURI(p)
+		switch {
+		case m.docs[u] == nil:
+			skipped = append(skipped, SkippedClose{Path: p, Reason: SkipNotOpen})
+		case m.pins[u] > 0:
+			skipped = append(skipped, SkippedClose{Path: p, Reason: SkipPinned})
+		default:
+			m.closed[u] = m.docs[u].version
+			delete(m.docs, u)
+			uris = append(uris, u)
+			closed = append(closed, p)
+		}
+	}
+	m.mu.Unlock()
+
+	for i, u := range uris {
+		if err := notifyClose(ctx, conn, u); err != nil {
+			return closed[:i], skipped, err
+		}
+	}
+	return closed, skipped, nil
+}
+
+// Reopen sends textDocument/didOpen for every tracked document with the
+// content and version last sent, to a server that replaced the one they
+// were opened with. The next SyncFile of each reads the file again.
+func (m *Manager) Reopen(ctx context.Context, conn jsonrpc2.Conn) error {
+	m.mu.Lock()
+	opens := make([]*protocol.DidOpenTextDocumentParams, 0, len(m.docs))
+	for u, d := range m.docs {
+		d.checkedAt = time.Time{}
+		opens = append(opens, &protocol.DidOpenTextDocumentParams{
+			TextDocument: protocol.TextDocumentItem{
+				URI:        protocol.DocumentURI(u),
+				LanguageID: languageIDFromPath(URIToFile(u)),
+				Version:    d.version,
+				Text:       d.content,
+			},
+		})
+	}
+	m.mu.Unlock()
+	sort.Slice(opens, func(i, j int) bool { return opens[i].TextDocument.URI < opens[j].TextDocument.URI })
+
+	for _, params := range opens {
+		if err := conn.Notify(ctx, protocol.MethodTextDocumentDidOpen, params); err != nil {
+			return fmt.Errorf("reopening %s: %w", URIToFile(string(params.TextDocument.URI)), err)
+		}
+	}
+	return nil
+}
+
 // Close sends textDocument/didClose for all tracked documents.
 func (m *Manager) Close(ctx context.Context, conn jsonrpc2.Conn) error {
 	m.mu.Lock()
 	uris := make([]string, 0, len(m.docs))
-	for u := range m.docs {
+	for u, d := range m.docs {
 		uris = append(uris, u)
+		m.closed[u] = d.version
 	}
 	m.docs = make(map[string]*trackedDoc)
 	m.mu.Unlock()
 
 	for _, u := range uris {
-		if err := conn.Notify(ctx, protocol.MethodTextDocumentDidClose, &protocol.DidCloseTextDocumentParams{
-			TextDocument: protocol.TextDocumentIdentifier{
-				URI: protocol.DocumentURI(u),
-			},
-		}); err != nil {
+		if err := notifyClose(ctx, conn, u); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+func notifyClose(ctx context.Context, conn jsonrpc2.Conn, docURI string) error {
+	return conn.Notify(ctx, protocol.MethodTextDocumentDidClose, &protocol.DidCloseTextDocumentParams{
+		TextDocument: protocol.TextDocumentIdentifier{
+			URI: protocol.DocumentURI(docURI),
+		},
+	})
+}
@@ -1,8 +1,21 @@
 package docsync
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
 )
 
@@ -16,6 +29,11 @@ func TestLanguageIDFromPath(t *testing.T) {
 		{"file.js", protocol.JavaScriptLanguage},
 		{"file.jsx", protocol.JavaScriptReactLanguage},
 		{"file.d.ts", protocol.TypeScriptLanguage}, // .d.ts extension is .ts
+		{"file.mts", protocol.TypeScriptLanguage},
+		{"file.cts", protocol.TypeScriptLanguage},
+		{"file.d.mts", protocol.TypeScriptLanguage},
+		{"file.mjs", protocol.JavaScriptLanguage},
+		{"file.cjs", protocol.JavaScriptLanguage},
 		{"/path/to/deep/file.ts", protocol.TypeScriptLanguage},
 		{"/path/to/deep/file.tsx", protocol.TypeScriptReactLanguage},
 		{"FILE.TS", protocol.TypeScriptLanguage},   // case insensitive
@@ -35,3 +53,572 @@ func TestLanguageIDFromPath(t *testing.T) {
 		})
 	}
 }
+
+// fakeConn records notifications instead of sending them.
+type fakeConn struct {
+	jsonrpc2.Conn
+	mu      sync.Mutex
+	notes   []string // "method uri"
+	failure error
+}
+
+func (c *fakeConn) Notify(_ context.Context, method string, params interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failure != nil {
+		return c.failure
+	}
+	var u protocol.DocumentURI
+	switch p := params.(type) {
+	case *protocol.DidOpenTextDocumentParams:
+		u = p.TextDocument.URI
+	case *protocol.DidChangeTextDocumentParams:
+		u = p.TextDocument.URI
+	case *protocol.DidCloseTextDocumentParams:
+		u = p.TextDocument.URI
+	}
+	c.notes = append(c.notes, method+" "+filepath.Base(URIToFile(string(u))))
+	return nil
+}
+
+func (c *fakeConn) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.notes
+	c.notes = nil
+	return out
+}
+
+func writeFiles(t *testing.T, names ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(names))
+	for i, n := range names {
+		paths[i] = filepath.Join(dir, n)
+		if err := os.WriteFile(paths[i], []byte("export const "+strings.TrimSuffix(n, filepath.Ext(n))+" = 1;\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts", "b.tsx", "c.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+
+	if err := m.SyncFiles(ctx, conn, paths); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths[0], []byte("export const a = 2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ResyncFile(ctx, conn, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"textDocument/didOpen a.ts",
+		"textDocument/didOpen b.tsx",
+		"textDocument/didOpen c.ts",
+		"textDocument/didChange a.ts",
+	}
+	if got := conn.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+
+	docs := m.Documents()
+	if len(docs) != 3 || docs[0].Version != 2 || docs[1].LanguageID != protocol.TypeScriptReactLanguage {
+		t.Errorf("Documents = %+v", docs)
+	}
+	if docs[0].OpenedAt.IsZero() || docs[0].SyncedAt.Before(docs[0].OpenedAt) {
+		t.Errorf("timestamps = %v, %v", docs[0].OpenedAt, docs[0].SyncedAt)
+	}
+
+	release := m.Pin(paths[1])
+	if !m.Documents()[1].Pinned {
+		t.Error("b.tsx not reported as pinned")
+	}
+	missing := filepath.Join(filepath.Dir(paths[0]), "missing.ts")
+	closed, skipped, err := m.CloseFiles(ctx, conn, []string{paths[0], paths[1], missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(closed, []string{paths[0]}) {
+		t.Errorf("closed = %v", closed)
+	}
+	wantSkipped := []SkippedClose{{Path: paths[1], Reason: SkipPinned}, {Path: missing, Reason: SkipNotOpen}}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("skipped = %v, want %v", skipped, wantSkipped)
+	}
+	if got, want := conn.take(), []string{"textDocument/didClose a.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+
+	release()
+	release() // releasing twice must not unpin a second holder
+	closed, skipped, err = m.CloseFiles(ctx, conn, []string{paths[1]})
+	if err != nil || len(closed) != 1 || len(skipped) != 0 {
+		t.Errorf("after release: closed %v, skipped %v, err %v", closed, skipped, err)
+	}
+
+	// A closed document is reopened on the next sync without reusing a
+	// version number.
+	conn.take()
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m.Version(paths[0]); !ok || v != 3 {
+		t.Errorf("Version after reopen = %d, %v", v, ok)
+	}
+	if got, want := conn.take(), []string{"textDocument/didOpen a.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+}
+
+func TestManagerNestedPins(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	r1, r2 := m.Pin(paths[0]), m.Pin(paths[0])
+	r1()
+	if _, skipped, _ := m.CloseFiles(ctx, conn, paths); len(skipped) != 1 {
+		t.Fatalf("closed while still pinned once")
+	}
+	r2()
+	if closed, _, _ := m.CloseFiles(ctx, conn, paths); len(closed) != 1 {
+		t.Fatalf("not closed after all pins released")
+	}
+}
+
+func TestManagerOpenLimit(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts", "b.ts", "c.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	m.SetMaxOpen(2)
+
+	if err := m.SyncFiles(ctx, conn, paths[:2]); err != nil {
+		t.Fatal(err)
+	}
+	err := m.SyncFile(ctx, conn, paths[2])
+	if !errors.Is(err, ErrOpenLimit) {
+		t.Fatalf("SyncFile over the cap = %v, want ErrOpenLimit", err)
+	}
+	if _, ok := m.Version(paths[2]); ok {
+		t.Error("document tracked despite the cap")
+	}
+
+	// Re-syncing an open document is not affected by the cap.
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+		t.Errorf("re-sync at the cap: %v", err)
+	}
+
+	if _, _, err := m.CloseFiles(ctx, conn, paths[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SyncFile(ctx, conn, paths[2]); err != nil {
+		t.Errorf("SyncFile after closing one = %v", err)
+	}
+}
+
+func TestCloseFilesNotifyError(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	conn.failure = errors.New("connection closed")
+	closed, _, err := m.CloseFiles(ctx, conn, paths)
+	if err == nil || len(closed) != 0 {
+		t.Errorf("CloseFiles = %v, %v; want error and nothing closed", closed, err)
+	}
+}
+
+func TestManagerReopen(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "b.ts", "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	m.SetFreshness(time.Hour)
+	if err := m.SyncFiles(ctx, conn, paths); err != nil {
+		t.Fatal(err)
+	}
+	conn.take()
+
+	restarted := &fakeConn{}
+	if err := m.Reopen(ctx, restarted); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := restarted.take(), []string{"textDocument/didOpen a.ts", "textDocument/didOpen b.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+	if v, _ := m.Version(paths[0]); v != 1 {
+		t.Errorf("version after reopening = %d, want 1", v)
+	}
+
+	// A change made while the server was down is picked up despite the
+	// freshness window.
+	if err := os.WriteFile(paths[0], []byte("export const b = 2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SyncFile(ctx, restarted, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := restarted.take(), []string{"textDocument/didChange b.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+
+	restarted.failure = errors.New("connection closed")
+	if err := m.Reopen(ctx, restarted); err == nil {
+		t.Error("Reopen ignored the notification error")
+	}
+}
+
+// countReads makes m count its disk reads.
+func countReads(m *Manager) *atomic.Int32 {
+	var n atomic.Int32
+	m.readFile = func(p string) ([]byte, error) {
+		n.Add(1)
+		return os.ReadFile(p)
+	}
+	return &n
+}
+
+func parallelSync(t testing.TB, m *Manager, conn jsonrpc2.Conn, path string, n int) {
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.SyncFile(context.Background(), conn, path)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncFileCoalescing(t *testing.T) {
+	paths := writeFiles(t, "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	m.SetFreshness(time.Hour)
+	reads := countReads(m)
+
+	// Callers either join the first sync or find the document fresh.
+	parallelSync(t, m, conn, paths[0], 20)
+	if n := reads.Load(); n != 1 {
+		t.Errorf("reads = %d, want 1", n)
+	}
+	if got, want := conn.take(), []string{"textDocument/didOpen a.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+
+	// Without a freshness window every caller may read, but the change is
+	// still announced once.
+	m.SetFreshness(0)
+	if err := os.WriteFile(paths[0], []byte("export const a = 2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reads.Store(0)
+	parallelSync(t, m, conn, paths[0], 20)
+	if n := reads.Load(); n < 1 || n > 20 {
+		t.Errorf("reads = %d, want 1..20", n)
+	}
+	if got, want := conn.take(), []string{"textDocument/didChange a.ts"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+	if v, _ := m.Version(paths[0]); v != 2 {
+		t.Errorf("version = %d, want 2", v)
+	}
+}
+
+func TestSyncFileFreshness(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	m.SetFreshness(time.Hour)
+	reads := countReads(m)
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	conn.take()
+
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(paths[0], []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	syncWith := func(f func(context.Context, jsonrpc2.Conn, string) error) []string {
+		t.Helper()
+		if err := f(ctx, conn, paths[0]); err != nil {
+			t.Fatal(err)
+		}
+		return conn.take()
+	}
+
+	if got := syncWith(m.SyncFile); len(got) != 0 || reads.Load() != 1 {
+		t.Errorf("within the window: notifications %v, reads %d; want none and 1", got, reads.Load())
+	}
+	write("export const a = 2;\n")
+	if got := syncWith(m.SyncFile); len(got) != 1 || reads.Load() != 2 {
+		t.Errorf("rewritten within the window: notifications %v, reads %d; want a didChange and 2", got, reads.Load())
+	}
+
+	// A rewrite keeping the size and modification time goes unnoticed
+	// until Invalidate.
+	fi, err := os.Stat(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("export const a = 3;\n")
+	if err := os.Chtimes(paths[0], fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got := syncWith(m.SyncFile); len(got) != 0 {
+		t.Errorf("same stamp: notifications = %v, want none", got)
+	}
+	m.Invalidate(paths[0])
+	if got := syncWith(m.SyncFile); len(got) != 1 {
+		t.Errorf("after Invalidate: notifications = %v, want a didChange", got)
+	}
+	write("export const a = 4;\n")
+	if got := syncWith(m.ResyncFile); len(got) != 1 {
+		t.Errorf("ResyncFile: notifications = %v, want a didChange", got)
+	}
+	if v, _ := m.Version(paths[0]); v != 4 {
+		t.Errorf("version = %d, want 4", v)
+	}
+}
+
+func BenchmarkParallelSyncFile(b *testing.B) {
+	for _, bc := range []struct {
+		name      string
+		freshness time.Duration
+	}{
+		{"window", DefaultFreshness},
+		{"no-window", 0},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "a.ts")
+			if err := os.WriteFile(path, []byte(strings.Repeat("export const a = 1;\n", 500)), 0644); err != nil {
+				b.Fatal(err)
+			}
+			conn := &fakeConn{}
+			m := NewManager()
+			m.SetFreshness(bc.freshness)
+			reads := countReads(m)
+			b.ResetTimer()
+			// Each iteration is one burst of hover+definition+references.
+			for i := 0; i < b.N; i++ {
+				parallelSync(b, m, conn, path, 3)
+			}
+			b.ReportMetric(float64(reads.Load())/float64(b.N), "reads/op")
+		})
+	}
+}
+
+func TestManagerOnChange(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	var changes []string
+	m.OnChange(func(p string, v int32) {
+		changes = append(changes, fmt.Sprintf("%s@%d", filepath.Base(p), v))
+	})
+
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil { // first open
+		t.Fatal(err)
+	}
+	if err := m.ResyncFile(ctx, conn, paths[0]); err != nil { // unchanged
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths[0], []byte("export const a = 2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ResyncFile(ctx, conn, paths[0]); err != nil { // didChange
+		t.Fatal(err)
+	}
+	if _, _, err := m.CloseFiles(ctx, conn, paths); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil { // reopen
+		t.Fatal(err)
+	}
+	if want := []string{"a.ts@2", "a.ts@3"}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+}
+
+func TestSyncContentOverlay(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+
+	if err := m.SyncContent(ctx, conn, paths[0], []byte("export const a = (;\n")); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := m.Document(paths[0]); d.Version != 1 {
+		t.Errorf("overlay version = %d, want 1", d.Version)
+	}
+	// The overlay is not trusted by the freshness window: the next
+	// SyncFile restores the content on disk.
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"textDocument/didOpen a.ts", "textDocument/didChange a.ts"}
+	if got := conn.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("notifications = %v, want %v", got, want)
+	}
+}
+
+func TestManagerContentHash(t *testing.T) {
+	ctx := context.Background()
+	paths := writeFiles(t, "a.ts")
+	conn := &fakeConn{}
+	m := NewManager()
+	hash := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	if _, ok := m.ContentHash(paths[0]); ok {
+		t.Error("ContentHash of a file not open")
+	}
+	if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m.ContentHash(paths[0]); !ok || got != hash("export const a = 1;\n") {
+		t.Errorf("ContentHash = %q, %v", got, ok)
+	}
+	// The hash is of the content the server has, not of the disk.
+	if err := os.WriteFile(paths[0], []byte("export const a = 2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.ContentHash(paths[0]); got != hash("export const a = 1;\n") {
+		t.Errorf("ContentHash after a write = %q", got)
+	}
+	if err := m.SyncContent(ctx, conn, paths[0], []byte("export const a = (;\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.ContentHash(paths[0]); got != hash("export const a = (;\n") {
+		t.Errorf("ContentHash of an overlay = %q", got)
+	}
+}
+
+// changesConn records the content changes of every didChange.
+type changesConn struct {
+	fakeConn
+	changes [][]protocol.TextDocumentContentChangeEvent
+}
+
+func (c *changesConn) Notify(ctx context.Context, method string, params interface{}) error {
+	if p, ok := params.(*protocol.DidChangeTextDocumentParams); ok {
+		c.mu.Lock()
+		c.changes = append(c.changes, p.ContentChanges)
+		c.mu.Unlock()
+	}
+	return c.fakeConn.Notify(ctx, method, params)
+}
+
+func TestManagerApplyEdits(t *testing.T) {
+	ctx := context.Background()
+	rename := []protocol.TextEdit{{
+		Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 13}, End: protocol.Position{Line: 0, Character: 14}},
+		NewText: "b",
+	}}
+	renamed := "export const b = 1;\n"
+
+	t.Run("incremental", func(t *testing.T) {
+		paths := writeFiles(t, "a.ts")
+		conn := &changesConn{}
+		m := NewManager()
+		m.SetIncremental(func() bool { return true })
+		var changed []int32
+		m.OnChange(func(_ string, v int32) { changed = append(changed, v) })
+		if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+			t.Fatal(err)
+		}
+		// The file is not read again: what is on disk does not matter.
+		if err := os.WriteFile(paths[0], []byte("unrelated\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.ApplyEdits(ctx, conn, paths[0], rename); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"textDocument/didOpen a.ts", "textDocument/didChange a.ts"}
+		if got := conn.take(); !reflect.DeepEqual(got, want) {
+			t.Errorf("notifications = %v, want %v", got, want)
+		}
+		if len(conn.changes) != 1 || !reflect.DeepEqual(conn.changes[0], []protocol.TextDocumentContentChangeEvent{{Range: rename[0].Range, Text: "b"}}) {
+			t.Errorf("changes = %+v, want the ranged rename", conn.changes)
+		}
+		if d, _ := m.Document(paths[0]); d.Version != 2 || !reflect.DeepEqual(changed, []int32{2}) {
+			t.Errorf("version = %d, listeners saw %v; want 2, seen once", d.Version, changed)
+		}
+		sum := sha256.Sum256([]byte(renamed))
+		if got, _ := m.ContentHash(paths[0]); got != hex.EncodeToString(sum[:]) {
+			t.Errorf("ContentHash = %q, want that of %q", got, renamed)
+		}
+	})
+
+	fullText := []struct {
+		name        string
+		incremental bool
+		open        bool
+		edits       []protocol.TextEdit
+		want        []string
+	}{
+		{name: "server takes only full text", open: true, edits: rename, want: []string{"textDocument/didOpen a.ts", "textDocument/didChange a.ts"}},
+		{name: "document not open", incremental: true, edits: rename, want: []string{"textDocument/didOpen a.ts"}},
+		{
+			name: "edits that do not apply", incremental: true, open: true,
+			edits: []protocol.TextEdit{{Range: protocol.Range{Start: protocol.Position{Line: 5}, End: protocol.Position{Line: 5}}, NewText: "x"}},
+			want:  []string{"textDocument/didOpen a.ts", "textDocument/didChange a.ts"},
+		},
+	}
+	for _, tt := range fullText {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := writeFiles(t, "a.ts")
+			conn := &changesConn{}
+			m := NewManager()
+			m.SetIncremental(func() bool { return tt.incremental })
+			if tt.open {
+				if err := m.SyncFile(ctx, conn, paths[0]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(paths[0], []byte(renamed), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := m.ApplyEdits(ctx, conn, paths[0], tt.edits); err != nil {
+				t.Fatal(err)
+			}
+			if got := conn.take(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("notifications = %v, want %v", got, tt.want)
+			}
+			for _, changes := range conn.changes {
+				if len(changes) != 1 || changes[0].Text != renamed {
+					t.Errorf("changes = %+v, want the full text read from disk", changes)
+				}
+			}
+		})
+	}
+}
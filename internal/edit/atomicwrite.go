@@ -0,0 +1,99 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// atomicWriteFault, when set, runs after the new content of path is synced
+// to its temp file and before the temp file replaces path; tests use it to
+// fail a write halfway.
+var atomicWriteFault func(path string) error
+
+// windowsRenameRetries bounds the retries of a rename on Windows, where
+// replacing a file fails while another process, such as an editor or a
+// virus scanner, briefly holds it open.
+const windowsRenameRetries = 10
+
+// WriteFileAtomic replaces the content of path with data so that a reader
+// or a crash sees either the old or the new content, never a truncated
+// file: data is written and synced to a temp file in the same directory,
+// which is then renamed over path. The file gets mode, and a symlink is
+// followed so that its target is replaced rather than the link.
+//
+// A file that exists but cannot be opened for writing is not replaced,
+// as writing it in place would fail too.
+func WriteFileAtomic(path string, data []byte, mode os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	if f, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		_ = f.Close()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if err == nil {
+		// CreateTemp uses 0600; Chmod sets the mode regardless of the umask.
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && atomicWriteFault != nil {
+		err = atomicWriteFault(path)
+	}
+	if err == nil {
+		err = renameReplace(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// renameReplace renames from over an existing to. On Windows os.Rename
+// replaces an existing file too, but fails with an access error while the
+// file is open elsewhere, so the rename is retried for a short while.
+func renameReplace(from, to string) error {
+	err := os.Rename(from, to)
+	if runtime.GOOS != "windows" {
+		return err
+	}
+	for i := 0; err != nil && errors.Is(err, os.ErrPermission) && i < windowsRenameRetries; i++ {
+		time.Sleep(time.Duration(i+1) * 10 * time.Millisecond)
+		err = os.Rename(from, to)
+	}
+	if err != nil {
+		return fmt.Errorf("replacing %s: %w", to, err)
+	}
+	return nil
+}
+
+// syncDir makes a rename in dir durable. Errors are ignored: not every
+// platform or file system can sync a directory, and the rename itself has
+// already happened.
+func syncDir(dir string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
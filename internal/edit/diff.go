@@ -0,0 +1,224 @@
+package edit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffContextLines is the number of unchanged lines UnifiedDiff shows
+// around each hunk.
+const DiffContextLines = 3
+
+// DiffOp is a single line-level operation produced by DiffLines.
+type DiffOp struct {
+	Kind byte // ' ', '-', or '+'
+	Text string
+}
+
+// UnifiedDiff returns a unified diff between two versions of a file. It
+// returns an empty string when the contents are identical.
+func UnifiedDiff(path string, original, updated []byte) string {
+	diff, _ := FileDiff(path, original, updated, DiffContextLines)
+	return diff
+}
+
+// FileDiff returns the unified diff between two versions of a file with
+// context unchanged lines around each hunk, and the 1-based lines of
+// updated that are new or changed. Both are empty when the contents are
+// identical.
+func FileDiff(path string, original, updated []byte, context int) (string, []int) {
+	if string(original) == string(updated) {
+		return "", nil
+	}
+	a := diffSplit(string(original))
+	b := diffSplit(string(updated))
+	ops := DiffLines(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", path, path)
+	writeHunks(&sb, ops, context)
+
+	var changed []int
+	line := 0
+	for _, op := range ops {
+		if op.Kind != '-' {
+			line++
+		}
+		if op.Kind == '+' {
+			changed = append(changed, line)
+		}
+	}
+	return sb.String(), changed
+}
+
+// TruncateDiff cuts diff after limit lines and ends it with a note on how
+// many lines were left out. It reports whether it cut anything.
+func TruncateDiff(diff string, limit int) (string, bool) {
+	lines := strings.SplitAfter(diff, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= limit {
+		return diff, false
+	}
+	omitted := len(lines) - limit
+	return strings.Join(lines[:limit], "") + fmt.Sprintf("... %d more diff lines omitted; the edit itself is complete\n", omitted), true
+}
+
+// diffSplit splits content into lines without their line endings, so
+// that a CRLF file diffs like an LF one.
+func diffSplit(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := SplitLines([]byte(s))
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, l := range lines {
+		lines[i] = trimEOL(l)
+	}
+	return lines
+}
+
+// DiffLines computes a shortest edit script between a and b using Myers'
+// algorithm. Rename-style edits touch few lines, so the O((N+M)D) cost stays
+// small even for large files.
+func DiffLines(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the Myers trace from the end to recover the edit script.
+func backtrack(trace [][]int, a, b []string, offset int) []DiffOp {
+	x, y := len(a), len(b)
+	var ops []DiffOp
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, DiffOp{Kind: ' ', Text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, DiffOp{Kind: '+', Text: b[y]})
+			} else {
+				x--
+				ops = append(ops, DiffOp{Kind: '-', Text: a[x]})
+			}
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// writeHunks renders ops as unified diff hunks with the given context size.
+func writeHunks(sb *strings.Builder, ops []DiffOp, context int) {
+	i := 0
+	for i < len(ops) {
+		// Find the next change.
+		for i < len(ops) && ops[i].Kind == ' ' {
+			i++
+		}
+		if i >= len(ops) {
+			return
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk while changes are within 2*context of each other.
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == ' ' {
+				run++
+			}
+			if run >= len(ops) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		aStart, bStart := 1, 1
+		for _, op := range ops[:start] {
+			if op.Kind != '+' {
+				aStart++
+			}
+			if op.Kind != '-' {
+				bStart++
+			}
+		}
+		aLen, bLen := 0, 0
+		for _, op := range ops[start:end] {
+			if op.Kind != '+' {
+				aLen++
+			}
+			if op.Kind != '-' {
+				bLen++
+			}
+		}
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+		fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.Kind)
+			sb.WriteString(op.Text)
+			sb.WriteByte('\n')
+		}
+		i = end
+	}
+}
@@ -0,0 +1,104 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		updated  string
+		want     string
+	}{
+		{
+			name:     "identical",
+			original: "a\nb\n",
+			updated:  "a\nb\n",
+			want:     "",
+		},
+		{
+			name:     "single line change",
+			original: "const a = greet;\nconst b = other;\n",
+			updated:  "const a = sayHello;\nconst b = other;\n",
+			want: "--- f.ts\n+++ f.ts\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"-const a = greet;\n" +
+				"+const a = sayHello;\n" +
+				" const b = other;\n",
+		},
+		{
+			name:     "separate hunks",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			updated:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- f.ts\n+++ f.ts\n" +
+				"@@ -1,4 +1,4 @@\n" +
+				"-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n" +
+				" 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name:     "insertion into empty file",
+			original: "",
+			updated:  "x\n",
+			want:     "--- f.ts\n+++ f.ts\n@@ -0,0 +1,1 @@\n+x\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("f.ts", []byte(tt.original), []byte(tt.updated))
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	diff := "--- f.ts\n+++ f.ts\n@@ -1,3 +1,3 @@\n-a\n+b\n c\n"
+	if got, cut := TruncateDiff(diff, 6); got != diff || cut {
+		t.Errorf("diff within the limit = %q, %v; want it unchanged", got, cut)
+	}
+	if got, cut := TruncateDiff("", 6); got != "" || cut {
+		t.Errorf("empty diff = %q, %v", got, cut)
+	}
+	got, cut := TruncateDiff(diff, 4)
+	want := "--- f.ts\n+++ f.ts\n@@ -1,3 +1,3 @@\n-a\n... 2 more diff lines omitted; the edit itself is complete\n"
+	if got != want || !cut {
+		t.Errorf("truncated diff = %q, %v; want %q", got, cut, want)
+	}
+	if !strings.HasSuffix(got, "\n") {
+		t.Error("the truncated diff does not end with a newline")
+	}
+}
+
+func TestFileDiffChangedLines(t *testing.T) {
+	original := "1\n2\n3\n4\n5\n6\n"
+	updated := "1\ntwo\n3\n4\n5\nfive and a half\n6\n"
+	diff, changed := FileDiff("f.ts", []byte(original), []byte(updated), 1)
+	want := "--- f.ts\n+++ f.ts\n" +
+		"@@ -1,3 +1,3 @@\n 1\n-2\n+two\n 3\n" +
+		"@@ -5,2 +5,3 @@\n 5\n+five and a half\n 6\n"
+	if diff != want {
+		t.Errorf("diff = %q, want %q", diff, want)
+	}
+	if len(changed) != 2 || changed[0] != 2 || changed[1] != 6 {
+		t.Errorf("changed lines = %v, want [2 6]", changed)
+	}
+	if diff, changed := FileDiff("f.ts", []byte(original), []byte(original), 1); diff != "" || changed != nil {
+		t.Errorf("identical contents = %q, %v", diff, changed)
+	}
+}
+
+func TestFileDiffLineEndings(t *testing.T) {
+	want := "--- f.ts\n+++ f.ts\n@@ -1,2 +1,2 @@\n-a\n+A\n b\n"
+	for name, eol := range map[string]string{"crlf": "\r\n", "cr": "\r"} {
+		original := "a" + eol + "b" + eol
+		updated := "A" + eol + "b" + eol
+		if diff, _ := FileDiff("f.ts", []byte(original), []byte(updated), 1); diff != want {
+			t.Errorf("%s: diff = %q, want %q", name, diff, want)
+		}
+	}
+}
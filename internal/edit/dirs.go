@@ -0,0 +1,50 @@
+package edit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"go.lsp.dev/protocol"
+)
+
+// CreatedFileMode is the mode of files an edit creates.
+const CreatedFileMode = 0o644
+
+// startsFile reports whether edits only insert at the start of a file, as
+// the edits of a file that does not exist yet do.
+func startsFile(edits []protocol.TextEdit) bool {
+	for _, e := range edits {
+		if e.Range.Start != (protocol.Position{}) {
+			return false
+		}
+	}
+	return len(edits) > 0
+}
+
+// MissingDirs returns dir and those of its parents that do not exist,
+// deepest first.
+func MissingDirs(dir string) []string {
+	var out []string
+	for {
+		if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+			return out
+		}
+		out = append(out, dir)
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return out
+		}
+		dir = parent
+	}
+}
+
+// RemoveEmptyDirs removes dirs, deepest first, stopping at the first one
+// that is not empty.
+func RemoveEmptyDirs(dirs []string) {
+	for _, d := range dirs {
+		if os.Remove(d) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package edit
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// Skipped is a file dropped from an edit because it is out of scope.
+type Skipped struct {
+	File string `json:"file"`
+	// Edits counts the text edits dropped with the file.
+	Edits  int    `json:"edits"`
+	Reason string `json:"reason"`
+}
+
+// Drop removes from edit the text edits and resource operations of the
+// files reason gives a reason for, and returns those files in sorted path
+// order. reason returns "" for a file to keep. The indexes of the
+// remaining operations are adjusted to the document changes kept.
+func Drop(edit *lsp.WorkspaceEdit, reason func(path string) string) []Skipped {
+	skipped := make(map[string]*Skipped)
+	out := func(u protocol.DocumentURI, edits int) bool {
+		if u == "" {
+			return false
+		}
+		p := docsync.URIToFile(string(u))
+		why := reason(p)
+		if why == "" {
+			return false
+		}
+		if f, ok := skipped[p]; ok {
+			f.Edits += edits
+		} else {
+			skipped[p] = &Skipped{File: p, Edits: edits, Reason: why}
+		}
+		return true
+	}
+
+	for u, edits := range edit.Changes {
+		if out(u, len(edits)) {
+			delete(edit.Changes, u)
+		}
+	}
+	// kept[i] is the number of document changes kept before the i-th.
+	kept := make([]int, len(edit.DocumentChanges)+1)
+	var changes []protocol.TextDocumentEdit
+	for i, dc := range edit.DocumentChanges {
+		if !out(dc.TextDocument.URI, len(dc.Edits)) {
+			changes = append(changes, dc)
+		}
+		kept[i+1] = len(changes)
+	}
+	var ops []lsp.ResourceOperation
+	for _, op := range edit.Operations {
+		// Both ends are checked, so that a move out of scope is dropped
+		// whole.
+		from, to := out(op.URI, 0), out(op.NewURI, 0)
+		if from || to {
+			continue
+		}
+		op.Index = kept[min(op.Index, len(edit.DocumentChanges))]
+		ops = append(ops, op)
+	}
+	if len(skipped) == 0 {
+		return nil
+	}
+	edit.DocumentChanges, edit.Operations = changes, ops
+
+	list := make([]Skipped, 0, len(skipped))
+	for _, f := range skipped {
+		list = append(list, *f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].File < list[j].File })
+	return list
+}
+
+// excludeReason returns the reason function of Drop for globs relative to
+// root, in tsconfig exclude syntax, or nil when there are none.
+func excludeReason(root string, globs []string) func(string) string {
+	var patterns []string
+	var res []*regexp.Regexp
+	for _, glob := range globs {
+		if re := tsconfig.ExcludePattern(filepath.Clean(root), glob); re != nil {
+			patterns = append(patterns, glob)
+			res = append(res, re)
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return func(path string) string {
+		p := filepath.ToSlash(filepath.Clean(path))
+		for i, re := range res {
+			if re.MatchString(p) {
+				return "matches exclude glob " + patterns[i]
+			}
+		}
+		return ""
+	}
+}
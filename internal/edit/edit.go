@@ -0,0 +1,123 @@
+// Package edit applies LSP workspace edits to disk. Text edits and create,
+// rename and delete operations are replayed in memory (see Plan), checked,
+// and written with rollback on failure; every tool that writes files goes
+// through Apply.
+package edit
+
+import (
+	"maps"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// Options control how Apply writes an edit.
+type Options struct {
+	// DryRun computes the result, running Check, without writing
+	// anything.
+	DryRun bool
+	// ExcludeGlobs are globs of files left out of the edit, relative to
+	// Root in tsconfig exclude syntax; a glob covers what it matches and
+	// everything below. The files left out are listed in
+	// ApplyResult.Skipped.
+	Root         string
+	ExcludeGlobs []string
+	// PreserveMode writes every file with the mode it had, or the moved
+	// file had. Otherwise files are written with CreatedFileMode.
+	PreserveMode bool
+	// Atomic replaces each file through a synced temporary file renamed
+	// over it, so that a reader or a crash sees either the old or the new
+	// content (see WriteFileAtomic). Otherwise files are written in place.
+	Atomic bool
+	// Diff adds to each file's result the unified diff of its change,
+	// with DiffContext unchanged lines around each hunk, cut after
+	// MaxDiffLines lines when that is positive.
+	Diff         bool
+	DiffContext  int
+	MaxDiffLines int
+	// Check, when set, is called with the planned files before any is
+	// written; an error stops the edit.
+	Check func(files []File) error
+	// Write, when set, writes the files in place of Write, e.g. through
+	// a journal. When it fails it must leave the files as they were.
+	Write func(files []File) error
+	// BeforeWrite, when set, runs just before WriteFile revalidates and
+	// writes each file; an error fails that write. Tests use it to
+	// interleave outside writes.
+	BeforeWrite func(path string) error
+}
+
+// ApplyResult is what Apply wrote, or would write with DryRun.
+type ApplyResult struct {
+	// Files are the files the edit writes, in sorted path order. A moved
+	// file is deleted at its old path and created at the new one.
+	Files []FileResult
+	// Skipped are the files Options.ExcludeGlobs left out.
+	Skipped []Skipped
+	DryRun  bool
+}
+
+// FileResult is one file of an ApplyResult.
+type FileResult struct {
+	File
+	// Diff is the change from the content the edits apply to (see
+	// File.Base) to the updated content, set with Options.Diff except for
+	// deleted files; DiffTruncated is set when it was cut. ChangedLines
+	// are the 1-based lines of the updated content that are new or
+	// changed.
+	Diff          string
+	DiffTruncated bool
+	ChangedLines  []int
+}
+
+// Apply applies edit to disk as opts say. Files are written in sorted
+// path order; on any write failure the files already written are rolled
+// back. Everything is checked before anything is written: the edit must
+// apply (see Plan) and pass opts.Check. A file changed on disk since it
+// was read stops the edit with a *ConcurrentModificationError, and
+// rollback leaves alone written files that changed again since. The
+// edit's files are locked against other edits throughout (see Lock).
+func Apply(edit *lsp.WorkspaceEdit, opts Options) (*ApplyResult, error) {
+	result := &ApplyResult{DryRun: opts.DryRun}
+	if reason := excludeReason(opts.Root, opts.ExcludeGlobs); reason != nil {
+		// Drop changes the edit; the caller's is left as it is.
+		copied := *edit
+		copied.Changes = maps.Clone(edit.Changes)
+		edit = &copied
+		result.Skipped = Drop(edit, reason)
+	}
+
+	// Hold the files from reading the originals until the last write.
+	defer Lock(Paths(edit))()
+
+	files, err := Plan(edit)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Check != nil {
+		if err := opts.Check(files); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.DryRun {
+		write := opts.Write
+		if write == nil {
+			write = func(files []File) error { return Write(files, opts) }
+		}
+		if err := write(files); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Files = make([]FileResult, len(files))
+	for i, f := range files {
+		r := FileResult{File: f}
+		if opts.Diff && !f.Deleted {
+			r.Diff, r.ChangedLines = FileDiff(f.Path, f.Base(), f.Updated, opts.DiffContext)
+			if opts.MaxDiffLines > 0 {
+				r.Diff, r.DiffTruncated = TruncateDiff(r.Diff, opts.MaxDiffLines)
+			}
+		}
+		result.Files[i] = r
+	}
+	return result, nil
+}
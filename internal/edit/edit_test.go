@@ -0,0 +1,413 @@
+package edit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// renameEdit is an edit renaming greet to sayHello at column 10 of the
+// first line of every file, as the files of fixture hold it.
+func renameEdit(files ...string) *lsp.WorkspaceEdit {
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+	for _, f := range files {
+		edit.Changes[protocol.DocumentURI("file://"+f)] = []protocol.TextEdit{textEdit(0, 10, 0, 15, "sayHello")}
+	}
+	return edit
+}
+
+const (
+	fixtureContent = "const x = greet;\n"
+	renamedContent = "const x = sayHello;\n"
+)
+
+// fixture writes fixtureContent to the named files in a new directory,
+// with mode 0644, and returns their paths.
+func fixture(t *testing.T, names ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var files []string
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(fixtureContent), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(p, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, p)
+	}
+	return files
+}
+
+// contents returns the content of every file, or "<absent>".
+func contents(t *testing.T, files []string) []string {
+	t.Helper()
+	out := make([]string, len(files))
+	for i, f := range files {
+		data, err := os.ReadFile(f)
+		if errors.Is(err, os.ErrNotExist) {
+			out[i] = "<absent>"
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = string(data)
+	}
+	return out
+}
+
+func TestApply(t *testing.T) {
+	t.Run("multi-file edit", func(t *testing.T) {
+		files := fixture(t, "b.ts", "a.ts")
+		result, err := Apply(renameEdit(files...), Options{Diff: true, DiffContext: DiffContextLines})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := contents(t, files); !slices.Equal(got, []string{renamedContent, renamedContent}) {
+			t.Errorf("contents = %q", got)
+		}
+		if len(result.Files) != 2 || result.Files[0].Path != files[1] || result.Files[1].Path != files[0] {
+			t.Fatalf("files = %+v, want a.ts and b.ts in path order", result.Files)
+		}
+		f := result.Files[0]
+		wantDiff := "--- " + f.Path + "\n+++ " + f.Path + "\n@@ -1,1 +1,1 @@\n-" + fixtureContent + "+" + renamedContent
+		if f.Diff != wantDiff || f.DiffTruncated || !reflect.DeepEqual(f.ChangedLines, []int{1}) {
+			t.Errorf("diff = %q, changed lines %v; want %q and [1]", f.Diff, f.ChangedLines, wantDiff)
+		}
+		if f.Original == nil || string(f.Updated) != renamedContent || len(f.Edits) != 1 {
+			t.Errorf("file = %+v", f.File)
+		}
+		if result.DryRun || result.Skipped != nil {
+			t.Errorf("result = %+v", result)
+		}
+	})
+
+	t.Run("rollback on write failure", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		// Names chosen so "aaa_writable.ts" sorts before "zzz_readonly.ts",
+		// guaranteeing the writable file is written first and must be rolled back.
+		writableFile := filepath.Join(tmpDir, "aaa_writable.ts")
+		readonlyFile := filepath.Join(tmpDir, "zzz_readonly.ts")
+
+		writableContent := "const a = greet;\n"
+		readonlyContent := "const b = greet;\n"
+
+		if err := os.WriteFile(writableFile, []byte(writableContent), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := os.WriteFile(readonlyFile, []byte(readonlyContent), 0444); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		// Ensure cleanup can remove the read-only file.
+		t.Cleanup(func() { _ = os.Chmod(readonlyFile, 0644) })
+
+		writableURI := protocol.DocumentURI("file://" + writableFile)
+		readonlyURI := protocol.DocumentURI("file://" + readonlyFile)
+
+		edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				writableURI: {
+					{
+						Range: protocol.Range{
+							Start: protocol.Position{Line: 0, Character: 10},
+							End:   protocol.Position{Line: 0, Character: 15},
+						},
+						NewText: "sayHello",
+					},
+				},
+				readonlyURI: {
+					{
+						Range: protocol.Range{
+							Start: protocol.Position{Line: 0, Character: 10},
+							End:   protocol.Position{Line: 0, Character: 15},
+						},
+						NewText: "sayHello",
+					},
+				},
+			},
+		}}
+
+		_, err := Apply(edit, Options{Atomic: true, PreserveMode: true})
+		if err == nil {
+			t.Fatal("expected error due to read-only file, got nil")
+		}
+
+		// Verify the writable file was rolled back to original.
+		got, err := os.ReadFile(writableFile)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if string(got) != writableContent {
+			t.Errorf("writable file not rolled back:\ngot:  %s\nwant: %s", string(got), writableContent)
+		}
+	})
+
+	t.Run("rollback on failure between writes", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		files := []string{
+			filepath.Join(tmpDir, "a.ts"),
+			filepath.Join(tmpDir, "b.ts"),
+			filepath.Join(tmpDir, "c.ts"),
+		}
+		edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+		for _, f := range files {
+			if err := os.WriteFile(f, []byte("const x = greet;\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			edit.Changes[protocol.DocumentURI("file://"+f)] = []protocol.TextEdit{{
+				Range:   protocol.Range{Start: protocol.Position{Character: 10}, End: protocol.Position{Character: 15}},
+				NewText: "sayHello",
+			}}
+		}
+		// Fail the second write after its temp file is complete, as a full
+		// disk or a crash between the writes would.
+		var attempted []string
+		atomicWriteFault = func(path string) error {
+			attempted = append(attempted, filepath.Base(path))
+			if filepath.Base(path) == "b.ts" {
+				return errors.New("injected failure")
+			}
+			return nil
+		}
+		t.Cleanup(func() { atomicWriteFault = nil })
+
+		_, err := Apply(edit, Options{Atomic: true, PreserveMode: true})
+		if err == nil || !strings.Contains(err.Error(), "injected failure") {
+			t.Fatalf("err = %v, want the injected failure", err)
+		}
+		// a.ts was written, then restored by the rollback's own atomic write.
+		if want := []string{"a.ts", "b.ts", "a.ts"}; !slices.Equal(attempted, want) {
+			t.Errorf("writes = %v, want %v", attempted, want)
+		}
+		for _, f := range files {
+			if got, _ := os.ReadFile(f); string(got) != "const x = greet;\n" {
+				t.Errorf("%s = %q, want the original", filepath.Base(f), got)
+			}
+		}
+		entries, _ := os.ReadDir(tmpDir)
+		if len(entries) != len(files) {
+			var names []string
+			for _, e := range entries {
+				names = append(names, e.Name())
+			}
+			t.Errorf("directory holds %v, want no temp files left", names)
+		}
+	})
+}
+
+func TestApplyOptions(t *testing.T) {
+	t.Run("dry run", func(t *testing.T) {
+		files := fixture(t, "a.ts")
+		result, err := Apply(renameEdit(files...), Options{DryRun: true, Diff: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := contents(t, files); got[0] != fixtureContent {
+			t.Errorf("dry run wrote %q", got[0])
+		}
+		if !result.DryRun || len(result.Files) != 1 || string(result.Files[0].Updated) != renamedContent || result.Files[0].Diff == "" {
+			t.Errorf("result = %+v, want the would-be update and its diff", result)
+		}
+	})
+
+	t.Run("exclude globs", func(t *testing.T) {
+		files := fixture(t, "a.ts", "a.d.ts")
+		edit := renameEdit(files...)
+		root := filepath.Dir(files[0])
+		result, err := Apply(edit, Options{Root: root, ExcludeGlobs: []string{"**/*.d.ts"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := contents(t, files); !slices.Equal(got, []string{renamedContent, fixtureContent}) {
+			t.Errorf("contents = %q, want only a.ts edited", got)
+		}
+		want := []Skipped{{File: files[1], Edits: 1, Reason: "matches exclude glob **/*.d.ts"}}
+		if !reflect.DeepEqual(result.Skipped, want) {
+			t.Errorf("skipped = %+v, want %+v", result.Skipped, want)
+		}
+		if len(edit.Changes) != 2 {
+			t.Errorf("the caller's edit lost its changes: %v", edit.Changes)
+		}
+	})
+
+	t.Run("modes", func(t *testing.T) {
+		for _, tt := range []struct {
+			name         string
+			preserveMode bool
+			atomic       bool
+			want         os.FileMode
+		}{
+			{"preserved atomically", true, true, 0o600},
+			{"preserved in place", true, false, 0o600},
+			{"default atomically", false, true, CreatedFileMode},
+			{"default in place", false, false, CreatedFileMode},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				files := fixture(t, "a.ts")
+				if err := os.Chmod(files[0], 0o600); err != nil {
+					t.Fatal(err)
+				}
+				before, _ := os.Stat(files[0])
+				if _, err := Apply(renameEdit(files...), Options{PreserveMode: tt.preserveMode, Atomic: tt.atomic}); err != nil {
+					t.Fatal(err)
+				}
+				after, err := os.Stat(files[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if after.Mode().Perm() != tt.want {
+					t.Errorf("mode = %v, want %v", after.Mode().Perm(), tt.want)
+				}
+				// An atomic write replaces the file; one in place keeps it.
+				if same := os.SameFile(before, after); same == tt.atomic {
+					t.Errorf("same file after the write = %v with atomic %v", same, tt.atomic)
+				}
+				if got := contents(t, files); got[0] != renamedContent {
+					t.Errorf("content = %q", got[0])
+				}
+			})
+		}
+	})
+
+	t.Run("diff limit", func(t *testing.T) {
+		dir := t.TempDir()
+		p := filepath.Join(dir, "a.ts")
+		if err := os.WriteFile(p, []byte(strings.Repeat(fixtureContent, 10)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+		var edits []protocol.TextEdit
+		for line := uint32(0); line < 10; line++ {
+			edits = append(edits, textEdit(line, 10, line, 15, "sayHello"))
+		}
+		edit.Changes[protocol.DocumentURI("file://"+p)] = edits
+		result, err := Apply(edit, Options{DryRun: true, Diff: true, MaxDiffLines: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := result.Files[0]
+		if !f.DiffTruncated || strings.Count(f.Diff, "\n") != 6 || len(f.ChangedLines) != 10 {
+			t.Errorf("diff of %d lines, truncated %v, %d changed lines; want 5 lines and a note, and 10", strings.Count(f.Diff, "\n"), f.DiffTruncated, len(f.ChangedLines))
+		}
+
+		result, err = Apply(edit, Options{DryRun: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f := result.Files[0]; f.Diff != "" || f.ChangedLines != nil {
+			t.Errorf("diff without Options.Diff: %q, %v", f.Diff, f.ChangedLines)
+		}
+	})
+
+	t.Run("check", func(t *testing.T) {
+		files := fixture(t, "a.ts", "b.ts")
+		var checked []string
+		_, err := Apply(renameEdit(files...), Options{Check: func(fs []File) error {
+			for _, f := range fs {
+				checked = append(checked, string(f.Updated))
+			}
+			return errors.New("rejected")
+		}})
+		if err == nil || err.Error() != "rejected" {
+			t.Fatalf("error = %v, want the check's", err)
+		}
+		if !slices.Equal(checked, []string{renamedContent, renamedContent}) {
+			t.Errorf("checked %q, want both updates", checked)
+		}
+		if got := contents(t, files); !slices.Equal(got, []string{fixtureContent, fixtureContent}) {
+			t.Errorf("contents = %q, want nothing written", got)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		files := fixture(t, "a.ts", "b.ts")
+		var written []string
+		result, err := Apply(renameEdit(files...), Options{Write: func(fs []File) error {
+			for _, f := range fs {
+				written = append(written, f.Path)
+			}
+			return nil
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(written, files) || len(result.Files) != 2 {
+			t.Errorf("write got %v, result %+v", written, result)
+		}
+		if got := contents(t, files); !slices.Equal(got, []string{fixtureContent, fixtureContent}) {
+			t.Errorf("contents = %q, want the default write replaced", got)
+		}
+
+		_, err = Apply(renameEdit(files...), Options{Write: func([]File) error { return errors.New("journal full") }})
+		if err == nil || err.Error() != "journal full" {
+			t.Errorf("error = %v, want the write's", err)
+		}
+	})
+
+	t.Run("before write", func(t *testing.T) {
+		files := fixture(t, "a.ts", "b.ts", "c.ts")
+		var seen []string
+		_, err := Apply(renameEdit(files...), Options{BeforeWrite: func(path string) error {
+			seen = append(seen, filepath.Base(path))
+			if filepath.Base(path) == "b.ts" {
+				return errors.New("disk full")
+			}
+			return nil
+		}})
+		if err == nil || err.Error() != "disk full" {
+			t.Fatalf("error = %v, want the injected failure", err)
+		}
+		if !slices.Equal(seen, []string{"a.ts", "b.ts"}) {
+			t.Errorf("before write saw %v", seen)
+		}
+		if got := contents(t, files); !slices.Equal(got, []string{fixtureContent, fixtureContent, fixtureContent}) {
+			t.Errorf("contents = %q, want a.ts rolled back", got)
+		}
+	})
+}
+
+func TestApplyMovedFile(t *testing.T) {
+	files := fixture(t, "a.ts")
+	moved := filepath.Join(filepath.Dir(files[0]), "lib", "b.ts")
+	// The rename comes first, at index 0, and the text edit applies to
+	// the file moved.
+	edit := &lsp.WorkspaceEdit{
+		WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{{
+			TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI("file://" + moved)}},
+			Edits:        []protocol.TextEdit{textEdit(0, 10, 0, 15, "sayHello")},
+		}}},
+		Operations: []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: protocol.DocumentURI("file://" + files[0]), NewURI: protocol.DocumentURI("file://" + moved)}},
+	}
+	result, err := Apply(edit, Options{PreserveMode: true, Diff: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := contents(t, []string{files[0], moved}); !slices.Equal(got, []string{"<absent>", renamedContent}) {
+		t.Errorf("contents = %q", got)
+	}
+	if len(result.Files) != 2 {
+		t.Fatalf("files = %+v, want the old and the new path", result.Files)
+	}
+	from, to := result.Files[0], result.Files[1]
+	if !from.Deleted || from.Diff != "" {
+		t.Errorf("old path = %+v, want deleted without a diff", from)
+	}
+	// The new path is diffed against the content moved there.
+	if !to.Created || to.RenamedFrom != files[0] || !to.Rebased || string(to.Base()) != fixtureContent {
+		t.Errorf("new path = %+v", to.File)
+	}
+	if !reflect.DeepEqual(to.ChangedLines, []int{1}) || !strings.Contains(to.Diff, "-"+fixtureContent) {
+		t.Errorf("new path diff = %q, changed lines %v", to.Diff, to.ChangedLines)
+	}
+}
@@ -0,0 +1,119 @@
+package edit
+
+import (
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// eolStyle is the line ending convention of a file, kept through edits so
+// that a rename in a CRLF file does not introduce LF lines.
+type eolStyle struct {
+	// eol is the file's most common line ending, "\n", "\r\n" or "\r";
+	// empty for content without line breaks, which has no style to keep.
+	eol string
+	// finalNewline reports whether the content ends with a line ending.
+	finalNewline bool
+	// empty is set for empty content, whose final newline is up to the edit.
+	empty bool
+}
+
+// detectEOL returns the line ending style of content. Ties go to "\n".
+func detectEOL(content []byte) eolStyle {
+	var lf, crlf, cr int
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '\n':
+			lf++
+		case '\r':
+			if i+1 < len(content) && content[i+1] == '\n' {
+				crlf++
+				i++
+			} else {
+				cr++
+			}
+		}
+	}
+	s := eolStyle{empty: len(content) == 0}
+	switch {
+	case lf+crlf+cr == 0:
+	case crlf > lf && crlf >= cr:
+		s.eol = "\r\n"
+	case cr > lf && cr > crlf:
+		s.eol = "\r"
+	default:
+		s.eol = "\n"
+	}
+	if n := len(content); n > 0 {
+		s.finalNewline = content[n-1] == '\n' || content[n-1] == '\r'
+	}
+	return s
+}
+
+// normalize converts the line breaks of text to the style's line ending.
+func (s eolStyle) normalize(text string) string {
+	if s.eol == "" || !strings.ContainsAny(text, "\r\n") {
+		return text
+	}
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i++
+			}
+			sb.WriteString(s.eol)
+		case '\n':
+			sb.WriteString(s.eol)
+		default:
+			sb.WriteByte(text[i])
+		}
+	}
+	return sb.String()
+}
+
+// keepFinalNewline adds or removes the line ending at the end of edited
+// content so that it ends as the original did. Content emptied by the
+// edit is left empty.
+func (s eolStyle) keepFinalNewline(content []byte) []byte {
+	if s.empty || len(content) == 0 {
+		return content
+	}
+	has := eolLen(string(content)) > 0
+	switch {
+	case s.finalNewline && !has:
+		return append(content, s.eol...)
+	case !s.finalNewline && has:
+		return content[:len(content)-eolLen(string(content))]
+	}
+	return content
+}
+
+// eolLen returns the length of the line ending that line ends with: 2 for
+// "\r\n", 1 for "\n" or "\r", 0 for none.
+func eolLen(line string) int {
+	switch {
+	case strings.HasSuffix(line, "\r\n"):
+		return 2
+	case strings.HasSuffix(line, "\n"), strings.HasSuffix(line, "\r"):
+		return 1
+	}
+	return 0
+}
+
+// trimEOL returns line without its line ending.
+func trimEOL(line string) string {
+	return line[:len(line)-eolLen(line)]
+}
+
+// NormalizeEdits returns edits with the line breaks of their new text
+// converted as ApplyTextEdits writes them into content.
+func NormalizeEdits(content []byte, edits []protocol.TextEdit) []protocol.TextEdit {
+	style := detectEOL(content)
+	out := make([]protocol.TextEdit, len(edits))
+	for i, e := range edits {
+		out[i] = protocol.TextEdit{Range: e.Range, NewText: style.normalize(e.NewText)}
+	}
+	return out
+}
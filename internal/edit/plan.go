@@ -0,0 +1,318 @@
+package edit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// File is one file of an edit being applied.
+type File struct {
+	Path    string
+	Mode    os.FileMode
+	ModTime time.Time // when Original was read
+	// Original is the content read before the edit and Updated the
+	// content it writes.
+	Original []byte
+	Updated  []byte
+	Edits    []protocol.TextEdit
+	// Created is set for a file the edit creates; Original is then empty.
+	// Deleted is set for a file it removes, as undoing a creation does.
+	Created, Deleted bool
+	// NewDirs are the parent directories a created file needs, deepest
+	// first. They are created with the file and removed with it when
+	// empty.
+	NewDirs []string
+	// RenamedFrom is the path a created file was moved from. Rebased is
+	// set when the edits apply to Source rather than Original: the
+	// content of the moved file before the edit, or nothing for a file a
+	// create operation replaced.
+	RenamedFrom string
+	Rebased     bool
+	Source      []byte
+}
+
+// Base returns the content f's edits apply to: Source for a rebased
+// file, Original otherwise.
+func (f File) Base() []byte {
+	if f.Rebased {
+		return f.Source
+	}
+	return f.Original
+}
+
+// Paths returns the sorted paths edit reads or writes: those of its
+// text edits and both ends of its resource operations.
+func Paths(edit *lsp.WorkspaceEdit) []string {
+	seen := make(map[string]bool)
+	add := func(u protocol.DocumentURI) {
+		if u != "" {
+			seen[docsync.URIToFile(string(u))] = true
+		}
+	}
+	for u := range edit.Changes {
+		add(u)
+	}
+	for _, dc := range edit.DocumentChanges {
+		add(dc.TextDocument.URI)
+	}
+	for _, op := range edit.Operations {
+		add(op.URI)
+		add(op.NewURI)
+	}
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// plannedFile is one file while an edit is replayed in memory.
+type plannedFile struct {
+	path string
+	// What was on disk before the edit.
+	existed  bool
+	isDir    bool
+	original []byte
+	origMode os.FileMode
+	modTime  time.Time
+
+	// The file as the edit leaves it so far: pending are text edits not
+	// yet applied to content.
+	exists  bool
+	content []byte
+	mode    os.FileMode
+	pending []protocol.TextEdit
+	edits   []protocol.TextEdit
+	// created is set once a create operation made the file.
+	created bool
+	// renamedFrom is the path a rename moved the file from. rebased is set
+	// once the content no longer derives from original but from source:
+	// the content before the edit of the file moved here, or nothing
+	// after a create operation.
+	renamedFrom string
+	rebased     bool
+	source      []byte
+}
+
+// editPlanner replays the text edits and resource operations of an edit
+// in order, reading every file once, to compute the work that writes it.
+type editPlanner struct {
+	files map[string]*plannedFile
+}
+
+// Plan computes the writes of edit without touching disk.
+// Changes apply first, then DocumentChanges and Operations in their order.
+// A missing file may be edited when a create operation made it or when
+// all its edits insert at line 1, column 1. Renaming or deleting a
+// directory is refused, as is an operation of an unknown kind. The work
+// is in sorted path order; a renamed file is deleted at its old path and
+// created at the new one.
+func Plan(edit *lsp.WorkspaceEdit) ([]File, error) {
+	p := &editPlanner{files: make(map[string]*plannedFile)}
+	for u, edits := range edit.Changes {
+		if err := p.addEdits(u, edits); err != nil {
+			return nil, err
+		}
+	}
+	ops := edit.Operations
+	for i, dc := range edit.DocumentChanges {
+		for len(ops) > 0 && ops[0].Index <= i {
+			if err := p.apply(ops[0]); err != nil {
+				return nil, err
+			}
+			ops = ops[1:]
+		}
+		if err := p.addEdits(dc.TextDocument.URI, dc.Edits); err != nil {
+			return nil, err
+		}
+	}
+	for _, op := range ops {
+		if err := p.apply(op); err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make([]string, 0, len(p.files))
+	for path := range p.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var work []File
+	for _, path := range paths {
+		f := p.files[path]
+		if err := p.flush(f); err != nil {
+			return nil, err
+		}
+		w := File{Path: path, Mode: f.mode, ModTime: f.modTime, Original: f.original, Updated: f.content, Edits: f.edits, RenamedFrom: f.renamedFrom, Rebased: f.rebased, Source: f.source}
+		switch {
+		case f.existed && f.exists:
+			if len(f.edits) == 0 && f.renamedFrom == "" && !f.created {
+				continue
+			}
+		case f.existed:
+			w.Deleted, w.Mode, w.Updated = true, f.origMode, nil
+		case f.exists:
+			w.Created, w.NewDirs = true, MissingDirs(filepath.Dir(path))
+		default:
+			continue
+		}
+		work = append(work, w)
+	}
+	return work, nil
+}
+
+// file returns the planned state of path, reading it on first use.
+func (p *editPlanner) file(path string) (*plannedFile, error) {
+	if f, ok := p.files[path]; ok {
+		return f, nil
+	}
+	f := &plannedFile{path: path, mode: CreatedFileMode}
+	fi, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	case fi.IsDir():
+		f.existed, f.exists, f.isDir = true, true, true
+	default:
+		if f.original, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		f.existed, f.exists = true, true
+		f.origMode, f.mode, f.modTime = fi.Mode().Perm(), fi.Mode().Perm(), fi.ModTime()
+		f.content = f.original
+	}
+	p.files[path] = f
+	return f, nil
+}
+
+// addEdits queues text edits to the file at u.
+func (p *editPlanner) addEdits(u protocol.DocumentURI, edits []protocol.TextEdit) error {
+	f, err := p.file(docsync.URIToFile(string(u)))
+	if err != nil {
+		return err
+	}
+	if f.isDir {
+		return fmt.Errorf("%s is a directory; text edits need a file", f.path)
+	}
+	f.pending = append(f.pending, edits...)
+	return nil
+}
+
+// flush applies the queued text edits of f to its content. A missing file
+// is created by edits that start it.
+func (p *editPlanner) flush(f *plannedFile) error {
+	if len(f.pending) == 0 {
+		return nil
+	}
+	if !f.exists {
+		if !startsFile(f.pending) {
+			return fmt.Errorf("reading %s: the file does not exist, and the edits to it start at line %d rather than creating it", f.path, FirstLine(f.pending)+1)
+		}
+		f.exists, f.content = true, nil
+	}
+	updated, err := ApplyTextEdits(f.content, f.pending)
+	if err != nil {
+		return fmt.Errorf("applying edits to %s: %w", f.path, err)
+	}
+	f.content = updated
+	f.edits = append(f.edits, f.pending...)
+	f.pending = nil
+	return nil
+}
+
+// apply replays a resource operation.
+func (p *editPlanner) apply(op lsp.ResourceOperation) error {
+	f, err := p.file(docsync.URIToFile(string(op.URI)))
+	if err != nil {
+		return err
+	}
+	if err := p.flush(f); err != nil {
+		return err
+	}
+	switch op.Kind {
+	case protocol.CreateResourceOperation:
+		if f.isDir {
+			return fmt.Errorf("cannot create %s: it is a directory", f.path)
+		}
+		if f.exists && !op.Overwrite {
+			if op.IgnoreIfExists {
+				return nil
+			}
+			return fmt.Errorf("cannot create %s: it already exists", f.path)
+		}
+		f.exists, f.content, f.created = true, nil, true
+		f.edits, f.renamedFrom, f.rebased, f.source = nil, "", true, nil
+		if !f.existed {
+			f.mode = CreatedFileMode
+		}
+		return nil
+
+	case protocol.DeleteResourceOperation:
+		if f.isDir {
+			return fmt.Errorf("cannot delete %s: deleting directories is not supported", f.path)
+		}
+		if !f.exists {
+			if op.IgnoreIfNotExists {
+				return nil
+			}
+			return fmt.Errorf("cannot delete %s: it does not exist", f.path)
+		}
+		f.vacate()
+		return nil
+
+	case protocol.RenameResourceOperation:
+		to, err := p.file(docsync.URIToFile(string(op.NewURI)))
+		if err != nil {
+			return err
+		}
+		if err := p.flush(to); err != nil {
+			return err
+		}
+		switch {
+		case f.isDir || to.isDir:
+			return fmt.Errorf("cannot rename %s to %s: renaming directories is not supported", f.path, to.path)
+		case !f.exists:
+			return fmt.Errorf("cannot rename %s: it does not exist", f.path)
+		case f == to:
+			return nil
+		case to.exists && !op.Overwrite:
+			if op.IgnoreIfExists {
+				return nil
+			}
+			return fmt.Errorf("cannot rename %s to %s: the target already exists", f.path, to.path)
+		}
+		to.exists, to.content, to.mode, to.created = true, f.content, f.mode, f.created
+		to.edits = f.edits
+		to.renamedFrom, to.rebased, to.source = f.renamedFrom, true, f.source
+		if to.renamedFrom == "" {
+			to.renamedFrom = f.path
+		}
+		if !f.rebased {
+			to.source = f.original
+		}
+		if to.renamedFrom == to.path {
+			// Moved back where it started.
+			to.renamedFrom = ""
+		}
+		f.vacate()
+		return nil
+	}
+	return fmt.Errorf("unsupported %s operation on %s in workspace edit", op.Kind, op.URI)
+}
+
+// vacate leaves f missing after a delete or a rename moved it away.
+func (f *plannedFile) vacate() {
+	f.exists, f.content, f.edits, f.created = false, nil, nil, false
+	f.renamedFrom, f.rebased, f.source = "", false, nil
+}
@@ -0,0 +1,171 @@
+package edit
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.lsp.dev/protocol"
+)
+
+// FirstLine returns the smallest line number from a set of edits.
+func FirstLine(edits []protocol.TextEdit) uint32 {
+	if len(edits) == 0 {
+		return 0
+	}
+	min := edits[0].Range.Start.Line
+	for _, e := range edits[1:] {
+		if e.Range.Start.Line < min {
+			min = e.Range.Start.Line
+		}
+	}
+	return min
+}
+
+// ApplyTextEdits applies a set of TextEdits to file content. Every range
+// refers to the content before any edit, as in a WorkspaceEdit: the edits
+// are converted to byte ranges up front, must not overlap, and are written
+// in one pass. Insertions at one position keep their order and go before
+// a replacement starting there, wherever it is listed.
+//
+// The file's line endings are kept: line breaks in the new text are
+// converted to the file's dominant ending, a column never lands inside a
+// line ending, and the content keeps or lacks its final newline as before
+// unless an edit reaches the end of the content and sets it itself.
+func ApplyTextEdits(content []byte, edits []protocol.TextEdit) ([]byte, error) {
+	style := detectEOL(content)
+	lines := SplitLines(content)
+	starts := lineStarts(lines)
+
+	type span struct {
+		start, end int
+		edit       protocol.TextEdit
+	}
+	spans := make([]span, len(edits))
+	reachesEnd := false
+	for i, edit := range edits {
+		startLine := int(edit.Range.Start.Line)
+		endLine := int(edit.Range.End.Line)
+
+		// The line one past the last is where LSP puts the end of the
+		// content: an insertion after a last line without a newline, or a
+		// deletion of the last line through its newline.
+		if startLine > len(lines) || endLine > len(lines) {
+			return nil, fmt.Errorf("edit range out of bounds: start line %d, end line %d, file has %d lines", startLine, endLine, len(lines))
+		}
+
+		absStart := positionOffset(content, lines, starts, startLine, edit.Range.Start.Character)
+		absEnd := positionOffset(content, lines, starts, endLine, edit.Range.End.Character)
+		if absStart > absEnd {
+			return nil, fmt.Errorf("computed byte offsets out of range: start=%d end=%d len=%d", absStart, absEnd, len(content))
+		}
+		spans[i] = span{start: absStart, end: absEnd, edit: edit}
+		reachesEnd = reachesEnd || absEnd == len(content)
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].start == spans[i].end && spans[j].start != spans[j].end
+	})
+
+	var buf bytes.Buffer
+	buf.Grow(len(content))
+	prev := 0
+	for i, s := range spans {
+		if s.start < prev {
+			r, o := s.edit.Range, spans[i-1].edit.Range
+			return nil, fmt.Errorf("overlapping edits: %d:%d-%d:%d and %d:%d-%d:%d",
+				o.Start.Line+1, o.Start.Character+1, o.End.Line+1, o.End.Character+1,
+				r.Start.Line+1, r.Start.Character+1, r.End.Line+1, r.End.Character+1)
+		}
+		buf.Write(content[prev:s.start])
+		buf.WriteString(style.normalize(s.edit.NewText))
+		prev = s.end
+	}
+	buf.Write(content[prev:])
+
+	if reachesEnd {
+		return buf.Bytes(), nil
+	}
+	return style.keepFinalNewline(buf.Bytes()), nil
+}
+
+// positionOffset returns the byte offset in content of a 0-based line, at
+// most len(lines), and UTF-16 column; starts are the lines' offsets. A
+// column past the end of a line means the end of its text; it must not
+// split a "\r\n". The line past the last means the end of content,
+// whatever the column.
+func positionOffset(content []byte, lines []string, starts []int, line int, col uint32) int {
+	if line == len(lines) {
+		return len(content)
+	}
+	return starts[line] + ByteOffset(trimEOL(lines[line]), col)
+}
+
+// lineStarts returns the byte offset of the start of each of lines.
+func lineStarts(lines []string) []int {
+	starts := make([]int, len(lines))
+	off := 0
+	for i, l := range lines {
+		starts[i] = off
+		off += len(l)
+	}
+	return starts
+}
+
+// SplitLines splits content into lines, preserving line endings.
+// Each element includes its trailing \n, \r\n or lone \r, the line
+// endings LSP positions count, except possibly the last.
+func SplitLines(content []byte) []string {
+	if len(content) == 0 {
+		return []string{""}
+	}
+	s := string(content)
+	var lines []string
+	for {
+		idx := strings.IndexAny(s, "\r\n")
+		if idx < 0 {
+			lines = append(lines, s)
+			break
+		}
+		if s[idx] == '\r' && idx+1 < len(s) && s[idx+1] == '\n' {
+			idx++
+		}
+		lines = append(lines, s[:idx+1])
+		s = s[idx+1:]
+	}
+	return lines
+}
+
+// LineOffset returns the byte offset of the start of a given line.
+func LineOffset(lines []string, line int) int {
+	off := 0
+	for i := 0; i < line && i < len(lines); i++ {
+		off += len(lines[i])
+	}
+	return off
+}
+
+// ByteOffset converts a UTF-16 column offset to a byte offset within
+// a line string. LSP positions use UTF-16 code units.
+func ByteOffset(line string, utf16Col uint32) int {
+	utf16Count := uint32(0)
+	byteOff := 0
+	for byteOff < len(line) {
+		if utf16Count >= utf16Col {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(line[byteOff:])
+		if r <= 0xFFFF {
+			utf16Count++
+		} else {
+			// Supplementary character: 2 UTF-16 code units.
+			utf16Count += 2
+		}
+		byteOff += size
+	}
+	return byteOff
+}
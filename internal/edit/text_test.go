@@ -0,0 +1,517 @@
+package edit
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"go.lsp.dev/protocol"
+)
+
+func textEdit(startLine, startChar, endLine, endChar uint32, text string) protocol.TextEdit {
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startChar},
+			End:   protocol.Position{Line: endLine, Character: endChar},
+		},
+		NewText: text,
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		utf16Col uint32
+		want     int
+	}{
+		// ASCII: each character is 1 byte and 1 UTF-16 unit.
+		{name: "ascii col=0", line: "hello", utf16Col: 0, want: 0},
+		{name: "ascii col=5", line: "hello", utf16Col: 5, want: 5},
+
+		// 2-byte UTF-8 character (e-acute U+00E9): 1 UTF-16 code unit.
+		{name: "2byte after h", line: "h\u00e9llo", utf16Col: 1, want: 1},
+		{name: "2byte after e-acute", line: "h\u00e9llo", utf16Col: 2, want: 3},
+
+		// 3-byte UTF-8 character (CJK U+4E2D): 1 UTF-16 code unit.
+		{name: "cjk col=1", line: "\u4e2d\u6587", utf16Col: 1, want: 3},
+
+		// 4-byte UTF-8 character (emoji U+1F600): 2 UTF-16 code units (surrogate pair).
+		{name: "emoji after a", line: "a\U0001F600b", utf16Col: 1, want: 1},
+		{name: "emoji after emoji", line: "a\U0001F600b", utf16Col: 3, want: 5},
+		{name: "emoji at b", line: "a\U0001F600b", utf16Col: 4, want: 6},
+
+		// col=0 for any string.
+		{name: "col=0 empty", line: "", utf16Col: 0, want: 0},
+		{name: "col=0 nonempty", line: "abc", utf16Col: 0, want: 0},
+
+		// col beyond end returns len(line).
+		{name: "beyond end ascii", line: "abc", utf16Col: 100, want: 3},
+		{name: "beyond end unicode", line: "\u4e2d", utf16Col: 100, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ByteOffset(tt.line, tt.utf16Col)
+			if got != tt.want {
+				t.Errorf("ByteOffset(%q, %d) = %d, want %d", tt.line, tt.utf16Col, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTextEdits(t *testing.T) {
+	t.Run("single edit replacing greet with sayHello", func(t *testing.T) {
+		content := []byte("export function greet(name: string): string {\n  return \"Hello\";\n}\n")
+		edits := []protocol.TextEdit{
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 0, Character: 16},
+					End:   protocol.Position{Line: 0, Character: 21},
+				},
+				NewText: "sayHello",
+			},
+		}
+		got, err := ApplyTextEdits(content, edits)
+		if err != nil {
+			t.Fatalf("ApplyTextEdits: %v", err)
+		}
+		want := "export function sayHello(name: string): string {\n  return \"Hello\";\n}\n"
+		if string(got) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
+		}
+	})
+
+	t.Run("multiple edits on different lines", func(t *testing.T) {
+		content := []byte("const a = greet;\nconst b = greet;\n")
+		edits := []protocol.TextEdit{
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 0, Character: 10},
+					End:   protocol.Position{Line: 0, Character: 15},
+				},
+				NewText: "sayHello",
+			},
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 1, Character: 10},
+					End:   protocol.Position{Line: 1, Character: 15},
+				},
+				NewText: "sayHello",
+			},
+		}
+		got, err := ApplyTextEdits(content, edits)
+		if err != nil {
+			t.Fatalf("ApplyTextEdits: %v", err)
+		}
+		want := "const a = sayHello;\nconst b = sayHello;\n"
+		if string(got) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
+		}
+	})
+
+	t.Run("multiple edits on the same line", func(t *testing.T) {
+		// "import { greet, greet2 } from './index';\n"
+		content := []byte("import { greet, greet2 } from './index';\n")
+		edits := []protocol.TextEdit{
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 0, Character: 9},
+					End:   protocol.Position{Line: 0, Character: 14},
+				},
+				NewText: "sayHello",
+			},
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 0, Character: 16},
+					End:   protocol.Position{Line: 0, Character: 22},
+				},
+				NewText: "sayHello2",
+			},
+		}
+		got, err := ApplyTextEdits(content, edits)
+		if err != nil {
+			t.Fatalf("ApplyTextEdits: %v", err)
+		}
+		want := "import { sayHello, sayHello2 } from './index';\n"
+		if string(got) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
+		}
+	})
+
+	t.Run("edit replacing multiple characters on same line", func(t *testing.T) {
+		content := []byte("function longFunctionName() {}\n")
+		edits := []protocol.TextEdit{
+			{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 0, Character: 9},
+					End:   protocol.Position{Line: 0, Character: 25},
+				},
+				NewText: "fn",
+			},
+		}
+		got, err := ApplyTextEdits(content, edits)
+		if err != nil {
+			t.Fatalf("ApplyTextEdits: %v", err)
+		}
+		want := "function fn() {}\n"
+		if string(got) != want {
+			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
+		}
+	})
+}
+
+func TestApplyTextEditsLineEndings(t *testing.T) {
+	edit := func(startLine, startChar, endLine, endChar uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: startLine, Character: startChar},
+				End:   protocol.Position{Line: endLine, Character: endChar},
+			},
+			NewText: text,
+		}
+	}
+	tests := []struct {
+		name    string
+		content string
+		edits   []protocol.TextEdit
+		want    string
+	}{
+		{
+			name:    "crlf rename",
+			content: "const a = greet;\r\nconst b = greet;\r\n",
+			edits:   []protocol.TextEdit{edit(0, 10, 0, 15, "sayHello"), edit(1, 10, 1, 15, "sayHello")},
+			want:    "const a = sayHello;\r\nconst b = sayHello;\r\n",
+		},
+		{
+			name:    "crlf column past the end of the line",
+			content: "const a = 1\r\nconst b = 2\r\n",
+			edits:   []protocol.TextEdit{edit(0, 12, 0, 12, ";")},
+			want:    "const a = 1;\r\nconst b = 2\r\n",
+		},
+		{
+			name:    "crlf insertion with lf lines",
+			content: "import { a } from './a';\r\nuse(a);\r\n",
+			edits:   []protocol.TextEdit{edit(1, 0, 1, 0, "import { b } from './b';\n\n")},
+			want:    "import { a } from './a';\r\nimport { b } from './b';\r\n\r\nuse(a);\r\n",
+		},
+		{
+			name:    "crlf edit removing the final newline",
+			content: "const a = 1;\r\nconst b = 2;\r\n",
+			edits:   []protocol.TextEdit{edit(1, 0, 2, 0, "const c = 3;")},
+			want:    "const a = 1;\r\nconst c = 3;",
+		},
+		{
+			name:    "final newline kept by an edit short of the end",
+			content: "const a = 1;\r\nconst b = 2;\r\n",
+			edits:   []protocol.TextEdit{edit(1, 0, 1, 12, "const c = 3;")},
+			want:    "const a = 1;\r\nconst c = 3;\r\n",
+		},
+		{
+			name:    "cr only",
+			content: "const a = greet;\rconst b = greet;\r",
+			edits:   []protocol.TextEdit{edit(1, 10, 1, 15, "sayHello"), edit(1, 16, 1, 16, "\nconst c = 3;")},
+			want:    "const a = greet;\rconst b = sayHello;\rconst c = 3;\r",
+		},
+		{
+			name:    "no trailing newline",
+			content: "const a = greet;\nconst b = greet;",
+			edits:   []protocol.TextEdit{edit(1, 10, 1, 15, "sayHello")},
+			want:    "const a = greet;\nconst b = sayHello;",
+		},
+		{
+			name:    "trailing newline appended by an edit",
+			content: "const a = greet;\nconst b = greet;",
+			edits:   []protocol.TextEdit{edit(1, 16, 1, 16, "\n")},
+			want:    "const a = greet;\nconst b = greet;\n",
+		},
+		{
+			name:    "mixed endings follow the dominant one",
+			content: "a\r\nb\r\nc\n",
+			edits:   []protocol.TextEdit{edit(0, 1, 0, 1, "\nx")},
+			want:    "a\r\nx\r\nb\r\nc\n",
+		},
+		{
+			name:    "empty file gets the edit as written",
+			content: "",
+			edits:   []protocol.TextEdit{edit(0, 0, 0, 0, "export {};\n")},
+			want:    "export {};\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyTextEdits([]byte(tt.content), tt.edits)
+			if err != nil {
+				t.Fatalf("ApplyTextEdits: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if before, after := detectEOL([]byte(tt.content)), detectEOL(got); tt.content != "" && before.eol != after.eol {
+				t.Errorf("line ending %q after the edit, want %q", after.eol, before.eol)
+			}
+		})
+	}
+}
+
+func TestApplyTextEditsReverseOrder(t *testing.T) {
+	// Create edits in FORWARD order (line 0 before line 2).
+	// The function must internally sort to reverse order.
+	content := []byte("const a = greet;\nconst b = other;\nconst c = greet;\n")
+	edits := []protocol.TextEdit{
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 0, Character: 10},
+				End:   protocol.Position{Line: 0, Character: 15},
+			},
+			NewText: "sayHello",
+		},
+		{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 2, Character: 10},
+				End:   protocol.Position{Line: 2, Character: 15},
+			},
+			NewText: "sayHello",
+		},
+	}
+
+	got, err := ApplyTextEdits(content, edits)
+	if err != nil {
+		t.Fatalf("ApplyTextEdits: %v", err)
+	}
+	want := "const a = sayHello;\nconst b = other;\nconst c = sayHello;\n"
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
+	}
+}
+
+func TestApplyTextEditsBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edits   []protocol.TextEdit
+		want    string
+	}{
+		{
+			name:    "insertion at the end without a final newline",
+			content: "import { a } from './a';",
+			edits:   []protocol.TextEdit{textEdit(1, 0, 1, 0, "\nimport { b } from './b';")},
+			want:    "import { a } from './a';\nimport { b } from './b';",
+		},
+		{
+			name:    "insertion on the line past the last",
+			content: "const a = 1;\n",
+			edits:   []protocol.TextEdit{textEdit(2, 0, 2, 0, "const b = 2;\n")},
+			want:    "const a = 1;\nconst b = 2;\n",
+		},
+		{
+			name:    "deletion of a whole line with its newline",
+			content: "const a = 1;\nconst b = 2;\nconst c = 3;\n",
+			edits:   []protocol.TextEdit{textEdit(1, 0, 2, 0, "")},
+			want:    "const a = 1;\nconst c = 3;\n",
+		},
+		{
+			name:    "deletion of the last line through the end",
+			content: "const a = 1;\nconst b = 2;",
+			edits:   []protocol.TextEdit{textEdit(1, 0, 2, 0, "")},
+			want:    "const a = 1;\n",
+		},
+		{
+			name:    "edit from mid-line to mid-line three lines down",
+			content: "line0\nline1\nline2\nline3\nline4\n",
+			edits:   []protocol.TextEdit{textEdit(1, 2, 4, 3, "X")},
+			want:    "line0\nliXe4\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyTextEdits([]byte(tt.content), tt.edits)
+			if err != nil {
+				t.Fatalf("ApplyTextEdits: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("line beyond the one past the last", func(t *testing.T) {
+		_, err := ApplyTextEdits([]byte("const a = 1;"), []protocol.TextEdit{textEdit(2, 0, 2, 0, "x")})
+		if err == nil || !strings.Contains(err.Error(), "out of bounds") {
+			t.Errorf("error = %v, want out of bounds", err)
+		}
+	})
+}
+
+func TestApplyTextEditsOverlap(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []protocol.TextEdit
+	}{
+		{name: "ranges cross", edits: []protocol.TextEdit{textEdit(0, 0, 0, 4, "x"), textEdit(0, 2, 0, 6, "y")}},
+		{name: "range inside another", edits: []protocol.TextEdit{textEdit(0, 0, 1, 2, "x"), textEdit(0, 3, 0, 4, "y")}},
+		{name: "replacements at one start", edits: []protocol.TextEdit{textEdit(0, 0, 0, 2, "x"), textEdit(0, 0, 0, 1, "y")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ApplyTextEdits([]byte("const a = 1;\nconst b = 2;\n"), tt.edits)
+			if err == nil || !strings.Contains(err.Error(), "overlapping edits") {
+				t.Errorf("error = %v, want overlapping edits", err)
+			}
+		})
+	}
+
+	t.Run("insertions at one position then a replacement", func(t *testing.T) {
+		got, err := ApplyTextEdits([]byte("ab\n"), []protocol.TextEdit{textEdit(0, 0, 0, 0, "1"), textEdit(0, 0, 0, 0, "2"), textEdit(0, 0, 0, 1, "3")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "123b\n" {
+			t.Errorf("got %q, want %q", got, "123b\n")
+		}
+	})
+}
+
+// applyTextEditsSequential is the previous ApplyTextEdits, which applied
+// the edits one at a time from the last and split the content into lines
+// again after each. It is kept to check the one-pass version against.
+func applyTextEditsSequential(content []byte, edits []protocol.TextEdit) ([]byte, error) {
+	style := detectEOL(content)
+	sorted := make([]protocol.TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Range.Start.Line != sorted[j].Range.Start.Line {
+			return sorted[i].Range.Start.Line > sorted[j].Range.Start.Line
+		}
+		return sorted[i].Range.Start.Character > sorted[j].Range.Start.Character
+	})
+	lines := SplitLines(content)
+	reachesEnd := false
+	for _, e := range sorted {
+		startLine, endLine := int(e.Range.Start.Line), int(e.Range.End.Line)
+		if startLine > len(lines) || endLine > len(lines) {
+			return nil, fmt.Errorf("edit range out of bounds: start line %d, end line %d, file has %d lines", startLine, endLine, len(lines))
+		}
+		offset := func(line int, col uint32) int {
+			if line == len(lines) {
+				return len(content)
+			}
+			return LineOffset(lines, line) + ByteOffset(trimEOL(lines[line]), col)
+		}
+		absStart, absEnd := offset(startLine, e.Range.Start.Character), offset(endLine, e.Range.End.Character)
+		if absStart > absEnd {
+			return nil, fmt.Errorf("computed byte offsets out of range: start=%d end=%d len=%d", absStart, absEnd, len(content))
+		}
+		// Edits are applied from the last, so only the first can reach
+		// the end of the original content.
+		reachesEnd = reachesEnd || absEnd == len(content)
+		var buf []byte
+		buf = append(buf, content[:absStart]...)
+		buf = append(buf, style.normalize(e.NewText)...)
+		buf = append(buf, content[absEnd:]...)
+		content = buf
+		lines = SplitLines(content)
+	}
+	if reachesEnd {
+		return content, nil
+	}
+	return style.keepFinalNewline(content), nil
+}
+
+// randomEdits returns a random file and a shuffled set of edits to it that
+// do not overlap and start at distinct positions, the edits on which the
+// order the sequential version applied them in is well defined.
+func randomEdits(rng *rand.Rand) (string, []protocol.TextEdit) {
+	pieces := []string{"a", "bc", " ", "x1", "\u4e2d", "\U0001F600", "\t"}
+	texts := []string{"", "z", "y\n", "\U0001F600", "w\nv", "\r\n"}
+	eol := []string{"\n", "\r\n"}[rng.Intn(2)]
+
+	var sb strings.Builder
+	// positions are the valid positions in the file, in order.
+	var positions []protocol.Position
+	nLines := 1 + rng.Intn(20)
+	for line := 0; line < nLines; line++ {
+		col := 0
+		positions = append(positions, protocol.Position{Line: uint32(line)})
+		for n := rng.Intn(8); n > 0; n-- {
+			piece := pieces[rng.Intn(len(pieces))]
+			sb.WriteString(piece)
+			col += len(utf16.Encode([]rune(piece)))
+			positions = append(positions, protocol.Position{Line: uint32(line), Character: uint32(col)})
+		}
+		if line < nLines-1 || rng.Intn(2) == 0 {
+			sb.WriteString(eol)
+		}
+	}
+
+	// Pick an even number of positions, in order, and pair them up.
+	var picked []protocol.Position
+	for _, p := range positions {
+		if rng.Intn(3) == 0 {
+			picked = append(picked, p)
+		}
+	}
+	if len(picked)%2 == 1 {
+		picked = picked[:len(picked)-1]
+	}
+	var edits []protocol.TextEdit
+	for i := 0; i < len(picked); i += 2 {
+		if len(edits) > 0 && edits[len(edits)-1].Range.Start == picked[i] {
+			continue
+		}
+		edits = append(edits, protocol.TextEdit{
+			Range:   protocol.Range{Start: picked[i], End: picked[i+1]},
+			NewText: texts[rng.Intn(len(texts))],
+		})
+	}
+	rng.Shuffle(len(edits), func(i, j int) { edits[i], edits[j] = edits[j], edits[i] })
+	return sb.String(), edits
+}
+
+func TestApplyTextEditsMatchesSequential(t *testing.T) {
+	// A replacement listed before an insertion at its start.
+	edits := []protocol.TextEdit{textEdit(0, 0, 0, 1, "3"), textEdit(0, 0, 0, 0, "1")}
+	want, wantErr := applyTextEditsSequential([]byte("ab\n"), edits)
+	got, err := ApplyTextEdits([]byte("ab\n"), edits)
+	if err != nil || wantErr != nil || string(got) != string(want) {
+		t.Errorf("replacement then insertion: got %q, %v; want %q, %v", got, err, want, wantErr)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		content, edits := randomEdits(rng)
+		want, wantErr := applyTextEditsSequential([]byte(content), edits)
+		got, err := ApplyTextEdits([]byte(content), edits)
+		if (err != nil) != (wantErr != nil) || string(got) != string(want) {
+			t.Fatalf("case %d: content %q, edits %+v:\ngot  %q, %v\nwant %q, %v", i, content, edits, got, err, want, wantErr)
+		}
+	}
+}
+
+// BenchmarkApplyTextEdits compares the sequential version with the
+// one-pass one on a rename touching every 40th line of a 20k-line file.
+func BenchmarkApplyTextEdits(b *testing.B) {
+	content := []byte(strings.Repeat("export const filler = greet('xxxxxxxxxxxxxxxxxxxx');\n", 20000))
+	var edits []protocol.TextEdit
+	for line := uint32(0); line < 20000; line += 40 {
+		edits = append(edits, textEdit(line, 22, line, 27, "sayHello"))
+	}
+
+	b.Run("sequential", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := applyTextEditsSequential(content, edits); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("one-pass", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := ApplyTextEdits(content, edits); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package edit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// locks serializes edits that touch the same files. An edit holds the
+// locks of all its files from reading the originals until the last write.
+var locks = &fileLockSet{locks: make(map[string]*sync.Mutex)}
+
+// Lock locks paths against other edits and returns the function unlocking
+// them. Apply holds the locks of an edit's files from planning it until
+// the last write; code writing Files it computed itself, such as an undo,
+// takes them first.
+func Lock(paths []string) (unlock func()) {
+	return locks.lock(paths)
+}
+
+// fileLockSet is a set of per-file mutexes.
+type fileLockSet struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks every path, in sorted order so that two edits cannot deadlock,
+// and returns the function unlocking them.
+func (s *fileLockSet) lock(paths []string) func() {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+	held := make([]*sync.Mutex, 0, len(sorted))
+	for _, p := range sorted {
+		s.mu.Lock()
+		m, ok := s.locks[p]
+		if !ok {
+			m = &sync.Mutex{}
+			s.locks[p] = m
+		}
+		s.mu.Unlock()
+		m.Lock()
+		held = append(held, m)
+	}
+	return func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i].Unlock()
+		}
+	}
+}
+
+// ConcurrentModificationError reports a file that changed on disk between
+// reading it and writing the edit, e.g. through the agent's own write tool.
+type ConcurrentModificationError struct {
+	File string
+	// Kept lists the files already written that were not rolled back
+	// because they changed again after the write.
+	Kept []string
+}
+
+func (e *ConcurrentModificationError) Error() string {
+	msg := fmt.Sprintf("ERR_CONCURRENT_MODIFICATION: %s changed on disk during the edit; files already written were restored", e.File)
+	if len(e.Kept) > 0 {
+		msg += fmt.Sprintf(" except %s, which changed again since", strings.Join(e.Kept, ", "))
+	}
+	return msg
+}
+
+// Write writes files in order as opts say, and rolls back the files
+// already written when one fails (see Rollback).
+func Write(files []File, opts Options) error {
+	var written []File
+	for _, f := range files {
+		if err := WriteFile(f, opts); err != nil {
+			return Rollback(err, written, opts)
+		}
+		written = append(written, f)
+	}
+	return nil
+}
+
+// WriteFile writes f's updated content after checking that the file
+// still holds what was read at the start of the edit, by modification
+// time and content. With opts.Atomic the file is replaced atomically, so
+// a failure or crash leaves either the original or the update. A created
+// file must still not exist; a deleted file is removed.
+func WriteFile(f File, opts Options) error {
+	if opts.BeforeWrite != nil {
+		if err := opts.BeforeWrite(f.Path); err != nil {
+			return err
+		}
+	}
+	if f.Created {
+		return writeCreated(f, opts.mode(f))
+	}
+	fi, err := os.Stat(f.Path)
+	if err != nil || !fi.ModTime().Equal(f.ModTime) {
+		return &ConcurrentModificationError{File: f.Path}
+	}
+	if current, err := os.ReadFile(f.Path); err != nil || !bytes.Equal(current, f.Original) {
+		return &ConcurrentModificationError{File: f.Path}
+	}
+	if f.Deleted {
+		if err := os.Remove(f.Path); err != nil {
+			return fmt.Errorf("removing %s: %w", f.Path, err)
+		}
+		RemoveEmptyDirs(f.NewDirs)
+		return nil
+	}
+	if err := opts.writeContent(f.Path, f.Updated, opts.mode(f)); err != nil {
+		return fmt.Errorf("writing %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// mode returns the mode f is written with: its own with o.PreserveMode,
+// CreatedFileMode otherwise. Rollback restores f.Mode either way.
+func (o Options) mode(f File) os.FileMode {
+	if o.PreserveMode {
+		return f.Mode
+	}
+	return CreatedFileMode
+}
+
+// writeContent replaces the content of path, atomically when o.Atomic is
+// set and in place otherwise. Either way the file ends up with mode.
+func (o Options) writeContent(path string, data []byte, mode os.FileMode) error {
+	if o.Atomic {
+		return WriteFileAtomic(path, data, mode)
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	// WriteFile leaves the mode of an existing file as it is.
+	return os.Chmod(path, mode)
+}
+
+// writeCreated creates f's file with mode and its missing parent
+// directories, failing with ERR_CONCURRENT_MODIFICATION when the file
+// appeared since the edit was computed.
+func writeCreated(f File, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		RemoveEmptyDirs(f.NewDirs)
+		return fmt.Errorf("creating %s: %w", filepath.Dir(f.Path), err)
+	}
+	file, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if errors.Is(err, os.ErrExist) {
+		return &ConcurrentModificationError{File: f.Path}
+	}
+	if err == nil {
+		_, err = file.Write(f.Updated)
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(f.Path)
+		}
+	}
+	if err != nil {
+		RemoveEmptyDirs(f.NewDirs)
+		return fmt.Errorf("creating %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// rollbackWritten restores the original content of the files an edit
+// wrote: created files are removed with the directories made for them,
+// deleted ones are restored. A file whose content is no longer what the
+// edit wrote was changed by someone else after the write; it is kept as it
+// is and returned.
+func rollbackWritten(written []File, opts Options) (kept []string) {
+	for _, f := range written {
+		if f.Deleted {
+			if _, err := os.Lstat(f.Path); !errors.Is(err, os.ErrNotExist) {
+				kept = append(kept, f.Path)
+				continue
+			}
+			_ = os.MkdirAll(filepath.Dir(f.Path), 0o755)
+			_ = opts.writeContent(f.Path, f.Original, f.Mode)
+			continue
+		}
+		current, err := os.ReadFile(f.Path)
+		if err != nil || !bytes.Equal(current, f.Updated) {
+			kept = append(kept, f.Path)
+			continue
+		}
+		if f.Created {
+			_ = os.Remove(f.Path)
+			RemoveEmptyDirs(f.NewDirs)
+			continue
+		}
+		_ = opts.writeContent(f.Path, f.Original, f.Mode)
+	}
+	return kept
+}
+
+// Rollback rolls back written after err stopped an edit and returns the
+// error to report. A *ConcurrentModificationError gets the files left
+// alone because they changed again since they were written.
+func Rollback(err error, written []File, opts Options) error {
+	kept := rollbackWritten(written, opts)
+	if cm, ok := err.(*ConcurrentModificationError); ok {
+		cm.Kept = kept
+		return cm
+	}
+	if len(kept) > 0 {
+		return fmt.Errorf("%w; not rolled back because they changed again since: %s", err, strings.Join(kept, ", "))
+	}
+	return err
+}
@@ -0,0 +1,30 @@
+package edit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileLockSet(t *testing.T) {
+	s := &fileLockSet{locks: make(map[string]*sync.Mutex)}
+	unlock := s.lock([]string{"/b", "/a"})
+	acquired := make(chan struct{})
+	go func() {
+		defer s.lock([]string{"/a"})()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second lock acquired while the first was held")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second lock not acquired after unlock")
+	}
+	// Disjoint files do not wait.
+	s.lock([]string{"/c"})()
+}
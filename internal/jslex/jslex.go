@@ -0,0 +1,143 @@
+// Package jslex tells the code of TypeScript and JavaScript source apart
+// from its strings, template literal text and comments, for the scanners
+// that look for brackets, terminators or import specifiers without a
+// parser. They share it so that escapes, template substitutions and
+// comment markers inside strings are handled the same way everywhere.
+//
+// Regular expression literals are not recognized: whether a "/" starts
+// one depends on the token before it, which callers that care about
+// them track themselves.
+package jslex
+
+import "strings"
+
+// Kind is what a span of source is.
+type Kind int
+
+const (
+	// Code is one byte outside strings, template literal text and
+	// comments.
+	Code Kind = iota
+	// String is a quoted string, quotes included. It ends with its line
+	// when it has no closing quote, as it must in valid code.
+	String
+	// Template is template literal text: from a backquote, or the "}"
+	// closing a substitution, through the closing backquote or the "${"
+	// opening a substitution.
+	Template
+	// Comment is a line comment, up to its line break, or a block comment.
+	Comment
+)
+
+// Scanner splits source into spans. Block comments and template literals
+// carry over from one call of Next to the next, so source may be scanned
+// whole or a line at a time. The zero Scanner starts in code.
+type Scanner struct {
+	comment  bool // inside a block comment
+	template bool // inside template literal text
+	// substs holds the braces open in each enclosing template literal
+	// substitution, innermost last.
+	substs []int
+}
+
+// Next returns the kind of the span of src starting at i and the index
+// just past it. A Code span is the single byte src[i]; callers skipping
+// code bytes themselves, such as a regular expression literal, must not
+// skip braces.
+func (s *Scanner) Next(src string, i int) (Kind, int) {
+	switch {
+	case s.comment:
+		return Comment, s.blockComment(src, i)
+	case s.template:
+		return Template, s.templateText(src, i)
+	}
+	c := src[i]
+	switch {
+	case c == '/' && i+1 < len(src) && src[i+1] == '/':
+		if nl := strings.IndexByte(src[i:], '\n'); nl >= 0 {
+			return Comment, i + nl
+		}
+		return Comment, len(src)
+	case c == '/' && i+1 < len(src) && src[i+1] == '*':
+		s.comment = true
+		return Comment, s.blockComment(src, i+2)
+	case c == '"' || c == '\'':
+		return String, quoted(src, i, c)
+	case c == '`':
+		s.template = true
+		return Template, s.templateText(src, i+1)
+	case c == '{' && len(s.substs) > 0:
+		s.substs[len(s.substs)-1]++
+	case c == '}' && len(s.substs) > 0:
+		top := len(s.substs) - 1
+		if s.substs[top] > 0 {
+			s.substs[top]--
+			break
+		}
+		s.substs = s.substs[:top]
+		s.template = true
+		return Template, s.templateText(src, i+1)
+	}
+	return Code, i + 1
+}
+
+// Open returns what is still open at the end of the source scanned so
+// far: Comment for a block comment, Template for a template literal,
+// including one whose substitution is open, or Code.
+func (s *Scanner) Open() Kind {
+	switch {
+	case s.comment:
+		return Comment
+	case s.template || len(s.substs) > 0:
+		return Template
+	}
+	return Code
+}
+
+// blockComment returns the index past the "*/" at or after i, or len(src)
+// when the comment goes on.
+func (s *Scanner) blockComment(src string, i int) int {
+	end := strings.Index(src[i:], "*/")
+	if end < 0 {
+		return len(src)
+	}
+	s.comment = false
+	return i + end + 2
+}
+
+// templateText returns the index past the backquote or "${" ending the
+// template text at i, or len(src) when the text goes on.
+func (s *Scanner) templateText(src string, i int) int {
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '`':
+			s.template = false
+			return i + 1
+		case '$':
+			if i+1 < len(src) && src[i+1] == '{' {
+				s.template = false
+				s.substs = append(s.substs, 0)
+				return i + 2
+			}
+		}
+	}
+	return len(src)
+}
+
+// quoted returns the index past the quote closing the string at i, or of
+// the line break or end of src ending it without one.
+func quoted(src string, i int, quote byte) int {
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '\n':
+			return i
+		case quote:
+			return i + 1
+		}
+	}
+	return len(src)
+}
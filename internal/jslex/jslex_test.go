@@ -0,0 +1,99 @@
+package jslex
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// spans scans lines with one Scanner and returns each non-code span as
+// kind:text, with runs of code joined.
+func spans(lines ...string) ([]string, Kind) {
+	names := map[Kind]string{Code: "code", String: "string", Template: "template", Comment: "comment"}
+	var s Scanner
+	var out []string
+	for _, line := range lines {
+		code := ""
+		for i := 0; i < len(line); {
+			kind, end := s.Next(line, i)
+			if kind == Code {
+				code += line[i:end]
+			} else {
+				if code != "" {
+					out = append(out, "code:"+code)
+					code = ""
+				}
+				out = append(out, names[kind]+":"+line[i:end])
+			}
+			i = end
+		}
+		if code != "" {
+			out = append(out, "code:"+code)
+		}
+	}
+	return out, s.Open()
+}
+
+func TestScanner(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		want     []string
+		wantOpen Kind
+	}{
+		{
+			name:  "strings with escapes",
+			lines: []string{`f("a\"(", 'b\'')`},
+			want:  []string{"code:f(", `string:"a\"("`, "code:, ", `string:'b\''`, "code:)"},
+		},
+		{
+			name:  "comment markers in strings",
+			lines: []string{`import "./a//b"; // c`},
+			want:  []string{"code:import ", `string:"./a//b"`, "code:; ", "comment:// c"},
+		},
+		{
+			name:  "unclosed string ends with its line",
+			lines: []string{"<p>Don't {x}</p>\nf()"},
+			want:  []string{"code:<p>Don", "string:'t {x}</p>", "code:\nf()"},
+		},
+		{
+			name:  "block comment across lines",
+			lines: []string{"a /* (", " ) */ b"},
+			want:  []string{"code:a ", "comment:/* (", "comment: ) */", "code: b"},
+		},
+		{
+			name:  "template substitution with braces and a nested template",
+			lines: []string{"`{ ${f({ a: `(${x}`})} }`;"},
+			want:  []string{"template:`{ ${", "code:f({ a: ", "template:`(${", "code:x", "template:}`", "code:})", "template:} }`", "code:;"},
+		},
+		{
+			name:     "template across lines",
+			lines:    []string{"const t = `", "  ) ]"},
+			want:     []string{"code:const t = ", "template:`", "template:  ) ]"},
+			wantOpen: Template,
+		},
+		{
+			name:     "open substitution",
+			lines:    []string{"`${a"},
+			want:     []string{"template:`${", "code:a"},
+			wantOpen: Template,
+		},
+		{
+			name:     "open block comment",
+			lines:    []string{"/* x"},
+			want:     []string{"comment:/* x"},
+			wantOpen: Comment,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, open := spans(tt.lines...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("spans =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(tt.want, "\n  "))
+			}
+			if open != tt.wantOpen {
+				t.Errorf("Open = %d, want %d", open, tt.wantOpen)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// EditApplier applies a workspace edit tsgo sent with workspace/applyEdit,
+// such as one a command makes, and returns why it was not applied. label
+// is the edit's optional label.
+type EditApplier func(ctx context.Context, label string, edit *WorkspaceEdit) error
+
+// SetEditApplier sets the function applying the edits tsgo sends. Until it
+// is set, they are refused.
+func (c *Client) SetEditApplier(fn EditApplier) {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	c.editApplier = fn
+}
+
+// applyEditHandler answers workspace/applyEdit ahead of next.
+// protocol.Client's ApplyEdit sees the edit without its resource
+// operations and can only answer true or false; here the edit is decoded
+// as decodeWorkspaceEdit does and a refusal carries its reason. The edit
+// is applied on its own goroutine, so that tsgo's other messages, such as
+// the diagnostics a tool call holding the edit's files waits for, keep
+// flowing meanwhile.
+func (c *Client) applyEditHandler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		if req.Method() != protocol.MethodWorkspaceApplyEdit {
+			return next(ctx, reply, req)
+		}
+		var params struct {
+			Label string          `json:"label"`
+			Edit  json.RawMessage `json:"edit"`
+		}
+		if err := json.Unmarshal(req.Params(), &params); err != nil {
+			return reply(ctx, nil, jsonrpc2.Errorf(jsonrpc2.ParseError, "decoding %s: %v", protocol.MethodWorkspaceApplyEdit, err))
+		}
+		go func() {
+			_ = reply(ctx, c.applyEdit(ctx, params.Label, params.Edit), nil)
+		}()
+		return nil
+	}
+}
+
+// applyEdit applies raw, an edit tsgo sent, with the EditApplier and
+// returns the response to send back.
+func (c *Client) applyEdit(ctx context.Context, label string, raw json.RawMessage) protocol.ApplyWorkspaceEditResponse {
+	c.startMu.Lock()
+	apply := c.editApplier
+	c.startMu.Unlock()
+
+	edit, err := decodeWorkspaceEdit(raw)
+	switch {
+	case err != nil:
+	case edit == nil:
+		err = errors.New("the request has no edit")
+	case apply == nil:
+		err = errors.New("edits from the server are not applied")
+	default:
+		err = apply(ctx, label, edit)
+	}
+	if err != nil {
+		slog.Warn("refused a workspace edit from tsgo", "label", label, "error", err)
+		return protocol.ApplyWorkspaceEditResponse{FailureReason: err.Error()}
+	}
+	return protocol.ApplyWorkspaceEditResponse{Applied: true}
+}
@@ -0,0 +1,132 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// pushApplyEdit sends workspace/applyEdit with params from the fake tsgo
+// and returns the client's response.
+func pushApplyEdit(t *testing.T, fake *fakeTsgo, params any) protocol.ApplyWorkspaceEditResponse {
+	t.Helper()
+	fake.mu.Lock()
+	conn := fake.conn
+	fake.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var resp protocol.ApplyWorkspaceEditResponse
+	if _, err := conn.Call(ctx, protocol.MethodWorkspaceApplyEdit, params, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestApplyEditRequest(t *testing.T) {
+	fake := &fakeTsgo{}
+	c := StartClient(context.Background(), "file:///repo", fake.start)
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]any{
+		"label": "Move to a new file",
+		"edit": map[string]any{"documentChanges": []any{
+			map[string]any{
+				"textDocument": map[string]any{"uri": "file:///repo/a.ts", "version": 3},
+				"edits":        []any{map[string]any{"range": map[string]any{"start": map[string]any{"line": 0, "character": 0}, "end": map[string]any{"line": 0, "character": 3}}, "newText": "let"}},
+			},
+			map[string]any{"kind": "rename", "oldUri": "file:///repo/a.ts", "newUri": "file:///repo/b.ts"},
+		}},
+	}
+
+	t.Run("without an applier", func(t *testing.T) {
+		resp := pushApplyEdit(t, fake, params)
+		if resp.Applied || resp.FailureReason != "edits from the server are not applied" {
+			t.Errorf("response = %+v, want a refusal", resp)
+		}
+	})
+
+	t.Run("applied", func(t *testing.T) {
+		var label string
+		var got *WorkspaceEdit
+		c.SetEditApplier(func(_ context.Context, l string, edit *WorkspaceEdit) error {
+			label, got = l, edit
+			return nil
+		})
+		resp := pushApplyEdit(t, fake, params)
+		if !resp.Applied || resp.FailureReason != "" {
+			t.Fatalf("response = %+v, want applied", resp)
+		}
+		if label != "Move to a new file" {
+			t.Errorf("label = %q", label)
+		}
+		if got == nil || len(got.DocumentChanges) != 1 || len(got.Operations) != 1 {
+			t.Fatalf("edit = %+v, want a text edit and a rename", got)
+		}
+		if op := got.Operations[0]; op.Kind != protocol.RenameResourceOperation || op.URI != "file:///repo/a.ts" || op.NewURI != "file:///repo/b.ts" || op.Index != 1 {
+			t.Errorf("operation = %+v, want the rename after the text edit", op)
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		c.SetEditApplier(func(context.Context, string, *WorkspaceEdit) error {
+			return errors.New("ERR_STALE_EDIT: file modified since analysis")
+		})
+		resp := pushApplyEdit(t, fake, params)
+		if resp.Applied || resp.FailureReason != "ERR_STALE_EDIT: file modified since analysis" {
+			t.Errorf("response = %+v, want the applier's error as the reason", resp)
+		}
+	})
+
+	t.Run("undecodable edit", func(t *testing.T) {
+		called := false
+		c.SetEditApplier(func(context.Context, string, *WorkspaceEdit) error {
+			called = true
+			return nil
+		})
+		resp := pushApplyEdit(t, fake, map[string]any{"edit": map[string]any{"documentChanges": []any{
+			map[string]any{"kind": "chmod", "uri": "file:///repo/a.ts"},
+		}}})
+		if resp.Applied || !strings.Contains(resp.FailureReason, "unsupported chmod operation") || called {
+			t.Errorf("response = %+v, applier called %v; want a refusal without applying", resp, called)
+		}
+	})
+
+	t.Run("messages keep flowing while an edit is applied", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		c.SetEditApplier(func(context.Context, string, *WorkspaceEdit) error {
+			close(started)
+			<-release
+			return nil
+		})
+		done := make(chan protocol.ApplyWorkspaceEditResponse)
+		go func() { done <- pushApplyEdit(t, fake, params) }()
+		<-started
+
+		fake.mu.Lock()
+		conn := fake.conn
+		fake.mu.Unlock()
+		if err := conn.Notify(context.Background(), protocol.MethodTextDocumentPublishDiagnostics, map[string]any{"uri": "file:///repo/c.ts", "diagnostics": []any{}}); err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for !slices.Contains(c.AnalyzedFiles(), "/repo/c.ts") {
+			if time.Now().After(deadline) {
+				t.Fatal("diagnostics not handled while the edit was applied")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		close(release)
+		if resp := <-done; !resp.Applied {
+			t.Errorf("response = %+v, want applied", resp)
+		}
+	})
+}
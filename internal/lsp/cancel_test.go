@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func TestRequestCancellation(t *testing.T) {
+	for _, tt := range []struct {
+		method string
+		call   func(context.Context, *Client) error
+	}{
+		{protocol.MethodTextDocumentReferences, func(ctx context.Context, c *Client) error {
+			_, err := c.References(ctx, "/repo/a.ts", 1, 1, true)
+			return err
+		}},
+		{"textDocument/diagnostic", func(ctx context.Context, c *Client) error {
+			_, err := c.DiagnosticReport(ctx, "/repo/a.ts", 1)
+			return err
+		}},
+	} {
+		t.Run(tt.method, func(t *testing.T) {
+			fake := &fakeTsgo{hold: tt.method, held: make(chan jsonrpc2.ID, 1), cancelled: make(chan jsonrpc2.ID, 1)}
+			c := StartClient(context.Background(), "file:///repo", fake.start)
+			t.Cleanup(func() { _ = c.Close() })
+			if err := c.Wait(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- tt.call(ctx, c) }()
+			var id jsonrpc2.ID
+			select {
+			case id = <-fake.held:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the request never reached tsgo")
+			}
+			cancel()
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("error = %v, want context.Canceled", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("the request outlived its context")
+			}
+			select {
+			case got := <-fake.cancelled:
+				if got != id {
+					t.Errorf("$/cancelRequest for %v, want %v", got, id)
+				}
+			case <-time.After(5 * time.Second):
+				t.Error("tsgo got no $/cancelRequest")
+			}
+		})
+	}
+}
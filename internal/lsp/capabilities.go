@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// ErrUnsupported is wrapped by the errors of requests tsgo does not
+// support: it did not advertise the capability, or answered that the
+// method does not exist.
+var ErrUnsupported = errors.New("not supported by this tsgo")
+
+// providerEnabled reports whether a server capability is on: a provider
+// is either a boolean or an options object.
+func providerEnabled(provider any) bool {
+	switch p := provider.(type) {
+	case nil:
+		return false
+	case bool:
+		return p
+	default:
+		return true
+	}
+}
+
+// requireProvider returns an ErrUnsupported error for method unless
+// provider, a field of the server capabilities, is enabled.
+func requireProvider(method string, provider any) error {
+	if providerEnabled(provider) {
+		return nil
+	}
+	return fmt.Errorf("%s: %w (the server did not advertise it at initialize)", method, ErrUnsupported)
+}
+
+// unsupportedCall replaces a method-not-found error of a call to method
+// by an ErrUnsupported one; other errors are returned as is.
+func unsupportedCall(method string, err error) error {
+	var rpcErr *jsonrpc2.Error
+	if errors.As(err, &rpcErr) && rpcErr.Code == jsonrpc2.MethodNotFound {
+		return fmt.Errorf("%s: %w (%s)", method, ErrUnsupported, rpcErr.Message)
+	}
+	return err
+}
+
+// methodDiagnostic is the LSP 3.17 pull diagnostics request, which
+// go.lsp.dev/protocol does not know.
+const methodDiagnostic = "textDocument/diagnostic"
+
+// Capability tells whether tsgo provides the feature of one request.
+type Capability struct {
+	Method    string `json:"method"`
+	Supported bool   `json:"supported"`
+}
+
+// capabilityProviders are the requests tsgo may not provide, each with
+// its provider in the server capabilities, in the order
+// CapabilitySummary reports them.
+var capabilityProviders = []struct {
+	method   string
+	provider func(c *Client) any
+}{
+	{protocol.MethodTextDocumentHover, func(c *Client) any { return c.session().capabilities.HoverProvider }},
+	{protocol.MethodTextDocumentDefinition, func(c *Client) any { return c.session().capabilities.DefinitionProvider }},
+	{protocol.MethodTextDocumentTypeDefinition, func(c *Client) any { return c.session().capabilities.TypeDefinitionProvider }},
+	{protocol.MethodTextDocumentImplementation, func(c *Client) any { return c.session().capabilities.ImplementationProvider }},
+	{protocol.MethodTextDocumentReferences, func(c *Client) any { return c.session().capabilities.ReferencesProvider }},
+	{protocol.MethodTextDocumentDocumentHighlight, func(c *Client) any { return c.session().capabilities.DocumentHighlightProvider }},
+	{protocol.MethodTextDocumentDocumentSymbol, func(c *Client) any { return c.session().capabilities.DocumentSymbolProvider }},
+	{protocol.MethodWorkspaceSymbol, func(c *Client) any { return c.session().capabilities.WorkspaceSymbolProvider }},
+	{protocol.MethodTextDocumentCompletion, func(c *Client) any { return c.session().capabilities.CompletionProvider != nil }},
+	{protocol.MethodTextDocumentSignatureHelp, func(c *Client) any { return c.session().capabilities.SignatureHelpProvider != nil }},
+	{protocol.MethodTextDocumentCodeAction, func(c *Client) any { return c.session().capabilities.CodeActionProvider }},
+	{protocol.MethodTextDocumentRename, func(c *Client) any { return c.session().capabilities.RenameProvider }},
+	{protocol.MethodTextDocumentPrepareRename, (*Client).prepareRenameProvider},
+	{protocol.MethodWillRenameFiles, (*Client).willRenameProvider},
+	{protocol.MethodTextDocumentFormatting, func(c *Client) any { return c.session().capabilities.DocumentFormattingProvider }},
+	{protocol.MethodTextDocumentRangeFormatting, func(c *Client) any { return c.session().capabilities.DocumentRangeFormattingProvider }},
+	{protocol.MethodTextDocumentFoldingRange, func(c *Client) any { return c.session().capabilities.FoldingRangeProvider }},
+	{methodSelectionRange, func(c *Client) any { return c.session().capabilities.SelectionRangeProvider }},
+	{protocol.MethodTextDocumentPrepareCallHierarchy, func(c *Client) any { return c.session().capabilities.CallHierarchyProvider }},
+	{methodPrepareTypeHierarchy, func(c *Client) any { return c.session().typeHierarchyProvider }},
+	{protocol.MethodSemanticTokensFull, func(c *Client) any { return c.session().capabilities.SemanticTokensProvider }},
+	{methodInlayHint, func(c *Client) any { return c.session().inlayHintProvider }},
+	{methodDiagnostic, func(c *Client) any { return c.session().diagnosticProvider }},
+}
+
+// Capabilities returns the capabilities tsgo announced at initialize, or
+// the zero value until it started. Some are not fields of
+// protocol.ServerCapabilities; Supports covers those too.
+func (c *Client) Capabilities() protocol.ServerCapabilities {
+	if !c.isReady() {
+		return protocol.ServerCapabilities{}
+	}
+	return c.session().capabilities
+}
+
+// ServerInfo returns the name and version tsgo announced at initialize,
+// or nil when it announced none or has not started.
+func (c *Client) ServerInfo() *protocol.ServerInfo {
+	if !c.isReady() {
+		return nil
+	}
+	return c.session().serverInfo
+}
+
+// CapabilitySummary reports for each request tsgo may not provide
+// whether it announced it at initialize. It is nil until tsgo started.
+func (c *Client) CapabilitySummary() []Capability {
+	if !c.isReady() {
+		return nil
+	}
+	caps := make([]Capability, len(capabilityProviders))
+	for i, p := range capabilityProviders {
+		caps[i] = Capability{Method: p.method, Supported: providerEnabled(p.provider(c))}
+	}
+	return caps
+}
+
+// Supports reports whether tsgo announced the capability of method. It
+// is true for methods every server provides, and false until tsgo
+// started.
+func (c *Client) Supports(method string) bool {
+	if !c.isReady() {
+		return false
+	}
+	for _, p := range capabilityProviders {
+		if p.method == method {
+			return providerEnabled(p.provider(c))
+		}
+	}
+	return true
+}
+
+// SupportsIncrementalSync reports whether tsgo announced that it takes
+// didChange notifications with ranged changes rather than only the full
+// text. It is false until tsgo started.
+func (c *Client) SupportsIncrementalSync() bool {
+	if !c.isReady() {
+		return false
+	}
+	return syncKind(c.session().capabilities.TextDocumentSync) == protocol.TextDocumentSyncKindIncremental
+}
+
+// syncKind returns the change kind of a textDocumentSync capability,
+// which is either the kind or an options object.
+func syncKind(sync any) protocol.TextDocumentSyncKind {
+	switch s := sync.(type) {
+	case float64:
+		return protocol.TextDocumentSyncKind(s)
+	case protocol.TextDocumentSyncKind:
+		return s
+	case map[string]any:
+		if k, ok := s["change"].(float64); ok {
+			return protocol.TextDocumentSyncKind(k)
+		}
+	case *protocol.TextDocumentSyncOptions:
+		if s != nil {
+			return s.Change
+		}
+	}
+	return protocol.TextDocumentSyncKindNone
+}
+
+// SupportsPullDiagnostics reports whether tsgo answers
+// textDocument/diagnostic; without it diagnostics are the ones tsgo
+// publishes.
+func (c *Client) SupportsPullDiagnostics() bool {
+	return c.Supports(methodDiagnostic)
+}
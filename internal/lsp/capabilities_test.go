@@ -0,0 +1,143 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func TestRequireProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider any
+		ok       bool
+	}{
+		{"absent", nil, false},
+		{"false", false, false},
+		{"true", true, true},
+		{"options", map[string]any{"workDoneProgress": false}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := requireProvider(protocol.MethodTextDocumentTypeDefinition, tt.provider)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok %v", err, tt.ok)
+			}
+			if err != nil && (!errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "textDocument/typeDefinition")) {
+				t.Errorf("err = %v, want an ErrUnsupported naming the method", err)
+			}
+		})
+	}
+}
+
+func TestUnsupportedCall(t *testing.T) {
+	err := unsupportedCall("textDocument/typeDefinition", fmt.Errorf("call: %w", jsonrpc2.NewError(jsonrpc2.MethodNotFound, "unhandled method")))
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("method not found = %v, want ErrUnsupported", err)
+	}
+	other := jsonrpc2.NewError(jsonrpc2.InternalError, "boom")
+	if err := unsupportedCall("textDocument/typeDefinition", other); err != other {
+		t.Errorf("internal error = %v, want it unchanged", err)
+	}
+}
+
+func TestClientCapabilities(t *testing.T) {
+	fake := &fakeTsgo{capabilities: map[string]any{
+		"hoverProvider":      true,
+		"renameProvider":     map[string]any{"prepareProvider": true},
+		"referencesProvider": false,
+	}}
+	c := StartClient(context.Background(), "file:///repo", fake.start)
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if info := c.ServerInfo(); info == nil || info.Name != "fake-tsgo" || info.Version != "7.0.0-dev" {
+		t.Errorf("ServerInfo = %+v", info)
+	}
+	if c.Capabilities().HoverProvider != true {
+		t.Errorf("Capabilities().HoverProvider = %v, want true", c.Capabilities().HoverProvider)
+	}
+	for method, want := range map[string]bool{
+		protocol.MethodTextDocumentHover:         true,
+		protocol.MethodTextDocumentPrepareRename: true,
+		protocol.MethodTextDocumentReferences:    false,
+		protocol.MethodTextDocumentCompletion:    false,
+		methodDiagnostic:                         false,
+		protocol.MethodTextDocumentDidOpen:       true,
+	} {
+		if got := c.Supports(method); got != want {
+			t.Errorf("Supports(%s) = %v, want %v", method, got, want)
+		}
+	}
+	if c.SupportsPullDiagnostics() {
+		t.Error("SupportsPullDiagnostics = true without a diagnosticProvider")
+	}
+	if c.SupportsIncrementalSync() {
+		t.Error("SupportsIncrementalSync = true without a textDocumentSync")
+	}
+	summary := c.CapabilitySummary()
+	if len(summary) != len(capabilityProviders) || summary[0] != (Capability{Method: protocol.MethodTextDocumentHover, Supported: true}) {
+		t.Errorf("CapabilitySummary = %+v", summary)
+	}
+}
+
+func TestSyncKind(t *testing.T) {
+	tests := []struct {
+		name string
+		sync any
+		want protocol.TextDocumentSyncKind
+	}{
+		{name: "absent", want: protocol.TextDocumentSyncKindNone},
+		{name: "kind", sync: float64(2), want: protocol.TextDocumentSyncKindIncremental},
+		{name: "full kind", sync: float64(1), want: protocol.TextDocumentSyncKindFull},
+		{name: "options", sync: map[string]any{"openClose": true, "change": float64(2)}, want: protocol.TextDocumentSyncKindIncremental},
+		{name: "options without change", sync: map[string]any{"openClose": true}, want: protocol.TextDocumentSyncKindNone},
+		{name: "typed options", sync: &protocol.TextDocumentSyncOptions{Change: protocol.TextDocumentSyncKindFull}, want: protocol.TextDocumentSyncKindFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncKind(tt.sync); got != tt.want {
+				t.Errorf("syncKind(%v) = %v, want %v", tt.sync, got, tt.want)
+			}
+		})
+	}
+
+	fake := &fakeTsgo{capabilities: map[string]any{"textDocumentSync": map[string]any{"openClose": true, "change": 2}}}
+	c := StartClient(context.Background(), "file:///repo", fake.start)
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !c.SupportsIncrementalSync() {
+		t.Error("SupportsIncrementalSync = false with change 2")
+	}
+}
+
+func TestDiagnosticReportWithoutPull(t *testing.T) {
+	// Without a diagnosticProvider the pull request is never sent, so
+	// holding it would hang the test if it were.
+	fake := &fakeTsgo{capabilities: map[string]any{}, hold: methodDiagnostic, held: make(chan jsonrpc2.ID, 1)}
+	c := StartClient(context.Background(), "file:///repo", fake.start)
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.pushed.wait = 10 * time.Millisecond
+	report, err := c.DiagnosticReport(context.Background(), "/repo/a.ts", 0)
+	if err != nil || report.Source != DiagnosticSourcePush {
+		t.Errorf("DiagnosticReport = %+v, %v; want push diagnostics", report, err)
+	}
+	select {
+	case <-fake.held:
+		t.Error("textDocument/diagnostic was sent")
+	default:
+	}
+}
@@ -1,14 +1,19 @@
 package lsp
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf16"
 
 	"go.lsp.dev/jsonrpc2"
 	"go.lsp.dev/protocol"
@@ -16,31 +21,107 @@ import (
 	"go.uber.org/zap"
 )
 
-// Client wraps a JSON-RPC connection to tsgo's LSP server.
+// Client wraps a JSON-RPC connection to tsgo's LSP server. Its requests
+// may be issued concurrently: the connection serializes the writes and
+// matches each response to its request by ID.
 type Client struct {
+	// current is the running tsgo; a restart replaces it.
+	current atomic.Pointer[tsgoSession]
+	rootURI string
+	// timeout is the request timeout; see SetRequestTimeout.
+	timeout atomic.Int64
+
+	// pushed stores push diagnostics received from the server.
+	pushed *pushStore
+
+	diagMu sync.Mutex
+	// analyzed records every URI the server has reported diagnostics for,
+	// by push or by a successful pull.
+	analyzed map[string]bool
+
+	// projects tracks file-to-project assignments parsed from tsgo's log
+	// messages.
+	projects projectTracker
+
+	// progress follows tsgo's work-done progress for WaitForProjectLoad.
+	progress *progressTracker
+
+	// start and startCtx spawn tsgo for every startup attempt.
+	start    ProcessStarter
+	startCtx context.Context
+
+	// startMu guards the fields below. ready is closed when the current
+	// startup finished; startErr is then set if it failed. The fields
+	// above that StartClient does not set may only be used after a
+	// successful startup.
+	startMu     sync.Mutex
+	ready       chan struct{}
+	startErr    error
+	cancelStart context.CancelFunc
+	closed      bool
+	phase       string
+	started     time.Time
+	finished    time.Time
+
+	// restart, crashes and restarts are guarded by startMu; see restart.go.
+	restart   restartConfig
+	crashes   int
+	restarts  int
+	onRestart []func(ctx context.Context, conn jsonrpc2.Conn) error
+	// editApplier, guarded by startMu, applies the edits tsgo sends; see
+	// SetEditApplier.
+	editApplier EditApplier
+}
+
+// tsgoSession is one tsgo process and the connection to it.
+type tsgoSession struct {
 	conn    jsonrpc2.Conn
 	server  protocol.Server
 	process *TsgoProcess
-	rootURI string
+	started time.Time
+	// ended is closed once the client has handled the end of the
+	// session, by restarting tsgo or giving up.
+	ended chan struct{}
+
+	// capabilities and serverInfo are those of the initialize result.
+	capabilities protocol.ServerCapabilities
+	serverInfo   *protocol.ServerInfo
+	// inlayHintProvider, typeHierarchyProvider and diagnosticProvider are
+	// the capabilities protocol.ServerCapabilities has no fields for.
+	inlayHintProvider     any
+	typeHierarchyProvider any
+	diagnosticProvider    any
+
+	// health detects a tsgo that stopped answering without exiting.
+	health     *healthMonitor
+	stopHealth context.CancelFunc
+}
 
-	// diagnostics stores push diagnostics received from the server.
-	diagMu      sync.Mutex
-	diagnostics map[string][]protocol.Diagnostic // URI -> diagnostics
+// session returns the running tsgo, or the last one when it exited and
+// has not been restarted yet. It is nil until the first startup succeeded.
+func (c *Client) session() *tsgoSession {
+	return c.current.Load()
 }
 
-// NewClient spawns tsgo and establishes an LSP connection.
+// NewClient spawns tsgo and establishes an LSP connection, returning once
+// the initialize handshake is done.
 // rootURI is the workspace root URI (e.g. "file:///path/to/project").
 // If empty, the current working directory is used.
 func NewClient(ctx context.Context, rootURI string) (*Client, error) {
-	if rootURI == "" {
-		if cwd, err := os.Getwd(); err == nil {
-			rootURI = string(uri.File(cwd))
-		}
+	c := StartClient(ctx, rootURI, StartTsgo)
+	if err := c.Wait(ctx); err != nil {
+		_ = c.Close()
+		return nil, err
 	}
+	return c, nil
+}
 
-	proc, err := StartTsgo(ctx)
+// connect spawns tsgo with start and performs the initialize handshake.
+func (c *Client) connect(ctx context.Context, start ProcessStarter) error {
+	c.setPhase(phaseSpawn)
+	proc, err := start(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("start tsgo: %w", err)
+		return fmt.Errorf("start tsgo: %w", err)
 	}
 
 	rwc := &readWriteCloser{
@@ -49,12 +130,6 @@ func NewClient(ctx context.Context, rootURI string) (*Client, error) {
 	}
 	stream := jsonrpc2.NewStream(rwc)
 
-	c := &Client{
-		process:     proc,
-		rootURI:     rootURI,
-		diagnostics: make(map[string][]protocol.Diagnostic),
-	}
-
 	var logger *zap.Logger
 	if os.Getenv("TYPESCRIPT_MCP_DEBUG") != "" {
 		logger, _ = zap.NewDevelopment()
@@ -62,31 +137,71 @@ func NewClient(ctx context.Context, rootURI string) (*Client, error) {
 		logger = zap.NewNop()
 	}
 
-	// NewClient creates a connection where:
+	// The connection is set up as protocol.NewClient does:
 	// - We are the "client" handling server-initiated notifications (publishDiagnostics, etc.)
 	// - We get back a "server" dispatcher to send requests to tsgo
-	_, conn, server := protocol.NewClient(ctx, c, stream, logger)
-	c.conn = conn
-	c.server = server
+	// but workspace/applyEdit is answered by applyEditHandler first.
+	conn := jsonrpc2.NewConn(stream)
+	conn.Go(protocol.WithClient(ctx, c), c.applyEditHandler(protocol.Handlers(protocol.ClientHandler(c, jsonrpc2.MethodNotFoundHandler))))
+	server := protocol.ServerDispatcher(conn, logger.Named("server"))
+	s := &tsgoSession{conn: conn, server: server, process: proc, started: time.Now(), ended: make(chan struct{})}
 
-	if err := c.initialize(ctx); err != nil {
+	c.setPhase(phaseInitialize)
+	if err := c.initialize(ctx, s); err != nil {
+		_ = conn.Close()
 		_ = proc.Stop()
-		return nil, fmt.Errorf("initialize: %w", err)
+		return fmt.Errorf("initialize: %w", err)
 	}
 
-	return c, nil
+	// A wedged tsgo is killed: pending requests then fail at once instead
+	// of each waiting for its caller's timeout, and watch restarts it.
+	s.health = newHealthMonitor(healthConfigFromEnv(), s.probe, func(WedgeReport) {
+		slog.Error("killing the unresponsive tsgo; it will be restarted")
+		_ = proc.Kill()
+	})
+	s.health.stderr = proc.StderrTail
+	var healthCtx context.Context
+	healthCtx, s.stopHealth = context.WithCancel(ctx)
+	go s.health.run(healthCtx)
+
+	c.current.Store(s)
+	return nil
 }
 
 // Conn returns the underlying JSON-RPC connection for sending notifications.
 func (c *Client) Conn() jsonrpc2.Conn {
-	return c.conn
+	return c.session().conn
+}
+
+// TsgoVersion returns the version of the running tsgo, or "" when it could
+// not be determined or tsgo has not started yet.
+func (c *Client) TsgoVersion() string {
+	if !c.isReady() {
+		return ""
+	}
+	return c.session().process.version
+}
+
+// VersionWarning describes how the running tsgo misses the required
+// version when the server was started in warn-only mode. It is "" until
+// the startup succeeded.
+func (c *Client) VersionWarning() string {
+	if !c.isReady() {
+		return ""
+	}
+	return c.session().process.versionWarning
+}
+
+// RootDir returns the workspace root directory the server was started with.
+func (c *Client) RootDir() string {
+	return uri.URI(c.rootURI).Filename()
 }
 
 // initialize performs the LSP initialize handshake.
-func (c *Client) initialize(ctx context.Context) error {
+func (c *Client) initialize(ctx context.Context, s *tsgoSession) error {
 	pid := int32(os.Getpid())
 
-	result, err := c.server.Initialize(ctx, &protocol.InitializeParams{
+	params, err := withExtraCapabilities(&protocol.InitializeParams{
 		ProcessID: pid,
 		RootURI:   protocol.DocumentURI(c.rootURI),
 		ClientInfo: &protocol.ClientInfo{
@@ -104,6 +219,43 @@ func (c *Client) initialize(ctx context.Context) error {
 				Hover: &protocol.HoverTextDocumentClientCapabilities{
 					ContentFormat: []protocol.MarkupKind{protocol.Markdown, protocol.PlainText},
 				},
+				SignatureHelp: &protocol.SignatureHelpTextDocumentClientCapabilities{
+					SignatureInformation: &protocol.TextDocumentClientCapabilitiesSignatureInformation{
+						DocumentationFormat: []protocol.MarkupKind{protocol.Markdown, protocol.PlainText},
+						ParameterInformation: &protocol.TextDocumentClientCapabilitiesParameterInformation{
+							LabelOffsetSupport: true,
+						},
+						ActiveParameterSupport: true,
+					},
+				},
+				Completion: &protocol.CompletionTextDocumentClientCapabilities{
+					CompletionItem: &protocol.CompletionTextDocumentClientCapabilitiesItem{
+						DocumentationFormat: []protocol.MarkupKind{protocol.Markdown, protocol.PlainText},
+						DeprecatedSupport:   true,
+						ResolveSupport: &protocol.CompletionTextDocumentClientCapabilitiesItemResolveSupport{
+							Properties: []string{"documentation", "detail"},
+						},
+					},
+				},
+				CodeAction: &protocol.CodeActionClientCapabilities{
+					CodeActionLiteralSupport: &protocol.CodeActionClientCapabilitiesLiteralSupport{
+						CodeActionKind: &protocol.CodeActionClientCapabilitiesKind{
+							ValueSet: []protocol.CodeActionKind{
+								protocol.QuickFix, protocol.Refactor, protocol.RefactorExtract, protocol.RefactorInline,
+								protocol.RefactorRewrite, protocol.Source, protocol.SourceOrganizeImports,
+							},
+						},
+					},
+					IsPreferredSupport: true,
+					DisabledSupport:    true,
+					DataSupport:        true,
+					ResolveSupport: &protocol.CodeActionClientCapabilitiesResolveSupport{
+						Properties: []string{"edit"},
+					},
+				},
+				FoldingRange: &protocol.FoldingRangeClientCapabilities{
+					LineFoldingOnly: true,
+				},
 				PublishDiagnostics: &protocol.PublishDiagnosticsClientCapabilities{
 					RelatedInformation: true,
 				},
@@ -111,24 +263,74 @@ func (c *Client) initialize(ctx context.Context) error {
 					HierarchicalDocumentSymbolSupport: true,
 				},
 				Rename: &protocol.RenameClientCapabilities{
-					PrepareSupport: false,
+					PrepareSupport: true,
 				},
+				SemanticTokens: semanticTokensClientCapabilities,
+			},
+			Window: &protocol.WindowClientCapabilities{
+				WorkDoneProgress: true,
 			},
 			Workspace: &protocol.WorkspaceClientCapabilities{
+				// tsgo reads its user preferences, which turn on inlay
+				// hints, from workspace/configuration.
+				Configuration: true,
+				// Edits tsgo sends are applied; see SetEditApplier.
+				ApplyEdit: true,
+				// Edits may create, rename and delete files; see
+				// decodeWorkspaceEdit. They are applied as a whole or not
+				// at all.
 				WorkspaceEdit: &protocol.WorkspaceClientCapabilitiesWorkspaceEdit{
-					DocumentChanges: false,
+					DocumentChanges: true,
+					ResourceOperations: []string{
+						string(protocol.CreateResourceOperation),
+						string(protocol.RenameResourceOperation),
+						string(protocol.DeleteResourceOperation),
+					},
+					FailureHandling: string(protocol.FailureHandlingKindTransactional),
+				},
+				FileOperations: &protocol.WorkspaceClientCapabilitiesFileOperations{
+					WillRename: true,
+					DidRename:  true,
 				},
 			},
 		},
 	})
 	if err != nil {
+		return fmt.Errorf("initialize params: %w", err)
+	}
+	var raw json.RawMessage
+	if err := protocol.Call(ctx, s.conn, protocol.MethodInitialize, params, &raw); err != nil {
 		return fmt.Errorf("initialize request: %w", err)
 	}
-	_ = result // Server capabilities available if needed later
+	var result struct {
+		Capabilities protocol.ServerCapabilities `json:"capabilities"`
+		ServerInfo   *protocol.ServerInfo        `json:"serverInfo"`
+	}
+	var extra struct {
+		Capabilities struct {
+			InlayHintProvider     any `json:"inlayHintProvider"`
+			TypeHierarchyProvider any `json:"typeHierarchyProvider"`
+			DiagnosticProvider    any `json:"diagnosticProvider"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return fmt.Errorf("initialize result: %w", err)
+	}
+	_ = json.Unmarshal(raw, &extra)
+	s.capabilities = result.Capabilities
+	s.serverInfo = result.ServerInfo
+	s.inlayHintProvider = extra.Capabilities.InlayHintProvider
+	s.typeHierarchyProvider = extra.Capabilities.TypeHierarchyProvider
+	s.diagnosticProvider = extra.Capabilities.DiagnosticProvider
 
-	if err := c.server.Initialized(ctx, &protocol.InitializedParams{}); err != nil {
+	if err := s.server.Initialized(ctx, &protocol.InitializedParams{}); err != nil {
 		return fmt.Errorf("initialized notification: %w", err)
 	}
+	// For a tsgo that does not ask for its settings.
+	settings := map[string]any{"typescript": inlayHintSettings, "javascript": inlayHintSettings}
+	if err := s.server.DidChangeConfiguration(ctx, &protocol.DidChangeConfigurationParams{Settings: settings}); err != nil {
+		return fmt.Errorf("configuration notification: %w", err)
+	}
 
 	return nil
 }
@@ -139,9 +341,12 @@ func (c *Client) Hover(ctx context.Context, file string, line, col int) (*protoc
 	if line < 1 || col < 1 {
 		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
 	}
-	return c.server.Hover(ctx, &protocol.HoverParams{
+	ctx, done := c.request(ctx, "textDocument/hover")
+	hover, err := c.session().server.Hover(ctx, &protocol.HoverParams{
 		TextDocumentPositionParams: makePosition(file, line, col),
 	})
+	err = done(err)
+	return hover, err
 }
 
 // Definition returns the definition location(s) for a symbol.
@@ -150,45 +355,510 @@ func (c *Client) Definition(ctx context.Context, file string, line, col int) ([]
 	if line < 1 || col < 1 {
 		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
 	}
-	return c.server.Definition(ctx, &protocol.DefinitionParams{
+	ctx, done := c.request(ctx, "textDocument/definition")
+	locs, err := c.session().server.Definition(ctx, &protocol.DefinitionParams{
 		TextDocumentPositionParams: makePosition(file, line, col),
 	})
+	err = done(err)
+	return locs, err
+}
+
+// TypeDefinition returns the definition of the type of the expression at
+// a position. It fails with ErrUnsupported when tsgo does not provide
+// type definitions.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) TypeDefinition(ctx context.Context, file string, line, col int) ([]protocol.Location, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	if err := requireProvider(protocol.MethodTextDocumentTypeDefinition, c.session().capabilities.TypeDefinitionProvider); err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	ctx, done := c.request(ctx, "textDocument/typeDefinition")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentTypeDefinition, &protocol.TypeDefinitionParams{
+		TextDocumentPositionParams: makePosition(file, line, col),
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(protocol.MethodTextDocumentTypeDefinition, err)
+	}
+	return decodeLocations(raw)
+}
+
+// PrepareCallHierarchy returns the call hierarchy items of the function
+// or method at a position, the roots for IncomingCalls and OutgoingCalls.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) PrepareCallHierarchy(ctx context.Context, file string, line, col int) ([]protocol.CallHierarchyItem, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	if err := requireProvider(protocol.MethodTextDocumentPrepareCallHierarchy, c.session().capabilities.CallHierarchyProvider); err != nil {
+		return nil, err
+	}
+	var items []protocol.CallHierarchyItem
+	ctx, done := c.request(ctx, "textDocument/prepareCallHierarchy")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentPrepareCallHierarchy, &protocol.CallHierarchyPrepareParams{
+		TextDocumentPositionParams: makePosition(file, line, col),
+	}, &items)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(protocol.MethodTextDocumentPrepareCallHierarchy, err)
+	}
+	return items, nil
 }
 
-// References returns all reference locations for a symbol.
+// IncomingCalls returns the callers of a call hierarchy item.
+func (c *Client) IncomingCalls(ctx context.Context, item protocol.CallHierarchyItem) ([]protocol.CallHierarchyIncomingCall, error) {
+	var calls []protocol.CallHierarchyIncomingCall
+	ctx, done := c.request(ctx, "callHierarchy/incomingCalls")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodCallHierarchyIncomingCalls, &protocol.CallHierarchyIncomingCallsParams{Item: item}, &calls)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(protocol.MethodCallHierarchyIncomingCalls, err)
+	}
+	return calls, nil
+}
+
+// OutgoingCalls returns the functions a call hierarchy item calls.
+func (c *Client) OutgoingCalls(ctx context.Context, item protocol.CallHierarchyItem) ([]protocol.CallHierarchyOutgoingCall, error) {
+	var calls []protocol.CallHierarchyOutgoingCall
+	ctx, done := c.request(ctx, "callHierarchy/outgoingCalls")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodCallHierarchyOutgoingCalls, &protocol.CallHierarchyOutgoingCallsParams{Item: item}, &calls)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(protocol.MethodCallHierarchyOutgoingCalls, err)
+	}
+	return calls, nil
+}
+
+// Implementation returns the implementations of an interface, abstract
+// member or type at a position.
 // Line and column are 1-based (converted to 0-based for LSP).
-func (c *Client) References(ctx context.Context, file string, line, col int) ([]protocol.Location, error) {
+func (c *Client) Implementation(ctx context.Context, file string, line, col int) ([]protocol.Location, error) {
 	if line < 1 || col < 1 {
 		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
 	}
-	return c.server.References(ctx, &protocol.ReferenceParams{
+	var raw json.RawMessage
+	ctx, done := c.request(ctx, "textDocument/implementation")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentImplementation, &protocol.ImplementationParams{
+		TextDocumentPositionParams: makePosition(file, line, col),
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, err
+	}
+	return decodeLocations(raw)
+}
+
+// decodeLocations decodes a Location, Location[], LocationLink[] or null
+// result. A LocationLink becomes the Location of its target selection.
+func decodeLocations(raw json.RawMessage) ([]protocol.Location, error) {
+	trimmed := bytes.TrimSpace(raw)
+	switch {
+	case len(trimmed) == 0 || string(trimmed) == "null":
+		return nil, nil
+	case trimmed[0] == '{':
+		trimmed = append(append([]byte{'['}, trimmed...), ']')
+	}
+	var items []struct {
+		protocol.Location
+		TargetURI            protocol.DocumentURI `json:"targetUri"`
+		TargetSelectionRange protocol.Range       `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(trimmed, &items); err != nil {
+		return nil, fmt.Errorf("decoding locations: %w", err)
+	}
+	locs := make([]protocol.Location, len(items))
+	for i, item := range items {
+		locs[i] = item.Location
+		if item.TargetURI != "" {
+			locs[i] = protocol.Location{URI: item.TargetURI, Range: item.TargetSelectionRange}
+		}
+	}
+	return locs, nil
+}
+
+// References returns all reference locations for a symbol, with its
+// declaration when includeDeclaration is set.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) References(ctx context.Context, file string, line, col int, includeDeclaration bool) ([]protocol.Location, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	ctx, done := c.request(ctx, "textDocument/references")
+	locs, err := c.session().server.References(ctx, &protocol.ReferenceParams{
 		TextDocumentPositionParams: makePosition(file, line, col),
 		Context: protocol.ReferenceContext{
-			IncludeDeclaration: true,
+			IncludeDeclaration: includeDeclaration,
 		},
 	})
+	err = done(err)
+	return locs, err
 }
 
-// Rename renames a symbol at the given position.
+// Rename renames a symbol at the given position. Resource operations in
+// the result are kept in its Operations.
 // Line and column are 1-based (converted to 0-based for LSP).
-func (c *Client) Rename(ctx context.Context, file string, line, col int, newName string) (*protocol.WorkspaceEdit, error) {
+func (c *Client) Rename(ctx context.Context, file string, line, col int, newName string) (*WorkspaceEdit, error) {
 	if line < 1 || col < 1 {
 		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
 	}
-	return c.server.Rename(ctx, &protocol.RenameParams{
+	ctx, done := c.request(ctx, "textDocument/rename")
+	var raw json.RawMessage
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentRename, &protocol.RenameParams{
 		TextDocumentPositionParams: makePosition(file, line, col),
 		NewName:                    newName,
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, err
+	}
+	return decodeWorkspaceEdit(raw)
+}
+
+// PrepareRenameResult is the answer to a prepareRename request.
+type PrepareRenameResult struct {
+	// Range is the symbol that would be renamed; it is zero when
+	// DefaultBehavior is set or the rename is refused.
+	Range       protocol.Range
+	Placeholder string
+	// DefaultBehavior is set when the server leaves finding the symbol
+	// to the client.
+	DefaultBehavior bool
+	// Reason is why the position cannot be renamed, or "" when it can.
+	Reason string
+}
+
+// prepareRenameProvider returns whether the server's rename options
+// announce prepareRename support, or nil.
+func (c *Client) prepareRenameProvider() any {
+	if opts, ok := c.session().capabilities.RenameProvider.(map[string]any); ok {
+		return opts["prepareProvider"]
+	}
+	return nil
+}
+
+// PrepareRename checks that the symbol at a position can be renamed. A
+// refusal is not an error: it is reported in the result's Reason. It
+// fails with ErrUnsupported when tsgo does not provide prepareRename.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) PrepareRename(ctx context.Context, file string, line, col int) (*PrepareRenameResult, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	if err := requireProvider(protocol.MethodTextDocumentPrepareRename, c.prepareRenameProvider()); err != nil {
+		return nil, err
+	}
+	ctx, done := c.request(ctx, "textDocument/prepareRename")
+	var raw json.RawMessage
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentPrepareRename, &protocol.PrepareRenameParams{
+		TextDocumentPositionParams: makePosition(file, line, col),
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		if reason, ok := renameRefusal(err); ok {
+			return &PrepareRenameResult{Reason: reason}, nil
+		}
+		return nil, unsupportedCall(protocol.MethodTextDocumentPrepareRename, err)
+	}
+	return decodePrepareRename(raw)
+}
+
+// renameRefusal returns the message of an error response refusing a
+// prepareRename. Missing methods, cancellations and internal errors are
+// not refusals.
+func renameRefusal(err error) (string, bool) {
+	var rpcErr *jsonrpc2.Error
+	if !errors.As(err, &rpcErr) {
+		return "", false
+	}
+	switch rpcErr.Code {
+	case jsonrpc2.MethodNotFound, jsonrpc2.InternalError, protocol.CodeRequestCancelled, protocol.CodeContentModified:
+		return "", false
+	}
+	return rpcErr.Message, true
+}
+
+// decodePrepareRename decodes a Range, {range, placeholder},
+// {defaultBehavior} or null result; null refuses the rename.
+func decodePrepareRename(raw json.RawMessage) (*PrepareRenameResult, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return &PrepareRenameResult{Reason: "no renameable symbol at this position"}, nil
+	}
+	var wire struct {
+		// Start and End are set by a bare Range.
+		Start           protocol.Position `json:"start"`
+		End             protocol.Position `json:"end"`
+		Range           *protocol.Range   `json:"range"`
+		Placeholder     string            `json:"placeholder"`
+		DefaultBehavior bool              `json:"defaultBehavior"`
+	}
+	if err := json.Unmarshal(trimmed, &wire); err != nil {
+		return nil, fmt.Errorf("decoding prepare rename: %w", err)
+	}
+	result := &PrepareRenameResult{Range: protocol.Range{Start: wire.Start, End: wire.End}, Placeholder: wire.Placeholder, DefaultBehavior: wire.DefaultBehavior}
+	if wire.Range != nil {
+		result.Range = *wire.Range
+	}
+	return result, nil
+}
+
+// willRenameProvider returns the server's willRenameFiles registration,
+// or nil.
+func (c *Client) willRenameProvider() any {
+	if ws := c.session().capabilities.Workspace; ws != nil && ws.FileOperations != nil && ws.FileOperations.WillRename != nil {
+		return ws.FileOperations.WillRename
+	}
+	return nil
+}
+
+// WillRenameFiles returns the edits that keep imports of oldPath working
+// once it is moved to newPath. It must be called before the move.
+func (c *Client) WillRenameFiles(ctx context.Context, oldPath, newPath string) (*WorkspaceEdit, error) {
+	if err := requireProvider(protocol.MethodWillRenameFiles, c.willRenameProvider()); err != nil {
+		return nil, err
+	}
+	ctx, done := c.request(ctx, "workspace/willRenameFiles")
+	var raw json.RawMessage
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodWillRenameFiles, &protocol.RenameFilesParams{
+		Files: []protocol.FileRename{{OldURI: string(uri.File(oldPath)), NewURI: string(uri.File(newPath))}},
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(protocol.MethodWillRenameFiles, err)
+	}
+	return decodeWorkspaceEdit(raw)
+}
+
+// DidRenameFiles tells the server oldPath was moved to newPath.
+func (c *Client) DidRenameFiles(ctx context.Context, oldPath, newPath string) error {
+	return c.session().conn.Notify(ctx, protocol.MethodDidRenameFiles, &protocol.RenameFilesParams{
+		Files: []protocol.FileRename{{OldURI: string(uri.File(oldPath)), NewURI: string(uri.File(newPath))}},
+	})
+}
+
+// Completion returns the completions at a position. A bare
+// CompletionItem[] response becomes a complete CompletionList.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) Completion(ctx context.Context, file string, line, col int) (*protocol.CompletionList, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	var raw json.RawMessage
+	ctx, done := c.request(ctx, "textDocument/completion")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentCompletion, &protocol.CompletionParams{
+		TextDocumentPositionParams: makePosition(file, line, col),
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCompletion(raw)
+}
+
+// ResolveCompletion fills in the documentation and detail of a completion
+// item returned by Completion.
+func (c *Client) ResolveCompletion(ctx context.Context, item *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	ctx, done := c.request(ctx, "completionItem/resolve")
+	resolved, err := c.session().server.CompletionResolve(ctx, item)
+	err = done(err)
+	return resolved, err
+}
+
+// decodeCompletion decodes a completion result: a CompletionList, a
+// CompletionItem[] or null.
+func decodeCompletion(raw json.RawMessage) (*protocol.CompletionList, error) {
+	trimmed := bytes.TrimSpace(raw)
+	switch {
+	case len(trimmed) == 0 || string(trimmed) == "null":
+		return &protocol.CompletionList{}, nil
+	case trimmed[0] == '[':
+		var items []protocol.CompletionItem
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("decoding completion items: %w", err)
+		}
+		return &protocol.CompletionList{Items: items}, nil
+	}
+	var list protocol.CompletionList
+	if err := json.Unmarshal(trimmed, &list); err != nil {
+		return nil, fmt.Errorf("decoding completion list: %w", err)
+	}
+	return &list, nil
+}
+
+// SignatureHelp returns the signatures of the call around a position, or
+// nil when there is none. Parameter labels given as offsets into the
+// signature label are replaced by the text they cover.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) SignatureHelp(ctx context.Context, file string, line, col int) (*protocol.SignatureHelp, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	var raw json.RawMessage
+	ctx, done := c.request(ctx, "textDocument/signatureHelp")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentSignatureHelp, &protocol.SignatureHelpParams{
+		TextDocumentPositionParams: makePosition(file, line, col),
+		Context: &protocol.SignatureHelpContext{
+			TriggerKind: protocol.SignatureHelpTriggerKindInvoked,
+		},
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSignatureHelp(raw)
+}
+
+// decodeSignatureHelp decodes a signature help result, resolving
+// parameter labels given as [start, end) UTF-16 offsets.
+func decodeSignatureHelp(raw json.RawMessage) (*protocol.SignatureHelp, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil, nil
+	}
+	var help struct {
+		Signatures []struct {
+			Label         string `json:"label"`
+			Documentation any    `json:"documentation,omitempty"`
+			Parameters    []struct {
+				Label         json.RawMessage `json:"label"`
+				Documentation any             `json:"documentation,omitempty"`
+			} `json:"parameters,omitempty"`
+			ActiveParameter uint32 `json:"activeParameter,omitempty"`
+		} `json:"signatures"`
+		ActiveSignature uint32 `json:"activeSignature,omitempty"`
+		ActiveParameter uint32 `json:"activeParameter,omitempty"`
+	}
+	if err := json.Unmarshal(trimmed, &help); err != nil {
+		return nil, fmt.Errorf("decoding signature help: %w", err)
+	}
+	out := &protocol.SignatureHelp{
+		Signatures:      make([]protocol.SignatureInformation, len(help.Signatures)),
+		ActiveSignature: help.ActiveSignature,
+		ActiveParameter: help.ActiveParameter,
+	}
+	for i, sig := range help.Signatures {
+		info := protocol.SignatureInformation{
+			Label:           sig.Label,
+			Documentation:   sig.Documentation,
+			ActiveParameter: sig.ActiveParameter,
+		}
+		for _, p := range sig.Parameters {
+			label, err := parameterLabel(sig.Label, p.Label)
+			if err != nil {
+				return nil, fmt.Errorf("decoding signature help: %w", err)
+			}
+			info.Parameters = append(info.Parameters, protocol.ParameterInformation{Label: label, Documentation: p.Documentation})
+		}
+		out.Signatures[i] = info
+	}
+	return out, nil
+}
+
+// parameterLabel returns the text of a parameter label, which is either a
+// string or UTF-16 offsets into the signature label.
+func parameterLabel(signature string, raw json.RawMessage) (string, error) {
+	var label string
+	if err := json.Unmarshal(raw, &label); err == nil {
+		return label, nil
+	}
+	var offsets [2]uint32
+	if err := json.Unmarshal(raw, &offsets); err != nil {
+		return "", fmt.Errorf("parameter label %s: %w", raw, err)
+	}
+	units := utf16.Encode([]rune(signature))
+	start, end := offsets[0], offsets[1]
+	if start > end || int(end) > len(units) {
+		return "", fmt.Errorf("parameter label offsets [%d, %d] outside %q", start, end, signature)
+	}
+	return string(utf16.Decode(units[start:end])), nil
+}
+
+// CodeAction returns the code actions for a range of a file, given the
+// diagnostics in it. A non-empty only restricts the kinds asked for. A
+// bare Command in the response becomes a CodeAction carrying just it.
+// Lines and columns are 1-based (converted to 0-based for LSP).
+func (c *Client) CodeAction(ctx context.Context, file string, startLine, startCol, endLine, endCol int, diags []protocol.Diagnostic, only []protocol.CodeActionKind) ([]CodeAction, error) {
+	if startLine < 1 || startCol < 1 || endLine < 1 || endCol < 1 {
+		return nil, fmt.Errorf("lines and columns must be >= 1, got %d:%d-%d:%d", startLine, startCol, endLine, endCol)
+	}
+	if diags == nil {
+		diags = []protocol.Diagnostic{}
+	}
+	var raw json.RawMessage
+	ctx, done := c.request(ctx, "textDocument/codeAction")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentCodeAction, &protocol.CodeActionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(file))},
+		Range:        makeRange(startLine, startCol, endLine, endCol),
+		Context:      protocol.CodeActionContext{Diagnostics: diags, Only: only},
+	}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, err
+	}
+	return decodeCodeActions(raw)
+}
+
+// ResolveCodeAction fills in the edit of a code action returned by
+// CodeAction without one.
+func (c *Client) ResolveCodeAction(ctx context.Context, action CodeAction) (CodeAction, error) {
+	var raw json.RawMessage
+	ctx, done := c.request(ctx, "codeAction/resolve")
+	err := protocol.Call(ctx, c.session().conn, "codeAction/resolve", &action, &raw)
+	err = done(err)
+	if err != nil {
+		return CodeAction{}, unsupportedCall("codeAction/resolve", err)
+	}
+	return decodeCodeAction(raw)
+}
+
+// Formatting returns the edits that format a whole file.
+func (c *Client) Formatting(ctx context.Context, file string, opts protocol.FormattingOptions) ([]protocol.TextEdit, error) {
+	if err := requireProvider(protocol.MethodTextDocumentFormatting, c.session().capabilities.DocumentFormattingProvider); err != nil {
+		return nil, err
+	}
+	ctx, done := c.request(ctx, "textDocument/formatting")
+	edits, err := c.session().server.Formatting(ctx, &protocol.DocumentFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(file))},
+		Options:      opts,
+	})
+	err = done(err)
+	return edits, unsupportedCall(protocol.MethodTextDocumentFormatting, err)
+}
+
+// RangeFormatting returns the edits that format lines startLine through
+// endLine of a file.
+// Lines are 1-based (converted to 0-based for LSP).
+func (c *Client) RangeFormatting(ctx context.Context, file string, startLine, endLine int, opts protocol.FormattingOptions) ([]protocol.TextEdit, error) {
+	if startLine < 1 || endLine < startLine {
+		return nil, fmt.Errorf("invalid line range %d-%d", startLine, endLine)
+	}
+	if err := requireProvider(protocol.MethodTextDocumentRangeFormatting, c.session().capabilities.DocumentRangeFormattingProvider); err != nil {
+		return nil, err
+	}
+	ctx, done := c.request(ctx, "textDocument/rangeFormatting")
+	edits, err := c.session().server.RangeFormatting(ctx, &protocol.DocumentRangeFormattingParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(file))},
+		// The range ends at the start of the line after endLine.
+		Range:   makeRange(startLine, 1, endLine+1, 1),
+		Options: opts,
 	})
+	err = done(err)
+	return edits, unsupportedCall(protocol.MethodTextDocumentRangeFormatting, err)
 }
 
 // DocumentSymbol returns the document symbols for a file.
 func (c *Client) DocumentSymbol(ctx context.Context, file string) ([]protocol.DocumentSymbol, error) {
 	docURI := uri.File(file)
-	raw, err := c.server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
+	ctx, done := c.request(ctx, "textDocument/documentSymbol")
+	raw, err := c.session().server.DocumentSymbol(ctx, &protocol.DocumentSymbolParams{
 		TextDocument: protocol.TextDocumentIdentifier{
 			URI: protocol.DocumentURI(docURI),
 		},
 	})
+	err = done(err)
 	if err != nil {
 		return nil, err
 	}
@@ -205,10 +875,105 @@ func (c *Client) DocumentSymbol(ctx context.Context, file string) ([]protocol.Do
 	return symbols, nil
 }
 
+// DocumentHighlight returns the occurrences in file of the symbol at a
+// position, each marked as a read, a write or a textual match.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) DocumentHighlight(ctx context.Context, file string, line, col int) ([]protocol.DocumentHighlight, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	if err := requireProvider(protocol.MethodTextDocumentDocumentHighlight, c.session().capabilities.DocumentHighlightProvider); err != nil {
+		return nil, err
+	}
+	ctx, done := c.request(ctx, "textDocument/documentHighlight")
+	highlights, err := c.session().server.DocumentHighlight(ctx, &protocol.DocumentHighlightParams{
+		TextDocumentPositionParams: makePosition(file, line, col),
+	})
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(protocol.MethodTextDocumentDocumentHighlight, err)
+	}
+	return highlights, nil
+}
+
+// FoldingRange returns the folding ranges of a file, line-based as
+// requested in the client capabilities.
+func (c *Client) FoldingRange(ctx context.Context, file string) ([]protocol.FoldingRange, error) {
+	if err := requireProvider(protocol.MethodTextDocumentFoldingRange, c.session().capabilities.FoldingRangeProvider); err != nil {
+		return nil, err
+	}
+	// protocol.FoldingRangeParams carries a position the request does not
+	// have.
+	params := struct {
+		TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+	}{protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(file))}}
+	var ranges []protocol.FoldingRange
+	ctx, done := c.request(ctx, "textDocument/foldingRange")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodTextDocumentFoldingRange, &params, &ranges)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(protocol.MethodTextDocumentFoldingRange, err)
+	}
+	return ranges, nil
+}
+
+// methodSelectionRange is the request protocol has no constant for.
+const methodSelectionRange = "textDocument/selectionRange"
+
+// SelectionRange returns, for each position of a file, the chain of
+// ranges enclosing it from the innermost out, linked by Parent. The
+// positions are LSP positions (0-based).
+func (c *Client) SelectionRange(ctx context.Context, file string, positions []protocol.Position) ([]protocol.SelectionRange, error) {
+	if err := requireProvider(methodSelectionRange, c.session().capabilities.SelectionRangeProvider); err != nil {
+		return nil, err
+	}
+	var ranges []protocol.SelectionRange
+	ctx, done := c.request(ctx, methodSelectionRange)
+	err := protocol.Call(ctx, c.session().conn, methodSelectionRange, &protocol.SelectionRangeParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(file))},
+		Positions:    positions,
+	}, &ranges)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(methodSelectionRange, err)
+	}
+	return ranges, nil
+}
+
+// WorkspaceSymbol returns the symbols of the loaded projects whose names
+// match query. Both SymbolInformation and WorkspaceSymbol items are
+// accepted; a WorkspaceSymbol without a range gets the start of its file.
+func (c *Client) WorkspaceSymbol(ctx context.Context, query string) ([]protocol.SymbolInformation, error) {
+	var raw []json.RawMessage
+	ctx, done := c.request(ctx, "workspace/symbol")
+	err := protocol.Call(ctx, c.session().conn, protocol.MethodWorkspaceSymbol, &protocol.WorkspaceSymbolParams{Query: query}, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, err
+	}
+
+	var symbols []protocol.SymbolInformation
+	for _, item := range raw {
+		if sym, ok := parseWorkspaceSymbolItem(item); ok {
+			symbols = append(symbols, sym)
+		}
+	}
+	return symbols, nil
+}
+
 // Diagnostic returns diagnostics for a file.
-// It first tries pull diagnostics (textDocument/diagnostic), then falls back
-// to any push diagnostics received via publishDiagnostics.
+// It first tries pull diagnostics (textDocument/diagnostic) when tsgo
+// announced them, then falls back to push diagnostics received via
+// publishDiagnostics, waiting for the first ones to arrive when none have.
 func (c *Client) Diagnostic(ctx context.Context, file string) ([]protocol.Diagnostic, error) {
+	report, err := c.DiagnosticReport(ctx, file, 0)
+	return report.Items, err
+}
+
+// DiagnosticReport is like Diagnostic but waits for push diagnostics of
+// version, the document version the file was synced at, and tells where
+// the diagnostics came from. Version 0 takes any push diagnostics.
+func (c *Client) DiagnosticReport(ctx context.Context, file string, version int32) (FileDiagnostics, error) {
 	docURI := uri.File(file)
 
 	// Try pull diagnostics via raw JSON-RPC call.
@@ -220,44 +985,87 @@ func (c *Client) Diagnostic(ctx context.Context, file string) ([]protocol.Diagno
 		Items []protocol.Diagnostic `json:"items"`
 	}
 
-	var report fullDocumentDiagnosticReport
-	_, err := c.conn.Call(ctx, "textDocument/diagnostic", &documentDiagnosticParams{
-		TextDocument: protocol.TextDocumentIdentifier{
-			URI: protocol.DocumentURI(docURI),
-		},
-	}, &report)
-	if err == nil {
-		return report.Items, nil
+	// A tsgo without pull diagnostics only publishes them.
+	if c.SupportsPullDiagnostics() {
+		var report fullDocumentDiagnosticReport
+		pullCtx, done := c.request(ctx, methodDiagnostic)
+		err := protocol.Call(pullCtx, c.session().conn, methodDiagnostic, &documentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{
+				URI: protocol.DocumentURI(docURI),
+			},
+		}, &report)
+		err = done(err)
+		if err == nil {
+			c.diagMu.Lock()
+			c.analyzed[string(docURI)] = true
+			c.diagMu.Unlock()
+			return FileDiagnostics{Items: report.Items, Source: DiagnosticSourcePull}, nil
+		}
+		if ctx.Err() != nil {
+			return FileDiagnostics{}, ctx.Err()
+		}
 	}
 
-	// Fall back to push diagnostics.
+	// Fall back to push diagnostics. Those of a freshly synced file arrive
+	// once tsgo has checked it; until then the file would look clean.
+	diags, ok := c.pushed.await(ctx, string(docURI), version)
+	return FileDiagnostics{Items: diags, Source: DiagnosticSourcePush, Incomplete: !ok}, nil
+}
+
+// AnalyzedFiles returns the paths of all files the server has reported
+// diagnostics for since startup, sorted.
+func (c *Client) AnalyzedFiles() []string {
 	c.diagMu.Lock()
-	diags := c.diagnostics[string(docURI)]
+	files := make([]string, 0, len(c.analyzed))
+	for u := range c.analyzed {
+		files = append(files, uri.URI(u).Filename())
+	}
 	c.diagMu.Unlock()
-	return diags, nil
+	sort.Strings(files)
+	return files
 }
 
-// Close shuts down the LSP connection and tsgo process.
+// Close shuts down the LSP connection and tsgo process. A startup still in
+// progress is abandoned.
 func (c *Client) Close() error {
+	c.startMu.Lock()
+	c.closed = true
+	ready, cancel := c.ready, c.cancelStart
+	c.startMu.Unlock()
+	select {
+	case <-ready:
+	default:
+		cancel()
+		<-ready
+	}
+	c.startMu.Lock()
+	startErr := c.startErr
+	c.startMu.Unlock()
+	s := c.session()
+	if startErr != nil || s == nil {
+		return nil
+	}
+	s.stopHealth()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	// Send shutdown request (best effort - still try to stop the process).
-	_ = c.server.Shutdown(ctx)
+	_ = s.server.Shutdown(ctx)
 
 	// Send exit notification.
-	_ = c.server.Exit(ctx)
+	_ = s.server.Exit(ctx)
 
 	// Close the JSON-RPC connection.
-	_ = c.conn.Close()
+	_ = s.conn.Close()
 
 	// Stop the process.
-	return c.process.Stop()
+	return s.process.Stop()
 }
 
 // --- protocol.Client implementation (server-initiated callbacks) ---
 
-func (c *Client) Progress(_ context.Context, _ *protocol.ProgressParams) error {
+func (c *Client) Progress(_ context.Context, params *protocol.ProgressParams) error {
+	c.progress.handle(params)
 	return nil
 }
 
@@ -265,13 +1073,15 @@ func (c *Client) WorkDoneProgressCreate(_ context.Context, _ *protocol.WorkDoneP
 	return nil
 }
 
-func (c *Client) LogMessage(_ context.Context, _ *protocol.LogMessageParams) error {
+func (c *Client) LogMessage(_ context.Context, params *protocol.LogMessageParams) error {
+	c.projects.record(ParseProjectLog(params.Message))
 	return nil
 }
 
 func (c *Client) PublishDiagnostics(_ context.Context, params *protocol.PublishDiagnosticsParams) error {
+	c.pushed.record(string(params.URI), int32(params.Version), params.Diagnostics)
 	c.diagMu.Lock()
-	c.diagnostics[string(params.URI)] = params.Diagnostics
+	c.analyzed[string(params.URI)] = true
 	c.diagMu.Unlock()
 	return nil
 }
@@ -296,12 +1106,23 @@ func (c *Client) UnregisterCapability(_ context.Context, _ *protocol.Unregistrat
 	return nil
 }
 
-func (c *Client) ApplyEdit(_ context.Context, _ *protocol.ApplyWorkspaceEditParams) (bool, error) {
-	return false, nil
+// ApplyEdit applies an edit tsgo sent. applyEditHandler answers
+// workspace/applyEdit before it gets here, with the edit's resource
+// operations, which params lacks.
+func (c *Client) ApplyEdit(ctx context.Context, params *protocol.ApplyWorkspaceEditParams) (bool, error) {
+	raw, err := json.Marshal(params.Edit)
+	if err != nil {
+		return false, err
+	}
+	return c.applyEdit(ctx, params.Label, raw).Applied, nil
 }
 
-func (c *Client) Configuration(_ context.Context, _ *protocol.ConfigurationParams) ([]interface{}, error) {
-	return nil, nil
+func (c *Client) Configuration(_ context.Context, params *protocol.ConfigurationParams) ([]interface{}, error) {
+	settings := make([]interface{}, len(params.Items))
+	for i, item := range params.Items {
+		settings[i] = configurationSection(item.Section)
+	}
+	return settings, nil
 }
 
 func (c *Client) WorkspaceFolders(_ context.Context) ([]protocol.WorkspaceFolder, error) {
@@ -348,6 +1169,39 @@ func parseDocumentSymbolItem(item interface{}) (protocol.DocumentSymbol, bool) {
 	return sym, true
 }
 
+// parseWorkspaceSymbolItem parses a single item from the workspace/symbol response.
+// It handles both SymbolInformation and WorkspaceSymbol, whose location may
+// be just a URI.
+func parseWorkspaceSymbolItem(item json.RawMessage) (protocol.SymbolInformation, bool) {
+	var sym struct {
+		Name          string               `json:"name"`
+		Kind          protocol.SymbolKind  `json:"kind"`
+		Tags          []protocol.SymbolTag `json:"tags,omitempty"`
+		Deprecated    bool                 `json:"deprecated,omitempty"`
+		ContainerName string               `json:"containerName,omitempty"`
+		Location      struct {
+			URI   protocol.DocumentURI `json:"uri"`
+			Range *protocol.Range      `json:"range,omitempty"`
+		} `json:"location"`
+	}
+	if err := json.Unmarshal(item, &sym); err != nil || sym.Location.URI == "" {
+		slog.Debug("WorkspaceSymbol: failed to unmarshal item", "error", err)
+		return protocol.SymbolInformation{}, false
+	}
+	si := protocol.SymbolInformation{
+		Name:          sym.Name,
+		Kind:          sym.Kind,
+		Tags:          sym.Tags,
+		Deprecated:    sym.Deprecated,
+		ContainerName: sym.ContainerName,
+		Location:      protocol.Location{URI: sym.Location.URI},
+	}
+	if sym.Location.Range != nil {
+		si.Location.Range = *sym.Location.Range
+	}
+	return si, true
+}
+
 // --- helpers ---
 
 // makePosition creates a TextDocumentPositionParams converting 1-based line/col to 0-based.
@@ -364,6 +1218,14 @@ func makePosition(file string, line, col int) protocol.TextDocumentPositionParam
 	}
 }
 
+// makeRange creates a Range converting 1-based lines and columns to 0-based.
+func makeRange(startLine, startCol, endLine, endCol int) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(startLine - 1), Character: uint32(startCol - 1)},
+		End:   protocol.Position{Line: uint32(endLine - 1), Character: uint32(endCol - 1)},
+	}
+}
+
 // readWriteCloser combines separate reader and writer into io.ReadWriteCloser.
 type readWriteCloser struct {
 	reader io.ReadCloser
@@ -0,0 +1,80 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"go.lsp.dev/protocol"
+)
+
+// decodeCodeActions decodes a (Command | CodeAction)[] result. Edits are
+// decoded as decodeWorkspaceEdit does; an action whose edit cannot be, say
+// because of an unknown resource operation, is kept but disabled with the
+// reason.
+func decodeCodeActions(raw json.RawMessage) ([]CodeAction, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil, nil
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(trimmed, &items); err != nil {
+		return nil, fmt.Errorf("decoding code actions: %w", err)
+	}
+	actions := make([]CodeAction, 0, len(items))
+	for _, item := range items {
+		action, err := decodeCodeAction(item)
+		if err != nil {
+			return nil, fmt.Errorf("decoding code actions: %w", err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
+
+// decodeCodeAction decodes one Command or CodeAction.
+func decodeCodeAction(raw json.RawMessage) (CodeAction, error) {
+	var wire struct {
+		Title       string                      `json:"title"`
+		Kind        protocol.CodeActionKind     `json:"kind,omitempty"`
+		Diagnostics []protocol.Diagnostic       `json:"diagnostics,omitempty"`
+		IsPreferred bool                        `json:"isPreferred,omitempty"`
+		Disabled    *protocol.CodeActionDisable `json:"disabled,omitempty"`
+		Edit        json.RawMessage             `json:"edit,omitempty"`
+		Command     json.RawMessage             `json:"command,omitempty"`
+		Arguments   []any                       `json:"arguments,omitempty"`
+		Data        any                         `json:"data,omitempty"`
+	}
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		return CodeAction{}, err
+	}
+	action := CodeAction{CodeAction: protocol.CodeAction{
+		Title:       wire.Title,
+		Kind:        wire.Kind,
+		Diagnostics: wire.Diagnostics,
+		IsPreferred: wire.IsPreferred,
+		Disabled:    wire.Disabled,
+		Data:        wire.Data,
+	}}
+	// A Command has a string command; a CodeAction's command is an object.
+	var name string
+	if len(wire.Command) > 0 && json.Unmarshal(wire.Command, &name) == nil {
+		action.Command = &protocol.Command{Title: wire.Title, Command: name, Arguments: wire.Arguments}
+		return action, nil
+	}
+	if len(wire.Command) > 0 && string(wire.Command) != "null" {
+		action.Command = new(protocol.Command)
+		if err := json.Unmarshal(wire.Command, action.Command); err != nil {
+			return CodeAction{}, err
+		}
+	}
+	edit, err := decodeWorkspaceEdit(wire.Edit)
+	if err != nil {
+		if action.Disabled == nil {
+			action.Disabled = &protocol.CodeActionDisable{Reason: err.Error()}
+		}
+		return action, nil
+	}
+	action.Edit = edit
+	return action, nil
+}
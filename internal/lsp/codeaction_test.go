@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCodeActions(t *testing.T) {
+	raw := `[
+		{"title": "Organize imports", "command": "_typescript.organizeImports", "arguments": ["/p/a.ts"]},
+		{"title": "Add missing import", "kind": "quickfix", "isPreferred": true, "edit": {"changes": {"file:///p/a.ts": [
+			{"range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 0}}, "newText": "import { x } from \"./x\";\n"}
+		]}}},
+		{"title": "Lazy fix", "kind": "quickfix", "data": {"id": 3}},
+		{"title": "Move to new file", "kind": "refactor.move", "edit": {"documentChanges": [{"kind": "create", "uri": "file:///p/new.ts"}]}},
+		{"title": "Unknown operation", "kind": "refactor", "edit": {"documentChanges": [{"kind": "chmod", "uri": "file:///p/old.ts"}]}},
+		{"title": "With command", "kind": "refactor", "command": {"title": "Rename", "command": "editor.rename"}}
+	]`
+	actions, err := decodeCodeActions(json.RawMessage(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 6 {
+		t.Fatalf("actions = %+v", actions)
+	}
+	if c := actions[0].Command; c == nil || c.Command != "_typescript.organizeImports" || len(c.Arguments) != 1 || actions[0].Edit != nil {
+		t.Errorf("bare command = %+v", actions[0])
+	}
+	if a := actions[1]; !a.IsPreferred || a.Edit == nil || len(a.Edit.Changes["file:///p/a.ts"]) != 1 {
+		t.Errorf("quick fix = %+v", a)
+	}
+	if a := actions[2]; a.Edit != nil || a.Data == nil {
+		t.Errorf("lazy fix = %+v", a)
+	}
+	if a := actions[3]; a.Disabled != nil || a.Edit == nil || len(a.Edit.Operations) != 1 || a.Edit.Operations[0].Kind != "create" {
+		t.Errorf("move = %+v, want its create operation", a)
+	}
+	if a := actions[4]; a.Disabled == nil || !strings.Contains(a.Disabled.Reason, "unsupported chmod operation") {
+		t.Errorf("undecodable edit = %+v, want it disabled", a)
+	}
+	if c := actions[5].Command; c == nil || c.Command != "editor.rename" {
+		t.Errorf("action command = %+v", actions[5])
+	}
+
+	if actions, err := decodeCodeActions(json.RawMessage("null")); actions != nil || err != nil {
+		t.Errorf("null = %+v, %v", actions, err)
+	}
+}
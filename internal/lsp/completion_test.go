@@ -0,0 +1,33 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeCompletion(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		labels     int
+		incomplete bool
+	}{
+		{"null", "null", 0, false},
+		{"items", `[{"label": "a"}, {"label": "b"}]`, 2, false},
+		{"list", `{"isIncomplete": true, "items": [{"label": "a"}]}`, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := decodeCompletion(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.Items) != tt.labels || list.IsIncomplete != tt.incomplete {
+				t.Errorf("list = %+v", list)
+			}
+		})
+	}
+	if _, err := decodeCompletion(json.RawMessage(`"nope"`)); err == nil {
+		t.Error("want an error for a string result")
+	}
+}
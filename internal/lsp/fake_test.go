@@ -0,0 +1,100 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// fakeTsgo answers the initialize handshake over pipes in place of tsgo.
+// Requests for the hold method get no answer; their ids are sent on held,
+// and those of $/cancelRequest on cancelled.
+type fakeTsgo struct {
+	mu      sync.Mutex
+	spawned int
+	conn    jsonrpc2.Conn // the server side of the latest spawn
+	fail    bool
+	// capabilities are announced at initialize; nil announces
+	// fakeCapabilities.
+	capabilities map[string]any
+
+	hold      string
+	held      chan jsonrpc2.ID
+	cancelled chan jsonrpc2.ID
+}
+
+// fakeCapabilities are the capabilities of the requests the tests send.
+var fakeCapabilities = map[string]any{
+	"hoverProvider":      true,
+	"referencesProvider": true,
+	"diagnosticProvider": map[string]any{"interFileDependencies": true},
+}
+
+func (f *fakeTsgo) start(context.Context) (*TsgoProcess, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.spawned++
+	if f.fail {
+		return nil, errors.New("tsgo not found")
+	}
+	cmd := exec.Command("true")
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	toServerR, toServerW := io.Pipe()
+	toClientR, toClientW := io.Pipe()
+	f.conn = jsonrpc2.NewConn(jsonrpc2.NewStream(&readWriteCloser{reader: toServerR, writer: toClientW}))
+	f.conn.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		switch req.Method() {
+		case protocol.MethodInitialize:
+			caps := f.capabilities
+			if caps == nil {
+				caps = fakeCapabilities
+			}
+			return reply(ctx, map[string]any{"capabilities": caps, "serverInfo": map[string]any{"name": "fake-tsgo", "version": "7.0.0-dev"}}, nil)
+		case f.hold:
+			if call, ok := req.(*jsonrpc2.Call); ok {
+				f.held <- call.ID()
+				return nil
+			}
+		case protocol.MethodCancelRequest:
+			var params struct {
+				ID int32 `json:"id"`
+			}
+			if err := json.Unmarshal(req.Params(), &params); err == nil && f.cancelled != nil {
+				f.cancelled <- jsonrpc2.NewNumberID(params.ID)
+			}
+		}
+		return reply(ctx, nil, nil)
+	})
+	return &TsgoProcess{cmd: cmd, stdin: toServerW, stdout: toClientR}, nil
+}
+
+// crash ends the connection of the running fake as an exiting tsgo would,
+// and waits for c to see it.
+func (f *fakeTsgo) crash(t *testing.T, c *Client) {
+	t.Helper()
+	s := c.session()
+	f.mu.Lock()
+	_ = f.conn.Close()
+	f.mu.Unlock()
+	select {
+	case <-s.conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client did not see tsgo exit")
+	}
+}
+
+func (f *fakeTsgo) spawns() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.spawned
+}
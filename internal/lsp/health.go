@@ -0,0 +1,290 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// Health monitor defaults. The interval can be changed with
+// TYPESCRIPT_MCP_HEALTH_INTERVAL; "0" turns the monitor off.
+const (
+	defaultHealthInterval  = 30 * time.Second
+	defaultHealthTimeout   = 10 * time.Second
+	defaultHealthThreshold = 3
+	defaultHealthIdle      = 5 * time.Minute
+)
+
+// Health monitor states reported by HealthStatus.
+const (
+	HealthOK       = "ok"
+	HealthPaused   = "paused"
+	HealthDegraded = "degraded"
+	HealthWedged   = "wedged"
+	HealthDisabled = "disabled"
+)
+
+// healthConfig controls the health monitor.
+type healthConfig struct {
+	// Interval is the time between probes; 0 disables the monitor.
+	Interval time.Duration
+	// Timeout bounds each probe, and is how long a regular request may
+	// run before it counts as timing out.
+	Timeout time.Duration
+	// Threshold is the number of consecutive probe timeouts that, with
+	// regular requests also timing out, marks tsgo as wedged.
+	Threshold int
+	// Idle pauses probing when no request was made for this long.
+	Idle time.Duration
+}
+
+// healthConfigFromEnv returns the default config with the interval from
+// TYPESCRIPT_MCP_HEALTH_INTERVAL, a Go duration such as "30s".
+func healthConfigFromEnv() healthConfig {
+	cfg := healthConfig{
+		Interval:  defaultHealthInterval,
+		Timeout:   defaultHealthTimeout,
+		Threshold: defaultHealthThreshold,
+		Idle:      defaultHealthIdle,
+	}
+	v := os.Getenv("TYPESCRIPT_MCP_HEALTH_INTERVAL")
+	if v == "" {
+		return cfg
+	}
+	if v == "0" {
+		cfg.Interval = 0
+		return cfg
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		slog.Warn("ignoring invalid TYPESCRIPT_MCP_HEALTH_INTERVAL", "value", v)
+		return cfg
+	}
+	cfg.Interval = d
+	return cfg
+}
+
+// InFlightRequest is a request to tsgo that has not been answered yet.
+type InFlightRequest struct {
+	Method string `json:"method"`
+	Age    string `json:"age"`
+}
+
+// WedgeReport is the diagnosis logged when tsgo is declared wedged.
+type WedgeReport struct {
+	Failures   int
+	InFlight   []InFlightRequest
+	LastStderr string
+}
+
+// HealthStatus is the health monitor's state for ts_server_status.
+type HealthStatus struct {
+	State               string            `json:"state"`
+	ConsecutiveFailures int               `json:"consecutiveFailures,omitempty"`
+	LastProbe           string            `json:"lastProbe,omitempty"` // time since the last probe
+	InFlight            []InFlightRequest `json:"inFlight,omitempty"`
+}
+
+type inFlight struct {
+	method string
+	start  time.Time
+}
+
+// healthMonitor probes tsgo periodically and declares it wedged when
+// probes and regular requests both keep timing out, which a deadlocked
+// tsgo shows while its process and pipes stay alive.
+type healthMonitor struct {
+	cfg   healthConfig
+	probe func(ctx context.Context) error
+	// onWedged runs once when tsgo is declared wedged.
+	onWedged func(WedgeReport)
+	// stderr returns the tail of tsgo's stderr for the report.
+	stderr func() string
+	now    func() time.Time
+
+	mu             sync.Mutex
+	nextID         uint64
+	inFlight       map[uint64]inFlight
+	lastRequest    time.Time
+	lastResponse   time.Time // last regular request that did not time out
+	lastTimeout    time.Time // last regular request that timed out
+	lastProbe      time.Time
+	failures       int
+	paused, wedged bool
+}
+
+func newHealthMonitor(cfg healthConfig, probe func(context.Context) error, onWedged func(WedgeReport)) *healthMonitor {
+	return &healthMonitor{
+		cfg:      cfg,
+		probe:    probe,
+		onWedged: onWedged,
+		now:      time.Now,
+		inFlight: make(map[uint64]inFlight),
+	}
+}
+
+// begin records the start of a regular request; the returned function
+// records its outcome.
+func (m *healthMonitor) begin(method string) func(error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := m.nextID
+	now := m.now()
+	m.inFlight[id] = inFlight{method: method, start: now}
+	m.lastRequest = now
+	return func(err error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.inFlight, id)
+		if errors.Is(err, context.DeadlineExceeded) {
+			m.lastTimeout = m.now()
+		} else {
+			m.lastResponse = m.now()
+		}
+	}
+}
+
+// run probes every cfg.Interval until ctx is done.
+func (m *healthMonitor) run(ctx context.Context) {
+	if m.cfg.Interval <= 0 {
+		return
+	}
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.tick(ctx)
+		}
+	}
+}
+
+// tick runs one probe unless the monitor is idle or has already declared
+// tsgo wedged.
+func (m *healthMonitor) tick(ctx context.Context) {
+	m.mu.Lock()
+	if m.wedged {
+		m.mu.Unlock()
+		return
+	}
+	m.paused = len(m.inFlight) == 0 && m.now().Sub(m.lastRequest) > m.cfg.Idle
+	if m.paused {
+		// A quiet server proves nothing; start counting afresh on wake.
+		m.failures = 0
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+
+	// Any answer, even an error response, shows tsgo is reading requests;
+	// only a timeout counts against it.
+	pctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	_ = m.probe(pctx)
+	timedOut := pctx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	cancel()
+
+	m.mu.Lock()
+	m.lastProbe = m.now()
+	if !timedOut {
+		m.failures = 0
+		m.mu.Unlock()
+		return
+	}
+	m.failures++
+	if m.failures < m.cfg.Threshold || !m.requestsTimingOutLocked() {
+		m.mu.Unlock()
+		return
+	}
+	m.wedged = true
+	report := WedgeReport{Failures: m.failures, InFlight: m.inFlightLocked()}
+	m.mu.Unlock()
+
+	if m.stderr != nil {
+		report.LastStderr = m.stderr()
+	}
+	slog.Error("tsgo stopped responding", "probeTimeouts", report.Failures,
+		"inFlight", fmt.Sprint(report.InFlight), "lastStderr", report.LastStderr)
+	if m.onWedged != nil {
+		m.onWedged(report)
+	}
+}
+
+// requestsTimingOutLocked reports whether regular requests are failing
+// too: one has been in flight longer than the probe timeout, or the last
+// one to finish timed out.
+func (m *healthMonitor) requestsTimingOutLocked() bool {
+	now := m.now()
+	for _, r := range m.inFlight {
+		if now.Sub(r.start) > m.cfg.Timeout {
+			return true
+		}
+	}
+	return m.lastTimeout.After(m.lastResponse)
+}
+
+// inFlightLocked lists the unanswered requests, oldest first.
+func (m *healthMonitor) inFlightLocked() []InFlightRequest {
+	reqs := make([]inFlight, 0, len(m.inFlight))
+	for _, r := range m.inFlight {
+		reqs = append(reqs, r)
+	}
+	sort.Slice(reqs, func(i, j int) bool { return reqs[i].start.Before(reqs[j].start) })
+	now := m.now()
+	out := make([]InFlightRequest, len(reqs))
+	for i, r := range reqs {
+		out[i] = InFlightRequest{Method: r.method, Age: now.Sub(r.start).Round(time.Millisecond).String()}
+	}
+	return out
+}
+
+// status returns the monitor's state.
+func (m *healthMonitor) status() HealthStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := HealthStatus{ConsecutiveFailures: m.failures, InFlight: m.inFlightLocked()}
+	switch {
+	case m.cfg.Interval <= 0:
+		st.State = HealthDisabled
+	case m.wedged:
+		st.State = HealthWedged
+	case m.paused:
+		st.State = HealthPaused
+	case m.failures > 0:
+		st.State = HealthDegraded
+	default:
+		st.State = HealthOK
+	}
+	if !m.lastProbe.IsZero() {
+		st.LastProbe = m.now().Sub(m.lastProbe).Round(time.Second).String()
+	}
+	return st
+}
+
+// healthProbeURI is a document that is never opened; hovering over it
+// costs tsgo almost nothing, and any reply shows it still serves requests.
+const healthProbeURI = "untitled:typescript-mcp-health-probe.ts"
+
+// probe sends the health probe request.
+func (s *tsgoSession) probe(ctx context.Context) error {
+	_, err := s.server.Hover(ctx, &protocol.HoverParams{
+		TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: healthProbeURI},
+		},
+	})
+	return err
+}
+
+// Health returns the state of the health monitor.
+func (c *Client) Health() HealthStatus {
+	return c.session().health.status()
+}
@@ -0,0 +1,174 @@
+package lsp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBackend answers probes at once until hung, then never answers.
+type fakeBackend struct {
+	hung atomic.Bool
+}
+
+func (b *fakeBackend) probe(ctx context.Context) error {
+	if b.hung.Load() {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func newTestMonitor(b *fakeBackend) (*healthMonitor, *time.Time, *[]WedgeReport) {
+	now := time.Unix(1_700_000_000, 0)
+	var reports []WedgeReport
+	m := newHealthMonitor(healthConfig{
+		Interval:  time.Second,
+		Timeout:   5 * time.Millisecond,
+		Threshold: 3,
+		Idle:      time.Minute,
+	}, b.probe, func(r WedgeReport) { reports = append(reports, r) })
+	m.now = func() time.Time { return now }
+	m.stderr = func() string { return "panic: deadlock" }
+	return m, &now, &reports
+}
+
+func TestHealthMonitorDetectsWedge(t *testing.T) {
+	b := &fakeBackend{}
+	m, now, reports := newTestMonitor(b)
+	ctx := context.Background()
+
+	m.begin("textDocument/hover")(nil)
+	m.tick(ctx)
+	if st := m.status(); st.State != HealthOK {
+		t.Fatalf("state = %q, want ok", st.State)
+	}
+
+	b.hung.Store(true)
+	done := m.begin("textDocument/references") // never answered
+	*now = now.Add(time.Second)
+	for i := 1; i <= 2; i++ {
+		m.tick(ctx)
+		if st := m.status(); st.State != HealthDegraded || st.ConsecutiveFailures != i {
+			t.Fatalf("after %d timeouts: %+v, want degraded", i, st)
+		}
+	}
+	if len(*reports) != 0 {
+		t.Fatal("declared wedged below the threshold")
+	}
+	m.tick(ctx)
+	if len(*reports) != 1 {
+		t.Fatalf("recovery triggered %d times, want once", len(*reports))
+	}
+	r := (*reports)[0]
+	if r.Failures != 3 || len(r.InFlight) != 1 || r.InFlight[0].Method != "textDocument/references" || r.LastStderr != "panic: deadlock" {
+		t.Errorf("report = %+v", r)
+	}
+	if st := m.status(); st.State != HealthWedged {
+		t.Errorf("state = %q, want wedged", st.State)
+	}
+	m.tick(ctx)
+	if len(*reports) != 1 {
+		t.Error("recovery triggered again after the wedge was declared")
+	}
+	done(context.DeadlineExceeded)
+}
+
+func TestHealthMonitorNeedsTimingOutRequests(t *testing.T) {
+	b := &fakeBackend{}
+	m, now, reports := newTestMonitor(b)
+	ctx := context.Background()
+
+	// Regular requests keep being answered: slow probes alone are not a
+	// wedge.
+	b.hung.Store(true)
+	for range 5 {
+		m.begin("textDocument/hover")(nil)
+		*now = now.Add(time.Second)
+		m.tick(ctx)
+	}
+	if len(*reports) != 0 {
+		t.Fatal("declared wedged while requests were answered")
+	}
+
+	// Once a regular request times out too, the next probe timeout past
+	// the threshold declares the wedge.
+	m.begin("textDocument/diagnostic")(context.DeadlineExceeded)
+	m.tick(ctx)
+	if len(*reports) != 1 {
+		t.Fatalf("recovery triggered %d times, want once", len(*reports))
+	}
+}
+
+func TestHealthMonitorIdlePause(t *testing.T) {
+	b := &fakeBackend{}
+	m, now, reports := newTestMonitor(b)
+	ctx := context.Background()
+	probes := 0
+	m.probe = func(ctx context.Context) error { probes++; return b.probe(ctx) }
+
+	// No request yet: nothing to probe for.
+	m.tick(ctx)
+	if probes != 0 || m.status().State != HealthPaused {
+		t.Fatalf("probed %d times before any request, state %q", probes, m.status().State)
+	}
+
+	m.begin("textDocument/hover")(context.DeadlineExceeded)
+	b.hung.Store(true)
+	m.tick(ctx)
+	m.tick(ctx)
+	if probes != 2 {
+		t.Fatalf("probes = %d, want 2", probes)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	m.tick(ctx)
+	if probes != 2 || m.status().State != HealthPaused {
+		t.Errorf("probed while idle: %d probes, state %q", probes, m.status().State)
+	}
+	if m.status().ConsecutiveFailures != 0 {
+		t.Error("failures survived the idle pause")
+	}
+
+	// Waking up starts the count afresh.
+	m.begin("textDocument/hover")(context.DeadlineExceeded)
+	m.tick(ctx)
+	m.tick(ctx)
+	if len(*reports) != 0 {
+		t.Error("declared wedged with failures from before the pause")
+	}
+	m.tick(ctx)
+	if len(*reports) != 1 {
+		t.Errorf("recovery triggered %d times, want once", len(*reports))
+	}
+}
+
+func TestHealthMonitorDisabled(t *testing.T) {
+	m := newHealthMonitor(healthConfig{}, nil, nil)
+	if st := m.status(); st.State != HealthDisabled {
+		t.Errorf("state = %q, want disabled", st.State)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.run(ctx) // returns at once
+}
+
+func TestHealthConfigFromEnv(t *testing.T) {
+	t.Setenv("TYPESCRIPT_MCP_HEALTH_INTERVAL", "")
+	if got := healthConfigFromEnv().Interval; got != defaultHealthInterval {
+		t.Errorf("default interval = %v", got)
+	}
+	t.Setenv("TYPESCRIPT_MCP_HEALTH_INTERVAL", "5s")
+	if got := healthConfigFromEnv().Interval; got != 5*time.Second {
+		t.Errorf("interval = %v, want 5s", got)
+	}
+	t.Setenv("TYPESCRIPT_MCP_HEALTH_INTERVAL", "0")
+	if got := healthConfigFromEnv().Interval; got != 0 {
+		t.Errorf("interval = %v, want 0", got)
+	}
+	t.Setenv("TYPESCRIPT_MCP_HEALTH_INTERVAL", "soon")
+	if got := healthConfigFromEnv().Interval; got != defaultHealthInterval {
+		t.Errorf("invalid value gave %v", got)
+	}
+}
@@ -0,0 +1,157 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// methodInlayHint is the LSP 3.17 request protocol does not define.
+const methodInlayHint = "textDocument/inlayHint"
+
+// InlayHintKind is the kind of an inlay hint.
+type InlayHintKind int
+
+const (
+	InlayHintKindType      InlayHintKind = 1
+	InlayHintKindParameter InlayHintKind = 2
+)
+
+// InlayHint is an inlay hint with its label parts joined into Label.
+type InlayHint struct {
+	Position     protocol.Position
+	Label        string
+	Kind         InlayHintKind
+	PaddingLeft  bool
+	PaddingRight bool
+}
+
+// inlayHintSettings turns on every inlay hint in tsgo's user preferences,
+// which has them all off by default. It has the shape of the "typescript"
+// and "javascript" settings sections of an editor.
+var inlayHintSettings = map[string]any{
+	"inlayHints": map[string]any{
+		"parameterNames":           map[string]any{"enabled": "all", "suppressWhenArgumentMatchesName": true},
+		"parameterTypes":           map[string]any{"enabled": true},
+		"variableTypes":            map[string]any{"enabled": true, "suppressWhenTypeMatchesName": true},
+		"propertyDeclarationTypes": map[string]any{"enabled": true},
+		"functionLikeReturnTypes":  map[string]any{"enabled": true},
+		"enumMemberValues":         map[string]any{"enabled": true},
+	},
+}
+
+// configurationSection returns the settings of a workspace/configuration
+// section: the inlay hint settings for the TypeScript and JavaScript
+// sections, nothing for others.
+func configurationSection(section string) any {
+	switch section {
+	case "typescript", "javascript", "js/ts":
+		return inlayHintSettings
+	}
+	return nil
+}
+
+// withExtraCapabilities encodes params with the client capabilities that
+// protocol.ClientCapabilities, which predates LSP 3.17, has no fields for.
+func withExtraCapabilities(params *protocol.InitializeParams) (json.RawMessage, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var wire map[string]any
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return nil, err
+	}
+	caps, _ := wire["capabilities"].(map[string]any)
+	if caps == nil {
+		caps = map[string]any{}
+		wire["capabilities"] = caps
+	}
+	textDocument, _ := caps["textDocument"].(map[string]any)
+	if textDocument == nil {
+		textDocument = map[string]any{}
+		caps["textDocument"] = textDocument
+	}
+	textDocument["inlayHint"] = map[string]any{"dynamicRegistration": false}
+	textDocument["typeHierarchy"] = map[string]any{"dynamicRegistration": false}
+	return json.Marshal(wire)
+}
+
+// decodeInlayHints decodes an InlayHint[] or null result.
+func decodeInlayHints(raw json.RawMessage) ([]InlayHint, error) {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil, nil
+	}
+	var wire []struct {
+		Position     protocol.Position `json:"position"`
+		Label        json.RawMessage   `json:"label"`
+		Kind         InlayHintKind     `json:"kind,omitempty"`
+		PaddingLeft  bool              `json:"paddingLeft,omitempty"`
+		PaddingRight bool              `json:"paddingRight,omitempty"`
+	}
+	if err := json.Unmarshal(trimmed, &wire); err != nil {
+		return nil, fmt.Errorf("decoding inlay hints: %w", err)
+	}
+	hints := make([]InlayHint, len(wire))
+	for i, w := range wire {
+		label, err := inlayHintLabel(w.Label)
+		if err != nil {
+			return nil, fmt.Errorf("decoding inlay hints: %w", err)
+		}
+		hints[i] = InlayHint{Position: w.Position, Label: label, Kind: w.Kind, PaddingLeft: w.PaddingLeft, PaddingRight: w.PaddingRight}
+	}
+	return hints, nil
+}
+
+// inlayHintLabel decodes a label, which is a string or an array of label
+// parts whose values are joined.
+func inlayHintLabel(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString(p.Value)
+	}
+	return b.String(), nil
+}
+
+// InlayHint returns the inlay hints of a range of a file. It fails with
+// ErrUnsupported when tsgo does not provide inlay hints.
+// Lines and columns are 1-based (converted to 0-based for LSP).
+func (c *Client) InlayHint(ctx context.Context, file string, startLine, startCol, endLine, endCol int) ([]InlayHint, error) {
+	if startLine < 1 || startCol < 1 || endLine < 1 || endCol < 1 {
+		return nil, fmt.Errorf("lines and columns must be >= 1, got %d:%d-%d:%d", startLine, startCol, endLine, endCol)
+	}
+	if err := requireProvider(methodInlayHint, c.session().inlayHintProvider); err != nil {
+		return nil, err
+	}
+	params := struct {
+		TextDocument protocol.TextDocumentIdentifier `json:"textDocument"`
+		Range        protocol.Range                  `json:"range"`
+	}{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(file))},
+		Range:        makeRange(startLine, startCol, endLine, endCol),
+	}
+	var raw json.RawMessage
+	ctx, done := c.request(ctx, methodInlayHint)
+	err := protocol.Call(ctx, c.session().conn, methodInlayHint, &params, &raw)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(methodInlayHint, err)
+	}
+	return decodeInlayHints(raw)
+}
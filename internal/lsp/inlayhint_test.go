@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestDecodeInlayHints(t *testing.T) {
+	raw := json.RawMessage(`[
+		{"position": {"line": 2, "character": 12}, "label": ": string", "kind": 1, "paddingLeft": true},
+		{"position": {"line": 2, "character": 21}, "label": [{"value": "name"}, {"value": ":"}], "kind": 2, "paddingRight": true}
+	]`)
+	hints, err := decodeInlayHints(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hints) != 2 {
+		t.Fatalf("hints = %+v", hints)
+	}
+	if h := hints[0]; h.Label != ": string" || h.Kind != InlayHintKindType || h.Position.Character != 12 || !h.PaddingLeft {
+		t.Errorf("type hint = %+v", h)
+	}
+	if h := hints[1]; h.Label != "name:" || h.Kind != InlayHintKindParameter || !h.PaddingRight {
+		t.Errorf("parameter hint = %+v", h)
+	}
+
+	if hints, err := decodeInlayHints(json.RawMessage("null")); err != nil || hints != nil {
+		t.Errorf("null = %v, %v", hints, err)
+	}
+	if _, err := decodeInlayHints(json.RawMessage(`[{"label": 3}]`)); err == nil {
+		t.Error("expected an error for a numeric label")
+	}
+}
+
+func TestWithExtraCapabilities(t *testing.T) {
+	raw, err := withExtraCapabilities(&protocol.InitializeParams{
+		Capabilities: protocol.ClientCapabilities{
+			TextDocument: &protocol.TextDocumentClientCapabilities{
+				Hover: &protocol.HoverTextDocumentClientCapabilities{ContentFormat: []protocol.MarkupKind{protocol.Markdown}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wire struct {
+		Capabilities struct {
+			TextDocument map[string]json.RawMessage `json:"textDocument"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"inlayHint", "typeHierarchy"} {
+		if _, ok := wire.Capabilities.TextDocument[name]; !ok {
+			t.Errorf("textDocument capabilities lack %s: %s", name, raw)
+		}
+	}
+	if _, ok := wire.Capabilities.TextDocument["hover"]; !ok {
+		t.Errorf("textDocument capabilities lost hover: %s", raw)
+	}
+}
+
+func TestConfigurationSection(t *testing.T) {
+	if configurationSection("typescript") == nil || configurationSection("javascript") == nil {
+		t.Error("expected inlay hint settings for the typescript and javascript sections")
+	}
+	if got := configurationSection("editor"); got != nil {
+		t.Errorf("editor section = %v, want nil", got)
+	}
+}
@@ -0,0 +1,44 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestDecodeLocations(t *testing.T) {
+	at := func(uri string, line uint32) protocol.Location {
+		return protocol.Location{URI: protocol.DocumentURI(uri), Range: protocol.Range{
+			Start: protocol.Position{Line: line, Character: 2},
+			End:   protocol.Position{Line: line, Character: 6},
+		}}
+	}
+	rng := `{"start": {"line": 6, "character": 2}, "end": {"line": 6, "character": 6}}`
+	tests := []struct {
+		name string
+		raw  string
+		want []protocol.Location
+	}{
+		{"null", "null", nil},
+		{"single location", `{"uri": "file:///p/a.ts", "range": ` + rng + `}`, []protocol.Location{at("file:///p/a.ts", 6)}},
+		{"locations", `[{"uri": "file:///p/a.ts", "range": ` + rng + `}, {"uri": "file:///p/b.ts", "range": ` + rng + `}]`, []protocol.Location{at("file:///p/a.ts", 6), at("file:///p/b.ts", 6)}},
+		{"location links", `[{"targetUri": "file:///p/c.ts", "targetRange": {"start": {"line": 5, "character": 0}, "end": {"line": 9, "character": 1}}, "targetSelectionRange": ` + rng + `}]`, []protocol.Location{at("file:///p/c.ts", 6)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeLocations(json.RawMessage(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("location %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func TestDecodePrepareRename(t *testing.T) {
+	want := protocol.Range{Start: protocol.Position{Line: 2, Character: 6}, End: protocol.Position{Line: 2, Character: 12}}
+	for name, raw := range map[string]string{
+		"range":       `{"start": {"line": 2, "character": 6}, "end": {"line": 2, "character": 12}}`,
+		"placeholder": `{"range": {"start": {"line": 2, "character": 6}, "end": {"line": 2, "character": 12}}, "placeholder": "result"}`,
+	} {
+		got, err := decodePrepareRename(json.RawMessage(raw))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.Range != want || got.Reason != "" {
+			t.Errorf("%s = %+v", name, got)
+		}
+	}
+
+	got, err := decodePrepareRename(json.RawMessage(`{"defaultBehavior": true}`))
+	if err != nil || !got.DefaultBehavior || got.Reason != "" {
+		t.Errorf("defaultBehavior = %+v, %v", got, err)
+	}
+	if got, err := decodePrepareRename(json.RawMessage("null")); err != nil || got.Reason == "" {
+		t.Errorf("null = %+v, %v, want a refusal", got, err)
+	}
+}
+
+func TestRenameRefusal(t *testing.T) {
+	// -32803 is RequestFailed, which protocol predates.
+	refused := fmt.Errorf("call: %w", jsonrpc2.NewError(-32803, "You cannot rename this element."))
+	if reason, ok := renameRefusal(refused); !ok || reason != "You cannot rename this element." {
+		t.Errorf("request failed = %q, %v", reason, ok)
+	}
+	for _, err := range []error{
+		jsonrpc2.NewError(jsonrpc2.MethodNotFound, "unhandled method"),
+		jsonrpc2.NewError(protocol.CodeRequestCancelled, "cancelled"),
+		context.DeadlineExceeded,
+	} {
+		if _, ok := renameRefusal(err); ok {
+			t.Errorf("%v is not a refusal", err)
+		}
+	}
+}
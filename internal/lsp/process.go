@@ -8,23 +8,45 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
+// stderrTailSize is how much of tsgo's recent stderr is kept for
+// diagnosing a hang.
+const stderrTailSize = 4096
+
 // TsgoProcess manages a running tsgo --lsp --stdio process.
 type TsgoProcess struct {
 	cmd    *exec.Cmd
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
 	stderr io.ReadCloser
+
+	// version is the tsgo version detected at startup, empty if unknown.
+	version string
+	// versionWarning describes a tolerated version mismatch.
+	versionWarning string
+
+	tailMu sync.Mutex
+	tail   []byte // the last stderrTailSize bytes of stderr
+
+	stopOnce sync.Once
+	stopErr  error
 }
 
 // StartTsgo spawns tsgo --lsp --stdio and returns a handle to the process.
+// It refuses to start a tsgo outside TYPESCRIPT_MCP_TSGO_VERSION unless
+// TYPESCRIPT_MCP_TSGO_VERSION_WARN_ONLY is set.
 func StartTsgo(ctx context.Context) (*TsgoProcess, error) {
-	bin, err := resolveTsgo()
+	bin, err := ResolveTsgo()
 	if err != nil {
 		return nil, fmt.Errorf("resolve tsgo: %w", err)
 	}
+	version, versionWarning, err := checkTsgoVersion(ctx, bin)
+	if err != nil {
+		return nil, err
+	}
 
 	cmd := exec.CommandContext(ctx, bin, "--lsp", "--stdio")
 	cmd.Env = os.Environ()
@@ -51,6 +73,9 @@ func StartTsgo(ctx context.Context) (*TsgoProcess, error) {
 		stdin:  stdin,
 		stdout: stdout,
 		stderr: stderr,
+
+		version:        version,
+		versionWarning: versionWarning,
 	}
 
 	// Drain stderr to logger in background.
@@ -61,7 +86,13 @@ func StartTsgo(ctx context.Context) (*TsgoProcess, error) {
 
 // Stop gracefully shuts down the tsgo process.
 // It closes stdin and waits for the process to exit, killing it after a timeout.
+// Later calls return the result of the first.
 func (p *TsgoProcess) Stop() error {
+	p.stopOnce.Do(func() { p.stopErr = p.stop() })
+	return p.stopErr
+}
+
+func (p *TsgoProcess) stop() error {
 	// Close stdin to signal EOF.
 	_ = p.stdin.Close()
 
@@ -84,12 +115,30 @@ func (p *TsgoProcess) Stop() error {
 	}
 }
 
+// StderrTail returns the most recent stderr output of tsgo.
+func (p *TsgoProcess) StderrTail() string {
+	p.tailMu.Lock()
+	defer p.tailMu.Unlock()
+	return string(p.tail)
+}
+
+// Kill ends the tsgo process at once, failing every pending request.
+func (p *TsgoProcess) Kill() error {
+	return p.cmd.Process.Kill()
+}
+
 func (p *TsgoProcess) drainStderr() {
 	buf := make([]byte, 4096)
 	for {
 		n, err := p.stderr.Read(buf)
 		if n > 0 {
 			slog.Debug("tsgo stderr", "output", string(buf[:n]))
+			p.tailMu.Lock()
+			p.tail = append(p.tail, buf[:n]...)
+			if len(p.tail) > stderrTailSize {
+				p.tail = p.tail[len(p.tail)-stderrTailSize:]
+			}
+			p.tailMu.Unlock()
 		}
 		if err != nil {
 			return
@@ -97,8 +146,9 @@ func (p *TsgoProcess) drainStderr() {
 	}
 }
 
-// resolveTsgo finds the tsgo binary, checking PATH first then common locations.
-func resolveTsgo() (string, error) {
+// ResolveTsgo finds the tsgo binary, checking PATH first then common locations.
+// The error explains how to install tsgo.
+func ResolveTsgo() (string, error) {
 	// Check PATH first.
 	if path, err := exec.LookPath("tsgo"); err == nil {
 		return path, nil
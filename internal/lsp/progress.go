@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// Project load wait defaults. The maximum wait can be changed with
+// TYPESCRIPT_MCP_PROJECT_LOAD_WAIT.
+const (
+	defaultLoadMaxWait = 20 * time.Second
+	// defaultLoadQuiet is how long loading progress must stay absent
+	// before the project counts as loaded.
+	defaultLoadQuiet = 500 * time.Millisecond
+	// defaultLoadStale drops a loading task that sent nothing for this
+	// long, for servers that never send the end event.
+	defaultLoadStale = 30 * time.Second
+)
+
+// loadingTitleWords mark a progress task as project loading when its title
+// or a report message contains one of them.
+var loadingTitleWords = []string{"load", "program", "project", "build"}
+
+// loadConfig controls WaitForProjectLoad.
+type loadConfig struct {
+	MaxWait time.Duration
+	Quiet   time.Duration
+	Stale   time.Duration
+}
+
+// loadConfigFromEnv returns the default config with the maximum wait from
+// TYPESCRIPT_MCP_PROJECT_LOAD_WAIT, a Go duration such as "20s".
+func loadConfigFromEnv() loadConfig {
+	cfg := loadConfig{MaxWait: defaultLoadMaxWait, Quiet: defaultLoadQuiet, Stale: defaultLoadStale}
+	v := os.Getenv("TYPESCRIPT_MCP_PROJECT_LOAD_WAIT")
+	if v == "" {
+		return cfg
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		slog.Warn("ignoring invalid TYPESCRIPT_MCP_PROJECT_LOAD_WAIT", "value", v)
+		return cfg
+	}
+	cfg.MaxWait = d
+	return cfg
+}
+
+// progressTask is a work-done progress the server began and has not ended.
+type progressTask struct {
+	loading bool
+	// last is the time of the task's latest event.
+	last time.Time
+}
+
+// progressTracker follows the server's $/progress notifications and tells
+// when project loading has gone quiet.
+type progressTracker struct {
+	cfg loadConfig
+	now func() time.Time
+
+	mu     sync.Mutex
+	active map[string]*progressTask // token -> task
+	// lastLoading is the time of the latest event of a loading task.
+	lastLoading time.Time
+	// changed is closed and replaced on every event, waking waiters.
+	changed chan struct{}
+}
+
+func newProgressTracker(cfg loadConfig) *progressTracker {
+	return &progressTracker{
+		cfg:     cfg,
+		now:     time.Now,
+		active:  make(map[string]*progressTask),
+		changed: make(chan struct{}),
+	}
+}
+
+// progressValue is the part of a begin, report or end value the tracker
+// reads.
+type progressValue struct {
+	Kind    protocol.WorkDoneProgressKind `json:"kind"`
+	Title   string                        `json:"title"`
+	Message string                        `json:"message"`
+}
+
+// handle records one $/progress notification. Values other than
+// work-done progress are ignored.
+func (p *progressTracker) handle(params *protocol.ProgressParams) {
+	data, err := json.Marshal(params.Value)
+	if err != nil {
+		return
+	}
+	var v progressValue
+	if err := json.Unmarshal(data, &v); err != nil {
+		return
+	}
+	p.event(params.Token.String(), v)
+}
+
+// event applies one begin, report or end to the task named token.
+func (p *progressTracker) event(token string, v progressValue) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	task := p.active[token]
+	switch v.Kind {
+	case protocol.WorkDoneProgressKindBegin:
+		task = &progressTask{loading: isLoadingProgress(v.Title) || isLoadingProgress(v.Message)}
+		p.active[token] = task
+	case protocol.WorkDoneProgressKindReport:
+		if task == nil {
+			return // a report for a task begun before we listened
+		}
+		task.loading = task.loading || isLoadingProgress(v.Message)
+	case protocol.WorkDoneProgressKindEnd:
+		if task == nil {
+			return
+		}
+		delete(p.active, token)
+	default:
+		return
+	}
+	task.last = now
+	if task.loading {
+		p.lastLoading = now
+	}
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func isLoadingProgress(s string) bool {
+	s = strings.ToLower(s)
+	for _, w := range loadingTitleWords {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// settled reports whether project loading has been quiet at now for a
+// wait that started at since: no loading task is active and none had an
+// event within the quiet period, counted from since at the earliest so a
+// load set off by the caller's own sync is seen. Otherwise it returns when
+// to check again; events may wake the waiter earlier. Loading tasks silent
+// for longer than the stale period no longer count as active.
+func (p *progressTracker) settled(now, since time.Time) (bool, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	quietFrom := since
+	if p.lastLoading.After(quietFrom) {
+		quietFrom = p.lastLoading
+	}
+	var next time.Time
+	for _, t := range p.active {
+		if !t.loading {
+			continue
+		}
+		stale := t.last.Add(p.cfg.Stale)
+		if now.Before(stale) {
+			if next.IsZero() || stale.Before(next) {
+				next = stale
+			}
+			continue
+		}
+		// A stale task ends, for the quiet period, when it went stale.
+		if stale.After(quietFrom) {
+			quietFrom = stale
+		}
+	}
+	if !next.IsZero() {
+		return false, next
+	}
+	quietUntil := quietFrom.Add(p.cfg.Quiet)
+	if now.Before(quietUntil) {
+		return false, quietUntil
+	}
+	return true, time.Time{}
+}
+
+// wait blocks until project loading settles, ctx is done or the maximum
+// wait passes. It reports whether loading was still going on.
+func (p *progressTracker) wait(ctx context.Context) (stillLoading bool) {
+	start := p.now()
+	deadline := start.Add(p.cfg.MaxWait)
+	for {
+		p.mu.Lock()
+		changed := p.changed
+		p.mu.Unlock()
+
+		now := p.now()
+		done, next := p.settled(now, start)
+		if done {
+			return false
+		}
+		if !now.Before(deadline) {
+			return true
+		}
+		if next.After(deadline) {
+			next = deadline
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return true
+		case <-changed:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// WaitForProjectLoad waits until tsgo reports no project loading progress
+// for a short quiet period, for at most the configured maximum wait. It
+// returns true when loading was still going on at the end.
+func (c *Client) WaitForProjectLoad(ctx context.Context) bool {
+	return c.progress.wait(ctx)
+}
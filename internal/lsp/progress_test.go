@@ -0,0 +1,190 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+func newTestTracker() (*progressTracker, *time.Time) {
+	now := time.Unix(1_700_000_000, 0)
+	p := newProgressTracker(loadConfig{MaxWait: 20 * time.Second, Quiet: time.Second, Stale: 10 * time.Second})
+	p.now = func() time.Time { return now }
+	return p, &now
+}
+
+func beginProgress(title string) progressValue {
+	return progressValue{Kind: protocol.WorkDoneProgressKindBegin, Title: title}
+}
+
+func reportProgress(message string) progressValue {
+	return progressValue{Kind: protocol.WorkDoneProgressKindReport, Message: message}
+}
+
+var endProgress = progressValue{Kind: protocol.WorkDoneProgressKindEnd}
+
+func TestProgressTrackerSettled(t *testing.T) {
+	type step struct {
+		at    time.Duration // since the wait started
+		token string
+		value progressValue
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		// settledAt is the earliest time, since the wait started, at which
+		// loading counts as settled.
+		settledAt time.Duration
+	}{
+		{
+			name:      "no progress",
+			settledAt: time.Second,
+		},
+		{
+			name: "begin report end",
+			steps: []step{
+				{0, "1", beginProgress("Loading project /repo/tsconfig.json")},
+				{2 * time.Second, "1", reportProgress("12 files")},
+				{3 * time.Second, "1", endProgress},
+			},
+			settledAt: 4 * time.Second,
+		},
+		{
+			name: "overlapping tokens",
+			steps: []step{
+				{0, "1", beginProgress("Loading project")},
+				{time.Second, "2", beginProgress("Building program")},
+				{2 * time.Second, "1", endProgress},
+				{4 * time.Second, "2", endProgress},
+			},
+			settledAt: 5 * time.Second,
+		},
+		{
+			name: "load begins during the quiet period",
+			steps: []step{
+				{500 * time.Millisecond, "1", beginProgress("Loading project")},
+				{2 * time.Second, "1", endProgress},
+			},
+			settledAt: 3 * time.Second,
+		},
+		{
+			name: "unrelated progress",
+			steps: []step{
+				{0, "1", beginProgress("Finding references")},
+			},
+			settledAt: time.Second,
+		},
+		{
+			name: "loading named in a report",
+			steps: []step{
+				{0, "1", beginProgress("Working")},
+				{time.Second, "1", reportProgress("loading /repo/tsconfig.json")},
+			},
+			settledAt: 12 * time.Second, // stale 10s after the report, then quiet
+		},
+		{
+			name: "never ends",
+			steps: []step{
+				{0, "1", beginProgress("Loading project")},
+				{3 * time.Second, "1", reportProgress("still loading")},
+			},
+			settledAt: 14 * time.Second,
+		},
+		{
+			name: "end for an unknown token",
+			steps: []step{
+				{0, "9", endProgress},
+				{0, "9", reportProgress("loading")},
+			},
+			settledAt: time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Replay the steps in order, checking every 100ms.
+			p, now := newTestTracker()
+			start := *now
+			applied := 0
+			var settledAt time.Duration = -1
+			for d := time.Duration(0); d <= 30*time.Second; d += 100 * time.Millisecond {
+				for applied < len(tt.steps) && tt.steps[applied].at <= d {
+					s := tt.steps[applied]
+					*now = start.Add(s.at)
+					p.event(s.token, s.value)
+					applied++
+				}
+				if ok, next := p.settled(start.Add(d), start); ok {
+					settledAt = d
+					break
+				} else if !next.After(start.Add(d)) {
+					t.Fatalf("at %v: next check %v is not in the future", d, next.Sub(start))
+				}
+			}
+			if settledAt != tt.settledAt {
+				t.Errorf("settled at %v, want %v", settledAt, tt.settledAt)
+			}
+		})
+	}
+}
+
+func TestProgressTrackerWait(t *testing.T) {
+	p := newProgressTracker(loadConfig{MaxWait: 50 * time.Millisecond, Quiet: 5 * time.Millisecond, Stale: time.Minute})
+	ctx := context.Background()
+	if p.wait(ctx) {
+		t.Error("wait reported loading without any progress")
+	}
+
+	p.event("1", beginProgress("Loading project"))
+	if !p.wait(ctx) {
+		t.Error("wait did not report the unfinished load at the bound")
+	}
+
+	// The end event wakes the waiter before the bound.
+	p.cfg.MaxWait = 10 * time.Second
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		p.event("1", endProgress)
+	}()
+	start := time.Now()
+	if p.wait(ctx) {
+		t.Error("wait reported loading after the end event")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("wait took %v", d)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("TYPESCRIPT_MCP_PROJECT_LOAD_WAIT", "")
+	if got := loadConfigFromEnv().MaxWait; got != defaultLoadMaxWait {
+		t.Errorf("default max wait = %v", got)
+	}
+	t.Setenv("TYPESCRIPT_MCP_PROJECT_LOAD_WAIT", "5s")
+	if got := loadConfigFromEnv().MaxWait; got != 5*time.Second {
+		t.Errorf("max wait = %v, want 5s", got)
+	}
+	t.Setenv("TYPESCRIPT_MCP_PROJECT_LOAD_WAIT", "later")
+	if got := loadConfigFromEnv().MaxWait; got != defaultLoadMaxWait {
+		t.Errorf("invalid value gave %v", got)
+	}
+}
+
+func TestProgressTrackerHandle(t *testing.T) {
+	p, _ := newTestTracker()
+	var params protocol.ProgressParams
+	if err := json.Unmarshal([]byte(`{"token":"load-1","value":{"kind":"begin","title":"Loading project"}}`), &params); err != nil {
+		t.Fatal(err)
+	}
+	p.handle(&params)
+	if task := p.active["load-1"]; task == nil || !task.loading {
+		t.Fatalf("active = %+v, want a loading task", p.active)
+	}
+	// Values that are not work-done progress are ignored.
+	p.handle(&protocol.ProgressParams{Token: *protocol.NewProgressToken("partial"), Value: []any{1, 2}})
+	if len(p.active) != 1 {
+		t.Errorf("active = %+v", p.active)
+	}
+}
@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"maps"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.lsp.dev/uri"
+)
+
+// ProjectAssignment records which project the server reported as owning a
+// file.
+type ProjectAssignment struct {
+	File    string
+	Project string
+}
+
+var (
+	// openFilePattern matches the "FileName:" line of an open-files dump;
+	// the project list follows on the next line.
+	openFilePattern = regexp.MustCompile(`^\s*FileName:\s+(\S+)`)
+	// openFileProjectsPattern matches the project list of an open file.
+	openFileProjectsPattern = regexp.MustCompile(`^\s*Projects:\s*(.*)$`)
+	// projectHeaderPattern matches the header of a project dump, e.g.
+	// "Project '/repo/tsconfig.json' (Configured) 0".
+	projectHeaderPattern = regexp.MustCompile(`^\s*Project '([^']+)' \((?:Configured|Inferred|External)\)`)
+	// projectFilesPattern matches the line that starts a dump's file list.
+	projectFilesPattern = regexp.MustCompile(`^\s*Files \(\d+\)`)
+)
+
+// ParseProjectLog extracts project assignments from a window/logMessage
+// message. Two layouts are understood, both printed by the TypeScript
+// project service:
+//
+//   - an "Open files:" dump, where each "FileName:" line is followed by a
+//     "Projects:" line whose first entry is the owning project;
+//   - project dumps ("Project '<config>' (Configured)" followed by a
+//     "Files (n)" list), where a file counts as owned only when exactly one
+//     project of the message lists it.
+//
+// Anything else yields no assignments.
+func ParseProjectLog(message string) []ProjectAssignment {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	var out []ProjectAssignment
+	seen := make(map[string]bool)
+
+	// Open-files dumps name the owner directly.
+	for i := 0; i+1 < len(lines); i++ {
+		m := openFilePattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		p := openFileProjectsPattern.FindStringSubmatch(lines[i+1])
+		if p == nil {
+			continue
+		}
+		project, _, _ := strings.Cut(p[1], ",")
+		file, project := logPath(m[1]), logPath(strings.TrimSpace(project))
+		if file != "" && project != "" && !seen[file] {
+			seen[file] = true
+			out = append(out, ProjectAssignment{File: file, Project: project})
+		}
+	}
+
+	// Project dumps list every project a file belongs to.
+	owners := make(map[string][]string)
+	var order []string
+	project, inFiles := "", false
+	for _, l := range lines {
+		if m := projectHeaderPattern.FindStringSubmatch(l); m != nil {
+			project, inFiles = logPath(m[1]), false
+			continue
+		}
+		if project == "" {
+			continue
+		}
+		if projectFilesPattern.MatchString(l) {
+			inFiles = true
+			continue
+		}
+		trimmed := strings.TrimSpace(l)
+		if trimmed == "" || strings.HasPrefix(trimmed, "-----") {
+			project, inFiles = "", false
+			continue
+		}
+		if !inFiles {
+			continue
+		}
+		// File lines may carry a version and script info after the path;
+		// indented explanation lines ("Matched by include pattern") do not
+		// start with a path.
+		fields := strings.Fields(trimmed)
+		file := logPath(fields[0])
+		if file == "" || seen[file] {
+			continue
+		}
+		if _, ok := owners[file]; !ok {
+			order = append(order, file)
+		}
+		owners[file] = append(owners[file], project)
+	}
+	for _, file := range order {
+		if ps := owners[file]; len(ps) == 1 {
+			out = append(out, ProjectAssignment{File: file, Project: ps[0]})
+		}
+	}
+	return out
+}
+
+// logPath converts a path or file URI from a log line into a clean
+// absolute path; it returns "" for anything else. Inferred project names
+// such as "/dev/null/inferredProject1*" are kept as they are.
+func logPath(s string) string {
+	s = strings.Trim(s, `"'`)
+	if strings.HasPrefix(s, "file://") {
+		return uri.URI(s).Filename()
+	}
+	if strings.HasSuffix(s, "*") && filepath.IsAbs(s) {
+		return s
+	}
+	if !filepath.IsAbs(s) {
+		return ""
+	}
+	return filepath.Clean(s)
+}
+
+// projectTracker keeps the project the server last reported for each
+// file, and the one last handed to a caller, to notice reassignments.
+type projectTracker struct {
+	mu       sync.Mutex
+	owners   map[string]string
+	observed map[string]string
+}
+
+func (t *projectTracker) record(assignments []ProjectAssignment) {
+	if len(assignments) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.owners == nil {
+		t.owners = make(map[string]string)
+	}
+	for _, a := range assignments {
+		t.owners[a.File] = a.Project
+	}
+}
+
+func (t *projectTracker) all() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.owners)
+}
+
+func (t *projectTracker) observe(file string) (project, previous string) {
+	file = filepath.Clean(file)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	project = t.owners[file]
+	if project == "" {
+		return "", ""
+	}
+	if t.observed == nil {
+		t.observed = make(map[string]string)
+	}
+	if last := t.observed[file]; last != project {
+		previous = last
+	}
+	t.observed[file] = project
+	return project, previous
+}
+
+// ProjectAssignments returns every file's owning project as last reported
+// by tsgo, keyed by absolute path.
+func (c *Client) ProjectAssignments() map[string]string {
+	return c.projects.all()
+}
+
+// ObserveProject returns the owning project of file for a tool response.
+// previous is set when it differs from the project returned by the
+// previous call for the same file, meaning tsgo moved the file to another
+// project in between.
+func (c *Client) ObserveProject(file string) (project, previous string) {
+	return c.projects.observe(file)
+}
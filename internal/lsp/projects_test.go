@@ -0,0 +1,68 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseProjectLog(t *testing.T) {
+	// testdata/project-log holds window/logMessage messages in the layouts
+	// of the TypeScript project service.
+	tests := map[string][]ProjectAssignment{
+		"open-files.txt": {
+			{File: "/repo/packages/api/src/server.ts", Project: "/repo/packages/api/tsconfig.json"},
+			{File: "/repo/packages/shared/src/util.ts", Project: "/repo/packages/shared/tsconfig.json"},
+		},
+		"open-files-uri.txt": {
+			{File: "/repo/packages/web/src/app.tsx", Project: "/repo/packages/web/tsconfig.json"},
+		},
+		// Files listed by both projects (the lib file and util.ts) are
+		// ambiguous and skipped.
+		"project-dump.txt": {
+			{File: "/repo/packages/api/src/server.ts", Project: "/repo/packages/api/tsconfig.json"},
+			{File: "/repo/scripts/release.ts", Project: "/dev/null/inferredProject1*"},
+		},
+		"unrelated.txt": nil,
+	}
+	for name, want := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", "project-log", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ParseProjectLog(string(data)); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: assignments = %+v, want %+v", name, got, want)
+		}
+	}
+
+	if got := ParseProjectLog(""); got != nil {
+		t.Errorf("empty message: %+v", got)
+	}
+}
+
+func TestProjectTracker(t *testing.T) {
+	var tr projectTracker
+	if project, previous := tr.observe("/repo/a.ts"); project != "" || previous != "" {
+		t.Errorf("unknown file: %q, %q", project, previous)
+	}
+
+	tr.record([]ProjectAssignment{{File: "/repo/a.ts", Project: "/repo/tsconfig.json"}})
+	if project, previous := tr.observe("/repo/a.ts"); project != "/repo/tsconfig.json" || previous != "" {
+		t.Errorf("first observation: %q, %q", project, previous)
+	}
+	if _, previous := tr.observe("/repo/./a.ts"); previous != "" {
+		t.Errorf("unchanged assignment reported as changed from %q", previous)
+	}
+
+	tr.record([]ProjectAssignment{{File: "/repo/a.ts", Project: "/repo/tsconfig.test.json"}})
+	if project, previous := tr.observe("/repo/a.ts"); project != "/repo/tsconfig.test.json" || previous != "/repo/tsconfig.json" {
+		t.Errorf("after reassignment: %q, %q", project, previous)
+	}
+	if _, previous := tr.observe("/repo/a.ts"); previous != "" {
+		t.Errorf("change reported twice: %q", previous)
+	}
+	if all := tr.all(); len(all) != 1 || all["/repo/a.ts"] != "/repo/tsconfig.test.json" {
+		t.Errorf("all = %v", all)
+	}
+}
@@ -0,0 +1,112 @@
+package lsp
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// defaultPushWait is how long Diagnostic waits for pushed diagnostics of
+// the synced version of a file when the server does not answer pull
+// requests. It can be changed with TYPESCRIPT_MCP_PUSH_DIAGNOSTICS_WAIT.
+const defaultPushWait = 3 * time.Second
+
+// Sources of FileDiagnostics.
+const (
+	DiagnosticSourcePull = "pull"
+	DiagnosticSourcePush = "push"
+)
+
+// FileDiagnostics are the diagnostics of one file.
+type FileDiagnostics struct {
+	Items []protocol.Diagnostic
+	// Source is DiagnosticSourcePull when the server answered the pull
+	// request, or DiagnosticSourcePush when the diagnostics are the ones it
+	// published.
+	Source string
+	// Incomplete is set when no published diagnostics of the version
+	// asked for arrived in time: Items are older ones, or none.
+	Incomplete bool
+}
+
+// pushWaitFromEnv returns the push wait from
+// TYPESCRIPT_MCP_PUSH_DIAGNOSTICS_WAIT, a Go duration such as "3s".
+func pushWaitFromEnv() time.Duration {
+	v := os.Getenv("TYPESCRIPT_MCP_PUSH_DIAGNOSTICS_WAIT")
+	if v == "" {
+		return defaultPushWait
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		slog.Warn("ignoring invalid TYPESCRIPT_MCP_PUSH_DIAGNOSTICS_WAIT", "value", v)
+		return defaultPushWait
+	}
+	return d
+}
+
+// pushedDiagnostics are the latest diagnostics published for a URI.
+type pushedDiagnostics struct {
+	// version is the document version they were computed for, 0 when the
+	// server did not say.
+	version int32
+	items   []protocol.Diagnostic
+}
+
+// pushStore keeps the diagnostics the server publishes and lets callers
+// wait for those of a document version.
+type pushStore struct {
+	wait time.Duration
+
+	mu    sync.Mutex
+	byURI map[string]pushedDiagnostics
+	// changed is closed, and replaced, by every record.
+	changed chan struct{}
+}
+
+func newPushStore(wait time.Duration) *pushStore {
+	return &pushStore{wait: wait, byURI: make(map[string]pushedDiagnostics), changed: make(chan struct{})}
+}
+
+// record stores the diagnostics published for u and wakes the waiters.
+func (s *pushStore) record(u string, version int32, items []protocol.Diagnostic) {
+	s.mu.Lock()
+	s.byURI[u] = pushedDiagnostics{version: version, items: items}
+	close(s.changed)
+	s.changed = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// matches reports whether p are diagnostics of version. Version 0 asks
+// for any diagnostics, and diagnostics published without a version match
+// every version.
+func (p pushedDiagnostics) matches(version int32) bool {
+	return version == 0 || p.version == 0 || p.version == version
+}
+
+// await returns the diagnostics published for u, waiting up to the push
+// wait for those of version to arrive. ok is false when the wait ran out,
+// or ctx ended, first; the latest diagnostics of u are returned then.
+func (s *pushStore) await(ctx context.Context, u string, version int32) (items []protocol.Diagnostic, ok bool) {
+	timer := time.NewTimer(s.wait)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		p, found := s.byURI[u]
+		changed := s.changed
+		s.mu.Unlock()
+		if found && p.matches(version) {
+			return p.items, true
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return p.items, false
+		case <-ctx.Done():
+			return p.items, false
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestPushStoreAwait(t *testing.T) {
+	ctx := context.Background()
+	const u = "file:///p/a.ts"
+	stale := []protocol.Diagnostic{{Message: "stale"}}
+	fresh := []protocol.Diagnostic{{Message: "fresh"}, {Message: "also fresh"}}
+
+	t.Run("already published", func(t *testing.T) {
+		s := newPushStore(time.Second)
+		s.record(u, 3, fresh)
+		items, ok := s.await(ctx, u, 3)
+		if !ok || len(items) != 2 {
+			t.Errorf("await = %v, %v", items, ok)
+		}
+	})
+
+	t.Run("waits for the version", func(t *testing.T) {
+		s := newPushStore(5 * time.Second)
+		s.record(u, 2, stale)
+		s.record("file:///p/b.ts", 3, stale)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			s.record(u, 3, fresh)
+		}()
+		items, ok := s.await(ctx, u, 3)
+		if !ok || len(items) != 2 {
+			t.Errorf("await = %v, %v", items, ok)
+		}
+	})
+
+	t.Run("times out with the latest", func(t *testing.T) {
+		s := newPushStore(20 * time.Millisecond)
+		s.record(u, 2, stale)
+		items, ok := s.await(ctx, u, 3)
+		if ok || len(items) != 1 || items[0].Message != "stale" {
+			t.Errorf("await = %v, %v", items, ok)
+		}
+	})
+
+	t.Run("nothing published", func(t *testing.T) {
+		s := newPushStore(20 * time.Millisecond)
+		if items, ok := s.await(ctx, u, 0); ok || items != nil {
+			t.Errorf("await = %v, %v", items, ok)
+		}
+	})
+
+	t.Run("any version", func(t *testing.T) {
+		s := newPushStore(time.Second)
+		s.record(u, 7, fresh)
+		if _, ok := s.await(ctx, u, 0); !ok {
+			t.Error("version 0 did not take the published diagnostics")
+		}
+	})
+
+	t.Run("unversioned push", func(t *testing.T) {
+		s := newPushStore(time.Second)
+		s.record(u, 0, fresh)
+		if _, ok := s.await(ctx, u, 4); !ok {
+			t.Error("diagnostics without a version did not match")
+		}
+	})
+
+	t.Run("context ends", func(t *testing.T) {
+		s := newPushStore(5 * time.Second)
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, ok := s.await(cctx, u, 1); ok {
+			t.Error("await succeeded after the context ended")
+		}
+	})
+}
+
+func TestPushWaitFromEnv(t *testing.T) {
+	t.Setenv("TYPESCRIPT_MCP_PUSH_DIAGNOSTICS_WAIT", "")
+	if got := pushWaitFromEnv(); got != defaultPushWait {
+		t.Errorf("default wait = %v", got)
+	}
+	t.Setenv("TYPESCRIPT_MCP_PUSH_DIAGNOSTICS_WAIT", "500ms")
+	if got := pushWaitFromEnv(); got != 500*time.Millisecond {
+		t.Errorf("wait = %v, want 500ms", got)
+	}
+	t.Setenv("TYPESCRIPT_MCP_PUSH_DIAGNOSTICS_WAIT", "eventually")
+	if got := pushWaitFromEnv(); got != defaultPushWait {
+		t.Errorf("invalid value gave %v", got)
+	}
+}
@@ -0,0 +1,181 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+// Restart defaults. The number of restarts in a row can be changed with
+// TYPESCRIPT_MCP_MAX_RESTARTS; "0" turns restarting off.
+const (
+	defaultMaxRestarts   = 5
+	defaultRestartDelay  = 500 * time.Millisecond
+	defaultRestartStable = time.Minute
+	maxRestartDelay      = 30 * time.Second
+)
+
+// restartConfig controls how a tsgo that exited is restarted.
+type restartConfig struct {
+	// Max is the number of restarts in a row before the client gives up;
+	// 0 disables restarting.
+	Max int
+	// Delay is the wait before the first restart in a row. It doubles with
+	// every further one, up to maxRestartDelay.
+	Delay time.Duration
+	// Stable is how long tsgo must have run for its exit to start a new
+	// row of restarts.
+	Stable time.Duration
+}
+
+// restartConfigFromEnv returns the default config with the maximum from
+// TYPESCRIPT_MCP_MAX_RESTARTS.
+func restartConfigFromEnv() restartConfig {
+	cfg := restartConfig{Max: defaultMaxRestarts, Delay: defaultRestartDelay, Stable: defaultRestartStable}
+	v := os.Getenv("TYPESCRIPT_MCP_MAX_RESTARTS")
+	if v == "" {
+		return cfg
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		slog.Warn("ignoring invalid TYPESCRIPT_MCP_MAX_RESTARTS", "value", v)
+		return cfg
+	}
+	cfg.Max = n
+	return cfg
+}
+
+// backoff returns the wait before the n-th restart in a row, counting
+// from 1.
+func (cfg restartConfig) backoff(n int) time.Duration {
+	d := cfg.Delay
+	for i := 1; i < n && d < maxRestartDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxRestartDelay)
+}
+
+// OnRestart registers fn to be called with the connection to a restarted
+// tsgo before the calls waiting for it resume, to bring it up to date:
+// docsync.Manager.Reopen replays the open documents. An error is logged.
+func (c *Client) OnRestart(fn func(ctx context.Context, conn jsonrpc2.Conn) error) {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	c.onRestart = append(c.onRestart, fn)
+}
+
+// Restarts returns how often tsgo was restarted after it exited.
+func (c *Client) Restarts() int {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	return c.restarts
+}
+
+// Exited reports whether the running tsgo has exited and its restart has
+// not begun yet. Requests to it fail; Wait waits for the restart.
+func (c *Client) Exited() bool {
+	s := c.session()
+	return s != nil && s.exited()
+}
+
+func (s *tsgoSession) exited() bool {
+	select {
+	case <-s.conn.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// watch waits for the connection of s to end and restarts tsgo with
+// backoff, unless the client was closed or tsgo exited too many times in
+// a row. A wedged tsgo killed by the health monitor ends here too.
+func (c *Client) watch(s *tsgoSession) {
+	<-s.conn.Done()
+	defer close(s.ended)
+	c.startMu.Lock()
+	closed := c.closed
+	c.startMu.Unlock()
+	if closed {
+		return
+	}
+	// Reap the exited tsgo, or kill one that only closed its output.
+	exitErr := s.process.Stop()
+	s.stopHealth()
+
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	if c.closed {
+		return
+	}
+	if time.Since(s.started) >= c.restart.Stable {
+		c.crashes = 0
+	}
+	c.crashes++
+	exit := "exit status 0"
+	if exitErr != nil {
+		exit = exitErr.Error()
+	}
+	if c.crashes > c.restart.Max {
+		slog.Error("tsgo exited; not restarting it", "exit", exit, "inARow", c.crashes, "stderr", s.process.StderrTail())
+		c.startErr = fmt.Errorf("tsgo exited (%s) %d times in a row and is not restarted", exit, c.crashes)
+		c.finished = time.Now()
+		return
+	}
+	slog.Error("tsgo exited; restarting it", "exit", exit, "in", c.restart.backoff(c.crashes), "stderr", s.process.StderrTail())
+	c.restarts++
+	c.beginStartup(true)
+}
+
+// connectAfter waits delay and then connects as connect does.
+func (c *Client) connectAfter(ctx context.Context, delay time.Duration) error {
+	if delay > 0 {
+		c.setPhase(phaseRestart)
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return c.connect(ctx, c.start)
+}
+
+// restartNow runs a restart begun by watch: it connects after the
+// backoff, tries again while the restarts in a row allow, and then brings
+// the new tsgo up to date with the OnRestart functions.
+func (c *Client) restartNow(ctx context.Context, delay time.Duration) error {
+	err := c.connectAfter(ctx, delay)
+	for err != nil && ctx.Err() == nil {
+		c.startMu.Lock()
+		c.crashes++
+		n, limit := c.crashes, c.restart.Max
+		delay = c.restart.backoff(n)
+		c.startMu.Unlock()
+		if n > limit {
+			return fmt.Errorf("restart tsgo after %d attempts: %w", n-1, err)
+		}
+		slog.Warn("restarting tsgo failed; trying again", "in", delay, "err", err)
+		err = c.connectAfter(ctx, delay)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.startMu.Lock()
+	hooks := c.onRestart
+	c.startMu.Unlock()
+	conn := c.session().conn
+	for _, fn := range hooks {
+		if err := fn(ctx, conn); err != nil {
+			slog.Warn("bringing the restarted tsgo up to date", "err", err)
+		}
+	}
+	return nil
+}
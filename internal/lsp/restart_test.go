@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+)
+
+func TestClientRestart(t *testing.T) {
+	fake := &fakeTsgo{}
+	c := StartClient(context.Background(), "file:///repo", fake.start)
+	t.Cleanup(func() { _ = c.Close() })
+	c.startMu.Lock()
+	c.restart = restartConfig{Max: 2, Delay: time.Millisecond, Stable: time.Hour}
+	c.startMu.Unlock()
+	var hooked []jsonrpc2.Conn
+	c.OnRestart(func(_ context.Context, conn jsonrpc2.Conn) error {
+		hooked = append(hooked, conn)
+		return nil
+	})
+	wait := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return c.Wait(ctx)
+	}
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.crash(t, c)
+	if !c.Exited() {
+		t.Error("Exited = false after the crash")
+	}
+	if err := wait(); err != nil {
+		t.Fatalf("Wait after the crash = %v, want the restarted tsgo", err)
+	}
+	if n := fake.spawns(); n != 2 || c.Restarts() != 1 || c.Exited() {
+		t.Errorf("spawns = %d, restarts = %d, exited = %v; want 2, 1, false", n, c.Restarts(), c.Exited())
+	}
+	if len(hooked) != 1 || hooked[0] != c.Conn() {
+		t.Errorf("OnRestart got %v, want the new connection once", hooked)
+	}
+	if st := c.Startup(); st.State != StartupReady || st.Restarts != 1 {
+		t.Errorf("status = %+v", st)
+	}
+
+	// A restart that fails to spawn counts towards the limit, and the
+	// client gives up once tsgo exited too often in a row.
+	fake.mu.Lock()
+	fake.fail = true
+	fake.mu.Unlock()
+	fake.crash(t, c)
+	err := wait()
+	if err == nil || !strings.Contains(err.Error(), "tsgo not found") {
+		t.Fatalf("Wait = %v, want the failed restart", err)
+	}
+	if n := fake.spawns(); n != 3 {
+		t.Errorf("spawns = %d, want 3", n)
+	}
+	if st := c.Startup(); st.State != StartupFailed || st.Restarts != 2 {
+		t.Errorf("status = %+v", st)
+	}
+}
+
+func TestClientRestartDisabled(t *testing.T) {
+	fake := &fakeTsgo{}
+	c := StartClient(context.Background(), "file:///repo", fake.start)
+	t.Cleanup(func() { _ = c.Close() })
+	c.startMu.Lock()
+	c.restart.Max = 0
+	c.startMu.Unlock()
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fake.crash(t, c)
+	err := c.Wait(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not restarted") {
+		t.Errorf("Wait = %v, want tsgo not restarted", err)
+	}
+	if n := fake.spawns(); n != 1 {
+		t.Errorf("spawns = %d, want 1", n)
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	cfg := restartConfig{Delay: time.Second}
+	for n, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: maxRestartDelay} {
+		if got := cfg.backoff(n); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// SemanticToken is a decoded semantic token. Line and Character are
+// 0-based; Character and Length count UTF-16 code units.
+type SemanticToken struct {
+	Line      uint32
+	Character uint32
+	Length    uint32
+	Type      string
+	Modifiers []string
+}
+
+// semanticTokensClientCapabilities asks for relative tokens of the
+// standard types and modifiers, whole-file and by range.
+var semanticTokensClientCapabilities = &protocol.SemanticTokensClientCapabilities{
+	Requests: protocol.SemanticTokensWorkspaceClientCapabilitiesRequests{Range: true, Full: true},
+	TokenTypes: []string{
+		"namespace", "type", "class", "enum", "interface", "struct", "typeParameter", "parameter",
+		"variable", "property", "enumMember", "event", "function", "method", "macro", "keyword",
+		"modifier", "comment", "string", "number", "regexp", "operator", "decorator",
+	},
+	TokenModifiers: []string{
+		"declaration", "definition", "readonly", "static", "deprecated", "abstract", "async",
+		"modification", "documentation", "defaultLibrary",
+	},
+	Formats: []protocol.TokenFormat{protocol.TokenFormatRelative},
+}
+
+// semanticTokensOptions is the semanticTokensProvider capability, which
+// protocol.ServerCapabilities leaves undecoded.
+type semanticTokensOptions struct {
+	Legend protocol.SemanticTokensLegend `json:"legend"`
+	Range  any                           `json:"range"`
+	Full   any                           `json:"full"`
+}
+
+// semanticTokensOptions decodes the server's semantic tokens options. It
+// reports false when the server did not announce semantic tokens.
+func (c *Client) semanticTokensOptions() (semanticTokensOptions, bool) {
+	var opts semanticTokensOptions
+	if !providerEnabled(c.session().capabilities.SemanticTokensProvider) {
+		return opts, false
+	}
+	data, err := json.Marshal(c.session().capabilities.SemanticTokensProvider)
+	if err != nil || json.Unmarshal(data, &opts) != nil {
+		return opts, false
+	}
+	return opts, true
+}
+
+// decodeSemanticTokens decodes the relative encoding of tokens, five
+// integers per token, with the types and modifiers of legend.
+func decodeSemanticTokens(data []uint32, legend protocol.SemanticTokensLegend) ([]SemanticToken, error) {
+	if len(data)%5 != 0 {
+		return nil, fmt.Errorf("decoding semantic tokens: %d integers is not a multiple of 5", len(data))
+	}
+	tokens := make([]SemanticToken, 0, len(data)/5)
+	var line, char uint32
+	for i := 0; i < len(data); i += 5 {
+		deltaLine, deltaChar, length, typ, mods := data[i], data[i+1], data[i+2], data[i+3], data[i+4]
+		if deltaLine > 0 {
+			line += deltaLine
+			char = deltaChar
+		} else {
+			char += deltaChar
+		}
+		if int(typ) >= len(legend.TokenTypes) {
+			return nil, fmt.Errorf("decoding semantic tokens: token type %d is not in the legend", typ)
+		}
+		tok := SemanticToken{Line: line, Character: char, Length: length, Type: string(legend.TokenTypes[typ])}
+		for bit := 0; mods != 0; bit++ {
+			if mods&1 != 0 && bit < len(legend.TokenModifiers) {
+				tok.Modifiers = append(tok.Modifiers, string(legend.TokenModifiers[bit]))
+			}
+			mods >>= 1
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens, nil
+}
+
+// SemanticTokens returns the semantic tokens of a file, or of the lines
+// startLine to endLine when the server serves ranges. A server serving
+// only whole files returns them all, so callers filter by line. It fails
+// with ErrUnsupported when tsgo does not provide semantic tokens.
+// Lines are 1-based (converted to 0-based for LSP); 0 means the whole file.
+func (c *Client) SemanticTokens(ctx context.Context, file string, startLine, endLine int) ([]SemanticToken, error) {
+	opts, ok := c.semanticTokensOptions()
+	if !ok {
+		return nil, requireProvider(protocol.MethodSemanticTokensFull, nil)
+	}
+	doc := protocol.TextDocumentIdentifier{URI: protocol.DocumentURI(uri.File(file))}
+	method := protocol.MethodSemanticTokensFull
+	var params any = &protocol.SemanticTokensParams{TextDocument: doc}
+	if startLine > 0 && endLine >= startLine && providerEnabled(opts.Range) {
+		method = protocol.MethodSemanticTokensRange
+		params = &protocol.SemanticTokensRangeParams{TextDocument: doc, Range: makeRange(startLine, 1, endLine+1, 1)}
+	} else if !providerEnabled(opts.Full) {
+		return nil, requireProvider(method, nil)
+	}
+	var result *protocol.SemanticTokens
+	ctx, done := c.request(ctx, method)
+	err := protocol.Call(ctx, c.session().conn, method, params, &result)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(method, err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return decodeSemanticTokens(result.Data, opts.Legend)
+}
@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"slices"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestDecodeSemanticTokens(t *testing.T) {
+	legend := protocol.SemanticTokensLegend{
+		TokenTypes:     []protocol.SemanticTokenTypes{"class", "enumMember", "variable"},
+		TokenModifiers: []protocol.SemanticTokenModifiers{"declaration", "readonly", "static"},
+	}
+	// Two tokens on line 2, then one on line 5.
+	data := []uint32{
+		2, 6, 5, 0, 1,
+		0, 8, 3, 2, 0b011,
+		3, 2, 4, 1, 0b110,
+	}
+	tokens, err := decodeSemanticTokens(data, legend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SemanticToken{
+		{Line: 2, Character: 6, Length: 5, Type: "class", Modifiers: []string{"declaration"}},
+		{Line: 2, Character: 14, Length: 3, Type: "variable", Modifiers: []string{"declaration", "readonly"}},
+		{Line: 5, Character: 2, Length: 4, Type: "enumMember", Modifiers: []string{"readonly", "static"}},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("tokens = %+v", tokens)
+	}
+	for i, w := range want {
+		got := tokens[i]
+		if got.Line != w.Line || got.Character != w.Character || got.Length != w.Length || got.Type != w.Type || !slices.Equal(got.Modifiers, w.Modifiers) {
+			t.Errorf("token %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if _, err := decodeSemanticTokens([]uint32{0, 1, 2, 3}, legend); err == nil {
+		t.Error("want an error for a partial token")
+	}
+	if _, err := decodeSemanticTokens([]uint32{0, 1, 2, 7, 0}, legend); err == nil {
+		t.Error("want an error for a type outside the legend")
+	}
+}
@@ -0,0 +1,31 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDecodeSignatureHelp(t *testing.T) {
+	raw := `{"signatures": [{"label": "fmt(ünï: string, n: number): void", "parameters": [
+		{"label": [4, 15]},
+		{"label": "n: number", "documentation": "count"}
+	]}], "activeParameter": 1}`
+	help, err := decodeSignatureHelp(json.RawMessage(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := help.Signatures[0].Parameters
+	if len(params) != 2 || params[0].Label != "ünï: string" || params[1].Label != "n: number" {
+		t.Errorf("parameters = %+v", params)
+	}
+	if help.ActiveParameter != 1 {
+		t.Errorf("activeParameter = %d", help.ActiveParameter)
+	}
+
+	if help, err := decodeSignatureHelp(json.RawMessage("null")); help != nil || err != nil {
+		t.Errorf("null = %+v, %v", help, err)
+	}
+	if _, err := decodeSignatureHelp(json.RawMessage(`{"signatures": [{"label": "f()", "parameters": [{"label": [2, 9]}]}]}`)); err == nil {
+		t.Error("want an error for offsets past the label")
+	}
+}
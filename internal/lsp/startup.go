@@ -0,0 +1,200 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.lsp.dev/uri"
+)
+
+// Startup states reported by StartupStatus.
+const (
+	StartupStarting = "starting"
+	StartupReady    = "ready"
+	StartupFailed   = "failed"
+)
+
+// Startup phases reported while starting.
+const (
+	phaseRestart    = "restart"
+	phaseSpawn      = "spawn"
+	phaseInitialize = "initialize"
+)
+
+// ProcessStarter spawns tsgo. StartTsgo is the one used outside tests.
+type ProcessStarter func(ctx context.Context) (*TsgoProcess, error)
+
+// StartupStatus is the progress of the tsgo spawn and initialize
+// handshake.
+type StartupStatus struct {
+	State string `json:"state"`
+	// Phase is the step in progress while starting: "restart" (waiting
+	// to restart tsgo after it exited), "spawn" or "initialize".
+	Phase string `json:"phase,omitempty"`
+	// Elapsed is the time since the startup began, or the time it took
+	// once it finished.
+	Elapsed string `json:"elapsed"`
+	Error   string `json:"error,omitempty"`
+	// Restarts is how often tsgo was restarted after it exited.
+	Restarts int `json:"restarts,omitempty"`
+}
+
+// errClientClosed is returned by Wait once Close has stopped tsgo.
+var errClientClosed = errors.New("tsgo was stopped")
+
+// startupRetryDelay is how long a failed startup is reported before
+// RetryStartup tries again, so a burst of calls spawns tsgo once.
+const startupRetryDelay = 2 * time.Second
+
+// StartClient returns a client for rootURI at once and spawns tsgo with
+// start and runs the initialize handshake in the background. Until Wait
+// returns nil, only RootDir, TsgoVersion, VersionWarning, Startup, Wait,
+// RetryStartup and Close may be called. A failed startup is logged and
+// reported by Wait and Startup until RetryStartup begins another. A tsgo
+// that exits later is restarted; see watch. rootURI is as for NewClient.
+func StartClient(ctx context.Context, rootURI string, start ProcessStarter) *Client {
+	if rootURI == "" {
+		if cwd, err := os.Getwd(); err == nil {
+			rootURI = string(uri.File(cwd))
+		}
+	}
+	c := &Client{
+		rootURI:  rootURI,
+		pushed:   newPushStore(pushWaitFromEnv()),
+		analyzed: make(map[string]bool),
+		progress: newProgressTracker(loadConfigFromEnv()),
+		start:    start,
+		startCtx: ctx,
+		restart:  restartConfigFromEnv(),
+	}
+	c.SetRequestTimeout(requestTimeoutFromEnv())
+	c.startMu.Lock()
+	c.beginStartup(false)
+	c.startMu.Unlock()
+	return c
+}
+
+// beginStartup runs a startup in the background, or a restart of a tsgo
+// that exited. c.startMu must be held.
+func (c *Client) beginStartup(restart bool) {
+	startCtx, cancel := context.WithCancel(c.startCtx)
+	var delay time.Duration
+	if restart {
+		delay = c.restart.backoff(c.crashes)
+	}
+	ready := make(chan struct{})
+	c.cancelStart = cancel
+	c.ready = ready
+	c.startErr = nil
+	c.phase = ""
+	c.started = time.Now()
+	c.finished = time.Time{}
+	go func() {
+		var err error
+		if restart {
+			err = c.restartNow(startCtx, delay)
+		} else {
+			err = c.connect(startCtx, c.start)
+		}
+		c.startMu.Lock()
+		c.startErr = err
+		c.finished = time.Now()
+		phase := c.phase
+		c.startMu.Unlock()
+		switch {
+		case err != nil && startCtx.Err() != nil:
+			// Abandoned by Close or the caller's context.
+		case err != nil:
+			slog.Error("tsgo failed to start; tools that need it will report the error", "phase", phase, "err", err)
+		default:
+			slog.Debug("tsgo started", "elapsed", time.Since(c.started).Round(time.Millisecond))
+		}
+		close(ready)
+		if err == nil {
+			go c.watch(c.session())
+		}
+	}()
+}
+
+// RetryStartup begins a new startup when the last one failed at least
+// startupRetryDelay ago, e.g. because tsgo has been installed since. It
+// reports whether one began; Wait then waits for it.
+func (c *Client) RetryStartup() bool {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	if c.closed || c.startErr == nil || c.startCtx.Err() != nil || time.Since(c.finished) < startupRetryDelay {
+		return false
+	}
+	slog.Info("retrying the tsgo startup", "lastError", c.startErr)
+	c.beginStartup(false)
+	return true
+}
+
+// Wait blocks until the startup finished or ctx is done. It returns the
+// startup error, or ctx's error when ctx ended first. When tsgo has
+// exited, Wait also waits for its restart.
+func (c *Client) Wait(ctx context.Context) error {
+	for {
+		c.startMu.Lock()
+		ready := c.ready
+		c.startMu.Unlock()
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		c.startMu.Lock()
+		err, s := c.startErr, c.session()
+		c.startMu.Unlock()
+		if err != nil || s == nil || !s.exited() {
+			return err
+		}
+		select {
+		case <-s.ended:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		c.startMu.Lock()
+		closed := c.closed
+		c.startMu.Unlock()
+		if closed {
+			return errClientClosed
+		}
+	}
+}
+
+// Startup returns the progress of the startup.
+func (c *Client) Startup() StartupStatus {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	if c.finished.IsZero() {
+		return StartupStatus{
+			State:    StartupStarting,
+			Phase:    c.phase,
+			Elapsed:  time.Since(c.started).Round(time.Millisecond).String(),
+			Restarts: c.restarts,
+		}
+	}
+	st := StartupStatus{State: StartupReady, Elapsed: c.finished.Sub(c.started).Round(time.Millisecond).String(), Restarts: c.restarts}
+	if c.startErr != nil {
+		st.State = StartupFailed
+		st.Error = c.startErr.Error()
+	}
+	return st
+}
+
+// isReady reports whether the startup succeeded.
+func (c *Client) isReady() bool {
+	c.startMu.Lock()
+	defer c.startMu.Unlock()
+	return !c.finished.IsZero() && c.startErr == nil
+}
+
+func (c *Client) setPhase(phase string) {
+	c.startMu.Lock()
+	c.phase = phase
+	c.startMu.Unlock()
+}
@@ -0,0 +1,103 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartClientFailure(t *testing.T) {
+	spawnErr := errors.New("tsgo not found")
+	c := StartClient(context.Background(), "file:///repo", func(context.Context) (*TsgoProcess, error) {
+		return nil, spawnErr
+	})
+	if got := c.RootDir(); got != "/repo" {
+		t.Errorf("RootDir = %q before the startup finished", got)
+	}
+	if err := c.Wait(context.Background()); !errors.Is(err, spawnErr) {
+		t.Fatalf("Wait = %v, want the spawn error", err)
+	}
+	st := c.Startup()
+	if st.State != StartupFailed || !strings.Contains(st.Error, "tsgo not found") {
+		t.Errorf("status = %+v", st)
+	}
+	if c.TsgoVersion() != "" || c.VersionWarning() != "" {
+		t.Error("failed client reports a version")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+}
+
+func TestStartClientSlow(t *testing.T) {
+	spawned := make(chan struct{})
+	c := StartClient(context.Background(), "file:///repo", func(ctx context.Context) (*TsgoProcess, error) {
+		close(spawned)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-spawned
+	if st := c.Startup(); st.State != StartupStarting || st.Phase != phaseSpawn {
+		t.Errorf("status = %+v, want starting in spawn", st)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait = %v, want the caller's deadline", err)
+	}
+
+	// Close abandons the startup rather than waiting for it.
+	done := make(chan error, 1)
+	go func() { done <- c.Close() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Close = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for the startup")
+	}
+	if st := c.Startup(); st.State != StartupFailed {
+		t.Errorf("status after Close = %+v", st)
+	}
+}
+
+func TestStartClientRetry(t *testing.T) {
+	var attempts int
+	c := StartClient(context.Background(), "file:///repo", func(context.Context) (*TsgoProcess, error) {
+		attempts++
+		return nil, fmt.Errorf("attempt %d: tsgo not found", attempts)
+	})
+	if err := c.Wait(context.Background()); err == nil || !strings.Contains(err.Error(), "attempt 1") {
+		t.Fatalf("Wait = %v, want the first attempt's error", err)
+	}
+	if c.RetryStartup() {
+		t.Fatal("retried right after the failure")
+	}
+
+	c.startMu.Lock()
+	c.finished = c.finished.Add(-startupRetryDelay)
+	c.startMu.Unlock()
+	if !c.RetryStartup() {
+		t.Fatal("did not retry after the delay")
+	}
+	if err := c.Wait(context.Background()); err == nil || !strings.Contains(err.Error(), "attempt 2") {
+		t.Fatalf("Wait = %v, want the second attempt's error", err)
+	}
+	if st := c.Startup(); st.State != StartupFailed || !strings.Contains(st.Error, "attempt 2") {
+		t.Errorf("status = %+v", st)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+	c.startMu.Lock()
+	c.finished = c.finished.Add(-startupRetryDelay)
+	c.startMu.Unlock()
+	if c.RetryStartup() || attempts != 2 {
+		t.Errorf("retried after Close: %d attempts", attempts)
+	}
+}
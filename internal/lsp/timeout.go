@@ -0,0 +1,105 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// Request timeout defaults. The timeout can be changed with
+// TYPESCRIPT_MCP_TIMEOUT; "0" turns timeouts off.
+const (
+	defaultRequestTimeout = 15 * time.Second
+	// longRequestFactor multiplies the timeout of longRequests.
+	longRequestFactor = 4
+)
+
+// longRequests may search the whole project, so they get
+// longRequestFactor times the timeout.
+var longRequests = map[string]bool{
+	protocol.MethodTextDocumentRename:         true,
+	protocol.MethodTextDocumentReferences:     true,
+	protocol.MethodTextDocumentImplementation: true,
+	protocol.MethodCallHierarchyIncomingCalls: true,
+	protocol.MethodWorkspaceSymbol:            true,
+	protocol.MethodWillRenameFiles:            true,
+}
+
+// TimeoutError is the error of a request tsgo did not answer within its
+// timeout. It wraps context.DeadlineExceeded.
+type TimeoutError struct {
+	Method  string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s: the language server timed out after %s; the project may still be loading, retry shortly (TYPESCRIPT_MCP_TIMEOUT sets the limit)", e.Method, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// requestTimeoutFromEnv returns the request timeout from
+// TYPESCRIPT_MCP_TIMEOUT, a Go duration such as "15s", or the default.
+func requestTimeoutFromEnv() time.Duration {
+	v := os.Getenv("TYPESCRIPT_MCP_TIMEOUT")
+	if v == "" {
+		return defaultRequestTimeout
+	}
+	if v == "0" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		slog.Warn("ignoring invalid TYPESCRIPT_MCP_TIMEOUT", "value", v)
+		return defaultRequestTimeout
+	}
+	return d
+}
+
+// SetRequestTimeout sets how long a request waits for tsgo's answer
+// before failing with a *TimeoutError; rename and project-wide requests
+// wait four times as long. d <= 0 removes the timeouts.
+func (c *Client) SetRequestTimeout(d time.Duration) {
+	c.timeout.Store(int64(max(d, 0)))
+}
+
+// requestTimeout returns the timeout of a request for method, or 0 for
+// none.
+func (c *Client) requestTimeout(method string) time.Duration {
+	d := time.Duration(c.timeout.Load())
+	if longRequests[method] {
+		d *= longRequestFactor
+	}
+	return d
+}
+
+// request starts a request for method: it bounds ctx by the method's
+// timeout and tells the health monitor. The returned function ends both
+// and turns the expiry of the timeout into a *TimeoutError; the returned
+// context must not be used after it.
+func (c *Client) request(ctx context.Context, method string) (context.Context, func(error) error) {
+	health := c.session().health.begin(method)
+	timeout := c.requestTimeout(method)
+	if timeout <= 0 {
+		return ctx, func(err error) error {
+			health(err)
+			return err
+		}
+	}
+	rctx, cancel := context.WithTimeout(ctx, timeout)
+	return rctx, func(err error) error {
+		health(err)
+		if err != nil && ctx.Err() == nil && errors.Is(rctx.Err(), context.DeadlineExceeded) {
+			err = &TimeoutError{Method: method, Timeout: timeout}
+		}
+		cancel()
+		return err
+	}
+}
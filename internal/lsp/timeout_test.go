@@ -0,0 +1,78 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	fake := &fakeTsgo{hold: protocol.MethodTextDocumentHover, held: make(chan jsonrpc2.ID, 1), cancelled: make(chan jsonrpc2.ID, 1)}
+	c := StartClient(context.Background(), "file:///repo", fake.start)
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.SetRequestTimeout(50 * time.Millisecond)
+
+	_, err := c.Hover(context.Background(), "/repo/a.ts", 1, 1)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Method != protocol.MethodTextDocumentHover || te.Timeout != 50*time.Millisecond {
+		t.Fatalf("error = %v, want a TimeoutError for hover after 50ms", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("the TimeoutError does not wrap context.DeadlineExceeded")
+	}
+	id := <-fake.held
+	select {
+	case got := <-fake.cancelled:
+		if got != id {
+			t.Errorf("$/cancelRequest for %v, want %v", got, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("tsgo got no $/cancelRequest")
+	}
+
+	// The caller's own deadline is not the server's fault.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.Hover(ctx, "/repo/a.ts", 1, 1)
+	<-fake.held
+	if !errors.Is(err, context.DeadlineExceeded) || errors.As(err, &te) {
+		t.Errorf("error = %v, want the caller's context.DeadlineExceeded", err)
+	}
+}
+
+func TestRequestTimeoutLong(t *testing.T) {
+	c := &Client{}
+	c.SetRequestTimeout(time.Second)
+	if got := c.requestTimeout(protocol.MethodTextDocumentHover); got != time.Second {
+		t.Errorf("hover timeout = %v, want 1s", got)
+	}
+	if got := c.requestTimeout(protocol.MethodTextDocumentRename); got != 4*time.Second {
+		t.Errorf("rename timeout = %v, want 4s", got)
+	}
+	c.SetRequestTimeout(0)
+	if got := c.requestTimeout(protocol.MethodTextDocumentRename); got != 0 {
+		t.Errorf("rename timeout = %v, want none", got)
+	}
+}
+
+func TestRequestTimeoutFromEnv(t *testing.T) {
+	for v, want := range map[string]time.Duration{
+		"":     defaultRequestTimeout,
+		"0":    0,
+		"2m":   2 * time.Minute,
+		"soon": defaultRequestTimeout,
+		"-1s":  defaultRequestTimeout,
+	} {
+		t.Setenv("TYPESCRIPT_MCP_TIMEOUT", v)
+		if got := requestTimeoutFromEnv(); got != want {
+			t.Errorf("TYPESCRIPT_MCP_TIMEOUT=%q: got %v, want %v", v, got, want)
+		}
+	}
+}
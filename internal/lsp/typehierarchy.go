@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+
+	"go.lsp.dev/protocol"
+)
+
+// The LSP 3.17 type hierarchy requests, which protocol does not define.
+const (
+	methodPrepareTypeHierarchy = "textDocument/prepareTypeHierarchy"
+	methodTypeHierarchySuper   = "typeHierarchy/supertypes"
+	methodTypeHierarchySub     = "typeHierarchy/subtypes"
+)
+
+// TypeHierarchyItem is a class or interface of a type hierarchy. Data is
+// passed back as is to the supertypes and subtypes requests.
+type TypeHierarchyItem struct {
+	Name           string               `json:"name"`
+	Kind           protocol.SymbolKind  `json:"kind"`
+	Tags           []protocol.SymbolTag `json:"tags,omitempty"`
+	Detail         string               `json:"detail,omitempty"`
+	URI            protocol.DocumentURI `json:"uri"`
+	Range          protocol.Range       `json:"range"`
+	SelectionRange protocol.Range       `json:"selectionRange"`
+	Data           any                  `json:"data,omitempty"`
+}
+
+// PrepareTypeHierarchy returns the type hierarchy items of the class or
+// interface at a position, the roots for Supertypes and Subtypes. It fails
+// with ErrUnsupported when tsgo does not provide type hierarchies.
+// Line and column are 1-based (converted to 0-based for LSP).
+func (c *Client) PrepareTypeHierarchy(ctx context.Context, file string, line, col int) ([]TypeHierarchyItem, error) {
+	if line < 1 || col < 1 {
+		return nil, fmt.Errorf("line and column must be >= 1, got line=%d col=%d", line, col)
+	}
+	if err := requireProvider(methodPrepareTypeHierarchy, c.session().typeHierarchyProvider); err != nil {
+		return nil, err
+	}
+	var items []TypeHierarchyItem
+	ctx, done := c.request(ctx, methodPrepareTypeHierarchy)
+	params := makePosition(file, line, col)
+	err := protocol.Call(ctx, c.session().conn, methodPrepareTypeHierarchy, &params, &items)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(methodPrepareTypeHierarchy, err)
+	}
+	return items, nil
+}
+
+// Supertypes returns the classes and interfaces item extends or
+// implements.
+func (c *Client) Supertypes(ctx context.Context, item TypeHierarchyItem) ([]TypeHierarchyItem, error) {
+	return c.typeHierarchy(ctx, methodTypeHierarchySuper, item)
+}
+
+// Subtypes returns the classes and interfaces extending or implementing
+// item.
+func (c *Client) Subtypes(ctx context.Context, item TypeHierarchyItem) ([]TypeHierarchyItem, error) {
+	return c.typeHierarchy(ctx, methodTypeHierarchySub, item)
+}
+
+func (c *Client) typeHierarchy(ctx context.Context, method string, item TypeHierarchyItem) ([]TypeHierarchyItem, error) {
+	params := struct {
+		Item TypeHierarchyItem `json:"item"`
+	}{Item: item}
+	var items []TypeHierarchyItem
+	ctx, done := c.request(ctx, method)
+	err := protocol.Call(ctx, c.session().conn, method, &params, &items)
+	err = done(err)
+	if err != nil {
+		return nil, unsupportedCall(method, err)
+	}
+	return items, nil
+}
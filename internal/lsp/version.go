@@ -0,0 +1,106 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/semver"
+)
+
+// versionTimeout bounds the tsgo --version probe.
+const versionTimeout = 10 * time.Second
+
+// ParseTsgoVersion extracts the version from tsgo --version output, which
+// looks like "Version 7.0.0-dev.20250610.1" but varies between preview
+// builds.
+func ParseTsgoVersion(output string) (semver.Version, error) {
+	for _, field := range strings.Fields(output) {
+		field = strings.Trim(field, "()[],;:'\"")
+		if v, err := semver.Parse(field); err == nil {
+			return v, nil
+		}
+	}
+	return semver.Version{}, fmt.Errorf("no version in tsgo output %q", strings.TrimSpace(output))
+}
+
+// TsgoVersion runs bin --version and returns the version it reports.
+func TsgoVersion(ctx context.Context, bin string) (semver.Version, error) {
+	ctx, cancel := context.WithTimeout(ctx, versionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "--version").Output()
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("%s --version: %w", bin, err)
+	}
+	return ParseTsgoVersion(string(out))
+}
+
+// VersionRequirement is the tsgo version range the server insists on.
+type VersionRequirement struct {
+	Range semver.Range
+	// WarnOnly starts the server on a mismatch instead of refusing to.
+	WarnOnly bool
+}
+
+// VersionRequirementFromEnv reads TYPESCRIPT_MCP_TSGO_VERSION and
+// TYPESCRIPT_MCP_TSGO_VERSION_WARN_ONLY. It returns nil when no range is
+// configured.
+func VersionRequirementFromEnv() (*VersionRequirement, error) {
+	raw := strings.TrimSpace(os.Getenv("TYPESCRIPT_MCP_TSGO_VERSION"))
+	if raw == "" {
+		return nil, nil
+	}
+	r, err := semver.ParseRange(raw)
+	if err != nil {
+		return nil, fmt.Errorf("TYPESCRIPT_MCP_TSGO_VERSION: %w", err)
+	}
+	warnOnly := os.Getenv("TYPESCRIPT_MCP_TSGO_VERSION_WARN_ONLY")
+	return &VersionRequirement{Range: r, WarnOnly: warnOnly != "" && warnOnly != "0"}, nil
+}
+
+// Check returns an error describing why version, as detected (empty when
+// detection failed), does not satisfy the requirement.
+func (r *VersionRequirement) Check(version string) error {
+	if version == "" {
+		return fmt.Errorf("could not determine the tsgo version, required %q (TYPESCRIPT_MCP_TSGO_VERSION)", r.Range)
+	}
+	v, err := semver.Parse(version)
+	if err != nil {
+		return err
+	}
+	if !r.Range.Contains(v) {
+		return fmt.Errorf("tsgo %s does not satisfy required version %q (TYPESCRIPT_MCP_TSGO_VERSION); install a matching build with: npm install -g @typescript/native-preview@<version>", version, r.Range)
+	}
+	return nil
+}
+
+// checkTsgoVersion detects bin's version and applies the configured
+// requirement. A mismatch is an error unless the requirement is warn-only,
+// in which case it is returned as warning.
+func checkTsgoVersion(ctx context.Context, bin string) (version, warning string, err error) {
+	req, err := VersionRequirementFromEnv()
+	if err != nil {
+		return "", "", err
+	}
+	if v, err := TsgoVersion(ctx, bin); err != nil {
+		slog.Warn("cannot determine tsgo version", "path", bin, "error", err)
+	} else {
+		version = v.String()
+		slog.Info("tsgo", "path", bin, "version", version)
+	}
+	if req == nil {
+		return version, "", nil
+	}
+	if err := req.Check(version); err != nil {
+		if !req.WarnOnly {
+			return version, "", err
+		}
+		slog.Warn("tsgo version mismatch", "error", err)
+		return version, err.Error(), nil
+	}
+	return version, "", nil
+}
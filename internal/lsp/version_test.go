@@ -0,0 +1,110 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseTsgoVersion(t *testing.T) {
+	// testdata/tsgo-version holds --version output of tsgo preview builds.
+	tests := map[string]string{
+		"preview-may-2025.txt":       "7.0.0-dev.20250523.1",
+		"preview-build-metadata.txt": "7.0.0-dev.20251022.1",
+		"bare.txt":                   "7.0.0-dev.20250610.1",
+		"verbose.txt":                "7.0.0-dev.20250702.1",
+	}
+	for name, want := range tests {
+		data, err := os.ReadFile(filepath.Join("testdata", "tsgo-version", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := ParseTsgoVersion(string(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if v.String() != want {
+			t.Errorf("%s: version = %s, want %s", name, v, want)
+		}
+	}
+
+	if _, err := ParseTsgoVersion("tsgo: unknown flag --version\n"); err == nil {
+		t.Error("expected an error for output without a version")
+	}
+}
+
+func TestVersionRequirementFromEnv(t *testing.T) {
+	t.Setenv("TYPESCRIPT_MCP_TSGO_VERSION", "")
+	if req, err := VersionRequirementFromEnv(); req != nil || err != nil {
+		t.Errorf("unset: got %v, %v; want nil, nil", req, err)
+	}
+
+	t.Setenv("TYPESCRIPT_MCP_TSGO_VERSION", "not a range")
+	if _, err := VersionRequirementFromEnv(); err == nil {
+		t.Error("expected an error for an invalid range")
+	}
+
+	t.Setenv("TYPESCRIPT_MCP_TSGO_VERSION", ">=7.0.0-dev.20250601")
+	t.Setenv("TYPESCRIPT_MCP_TSGO_VERSION_WARN_ONLY", "1")
+	req, err := VersionRequirementFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !req.WarnOnly {
+		t.Error("WarnOnly = false, want true")
+	}
+	if err := req.Check("7.0.0-dev.20250610.1"); err != nil {
+		t.Errorf("Check(matching) = %v", err)
+	}
+	if err := req.Check("7.0.0-dev.20250523.1"); err == nil || !strings.Contains(err.Error(), "does not satisfy") {
+		t.Errorf("Check(older) = %v, want a mismatch", err)
+	}
+	if err := req.Check(""); err == nil {
+		t.Error("Check(unknown) should fail")
+	}
+}
+
+func TestCheckTsgoVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as a fake tsgo")
+	}
+	bin := filepath.Join(t.TempDir(), "tsgo")
+	script := "#!/bin/sh\necho 'Version 7.0.0-dev.20250523.1'\n"
+	if err := os.WriteFile(bin, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		constraint string
+		warnOnly   string
+		wantErr    bool
+		wantWarn   bool
+	}{
+		{name: "no requirement"},
+		{name: "satisfied", constraint: "^7.0.0-dev"},
+		{name: "mismatch refuses", constraint: ">=7.0.0-dev.20250601", wantErr: true},
+		{name: "mismatch warns", constraint: ">=7.0.0-dev.20250601", warnOnly: "1", wantWarn: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TYPESCRIPT_MCP_TSGO_VERSION", tt.constraint)
+			t.Setenv("TYPESCRIPT_MCP_TSGO_VERSION_WARN_ONLY", tt.warnOnly)
+			version, warning, err := checkTsgoVersion(ctx, bin)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if version != "7.0.0-dev.20250523.1" {
+				t.Errorf("version = %q", version)
+			}
+			if (warning != "") != tt.wantWarn {
+				t.Errorf("warning = %q, wantWarn %v", warning, tt.wantWarn)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.lsp.dev/protocol"
+)
+
+// WorkspaceEdit is a decoded workspace edit. protocol.WorkspaceEdit has no
+// room for the resource operations documentChanges may mix in between its
+// text edits, so they are kept beside it in Operations.
+type WorkspaceEdit struct {
+	protocol.WorkspaceEdit
+	// Operations are the create, rename and delete operations of
+	// documentChanges, in order.
+	Operations []ResourceOperation `json:"-"`
+}
+
+// ResourceOperation is a create, rename or delete operation of a workspace
+// edit.
+type ResourceOperation struct {
+	Kind protocol.ResourceOperationKind
+	// URI is the file created or deleted, or the file renamed.
+	URI protocol.DocumentURI
+	// NewURI is where a renamed file goes.
+	NewURI protocol.DocumentURI
+	// Overwrite and IgnoreIfExists decide what a create or rename does
+	// when its target exists; Overwrite wins.
+	Overwrite, IgnoreIfExists bool
+	// IgnoreIfNotExists makes deleting a missing file a no-op.
+	IgnoreIfNotExists bool
+	// Recursive allows deleting a directory with its content.
+	Recursive bool
+	// Index is the number of DocumentChanges entries before the
+	// operation: it applies after them and before the rest.
+	Index int
+}
+
+// decodeWorkspaceEdit decodes a WorkspaceEdit result. Text edits go into
+// the protocol.WorkspaceEdit and resource operations into Operations; an
+// operation of an unknown kind fails the decoding.
+func decodeWorkspaceEdit(raw json.RawMessage) (*WorkspaceEdit, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var wire struct {
+		Changes           map[protocol.DocumentURI][]protocol.TextEdit                      `json:"changes,omitempty"`
+		DocumentChanges   []json.RawMessage                                                 `json:"documentChanges,omitempty"`
+		ChangeAnnotations map[protocol.ChangeAnnotationIdentifier]protocol.ChangeAnnotation `json:"changeAnnotations,omitempty"`
+	}
+	if err := json.Unmarshal(raw, &wire); err != nil {
+		return nil, fmt.Errorf("decoding workspace edit: %w", err)
+	}
+	edit := &WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: wire.Changes, ChangeAnnotations: wire.ChangeAnnotations}}
+	for _, change := range wire.DocumentChanges {
+		var op struct {
+			Kind    protocol.ResourceOperationKind `json:"kind"`
+			URI     protocol.DocumentURI           `json:"uri"`
+			OldURI  protocol.DocumentURI           `json:"oldUri"`
+			NewURI  protocol.DocumentURI           `json:"newUri"`
+			Options struct {
+				Overwrite         bool `json:"overwrite"`
+				IgnoreIfExists    bool `json:"ignoreIfExists"`
+				IgnoreIfNotExists bool `json:"ignoreIfNotExists"`
+				Recursive         bool `json:"recursive"`
+			} `json:"options"`
+		}
+		if err := json.Unmarshal(change, &op); err != nil {
+			return nil, fmt.Errorf("decoding workspace edit: %w", err)
+		}
+		operation := ResourceOperation{
+			Kind:              op.Kind,
+			URI:               op.URI,
+			Overwrite:         op.Options.Overwrite,
+			IgnoreIfExists:    op.Options.IgnoreIfExists,
+			IgnoreIfNotExists: op.Options.IgnoreIfNotExists,
+			Recursive:         op.Options.Recursive,
+			Index:             len(edit.DocumentChanges),
+		}
+		switch op.Kind {
+		case "":
+			var te protocol.TextDocumentEdit
+			if err := json.Unmarshal(change, &te); err != nil {
+				return nil, fmt.Errorf("decoding workspace edit: %w", err)
+			}
+			edit.DocumentChanges = append(edit.DocumentChanges, te)
+			continue
+		case protocol.CreateResourceOperation, protocol.DeleteResourceOperation:
+		case protocol.RenameResourceOperation:
+			operation.URI, operation.NewURI = op.OldURI, op.NewURI
+		default:
+			return nil, fmt.Errorf("unsupported %s operation on %s in workspace edit", op.Kind, op.URI)
+		}
+		edit.Operations = append(edit.Operations, operation)
+	}
+	return edit, nil
+}
+
+// CodeAction is a protocol.CodeAction whose edit keeps its resource
+// operations.
+type CodeAction struct {
+	protocol.CodeAction
+	// Edit replaces CodeAction.Edit, which stays nil.
+	Edit *WorkspaceEdit `json:"edit,omitempty"`
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDecodeWorkspaceEdit(t *testing.T) {
+	raw := `{"documentChanges": [
+		{"kind": "create", "uri": "file:///p/new.ts", "options": {"ignoreIfExists": true}},
+		{"textDocument": {"uri": "file:///p/new.ts", "version": null}, "edits": [
+			{"range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 0}}, "newText": "export {};\n"}
+		]},
+		{"kind": "rename", "oldUri": "file:///p/a.ts", "newUri": "file:///p/b.ts", "options": {"overwrite": true}},
+		{"kind": "delete", "uri": "file:///p/old.ts", "options": {"ignoreIfNotExists": true}}
+	]}`
+	edit, err := decodeWorkspaceEdit(json.RawMessage(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edit.DocumentChanges) != 1 {
+		t.Fatalf("document changes = %+v", edit.DocumentChanges)
+	}
+	if change := edit.DocumentChanges[0]; len(change.Edits) != 1 || change.Edits[0].NewText != "export {};\n" {
+		t.Errorf("text change = %+v", change)
+	}
+	want := []ResourceOperation{
+		{Kind: "create", URI: "file:///p/new.ts", IgnoreIfExists: true, Index: 0},
+		{Kind: "rename", URI: "file:///p/a.ts", NewURI: "file:///p/b.ts", Overwrite: true, Index: 1},
+		{Kind: "delete", URI: "file:///p/old.ts", IgnoreIfNotExists: true, Index: 1},
+	}
+	if len(edit.Operations) != len(want) {
+		t.Fatalf("operations = %+v, want %+v", edit.Operations, want)
+	}
+	for i, op := range edit.Operations {
+		if op != want[i] {
+			t.Errorf("operation %d = %+v, want %+v", i, op, want[i])
+		}
+	}
+
+	if edit, err := decodeWorkspaceEdit(json.RawMessage("null")); edit != nil || err != nil {
+		t.Errorf("null = %+v, %v", edit, err)
+	}
+	_, err = decodeWorkspaceEdit(json.RawMessage(`{"documentChanges": [{"kind": "chmod", "uri": "file:///p/old.ts"}]}`))
+	if err == nil || !strings.Contains(err.Error(), "unsupported chmod operation on file:///p/old.ts") {
+		t.Errorf("unknown operation: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestParseWorkspaceSymbolItem(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want protocol.SymbolInformation
+		ok   bool
+	}{
+		{
+			name: "SymbolInformation",
+			raw:  `{"name": "Runner", "kind": 5, "containerName": "mod", "location": {"uri": "file:///p/run.ts", "range": {"start": {"line": 8, "character": 13}, "end": {"line": 8, "character": 19}}}}`,
+			want: protocol.SymbolInformation{Name: "Runner", Kind: protocol.SymbolKindClass, ContainerName: "mod", Location: protocol.Location{
+				URI:   "file:///p/run.ts",
+				Range: protocol.Range{Start: protocol.Position{Line: 8, Character: 13}, End: protocol.Position{Line: 8, Character: 19}},
+			}},
+			ok: true,
+		},
+		{
+			name: "WorkspaceSymbol without a range",
+			raw:  `{"name": "greet", "kind": 12, "location": {"uri": "file:///p/index.ts"}}`,
+			want: protocol.SymbolInformation{Name: "greet", Kind: protocol.SymbolKindFunction, Location: protocol.Location{URI: "file:///p/index.ts"}},
+			ok:   true,
+		},
+		{
+			name: "no location",
+			raw:  `{"name": "x", "kind": 13}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseWorkspaceSymbolItem(json.RawMessage(tt.raw))
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && (got.Name != tt.want.Name || got.Kind != tt.want.Kind || got.ContainerName != tt.want.ContainerName || got.Location != tt.want.Location) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
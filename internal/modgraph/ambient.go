@@ -0,0 +1,179 @@
+package modgraph
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/jslex"
+)
+
+// Reference is a triple-slash reference directive.
+type Reference struct {
+	// Kind is "path", "types" or "lib".
+	Kind   string
+	Target string
+	Line   int
+}
+
+// AmbientDeclaration is a name a file declares in the global scope.
+type AmbientDeclaration struct {
+	Name string
+	// Kind is the declaring keyword: const, let, var, function, class,
+	// enum, "const enum", namespace, interface or type.
+	Kind string
+	// Line and Column are 1-based and locate the name.
+	Line   int
+	Column int
+	// InGlobalBlock is set for members of a "declare global" block, which
+	// module files use to augment the global scope.
+	InGlobalBlock bool
+}
+
+// AmbientModule is a `declare module "name"` statement.
+type AmbientModule struct {
+	Name string
+	Line int
+	// Augmentation is set when the declaring file is itself a module, so
+	// the statement extends an existing module instead of declaring one.
+	Augmentation bool
+}
+
+// Ambient lists what a file contributes outside its own module scope.
+type Ambient struct {
+	References []Reference
+	Globals    []AmbientDeclaration
+	Modules    []AmbientModule
+	// GlobalBlocks are the lines of its "declare global" blocks.
+	GlobalBlocks []int
+	// IsModule is set when the file has a top-level import or export, so
+	// its other top-level declarations are not global.
+	IsModule bool
+}
+
+// Empty reports whether the file declares nothing ambient.
+func (a Ambient) Empty() bool {
+	return len(a.References) == 0 && len(a.Globals) == 0 && len(a.Modules) == 0 && len(a.GlobalBlocks) == 0
+}
+
+var (
+	referenceDirective = regexp.MustCompile(`^\s*///\s*<reference\s+(path|types|lib)\s*=\s*(['"])([^'"]+)['"]`)
+	declareGlobal      = regexp.MustCompile(`^\s*declare\s+global\b`)
+	declareModule      = regexp.MustCompile(`^\s*declare\s+module\s+(['"])([^'"]+)['"]`)
+	declaration        = regexp.MustCompile(`^\s*(export\s+)?(declare\s+)?(?:abstract\s+)?(const\s+enum|const|let|var|function|class|enum|namespace|module|interface|type)\s+([A-Za-z_$][\w$]*)`)
+	umdGlobal          = regexp.MustCompile(`^\s*export\s+as\s+namespace\s+([A-Za-z_$][\w$]*)`)
+	moduleSyntax       = regexp.MustCompile(`^\s*(?:import|export)\b`)
+)
+
+// ScanAmbient returns the triple-slash references, global declarations
+// and ambient modules of a TypeScript source. Like ScanImports it is
+// line-based and tracks brace depth to tell top-level statements and
+// "declare global" members apart; braces in strings and comments are
+// ignored.
+//
+// In a script (a file without top-level import or export), top-level
+// statements declared with "declare", interfaces and type aliases are
+// global. In a module only "declare global" members and "export as
+// namespace" are.
+func ScanAmbient(content string) Ambient {
+	lines := strings.Split(content, "\n")
+	code := stripComments(lines)
+
+	var a Ambient
+	for i, line := range lines {
+		if m := referenceDirective.FindStringSubmatch(line); m != nil {
+			a.References = append(a.References, Reference{Kind: m[1], Target: m[3], Line: i + 1})
+		}
+	}
+
+	type topLevel struct {
+		decl     AmbientDeclaration
+		declared bool
+	}
+	var (
+		top   []topLevel
+		depth int
+		// globalDepth is the depth inside the innermost "declare global"
+		// block, or -1 outside one; pendingGlobal is set between the
+		// keyword and its opening brace.
+		globalDepth   = -1
+		pendingGlobal bool
+	)
+	for i, line := range code {
+		switch {
+		case depth == 0:
+			if declareGlobal.MatchString(line) {
+				a.GlobalBlocks = append(a.GlobalBlocks, i+1)
+				pendingGlobal = true
+			} else if m := declareModule.FindStringSubmatch(line); m != nil {
+				a.Modules = append(a.Modules, AmbientModule{Name: m[2], Line: i + 1})
+			} else if m := umdGlobal.FindStringSubmatchIndex(line); m != nil {
+				a.IsModule = true
+				a.Globals = append(a.Globals, AmbientDeclaration{Name: line[m[2]:m[3]], Kind: "namespace", Line: i + 1, Column: m[2] + 1})
+			} else if m := declaration.FindStringSubmatchIndex(line); m != nil {
+				if m[2] >= 0 {
+					a.IsModule = true
+				}
+				top = append(top, topLevel{decl: declarationAt(line, m, i+1), declared: m[4] >= 0})
+			} else if moduleSyntax.MatchString(line) {
+				a.IsModule = true
+			}
+		case globalDepth >= 0 && depth == globalDepth:
+			if m := declaration.FindStringSubmatchIndex(line); m != nil {
+				d := declarationAt(line, m, i+1)
+				d.InGlobalBlock = true
+				a.Globals = append(a.Globals, d)
+			}
+		}
+
+		for _, c := range braces(line) {
+			if c == '{' {
+				depth++
+				if pendingGlobal {
+					globalDepth, pendingGlobal = depth, false
+				}
+				continue
+			}
+			if depth == globalDepth {
+				globalDepth = -1
+			}
+			depth = max(depth-1, 0)
+		}
+	}
+
+	for i := range a.Modules {
+		a.Modules[i].Augmentation = a.IsModule
+	}
+	if !a.IsModule {
+		for _, t := range top {
+			if t.declared || t.decl.Kind == "interface" || t.decl.Kind == "type" {
+				a.Globals = append(a.Globals, t.decl)
+			}
+		}
+	}
+	sort.SliceStable(a.Globals, func(i, j int) bool { return a.Globals[i].Line < a.Globals[j].Line })
+	return a
+}
+
+func declarationAt(line string, m []int, lineNum int) AmbientDeclaration {
+	kind := strings.Join(strings.Fields(line[m[6]:m[7]]), " ")
+	if kind == "module" {
+		kind = "namespace"
+	}
+	return AmbientDeclaration{Name: line[m[8]:m[9]], Kind: kind, Line: lineNum, Column: m[8] + 1}
+}
+
+// braces returns the braces of a comment-free line that are outside
+// strings and template literal text, in order.
+func braces(line string) []byte {
+	var out []byte
+	var lex jslex.Scanner
+	for i := 0; i < len(line); {
+		kind, next := lex.Next(line, i)
+		if c := line[i]; kind == jslex.Code && (c == '{' || c == '}') {
+			out = append(out, c)
+		}
+		i = next
+	}
+	return out
+}
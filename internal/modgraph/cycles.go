@@ -0,0 +1,122 @@
+package modgraph
+
+// CycleOptions bounds cycle enumeration.
+type CycleOptions struct {
+	// MaxLength is the longest cycle reported, in files.
+	MaxLength int
+	// MaxCycles caps the number of cycles returned.
+	MaxCycles int
+	// IncludeTypeOnly follows type-only imports. They are erased at
+	// runtime, so cycles through them are usually harmless.
+	IncludeTypeOnly bool
+}
+
+// maxCycleSteps bounds the total search work; dense graphs have
+// exponentially many long cycles.
+const maxCycleSteps = 2_000_000
+
+// Cycle is an elementary import cycle: Edges[i].To == Edges[i+1].From and
+// the last edge returns to Edges[0].From.
+type Cycle struct {
+	Edges []Edge
+	// TypeOnly is set when any edge is a type-only import, so the cycle
+	// does not exist at runtime.
+	TypeOnly bool
+}
+
+// Files returns the files of the cycle in import order.
+func (c Cycle) Files() []string {
+	out := make([]string, len(c.Edges))
+	for i, e := range c.Edges {
+		out[i] = e.From
+	}
+	return out
+}
+
+// CyclesThrough returns the elementary cycles that contain file, shortest
+// first. truncated is set when MaxCycles or the search budget cut the
+// result short.
+func (g *Graph) CyclesThrough(file string, opts CycleOptions) (cycles []Cycle, truncated bool) {
+	return g.cycles([]string{file}, false, func(Cycle) bool { return true }, opts)
+}
+
+// CyclesWithin returns the elementary cycles that contain at least one
+// file for which inScope is true, shortest first. Each cycle is reported
+// once, starting at its lexically smallest file.
+func (g *Graph) CyclesWithin(inScope func(string) bool, opts CycleOptions) (cycles []Cycle, truncated bool) {
+	keep := func(c Cycle) bool {
+		for _, e := range c.Edges {
+			if inScope(e.From) {
+				return true
+			}
+		}
+		return false
+	}
+	return g.cycles(g.nodes(), true, keep, opts)
+}
+
+// cycles runs a depth-limited search from each start for every length up
+// to MaxLength, so shorter cycles are found first. With minStart, only
+// files greater than the start are visited, which reports each cycle
+// exactly once.
+func (g *Graph) cycles(starts []string, minStart bool, keep func(Cycle) bool, opts CycleOptions) ([]Cycle, bool) {
+	var (
+		out    []Cycle
+		steps  int
+		path   []Edge
+		onPath = make(map[string]bool)
+	)
+	// One cycle past the cap is collected so truncation is only reported
+	// when something was actually left out.
+	full := func() bool { return len(out) > opts.MaxCycles || steps > maxCycleSteps }
+
+	var visit func(start, node string, length int)
+	visit = func(start, node string, length int) {
+		for _, e := range g.out[node] {
+			if full() {
+				return
+			}
+			steps++
+			if e.TypeOnly && !opts.IncludeTypeOnly {
+				continue
+			}
+			if e.To == start {
+				if len(path)+1 != length {
+					continue
+				}
+				c := Cycle{Edges: append(append([]Edge(nil), path...), e)}
+				for _, ce := range c.Edges {
+					c.TypeOnly = c.TypeOnly || ce.TypeOnly
+				}
+				if keep(c) {
+					out = append(out, c)
+				}
+				continue
+			}
+			if onPath[e.To] || len(path)+1 >= length || (minStart && e.To < start) {
+				continue
+			}
+			onPath[e.To] = true
+			path = append(path, e)
+			visit(start, e.To, length)
+			path = path[:len(path)-1]
+			onPath[e.To] = false
+		}
+	}
+
+	for length := 1; length <= opts.MaxLength && !full(); length++ {
+		for _, s := range starts {
+			if full() {
+				break
+			}
+			onPath[s] = true
+			visit(s, s, length)
+			onPath[s] = false
+		}
+	}
+	truncated := full()
+	if len(out) > opts.MaxCycles {
+		out = out[:opts.MaxCycles]
+	}
+	return out, truncated
+}
@@ -0,0 +1,178 @@
+package modgraph
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// Edge is an import from one project file of another.
+type Edge struct {
+	From string
+	To   string
+	// Line, Specifier, TypeOnly and Text describe the import statement in
+	// From that creates the edge.
+	Line      int
+	Specifier string
+	TypeOnly  bool
+	Text      string
+}
+
+// Graph is the import graph of a set of project files. Imports that do
+// not resolve to one of those files (packages, missing files) are not
+// part of it.
+type Graph struct {
+	out map[string][]Edge
+}
+
+// Resolver maps import specifiers to project files the way the compiler
+// would for relative and compilerOptions.paths specifiers, probing
+// TypeScript extensions and directory index files.
+type Resolver struct {
+	paths *tsconfig.PathMapper
+	files map[string]bool
+}
+
+// NewResolver creates a resolver over files; paths may be nil.
+func NewResolver(paths *tsconfig.PathMapper, files []string) *Resolver {
+	set := make(map[string]bool, len(files))
+	for _, f := range files {
+		set[f] = true
+	}
+	return &Resolver{paths: paths, files: set}
+}
+
+// probeExtensions are tried, in order, after a specifier without a
+// recognized extension.
+var probeExtensions = []string{".ts", ".tsx", ".d.ts", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"}
+
+// jsToTS maps the output extensions written in Node16-style specifiers to
+// the source extensions they are compiled from.
+var jsToTS = map[string][]string{
+	".js":  {".ts", ".tsx"},
+	".jsx": {".tsx"},
+	".mjs": {".mts"},
+	".cjs": {".cts"},
+}
+
+// Resolve returns the project file specifier refers to from the file
+// fromFile, or false when it is external or does not resolve.
+func (r *Resolver) Resolve(fromFile, specifier string) (string, bool) {
+	var bases []string
+	switch tsconfig.ClassifySpecifier(specifier, r.paths) {
+	case tsconfig.ModuleRelative:
+		if filepath.IsAbs(specifier) {
+			bases = []string{filepath.Clean(specifier)}
+		} else {
+			bases = []string{filepath.Join(filepath.Dir(fromFile), filepath.FromSlash(specifier))}
+		}
+	case tsconfig.ModuleAlias:
+		for _, c := range r.paths.Resolve(specifier) {
+			bases = append(bases, filepath.FromSlash(c))
+		}
+	default:
+		return "", false
+	}
+	for _, b := range bases {
+		if f, ok := r.probe(b); ok {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+func (r *Resolver) probe(base string) (string, bool) {
+	ext := filepath.Ext(base)
+	for _, src := range jsToTS[ext] {
+		if f := strings.TrimSuffix(base, ext) + src; r.files[f] {
+			return f, true
+		}
+	}
+	if r.files[base] {
+		return base, true
+	}
+	for _, e := range probeExtensions {
+		if r.files[base+e] {
+			return base + e, true
+		}
+	}
+	for _, e := range probeExtensions {
+		if f := filepath.Join(base, "index"+e); r.files[f] {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// Build scans files and links their imports with r. read returns a file's
+// content; files that cannot be read have no outgoing edges. Several
+// imports of the same file collapse into one edge, preferring a runtime
+// import over a type-only one.
+func Build(files []string, r *Resolver, read func(string) ([]byte, error)) *Graph {
+	if read == nil {
+		read = os.ReadFile
+	}
+	g := &Graph{out: make(map[string][]Edge, len(files))}
+	for _, f := range files {
+		content, err := read(f)
+		if err != nil {
+			continue
+		}
+		seen := make(map[string]int)
+		for _, imp := range ScanImports(string(content)) {
+			to, ok := r.Resolve(f, imp.Specifier)
+			if !ok {
+				continue
+			}
+			e := Edge{From: f, To: to, Line: imp.Line, Specifier: imp.Specifier, TypeOnly: imp.TypeOnly, Text: imp.Text}
+			if i, dup := seen[to]; dup {
+				if g.out[f][i].TypeOnly && !e.TypeOnly {
+					g.out[f][i] = e
+				}
+				continue
+			}
+			seen[to] = len(g.out[f])
+			g.out[f] = append(g.out[f], e)
+		}
+		sort.Slice(g.out[f], func(i, j int) bool { return g.out[f][i].To < g.out[f][j].To })
+	}
+	return g
+}
+
+// NewGraph builds a graph directly from edges, for callers that already
+// know them.
+func NewGraph(edges []Edge) *Graph {
+	g := &Graph{out: make(map[string][]Edge)}
+	for _, e := range edges {
+		g.out[e.From] = append(g.out[e.From], e)
+	}
+	for f := range g.out {
+		sort.Slice(g.out[f], func(i, j int) bool { return g.out[f][i].To < g.out[f][j].To })
+	}
+	return g
+}
+
+// Imports returns the outgoing edges of file, sorted by target.
+func (g *Graph) Imports(file string) []Edge {
+	return g.out[file]
+}
+
+// nodes returns every file with an outgoing or incoming edge, sorted.
+func (g *Graph) nodes() []string {
+	set := make(map[string]bool)
+	for f, edges := range g.out {
+		set[f] = true
+		for _, e := range edges {
+			set[e.To] = true
+		}
+	}
+	out := make([]string, 0, len(set))
+	for f := range set {
+		out = append(out, f)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,122 @@
+// Package modgraph reads the project's module structure from source text,
+// without tsgo: the import graph, circular imports in it, and ambient
+// declarations.
+package modgraph
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/jslex"
+)
+
+// Import is one static import, re-export or require call in a file.
+type Import struct {
+	Specifier string
+	// Line is the 1-based line the statement starts on.
+	Line int
+	// TypeOnly is set for statements erased at runtime: "import type",
+	// "export type ... from", and named imports whose every binding has
+	// the type modifier.
+	TypeOnly bool
+	// Text is the statement's first line, trimmed.
+	Text string
+}
+
+// maxStatementLines bounds how far a multi-line import list is followed
+// looking for its "from" clause.
+const maxStatementLines = 100
+
+var (
+	statementStart = regexp.MustCompile(`^\s*(?:import|export)\b`)
+	fromClause     = regexp.MustCompile(`\bfrom\s*(['"])([^'"\n]+)['"]`)
+	sideEffect     = regexp.MustCompile(`^\s*import\s*(['"])([^'"\n]+)['"]`)
+	requireCall    = regexp.MustCompile(`\brequire\s*\(\s*(['"])([^'"\n]+)['"]\s*\)`)
+)
+
+// ScanImports returns the imports of a TypeScript or JavaScript source in
+// order. It is line-based: import lists spanning several lines are
+// followed to their "from" clause, comments are skipped, and dynamic
+// import() calls are ignored because they do not take part in module
+// initialization order.
+func ScanImports(content string) []Import {
+	lines := strings.Split(content, "\n")
+	code := stripComments(lines)
+
+	var out []Import
+	for i := 0; i < len(code); i++ {
+		line := code[i]
+		if statementStart.MatchString(line) {
+			stmt, end := line, i
+			for !fromClause.MatchString(stmt) && strings.Count(stmt, "{") > strings.Count(stmt, "}") &&
+				end+1 < len(code) && end-i < maxStatementLines {
+				end++
+				stmt += "\n" + code[end]
+			}
+			if m := fromClause.FindStringSubmatch(stmt); m != nil {
+				out = append(out, Import{Specifier: m[2], Line: i + 1, TypeOnly: typeOnly(stmt), Text: strings.TrimSpace(lines[i])})
+				i = end
+				continue
+			}
+			if m := sideEffect.FindStringSubmatch(line); m != nil {
+				out = append(out, Import{Specifier: m[2], Line: i + 1, Text: strings.TrimSpace(lines[i])})
+				continue
+			}
+		}
+		for _, m := range requireCall.FindAllStringSubmatch(line, -1) {
+			out = append(out, Import{Specifier: m[2], Line: i + 1, Text: strings.TrimSpace(lines[i])})
+		}
+	}
+	return out
+}
+
+// typeOnly classifies an import or export statement ending in a from
+// clause.
+func typeOnly(stmt string) bool {
+	fields := strings.Fields(stmt)
+	if len(fields) < 2 {
+		return false
+	}
+	// "import type from './x'" imports a default binding named type.
+	if fields[1] == "type" && len(fields) > 2 && fields[2] != "from" && !strings.HasPrefix(fields[2], ",") {
+		return true
+	}
+	clause := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(stmt), fields[0]))
+	if k := fromClause.FindStringIndex(clause); k != nil {
+		clause = strings.TrimSpace(clause[:k[0]])
+	}
+	if !strings.HasPrefix(clause, "{") || !strings.HasSuffix(clause, "}") {
+		return false // default, namespace or star import
+	}
+	named := false
+	for _, b := range strings.Split(clause[1:len(clause)-1], ",") {
+		b = strings.TrimSpace(b)
+		if b == "" {
+			continue
+		}
+		if !strings.HasPrefix(b, "type ") {
+			return false
+		}
+		named = true
+	}
+	return named
+}
+
+// stripComments blanks // and /* */ comments, keeping line numbers.
+// Comment markers inside strings and template literals are respected.
+func stripComments(lines []string) []string {
+	out := make([]string, len(lines))
+	var lex jslex.Scanner
+	for n, line := range lines {
+		var b strings.Builder
+		for i := 0; i < len(line); {
+			kind, next := lex.Next(line, i)
+			if kind != jslex.Comment {
+				b.WriteString(line[i:next])
+			}
+			i = next
+		}
+		out[n] = b.String()
+	}
+	return out
+}
@@ -0,0 +1,412 @@
+package modgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+func TestScanImports(t *testing.T) {
+	src := strings.Join([]string{
+		`import { a } from "./a";`,                 // 1
+		`import type { B } from './b';`,            // 2
+		`import { type C, type D } from "./cd";`,   // 3
+		`import { type E, f } from "./ef";`,        // 4
+		`import type from "./default-named-type";`, // 5
+		`export * from "./star";`,                  // 6
+		`export type { G } from "./g";`,            // 7
+		`import {`,                                 // 8
+		`  h,`,                                     // 9
+		`  i,`,                                     // 10
+		`} from "./hi";`,                           // 11
+		`import "./side-effect";`,                  // 12
+		`const j = require("./j");`,                // 13
+		`// import { k } from "./commented";`,      // 14
+		`/* import { l } from "./block";`,          // 15
+		`   still comment */`,                      // 16
+		`const lazy = import("./dynamic");`,        // 17
+		`const s = "// not a comment"; import { m } from "./m";`,
+		`import React from "react";`,
+		`export const n = { o: 1,`,
+		`  p: require("./p") };`,
+	}, "\n")
+
+	type imp struct {
+		spec     string
+		line     int
+		typeOnly bool
+	}
+	want := []imp{
+		{"./a", 1, false},
+		{"./b", 2, true},
+		{"./cd", 3, true},
+		{"./ef", 4, false},
+		{"./default-named-type", 5, false},
+		{"./star", 6, false},
+		{"./g", 7, true},
+		{"./hi", 8, false},
+		{"./side-effect", 12, false},
+		{"./j", 13, false},
+		{"react", 19, false},
+		{"./p", 21, false},
+	}
+	var got []imp
+	for _, i := range ScanImports(src) {
+		got = append(got, imp{i.Specifier, i.Line, i.TypeOnly})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanImports =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestTypeOnly(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{`import type { A } from "./a"`, true},
+		{`import type * as ns from "./a"`, true},
+		{`import type A from "./a"`, true},
+		{`import type from "./a"`, false},
+		{`import { type A } from "./a"`, true},
+		{"import {\n  type A,\n  type B,\n} from \"./a\"", true},
+		{`import { type A, b } from "./a"`, false},
+		{`import A, { type B } from "./a"`, false},
+		{`import * as ns from "./a"`, false},
+		{`export type { A } from "./a"`, true},
+		{`export { type A } from "./a"`, true},
+		{`export * from "./a"`, false},
+	}
+	for _, tt := range tests {
+		if got := typeOnly(tt.stmt); got != tt.want {
+			t.Errorf("typeOnly(%q) = %v, want %v", tt.stmt, got, tt.want)
+		}
+	}
+}
+
+func TestResolver(t *testing.T) {
+	root := filepath.FromSlash("/proj")
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+	files := []string{
+		abs("src/a.ts"),
+		abs("src/b.tsx"),
+		abs("src/util/index.ts"),
+		abs("src/esm.mts"),
+		abs("src/types.d.ts"),
+		abs("lib/shared.ts"),
+	}
+	paths := tsconfig.NewPathMapper(root, "", map[string][]string{"@lib/*": {"lib/*"}}, []string{"@lib/*"})
+	r := NewResolver(paths, files)
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"./b", abs("src/b.tsx")},
+		{"./b.js", abs("src/b.tsx")},
+		{"./a.js", abs("src/a.ts")},
+		{"./util", abs("src/util/index.ts")},
+		{"./esm.mjs", abs("src/esm.mts")},
+		{"./types", abs("src/types.d.ts")},
+		{"../lib/shared", abs("lib/shared.ts")},
+		{"@lib/shared", abs("lib/shared.ts")},
+		{"./missing", ""},
+		{"react", ""},
+	}
+	for _, tt := range tests {
+		got, ok := r.Resolve(abs("src/a.ts"), tt.spec)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", tt.spec, got, ok, tt.want)
+		}
+	}
+}
+
+// edges builds a graph from "from->to" pairs; a "~>" arrow marks a
+// type-only import.
+func edges(pairs ...string) *Graph {
+	var es []Edge
+	for i, p := range pairs {
+		typeOnly := strings.Contains(p, "~>")
+		from, to, _ := strings.Cut(strings.Replace(p, "~>", "->", 1), "->")
+		es = append(es, Edge{From: from, To: to, Line: i + 1, TypeOnly: typeOnly})
+	}
+	return NewGraph(es)
+}
+
+func cycleStrings(cycles []Cycle) []string {
+	out := make([]string, len(cycles))
+	for i, c := range cycles {
+		out[i] = strings.Join(c.Files(), " ")
+		if c.TypeOnly {
+			out[i] += " (type)"
+		}
+	}
+	return out
+}
+
+func TestCyclesThrough(t *testing.T) {
+	opts := CycleOptions{MaxLength: 10, MaxCycles: 20}
+	tests := []struct {
+		name  string
+		graph *Graph
+		file  string
+		opts  CycleOptions
+		want  []string
+		trunc bool
+	}{
+		{
+			name:  "no cycle",
+			graph: edges("a->b", "b->c"),
+			file:  "a",
+			opts:  opts,
+			want:  []string{},
+		},
+		{
+			name:  "three-file cycle",
+			graph: edges("a->b", "b->c", "c->a", "c->d"),
+			file:  "b",
+			opts:  opts,
+			want:  []string{"b c a"},
+		},
+		{
+			name:  "self import",
+			graph: edges("a->a"),
+			file:  "a",
+			opts:  opts,
+			want:  []string{"a"},
+		},
+		{
+			name:  "shortest first",
+			graph: edges("a->b", "b->c", "c->a", "b->a"),
+			file:  "a",
+			opts:  opts,
+			want:  []string{"a b", "a b c"},
+		},
+		{
+			name:  "cycle not through target is ignored",
+			graph: edges("a->b", "b->c", "c->b"),
+			file:  "a",
+			opts:  opts,
+			want:  []string{},
+		},
+		{
+			name:  "length bound",
+			graph: edges("a->b", "b->c", "c->d", "d->a"),
+			file:  "a",
+			opts:  CycleOptions{MaxLength: 3, MaxCycles: 20},
+			want:  []string{},
+		},
+		{
+			name:  "type-only edge skipped by default",
+			graph: edges("a->b", "b~>a"),
+			file:  "a",
+			opts:  opts,
+			want:  []string{},
+		},
+		{
+			name:  "type-only edge included and flagged",
+			graph: edges("a->b", "b~>a"),
+			file:  "a",
+			opts:  CycleOptions{MaxLength: 10, MaxCycles: 20, IncludeTypeOnly: true},
+			want:  []string{"a b (type)"},
+		},
+		{
+			name:  "cycle cap",
+			graph: edges("a->b", "b->a", "a->c", "c->a", "a->d", "d->a"),
+			file:  "a",
+			opts:  CycleOptions{MaxLength: 10, MaxCycles: 2},
+			want:  []string{"a b", "a c"},
+			trunc: true,
+		},
+		{
+			name:  "cap reached exactly is not truncation",
+			graph: edges("a->b", "b->a", "a->c", "c->a"),
+			file:  "a",
+			opts:  CycleOptions{MaxLength: 10, MaxCycles: 2},
+			want:  []string{"a b", "a c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cycles, trunc := tt.graph.CyclesThrough(tt.file, tt.opts)
+			if got := cycleStrings(cycles); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cycles = %q, want %q", got, tt.want)
+			}
+			if trunc != tt.trunc {
+				t.Errorf("truncated = %v, want %v", trunc, tt.trunc)
+			}
+		})
+	}
+}
+
+func TestCyclesWithin(t *testing.T) {
+	g := edges(
+		"src/a->src/b", "src/b->src/a", // inside src
+		"lib/x->src/c", "src/c->lib/x", // crosses into src
+		"lib/y->lib/z", "lib/z->lib/y", // outside src
+		"src/a->src/d", "src/d->src/e", "src/e->src/a",
+	)
+	inSrc := func(f string) bool { return strings.HasPrefix(f, "src/") }
+	cycles, trunc := g.CyclesWithin(inSrc, CycleOptions{MaxLength: 10, MaxCycles: 20})
+	want := []string{"lib/x src/c", "src/a src/b", "src/a src/d src/e"}
+	if got := cycleStrings(cycles); !reflect.DeepEqual(got, want) || trunc {
+		t.Errorf("cycles = %q (truncated %v), want %q", got, trunc, want)
+	}
+}
+
+func TestCyclesBudget(t *testing.T) {
+	// A complete graph has a huge number of long cycles; the search must
+	// stop and report truncation instead of running away.
+	var pairs []string
+	for i := 0; i < 14; i++ {
+		for j := 0; j < 14; j++ {
+			if i != j {
+				pairs = append(pairs, fmt.Sprintf("n%02d->n%02d", i, j))
+			}
+		}
+	}
+	cycles, trunc := edges(pairs...).CyclesThrough("n00", CycleOptions{MaxLength: 14, MaxCycles: 1 << 30})
+	if !trunc || len(cycles) == 0 {
+		t.Errorf("got %d cycles, truncated %v; want a truncated partial result", len(cycles), trunc)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/a.ts": "import { b } from './b';\nimport type { B } from './b';\n",
+		"src/b.ts": "import type { C } from './c';\nexport const b = 1;\n",
+		"src/c.ts": "import { a } from './a';\nimport { z } from 'zod';\n",
+	}
+	var paths []string
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	g := Build(paths, NewResolver(nil, paths), nil)
+
+	a := filepath.Join(root, "src", "a.ts")
+	got := g.Imports(a)
+	if len(got) != 1 || got[0].Line != 1 || got[0].TypeOnly {
+		data, _ := json.Marshal(got)
+		t.Errorf("a.ts edges = %s, want the runtime import on line 1", data)
+	}
+	if cycles, _ := g.CyclesThrough(a, CycleOptions{MaxLength: 10, MaxCycles: 10}); len(cycles) != 0 {
+		t.Errorf("runtime cycles = %v, want none (b -> c is type-only)", cycleStrings(cycles))
+	}
+	cycles, _ := g.CyclesThrough(a, CycleOptions{MaxLength: 10, MaxCycles: 10, IncludeTypeOnly: true})
+	if len(cycles) != 1 || !cycles[0].TypeOnly || len(cycles[0].Edges) != 3 {
+		t.Errorf("cycles with type-only = %v", cycleStrings(cycles))
+	}
+}
+
+func TestScanAmbient(t *testing.T) {
+	t.Run("script declaration file", func(t *testing.T) {
+		src := strings.Join([]string{
+			`/// <reference path="./vendor.d.ts" />`, // 1
+			`/// <reference types="node" />`,         // 2
+			`/// <reference lib="dom" />`,            // 3
+			`declare const __APP_VERSION__: string;`, // 4
+			`declare function track(event: string): void;`,
+			`interface Window {`, // 6
+			`  analytics: { track(e: string): void };`,
+			`}`,
+			`type Brand<T> = T & { __brand: "{" };`, // 9
+			`declare namespace Config {`,            // 10
+			`  const nested: number;`,
+			`}`,
+			`declare module "*.svg" {`, // 13
+			`  const url: string;`,
+			`  export default url;`,
+			`}`,
+			`declare module "legacy-lib";`, // 17
+			`// declare const commented: number;`,
+			`const notAmbient = 1;`,
+		}, "\n")
+		a := ScanAmbient(src)
+
+		wantRefs := []Reference{{"path", "./vendor.d.ts", 1}, {"types", "node", 2}, {"lib", "dom", 3}}
+		if !reflect.DeepEqual(a.References, wantRefs) {
+			t.Errorf("References = %v, want %v", a.References, wantRefs)
+		}
+		wantGlobals := []AmbientDeclaration{
+			{Name: "__APP_VERSION__", Kind: "const", Line: 4, Column: 15},
+			{Name: "track", Kind: "function", Line: 5, Column: 18},
+			{Name: "Window", Kind: "interface", Line: 6, Column: 11},
+			{Name: "Brand", Kind: "type", Line: 9, Column: 6},
+			{Name: "Config", Kind: "namespace", Line: 10, Column: 19},
+		}
+		if !reflect.DeepEqual(a.Globals, wantGlobals) {
+			t.Errorf("Globals =\n%+v\nwant\n%+v", a.Globals, wantGlobals)
+		}
+		wantModules := []AmbientModule{{Name: "*.svg", Line: 13}, {Name: "legacy-lib", Line: 17}}
+		if !reflect.DeepEqual(a.Modules, wantModules) || a.IsModule {
+			t.Errorf("Modules = %v (module file %v), want %v in a script", a.Modules, a.IsModule, wantModules)
+		}
+	})
+
+	t.Run("module with global augmentation", func(t *testing.T) {
+		src := strings.Join([]string{
+			`import type { User } from "./user";`, // 1
+			`declare const local: number;`,        // 2
+			`declare global`,                      // 3
+			`{`,
+			`  interface Window { currentUser?: User }`, // 5
+			`  var __DEV__: boolean;`,                   // 6
+			`  namespace NodeJS {`,                      // 7
+			`    interface ProcessEnv { API_URL: string }`,
+			`  }`,
+			`}`,
+			`declare module "express-serve-static-core" {`, // 11
+			`  interface Request { user?: User }`,
+			`}`,
+			`export {};`,
+		}, "\n")
+		a := ScanAmbient(src)
+
+		if !a.IsModule || !reflect.DeepEqual(a.GlobalBlocks, []int{3}) {
+			t.Errorf("IsModule = %v, GlobalBlocks = %v", a.IsModule, a.GlobalBlocks)
+		}
+		var got []string
+		for _, d := range a.Globals {
+			if !d.InGlobalBlock {
+				t.Errorf("%s not flagged as a declare global member", d.Name)
+			}
+			got = append(got, fmt.Sprintf("%s %s:%d", d.Kind, d.Name, d.Line))
+		}
+		want := []string{"interface Window:5", "var __DEV__:6", "namespace NodeJS:7"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Globals = %q, want %q", got, want)
+		}
+		wantModules := []AmbientModule{{Name: "express-serve-static-core", Line: 11, Augmentation: true}}
+		if !reflect.DeepEqual(a.Modules, wantModules) {
+			t.Errorf("Modules = %v, want %v", a.Modules, wantModules)
+		}
+	})
+
+	t.Run("UMD global", func(t *testing.T) {
+		a := ScanAmbient("export declare function render(): void;\nexport as namespace MyLib;\n")
+		want := []AmbientDeclaration{{Name: "MyLib", Kind: "namespace", Line: 2, Column: 21}}
+		if !reflect.DeepEqual(a.Globals, want) {
+			t.Errorf("Globals = %+v, want %+v", a.Globals, want)
+		}
+	})
+
+	t.Run("plain source", func(t *testing.T) {
+		if a := ScanAmbient("export const x = 1;\nfunction f() { return \"}\"; }\n"); !a.Empty() {
+			t.Errorf("ScanAmbient = %+v, want nothing", a)
+		}
+	})
+}
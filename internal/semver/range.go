@@ -0,0 +1,270 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Range is a parsed npm style version range: alternatives separated by
+// "||", each a space separated list of comparators that must all hold.
+//
+// Supported forms are exact versions ("1.2.3", "=1.2.3"), comparisons
+// (">=1.2.3", "<2"), x-ranges ("7", "7.x", "7.0.*", "*"), caret ("^7.0.0")
+// and tilde ("~7.0.0") ranges, and hyphen ranges ("1.2 - 1.4").
+type Range struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op string // one of "=", "<", "<=", ">", ">="
+	v  Version
+}
+
+func (c comparator) match(v Version) bool {
+	n := v.Compare(c.v)
+	switch c.op {
+	case "<":
+		return n < 0
+	case "<=":
+		return n <= 0
+	case ">":
+		return n > 0
+	case ">=":
+		return n >= 0
+	}
+	return n == 0
+}
+
+// ParseRange parses a range expression.
+func ParseRange(s string) (Range, error) {
+	r := Range{raw: strings.TrimSpace(s)}
+	for _, alt := range strings.Split(s, "||") {
+		set, err := parseSet(alt)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range %q: %w", r.raw, err)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+// String returns the range as written.
+func (r Range) String() string {
+	return r.raw
+}
+
+// Contains reports whether v satisfies r.
+func (r Range) Contains(v Version) bool {
+	for _, set := range r.sets {
+		ok := true
+		for _, c := range set {
+			if !c.match(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSet(s string) ([]comparator, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 3 && fields[1] == "-" {
+		return hyphenRange(fields[0], fields[2])
+	}
+	// Join operators written apart from their version: ">= 1.2.3".
+	var terms []string
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Trim(f, "<>=^~") == "" && i+1 < len(fields) {
+			f += fields[i+1]
+			i++
+		}
+		terms = append(terms, f)
+	}
+	set := []comparator{}
+	for _, t := range terms {
+		cs, err := parseTerm(t)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cs...)
+	}
+	return set, nil
+}
+
+func parseTerm(t string) ([]comparator, error) {
+	op := t[:len(t)-len(strings.TrimLeft(t, "<>=^~"))]
+	p, err := parsePartial(t[len(op):])
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "", "=":
+		if p.n == 3 {
+			return []comparator{{"=", p.version()}}, nil
+		}
+		return p.span(), nil
+	case "^":
+		return caret(p), nil
+	case "~", "~>":
+		return tilde(p), nil
+	case ">=":
+		return []comparator{{">=", p.lower()}}, nil
+	case "<":
+		return []comparator{{"<", p.lower()}}, nil
+	case ">":
+		if p.n == 3 {
+			return []comparator{{">", p.version()}}, nil
+		}
+		if p.n == 0 {
+			// ">*" matches nothing.
+			return []comparator{{"<", Version{Prerelease: []string{"0"}}}}, nil
+		}
+		return []comparator{{">=", p.next()}}, nil
+	case "<=":
+		if p.n == 3 {
+			return []comparator{{"<=", p.version()}}, nil
+		}
+		if p.n == 0 {
+			return nil, nil
+		}
+		return []comparator{{"<", p.next()}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+func hyphenRange(from, to string) ([]comparator, error) {
+	lo, err := parsePartial(from)
+	if err != nil {
+		return nil, err
+	}
+	hi, err := parsePartial(to)
+	if err != nil {
+		return nil, err
+	}
+	var set []comparator
+	if lo.n > 0 {
+		set = append(set, comparator{">=", lo.lower()})
+	}
+	switch {
+	case hi.n == 3:
+		set = append(set, comparator{"<=", hi.version()})
+	case hi.n > 0:
+		set = append(set, comparator{"<", hi.next()})
+	}
+	return set, nil
+}
+
+// caret allows changes that keep the leftmost non-zero component.
+func caret(p partial) []comparator {
+	var upper Version
+	switch {
+	case p.major > 0 || p.n == 1:
+		upper = Version{Major: p.major + 1}
+	case p.minor > 0 || p.n == 2:
+		upper = Version{Minor: p.minor + 1}
+	case p.n == 3:
+		upper = Version{Patch: p.patch + 1}
+	default:
+		return nil
+	}
+	upper.Prerelease = []string{"0"}
+	return []comparator{{">=", p.lower()}, {"<", upper}}
+}
+
+// tilde allows patch changes when a minor version is given, minor changes
+// otherwise.
+func tilde(p partial) []comparator {
+	var upper Version
+	switch p.n {
+	case 0:
+		return nil
+	case 1:
+		upper = Version{Major: p.major + 1}
+	default:
+		upper = Version{Major: p.major, Minor: p.minor + 1}
+	}
+	upper.Prerelease = []string{"0"}
+	return []comparator{{">=", p.lower()}, {"<", upper}}
+}
+
+// partial is a possibly incomplete version: n counts the leading
+// components that were given rather than omitted or written as x.
+type partial struct {
+	major, minor, patch int
+	n                   int
+	pre                 []string
+}
+
+func parsePartial(s string) (partial, error) {
+	if s == "" {
+		return partial{}, nil
+	}
+	if v, err := Parse(s); err == nil {
+		return partial{major: v.Major, minor: v.Minor, patch: v.Patch, n: 3, pre: v.Prerelease}, nil
+	}
+	s = strings.TrimPrefix(s, "v")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return partial{}, fmt.Errorf("bad version %q", s)
+	}
+	var p partial
+	nums := [3]*int{&p.major, &p.minor, &p.patch}
+	wild := false
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			wild = true
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || wild {
+			return partial{}, fmt.Errorf("bad version %q", s)
+		}
+		*nums[i] = n
+		p.n = i + 1
+	}
+	return p, nil
+}
+
+func (p partial) version() Version {
+	return Version{Major: p.major, Minor: p.minor, Patch: p.patch, Prerelease: p.pre}
+}
+
+// lower is the lowest version p covers; an incomplete version starts at
+// its first prerelease.
+func (p partial) lower() Version {
+	v := p.version()
+	if p.n < 3 {
+		v.Prerelease = []string{"0"}
+	}
+	return v
+}
+
+// next is the lowest version above everything p covers.
+func (p partial) next() Version {
+	v := Version{Prerelease: []string{"0"}}
+	switch p.n {
+	case 1:
+		v.Major = p.major + 1
+	case 2:
+		v.Major, v.Minor = p.major, p.minor+1
+	default:
+		v.Major, v.Minor, v.Patch = p.major, p.minor, p.patch+1
+		v.Prerelease = nil
+	}
+	return v
+}
+
+// span is the x-range covering p.
+func (p partial) span() []comparator {
+	if p.n == 0 {
+		return nil
+	}
+	return []comparator{{">=", p.lower()}, {"<", p.next()}}
+}
@@ -0,0 +1,120 @@
+// Package semver parses semantic versions and matches them against npm
+// style ranges, enough to check which tsgo build is installed.
+//
+// Unlike npm, prerelease versions are not excluded from ranges that name
+// no prerelease: every tsgo build is a prerelease (7.0.0-dev.20250610.1),
+// so "^7" must match them. Ranges with an implied lower bound (x-ranges,
+// ^, ~, hyphen ranges) start at the lowest prerelease of that bound.
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Build metadata is kept but does
+// not take part in comparisons.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          []string
+	Build               string
+}
+
+// Parse parses "1.2.3", "v1.2.3", "1.2.3-dev.20250610.1" or
+// "1.2.3+build".
+func Parse(s string) (Version, error) {
+	orig := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	var v Version
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		pre := s[i+1:]
+		s = s[:i]
+		if pre == "" {
+			return Version{}, fmt.Errorf("invalid version %q: empty prerelease", orig)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+		for _, id := range v.Prerelease {
+			if id == "" {
+				return Version{}, fmt.Errorf("invalid version %q: empty prerelease identifier", orig)
+			}
+		}
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q: want major.minor.patch", orig)
+	}
+	nums := [3]*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q: bad number %q", orig, p)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// String formats v without build metadata.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to, or higher than
+// w in semver precedence.
+func (v Version) Compare(w Version) int {
+	for _, d := range [3][2]int{{v.Major, w.Major}, {v.Minor, w.Minor}, {v.Patch, w.Patch}} {
+		if d[0] != d[1] {
+			return cmpInt(d[0], d[1])
+		}
+	}
+	// A release is higher than any of its prereleases.
+	switch {
+	case len(v.Prerelease) == 0 && len(w.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(w.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(w.Prerelease); i++ {
+		if c := compareIdent(v.Prerelease[i], w.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(v.Prerelease), len(w.Prerelease))
+}
+
+// compareIdent orders prerelease identifiers: numeric ones numerically and
+// below alphanumeric ones, which compare as strings.
+func compareIdent(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return cmpInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package semver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Version
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"v1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"7.0.0-dev.20250610.1", Version{Major: 7, Prerelease: []string{"dev", "20250610", "1"}}},
+		{"7.0.0-dev.20250610.1+8f3a2c1", Version{Major: 7, Prerelease: []string{"dev", "20250610", "1"}, Build: "8f3a2c1"}},
+		{"0.0.0+local", Version{Build: "local"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "1.2", "1.2.3.4", "1.2.x", "1.2.3-", "1.2.3-a..b", "a.b.c", "-1.2.3"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Each version is lower than the next.
+	ordered := []string{
+		"1.0.0-0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.10.0",
+		"7.0.0-dev.20250523.1",
+		"7.0.0-dev.20250610.1",
+		"7.0.0-dev.20250610.2",
+		"7.0.0-dev.20251022.1",
+		"7.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, b := mustParse(t, ordered[i]), mustParse(t, ordered[j])
+			want := cmpInt(i, j)
+			if got := a.Compare(b); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+	if mustParse(t, "1.0.0+a").Compare(mustParse(t, "1.0.0+b")) != 0 {
+		t.Error("build metadata should not affect precedence")
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		rng string
+		in  []string
+		out []string
+	}{
+		{"1.2.3", []string{"1.2.3", "1.2.3+build"}, []string{"1.2.4", "1.2.3-rc.1"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.2"}},
+		{">=1.2.3", []string{"1.2.3", "2.0.0"}, []string{"1.2.2", "1.2.3-rc.1"}},
+		{">1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"<1.2.3", []string{"1.2.2", "1.2.3-rc.1"}, []string{"1.2.3"}},
+		{"<=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{">= 1.2.3 < 2", []string{"1.9.9"}, []string{"2.0.0-dev.1", "1.0.0"}},
+		{"*", []string{"0.0.0", "7.0.0-dev.20250610.1"}, nil},
+		{"", []string{"1.0.0"}, nil},
+		{"7", []string{"7.0.0-dev.20250610.1", "7.3.0"}, []string{"8.0.0-dev.1", "6.9.9"}},
+		{"7.x", []string{"7.0.0-dev.20250610.1"}, []string{"8.0.0"}},
+		{"7.1.*", []string{"7.1.0", "7.1.9"}, []string{"7.2.0", "7.0.9"}},
+		{">7", []string{"8.0.0-dev.1", "8.0.0"}, []string{"7.9.9"}},
+		{">7.1", []string{"7.2.0"}, []string{"7.1.9"}},
+		{"<=7.1", []string{"7.1.9"}, []string{"7.2.0-0", "7.2.0"}},
+		{"<7", []string{"6.9.9"}, []string{"7.0.0-dev.1"}},
+		{">=7", []string{"7.0.0-dev.1"}, []string{"6.9.9"}},
+		{"^7.0.0-dev.20250601", []string{"7.0.0-dev.20250610.1", "7.4.0"}, []string{"7.0.0-dev.20250523.1", "8.0.0-dev.1"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"^0.x", []string{"0.9.0"}, []string{"1.0.0"}},
+		{"~1.2.3", []string{"1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.9.0"}, []string{"2.0.0"}},
+		{"~> 1.2", []string{"1.2.0"}, []string{"1.3.0"}},
+		{"1.2 - 1.4", []string{"1.2.0", "1.4.9"}, []string{"1.5.0", "1.1.9"}},
+		{"1.2.3 - 1.4.0", []string{"1.4.0"}, []string{"1.4.1"}},
+		{"<7 || >=7.0.0-dev.20250610", []string{"6.0.0", "7.0.0-dev.20250610.1"}, []string{"7.0.0-dev.20250523.1"}},
+	}
+	for _, tt := range tests {
+		r, err := ParseRange(tt.rng)
+		if err != nil {
+			t.Errorf("ParseRange(%q): %v", tt.rng, err)
+			continue
+		}
+		for _, v := range tt.in {
+			if !r.Contains(mustParse(t, v)) {
+				t.Errorf("%q should contain %s", tt.rng, v)
+			}
+		}
+		for _, v := range tt.out {
+			if r.Contains(mustParse(t, v)) {
+				t.Errorf("%q should not contain %s", tt.rng, v)
+			}
+		}
+	}
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	for _, bad := range []string{"1.x.3", "1.2.3.4", "abc", "!1.2.3", ">=1.2.3 ||| 2", "1.2.3-"} {
+		if _, err := ParseRange(bad); err == nil {
+			t.Errorf("ParseRange(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func mustParse(t *testing.T, s string) Version {
+	t.Helper()
+	v, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,149 @@
+// Package sourcemap decodes version 3 source maps, enough to map a
+// position in a generated file, such as a .d.ts with a declaration map,
+// back to its original source.
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Map is a decoded source map.
+type Map struct {
+	// Sources are the original sources, with the sourceRoot applied, as
+	// written in the map: usually relative to the map's directory.
+	Sources []string
+	// lines holds the segments of each generated line, by column.
+	lines [][]segment
+}
+
+// segment maps a generated column to an original position. source is -1
+// for a segment without one.
+type segment struct {
+	genCol int
+	source int
+	line   int
+	col    int
+}
+
+type rawMap struct {
+	Version    int      `json:"version"`
+	SourceRoot string   `json:"sourceRoot"`
+	Sources    []string `json:"sources"`
+	Mappings   string   `json:"mappings"`
+}
+
+// Parse decodes the JSON of a source map.
+func Parse(data []byte) (*Map, error) {
+	var raw rawMap
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", raw.Version)
+	}
+	m := &Map{Sources: make([]string, len(raw.Sources))}
+	for i, s := range raw.Sources {
+		if raw.SourceRoot != "" && !path.IsAbs(s) {
+			s = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + s
+		}
+		m.Sources[i] = s
+	}
+	lines, err := decodeMappings(raw.Mappings, len(raw.Sources))
+	if err != nil {
+		return nil, err
+	}
+	m.lines = lines
+	return m, nil
+}
+
+// Original returns the original position of a generated one. Lines and
+// columns are 0-based. The segment starting at or before col on the line
+// is used; ok is false when there is none, or it has no source.
+func (m *Map) Original(line, col int) (source string, origLine, origCol int, ok bool) {
+	if line < 0 || line >= len(m.lines) {
+		return "", 0, 0, false
+	}
+	segs := m.lines[line]
+	i := sort.Search(len(segs), func(i int) bool { return segs[i].genCol > col }) - 1
+	if i < 0 || segs[i].source < 0 {
+		return "", 0, 0, false
+	}
+	s := segs[i]
+	return m.Sources[s.source], s.line, s.col, true
+}
+
+// decodeMappings decodes the mappings field: lines separated by ";",
+// segments by ",", each a list of base64 VLQ fields relative to the
+// previous segment.
+func decodeMappings(mappings string, sources int) ([][]segment, error) {
+	var lines [][]segment
+	var source, line, col int
+	for _, text := range strings.Split(mappings, ";") {
+		var segs []segment
+		genCol := 0
+		for _, field := range strings.Split(text, ",") {
+			if field == "" {
+				continue
+			}
+			values, err := decodeVLQ(field)
+			if err != nil {
+				return nil, err
+			}
+			genCol += values[0]
+			seg := segment{genCol: genCol, source: -1}
+			switch len(values) {
+			case 1:
+			case 4, 5:
+				source += values[1]
+				line += values[2]
+				col += values[3]
+				if source < 0 || source >= sources {
+					return nil, fmt.Errorf("segment %q names source %d of %d", field, source, sources)
+				}
+				seg.source, seg.line, seg.col = source, line, col
+			default:
+				return nil, fmt.Errorf("segment %q has %d fields", field, len(values))
+			}
+			segs = append(segs, seg)
+		}
+		sort.SliceStable(segs, func(i, j int) bool { return segs[i].genCol < segs[j].genCol })
+		lines = append(lines, segs)
+	}
+	return lines, nil
+}
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// decodeVLQ decodes the base64 VLQ values of one segment.
+func decodeVLQ(field string) ([]int, error) {
+	var values []int
+	value, shift := 0, 0
+	for i := 0; i < len(field); i++ {
+		digit := strings.IndexByte(base64Digits, field[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base64 digit %q in segment %q", field[i], field)
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			if shift > 30 {
+				return nil, fmt.Errorf("value too large in segment %q", field)
+			}
+			continue
+		}
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated segment %q", field)
+	}
+	return values, nil
+}
@@ -0,0 +1,87 @@
+package sourcemap
+
+import (
+	"reflect"
+	"testing"
+)
+
+// greetMap is the declaration map tsc writes for
+// `export function greet(name: string): string { ... }` in src/index.ts.
+const greetMap = `{"version":3,"file":"index.d.ts","sourceRoot":"","sources":["../src/index.ts"],"names":[],"mappings":"AAAA,wBAAgB,KAAK,CAAC,IAAI,EAAE,MAAM,GAAG,MAAM,CAE1C"}`
+
+func TestOriginal(t *testing.T) {
+	m, err := Parse([]byte(greetMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		line, col int
+		wantLine  int
+		wantCol   int
+		wantOK    bool
+	}{
+		// "export declare function greet": greet is at column 24 of the
+		// .d.ts and column 16 of the source.
+		{name: "start of a segment", line: 0, col: 24, wantLine: 0, wantCol: 16, wantOK: true},
+		{name: "inside a segment", line: 0, col: 27, wantLine: 0, wantCol: 16, wantOK: true},
+		{name: "start of the line", line: 0, col: 0, wantLine: 0, wantCol: 0, wantOK: true},
+		{name: "line without mappings", line: 3, col: 0},
+		{name: "negative line", line: -1, col: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, line, col, ok := m.Original(tt.line, tt.col)
+			if ok != tt.wantOK || line != tt.wantLine || col != tt.wantCol {
+				t.Errorf("Original(%d, %d) = %d, %d, %v; want %d, %d, %v", tt.line, tt.col, line, col, ok, tt.wantLine, tt.wantCol, tt.wantOK)
+			}
+			if ok && source != "../src/index.ts" {
+				t.Errorf("source = %q", source)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(`{"version":3,"sourceRoot":"lib/","sources":["a.ts","/abs/b.ts"],"mappings":";AAAA;ACCA,E"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"lib/a.ts", "/abs/b.ts"}; !reflect.DeepEqual(m.Sources, want) {
+		t.Errorf("sources = %q, want %q", m.Sources, want)
+	}
+	if _, _, _, ok := m.Original(0, 0); ok {
+		t.Error("the empty first line has a mapping")
+	}
+	// "ACCA" moves to the second source and one line down; "E" has no
+	// source.
+	if source, line, col, ok := m.Original(2, 0); !ok || source != "/abs/b.ts" || line != 1 || col != 0 {
+		t.Errorf("Original(2, 0) = %q, %d, %d, %v", source, line, col, ok)
+	}
+	if _, _, _, ok := m.Original(2, 5); ok {
+		t.Error("a segment without a source mapped")
+	}
+
+	for _, bad := range []string{
+		`{"version":2,"sources":[],"mappings":""}`,
+		`{"version":3,"sources":["a.ts"],"mappings":"AA"}`,
+		`{"version":3,"sources":["a.ts"],"mappings":"ACAA"}`,
+		`{"version":3,"sources":["a.ts"],"mappings":"A!AA"}`,
+		`{"version":3,"sources":["a.ts"],"mappings":"g"}`,
+		`not json`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("Parse(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestDecodeVLQ(t *testing.T) {
+	got, err := decodeVLQ("AACDgBwB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 0, 1, -1, 16, 24}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeVLQ = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// configFile is the JSON file named by TYPESCRIPT_MCP_CONFIG.
+type configFile struct {
+	// StrictTypes turns off argument coercion: arguments reach the
+	// handlers exactly as the client sent them.
+	StrictTypes bool `json:"strictTypes"`
+	// EditOverlayCheck makes ts_rename and ts_apply_edit try every edit
+	// on tsgo as an unsaved overlay first and refuse it when it adds
+	// syntax errors.
+	EditOverlayCheck bool `json:"editOverlayCheck"`
+	// JournalEdits journals every ts_rename and ts_apply_edit write, not
+	// only those touching more than JournalThreshold files (default 50).
+	JournalEdits     bool `json:"journalEdits"`
+	JournalThreshold int  `json:"journalThreshold"`
+	// RecordEdits writes a provenance record of every applied edit to
+	// .typescript-mcp/edits, keeping the newest RecordLimit (default 200).
+	RecordEdits bool `json:"recordEdits"`
+	RecordLimit int  `json:"recordLimit"`
+	// Redaction hides paths and source text from responses.
+	Redaction redactionConfig `json:"redaction"`
+	// GeneratedPaths are globs, relative to the workspace root, of
+	// generated code; ts_diagnostics classifyCauses reports imports of
+	// them as "run codegen". Unset means defaultGeneratedPaths.
+	GeneratedPaths []string `json:"generatedPaths"`
+	// RenameExcludeGlobs are the files ts_rename leaves out of its edits
+	// when the call sets no excludeGlobs. Unset means
+	// defaultRenameExcludeGlobs.
+	RenameExcludeGlobs []string `json:"renameExcludeGlobs"`
+	// Aliases maps an alias tool name to the built-in tool it presets.
+	Aliases map[string]toolAlias `json:"aliases"`
+}
+
+// toolAlias is a named shortcut for a built-in tool with preset arguments.
+type toolAlias struct {
+	Tool        string         `json:"tool"`
+	Description string         `json:"description"`
+	Arguments   map[string]any `json:"arguments"`
+}
+
+// registeredTool is a tool definition together with its wrapped handler.
+type registeredTool struct {
+	tool    mcp.Tool
+	handler server.ToolHandlerFunc
+}
+
+var aliasNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// loadConfigFile reads the config file named by TYPESCRIPT_MCP_CONFIG. It
+// returns an empty config when the variable is unset.
+func loadConfigFile() (*configFile, error) {
+	path := os.Getenv("TYPESCRIPT_MCP_CONFIG")
+	if path == "" {
+		return &configFile{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	var cfg configFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// validateAliases checks aliases against the built-in tools. An alias may
+// not reuse a built-in name, must name an existing tool, and may only
+// preset that tool's parameters.
+func validateAliases(aliases map[string]toolAlias, builtin map[string]registeredTool) error {
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		a := aliases[name]
+		if !aliasNamePattern.MatchString(name) {
+			return fmt.Errorf("alias %q: name must be 1-64 letters, digits, '_' or '-'", name)
+		}
+		if _, ok := builtin[name]; ok {
+			return fmt.Errorf("alias %q shadows the built-in tool of the same name", name)
+		}
+		base, ok := builtin[a.Tool]
+		if !ok {
+			return fmt.Errorf("alias %q: unknown tool %q", name, a.Tool)
+		}
+		for _, arg := range slices.Sorted(maps.Keys(a.Arguments)) {
+			if _, ok := base.tool.InputSchema.Properties[arg]; !ok {
+				return fmt.Errorf("alias %q: %s has no parameter %q", name, a.Tool, arg)
+			}
+		}
+	}
+	return nil
+}
+
+// aliasTool derives the definition of alias name from its base tool. The
+// preset parameters stay in the schema, since callers may override them,
+// but are no longer required.
+func aliasTool(name string, a toolAlias, base mcp.Tool) mcp.Tool {
+	tool := base
+	tool.Name = name
+	tool.Description = a.Description
+	if tool.Description == "" {
+		tool.Description = fmt.Sprintf("Alias of %s with preset arguments.", a.Tool)
+	}
+	tool.InputSchema.Properties = maps.Clone(base.InputSchema.Properties)
+	tool.InputSchema.Required = nil
+	for _, r := range base.InputSchema.Required {
+		if _, preset := a.Arguments[r]; !preset {
+			tool.InputSchema.Required = append(tool.InputSchema.Required, r)
+		}
+	}
+	return tool
+}
+
+// aliasHandler merges the alias's preset arguments under the caller's,
+// which win, and delegates to the base tool's handler.
+func aliasHandler(a toolAlias, base server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := maps.Clone(a.Arguments)
+		if args == nil {
+			args = make(map[string]any)
+		}
+		maps.Copy(args, request.GetArguments())
+		request.Params.Name = a.Tool
+		request.Params.Arguments = args
+		return base(ctx, request)
+	}
+}
+
+// registerAliases validates aliases and adds each one to s as a tool of
+// its own.
+func registerAliases(s *server.MCPServer, aliases map[string]toolAlias, builtin map[string]registeredTool) error {
+	if err := validateAliases(aliases, builtin); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(aliases)) {
+		a := aliases[name]
+		base := builtin[a.Tool]
+		s.AddTool(aliasTool(name, a, base.tool), aliasHandler(a, base.handler))
+	}
+	return nil
+}
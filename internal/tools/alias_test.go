@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// echoTools returns a built-in registry with one tool whose handler
+// reports the name and arguments it was called with.
+func echoTools(got *mcp.CallToolRequest) map[string]registeredTool {
+	tool := mcp.NewTool("ts_diagnostics",
+		mcp.WithDescription("Get TypeScript errors."),
+		mcp.WithString("file", mcp.Required()),
+		mcp.WithNumber("maxResults"),
+	)
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		*got = request
+		return mcp.NewToolResultText("ok"), nil
+	}
+	return map[string]registeredTool{tool.Name: {tool: tool, handler: handler}}
+}
+
+func TestAliasArgumentPrecedence(t *testing.T) {
+	var got mcp.CallToolRequest
+	alias := toolAlias{Tool: "ts_diagnostics", Arguments: map[string]any{"file": "/src/a.ts", "maxResults": 100}}
+	h := aliasHandler(alias, echoTools(&got)["ts_diagnostics"].handler)
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want map[string]any
+	}{
+		{"presets only", nil, map[string]any{"file": "/src/a.ts", "maxResults": 100}},
+		{"caller wins", map[string]any{"maxResults": 5}, map[string]any{"file": "/src/a.ts", "maxResults": 5}},
+		{"caller adds", map[string]any{"tsconfig": "/t.json"}, map[string]any{"file": "/src/a.ts", "maxResults": 100, "tsconfig": "/t.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req mcp.CallToolRequest
+			req.Params.Name = "diag"
+			if tt.args != nil {
+				req.Params.Arguments = tt.args
+			}
+			if _, err := h(context.Background(), req); err != nil {
+				t.Fatal(err)
+			}
+			if got.Params.Name != "ts_diagnostics" {
+				t.Errorf("delegated as %q, want ts_diagnostics", got.Params.Name)
+			}
+			if !reflect.DeepEqual(got.GetArguments(), tt.want) {
+				t.Errorf("arguments = %v, want %v", got.GetArguments(), tt.want)
+			}
+		})
+	}
+	if alias.Arguments["maxResults"] != 100 {
+		t.Errorf("presets were modified: %v", alias.Arguments)
+	}
+}
+
+func TestRegisterAliases(t *testing.T) {
+	var got mcp.CallToolRequest
+	builtin := echoTools(&got)
+	s := server.NewMCPServer("test", "test")
+	aliases := map[string]toolAlias{
+		"diag":  {Tool: "ts_diagnostics", Description: "Errors in a.ts, 100 at a time.", Arguments: map[string]any{"file": "/src/a.ts", "maxResults": 100}},
+		"diag2": {Tool: "ts_diagnostics"},
+	}
+	if err := registerAliases(s, aliases, builtin); err != nil {
+		t.Fatal(err)
+	}
+
+	diag := s.GetTool("diag")
+	if diag == nil {
+		t.Fatal("alias diag is not listed")
+	}
+	if diag.Tool.Description != "Errors in a.ts, 100 at a time." {
+		t.Errorf("description = %q", diag.Tool.Description)
+	}
+	if len(diag.Tool.InputSchema.Required) != 0 {
+		t.Errorf("preset file still required: %v", diag.Tool.InputSchema.Required)
+	}
+	if _, ok := diag.Tool.InputSchema.Properties["maxResults"]; !ok {
+		t.Error("preset parameter dropped from the schema")
+	}
+
+	diag2 := s.GetTool("diag2")
+	if diag2 == nil {
+		t.Fatal("alias diag2 is not listed")
+	}
+	if !reflect.DeepEqual(diag2.Tool.InputSchema.Required, []string{"file"}) {
+		t.Errorf("required = %v, want [file]", diag2.Tool.InputSchema.Required)
+	}
+	if !strings.Contains(diag2.Tool.Description, "ts_diagnostics") {
+		t.Errorf("default description = %q", diag2.Tool.Description)
+	}
+	if req := builtin["ts_diagnostics"].tool.InputSchema.Required; !reflect.DeepEqual(req, []string{"file"}) {
+		t.Errorf("base tool schema modified: required = %v", req)
+	}
+}
+
+func TestValidateAliases(t *testing.T) {
+	var got mcp.CallToolRequest
+	builtin := echoTools(&got)
+	tests := []struct {
+		name    string
+		alias   toolAlias
+		wantErr string
+	}{
+		{"ts_diagnostics", toolAlias{Tool: "ts_diagnostics"}, "shadows the built-in tool"},
+		{"diag", toolAlias{Tool: "ts_lint"}, `unknown tool "ts_lint"`},
+		{"diag", toolAlias{Tool: "ts_diagnostics", Arguments: map[string]any{"severity": "error"}}, `has no parameter "severity"`},
+		{"my diag", toolAlias{Tool: "ts_diagnostics"}, "name must be"},
+		{"diag", toolAlias{Tool: "ts_diagnostics", Arguments: map[string]any{"maxResults": 10}}, ""},
+	}
+	for _, tt := range tests {
+		err := validateAliases(map[string]toolAlias{tt.name: tt.alias}, builtin)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"aliases": {"diag": {"tool": "ts_diagnostics", "description": "d", "arguments": {"maxResults": 100}}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TYPESCRIPT_MCP_CONFIG", path)
+	cfg, err := loadConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := toolAlias{Tool: "ts_diagnostics", Description: "d", Arguments: map[string]any{"maxResults": float64(100)}}
+	if !reflect.DeepEqual(cfg.Aliases["diag"], want) {
+		t.Errorf("alias = %+v, want %+v", cfg.Aliases["diag"], want)
+	}
+
+	t.Setenv("TYPESCRIPT_MCP_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := loadConfigFile(); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/modgraph"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+// ambientMaxVisits caps the walk for declaration files behind the
+// ts_definition fallback.
+const ambientMaxVisits = 100000
+
+type ambientReference struct {
+	Line   int    `json:"line"`
+	Kind   string `json:"kind"`
+	Target string `json:"target"`
+	// Resolved is the referenced file for path references that exist.
+	Resolved string `json:"resolved,omitempty"`
+}
+
+type ambientGlobal struct {
+	Name          string `json:"name"`
+	Kind          string `json:"kind"`
+	Line          int    `json:"line"`
+	Column        int    `json:"column"`
+	InGlobalBlock bool   `json:"inGlobalBlock,omitempty"`
+}
+
+type ambientModule struct {
+	Name         string `json:"name"`
+	Line         int    `json:"line"`
+	Augmentation bool   `json:"augmentation,omitempty"`
+}
+
+type ambientFile struct {
+	File         string             `json:"file"`
+	References   []ambientReference `json:"references,omitempty"`
+	GlobalBlocks []int              `json:"globalBlocks,omitempty"`
+	Globals      []ambientGlobal    `json:"globals,omitempty"`
+	Modules      []ambientModule    `json:"modules,omitempty"`
+}
+
+type ambientDeclarationsResult struct {
+	Files        []ambientFile `json:"files"`
+	FilesScanned int           `json:"filesScanned"`
+	// Stream is set when the files were streamed, one chunk per file keyed
+	// by its path; Files then holds only those that were not.
+	Stream *streamSummary `json:"stream,omitempty"`
+}
+
+// listAmbientDeclarations scans cfg's project files and returns the ambient
+// declarations of each file that has any, with paths relative to the
+// config's directory.
+func listAmbientDeclarations(cfg *tsconfig.Config) ambientDeclarationsResult {
+	return scanAmbientDeclarations(cfg, nil)
+}
+
+// scanAmbientDeclarations is listAmbientDeclarations, streaming each file's
+// declarations as soon as they are found when stream is not nil.
+func scanAmbientDeclarations(cfg *tsconfig.Config, stream *chunkStream) ambientDeclarationsResult {
+	rel := func(f string) string {
+		if r, err := filepath.Rel(cfg.Dir, f); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return f
+	}
+	files := workspace.ProjectFiles(cfg)
+	result := ambientDeclarationsResult{Files: []ambientFile{}, FilesScanned: len(files)}
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		a := modgraph.ScanAmbient(string(content))
+		if a.Empty() {
+			continue
+		}
+		entry := ambientFile{File: rel(f), GlobalBlocks: a.GlobalBlocks}
+		for _, r := range a.References {
+			ref := ambientReference{Line: r.Line, Kind: r.Kind, Target: r.Target}
+			if r.Kind == "path" {
+				if p := filepath.Join(filepath.Dir(f), filepath.FromSlash(r.Target)); fileExists(p) {
+					ref.Resolved = rel(p)
+				}
+			}
+			entry.References = append(entry.References, ref)
+		}
+		for _, g := range a.Globals {
+			entry.Globals = append(entry.Globals, ambientGlobal(g))
+		}
+		for _, m := range a.Modules {
+			entry.Modules = append(entry.Modules, ambientModule(m))
+		}
+		if stream != nil && stream.send(entry.File, entry) {
+			continue
+		}
+		result.Files = append(result.Files, entry)
+	}
+	if stream != nil {
+		result.Stream = stream.summary()
+	}
+	return result
+}
+
+// ambientDefinitions searches the declaration files under root for global
+// declarations of name. It backs ts_definition when the server finds
+// nothing, which happens for some ambient globals.
+func ambientDefinitions(root, name string) []definitionEntry {
+	var entries []definitionEntry
+	_ = workspace.Walk(root, workspace.WalkOptions{MaxDepth: -1, MaxVisits: ambientMaxVisits}, func(p string, d fs.DirEntry, _ int) error {
+		if d.IsDir() || !strings.HasSuffix(p, ".d.ts") {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil || !strings.Contains(string(content), name) {
+			return nil
+		}
+		for _, g := range modgraph.ScanAmbient(string(content)).Globals {
+			if g.Name != name {
+				continue
+			}
+			entry := definitionEntry{File: p, Line: g.Line, Column: g.Column, Ambient: true}
+			if preview, capped, err := signaturePreview(p, g.Line); err == nil {
+				entry.Preview, entry.PreviewTruncated = preview, capped
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries
+}
+
+func fileExists(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && !info.IsDir()
+}
+
+func makeAmbientDeclarationsHandler(client *lsp.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		configPath := request.GetString("tsconfig", "")
+		if configPath == "" {
+			configPath = filepath.Join(client.RootDir(), "tsconfig.json")
+		}
+		cfg, err := tsconfig.Load(configPath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("tsconfig error: %v", err)), nil
+		}
+
+		data, err := json.MarshalIndent(scanAmbientDeclarations(cfg, streamFromContext(ctx)), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+func ambientFixture(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", "..", "testdata", "ambient"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestListAmbientDeclarations(t *testing.T) {
+	root := ambientFixture(t)
+	cfg, err := tsconfig.Load(filepath.Join(root, "tsconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := listAmbientDeclarations(cfg)
+	if res.FilesScanned != 4 {
+		t.Errorf("FilesScanned = %d, want 4", res.FilesScanned)
+	}
+	want := []ambientFile{
+		{
+			File: "src/globals.d.ts",
+			References: []ambientReference{
+				{Line: 1, Kind: "path", Target: "./vendor.d.ts", Resolved: "src/vendor.d.ts"},
+				{Line: 2, Kind: "lib", Target: "es2022"},
+			},
+			Globals: []ambientGlobal{{Name: "__APP_VERSION__", Kind: "const", Line: 4, Column: 15}},
+			Modules: []ambientModule{{Name: "*.svg", Line: 6}},
+		},
+		{
+			File:         "src/session.ts",
+			GlobalBlocks: []int{5},
+			Globals:      []ambientGlobal{{Name: "Window", Kind: "interface", Line: 6, Column: 13, InGlobalBlock: true}},
+		},
+		{
+			File: "src/vendor.d.ts",
+			Globals: []ambientGlobal{
+				{Name: "track", Kind: "function", Line: 1, Column: 18},
+				{Name: "AnalyticsEvent", Kind: "interface", Line: 3, Column: 11},
+			},
+		},
+	}
+	if !reflect.DeepEqual(res.Files, want) {
+		t.Errorf("files =\n%+v\nwant\n%+v", res.Files, want)
+	}
+}
+
+func TestAmbientDefinitions(t *testing.T) {
+	root := ambientFixture(t)
+	tests := []struct {
+		name string
+		want []definitionEntry
+	}{
+		{"__APP_VERSION__", []definitionEntry{{
+			File: filepath.Join(root, "src", "globals.d.ts"), Line: 4, Column: 15,
+			Preview: "declare const __APP_VERSION__: string;", Ambient: true,
+		}}},
+		{"track", []definitionEntry{{
+			File: filepath.Join(root, "src", "vendor.d.ts"), Line: 1, Column: 18,
+			Preview: "declare function track(event: string, props?: Record<string, unknown>): void;", Ambient: true,
+		}}},
+		// Only declaration files are searched.
+		{"Window", nil},
+		{"boot", nil},
+	}
+	for _, tt := range tests {
+		if got := ambientDefinitions(root, tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ambientDefinitions(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
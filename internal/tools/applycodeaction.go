@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+type applyCodeActionResult struct {
+	Title      string     `json:"title"`
+	Kind       string     `json:"kind,omitempty"`
+	TotalEdits int        `json:"totalEdits"`
+	Changes    []editInfo `json:"changes"`
+	fileOps
+}
+
+// selectCodeAction picks the action titled title, or else the one at
+// index, which is -1 when not given.
+func selectCodeAction(actions []lsp.CodeAction, title string, index int) (lsp.CodeAction, error) {
+	if title != "" {
+		for _, a := range actions {
+			if a.Title == title {
+				return a, nil
+			}
+		}
+		titles := make([]string, len(actions))
+		for i, a := range actions {
+			titles[i] = fmt.Sprintf("%q", a.Title)
+		}
+		if len(titles) == 0 {
+			return lsp.CodeAction{}, fmt.Errorf("no code action titled %q: the range has no code actions", title)
+		}
+		return lsp.CodeAction{}, fmt.Errorf("no code action titled %q; available: %s", title, strings.Join(titles, ", "))
+	}
+	if index < 0 {
+		return lsp.CodeAction{}, fmt.Errorf("pass title or index to select a code action")
+	}
+	if index >= len(actions) {
+		return lsp.CodeAction{}, fmt.Errorf("index %d out of range: the range has %s; list them again with ts_code_actions", index, plural(len(actions), "code action"))
+	}
+	return actions[index], nil
+}
+
+// codeActionEdit returns the edit of action, resolving it first when tsgo
+// computes it on demand.
+func codeActionEdit(ctx context.Context, client *lsp.Client, action lsp.CodeAction) (*lsp.WorkspaceEdit, error) {
+	if action.Disabled != nil {
+		return nil, fmt.Errorf("code action %q is disabled: %s", action.Title, action.Disabled.Reason)
+	}
+	if action.Edit == nil && action.Data != nil {
+		resolved, err := client.ResolveCodeAction(ctx, action)
+		if err != nil {
+			return nil, fmt.Errorf("resolving code action %q: %w", action.Title, err)
+		}
+		if resolved.Disabled != nil {
+			return nil, fmt.Errorf("code action %q is disabled: %s", action.Title, resolved.Disabled.Reason)
+		}
+		action.Edit = resolved.Edit
+		if resolved.Command != nil {
+			action.Command = resolved.Command
+		}
+	}
+	if action.Edit == nil || (len(action.Edit.Changes) == 0 && len(action.Edit.DocumentChanges) == 0) {
+		if action.Command != nil {
+			return nil, fmt.Errorf("code action %q only runs the command %q; commands aren't supported yet", action.Title, action.Command.Command)
+		}
+		return nil, fmt.Errorf("code action %q has no edit", action.Title)
+	}
+	return action.Edit, nil
+}
+
+func makeApplyCodeActionHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, packages *workspace.PackageResolver, overlayCheck bool, journal *journalPolicy, recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		r, err := parseCodeActionRange(request, file, cols)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		kind := request.GetString("kind", "")
+		title := request.GetString("title", "")
+		index := request.GetInt("index", -1)
+		confirm := request.GetBool("confirm", false)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		actions, _, err := listCodeActions(ctx, client, file, r, kind)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		action, err := selectCodeAction(actions, title, index)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		edit, err := codeActionEdit(ctx, client, action)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if path, pkg := installedPackageEdit(edit, packages); pkg != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("refusing to apply %q: it edits the installed package %s (%s)", action.Title, pkg, pkg.DisplayPath(path))), nil
+		}
+
+		if confirm {
+			return previewEdit(pending, request.Params.Name, "", edit)
+		}
+
+		var gate editGate
+		if overlayCheck {
+			gate = overlayGate(ctx, client, docs)
+		}
+		changes, err := applyWorkspaceEdit(edit, docs, gate, journal, recorder.recording(editOrigin{tool: request.Params.Name}))
+		if err != nil {
+			resyncStale(ctx, client, docs, err)
+			return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
+		}
+		paths := sortedChangePaths(changes)
+		pending.InvalidateFiles(paths)
+
+		// Re-sync all modified files so the LSP server sees the new content.
+		if err := resyncChanged(ctx, client, docs, changes); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		ClearFileCache()
+
+		result := applyCodeActionResult{Title: action.Title, Kind: string(action.Kind), Changes: []editInfo{}, fileOps: fileOpsOf(changes)}
+		for _, p := range paths {
+			result.TotalEdits += changes[p].Edits
+			result.Changes = append(result.Changes, changes[p])
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestSelectCodeAction(t *testing.T) {
+	actions := []lsp.CodeAction{{CodeAction: protocol.CodeAction{Title: "Add import"}}, {CodeAction: protocol.CodeAction{Title: "Remove unused"}}}
+	tests := []struct {
+		name    string
+		title   string
+		index   int
+		want    string
+		wantErr string
+	}{
+		{name: "by title", title: "Remove unused", index: 0, want: "Remove unused"},
+		{name: "by index", index: 0, want: "Add import"},
+		{name: "unknown title", title: "Fix all", index: -1, wantErr: `available: "Add import", "Remove unused"`},
+		{name: "index out of range", index: 2, wantErr: "index 2 out of range: the range has 2 code actions"},
+		{name: "neither", index: -1, wantErr: "pass title or index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectCodeAction(actions, tt.title, tt.index)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.Title != tt.want {
+				t.Errorf("got %q, %v, want %q", got.Title, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeActionEdit(t *testing.T) {
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{"file:///p/a.ts": {{NewText: "x"}}}}}
+	if got, err := codeActionEdit(context.Background(), nil, lsp.CodeAction{CodeAction: protocol.CodeAction{Title: "Fix"}, Edit: edit}); err != nil || got != edit {
+		t.Errorf("edit = %v, %v", got, err)
+	}
+	_, err := codeActionEdit(context.Background(), nil, lsp.CodeAction{CodeAction: protocol.CodeAction{Title: "Organize", Command: &protocol.Command{Command: "_typescript.organizeImports"}}})
+	if err == nil || !strings.Contains(err.Error(), "commands aren't supported yet") {
+		t.Errorf("command-only action: %v", err)
+	}
+	_, err = codeActionEdit(context.Background(), nil, lsp.CodeAction{CodeAction: protocol.CodeAction{Title: "Move", Disabled: &protocol.CodeActionDisable{Reason: "deletes a file"}}, Edit: edit})
+	if err == nil || !strings.Contains(err.Error(), "is disabled: deletes a file") {
+		t.Errorf("disabled action: %v", err)
+	}
+}
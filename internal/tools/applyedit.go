@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+type applyEditResult struct {
+	Tool       string     `json:"tool"`
+	TotalEdits int        `json:"totalEdits"`
+	Changes    []editInfo `json:"changes"`
+	fileOps
+}
+
+// editPreviewResult is returned by edit-producing tools in confirmation
+// mode. The token is passed to ts_apply_edit to write the previewed edit.
+type editPreviewResult struct {
+	EditToken  string        `json:"editToken"`
+	ExpiresIn  string        `json:"expiresIn"`
+	TotalEdits int           `json:"totalEdits"`
+	Changes    []editPreview `json:"changes"`
+}
+
+// editDryRunResult is returned by edit-producing tools in dry-run mode:
+// the previewed edit, with nothing written or kept to apply later.
+type editDryRunResult struct {
+	DryRun     bool          `json:"dryRun"`
+	TotalEdits int           `json:"totalEdits"`
+	Changes    []editPreview `json:"changes"`
+}
+
+func makeApplyEditHandler(client *lsp.Client, docs *docsync.Manager, edits *editTokenStore, overlayCheck bool, journal *journalPolicy, recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		token, err := request.RequireString("editToken")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		pending, err := edits.Take(token)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if drifted := pending.driftedFiles(); len(drifted) > 0 {
+			return mcp.NewToolResultError(fmt.Sprintf(
+				"files changed since the edit was previewed; re-run %s: %s",
+				pending.tool, strings.Join(drifted, ", "))), nil
+		}
+
+		var gate editGate
+		if overlayCheck {
+			gate = overlayGate(ctx, client, docs)
+		}
+		changes, err := applyWorkspaceEdit(pending.edit, docs, gate, journal, recorder.recording(editOrigin{tool: pending.tool, symbol: pending.symbol}))
+		if err != nil {
+			resyncStale(ctx, client, docs, err)
+			return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
+		}
+
+		paths := sortedChangePaths(changes)
+		edits.InvalidateFiles(paths)
+
+		if err := resyncChanged(ctx, client, docs, changes); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		ClearFileCache()
+
+		result := applyEditResult{Tool: pending.tool, fileOps: fileOpsOf(changes)}
+		for _, p := range paths {
+			result.TotalEdits += changes[p].Edits
+			result.Changes = append(result.Changes, changes[p])
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+// previewEdit computes the diff for edit, stores it in pending, and returns
+// the preview with its token instead of writing anything.
+func previewEdit(pending *editTokenStore, tool, symbol string, edit *lsp.WorkspaceEdit) (*mcp.CallToolResult, error) {
+	previews, hashes, err := previewWorkspaceEdit(edit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("preview error: %v", err)), nil
+	}
+	token, err := pending.Put(tool, symbol, edit, hashes)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	result := editPreviewResult{
+		EditToken: token,
+		ExpiresIn: pending.ttl.String(),
+		Changes:   previews,
+	}
+	for _, p := range previews {
+		result.TotalEdits += p.Edits
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// dryRunEdit computes the diff for edit and returns it without writing
+// anything or issuing an edit token.
+func dryRunEdit(edit *lsp.WorkspaceEdit) (*mcp.CallToolResult, error) {
+	previews, _, err := previewWorkspaceEdit(edit)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("preview error: %v", err)), nil
+	}
+	result := editDryRunResult{DryRun: true, Changes: previews}
+	for _, p := range previews {
+		result.TotalEdits += p.Edits
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// sortedChangePaths returns the keys of changes in sorted order.
+func sortedChangePaths(changes map[string]editInfo) []string {
+	paths := make([]string, 0, len(changes))
+	for p := range changes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
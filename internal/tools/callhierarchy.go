@@ -0,0 +1,224 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const (
+	callsIncoming = "incoming"
+	callsOutgoing = "outgoing"
+
+	// maxCallHierarchyDepth bounds the depth argument of ts_call_hierarchy,
+	// and maxCallHierarchyNodes the size of the tree: every expanded node
+	// is one more round trip to tsgo.
+	maxCallHierarchyDepth = 5
+	maxCallHierarchyNodes = 200
+
+	noCallHierarchy = "No function or method found at this position"
+)
+
+// callSite is the 1-based range of one call.
+type callSite struct {
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+}
+
+type callHierarchyNode struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// VisualColumn is set in the visual column mode.
+	VisualColumn int `json:"visualColumn,omitempty"`
+	// CallSites are the calls linking the node to its parent: in the
+	// node's own file for incoming calls, in the parent's for outgoing.
+	CallSites []callSite          `json:"callSites,omitempty"`
+	Calls     []callHierarchyNode `json:"calls,omitempty"`
+	// Recursive is set on a node that is also one of its ancestors; its
+	// calls are not expanded again.
+	Recursive bool `json:"recursive,omitempty"`
+}
+
+type callHierarchyResult struct {
+	Direction string              `json:"direction"`
+	Depth     int                 `json:"depth"`
+	Roots     []callHierarchyNode `json:"roots"`
+	// Truncated is set when expansion stopped at maxCallHierarchyNodes.
+	Truncated bool `json:"truncated"`
+}
+
+// callEdge is one call of an item's incoming or outgoing calls.
+type callEdge struct {
+	item   protocol.CallHierarchyItem
+	ranges []protocol.Range
+}
+
+// callExpander returns the calls of item in one direction.
+type callExpander func(ctx context.Context, item protocol.CallHierarchyItem) ([]callEdge, error)
+
+func clientCallExpander(client *lsp.Client, direction string) callExpander {
+	if direction == callsOutgoing {
+		return func(ctx context.Context, item protocol.CallHierarchyItem) ([]callEdge, error) {
+			calls, err := client.OutgoingCalls(ctx, item)
+			edges := make([]callEdge, len(calls))
+			for i, c := range calls {
+				edges[i] = callEdge{item: c.To, ranges: c.FromRanges}
+			}
+			return edges, err
+		}
+	}
+	return func(ctx context.Context, item protocol.CallHierarchyItem) ([]callEdge, error) {
+		calls, err := client.IncomingCalls(ctx, item)
+		edges := make([]callEdge, len(calls))
+		for i, c := range calls {
+			edges[i] = callEdge{item: c.From, ranges: c.FromRanges}
+		}
+		return edges, err
+	}
+}
+
+// callTree builds call hierarchy trees, counting the nodes it expands.
+type callTree struct {
+	expand    callExpander
+	cols      columnMode
+	expanded  int
+	truncated bool
+}
+
+// callItemKey identifies an item by its file and the start of its name.
+func callItemKey(item protocol.CallHierarchyItem) string {
+	start := item.SelectionRange.Start
+	return fmt.Sprintf("%s:%d:%d", item.URI, start.Line, start.Character)
+}
+
+func callNodeOf(item protocol.CallHierarchyItem, ranges []protocol.Range) callHierarchyNode {
+	node := callHierarchyNode{
+		Name:   item.Name,
+		Kind:   symbolKindName(item.Kind),
+		Detail: item.Detail,
+		File:   docsync.URIToFile(string(item.URI)),
+		Line:   int(item.SelectionRange.Start.Line) + 1,
+		Column: int(item.SelectionRange.Start.Character) + 1,
+	}
+	for _, r := range ranges {
+		node.CallSites = append(node.CallSites, callSite{
+			Line:      int(r.Start.Line) + 1,
+			Column:    int(r.Start.Character) + 1,
+			EndLine:   int(r.End.Line) + 1,
+			EndColumn: int(r.End.Character) + 1,
+		})
+	}
+	return node
+}
+
+// build returns the tree of item down to depth levels of calls. path holds
+// the keys of item's ancestors, so a call back into one of them becomes a
+// Recursive leaf instead of looping.
+func (t *callTree) build(ctx context.Context, item protocol.CallHierarchyItem, ranges []protocol.Range, depth int, path map[string]bool) (callHierarchyNode, error) {
+	node := callNodeOf(item, ranges)
+	node.VisualColumn = t.cols.visualColumn(node.File, node.Line, node.Column)
+	key := callItemKey(item)
+	if path[key] {
+		node.Recursive = true
+		return node, nil
+	}
+	if depth == 0 {
+		return node, nil
+	}
+	if t.expanded >= maxCallHierarchyNodes {
+		t.truncated = true
+		return node, nil
+	}
+	t.expanded++
+	edges, err := t.expand(ctx, item)
+	if err != nil {
+		return node, err
+	}
+	path[key] = true
+	defer delete(path, key)
+	for _, e := range edges {
+		child, err := t.build(ctx, e.item, e.ranges, depth-1, path)
+		if err != nil {
+			return node, err
+		}
+		node.Calls = append(node.Calls, child)
+	}
+	return node, nil
+}
+
+func makeCallHierarchyHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		direction := request.GetString("direction", callsIncoming)
+		if direction != callsIncoming && direction != callsOutgoing {
+			return mcp.NewToolResultError(fmt.Sprintf("direction must be %q or %q, got %q", callsIncoming, callsOutgoing, direction)), nil
+		}
+		depth := request.GetInt("depth", 1)
+		if depth < 1 || depth > maxCallHierarchyDepth {
+			return mcp.NewToolResultError(fmt.Sprintf("depth must be between 1 and %d", maxCallHierarchyDepth)), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		items, err := client.PrepareCallHierarchy(ctx, file, line, col)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("call hierarchy is unavailable: %v; use ts_references instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("call hierarchy error: %v", err)), nil
+		}
+		if len(items) == 0 {
+			return mcp.NewToolResultText(noCallHierarchy), nil
+		}
+
+		tree := &callTree{expand: clientCallExpander(client, direction), cols: cols}
+		result := callHierarchyResult{Direction: direction, Depth: depth, Roots: []callHierarchyNode{}}
+		for _, item := range items {
+			root, err := tree.build(ctx, item, nil, depth, map[string]bool{})
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%s calls error: %v", direction, err)), nil
+			}
+			result.Roots = append(result.Roots, root)
+		}
+		result.Truncated = tree.truncated
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func callItem(name string, line uint32) protocol.CallHierarchyItem {
+	pos := protocol.Position{Line: line, Character: 16}
+	return protocol.CallHierarchyItem{
+		Name:           name,
+		Kind:           protocol.SymbolKindFunction,
+		URI:            "file:///p/src/parity.ts",
+		SelectionRange: protocol.Range{Start: pos, End: pos},
+	}
+}
+
+func TestCallTreeBuild(t *testing.T) {
+	even, odd, main := callItem("isEven", 0), callItem("isOdd", 4), callItem("main", 8)
+	site := []protocol.Range{{Start: protocol.Position{Line: 1, Character: 26}, End: protocol.Position{Line: 1, Character: 31}}}
+	// isEven and isOdd call each other; main calls isEven.
+	callers := map[string][]callEdge{
+		"isEven": {{item: odd, ranges: site}, {item: main, ranges: site}},
+		"isOdd":  {{item: even, ranges: site}},
+	}
+	expand := func(_ context.Context, item protocol.CallHierarchyItem) ([]callEdge, error) {
+		return callers[item.Name], nil
+	}
+
+	tree := &callTree{expand: expand}
+	root, err := tree.build(context.Background(), even, nil, 5, map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "isEven" || root.Kind != "function" || root.Line != 1 || root.Column != 17 || len(root.Calls) != 2 {
+		t.Fatalf("root = %+v", root)
+	}
+	oddNode := root.Calls[0]
+	if oddNode.Name != "isOdd" || len(oddNode.CallSites) != 1 || oddNode.CallSites[0] != (callSite{Line: 2, Column: 27, EndLine: 2, EndColumn: 32}) {
+		t.Errorf("isOdd = %+v", oddNode)
+	}
+	if len(oddNode.Calls) != 1 || !oddNode.Calls[0].Recursive || oddNode.Calls[0].Calls != nil {
+		t.Errorf("isOdd's caller = %+v, want a recursive isEven leaf", oddNode.Calls)
+	}
+	if root.Calls[1].Name != "main" || root.Calls[1].Recursive {
+		t.Errorf("second caller = %+v", root.Calls[1])
+	}
+
+	// Depth 1 expands the root only.
+	tree = &callTree{expand: expand}
+	root, _ = tree.build(context.Background(), even, nil, 1, map[string]bool{})
+	if len(root.Calls) != 2 || root.Calls[0].Calls != nil || tree.expanded != 1 {
+		t.Errorf("depth 1 = %+v, %d expanded", root, tree.expanded)
+	}
+}
+
+func TestCallTreeTruncates(t *testing.T) {
+	// Every function has a new caller, so only the node limit stops it.
+	expand := func(_ context.Context, item protocol.CallHierarchyItem) ([]callEdge, error) {
+		return []callEdge{{item: callItem("f", item.SelectionRange.Start.Line+1)}}, nil
+	}
+	tree := &callTree{expand: expand, expanded: maxCallHierarchyNodes - 2}
+	root, err := tree.build(context.Background(), callItem("f", 0), nil, maxCallHierarchyDepth, map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tree.truncated || len(root.Calls) != 1 || len(root.Calls[0].Calls) != 1 || root.Calls[0].Calls[0].Calls != nil {
+		t.Errorf("truncated = %v, root = %+v", tree.truncated, root)
+	}
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// requestIDMeta is the _meta field that carries the JSON-RPC id of a tool
+// call from the before-call hook to callCancels.wrap, as mcp-go gives
+// handlers no other way to learn it.
+const requestIDMeta = "typescript-mcp/requestId"
+
+// methodNotificationCancelled is the MCP notification that cancels a
+// request; mcp-go has no constant for it.
+const methodNotificationCancelled = "notifications/cancelled"
+
+// callCancels cancels the context of a tool call when the client sends
+// notifications/cancelled for it. mcp-go runs every call with the
+// server's context, so without it a call the client gave up on keeps
+// tsgo busy; with it the LSP requests of the call end at once and tsgo
+// gets $/cancelRequest for them.
+type callCancels struct {
+	mu    sync.Mutex
+	calls map[string]context.CancelFunc
+}
+
+func newCallCancels() *callCancels {
+	return &callCancels{calls: make(map[string]context.CancelFunc)}
+}
+
+// callKey identifies a call by its session and JSON-RPC id.
+func callKey(ctx context.Context, id mcp.RequestId) string {
+	key := id.String()
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		key = session.SessionID() + "/" + key
+	}
+	return key
+}
+
+// install makes hooks record the id of every tool call in its request,
+// and s cancel the calls named by notifications/cancelled.
+func (c *callCancels) install(s *server.MCPServer, hooks *server.Hooks) {
+	hooks.AddBeforeCallTool(func(_ context.Context, id any, request *mcp.CallToolRequest) {
+		if id == nil {
+			return
+		}
+		if request.Params.Meta == nil {
+			request.Params.Meta = &mcp.Meta{}
+		}
+		if request.Params.Meta.AdditionalFields == nil {
+			request.Params.Meta.AdditionalFields = make(map[string]any)
+		}
+		request.Params.Meta.AdditionalFields[requestIDMeta] = mcp.NewRequestId(id)
+	})
+	s.AddNotificationHandler(methodNotificationCancelled, c.handleCancelled)
+}
+
+func (c *callCancels) handleCancelled(ctx context.Context, n mcp.JSONRPCNotification) {
+	id, ok := n.Params.AdditionalFields["requestId"]
+	if !ok {
+		return
+	}
+	key := callKey(ctx, mcp.NewRequestId(id))
+	c.mu.Lock()
+	cancel := c.calls[key]
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// wrap runs h with a context that a notifications/cancelled for the call
+// cancels.
+func (c *callCancels) wrap(h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var id mcp.RequestId
+		if meta := request.Params.Meta; meta != nil {
+			id, _ = meta.AdditionalFields[requestIDMeta].(mcp.RequestId)
+		}
+		if id.IsNil() {
+			return h(ctx, request)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		key := callKey(ctx, id)
+		c.mu.Lock()
+		c.calls[key] = cancel
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.calls, key)
+			c.mu.Unlock()
+		}()
+		return h(ctx, request)
+	}
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestCallCancellation(t *testing.T) {
+	hooks := &server.Hooks{}
+	s := server.NewMCPServer("test", "test", server.WithHooks(hooks))
+	cancels := newCallCancels()
+	cancels.install(s, hooks)
+	started := make(chan struct{}, 2)
+	s.AddTool(mcp.NewTool("ts_slow"), cancels.wrap(func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			return mcp.NewToolResultError(ctx.Err().Error()), nil
+		case <-time.After(10 * time.Second):
+			return mcp.NewToolResultText("finished"), nil
+		}
+	}))
+	ctx := context.Background()
+	s.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"test","version":"1"},"capabilities":{}}}`))
+
+	call := func(id string) chan mcp.JSONRPCMessage {
+		done := make(chan mcp.JSONRPCMessage, 1)
+		go func() {
+			done <- s.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","id":`+id+`,"method":"tools/call","params":{"name":"ts_slow"}}`))
+		}()
+		<-started
+		return done
+	}
+	first, second := call("7"), call(`"eight"`)
+	s.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user gave up"}}`))
+	select {
+	case msg := <-first:
+		resp, ok := msg.(mcp.JSONRPCResponse)
+		if !ok {
+			t.Fatalf("response = %#v", msg)
+		}
+		if result, ok := resp.Result.(mcp.CallToolResult); !ok || !result.IsError {
+			t.Errorf("result = %#v, want the cancellation error", resp.Result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the cancelled call did not return")
+	}
+	select {
+	case <-second:
+		t.Error("cancelling call 7 ended another call")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.HandleMessage(ctx, []byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":"eight"}}`))
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("a call with a string id was not cancelled")
+	}
+}
@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// Kinds of cause for a module that could not be found.
+const (
+	// causeMissingPackage is a bare specifier whose package is not
+	// installed.
+	causeMissingPackage = "missingPackage"
+	// causeMissingTypes is an installed package, or a Node builtin,
+	// without type declarations and without its @types package.
+	causeMissingTypes = "missingTypes"
+	// causeTypesNotIncluded is an @types package on disk that the program
+	// does not load, e.g. because compilerOptions.types leaves it out.
+	causeTypesNotIncluded = "typesNotIncluded"
+	// causeGenerated is a path matching a generated-code glob, which
+	// exists once codegen has run.
+	causeGenerated = "generated"
+	// causeMissingFile is a relative or aliased path to a file that does
+	// not exist.
+	causeMissingFile = "missingFile"
+)
+
+// moduleNotFoundCodes are the diagnostics for an import that could not be
+// resolved, each naming the specifier in its first quoted string.
+var moduleNotFoundCodes = map[int]bool{
+	2307: true, // Cannot find module '{0}' or its corresponding type declarations.
+	2792: true, // Cannot find module '{0}'. Did you mean to set the 'moduleResolution' option ...
+	7016: true, // Could not find a declaration file for module '{0}'.
+	2688: true, // Cannot find type definition file for '{0}'.
+}
+
+// defaultGeneratedPaths are the generated-code globs used unless the
+// config file sets generatedPaths.
+var defaultGeneratedPaths = []string{
+	"**/__generated__/**",
+	"**/generated/**",
+	"**/*.generated",
+	"**/*.generated.*",
+}
+
+// nodeBuiltins are the Node core modules, whose types come from
+// @types/node.
+var nodeBuiltins = map[string]bool{
+	"assert": true, "async_hooks": true, "buffer": true, "child_process": true,
+	"cluster": true, "console": true, "crypto": true, "dgram": true,
+	"dns": true, "events": true, "fs": true, "http": true, "http2": true,
+	"https": true, "inspector": true, "module": true, "net": true, "os": true,
+	"path": true, "perf_hooks": true, "process": true, "querystring": true,
+	"readline": true, "repl": true, "stream": true, "string_decoder": true,
+	"timers": true, "tls": true, "tty": true, "url": true, "util": true,
+	"v8": true, "vm": true, "worker_threads": true, "zlib": true,
+}
+
+// resolvableExtensions are tried after a relative specifier, in order.
+var resolvableExtensions = []string{
+	"", ".ts", ".tsx", ".d.ts", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs", ".json",
+	"/index.ts", "/index.tsx", "/index.d.ts", "/index.js",
+}
+
+var quotedSpecifierPattern = regexp.MustCompile(`'([^']+)'`)
+
+// diagnosticCause explains a diagnostic that comes from the environment
+// rather than the code: a package, its types or generated code missing.
+type diagnosticCause struct {
+	Kind      string `json:"kind"`
+	Specifier string `json:"specifier"`
+	Remedy    string `json:"remedy"`
+}
+
+// causeSummary counts the diagnostics of one cause kind.
+type causeSummary struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+	// Remedies are the distinct remedies, e.g. one install per package.
+	Remedies []string `json:"remedies"`
+}
+
+// causeClassifier buckets module-not-found diagnostics by cause. It only
+// looks at the file system.
+type causeClassifier struct {
+	generated []*regexp.Regexp
+	paths     *tsconfig.PathMapper
+}
+
+// newCauseClassifier compiles the generated-code globs, relative to root
+// and in tsconfig exclude syntax; nil globs mean defaultGeneratedPaths.
+// paths resolves aliased specifiers and may be nil.
+func newCauseClassifier(root string, generated []string, paths *tsconfig.PathMapper) *causeClassifier {
+	if generated == nil {
+		generated = defaultGeneratedPaths
+	}
+	c := &causeClassifier{paths: paths}
+	for _, glob := range generated {
+		if re := tsconfig.ExcludePattern(filepath.Clean(root), glob); re != nil {
+			c.generated = append(c.generated, re)
+		}
+	}
+	return c
+}
+
+// diagnosticCode returns the numeric code of a diagnostic, which arrives
+// as a JSON number or a string.
+func diagnosticCode(code any) (int, bool) {
+	switch v := code.(type) {
+	case float64:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int:
+		return v, true
+	case string:
+		var n int
+		_, err := fmt.Sscanf(v, "%d", &n)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// classify returns the cause of a diagnostic in file, or nil when it is
+// not a module-not-found diagnostic or its cause is unknown.
+func (c *causeClassifier) classify(file string, code any, message string) *diagnosticCause {
+	n, ok := diagnosticCode(code)
+	if !ok || !moduleNotFoundCodes[n] {
+		return nil
+	}
+	m := quotedSpecifierPattern.FindStringSubmatch(message)
+	if m == nil {
+		return nil
+	}
+	spec := m[1]
+	dir := filepath.Dir(file)
+	if n == 2688 {
+		// The specifier names a types package, not a module.
+		return c.classifyPackage(dir, spec, true)
+	}
+
+	var candidates []string
+	switch tsconfig.ClassifySpecifier(spec, c.paths) {
+	case tsconfig.ModuleRelative:
+		p := filepath.FromSlash(spec)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		candidates = []string{p}
+	case tsconfig.ModuleAlias:
+		for _, p := range c.paths.Resolve(spec) {
+			candidates = append(candidates, filepath.FromSlash(p))
+		}
+	default:
+		return c.classifyPackage(dir, spec, n == 7016)
+	}
+	for _, p := range candidates {
+		if c.isGenerated(p) {
+			return &diagnosticCause{Kind: causeGenerated, Specifier: spec, Remedy: "run codegen"}
+		}
+	}
+	for _, p := range candidates {
+		if resolvable(p) {
+			// The file is there, so the import fails for another reason,
+			// e.g. a missing extension under node16 resolution.
+			return nil
+		}
+	}
+	return &diagnosticCause{Kind: causeMissingFile, Specifier: spec, Remedy: "create the file or fix the import"}
+}
+
+// classifyPackage buckets a bare specifier by what node_modules has.
+// installed is set when the diagnostic already says the package is there
+// but lacks types.
+func (c *causeClassifier) classifyPackage(dir, spec string, installed bool) *diagnosticCause {
+	pkg := packageName(spec)
+	if builtin := strings.TrimPrefix(pkg, "node:"); nodeBuiltins[builtin] || builtin != pkg {
+		pkg, installed = "node", true
+	}
+	types := typesPackage(pkg)
+	if _, ok := findNodeModule(dir, types); ok {
+		return &diagnosticCause{Kind: causeTypesNotIncluded, Specifier: spec, Remedy: fmt.Sprintf("add %q to compilerOptions.types", strings.TrimPrefix(types, "@types/"))}
+	}
+	if !installed {
+		if p, ok := findNodeModule(dir, pkg); ok {
+			if hasOwnTypes(p) {
+				// Resolution fails for another reason, e.g. the package's
+				// exports under the configured moduleResolution.
+				return nil
+			}
+			installed = true
+		}
+	}
+	if installed {
+		return &diagnosticCause{Kind: causeMissingTypes, Specifier: spec, Remedy: "install " + types}
+	}
+	return &diagnosticCause{Kind: causeMissingPackage, Specifier: spec, Remedy: "install " + pkg}
+}
+
+func (c *causeClassifier) isGenerated(p string) bool {
+	slash := filepath.ToSlash(filepath.Clean(p))
+	for _, re := range c.generated {
+		if re.MatchString(slash) {
+			return true
+		}
+	}
+	return false
+}
+
+// packageName returns the package a bare specifier imports from:
+// "lodash" for "lodash/fp", "@scope/pkg" for "@scope/pkg/sub".
+func packageName(spec string) string {
+	parts := strings.SplitN(spec, "/", 3)
+	if strings.HasPrefix(spec, "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// typesPackage returns the DefinitelyTyped package for pkg:
+// "@types/lodash", "@types/scope__pkg" for "@scope/pkg".
+func typesPackage(pkg string) string {
+	if strings.HasPrefix(pkg, "@types/") {
+		return pkg
+	}
+	if scope, name, ok := strings.Cut(strings.TrimPrefix(pkg, "@"), "/"); ok && strings.HasPrefix(pkg, "@") {
+		return "@types/" + scope + "__" + name
+	}
+	return "@types/" + pkg
+}
+
+// typesModule returns the module a DefinitelyTyped package types: "node"
+// for "@types/node", "@scope/pkg" for "@types/scope__pkg". Other names are
+// returned as they are.
+func typesModule(pkg string) string {
+	name, ok := strings.CutPrefix(pkg, "@types/")
+	if !ok {
+		return pkg
+	}
+	if scope, rest, ok := strings.Cut(name, "__"); ok {
+		return "@" + scope + "/" + rest
+	}
+	return name
+}
+
+// findNodeModule returns the directory of pkg in a node_modules directory
+// of dir or one of its parents, as Node resolution finds it.
+func findNodeModule(dir, pkg string) (string, bool) {
+	for {
+		p := filepath.Join(dir, "node_modules", filepath.FromSlash(pkg))
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			return p, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// hasOwnTypes reports whether the package in dir ships its own type
+// declarations.
+func hasOwnTypes(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err == nil {
+		var m struct {
+			Types   string `json:"types"`
+			Typings string `json:"typings"`
+		}
+		if json.Unmarshal(data, &m) == nil && (m.Types != "" || m.Typings != "") {
+			return true
+		}
+	}
+	_, err = os.Stat(filepath.Join(dir, "index.d.ts"))
+	return err == nil
+}
+
+// resolvable reports whether p names an existing file, with or without
+// one of the extensions module resolution tries. A JavaScript extension
+// may stand for the TypeScript source, as under node16 resolution.
+func resolvable(p string) bool {
+	bases := []string{p}
+	switch ext := filepath.Ext(p); ext {
+	case ".js", ".jsx", ".mjs", ".cjs":
+		bases = append(bases, strings.TrimSuffix(p, ext))
+	}
+	for _, base := range bases {
+		for _, ext := range resolvableExtensions {
+			if info, err := os.Stat(base + filepath.FromSlash(ext)); err == nil && !info.IsDir() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classifyCauses sets the cause of each entry it can explain and
+// summarizes them by kind, in the order the kinds first appear.
+func classifyCauses(c *causeClassifier, entries []diagnosticEntry) []causeSummary {
+	var summary []causeSummary
+	for i := range entries {
+		cause := c.classify(entries[i].File, entries[i].Code, entries[i].Message)
+		if cause == nil {
+			continue
+		}
+		entries[i].Cause = cause
+		j := slices.IndexFunc(summary, func(s causeSummary) bool { return s.Kind == cause.Kind })
+		if j < 0 {
+			summary = append(summary, causeSummary{Kind: cause.Kind})
+			j = len(summary) - 1
+		}
+		summary[j].Count++
+		if !slices.Contains(summary[j].Remedies, cause.Remedy) {
+			summary[j].Remedies = append(summary[j].Remedies, cause.Remedy)
+		}
+	}
+	return summary
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// writeFiles creates files, keyed by slash path relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClassifyCause(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"node_modules/lodash/package.json":        `{"name": "lodash"}`,
+		"node_modules/left-pad/package.json":      `{"name": "left-pad"}`,
+		"node_modules/@types/left-pad/index.d.ts": "",
+		"node_modules/typed/package.json":         `{"name": "typed", "types": "index.d.ts"}`,
+		"node_modules/@acme/ui/package.json":      `{"name": "@acme/ui"}`,
+		"src/util.ts":                             "",
+		"src/lib/shared.ts":                       "",
+	})
+	paths := tsconfig.NewPathMapper(root, "", map[string][]string{"@lib/*": {"src/lib/*"}}, nil)
+	c := newCauseClassifier(root, nil, paths)
+	file := filepath.Join(root, "src", "app.ts")
+
+	tests := []struct {
+		name    string
+		code    any
+		message string
+		want    *diagnosticCause
+	}{
+		{
+			name:    "package without types",
+			code:    float64(7016),
+			message: "Could not find a declaration file for module 'lodash/fp'. '/repo/node_modules/lodash/fp.js' implicitly has an 'any' type.",
+			want:    &diagnosticCause{Kind: causeMissingTypes, Specifier: "lodash/fp", Remedy: "install @types/lodash"},
+		},
+		{
+			name:    "scoped package without types",
+			code:    float64(2307),
+			message: "Cannot find module '@acme/ui' or its corresponding type declarations.",
+			want:    &diagnosticCause{Kind: causeMissingTypes, Specifier: "@acme/ui", Remedy: "install @types/acme__ui"},
+		},
+		{
+			name:    "package not installed",
+			code:    float64(2307),
+			message: "Cannot find module 'zod' or its corresponding type declarations.",
+			want:    &diagnosticCause{Kind: causeMissingPackage, Specifier: "zod", Remedy: "install zod"},
+		},
+		{
+			name:    "types installed but not loaded",
+			code:    float64(2307),
+			message: "Cannot find module 'left-pad' or its corresponding type declarations.",
+			want:    &diagnosticCause{Kind: causeTypesNotIncluded, Specifier: "left-pad", Remedy: `add "left-pad" to compilerOptions.types`},
+		},
+		{
+			name:    "node builtin",
+			code:    "2307",
+			message: "Cannot find module 'node:fs' or its corresponding type declarations.",
+			want:    &diagnosticCause{Kind: causeMissingTypes, Specifier: "node:fs", Remedy: "install @types/node"},
+		},
+		{
+			name:    "type definition file",
+			code:    float64(2688),
+			message: "Cannot find type definition file for 'jest'.",
+			want:    &diagnosticCause{Kind: causeMissingTypes, Specifier: "jest", Remedy: "install @types/jest"},
+		},
+		{
+			name:    "generated module",
+			code:    float64(2307),
+			message: "Cannot find module './__generated__/graphql' or its corresponding type declarations.",
+			want:    &diagnosticCause{Kind: causeGenerated, Specifier: "./__generated__/graphql", Remedy: "run codegen"},
+		},
+		{
+			name:    "missing relative file",
+			code:    float64(2307),
+			message: "Cannot find module '../utils' or its corresponding type declarations.",
+			want:    &diagnosticCause{Kind: causeMissingFile, Specifier: "../utils", Remedy: "create the file or fix the import"},
+		},
+		{
+			name:    "missing aliased file",
+			code:    float64(2307),
+			message: "Cannot find module '@lib/missing' or its corresponding type declarations.",
+			want:    &diagnosticCause{Kind: causeMissingFile, Specifier: "@lib/missing", Remedy: "create the file or fix the import"},
+		},
+		{name: "existing relative file", code: float64(2307), message: "Cannot find module './util' or its corresponding type declarations."},
+		{name: "existing file by its output name", code: float64(2307), message: "Cannot find module './util.js' or its corresponding type declarations."},
+		{name: "existing aliased file", code: float64(2792), message: "Cannot find module '@lib/shared'. Did you mean to set the 'moduleResolution' option to 'nodenext'?"},
+		{name: "package with its own types", code: float64(2307), message: "Cannot find module 'typed' or its corresponding type declarations."},
+		{name: "other code", code: float64(2322), message: "Type 'string' is not assignable to type 'number'."},
+		{name: "no code", message: "Cannot find module 'zod'."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.classify(file, tt.code, tt.message)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cause = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedPaths(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	file := filepath.Join(root, "src", "app.ts")
+	message := func(spec string) string {
+		return "Cannot find module '" + spec + "' or its corresponding type declarations."
+	}
+	tests := []struct {
+		name      string
+		generated []string
+		spec      string
+		want      string
+	}{
+		{"default directory", nil, "../generated/api", causeGenerated},
+		{"default suffix", nil, "./schema.generated", causeGenerated},
+		{"not generated", nil, "./schema", causeMissingFile},
+		{"configured glob", []string{"src/gql/**"}, "./gql/types", causeGenerated},
+		{"configured globs replace the defaults", []string{"src/gql/**"}, "./__generated__/graphql", causeMissingFile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newCauseClassifier(root, tt.generated, nil).classify(file, 2307, message(tt.spec))
+			if got == nil || got.Kind != tt.want {
+				t.Errorf("cause = %+v, want kind %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyCauses(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"node_modules/lodash/package.json": `{}`,
+		"node_modules/react/package.json":  `{}`,
+	})
+	file := filepath.Join(root, "app.ts")
+	entries := []diagnosticEntry{
+		{File: file, Code: float64(7016), Message: "Could not find a declaration file for module 'lodash'."},
+		{File: file, Code: float64(2322), Message: "Type 'string' is not assignable to type 'number'."},
+		{File: file, Code: float64(2307), Message: "Cannot find module './__generated__/graphql' or its corresponding type declarations."},
+		{File: file, Code: float64(7016), Message: "Could not find a declaration file for module 'react'."},
+		{File: file, Code: float64(7016), Message: "Could not find a declaration file for module 'lodash/fp'."},
+	}
+	got := classifyCauses(newCauseClassifier(root, nil, nil), entries)
+	want := []causeSummary{
+		{Kind: causeMissingTypes, Count: 3, Remedies: []string{"install @types/lodash", "install @types/react"}},
+		{Kind: causeGenerated, Count: 1, Remedies: []string{"run codegen"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+	if entries[1].Cause != nil || entries[0].Cause == nil {
+		t.Errorf("causes = %+v, %+v", entries[0].Cause, entries[1].Cause)
+	}
+}
+
+func TestTypesPackage(t *testing.T) {
+	for pkg, want := range map[string]string{
+		"lodash":       "@types/lodash",
+		"@scope/pkg":   "@types/scope__pkg",
+		"@types/react": "@types/react",
+	} {
+		if got := typesPackage(pkg); got != want {
+			t.Errorf("typesPackage(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+	for spec, want := range map[string]string{"lodash/fp": "lodash", "@scope/pkg/sub/path": "@scope/pkg", "@scope": "@scope"} {
+		if got := packageName(spec); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
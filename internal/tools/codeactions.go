@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+type codeActionEntry struct {
+	// Index identifies the action to ts_apply_code_action for the same
+	// range and kind.
+	Index int    `json:"index"`
+	Title string `json:"title"`
+	Kind  string `json:"kind,omitempty"`
+	// HasEdit is also set for actions whose edit is resolved on demand.
+	HasEdit     bool `json:"hasEdit"`
+	HasCommand  bool `json:"hasCommand"`
+	IsPreferred bool `json:"isPreferred,omitempty"`
+	// Disabled is the reason the action cannot be applied, if any.
+	Disabled string `json:"disabled,omitempty"`
+	// Fixes are the messages of the diagnostics the action resolves.
+	Fixes []string `json:"fixes,omitempty"`
+}
+
+type codeActionsResult struct {
+	File    string            `json:"file"`
+	Actions []codeActionEntry `json:"actions"`
+	// Diagnostics is the number of diagnostics in the range sent along
+	// with the request.
+	Diagnostics int `json:"diagnostics"`
+}
+
+// codeActionRange is the 1-based, character-column range code actions are
+// asked for.
+type codeActionRange struct {
+	startLine, startCol, endLine, endCol int
+}
+
+// parseCodeActionRange reads startLine and startColumn, and endLine and
+// endColumn which default to the start, converting columns from cols.
+func parseCodeActionRange(request mcp.CallToolRequest, file string, cols columnMode) (codeActionRange, error) {
+	startLine, err := request.RequireInt("startLine")
+	if err != nil {
+		return codeActionRange{}, err
+	}
+	startCol, err := request.RequireInt("startColumn")
+	if err != nil {
+		return codeActionRange{}, err
+	}
+	endLine := request.GetInt("endLine", startLine)
+	endCol := request.GetInt("endColumn", startCol)
+	if endLine < startLine || (endLine == startLine && endCol < startCol) {
+		return codeActionRange{}, fmt.Errorf("the range ends at %d:%d, before its start at %d:%d", endLine, endCol, startLine, startCol)
+	}
+	return codeActionRange{
+		startLine: startLine,
+		startCol:  cols.charColumn(file, startLine, startCol),
+		endLine:   endLine,
+		endCol:    cols.charColumn(file, endLine, endCol),
+	}, nil
+}
+
+// overlaps reports whether the 0-based LSP range rng touches r.
+func (r codeActionRange) overlaps(rng protocol.Range) bool {
+	before := func(a protocol.Position, line, col int) bool {
+		return int(a.Line) < line-1 || (int(a.Line) == line-1 && int(a.Character) < col-1)
+	}
+	after := func(a protocol.Position, line, col int) bool {
+		return int(a.Line) > line-1 || (int(a.Line) == line-1 && int(a.Character) > col-1)
+	}
+	return !before(rng.End, r.startLine, r.startCol) && !after(rng.Start, r.endLine, r.endCol)
+}
+
+// kindMatches reports whether kind is filter or one of its sub-kinds, as
+// "refactor.extract" is of "refactor". An empty filter matches all.
+func kindMatches(kind protocol.CodeActionKind, filter string) bool {
+	return filter == "" || string(kind) == filter || strings.HasPrefix(string(kind), filter+".")
+}
+
+// listCodeActions returns the code actions of kind for r in file, which
+// must be synced, passing tsgo the file's diagnostics in r. It also
+// returns the number of those diagnostics. The result is in tsgo's order,
+// which the indexes of ts_code_actions refer to.
+func listCodeActions(ctx context.Context, client *lsp.Client, file string, r codeActionRange, kind string) ([]lsp.CodeAction, int, error) {
+	all, err := client.Diagnostic(ctx, file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("diagnostics error: %w", err)
+	}
+	var diags []protocol.Diagnostic
+	for _, d := range all {
+		if r.overlaps(d.Range) {
+			diags = append(diags, d)
+		}
+	}
+	var only []protocol.CodeActionKind
+	if kind != "" {
+		only = []protocol.CodeActionKind{protocol.CodeActionKind(kind)}
+	}
+	actions, err := client.CodeAction(ctx, file, r.startLine, r.startCol, r.endLine, r.endCol, diags, only)
+	if err != nil {
+		return nil, 0, fmt.Errorf("code action error: %w", err)
+	}
+	// only is a hint tsgo may ignore.
+	var out []lsp.CodeAction
+	for _, a := range actions {
+		if kindMatches(a.Kind, kind) {
+			out = append(out, a)
+		}
+	}
+	return out, len(diags), nil
+}
+
+// codeActionEntries describes actions by index.
+func codeActionEntries(actions []lsp.CodeAction) []codeActionEntry {
+	entries := make([]codeActionEntry, len(actions))
+	for i, a := range actions {
+		entry := codeActionEntry{
+			Index:       i,
+			Title:       a.Title,
+			Kind:        string(a.Kind),
+			HasEdit:     a.Edit != nil || a.Data != nil,
+			HasCommand:  a.Command != nil,
+			IsPreferred: a.IsPreferred,
+		}
+		if a.Disabled != nil {
+			entry.Disabled = a.Disabled.Reason
+		}
+		for _, d := range a.Diagnostics {
+			entry.Fixes = append(entry.Fixes, d.Message)
+		}
+		entries[i] = entry
+	}
+	return entries
+}
+
+func makeCodeActionsHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		r, err := parseCodeActionRange(request, file, cols)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		kind := request.GetString("kind", "")
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		actions, diags, err := listCodeActions(ctx, client, file, r, kind)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result := codeActionsResult{File: file, Actions: codeActionEntries(actions), Diagnostics: diags}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
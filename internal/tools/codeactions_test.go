@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestParseCodeActionRange(t *testing.T) {
+	request := func(args map[string]any) mcp.CallToolRequest {
+		var r mcp.CallToolRequest
+		r.Params.Arguments = args
+		return r
+	}
+	r, err := parseCodeActionRange(request(map[string]any{"startLine": 3, "startColumn": 7}), "/p/a.ts", columnMode{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (codeActionRange{startLine: 3, startCol: 7, endLine: 3, endCol: 7}) {
+		t.Errorf("range = %+v, want an empty range at the start", r)
+	}
+	_, err = parseCodeActionRange(request(map[string]any{"startLine": 3, "startColumn": 7, "endLine": 2, "endColumn": 1}), "/p/a.ts", columnMode{})
+	if err == nil {
+		t.Error("want an error for a range ending before it starts")
+	}
+}
+
+func TestCodeActionRangeOverlaps(t *testing.T) {
+	// Lines 3-4 in 1-based terms, from column 5 to column 2.
+	r := codeActionRange{startLine: 3, startCol: 5, endLine: 4, endCol: 2}
+	span := func(sl, sc, el, ec uint32) protocol.Range {
+		return protocol.Range{Start: protocol.Position{Line: sl, Character: sc}, End: protocol.Position{Line: el, Character: ec}}
+	}
+	tests := []struct {
+		name string
+		rng  protocol.Range
+		want bool
+	}{
+		{"inside", span(2, 6, 2, 9), true},
+		{"ends at the start", span(2, 0, 2, 4), true},
+		{"before", span(2, 0, 2, 3), false},
+		{"starts at the end", span(3, 1, 3, 8), true},
+		{"after", span(3, 2, 3, 8), false},
+		{"around", span(0, 0, 9, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.overlaps(tt.rng); got != tt.want {
+				t.Errorf("overlaps(%+v) = %v, want %v", tt.rng, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodeActionEntries(t *testing.T) {
+	actions := []lsp.CodeAction{
+		{CodeAction: protocol.CodeAction{Title: "Add import", Kind: protocol.QuickFix, IsPreferred: true,
+			Diagnostics: []protocol.Diagnostic{{Message: "Cannot find name 'x'."}}}, Edit: &lsp.WorkspaceEdit{}},
+		{CodeAction: protocol.CodeAction{Title: "Lazy", Kind: protocol.QuickFix, Data: map[string]any{"id": 1}}},
+		{CodeAction: protocol.CodeAction{Title: "Run", Command: &protocol.Command{Command: "x"}, Disabled: &protocol.CodeActionDisable{Reason: "not here"}}},
+	}
+	got := codeActionEntries(actions)
+	if e := got[0]; e.Index != 0 || !e.HasEdit || e.HasCommand || len(e.Fixes) != 1 || e.Kind != "quickfix" {
+		t.Errorf("entry 0 = %+v", e)
+	}
+	if e := got[1]; e.Index != 1 || !e.HasEdit {
+		t.Errorf("entry 1 = %+v, want a resolvable edit", e)
+	}
+	if e := got[2]; e.HasEdit || !e.HasCommand || e.Disabled != "not here" {
+		t.Errorf("entry 2 = %+v", e)
+	}
+
+	for kind, want := range map[protocol.CodeActionKind]bool{"refactor": true, "refactor.extract": true, "refactoring": false, "quickfix": false} {
+		if got := kindMatches(kind, "refactor"); got != want {
+			t.Errorf("kindMatches(%q, refactor) = %v", kind, got)
+		}
+	}
+}
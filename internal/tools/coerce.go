@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// JSON kinds of an argument value as decoded by encoding/json.
+const (
+	kindNull    = "null"
+	kindString  = "string"
+	kindNumber  = "number"
+	kindBoolean = "boolean"
+	kindArray   = "array"
+	kindObject  = "object"
+)
+
+// jsonKind classifies a decoded argument value.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return kindNull
+	case string:
+		return kindString
+	case float64, int, int64:
+		return kindNumber
+	case bool:
+		return kindBoolean
+	case []any:
+		return kindArray
+	case map[string]any:
+		return kindObject
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// coercion converts a value of one JSON kind to the type a schema expects.
+type coercion func(v any) (any, error)
+
+type coercionKey struct{ from, to string }
+
+// coercions is the lenient matrix of argument kind × schema type. Pairs
+// that are absent are type errors; null is handled before the lookup and
+// means the argument was not passed.
+var coercions = map[coercionKey]coercion{
+	{kindString, kindString}:   identity,
+	{kindNumber, kindString}:   numberToString,
+	{kindBoolean, kindString}:  func(v any) (any, error) { return strconv.FormatBool(v.(bool)), nil },
+	{kindNumber, kindNumber}:   identity,
+	{kindString, kindNumber}:   stringToNumber,
+	{kindBoolean, kindBoolean}: identity,
+	{kindString, kindBoolean}:  stringToBoolean,
+	{kindArray, kindArray}:     identity,
+	{kindString, kindArray}:    func(v any) (any, error) { return decodeJSONString[[]any](v.(string), kindArray) },
+	{kindObject, kindObject}:   identity,
+	{kindString, kindObject}:   func(v any) (any, error) { return decodeJSONString[map[string]any](v.(string), kindObject) },
+}
+
+func identity(v any) (any, error) { return v, nil }
+
+func numberToString(v any) (any, error) {
+	switch n := v.(type) {
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(n), nil
+	}
+	return strconv.FormatInt(v.(int64), 10), nil
+}
+
+func stringToNumber(v any) (any, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v.(string)), 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", v)
+	}
+	return f, nil
+}
+
+func stringToBoolean(v any) (any, error) {
+	switch strings.ToLower(strings.TrimSpace(v.(string))) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return nil, fmt.Errorf("%q is not a boolean", v)
+}
+
+// decodeJSONString accepts a JSON-encoded array or object passed as a
+// string.
+func decodeJSONString[T any](s, kind string) (any, error) {
+	var out T
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		return nil, fmt.Errorf("%q is not a JSON %s", s, kind)
+	}
+	return out, nil
+}
+
+// coerceArgument converts v to the schema type want. ok is false when v
+// is null, which is treated as if the argument were missing.
+func coerceArgument(v any, want string) (out any, ok bool, err error) {
+	from := jsonKind(v)
+	if from == kindNull {
+		return nil, false, nil
+	}
+	if want == "integer" {
+		want = kindNumber
+	}
+	c, found := coercions[coercionKey{from, want}]
+	if !found {
+		return nil, false, fmt.Errorf("expected %s, got %s", want, from)
+	}
+	out, err = c(v)
+	if err != nil {
+		return nil, false, fmt.Errorf("expected %s: %w", want, err)
+	}
+	return out, true, nil
+}
+
+// coerceArguments converts args to the types declared by schema.
+// Arguments the schema does not describe are passed through unchanged.
+func coerceArguments(args map[string]any, schema mcp.ToolInputSchema) (map[string]any, error) {
+	out := make(map[string]any, len(args))
+	for _, name := range slices.Sorted(maps.Keys(args)) {
+		v := args[name]
+		prop, _ := schema.Properties[name].(map[string]any)
+		want, _ := prop["type"].(string)
+		if want == "" {
+			out[name] = v
+			continue
+		}
+		c, ok, err := coerceArgument(v, want)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", name, err)
+		}
+		if ok {
+			out[name] = c
+		}
+	}
+	return out, nil
+}
+
+// withArgumentCoercion converts the arguments of every call to the types of
+// tool's input schema before h reads them, for clients that pass every
+// argument as a string. With debug set, the converted arguments are echoed
+// back as a coercedArguments item.
+func withArgumentCoercion(tool mcp.Tool, debug bool, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args, err := coerceArguments(request.GetArguments(), tool.InputSchema)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		request.Params.Arguments = args
+		result, err := h(ctx, request)
+		if err != nil || result == nil || !debug {
+			return result, err
+		}
+		if data, err := json.Marshal(args); err == nil {
+			result.Content = append(result.Content, mcp.NewTextContent("coercedArguments: "+string(data)))
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCoerceArgument(t *testing.T) {
+	// Every JSON input kind against every schema type. An empty err means
+	// the conversion succeeds with want; missing means null was dropped.
+	tests := []struct {
+		in      any
+		to      string
+		want    any
+		missing bool
+		err     string
+	}{
+		{in: "src/a.ts", to: "string", want: "src/a.ts"},
+		{in: "42", to: "number", want: 42.0},
+		{in: " 4.5 ", to: "number", want: 4.5},
+		{in: "forty", to: "number", err: `"forty" is not a number`},
+		{in: "", to: "number", err: "is not a number"},
+		{in: "true", to: "boolean", want: true},
+		{in: "FALSE", to: "boolean", want: false},
+		{in: "yes", to: "boolean", err: `"yes" is not a boolean`},
+		{in: `["a.ts","b.ts"]`, to: "array", want: []any{"a.ts", "b.ts"}},
+		{in: "a.ts", to: "array", err: "is not a JSON array"},
+		{in: `{"a":1}`, to: "object", want: map[string]any{"a": 1.0}},
+		{in: "[1]", to: "object", err: "is not a JSON object"},
+
+		{in: 42.0, to: "string", want: "42"},
+		{in: 1.5, to: "string", want: "1.5"},
+		{in: 42.0, to: "number", want: 42.0},
+		{in: 42, to: "integer", want: 42},
+		{in: 1.0, to: "boolean", err: "expected boolean, got number"},
+		{in: 1.0, to: "array", err: "expected array, got number"},
+		{in: 1.0, to: "object", err: "expected object, got number"},
+
+		{in: true, to: "string", want: "true"},
+		{in: true, to: "number", err: "expected number, got boolean"},
+		{in: false, to: "boolean", want: false},
+		{in: true, to: "array", err: "expected array, got boolean"},
+		{in: true, to: "object", err: "expected object, got boolean"},
+
+		{in: []any{"a"}, to: "string", err: "expected string, got array"},
+		{in: []any{"a"}, to: "number", err: "expected number, got array"},
+		{in: []any{"a"}, to: "boolean", err: "expected boolean, got array"},
+		{in: []any{"a"}, to: "array", want: []any{"a"}},
+		{in: []any{"a"}, to: "object", err: "expected object, got array"},
+
+		{in: map[string]any{}, to: "string", err: "expected string, got object"},
+		{in: map[string]any{}, to: "number", err: "expected number, got object"},
+		{in: map[string]any{}, to: "boolean", err: "expected boolean, got object"},
+		{in: map[string]any{}, to: "array", err: "expected array, got object"},
+		{in: map[string]any{}, to: "object", want: map[string]any{}},
+
+		{in: nil, to: "string", missing: true},
+		{in: nil, to: "number", missing: true},
+		{in: nil, to: "boolean", missing: true},
+		{in: nil, to: "array", missing: true},
+		{in: nil, to: "object", missing: true},
+	}
+	for _, tt := range tests {
+		got, ok, err := coerceArgument(tt.in, tt.to)
+		switch {
+		case tt.err != "":
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("coerce(%#v, %s) error = %v, want %q", tt.in, tt.to, err, tt.err)
+			}
+		case err != nil:
+			t.Errorf("coerce(%#v, %s): %v", tt.in, tt.to, err)
+		case ok == tt.missing:
+			t.Errorf("coerce(%#v, %s) ok = %v", tt.in, tt.to, ok)
+		case !reflect.DeepEqual(got, tt.want):
+			t.Errorf("coerce(%#v, %s) = %#v, want %#v", tt.in, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestWithArgumentCoercion(t *testing.T) {
+	tool := mcp.NewTool("ts_hover",
+		mcp.WithString("file", mcp.Required()),
+		mcp.WithNumber("line", mcp.Required()),
+		mcp.WithBoolean("confirm"),
+		mcp.WithArray("files", mcp.WithStringItems()),
+	)
+	var got map[string]any
+	h := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		got = request.GetArguments()
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if !request.GetBool("confirm", false) || line != 42 {
+			return mcp.NewToolResultError("arguments not coerced"), nil
+		}
+		return mcp.NewToolResultText("ok"), nil
+	}
+
+	var req mcp.CallToolRequest
+	req.Params.Arguments = map[string]any{
+		"file": "/a.ts", "line": "42", "confirm": "true", "files": `["/b.ts"]`, "tsconfig": nil, "extra": 1,
+	}
+	res, err := withArgumentCoercion(tool, false, h)(context.Background(), req)
+	if err != nil || res.IsError {
+		t.Fatalf("call = %+v, %v", res, err)
+	}
+	want := map[string]any{"file": "/a.ts", "line": 42.0, "confirm": true, "files": []any{"/b.ts"}, "tsconfig": nil, "extra": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("arguments = %v, want %v", got, want)
+	}
+	if len(res.Content) != 1 {
+		t.Errorf("coercedArguments echoed without debug: %+v", res.Content)
+	}
+
+	res, _ = withArgumentCoercion(tool, true, h)(context.Background(), req)
+	last := res.Content[len(res.Content)-1].(mcp.TextContent).Text
+	if !strings.HasPrefix(last, `coercedArguments: {"confirm":true`) {
+		t.Errorf("debug echo = %q", last)
+	}
+
+	req.Params.Arguments = map[string]any{"file": "/a.ts", "line": "x"}
+	res, _ = withArgumentCoercion(tool, false, h)(context.Background(), req)
+	if !res.IsError || !strings.Contains(res.Content[0].(mcp.TextContent).Text, `argument "line": expected number`) {
+		t.Errorf("bad line = %+v", res.Content)
+	}
+}
@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// maxResolvedCompletions bounds the resolveDocs argument of ts_completion:
+// every resolved item is one more round trip to tsgo.
+const maxResolvedCompletions = 20
+
+type completionEntry struct {
+	Label string `json:"label"`
+	Kind  string `json:"kind,omitempty"`
+	// Detail is usually the type or signature of the completion.
+	Detail     string `json:"detail,omitempty"`
+	InsertText string `json:"insertText"`
+	// Documentation is only set for the first resolveDocs entries.
+	Documentation string `json:"documentation,omitempty"`
+	Deprecated    bool   `json:"deprecated,omitempty"`
+}
+
+type completionResult struct {
+	Completions []completionEntry `json:"completions"`
+	TotalCount  int               `json:"totalCount"`
+	// Truncated is set when more than maxResults completions matched.
+	Truncated bool `json:"truncated"`
+	// IsIncomplete is set when tsgo did not compute the full list, e.g.
+	// because more typing would narrow it; ask again further on.
+	IsIncomplete bool `json:"isIncomplete,omitempty"`
+}
+
+// completionKindName returns the lowercase name of a completion kind, or
+// "" when it is unknown.
+func completionKindName(k protocol.CompletionItemKind) string {
+	if k < protocol.CompletionItemKindText || k > protocol.CompletionItemKindTypeParameter {
+		return ""
+	}
+	switch k {
+	case protocol.CompletionItemKindEnumMember:
+		return "enum member"
+	case protocol.CompletionItemKindTypeParameter:
+		return "type parameter"
+	}
+	return strings.ToLower(k.String())
+}
+
+// markupText returns the text of an LSP documentation value, which is a
+// string or a MarkupContent.
+func markupText(doc any) string {
+	switch d := doc.(type) {
+	case string:
+		return strings.TrimSpace(d)
+	case map[string]any:
+		v, _ := d["value"].(string)
+		return strings.TrimSpace(v)
+	case protocol.MarkupContent:
+		return strings.TrimSpace(d.Value)
+	case *protocol.MarkupContent:
+		if d != nil {
+			return strings.TrimSpace(d.Value)
+		}
+	}
+	return ""
+}
+
+// sortCompletions orders items as an editor would show them: by sortText,
+// falling back to the label.
+func sortCompletions(items []protocol.CompletionItem) {
+	key := func(it protocol.CompletionItem) string {
+		if it.SortText != "" {
+			return it.SortText
+		}
+		return it.Label
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := key(items[i]), key(items[j])
+		if a != b {
+			return a < b
+		}
+		return items[i].Label < items[j].Label
+	})
+}
+
+// completionEntryOf converts a completion item. The insert text is the
+// item's insertText, else the new text of its edit, else its label.
+func completionEntryOf(item protocol.CompletionItem) completionEntry {
+	e := completionEntry{
+		Label:         item.Label,
+		Kind:          completionKindName(item.Kind),
+		Detail:        strings.TrimSpace(item.Detail),
+		InsertText:    item.InsertText,
+		Documentation: markupText(item.Documentation),
+		Deprecated:    item.Deprecated || slices.Contains(item.Tags, protocol.CompletionItemTagDeprecated),
+	}
+	if e.InsertText == "" && item.TextEdit != nil {
+		e.InsertText = item.TextEdit.NewText
+	}
+	if e.InsertText == "" {
+		e.InsertText = item.Label
+	}
+	return e
+}
+
+func makeCompletionHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		maxResults := request.GetInt("maxResults", 50)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+		resolveDocs := request.GetInt("resolveDocs", 0)
+		if resolveDocs < 0 || resolveDocs > maxResolvedCompletions {
+			return mcp.NewToolResultError(fmt.Sprintf("resolveDocs must be between 0 and %d", maxResolvedCompletions)), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		list, err := client.Completion(ctx, file, line, col)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("completion error: %v", err)), nil
+		}
+
+		items := list.Items
+		sortCompletions(items)
+		result := completionResult{
+			Completions:  []completionEntry{},
+			TotalCount:   len(items),
+			IsIncomplete: list.IsIncomplete,
+		}
+		if len(items) > maxResults {
+			items = items[:maxResults]
+			result.Truncated = true
+		}
+		for i, item := range items {
+			// A failed resolve keeps the unresolved item; documentation is
+			// a bonus.
+			if i < resolveDocs {
+				if resolved, err := client.ResolveCompletion(ctx, &item); err == nil && resolved != nil {
+					item = *resolved
+				}
+			}
+			result.Completions = append(result.Completions, completionEntryOf(item))
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestCompletionEntryOf(t *testing.T) {
+	tests := []struct {
+		name string
+		item protocol.CompletionItem
+		want completionEntry
+	}{
+		{
+			name: "insert text",
+			item: protocol.CompletionItem{Label: "greet", Kind: protocol.CompletionItemKindFunction, Detail: " function greet(name: string): string ", InsertText: "greet"},
+			want: completionEntry{Label: "greet", Kind: "function", Detail: "function greet(name: string): string", InsertText: "greet"},
+		},
+		{
+			name: "text edit",
+			item: protocol.CompletionItem{Label: "x-y", Kind: protocol.CompletionItemKindProperty, TextEdit: &protocol.TextEdit{NewText: `["x-y"]`}},
+			want: completionEntry{Label: "x-y", Kind: "property", InsertText: `["x-y"]`},
+		},
+		{
+			name: "label",
+			item: protocol.CompletionItem{Label: "Red", Kind: protocol.CompletionItemKindEnumMember, Tags: []protocol.CompletionItemTag{protocol.CompletionItemTagDeprecated}},
+			want: completionEntry{Label: "Red", Kind: "enum member", InsertText: "Red", Deprecated: true},
+		},
+		{
+			name: "markup documentation",
+			item: protocol.CompletionItem{Label: "a", Documentation: map[string]any{"kind": "markdown", "value": "Says hello.\n"}},
+			want: completionEntry{Label: "a", InsertText: "a", Documentation: "Says hello."},
+		},
+		{
+			name: "string documentation",
+			item: protocol.CompletionItem{Label: "a", Documentation: "plain"},
+			want: completionEntry{Label: "a", InsertText: "a", Documentation: "plain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := completionEntryOf(tt.item); got != tt.want {
+				t.Errorf("completionEntryOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortCompletions(t *testing.T) {
+	items := []protocol.CompletionItem{
+		{Label: "zeta", SortText: "11"},
+		{Label: "beta"},
+		{Label: "alpha", SortText: "11"},
+		{Label: "gamma", SortText: "0"},
+	}
+	sortCompletions(items)
+	var got []string
+	for _, it := range items {
+		got = append(got, it.Label)
+	}
+	want := []string{"gamma", "alpha", "zeta", "beta"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
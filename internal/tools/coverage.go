@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+type projectCoverageResult struct {
+	workspace.Coverage
+	// AssignedElsewhere lists files the config includes that tsgo's logs
+	// report as served by another project.
+	AssignedElsewhere []projectAssignment `json:"assignedElsewhere,omitempty"`
+	// Truncated is set when any file list was cut to maxResults.
+	Truncated bool `json:"truncated"`
+}
+
+type projectAssignment struct {
+	File    string `json:"file"`
+	Project string `json:"project"`
+}
+
+// assignedElsewhere returns the project files whose owning project, as
+// reported by tsgo, is not configPath. Files without a reported owner are
+// left out.
+func assignedElsewhere(configPath string, projectFiles []string, owners map[string]string) []projectAssignment {
+	var out []projectAssignment
+	for _, f := range projectFiles {
+		if owner, ok := owners[filepath.Clean(f)]; ok && owner != filepath.Clean(configPath) {
+			out = append(out, projectAssignment{File: f, Project: owner})
+		}
+	}
+	return out
+}
+
+func makeProjectCoverageHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		configPath := request.GetString("tsconfig", "")
+		if configPath == "" {
+			configPath = filepath.Join(client.RootDir(), "tsconfig.json")
+		}
+		maxResults := request.GetInt("maxResults", 50)
+
+		cfg, err := tsconfig.Load(configPath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("tsconfig error: %v", err)), nil
+		}
+
+		files := workspace.ProjectFiles(cfg)
+		analyzed := append(client.AnalyzedFiles(), docs.OpenFiles()...)
+		result := projectCoverageResult{
+			Coverage:          workspace.Reconcile(cfg, files, analyzed),
+			AssignedElsewhere: assignedElsewhere(cfg.Path, files, client.ProjectAssignments()),
+		}
+		if len(result.NeverAnalyzed) > maxResults {
+			result.NeverAnalyzed = result.NeverAnalyzed[:maxResults]
+			result.Truncated = true
+		}
+		if len(result.AnalyzedButExcluded) > maxResults {
+			result.AnalyzedButExcluded = result.AnalyzedButExcluded[:maxResults]
+			result.Truncated = true
+		}
+		if len(result.AssignedElsewhere) > maxResults {
+			result.AssignedElsewhere = result.AssignedElsewhere[:maxResults]
+			result.Truncated = true
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,23 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssignedElsewhere(t *testing.T) {
+	files := []string{"/repo/src/a.ts", "/repo/src/b.ts", "/repo/src/c.ts"}
+	owners := map[string]string{
+		"/repo/src/a.ts": "/repo/tsconfig.json",
+		"/repo/src/b.ts": "/repo/tsconfig.test.json",
+		"/other/x.ts":    "/other/tsconfig.json",
+	}
+	got := assignedElsewhere("/repo/./tsconfig.json", files, owners)
+	want := []projectAssignment{{File: "/repo/src/b.ts", Project: "/repo/tsconfig.test.json"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("assignedElsewhere = %+v, want %+v", got, want)
+	}
+	if got := assignedElsewhere("/repo/tsconfig.json", files, nil); got != nil {
+		t.Errorf("without assignments = %+v, want nil", got)
+	}
+}
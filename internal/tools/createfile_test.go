@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const createdContent = "export const renamed = 1;\n"
+
+// insertAtStart is an edit inserting text at the start of a file.
+func insertAtStart(text string) protocol.TextEdit {
+	return protocol.TextEdit{NewText: text}
+}
+
+func TestApplyWorkspaceEditCreatesFiles(t *testing.T) {
+	t.Run("explicit create in new directories", func(t *testing.T) {
+		dir := t.TempDir()
+		p := filepath.Join(dir, "src", "gen", "new.ts")
+		uri := protocol.DocumentURI("file://" + p)
+		edit := &lsp.WorkspaceEdit{
+			WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{
+				{TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri}}, Edits: []protocol.TextEdit{insertAtStart(createdContent)}},
+			}},
+			Operations: []lsp.ResourceOperation{{Kind: protocol.CreateResourceOperation, URI: uri}},
+		}
+		result, err := applyWorkspaceEdit(edit, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info := result[p]; !info.Created || info.Edits != 1 {
+			t.Errorf("result = %+v, want a created file", info)
+		}
+		if got := fileContents(t, []string{p}); got[0] != createdContent {
+			t.Errorf("created file = %q", got[0])
+		}
+	})
+
+	t.Run("implicit create at the start of a file", func(t *testing.T) {
+		files, edit := journalFixture(t, 1)
+		p := filepath.Join(filepath.Dir(files[0]), "new.ts")
+		edit.Changes[protocol.DocumentURI("file://"+p)] = []protocol.TextEdit{insertAtStart(createdContent)}
+		result, err := applyWorkspaceEdit(edit, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result[p].Created || result[files[0]].Created {
+			t.Errorf("result = %+v", result)
+		}
+		if got := fileContents(t, []string{files[0], p}); got[0] != renamedContent || got[1] != createdContent {
+			t.Errorf("contents = %q", got)
+		}
+	})
+
+	t.Run("edits inside a missing file", func(t *testing.T) {
+		files, edit := journalFixture(t, 1)
+		p := filepath.Join(filepath.Dir(files[0]), "missing.ts")
+		edit.Changes[protocol.DocumentURI("file://"+p)] = []protocol.TextEdit{{
+			Range:   protocol.Range{Start: protocol.Position{Line: 4, Character: 2}, End: protocol.Position{Line: 4, Character: 5}},
+			NewText: "renamed",
+		}}
+		_, err := applyWorkspaceEdit(edit, nil, nil, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "start at line 5 rather than creating it") {
+			t.Fatalf("error = %v", err)
+		}
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("missing file was created: %v", err)
+		}
+		if got := fileContents(t, files); got[0] != oldContent {
+			t.Errorf("existing file = %q", got[0])
+		}
+	})
+}
+
+func TestCreatedFileRollback(t *testing.T) {
+	t.Cleanup(func() { beforeEditWrite = nil })
+	for _, journaled := range []bool{false, true} {
+		name := "direct"
+		if journaled {
+			name = "journaled"
+		}
+		t.Run(name, func(t *testing.T) {
+			files, we := journalFixture(t, 1)
+			dir := filepath.Dir(files[0])
+			// "a" sorts before the existing f00.ts, so it is written first.
+			p := filepath.Join(dir, "a", "b", "new.ts")
+			we.Changes[protocol.DocumentURI("file://"+p)] = []protocol.TextEdit{insertAtStart(createdContent)}
+			beforeEditWrite = func(path string) error {
+				if path == files[0] {
+					writeString(t, path, agentContent)
+				}
+				return nil
+			}
+			var journal *journalPolicy
+			if journaled {
+				journal = &journalPolicy{dir: t.TempDir(), always: true, chunkSize: 1}
+			}
+
+			_, err := applyWorkspaceEdit(we, nil, nil, journal, nil)
+			var cm *edit.ConcurrentModificationError
+			if !errors.As(err, &cm) || cm.File != files[0] {
+				t.Fatalf("error = %v, want ERR_CONCURRENT_MODIFICATION on %s", err, files[0])
+			}
+			if _, err := os.Stat(filepath.Join(dir, "a")); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("rollback left the created file's directories: %v", err)
+			}
+		})
+	}
+}
+
+func TestUndoCreatedFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "gen", "new.ts")
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+		protocol.DocumentURI("file://" + p): {insertAtStart(createdContent)},
+	}}}
+	r := testRecorder(t.TempDir(), 0, recordStart)
+	if _, err := applyWorkspaceEdit(edit, nil, nil, nil, r.recording(editOrigin{tool: "ts_apply_edit"})); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := r.undoLast("ts_undo_last_edit"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gen")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("undo left the created file or its directory: %v", err)
+	}
+}
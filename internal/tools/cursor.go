@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCursorTTL is how long a pagination snapshot is kept.
+	defaultCursorTTL = 10 * time.Minute
+	// defaultMaxCursors caps live snapshots per tool; the oldest is
+	// dropped first.
+	defaultMaxCursors = 32
+)
+
+// pageCursor is a snapshot of a complete, ordered result that later pages
+// are served from, so they neither overlap nor miss entries when the
+// server would answer a re-run query differently.
+type pageCursor[T any] struct {
+	tool  string
+	items []T
+	// versions maps every file the items point into to its docsync
+	// version when the snapshot was taken (0 when it was not open).
+	versions map[string]int32
+	// info is tool-specific data computed with the first page and
+	// returned with every later one.
+	info    any
+	created time.Time
+	// invalid is the reason the snapshot went stale, once it has.
+	invalid string
+}
+
+// cursorStore holds pagination snapshots keyed by an opaque ID. A snapshot
+// is invalidated when any file it covers gets a new docsync version, and
+// the reason is reported to the next caller instead of serving stale
+// entries.
+type cursorStore[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	now     func() time.Time
+	cursors map[string]*pageCursor[T]
+}
+
+// newCursorStore creates a cursor store. Non-positive arguments select
+// defaultCursorTTL and defaultMaxCursors.
+func newCursorStore[T any](ttl time.Duration, maxCursors int) *cursorStore[T] {
+	if ttl <= 0 {
+		ttl = defaultCursorTTL
+	}
+	if maxCursors <= 0 {
+		maxCursors = defaultMaxCursors
+	}
+	return &cursorStore[T]{
+		ttl:     ttl,
+		max:     maxCursors,
+		now:     time.Now,
+		cursors: make(map[string]*pageCursor[T]),
+	}
+}
+
+// Put snapshots items and info for tool and returns the cursor ID.
+func (s *cursorStore[T]) Put(tool string, items []T, versions map[string]int32, info any) (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating cursor: %w", err)
+	}
+	id := hex.EncodeToString(b[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	for len(s.cursors) >= s.max {
+		s.evictOldestLocked()
+	}
+	s.cursors[id] = &pageCursor[T]{tool: tool, items: items, versions: versions, info: info, created: s.now()}
+	return id, nil
+}
+
+// Get returns the snapshot for id. It fails if the cursor is unknown,
+// expired, belongs to another tool, or was invalidated.
+func (s *cursorStore[T]) Get(tool, id string) (items []T, info any, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	c, ok := s.cursors[id]
+	if !ok {
+		return nil, nil, fmt.Errorf("cursor %q is unknown or expired; restart pagination without a cursor", id)
+	}
+	if c.tool != tool {
+		return nil, nil, fmt.Errorf("cursor %q belongs to %s, not %s", id, c.tool, tool)
+	}
+	if c.invalid != "" {
+		return nil, nil, fmt.Errorf("cursor invalidated, restart pagination: %s", c.invalid)
+	}
+	return c.items, c.info, nil
+}
+
+// InvalidateFile marks every snapshot covering path as stale unless it
+// was taken at version. It is registered with docsync.Manager.OnChange.
+func (s *cursorStore[T]) InvalidateFile(path string, version int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.cursors {
+		if old, ok := c.versions[path]; ok && old != version && c.invalid == "" {
+			c.invalid = fmt.Sprintf("%s changed since the first page (version %d, now %d)", path, old, version)
+		}
+	}
+}
+
+// Len returns the number of live cursors, including invalidated ones that
+// have not expired.
+func (s *cursorStore[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	return len(s.cursors)
+}
+
+func (s *cursorStore[T]) pruneLocked() {
+	cutoff := s.now().Add(-s.ttl)
+	for id, c := range s.cursors {
+		if c.created.Before(cutoff) {
+			delete(s.cursors, id)
+		}
+	}
+}
+
+func (s *cursorStore[T]) evictOldestLocked() {
+	ids := make([]string, 0, len(s.cursors))
+	for id := range s.cursors {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return s.cursors[ids[i]].created.Before(s.cursors[ids[j]].created) })
+	delete(s.cursors, ids[0])
+}
+
+// formatCursor encodes a snapshot ID and the offset of the next page into
+// the cursor string handed to callers.
+func formatCursor(id string, offset int) string {
+	return id + ":" + strconv.Itoa(offset)
+}
+
+// parseCursor splits a cursor string made by formatCursor.
+func parseCursor(cursor string) (id string, offset int, err error) {
+	id, off, ok := strings.Cut(cursor, ":")
+	if ok {
+		offset, err = strconv.Atoi(off)
+	}
+	if !ok || err != nil || offset < 0 || id == "" {
+		return "", 0, fmt.Errorf("malformed cursor %q", cursor)
+	}
+	return id, offset, nil
+}
+
+// page is one page of a snapshot.
+type page[T any] struct {
+	Items      []T
+	Offset     int
+	NextCursor string
+}
+
+// paginate serves items[offset:offset+size]. The first page (id empty) of
+// a result that does not fit is snapshotted with versions and info under a
+// new cursor; later pages reuse the cursor's ID.
+func paginate[T any](store *cursorStore[T], tool string, items []T, versions map[string]int32, info any, id string, offset, size int) (page[T], error) {
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := min(offset+size, len(items))
+	p := page[T]{Items: items[offset:end], Offset: offset}
+	if end < len(items) {
+		if id == "" {
+			var err error
+			if id, err = store.Put(tool, items, versions, info); err != nil {
+				return page[T]{}, err
+			}
+		}
+		p.NextCursor = formatCursor(id, end)
+	}
+	return p, nil
+}
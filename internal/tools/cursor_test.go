@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+)
+
+func TestCursorStore(t *testing.T) {
+	t.Run("put then get", func(t *testing.T) {
+		store := newCursorStore[int](0, 0)
+		id, err := store.Put("ts_references", []int{1, 2, 3}, nil, "info")
+		if err != nil || id == "" {
+			t.Fatalf("Put = %q, %v", id, err)
+		}
+		items, info, err := store.Get("ts_references", id)
+		if err != nil || !reflect.DeepEqual(items, []int{1, 2, 3}) || info != "info" {
+			t.Errorf("Get = %v, %v, %v", items, info, err)
+		}
+		if _, _, err := store.Get("ts_diagnostics", id); err == nil {
+			t.Error("expected a cursor of another tool to be refused")
+		}
+		if _, _, err := store.Get("ts_references", "nope"); err == nil {
+			t.Error("expected error for an unknown cursor")
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		store := newCursorStore[int](time.Minute, 0)
+		now := time.Now()
+		store.now = func() time.Time { return now }
+		id, _ := store.Put("ts_references", []int{1}, nil, nil)
+		now = now.Add(2 * time.Minute)
+		if _, _, err := store.Get("ts_references", id); err == nil || !strings.Contains(err.Error(), "expired") {
+			t.Errorf("Get after TTL = %v, want an expiry error", err)
+		}
+		if store.Len() != 0 {
+			t.Errorf("Len = %d, want 0", store.Len())
+		}
+	})
+
+	t.Run("cap evicts oldest", func(t *testing.T) {
+		store := newCursorStore[int](0, 2)
+		now := time.Now()
+		store.now = func() time.Time { now = now.Add(time.Second); return now }
+		first, _ := store.Put("ts_references", nil, nil, nil)
+		second, _ := store.Put("ts_references", nil, nil, nil)
+		third, _ := store.Put("ts_references", nil, nil, nil)
+		if store.Len() != 2 {
+			t.Fatalf("Len = %d, want 2", store.Len())
+		}
+		if _, _, err := store.Get("ts_references", first); err == nil {
+			t.Error("oldest cursor survived the cap")
+		}
+		for _, id := range []string{second, third} {
+			if _, _, err := store.Get("ts_references", id); err != nil {
+				t.Errorf("Get(%s): %v", id, err)
+			}
+		}
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+		store := newCursorStore[int](0, 0)
+		id, _ := store.Put("ts_references", []int{1}, map[string]int32{"/a.ts": 2, "/b.ts": 0}, nil)
+		other, _ := store.Put("ts_references", []int{1}, map[string]int32{"/c.ts": 1}, nil)
+
+		store.InvalidateFile("/a.ts", 2) // the snapshot's own version
+		if _, _, err := store.Get("ts_references", id); err != nil {
+			t.Fatalf("invalidated by its own version: %v", err)
+		}
+		store.InvalidateFile("/a.ts", 3)
+		_, _, err := store.Get("ts_references", id)
+		if err == nil || !strings.Contains(err.Error(), "cursor invalidated, restart pagination: /a.ts changed") {
+			t.Errorf("Get after change = %v", err)
+		}
+		if _, _, err := store.Get("ts_references", other); err != nil {
+			t.Errorf("unrelated cursor invalidated: %v", err)
+		}
+	})
+}
+
+func TestParseCursor(t *testing.T) {
+	id, offset, err := parseCursor(formatCursor("abc", 50))
+	if err != nil || id != "abc" || offset != 50 {
+		t.Errorf("round trip = %q, %d, %v", id, offset, err)
+	}
+	for _, bad := range []string{"abc", ":5", "abc:", "abc:-1", "abc:x"} {
+		if _, _, err := parseCursor(bad); err == nil {
+			t.Errorf("parseCursor(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	store := newCursorStore[int](0, 0)
+	items := []int{1, 2, 3, 4, 5}
+
+	first, err := paginate(store, "ts_references", items, nil, nil, "", 0, 2)
+	if err != nil || !reflect.DeepEqual(first.Items, []int{1, 2}) || first.NextCursor == "" {
+		t.Fatalf("first page = %+v, %v", first, err)
+	}
+	id, offset, _ := parseCursor(first.NextCursor)
+	snapshot, _, err := store.Get("ts_references", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, _ := paginate(store, "ts_references", snapshot, nil, nil, id, offset, 2)
+	if !reflect.DeepEqual(second.Items, []int{3, 4}) || second.Offset != 2 {
+		t.Errorf("second page = %+v", second)
+	}
+	if gotID, _, _ := parseCursor(second.NextCursor); gotID != id {
+		t.Errorf("second page cursor ID = %q, want the snapshot's %q", gotID, id)
+	}
+	third, _ := paginate(store, "ts_references", snapshot, nil, nil, id, 4, 2)
+	if !reflect.DeepEqual(third.Items, []int{5}) || third.NextCursor != "" {
+		t.Errorf("last page = %+v", third)
+	}
+	if store.Len() != 1 {
+		t.Errorf("Len = %d, want one snapshot for all pages", store.Len())
+	}
+
+	whole, _ := paginate(store, "ts_references", items, nil, nil, "", 0, 10)
+	if whole.NextCursor != "" || store.Len() != 1 {
+		t.Errorf("a result that fits got a cursor: %+v", whole)
+	}
+}
+
+func TestCursorInvalidatedBySync(t *testing.T) {
+	ctx := context.Background()
+	p := filepath.Join(t.TempDir(), "a.ts")
+	if err := os.WriteFile(p, []byte("export const a = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	docs := docsync.NewManager()
+	conn := &nopConn{}
+	store := newCursorStore[referenceEntry](0, 0)
+	docs.OnChange(store.InvalidateFile)
+	if err := docs.SyncFile(ctx, conn, p); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := []referenceEntry{{File: p, Line: 1, Column: 14}, {File: p, Line: 1, Column: 20}}
+	pg, err := paginate(store, "ts_references", refs, fileVersions(docs, []string{p}), nil, "", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, _ := parseCursor(pg.NextCursor)
+
+	if err := os.WriteFile(p, []byte("export const a = 2;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.ResyncFile(ctx, conn, p); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Get("ts_references", id); err == nil || !strings.Contains(err.Error(), "version 1, now 2") {
+		t.Errorf("Get after edit = %v, want an invalidation naming the versions", err)
+	}
+}
+
+func TestSortReferences(t *testing.T) {
+	refs := []referenceEntry{
+		{File: "/b.ts", Line: 1, Column: 1},
+		{File: "/a.ts", Line: 9, Column: 1},
+		{File: "/a.ts", Line: 2, Column: 7},
+		{File: "/a.ts", Line: 2, Column: 3},
+	}
+	sortReferences(refs)
+	var got []string
+	for _, r := range refs {
+		got = append(got, fmt.Sprintf("%s:%d:%d", r.File, r.Line, r.Column))
+	}
+	want := []string{"/a.ts:2:3", "/a.ts:2:7", "/a.ts:9:1", "/b.ts:1:1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted = %v, want %v", got, want)
+	}
+}
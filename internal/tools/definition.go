@@ -4,22 +4,72 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
 	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
 	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
 )
 
 type definitionEntry struct {
-	File    string `json:"file"`
-	Line    int    `json:"line"`
-	Column  int    `json:"column"`
-	Preview string `json:"preview,omitempty"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Preview is the declaration's line, or all lines of a signature that
+	// spans several, up to maxSignatureLines; PreviewTruncated is set when
+	// the cap cut it short.
+	Preview          string `json:"preview,omitempty"`
+	PreviewTruncated bool   `json:"previewTruncated,omitempty"`
+	// Package and DisplayPath are set for locations inside node_modules.
+	Package     *workspace.Package `json:"package,omitempty"`
+	DisplayPath string             `json:"displayPath,omitempty"`
+	// Ambient marks a global declaration found by scanning declaration
+	// files after the server returned nothing.
+	Ambient bool `json:"ambient,omitempty"`
+	// AdjustedColumn is the column the lookup was retried at, the type
+	// name of a JSDoc annotation, when the requested one found nothing.
+	AdjustedColumn int `json:"adjustedColumn,omitempty"`
+	// VisualColumn is Column with tabs expanded, in columnMode "visual".
+	VisualColumn int `json:"visualColumn,omitempty"`
+	// Body is the source of the declaration, with includeBody: the lines
+	// of the innermost document symbol enclosing it, or a window of lines
+	// around it when there is none (BodyFallback). BodyStartLine and
+	// BodyEndLine are its first and last line; BodyTruncated is set when
+	// maxBodyLines cut it short.
+	Body          string `json:"body,omitempty"`
+	BodyStartLine int    `json:"bodyStartLine,omitempty"`
+	BodyEndLine   int    `json:"bodyEndLine,omitempty"`
+	BodyTruncated bool   `json:"bodyTruncated,omitempty"`
+	BodyFallback  bool   `json:"bodyFallback,omitempty"`
+	// BodySkipped is set for declarations under node_modules, whose bodies
+	// are left out unless nodeModulesBodies is set.
+	BodySkipped bool `json:"bodySkipped,omitempty"`
+	// Via are the re-exports and declaration files passed on the way to
+	// this location, first to last, unless raw is set.
+	Via []definitionHop `json:"via,omitempty"`
+}
+
+const (
+	// defaultMaxBodyLines bounds each body of includeBody.
+	defaultMaxBodyLines = 40
+	// bodyWindowBefore and bodyWindowLines place the fallback body: it
+	// starts that many lines above the declaration and is that long.
+	bodyWindowBefore = 2
+	bodyWindowLines  = 12
+)
+
+// bodyOptions are the includeBody parameters of ts_definition.
+type bodyOptions struct {
+	maxLines    int
+	nodeModules bool
 }
 
-func makeDefinitionHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+func makeDefinitionHandler(client *lsp.Client, docs *docsync.Manager, packages *workspace.PackageResolver, symbolCache *symbolCache) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		file, err := request.RequireString("file")
 		if err != nil {
@@ -33,7 +83,21 @@ func makeDefinitionHandler(client *lsp.Client, docs *docsync.Manager) server.Too
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+		includeBody := request.GetBool("includeBody", false)
+		bodies := bodyOptions{
+			maxLines:    request.GetInt("maxBodyLines", defaultMaxBodyLines),
+			nodeModules: request.GetBool("nodeModulesBodies", false),
+		}
+		if bodies.maxLines < 1 {
+			return mcp.NewToolResultError("maxBodyLines must be at least 1"), nil
+		}
 
+		defer docs.Pin(file)()
 		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
 		}
@@ -43,28 +107,67 @@ func makeDefinitionHandler(client *lsp.Client, docs *docsync.Manager) server.Too
 			return mcp.NewToolResultError(fmt.Sprintf("definition error: %v", err)), nil
 		}
 
+		adjusted := 0
 		if len(locs) == 0 {
-			return mcp.NewToolResultText("No definition found"), nil
+			if typeCol, ok := jsdocRetryColumn(file, line, col); ok {
+				if retry, err := client.Definition(ctx, file, line, typeCol); err == nil && len(retry) > 0 {
+					locs, adjusted = retry, typeCol
+				}
+			}
 		}
 
-		entries := make([]definitionEntry, len(locs))
-		for i, loc := range locs {
-			defFile := docsync.URIToFile(string(loc.URI))
-			defLine := int(loc.Range.Start.Line) + 1
-			defCol := int(loc.Range.Start.Character) + 1
-
-			entry := definitionEntry{
-				File:   defFile,
-				Line:   defLine,
-				Column: defCol,
+		if len(locs) == 0 {
+			var ambient []definitionEntry
+			if text, err := readLine(file, line); err == nil {
+				if name := identifierAt(strings.TrimSuffix(text, "\r"), col); name != "" {
+					ambient = ambientDefinitions(client.RootDir(), name)
+				}
+			}
+			if len(ambient) == 0 {
+				return mcp.NewToolResultText("No definition found"), nil
+			}
+			for i, e := range ambient {
+				ambient[i].VisualColumn = cols.visualColumn(e.File, e.Line, e.Column)
 			}
+			if includeBody {
+				addDefinitionBodies(ctx, cachedSymbols{cache: symbolCache, src: client, docs: docs}, ambient, bodies)
+			}
+			data, err := json.MarshalIndent(ambient, "", "  ")
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(data)), nil
+		}
 
-			// Read the preview line from the target file
-			if preview, err := readLine(defFile, defLine); err == nil {
-				entry.Preview = strings.TrimSpace(preview)
+		var vias [][]definitionHop
+		if !request.GetBool("raw", false) {
+			var unpins []func()
+			defer func() {
+				for _, unpin := range unpins {
+					unpin()
+				}
+			}()
+			syncFile := func(f string) error {
+				unpins = append(unpins, docs.Pin(f))
+				return docs.SyncFile(ctx, client.Conn(), f)
 			}
+			locs, vias = followDefinitions(ctx, client, syncFile, locs)
+		}
 
-			entries[i] = entry
+		entries := definitionEntries(locs, packages, cols, adjusted)
+		for i := range vias {
+			entries[i].Via = vias[i]
+		}
+		if includeBody {
+			// Symbols need the declaring files open with the server; one
+			// that fails to sync falls back to a window of lines.
+			for _, e := range entries {
+				if e.File != file && (bodies.nodeModules || !inNodeModules(e.File)) {
+					defer docs.Pin(e.File)()
+					_ = docs.SyncFile(ctx, client.Conn(), e.File)
+				}
+			}
+			addDefinitionBodies(ctx, cachedSymbols{cache: symbolCache, src: client, docs: docs}, entries, bodies)
 		}
 
 		data, err := json.MarshalIndent(entries, "", "  ")
@@ -74,3 +177,91 @@ func makeDefinitionHandler(client *lsp.Client, docs *docsync.Manager) server.Too
 		return mcp.NewToolResultText(string(data)), nil
 	}
 }
+
+// definitionEntries converts the locations of declarations, reading a
+// preview of each. adjusted is the AdjustedColumn of every entry.
+func definitionEntries(locs []protocol.Location, packages *workspace.PackageResolver, cols columnMode, adjusted int) []definitionEntry {
+	entries := make([]definitionEntry, len(locs))
+	for i, loc := range locs {
+		defFile := docsync.URIToFile(string(loc.URI))
+		defLine := int(loc.Range.Start.Line) + 1
+		defCol := int(loc.Range.Start.Character) + 1
+
+		entry := definitionEntry{
+			File:           defFile,
+			Line:           defLine,
+			Column:         defCol,
+			AdjustedColumn: adjusted,
+			VisualColumn:   cols.visualColumn(defFile, defLine, defCol),
+		}
+		if pkg := packages.Resolve(defFile); pkg != nil {
+			entry.Package = pkg
+			entry.DisplayPath = pkg.DisplayPath(defFile)
+		}
+
+		// Read the preview from the target file
+		if preview, capped, err := signaturePreview(defFile, defLine); err == nil {
+			entry.Preview, entry.PreviewTruncated = preview, capped
+		}
+
+		entries[i] = entry
+	}
+	return entries
+}
+
+// inNodeModules reports whether file lies under node_modules.
+func inNodeModules(file string) bool {
+	return strings.Contains(filepath.ToSlash(file), "/node_modules/")
+}
+
+// addDefinitionBodies reads the body of every entry, skipping those under
+// node_modules unless opts.nodeModules is set.
+func addDefinitionBodies(ctx context.Context, symbols symbolSource, entries []definitionEntry, opts bodyOptions) {
+	for i := range entries {
+		e := &entries[i]
+		if !opts.nodeModules && inNodeModules(e.File) {
+			e.BodySkipped = true
+			continue
+		}
+		lines, err := cachedReadLines(e.File)
+		if err != nil || e.Line > len(lines) {
+			continue
+		}
+		pos := protocol.Position{Line: uint32(e.Line - 1), Character: uint32(e.Column - 1)}
+		first, last, ok := 0, 0, false
+		if syms, err := symbols.DocumentSymbol(ctx, e.File); err == nil {
+			first, last, ok = symbolBodyLines(syms, pos)
+		}
+		if !ok {
+			first = max(1, e.Line-bodyWindowBefore)
+			last = first + bodyWindowLines - 1
+			e.BodyFallback = true
+		}
+		e.Body, e.BodyStartLine, e.BodyEndLine, e.BodyTruncated = bodyText(lines, first, last, opts.maxLines)
+	}
+}
+
+// symbolBodyLines returns the first and last line, 1-based, of the
+// innermost document symbol whose range contains pos.
+func symbolBodyLines(symbols []protocol.DocumentSymbol, pos protocol.Position) (first, last int, ok bool) {
+	chain := symbolChainAt(symbols, pos)
+	if len(chain) == 0 {
+		return 0, 0, false
+	}
+	r := chain[len(chain)-1].Range
+	return int(r.Start.Line) + 1, int(r.End.Line) + 1, true
+}
+
+// bodyText joins lines first to last (1-based) of lines, clamped to the
+// file and to at most maxLines lines.
+func bodyText(lines []string, first, last, maxLines int) (body string, start, end int, truncated bool) {
+	last = min(last, len(lines))
+	if last-first+1 > maxLines {
+		last, truncated = first+maxLines-1, true
+	}
+	out := make([]string, 0, last-first+1)
+	for _, l := range lines[first-1 : last] {
+		out = append(out, strings.TrimSuffix(l, "\r"))
+	}
+	return strings.Join(out, "\n"), first, last, truncated
+}
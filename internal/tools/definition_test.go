@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// fakeSymbolSource answers DocumentSymbol from a map keyed by file.
+type fakeSymbolSource map[string][]protocol.DocumentSymbol
+
+func (f fakeSymbolSource) DocumentSymbol(_ context.Context, file string) ([]protocol.DocumentSymbol, error) {
+	syms, ok := f[file]
+	if !ok {
+		return nil, errors.New("no such document")
+	}
+	return syms, nil
+}
+
+func symbolRange(startLine, endLine uint32) protocol.Range {
+	return protocol.Range{Start: protocol.Position{Line: startLine}, End: protocol.Position{Line: endLine, Character: 1}}
+}
+
+func TestAddDefinitionBodies(t *testing.T) {
+	dir := t.TempDir()
+	src := "import { db } from \"./db\";\r\n" +
+		"\r\n" +
+		"export class Repo {\r\n" +
+		"  find(id: string) {\r\n" +
+		"    return db.get(id);\r\n" +
+		"  }\r\n" +
+		"}\r\n" +
+		"\r\n" +
+		"export function createRepo() {\r\n" +
+		"  return new Repo();\r\n" +
+		"}\r\n"
+	repo := filepath.Join(dir, "repo.ts")
+	if err := os.WriteFile(repo, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var long []string
+	for i := 1; i <= 30; i++ {
+		long = append(long, fmt.Sprintf("const v%d = %d;", i, i))
+	}
+	plain := filepath.Join(dir, "plain.ts")
+	if err := os.WriteFile(plain, []byte(strings.Join(long, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { forgetCachedLines(repo, plain) })
+
+	symbols := fakeSymbolSource{repo: {
+		{Name: "Repo", Range: symbolRange(2, 6), Children: []protocol.DocumentSymbol{
+			{Name: "find", Range: symbolRange(3, 5)},
+		}},
+		{Name: "createRepo", Range: symbolRange(8, 10)},
+	}}
+	entries := []definitionEntry{
+		{File: repo, Line: 9, Column: 17},
+		{File: repo, Line: 4, Column: 3},
+		{File: plain, Line: 20, Column: 7},
+		{File: "/p/node_modules/lib/index.d.ts", Line: 1, Column: 1},
+	}
+	addDefinitionBodies(context.Background(), symbols, entries, bodyOptions{maxLines: 40})
+
+	if e := entries[0]; e.Body != "export function createRepo() {\n  return new Repo();\n}" ||
+		e.BodyStartLine != 9 || e.BodyEndLine != 11 || e.BodyFallback || e.BodyTruncated {
+		t.Errorf("function body = %+v", e)
+	}
+	if e := entries[1]; e.BodyStartLine != 4 || e.BodyEndLine != 6 || !strings.HasPrefix(e.Body, "  find(") {
+		t.Errorf("method body = %+v", e)
+	}
+	if e := entries[2]; !e.BodyFallback || e.BodyStartLine != 18 || e.BodyEndLine != 18+bodyWindowLines-1 {
+		t.Errorf("fallback body = %+v", e)
+	}
+	if e := entries[3]; !e.BodySkipped || e.Body != "" {
+		t.Errorf("node_modules body = %+v", e)
+	}
+
+	capped := []definitionEntry{{File: repo, Line: 3, Column: 14}}
+	addDefinitionBodies(context.Background(), symbols, capped, bodyOptions{maxLines: 2})
+	if e := capped[0]; e.Body != "export class Repo {\n  find(id: string) {" || e.BodyEndLine != 4 || !e.BodyTruncated {
+		t.Errorf("capped body = %+v", e)
+	}
+}
+
+func TestBodyText(t *testing.T) {
+	lines := []string{"a", "b", "c"}
+	body, start, end, truncated := bodyText(lines, 2, 9, 40)
+	if body != "b\nc" || start != 2 || end != 3 || truncated {
+		t.Errorf("past the end = %q, %d-%d, %v", body, start, end, truncated)
+	}
+}
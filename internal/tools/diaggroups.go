@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// maxGroupExamples bounds the example locations of a diagnostic group.
+const maxGroupExamples = 3
+
+// diagnosticLocation is where a diagnostic is.
+type diagnosticLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// diagnosticCodeGroup is the diagnostics of one code.
+type diagnosticCodeGroup struct {
+	// Code is the code's label, such as "TS2345", or "none".
+	Code string `json:"code"`
+	// Severity is the most severe severity of the group's diagnostics.
+	Severity string `json:"severity"`
+	Count    int    `json:"count"`
+	// Message is the message of the first diagnostic.
+	Message  string               `json:"message"`
+	Examples []diagnosticLocation `json:"examples"`
+}
+
+type groupedDiagnosticsResult struct {
+	// Groups are ordered by count, then severity, then code.
+	Groups []diagnosticCodeGroup `json:"groups"`
+	// Truncated is set when there were more than maxResults groups.
+	Truncated   bool `json:"truncated"`
+	TotalCount  int  `json:"totalCount"`
+	FilteredOut int  `json:"filteredOut"`
+	Errors      int  `json:"errors"`
+	Warnings    int  `json:"warnings"`
+	// Other counts information and hint diagnostics.
+	Other int `json:"other"`
+	// Failed maps the files that could not be checked to the error.
+	Failed              map[string]string `json:"failed,omitempty"`
+	AnalysisIncomplete  bool              `json:"analysisIncomplete,omitempty"`
+	ProjectStillLoading bool              `json:"projectStillLoading,omitempty"`
+	Causes              []causeSummary    `json:"causes,omitempty"`
+}
+
+// codeLabel labels a diagnostic code: "TS" and its number, the code
+// itself when it is not a number, or "none".
+func codeLabel(code any) string {
+	if n, ok := diagnosticCode(code); ok {
+		return fmt.Sprintf("TS%d", n)
+	}
+	if s, ok := code.(string); ok && s != "" {
+		return s
+	}
+	return "none"
+}
+
+// severityRank orders severities, the most severe first.
+func severityRank(severity string) int {
+	return int(severityLevels[severity])
+}
+
+// groupDiagnostics groups entries by code, keeping at most maxGroups
+// groups, and counts them by severity.
+func groupDiagnostics(entries []diagnosticEntry, maxGroups int) groupedDiagnosticsResult {
+	result := groupedDiagnosticsResult{Groups: []diagnosticCodeGroup{}, TotalCount: len(entries)}
+	index := make(map[string]int)
+	for _, e := range entries {
+		switch e.Severity {
+		case "error":
+			result.Errors++
+		case "warning":
+			result.Warnings++
+		default:
+			result.Other++
+		}
+		label := codeLabel(e.Code)
+		i, ok := index[label]
+		if !ok {
+			i = len(result.Groups)
+			index[label] = i
+			result.Groups = append(result.Groups, diagnosticCodeGroup{Code: label, Severity: e.Severity, Message: e.Message, Examples: []diagnosticLocation{}})
+		}
+		g := &result.Groups[i]
+		g.Count++
+		if severityRank(e.Severity) < severityRank(g.Severity) {
+			g.Severity = e.Severity
+		}
+		if len(g.Examples) < maxGroupExamples {
+			g.Examples = append(g.Examples, diagnosticLocation{File: e.File, Line: e.Line, Column: e.Column})
+		}
+	}
+	sort.SliceStable(result.Groups, func(i, j int) bool {
+		a, b := result.Groups[i], result.Groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if ra, rb := severityRank(a.Severity), severityRank(b.Severity); ra != rb {
+			return ra < rb
+		}
+		// Shorter first, so TS codes sort by number.
+		if len(a.Code) != len(b.Code) {
+			return len(a.Code) < len(b.Code)
+		}
+		return a.Code < b.Code
+	})
+	if len(result.Groups) > maxGroups {
+		result.Groups, result.Truncated = result.Groups[:maxGroups], true
+	}
+	return result
+}
+
+func groupedDiagnosticsResponse(result groupedDiagnosticsResult) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodeLabel(t *testing.T) {
+	tests := []struct {
+		code any
+		want string
+	}{
+		{float64(2345), "TS2345"},
+		{"2551", "TS2551"},
+		{"no-unused", "no-unused"},
+		{nil, "none"},
+		{"", "none"},
+	}
+	for _, tt := range tests {
+		if got := codeLabel(tt.code); got != tt.want {
+			t.Errorf("codeLabel(%#v) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestGroupDiagnostics(t *testing.T) {
+	entry := func(file string, line int, severity string, code any, message string) diagnosticEntry {
+		return diagnosticEntry{File: file, Line: line, Column: 1, Severity: severity, Code: code, Message: message}
+	}
+	entries := []diagnosticEntry{
+		entry("/p/a.ts", 1, "hint", float64(6133), "'x' is declared but its value is never read."),
+		entry("/p/a.ts", 2, "error", float64(2345), "Argument of type 'string' is not assignable to parameter of type 'number'."),
+		entry("/p/a.ts", 3, "error", float64(2345), "Argument of type 'boolean' is not assignable to parameter of type 'number'."),
+		entry("/p/b.ts", 1, "warning", float64(18048), "'y' is possibly 'undefined'."),
+		entry("/p/b.ts", 4, "error", float64(2345), "Argument of type 'string' is not assignable to parameter of type 'number'."),
+		entry("/p/b.ts", 5, "error", float64(2345), "Argument of type 'string' is not assignable to parameter of type 'number'."),
+		entry("/p/b.ts", 6, "error", float64(18048), "'z' is possibly 'undefined'."),
+		entry("/p/c.ts", 2, "hint", float64(6133), "'w' is declared but its value is never read."),
+		entry("/p/c.ts", 3, "information", nil, "Consider a more specific type."),
+	}
+
+	got := groupDiagnostics(entries, 10)
+	if got.TotalCount != 9 || got.Errors != 5 || got.Warnings != 1 || got.Other != 3 || got.Truncated {
+		t.Errorf("counts = %d total, %d errors, %d warnings, %d other, truncated %v",
+			got.TotalCount, got.Errors, got.Warnings, got.Other, got.Truncated)
+	}
+	// TS2345 has the most; TS18048 and TS6133 tie, and the error comes
+	// first although its first diagnostic is a warning.
+	want := []diagnosticCodeGroup{
+		{Code: "TS2345", Severity: "error", Count: 4, Message: entries[1].Message, Examples: []diagnosticLocation{
+			{File: "/p/a.ts", Line: 2, Column: 1}, {File: "/p/a.ts", Line: 3, Column: 1}, {File: "/p/b.ts", Line: 4, Column: 1},
+		}},
+		{Code: "TS18048", Severity: "error", Count: 2, Message: entries[3].Message, Examples: []diagnosticLocation{
+			{File: "/p/b.ts", Line: 1, Column: 1}, {File: "/p/b.ts", Line: 6, Column: 1},
+		}},
+		{Code: "TS6133", Severity: "hint", Count: 2, Message: entries[0].Message, Examples: []diagnosticLocation{
+			{File: "/p/a.ts", Line: 1, Column: 1}, {File: "/p/c.ts", Line: 2, Column: 1},
+		}},
+		{Code: "none", Severity: "information", Count: 1, Message: entries[8].Message, Examples: []diagnosticLocation{
+			{File: "/p/c.ts", Line: 3, Column: 1},
+		}},
+	}
+	if !reflect.DeepEqual(got.Groups, want) {
+		t.Errorf("groups =\n%+v\nwant\n%+v", got.Groups, want)
+	}
+
+	top := groupDiagnostics(entries, 2)
+	if len(top.Groups) != 2 || !top.Truncated || top.Groups[1].Code != "TS18048" || top.TotalCount != 9 {
+		t.Errorf("maxGroups 2 = %+v", top)
+	}
+
+	if empty := groupDiagnostics(nil, 10); empty.Groups == nil || len(empty.Groups) != 0 {
+		t.Errorf("no diagnostics = %+v", empty)
+	}
+}
@@ -4,6 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -11,6 +17,7 @@ import (
 
 	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
 	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
 )
 
 type diagnosticEntry struct {
@@ -20,70 +27,551 @@ type diagnosticEntry struct {
 	Severity string `json:"severity"`
 	Code     any    `json:"code,omitempty"`
 	Message  string `json:"message"`
+	// Related are the locations tsgo relates the diagnostic to, such as
+	// the declaration an expected type comes from.
+	Related []diagnosticRelated `json:"related,omitempty"`
+	// Context is set with contextLines: the lines around Line, numbered,
+	// with Line marked by '>'.
+	Context string `json:"context,omitempty"`
+	// Cause is set with classifyCauses for a missing module that the
+	// environment, not the code, has to provide.
+	Cause *diagnosticCause `json:"cause,omitempty"`
+}
+
+// diagnosticRelated is a related location of a diagnostic.
+type diagnosticRelated struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
 }
 
 type diagnosticsResult struct {
+	Diagnostics []diagnosticEntry `json:"diagnostics"`
+	// TotalCount counts the diagnostics the severity and codes filters
+	// kept; FilteredOut those they dropped.
+	TotalCount  int `json:"totalCount"`
+	FilteredOut int `json:"filteredOut"`
+	// Truncated is set when more pages follow; pass NextCursor to get the
+	// next one.
+	Truncated  bool   `json:"truncated"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	// Source is "pull" when tsgo answered the diagnostics request, or
+	// "push" when the diagnostics are those it published for the file.
+	Source string `json:"source"`
+	// AnalysisIncomplete is set when tsgo published no diagnostics of the
+	// synced file in time: the file may have errors not reported.
+	AnalysisIncomplete bool `json:"analysisIncomplete,omitempty"`
+	// InProgram is a best-effort guess at whether tsgo has the file in its
+	// loaded program; clean diagnostics for a file outside it mean nothing.
+	InProgram bool `json:"inProgram"`
+	// Project is the project tsgo reported as owning the file in its logs,
+	// when it did.
+	Project string `json:"project,omitempty"`
+	// ProjectStillLoading is set when waitForProjectLoad gave up before
+	// tsgo finished loading: cross-file errors may be missing.
+	ProjectStillLoading bool `json:"projectStillLoading,omitempty"`
+	// Causes counts the diagnostics of each cause with classifyCauses.
+	Causes []causeSummary `json:"causes,omitempty"`
+}
+
+// maxDiagnosticContextLines bounds the contextLines of ts_diagnostics.
+const maxDiagnosticContextLines = 10
+
+// maxDiagnosticsFiles bounds the files of one multi-file ts_diagnostics
+// call; ts_project_diagnostics checks more.
+const maxDiagnosticsFiles = 50
+
+// diagnosticsFileReport is one file of a multi-file ts_diagnostics result.
+type diagnosticsFileReport struct {
 	Diagnostics []diagnosticEntry `json:"diagnostics"`
 	TotalCount  int               `json:"totalCount"`
-	Truncated   bool              `json:"truncated"`
+	FilteredOut int               `json:"filteredOut"`
+	// Truncated is set when the file had more than maxResults
+	// diagnostics; ts_diagnostics with file pages through all of them.
+	Truncated          bool   `json:"truncated"`
+	Source             string `json:"source,omitempty"`
+	AnalysisIncomplete bool   `json:"analysisIncomplete,omitempty"`
+	InProgram          bool   `json:"inProgram"`
+	Project            string `json:"project,omitempty"`
+	// Error is set when the file could not be checked.
+	Error string `json:"error,omitempty"`
+}
+
+type multiDiagnosticsResult struct {
+	Files map[string]diagnosticsFileReport `json:"files"`
+	// TotalCount, Errors, Warnings and Other count the diagnostics of all
+	// files, including those truncation left out but not those the
+	// filters dropped, which FilteredOut counts.
+	TotalCount  int `json:"totalCount"`
+	FilteredOut int `json:"filteredOut"`
+	Errors      int `json:"errors"`
+	Warnings    int `json:"warnings"`
+	// Other counts information and hint diagnostics.
+	Other               int            `json:"other"`
+	ProjectStillLoading bool           `json:"projectStillLoading,omitempty"`
+	Causes              []causeSummary `json:"causes,omitempty"`
+}
+
+// diagnosticsInfo is what the first page of a result records for the
+// later ones.
+type diagnosticsInfo struct {
+	source       string
+	incomplete   bool
+	inProgram    bool
+	project      string
+	stillLoading bool
+	causes       []causeSummary
+	filteredOut  int
+}
+
+// diagnosticFilter is the severity and codes filters of ts_diagnostics.
+type diagnosticFilter struct {
+	// minimum is the least severe severity kept.
+	minimum protocol.DiagnosticSeverity
+	// codes, when set, are the only codes kept.
+	codes []int
+}
+
+// requestDiagnosticFilter reads the filters of request; by default they
+// keep every diagnostic.
+func requestDiagnosticFilter(request mcp.CallToolRequest) (diagnosticFilter, error) {
+	severity := request.GetString("severity", "hint")
+	minimum, ok := severityLevels[severity]
+	if !ok {
+		return diagnosticFilter{}, fmt.Errorf("invalid severity %q (valid: error, warning, information, hint)", severity)
+	}
+	return diagnosticFilter{minimum: minimum, codes: request.GetIntSlice("codes", nil)}, nil
+}
+
+// keep reports whether the filter keeps e.
+func (f diagnosticFilter) keep(e diagnosticEntry) bool {
+	if severityLevels[e.Severity] > f.minimum {
+		return false
+	}
+	if len(f.codes) == 0 {
+		return true
+	}
+	code, ok := diagnosticCode(e.Code)
+	return ok && slices.Contains(f.codes, code)
+}
+
+// apply returns the entries the filter keeps, in order, and the number it
+// dropped.
+func (f diagnosticFilter) apply(entries []diagnosticEntry) ([]diagnosticEntry, int) {
+	kept := entries[:0:0]
+	for _, e := range entries {
+		if f.keep(e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept, len(entries) - len(kept)
+}
+
+// programBackend is the subset of *lsp.Client used to guess program
+// membership.
+type programBackend interface {
+	Hover(ctx context.Context, file string, line, col int) (*protocol.Hover, error)
+}
+
+// severityName names a diagnostic severity; a missing one is an error.
+func severityName(sev protocol.DiagnosticSeverity) string {
+	switch sev {
+	case protocol.DiagnosticSeverityWarning:
+		return "warning"
+	case protocol.DiagnosticSeverityInformation:
+		return "information"
+	case protocol.DiagnosticSeverityHint:
+		return "hint"
+	}
+	return "error"
+}
+
+// newDiagnosticEntry converts d, a diagnostic of file.
+func newDiagnosticEntry(file string, d protocol.Diagnostic) diagnosticEntry {
+	e := diagnosticEntry{
+		File:     file,
+		Line:     int(d.Range.Start.Line) + 1,
+		Column:   int(d.Range.Start.Character) + 1,
+		Severity: severityName(d.Severity),
+		Code:     d.Code,
+		Message:  d.Message,
+	}
+	for _, r := range d.RelatedInformation {
+		e.Related = append(e.Related, diagnosticRelated{
+			File:    docsync.URIToFile(string(r.Location.URI)),
+			Line:    int(r.Location.Range.Start.Line) + 1,
+			Column:  int(r.Location.Range.Start.Character) + 1,
+			Message: r.Message,
+		})
+	}
+	return e
+}
+
+// diagnosticContext renders the n lines before and after the 1-based
+// line of lines, each numbered, with line marked by '>'.
+func diagnosticContext(lines []string, line, n int) string {
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	first, last := max(line-n, 1), min(line+n, len(lines))
+	width := len(fmt.Sprint(last))
+	var b strings.Builder
+	for i := first; i <= last; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, i, lines[i-1])
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// addDiagnosticContext sets the Context of entries to n lines around
+// each. Files that cannot be read get none.
+func addDiagnosticContext(entries []diagnosticEntry, n int) {
+	if n == 0 {
+		return
+	}
+	for i := range entries {
+		lines, err := cachedReadLines(entries[i].File)
+		if err != nil {
+			continue
+		}
+		entries[i].Context = diagnosticContext(lines, entries[i].Line, n)
+	}
+}
+
+// importSpecifierPattern finds the module specifier of the first static
+// import, re-export, or require call on a line.
+var importSpecifierPattern = regexp.MustCompile(`^\s*(?:import\s+(?:type\s+)?(?:[^'"]*?\s+from\s+)?|export\s+[^'"]*?\s+from\s+|.*\brequire\(\s*)(['"])`)
+
+// firstImportPosition returns the 1-based position of the first character
+// inside the first import's module specifier.
+func firstImportPosition(lines []string) (line, col int, ok bool) {
+	for i, l := range lines {
+		if loc := importSpecifierPattern.FindStringSubmatchIndex(l); loc != nil {
+			// loc[3] is the end of the opening quote.
+			return i + 1, loc[3] + 1, true
+		}
+	}
+	return 0, 0, false
+}
+
+// inProgram guesses whether file is part of tsgo's program. A failed pull
+// request (push fallback) means tsgo would not compute diagnostics for the
+// file on demand. When the pull succeeded, the first import is hovered: a
+// file outside any program cannot resolve its imports, so hover comes back
+// empty. Files without imports are given the benefit of the doubt.
+func inProgram(ctx context.Context, backend programBackend, file string, pulled bool) bool {
+	if !pulled {
+		return false
+	}
+	// Read fresh rather than through the line cache: the agent edits files
+	// between diagnostics calls.
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	line, col, ok := firstImportPosition(strings.Split(string(content), "\n"))
+	if !ok {
+		return true
+	}
+	hover, err := backend.Hover(ctx, file, line, col)
+	if err != nil || hover == nil {
+		return false
+	}
+	return strings.TrimSpace(hover.Contents.Value) != ""
+}
+
+// sortDiagnosticEntries sorts the entries of one file by position.
+func sortDiagnosticEntries(entries []diagnosticEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].Column < entries[j].Column
+	})
+}
+
+// fileDiagnosticEntries returns the diagnostics of file, which must be
+// synced, sorted by position, and the report of DiagnosticReport they
+// come from.
+func fileDiagnosticEntries(ctx context.Context, client *lsp.Client, docs *docsync.Manager, file string) ([]diagnosticEntry, lsp.FileDiagnostics, error) {
+	version, _ := docs.Version(file)
+	report, err := client.DiagnosticReport(ctx, file, version)
+	if err != nil {
+		return nil, report, fmt.Errorf("diagnostic error: %v", err)
+	}
+	entries := make([]diagnosticEntry, len(report.Items))
+	for i, d := range report.Items {
+		entries[i] = newDiagnosticEntry(file, d)
+	}
+	sortDiagnosticEntries(entries)
+	return entries, report, nil
+}
+
+// requestCauseClassifier returns the classifier for classifyCauses, with
+// the path aliases of the request's tsconfig.
+func requestCauseClassifier(request mcp.CallToolRequest, client *lsp.Client, generatedPaths []string) *causeClassifier {
+	configPath := request.GetString("tsconfig", "")
+	if configPath == "" {
+		configPath = filepath.Join(client.RootDir(), "tsconfig.json")
+	}
+	// Without a readable config, aliases look like packages.
+	var paths *tsconfig.PathMapper
+	if cfg, err := tsconfig.Load(configPath); err == nil {
+		paths, _ = cfg.PathMapper()
+	}
+	return newCauseClassifier(client.RootDir(), generatedPaths, paths)
 }
 
-func makeDiagnosticsHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+func makeDiagnosticsHandler(client *lsp.Client, docs *docsync.Manager, cursors *cursorStore[diagnosticEntry], generatedPaths []string) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		maxResults := request.GetInt("maxResults", 50)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+		if cursor := request.GetString("cursor", ""); cursor != "" {
+			id, offset, err := parseCursor(cursor)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			all, info, err := cursors.Get("ts_diagnostics", id)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return diagnosticsPage(cursors, all, nil, info.(diagnosticsInfo), id, offset, maxResults)
+		}
+
+		filter, err := requestDiagnosticFilter(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		contextLines := request.GetInt("contextLines", 0)
+		if contextLines < 0 || contextLines > maxDiagnosticContextLines {
+			return mcp.NewToolResultError(fmt.Sprintf("contextLines must be between 0 and %d", maxDiagnosticContextLines)), nil
+		}
+		groupBy := request.GetString("groupBy", "")
+		if groupBy != "" && groupBy != "code" {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid groupBy %q (valid: code)", groupBy)), nil
+		}
 		file := request.GetString("file", "")
-		if file == "" {
+		files := request.GetStringSlice("files", nil)
+		switch {
+		case file != "" && len(files) > 0:
+			return mcp.NewToolResultError("pass either file or files, not both"), nil
+		case len(files) > 0:
+			return multiFileDiagnostics(ctx, client, docs, request, files, filter, contextLines, maxResults, groupBy != "", generatedPaths)
+		case file == "":
 			return mcp.NewToolResultError("file parameter is required"), nil
 		}
 
-		maxResults := request.GetInt("maxResults", 50)
+		defer docs.Pin(file)()
 
 		// Sync file before requesting diagnostics
 		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
 		}
+		stillLoading := false
+		if request.GetBool("waitForProjectLoad", false) {
+			stillLoading = client.WaitForProjectLoad(ctx)
+		}
 
-		diags, err := client.Diagnostic(ctx, file)
+		entries, report, err := fileDiagnosticEntries(ctx, client, docs, file)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("diagnostic error: %v", err)), nil
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		entries, filteredOut := filter.apply(entries)
+		forgetCachedLines(file)
+		addDiagnosticContext(entries, contextLines)
+		var causes []causeSummary
+		if request.GetBool("classifyCauses", false) {
+			causes = classifyCauses(requestCauseClassifier(request, client, generatedPaths), entries)
+		}
+		project, previous := client.ObserveProject(file)
+		var result *mcp.CallToolResult
+		if groupBy != "" {
+			grouped := groupDiagnostics(entries, maxResults)
+			grouped.FilteredOut = filteredOut
+			grouped.AnalysisIncomplete = report.Incomplete
+			grouped.ProjectStillLoading = stillLoading
+			grouped.Causes = causes
+			result, err = groupedDiagnosticsResponse(grouped)
+		} else {
+			result, err = diagnosticsPage(cursors, entries, fileVersions(docs, []string{file}), diagnosticsInfo{
+				source:       report.Source,
+				incomplete:   report.Incomplete,
+				inProgram:    inProgram(ctx, client, file, report.Source == lsp.DiagnosticSourcePull),
+				project:      project,
+				stillLoading: stillLoading,
+				causes:       causes,
+				filteredOut:  filteredOut,
+			}, "", 0, maxResults)
+		}
+		if err == nil && previous != "" && !result.IsError {
+			result.Content = append([]mcp.Content{mcp.NewTextContent(projectChangedWarning(file, project, previous))}, result.Content...)
+		}
+		return result, err
+	}
+}
+
+// multiFileDiagnostics is ts_diagnostics for several files: each file's
+// diagnostics are truncated to maxResults, and the counts cover them all.
+// A file that fails to sync or check gets an error instead. With group,
+// the diagnostics of all files are grouped by code instead.
+func multiFileDiagnostics(ctx context.Context, client *lsp.Client, docs *docsync.Manager, request mcp.CallToolRequest, files []string, filter diagnosticFilter, contextLines, maxResults int, group bool, generatedPaths []string) (*mcp.CallToolResult, error) {
+	var unique []string
+	for _, f := range files {
+		if f == "" {
+			return mcp.NewToolResultError("files must not contain empty paths"), nil
+		}
+		if !slices.Contains(unique, f) {
+			unique = append(unique, f)
 		}
+	}
+	if len(unique) > maxDiagnosticsFiles {
+		return mcp.NewToolResultError(fmt.Sprintf("%d files; at most %d per call, or use ts_project_diagnostics", len(unique), maxDiagnosticsFiles)), nil
+	}
 
-		totalCount := len(diags)
-		truncated := totalCount > maxResults
-		if truncated {
-			diags = diags[:maxResults]
+	reports := make(map[string]diagnosticsFileReport, len(unique))
+	var synced []string
+	for _, f := range unique {
+		defer docs.Pin(f)()
+		if err := docs.SyncFile(ctx, client.Conn(), f); err != nil {
+			reports[f] = diagnosticsFileReport{Diagnostics: []diagnosticEntry{}, Error: fmt.Sprintf("sync error: %v", err)}
+			continue
 		}
+		synced = append(synced, f)
+	}
+	result := multiDiagnosticsResult{Files: reports}
+	if request.GetBool("waitForProjectLoad", false) {
+		result.ProjectStillLoading = client.WaitForProjectLoad(ctx)
+	}
 
-		entries := make([]diagnosticEntry, len(diags))
-		for i, d := range diags {
-			sev := "error"
-			switch d.Severity {
-			case protocol.DiagnosticSeverityWarning:
-				sev = "warning"
-			case protocol.DiagnosticSeverityInformation:
-				sev = "information"
-			case protocol.DiagnosticSeverityHint:
-				sev = "hint"
-			}
-			entries[i] = diagnosticEntry{
-				File:     file,
-				Line:     int(d.Range.Start.Line) + 1,
-				Column:   int(d.Range.Start.Character) + 1,
-				Severity: sev,
-				Code:     d.Code,
-				Message:  d.Message,
-			}
+	// The entries of all files share one slice, so classifyCauses sets
+	// the causes seen through each file's part of it.
+	var all []diagnosticEntry
+	parts := make(map[string][2]int, len(synced))
+	reported := make(map[string]lsp.FileDiagnostics, len(synced))
+	filteredOut := make(map[string]int, len(synced))
+	for _, f := range synced {
+		entries, fr, err := fileDiagnosticEntries(ctx, client, docs, f)
+		if err != nil {
+			reports[f] = diagnosticsFileReport{Diagnostics: []diagnosticEntry{}, Error: err.Error()}
+			continue
 		}
+		entries, filteredOut[f] = filter.apply(entries)
+		parts[f] = [2]int{len(all), len(all) + len(entries)}
+		reported[f] = fr
+		all = append(all, entries...)
+	}
+	forgetCachedLines(synced...)
+	addDiagnosticContext(all, contextLines)
+	if request.GetBool("classifyCauses", false) {
+		result.Causes = classifyCauses(requestCauseClassifier(request, client, generatedPaths), all)
+	}
 
-		result := diagnosticsResult{
-			Diagnostics: entries,
-			TotalCount:  totalCount,
-			Truncated:   truncated,
+	var warnings []mcp.Content
+	for _, f := range synced {
+		part, ok := parts[f]
+		if !ok {
+			continue
+		}
+		entries := all[part[0]:part[1]]
+		fr := reported[f]
+		report := diagnosticsFileReport{
+			Diagnostics:        entries,
+			TotalCount:         len(entries),
+			FilteredOut:        filteredOut[f],
+			Source:             fr.Source,
+			AnalysisIncomplete: fr.Incomplete,
+			InProgram:          inProgram(ctx, client, f, fr.Source == lsp.DiagnosticSourcePull),
+		}
+		if len(entries) > maxResults {
+			report.Diagnostics, report.Truncated = entries[:maxResults], true
+		}
+		if report.Diagnostics == nil {
+			report.Diagnostics = []diagnosticEntry{}
 		}
+		for _, e := range entries {
+			switch e.Severity {
+			case "error":
+				result.Errors++
+			case "warning":
+				result.Warnings++
+			default:
+				result.Other++
+			}
+		}
+		result.TotalCount += len(entries)
+		result.FilteredOut += filteredOut[f]
+		var previous string
+		report.Project, previous = client.ObserveProject(f)
+		if previous != "" {
+			warnings = append(warnings, mcp.NewTextContent(projectChangedWarning(f, report.Project, previous)))
+		}
+		reports[f] = report
+	}
 
+	var res *mcp.CallToolResult
+	if group {
+		grouped := groupDiagnostics(all, maxResults)
+		grouped.FilteredOut = result.FilteredOut
+		grouped.ProjectStillLoading = result.ProjectStillLoading
+		grouped.Causes = result.Causes
+		for f, report := range reports {
+			if report.Error != "" {
+				if grouped.Failed == nil {
+					grouped.Failed = make(map[string]string)
+				}
+				grouped.Failed[f] = report.Error
+			}
+			grouped.AnalysisIncomplete = grouped.AnalysisIncomplete || report.AnalysisIncomplete
+		}
+		res, _ = groupedDiagnosticsResponse(grouped)
+	} else {
 		data, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
 		}
-		return mcp.NewToolResultText(string(data)), nil
+		res = mcp.NewToolResultText(string(data))
+	}
+	res.Content = append(warnings, res.Content...)
+	return res, nil
+}
+
+// projectChangedWarning tells the agent that tsgo moved file to another
+// project since the last response about it, so its answers may differ.
+func projectChangedWarning(file, project, previous string) string {
+	return fmt.Sprintf("warning: projectChanged: tsgo now serves %s from %s instead of %s; results may differ from earlier calls", file, project, previous)
+}
+
+// diagnosticsPage renders one page of diagnostics; see paginate.
+func diagnosticsPage(cursors *cursorStore[diagnosticEntry], all []diagnosticEntry, versions map[string]int32, info diagnosticsInfo, id string, offset, size int) (*mcp.CallToolResult, error) {
+	pg, err := paginate(cursors, "ts_diagnostics", all, versions, info, id, offset, size)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	result := diagnosticsResult{
+		Diagnostics:         pg.Items,
+		TotalCount:          len(all),
+		FilteredOut:         info.filteredOut,
+		Truncated:           pg.NextCursor != "",
+		Offset:              pg.Offset,
+		NextCursor:          pg.NextCursor,
+		Source:              info.source,
+		AnalysisIncomplete:  info.incomplete,
+		InProgram:           info.inProgram,
+		Project:             info.project,
+		ProjectStillLoading: info.stillLoading,
+		Causes:              info.causes,
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
 	}
+	return mcp.NewToolResultText(string(data)), nil
 }
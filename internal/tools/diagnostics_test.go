@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestFirstImportPosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		wantLine int
+		wantCol  int
+		wantOK   bool
+	}{
+		{"named import", []string{"// header", `import { a } from "./a";`}, 2, 20, true},
+		{"side-effect import", []string{`import './polyfill';`}, 1, 9, true},
+		{"type import", []string{`import type { T } from '@app/types';`}, 1, 25, true},
+		{"re-export", []string{`export * from "./b";`}, 1, 16, true},
+		{"require", []string{`const fs = require('fs');`}, 1, 21, true},
+		{"no imports", []string{"export const x = 1;", "console.log(x);"}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col, ok := firstImportPosition(tt.lines)
+			if line != tt.wantLine || col != tt.wantCol || ok != tt.wantOK {
+				t.Errorf("firstImportPosition = (%d, %d, %v), want (%d, %d, %v)", line, col, ok, tt.wantLine, tt.wantCol, tt.wantOK)
+			}
+		})
+	}
+}
+
+// fakeProgramBackend answers hover with a fixed result.
+type fakeProgramBackend struct {
+	hover      *protocol.Hover
+	err        error
+	line, col  int
+	hoverCalls int
+}
+
+func (f *fakeProgramBackend) Hover(_ context.Context, _ string, line, col int) (*protocol.Hover, error) {
+	f.hoverCalls++
+	f.line, f.col = line, col
+	return f.hover, f.err
+}
+
+func TestDiagnosticFilter(t *testing.T) {
+	entries := []diagnosticEntry{
+		{Line: 1, Severity: "error", Code: float64(2322)},
+		{Line: 2, Severity: "hint", Code: float64(6133)},
+		{Line: 3, Severity: "warning", Code: "2345"},
+		{Line: 4, Severity: "information", Code: float64(80001)},
+		{Line: 5, Severity: "error", Code: float64(2345)},
+	}
+	tests := []struct {
+		name      string
+		filter    diagnosticFilter
+		wantLines []int
+	}{
+		{"everything", diagnosticFilter{minimum: protocol.DiagnosticSeverityHint}, []int{1, 2, 3, 4, 5}},
+		{"errors", diagnosticFilter{minimum: protocol.DiagnosticSeverityError}, []int{1, 5}},
+		{"warnings and worse", diagnosticFilter{minimum: protocol.DiagnosticSeverityWarning}, []int{1, 3, 5}},
+		{"codes", diagnosticFilter{minimum: protocol.DiagnosticSeverityHint, codes: []int{2345, 6133}}, []int{2, 3, 5}},
+		{"codes and severity", diagnosticFilter{minimum: protocol.DiagnosticSeverityError, codes: []int{2345}}, []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := tt.filter.apply(entries)
+			var lines []int
+			for _, e := range kept {
+				lines = append(lines, e.Line)
+			}
+			if !slices.Equal(lines, tt.wantLines) || dropped != len(entries)-len(tt.wantLines) {
+				t.Errorf("apply = lines %v, %d dropped; want lines %v", lines, dropped, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestNewDiagnosticEntryRelated(t *testing.T) {
+	d := protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: 4, Character: 2}},
+		Severity: protocol.DiagnosticSeverityError,
+		Code:     float64(2322),
+		Message:  "Type 'number' is not assignable to type 'string'.",
+		RelatedInformation: []protocol.DiagnosticRelatedInformation{{
+			Location: protocol.Location{
+				URI:   "file:///p/src/types.ts",
+				Range: protocol.Range{Start: protocol.Position{Line: 9, Character: 4}},
+			},
+			Message: "The expected type comes from property 'name' which is declared here on type 'User'",
+		}},
+	}
+	e := newDiagnosticEntry("/p/src/a.ts", d)
+	if e.Line != 5 || e.Column != 3 || e.Severity != "error" {
+		t.Errorf("entry = %+v", e)
+	}
+	want := []diagnosticRelated{{File: "/p/src/types.ts", Line: 10, Column: 5, Message: d.RelatedInformation[0].Message}}
+	if !slices.Equal(e.Related, want) {
+		t.Errorf("related = %+v, want %+v", e.Related, want)
+	}
+}
+
+func TestDiagnosticContext(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	tests := []struct {
+		name    string
+		line, n int
+		want    string
+	}{
+		{"middle", 5, 1, "  4 | line 4\n> 5 | line 5\n  6 | line 6"},
+		{"first line", 1, 2, "> 1 | line 1\n  2 | line 2\n  3 | line 3"},
+		{"widths", 11, 2, "   9 | line 9\n  10 | line 10\n> 11 | line 11\n  12 | line 12"},
+		{"out of range", 13, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diagnosticContext(lines, tt.line, tt.n); got != tt.want {
+				t.Errorf("diagnosticContext = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInProgram(t *testing.T) {
+	dir := t.TempDir()
+	member := filepath.Join(dir, "src", "index.ts")
+	orphan := filepath.Join(dir, "scripts", "seed.ts")
+	for path, content := range map[string]string{
+		member: "import { db } from './db';\ndb.connect();\n",
+		orphan: "console.log('seeding');\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolved := &protocol.Hover{Contents: protocol.MarkupContent{Value: `module "./db"`}}
+
+	tests := []struct {
+		name      string
+		file      string
+		pulled    bool
+		backend   *fakeProgramBackend
+		want      bool
+		wantHover bool
+	}{
+		{"pulled and import resolves", member, true, &fakeProgramBackend{hover: resolved}, true, true},
+		{"pulled but import unresolved", member, true, &fakeProgramBackend{}, false, true},
+		{"pulled but hover empty", member, true, &fakeProgramBackend{hover: &protocol.Hover{}}, false, true},
+		{"pulled but hover fails", member, true, &fakeProgramBackend{err: errors.New("no project")}, false, true},
+		{"push fallback", member, false, &fakeProgramBackend{hover: resolved}, false, false},
+		{"orphaned script without imports", orphan, true, &fakeProgramBackend{}, true, false},
+		{"orphaned script push fallback", orphan, false, &fakeProgramBackend{}, false, false},
+		{"missing file", filepath.Join(dir, "gone.ts"), true, &fakeProgramBackend{hover: resolved}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inProgram(context.Background(), tt.backend, tt.file, tt.pulled)
+			if got != tt.want {
+				t.Errorf("inProgram = %v, want %v", got, tt.want)
+			}
+			if (tt.backend.hoverCalls > 0) != tt.wantHover {
+				t.Errorf("hover calls = %d, wantHover %v", tt.backend.hoverCalls, tt.wantHover)
+			}
+			if tt.wantHover && (tt.backend.line != 1 || tt.backend.col != 21) {
+				t.Errorf("hovered at %d:%d, want 1:21", tt.backend.line, tt.backend.col)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"go.lsp.dev/protocol"
+)
+
+// defaultEditTokenTTL is how long a previewed edit stays applicable.
+const defaultEditTokenTTL = 5 * time.Minute
+
+// pendingEdit is a computed WorkspaceEdit waiting for confirmation.
+type pendingEdit struct {
+	tool    string
+	symbol  string
+	edit    *lsp.WorkspaceEdit
+	hashes  map[string]string // file path -> content hash at preview time
+	created time.Time
+}
+
+// editTokenStore holds previewed WorkspaceEdits keyed by an opaque token so
+// they can be applied later without recomputing them. A token is only
+// honored while every affected file still has the content it was previewed
+// against.
+type editTokenStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	pending map[string]*pendingEdit
+}
+
+// newEditTokenStore creates a token store. A non-positive ttl selects
+// defaultEditTokenTTL.
+func newEditTokenStore(ttl time.Duration) *editTokenStore {
+	if ttl <= 0 {
+		ttl = defaultEditTokenTTL
+	}
+	return &editTokenStore{
+		ttl:     ttl,
+		now:     time.Now,
+		pending: make(map[string]*pendingEdit),
+	}
+}
+
+// editTokenTTLFromEnv reads TYPESCRIPT_MCP_EDIT_TOKEN_TTL as a Go duration.
+// It returns zero (the default) when the variable is unset or invalid.
+func editTokenTTLFromEnv() time.Duration {
+	v := os.Getenv("TYPESCRIPT_MCP_EDIT_TOKEN_TTL")
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// Put stores edit, made by tool for symbol, and returns its token. hashes
+// maps every affected file to the content hash the preview was computed
+// from.
+func (s *editTokenStore) Put(tool, symbol string, edit *lsp.WorkspaceEdit, hashes map[string]string) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating edit token: %w", err)
+	}
+	token := hex.EncodeToString(b[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	s.pending[token] = &pendingEdit{
+		tool:    tool,
+		symbol:  symbol,
+		edit:    edit,
+		hashes:  hashes,
+		created: s.now(),
+	}
+	return token, nil
+}
+
+// Take removes and returns the pending edit for token. It fails if the
+// token is unknown, expired, or was invalidated by another write.
+func (s *editTokenStore) Take(token string) (*pendingEdit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	p, ok := s.pending[token]
+	if !ok {
+		return nil, fmt.Errorf("edit token %q is unknown, expired, or was invalidated by another write", token)
+	}
+	delete(s.pending, token)
+	return p, nil
+}
+
+// InvalidateFiles drops every pending edit that touches any of paths.
+// Call it after writing files so stale previews cannot be applied.
+func (s *editTokenStore) InvalidateFiles(paths []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, p := range s.pending {
+		for _, path := range paths {
+			if _, ok := p.hashes[path]; ok {
+				delete(s.pending, token)
+				break
+			}
+		}
+	}
+}
+
+// Len returns the number of live tokens.
+func (s *editTokenStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	return len(s.pending)
+}
+
+func (s *editTokenStore) pruneLocked() {
+	cutoff := s.now().Add(-s.ttl)
+	for token, p := range s.pending {
+		if p.created.Before(cutoff) {
+			delete(s.pending, token)
+		}
+	}
+}
+
+// driftedFiles returns the sorted paths whose current content no longer
+// matches the hash recorded at preview time (including deleted files). A
+// file the edit creates is recorded without a hash and drifts by
+// appearing.
+func (p *pendingEdit) driftedFiles() []string {
+	var drifted []string
+	for path, want := range p.hashes {
+		content, err := os.ReadFile(path)
+		if want == "" {
+			if !errors.Is(err, os.ErrNotExist) {
+				drifted = append(drifted, path)
+			}
+			continue
+		}
+		if err != nil || hashContent(content) != want {
+			drifted = append(drifted, path)
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
+
+// hashContent returns a hex SHA-256 digest of content.
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// editPreview describes the would-be change to one file.
+type editPreview struct {
+	File  string `json:"file"`
+	Edits int    `json:"edits"`
+	Diff  string `json:"diff"`
+	// DiffTruncated is set when Diff was cut at maxDiffLines.
+	DiffTruncated bool `json:"diffTruncated,omitempty"`
+	// Created is set when the edit creates the file, Deleted when it
+	// removes it, and RenamedFrom is the path it moves the file from.
+	Created     bool   `json:"created,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+	RenamedFrom string `json:"renamedFrom,omitempty"`
+}
+
+// maxDiffLines bounds the diff of one file in a preview; edit.TruncateDiff
+// replaces the rest by a note.
+const maxDiffLines = 400
+
+// previewWorkspaceEdit computes the result of we without writing it. It
+// returns per-file previews in sorted path order, each diff truncated at
+// maxDiffLines, and the content hash of every affected file, "" for a
+// file the edit creates. A moved file is diffed against its content
+// before the move.
+func previewWorkspaceEdit(we *lsp.WorkspaceEdit) ([]editPreview, map[string]string, error) {
+	result, err := edit.Apply(we, edit.Options{
+		DryRun:       true,
+		Diff:         true,
+		DiffContext:  edit.DiffContextLines,
+		MaxDiffLines: maxDiffLines,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	previews := make([]editPreview, 0, len(result.Files))
+	hashes := make(map[string]string, len(result.Files))
+	for _, f := range result.Files {
+		hashes[f.Path] = ""
+		if !f.Created {
+			hashes[f.Path] = hashContent(f.Original)
+		}
+		previews = append(previews, editPreview{
+			File:          f.Path,
+			Edits:         len(f.Edits),
+			Diff:          f.Diff,
+			DiffTruncated: f.DiffTruncated,
+			Created:       f.Created && f.RenamedFrom == "",
+			Deleted:       f.Deleted,
+			RenamedFrom:   f.RenamedFrom,
+		})
+	}
+	return previews, hashes, nil
+}
+
+// mergeWorkspaceEdit flattens Changes and DocumentChanges into a map from
+// file path to its TextEdits.
+func mergeWorkspaceEdit(edit *lsp.WorkspaceEdit) map[string][]protocol.TextEdit {
+	merged := make(map[string][]protocol.TextEdit)
+	for docURI, edits := range edit.Changes {
+		p := docsync.URIToFile(string(docURI))
+		merged[p] = append(merged[p], edits...)
+	}
+	for _, dc := range edit.DocumentChanges {
+		p := docsync.URIToFile(string(dc.TextDocument.URI))
+		merged[p] = append(merged[p], dc.Edits...)
+	}
+	return merged
+}
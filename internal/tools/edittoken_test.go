@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestEditTokenStore(t *testing.T) {
+	t.Run("put then take", func(t *testing.T) {
+		store := newEditTokenStore(0)
+		edit := &lsp.WorkspaceEdit{}
+		token, err := store.Put("ts_rename", "", edit, map[string]string{"/a.ts": "h"})
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		if token == "" {
+			t.Fatal("expected non-empty token")
+		}
+		got, err := store.Take(token)
+		if err != nil {
+			t.Fatalf("Take: %v", err)
+		}
+		if got.edit != edit || got.tool != "ts_rename" {
+			t.Errorf("Take returned wrong pending edit: %+v", got)
+		}
+		if _, err := store.Take(token); err == nil {
+			t.Error("expected second Take of the same token to fail")
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		store := newEditTokenStore(0)
+		if _, err := store.Take("nope"); err == nil {
+			t.Error("expected error for unknown token")
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		store := newEditTokenStore(time.Minute)
+		now := time.Unix(1000, 0)
+		store.now = func() time.Time { return now }
+
+		token, err := store.Put("ts_rename", "", &lsp.WorkspaceEdit{}, nil)
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		now = now.Add(59 * time.Second)
+		if store.Len() != 1 {
+			t.Fatalf("token expired early")
+		}
+		now = now.Add(2 * time.Second)
+		if _, err := store.Take(token); err == nil {
+			t.Error("expected expired token to be rejected")
+		}
+	})
+
+	t.Run("invalidated by write to affected file", func(t *testing.T) {
+		store := newEditTokenStore(0)
+		hit, _ := store.Put("ts_rename", "", &lsp.WorkspaceEdit{}, map[string]string{"/a.ts": "h", "/b.ts": "h"})
+		miss, _ := store.Put("ts_rename", "", &lsp.WorkspaceEdit{}, map[string]string{"/c.ts": "h"})
+
+		store.InvalidateFiles([]string{"/b.ts"})
+
+		if _, err := store.Take(hit); err == nil {
+			t.Error("expected token touching /b.ts to be invalidated")
+		}
+		if _, err := store.Take(miss); err != nil {
+			t.Errorf("unrelated token should survive: %v", err)
+		}
+	})
+
+	t.Run("default ttl", func(t *testing.T) {
+		if got := newEditTokenStore(-1).ttl; got != defaultEditTokenTTL {
+			t.Errorf("ttl = %v, want %v", got, defaultEditTokenTTL)
+		}
+	})
+}
+
+func TestPreviewWorkspaceEditAndDrift(t *testing.T) {
+	tmpDir := t.TempDir()
+	file := filepath.Join(tmpDir, "index.ts")
+	content := "export const greet = 1;\nconsole.log(greet);\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			protocol.DocumentURI("file://" + file): {
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 0, Character: 13},
+						End:   protocol.Position{Line: 0, Character: 18},
+					},
+					NewText: "hello",
+				},
+			},
+		},
+	}}
+
+	previews, hashes, err := previewWorkspaceEdit(edit)
+	if err != nil {
+		t.Fatalf("previewWorkspaceEdit: %v", err)
+	}
+	if len(previews) != 1 || previews[0].Edits != 1 {
+		t.Fatalf("unexpected previews: %+v", previews)
+	}
+	if !strings.Contains(previews[0].Diff, "-export const greet = 1;") ||
+		!strings.Contains(previews[0].Diff, "+export const hello = 1;") {
+		t.Errorf("diff missing expected lines:\n%s", previews[0].Diff)
+	}
+
+	// Preview must not write.
+	got, _ := os.ReadFile(file)
+	if string(got) != content {
+		t.Fatalf("preview modified the file")
+	}
+
+	p := &pendingEdit{edit: edit, hashes: hashes}
+	if drifted := p.driftedFiles(); len(drifted) != 0 {
+		t.Errorf("expected no drift, got %v", drifted)
+	}
+
+	if err := os.WriteFile(file, []byte(content+"// changed\n"), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	drifted := p.driftedFiles()
+	if len(drifted) != 1 || drifted[0] != file {
+		t.Errorf("drifted = %v, want [%s]", drifted, file)
+	}
+
+	if err := os.Remove(file); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if drifted := p.driftedFiles(); len(drifted) != 1 {
+		t.Errorf("deleted file should count as drifted, got %v", drifted)
+	}
+}
+
+func TestDryRunEditTruncates(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "big.ts")
+	content := strings.Repeat("greet();\n", maxDiffLines)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var edits []protocol.TextEdit
+	for i := range maxDiffLines {
+		edits = append(edits, protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Line: uint32(i)}, End: protocol.Position{Line: uint32(i), Character: 5}},
+			NewText: "hello",
+		})
+	}
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+		protocol.DocumentURI("file://" + file): edits,
+	}}}
+
+	result, err := dryRunEdit(edit)
+	if err != nil || result.IsError {
+		t.Fatalf("dryRunEdit = %+v, %v", result, err)
+	}
+	var got editDryRunResult
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.DryRun || got.TotalEdits != maxDiffLines || len(got.Changes) != 1 {
+		t.Fatalf("result = %+v", got)
+	}
+	c := got.Changes[0]
+	if !c.DiffTruncated || strings.Count(c.Diff, "\n") != maxDiffLines+1 || !strings.Contains(c.Diff, "more diff lines omitted") {
+		t.Errorf("diff of %d lines, truncated %v; want %d lines and a note", strings.Count(c.Diff, "\n"), c.DiffTruncated, maxDiffLines+1)
+	}
+	if data, _ := os.ReadFile(file); string(data) != content {
+		t.Error("the dry run wrote the file")
+	}
+}
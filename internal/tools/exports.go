@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"strings"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/jslex"
+)
+
+// Values of ts_document_symbols' visibility parameter.
+const (
+	visibilityAll      = "all"
+	visibilityExported = "exported"
+	visibilityInternal = "internal"
+)
+
+// memberKinds are the symbol kinds whose members are part of the API when
+// the symbol itself is exported. Namespace members need their own export
+// modifier.
+var memberKinds = map[string]bool{
+	"class":     true,
+	"interface": true,
+	"enum":      true,
+}
+
+// exportInfo tells which declarations of a file are exported.
+type exportInfo struct {
+	lines []string
+	// names are the local names exported by export statements.
+	names map[string]bool
+}
+
+func newExportInfo(lines []string) exportInfo {
+	return exportInfo{lines: lines, names: exportedNames(lines)}
+}
+
+// exported reports whether sym, nested in parent (nil at the top level), is
+// exported. The modifier is looked for on the line of the symbol's name, so
+// decorators and JSDoc above the declaration do not hide it.
+func (x exportInfo) exported(sym protocol.DocumentSymbol, parent *symbolEntry) bool {
+	line := ""
+	if n := int(sym.SelectionRange.Start.Line); n < len(x.lines) {
+		line = x.lines[n]
+	}
+	if isExportedDeclaration(line) {
+		return true
+	}
+	if parent == nil {
+		return x.names[sym.Name]
+	}
+	return parent.Exported && memberKinds[parent.Kind] && !isPrivateMember(line)
+}
+
+// isPrivateMember reports whether a class member line declares a private
+// or #private member.
+func isPrivateMember(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "private ") || strings.HasPrefix(line, "#")
+}
+
+// exportedNames returns the local names a module exports through export
+// statements rather than modifiers: `export { a, b as c }` lists, which may
+// span lines, and `export default a;`. Lists re-exporting from another
+// module (`export { a } from "./a"`) name no local and are skipped.
+// Statements must start a line, as they do in formatted code.
+func exportedNames(lines []string) map[string]bool {
+	names := make(map[string]bool)
+	code := make([]string, len(lines))
+	var lex jslex.Scanner
+	for i, line := range lines {
+		code[i] = strings.TrimSpace(stripComments(line, &lex))
+	}
+
+	for i := 0; i < len(code); i++ {
+		rest, ok := cutKeyword(code[i], "export")
+		if !ok {
+			continue
+		}
+		if name, ok := cutKeyword(rest, "default"); ok {
+			name = strings.TrimSuffix(strings.TrimSpace(name), ";")
+			if n := identLen(name); n > 0 && n == len(name) && !declarationKeywords[name] {
+				names[name] = true
+			}
+			continue
+		}
+		if r, ok := cutKeyword(rest, "type"); ok {
+			rest = r
+		}
+		if !strings.HasPrefix(rest, "{") {
+			continue
+		}
+
+		// Collect the list up to its closing brace.
+		list := rest[1:]
+		for !strings.Contains(list, "}") && i+1 < len(code) {
+			i++
+			list += "\n" + code[i]
+		}
+		list, tail, _ := strings.Cut(list, "}")
+		if tail = strings.TrimSpace(tail); tail == "" && i+1 < len(code) {
+			tail = code[i+1]
+		}
+		if _, ok := cutKeyword(tail, "from"); ok {
+			continue
+		}
+		for _, local := range exportListLocals(list) {
+			names[local] = true
+		}
+	}
+	return names
+}
+
+// exportListLocals returns the local names of the specifiers between the
+// braces of an export list: a in "a", "a as b" and "type a".
+func exportListLocals(list string) []string {
+	var locals []string
+	for _, spec := range strings.Split(list, ",") {
+		fields := strings.Fields(spec)
+		if len(fields) > 1 && fields[0] == "type" && fields[1] != "as" {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if n := identLen(fields[0]); n > 0 && n == len(fields[0]) {
+			locals = append(locals, fields[0])
+		}
+	}
+	return locals
+}
+
+// declarationKeywords start a declaration after "export default"; the
+// modifier check on the declaration's own line covers those.
+var declarationKeywords = map[string]bool{
+	"abstract":  true,
+	"async":     true,
+	"class":     true,
+	"function":  true,
+	"interface": true,
+}
+
+// cutKeyword returns s after the keyword kw and following spaces when s
+// starts with kw as a whole word.
+func cutKeyword(s, kw string) (string, bool) {
+	if !strings.HasPrefix(s, kw) || (len(s) > len(kw) && isIdentByte(s[len(kw)])) {
+		return s, false
+	}
+	return strings.TrimLeft(s[len(kw):], " \t"), true
+}
+
+// stripComments removes the comments from one line of source; lex
+// carries block comments and template literals across lines. Strings are
+// kept, so "//" or "/*" inside them starts no comment.
+func stripComments(line string, lex *jslex.Scanner) string {
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		kind, next := lex.Next(line, i)
+		if kind != jslex.Comment {
+			b.WriteString(line[i:next])
+		}
+		i = next
+	}
+	return b.String()
+}
+
+// filterSymbols keeps the entries for which keep holds. An entry that does
+// not match stays, with its matching descendants only, when it has any,
+// so nested matches keep their parent context.
+func filterSymbols(entries []symbolEntry, keep func(symbolEntry) bool) []symbolEntry {
+	var out []symbolEntry
+	for _, e := range entries {
+		e.Children = filterSymbols(e.Children, keep)
+		if keep(e) || len(e.Children) > 0 {
+			out = append(out, e)
+		}
+	}
+	return out
+}
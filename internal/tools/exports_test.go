@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestExportedNames(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "single line list",
+			src:  "const a = 1;\nconst b = 2;\nexport { a, b };",
+			want: []string{"a", "b"},
+		},
+		{
+			name: "aliased",
+			src:  "export { a as b, c as default };",
+			want: []string{"a", "c"},
+		},
+		{
+			name: "multi-line list",
+			src:  "export {\n  a,\n  b as bee, // the bee\n  type C,\n};",
+			want: []string{"C", "a", "b"},
+		},
+		{
+			name: "type-only list",
+			src:  "export type { A, B as Bee };",
+			want: []string{"A", "B"},
+		},
+		{
+			name: "export default identifier",
+			src:  "function main() {}\nexport default main;",
+			want: []string{"main"},
+		},
+		{
+			name: "export default declaration",
+			src:  "export default function main() {}\nexport default class {}",
+		},
+		{
+			name: "re-export from another module",
+			src:  "export { a, b as c } from \"./other\";\nexport {\n  d,\n} from './more';",
+		},
+		{
+			name: "from on the next line",
+			src:  "export { a }\n  from \"./other\";",
+		},
+		{
+			name: "commented out",
+			src:  "// export { a };\n/*\nexport { b };\n*/\nexport { c }; /* export { d } */",
+			want: []string{"c"},
+		},
+		{
+			name: "comment markers inside strings",
+			src:  "const glob = \"src/**/*.ts\";\nexport { glob };",
+			want: []string{"glob"},
+		},
+		{
+			name: "not at the start of a line",
+			src:  "const s = `export { a }`;\nreexport { b };",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.Sorted(maps.Keys(exportedNames(strings.Split(tt.src, "\n"))))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("exportedNames = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertSymbolsExported(t *testing.T) {
+	src := []string{
+		"export class Service {",     // 0
+		"  run() {}",                 // 1
+		"  private helper() {}",      // 2
+		"}",                          // 3
+		"@sealed",                    // 4
+		"export class Decorated {}",  // 5
+		"function internal() {",      // 6
+		"  const local = 1;",         // 7
+		"}",                          // 8
+		"namespace NS {",             // 9
+		"  export const inner = 1;",  // 10
+		"  const hidden = 2;",        // 11
+		"}",                          // 12
+		"const listed = 1;",          // 13
+		"export { listed, NS };",     // 14
+		"export interface Options {", // 15
+		"  verbose: boolean;",        // 16
+		"}",                          // 17
+	}
+	sym := func(name string, kind protocol.SymbolKind, line, nameLine uint32, children ...protocol.DocumentSymbol) protocol.DocumentSymbol {
+		return protocol.DocumentSymbol{
+			Name:           name,
+			Kind:           kind,
+			Range:          protocol.Range{Start: protocol.Position{Line: line}},
+			SelectionRange: protocol.Range{Start: protocol.Position{Line: nameLine}},
+			Children:       children,
+		}
+	}
+	symbols := []protocol.DocumentSymbol{
+		sym("Service", protocol.SymbolKindClass, 0, 0,
+			sym("run", protocol.SymbolKindMethod, 1, 1),
+			sym("helper", protocol.SymbolKindMethod, 2, 2)),
+		sym("Decorated", protocol.SymbolKindClass, 4, 5),
+		sym("internal", protocol.SymbolKindFunction, 6, 6,
+			sym("local", protocol.SymbolKindConstant, 7, 7)),
+		sym("NS", protocol.SymbolKindNamespace, 9, 9,
+			sym("inner", protocol.SymbolKindConstant, 10, 10),
+			sym("hidden", protocol.SymbolKindConstant, 11, 11)),
+		sym("listed", protocol.SymbolKindConstant, 13, 13),
+		sym("Options", protocol.SymbolKindInterface, 15, 15,
+			sym("verbose", protocol.SymbolKindProperty, 16, 16)),
+	}
+	entries := convertSymbols(symbols, newExportInfo(src), nil)
+
+	if got, want := outline(entries), "Service+(run+ helper-) Decorated+ internal-(local-) NS+(inner+ hidden-) listed+ Options+(verbose+)"; got != want {
+		t.Errorf("all:\n got %s\nwant %s", got, want)
+	}
+	exported := func(e symbolEntry) bool { return e.Exported }
+	if got, want := outline(filterSymbols(entries, exported)), "Service+(run+) Decorated+ NS+(inner+) listed+ Options+(verbose+)"; got != want {
+		t.Errorf("exported:\n got %s\nwant %s", got, want)
+	}
+	internal := func(e symbolEntry) bool { return !e.Exported }
+	if got, want := outline(filterSymbols(entries, internal)), "Service+(helper-) internal-(local-) NS+(hidden-)"; got != want {
+		t.Errorf("internal:\n got %s\nwant %s", got, want)
+	}
+}
+
+// outline renders entries as "name+" or "name-" by export status, with
+// children in parentheses.
+func outline(entries []symbolEntry) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		mark := "-"
+		if e.Exported {
+			mark = "+"
+		}
+		parts[i] = e.Name + mark
+		if len(e.Children) > 0 {
+			parts[i] += "(" + outline(e.Children) + ")"
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+// extractKind is the code action kind of TypeScript's extract
+// refactorings: to a function, method, constant, type alias or interface.
+const extractKind = "refactor.extract"
+
+type extractRefactoring struct {
+	Title string `json:"title"`
+	Kind  string `json:"kind,omitempty"`
+	// Disabled is the reason the refactoring does not apply to the
+	// selection, if any.
+	Disabled string `json:"disabled,omitempty"`
+}
+
+type extractRefactoringsResult struct {
+	File         string               `json:"file"`
+	Refactorings []extractRefactoring `json:"refactorings"`
+}
+
+type extractRefactorResult struct {
+	Title string `json:"title"`
+	Kind  string `json:"kind,omitempty"`
+	// Name is the name of the generated function, constant or type, when
+	// it could be found in the edit.
+	Name       string     `json:"name,omitempty"`
+	TotalEdits int        `json:"totalEdits"`
+	Changes    []editInfo `json:"changes"`
+	fileOps
+}
+
+// declaredName matches the declarations an extract refactoring generates:
+// "function newFunction(", "const newLocal =", "type NewType =",
+// "interface NewType {" and class members such as "private newMethod(" or
+// "private readonly newProperty =".
+var declaredName = regexp.MustCompile(`\b(?:function\*?|const|let|type|interface)\s+([A-Za-z_$][\w$]*)|\b(?:private|public|protected)\s+(?:static\s+)?(?:readonly\s+)?(?:async\s+)?\*?([A-Za-z_$][\w$]*)`)
+
+// generatedName returns the name an extract refactoring gave the code it
+// extracted from original: the first name declared by the inserted text
+// that original does not already use. TypeScript picks names unused in the
+// file, which tells them apart from declarations moved by the extraction.
+func generatedName(original string, edits []protocol.TextEdit) string {
+	for _, e := range edits {
+		for _, m := range declaredName.FindAllStringSubmatch(e.NewText, -1) {
+			name := m[1]
+			if name == "" {
+				name = m[2]
+			}
+			if len(findWordOccurrences(original, name)) == 0 {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func makeExtractRefactorHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, packages *workspace.PackageResolver, overlayCheck bool, journal *journalPolicy, recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		r, err := parseCodeActionRange(request, file, cols)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		pick := request.GetString("pick", "")
+		confirm := request.GetBool("confirm", false)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		actions, _, err := listCodeActions(ctx, client, file, r, extractKind)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if pick == "" {
+			result := extractRefactoringsResult{File: file, Refactorings: []extractRefactoring{}}
+			for _, a := range actions {
+				entry := extractRefactoring{Title: a.Title, Kind: string(a.Kind)}
+				if a.Disabled != nil {
+					entry.Disabled = a.Disabled.Reason
+				}
+				result.Refactorings = append(result.Refactorings, entry)
+			}
+			data, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(data)), nil
+		}
+
+		action, err := selectCodeAction(actions, pick, -1)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		// Extract refactorings are usually computed on demand, through
+		// codeAction/resolve.
+		edit, err := codeActionEdit(ctx, client, action)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if path, pkg := installedPackageEdit(edit, packages); pkg != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("refusing to apply %q: it edits the installed package %s (%s)", action.Title, pkg, pkg.DisplayPath(path))), nil
+		}
+
+		if confirm {
+			return previewEdit(pending, request.Params.Name, "", edit)
+		}
+
+		// The name is looked up before the edit adds it to the file.
+		name := ""
+		if original, err := os.ReadFile(file); err == nil {
+			name = generatedName(string(original), mergeWorkspaceEdit(edit)[file])
+		}
+
+		var gate editGate
+		if overlayCheck {
+			gate = overlayGate(ctx, client, docs)
+		}
+		changes, err := applyWorkspaceEdit(edit, docs, gate, journal, recorder.recording(editOrigin{tool: request.Params.Name}))
+		if err != nil {
+			resyncStale(ctx, client, docs, err)
+			return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
+		}
+		paths := sortedChangePaths(changes)
+		pending.InvalidateFiles(paths)
+
+		// Re-sync all modified files so the LSP server sees the new content.
+		if err := resyncChanged(ctx, client, docs, changes); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		ClearFileCache()
+
+		result := extractRefactorResult{Title: action.Title, Kind: string(action.Kind), Name: name, Changes: []editInfo{}, fileOps: fileOpsOf(changes)}
+		for _, p := range paths {
+			result.TotalEdits += changes[p].Edits
+			result.Changes = append(result.Changes, changes[p])
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestGeneratedName(t *testing.T) {
+	original := "import { add } from \"./index\";\n\nexport function total() {\n  return add(1, 2);\n}\n"
+	tests := []struct {
+		name  string
+		edits []string
+		want  string
+	}{
+		{
+			name:  "constant",
+			edits: []string{"const newLocal = add(1, 2);\n", "newLocal"},
+			want:  "newLocal",
+		},
+		{
+			name:  "function after a moved declaration",
+			edits: []string{"newFunction();\n", "\nfunction newFunction() {\n  function total() {}\n  return add(1, 2);\n}\n"},
+			want:  "newFunction",
+		},
+		{
+			name:  "existing names are skipped",
+			edits: []string{"export function total() {}\nconst newLocal_1 = 1;\n"},
+			want:  "newLocal_1",
+		},
+		{
+			name:  "method",
+			edits: []string{"this.newMethod()", "\n  private static async newMethod() {\n    return add(1, 2);\n  }\n"},
+			want:  "newMethod",
+		},
+		{
+			name:  "readonly property",
+			edits: []string{"\n  private readonly newProperty = add(1, 2);\n"},
+			want:  "newProperty",
+		},
+		{
+			name:  "type alias",
+			edits: []string{"type NewType = {\n  a: number;\n};\n", "NewType"},
+			want:  "NewType",
+		},
+		{
+			name:  "no declaration",
+			edits: []string{"add(1, 2)"},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var edits []protocol.TextEdit
+			for _, text := range tt.edits {
+				edits = append(edits, protocol.TextEdit{NewText: text})
+			}
+			if got := generatedName(original, edits); got != tt.want {
+				t.Errorf("generatedName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+)
+
+// fakeTsgoEnv names the crash marker of a fake tsgo: the test binary runs
+// as one instead of the tests when it is set.
+const fakeTsgoEnv = "TYPESCRIPT_MCP_TEST_FAKE_TSGO"
+
+func TestMain(m *testing.M) {
+	if marker := os.Getenv(fakeTsgoEnv); marker != "" {
+		runFakeTsgo(marker)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// useFakeTsgo puts the test binary on PATH as tsgo, so that lsp.StartTsgo
+// runs runFakeTsgo. The first fake tsgo crashes on the first document
+// synced to it; marker records the crash, and the restarted ones do not.
+func useFakeTsgo(t *testing.T, marker string) {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := t.TempDir()
+	if err := os.Symlink(self, filepath.Join(bin, "tsgo")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv(fakeTsgoEnv, marker)
+}
+
+// runFakeTsgo serves LSP on stdin and stdout, answering initialize and
+// nothing else, as useFakeTsgo describes.
+func runFakeTsgo(marker string) {
+	if len(os.Args) > 1 && os.Args[1] == "--version" {
+		fmt.Println("Version 7.0.0-dev")
+		return
+	}
+	_, err := os.Stat(marker)
+	crash := err != nil
+	conn := jsonrpc2.NewConn(jsonrpc2.NewStream(stdio{}))
+	conn.Go(context.Background(), func(ctx context.Context, reply jsonrpc2.Replier, req jsonrpc2.Request) error {
+		switch req.Method() {
+		case protocol.MethodInitialize:
+			return reply(ctx, map[string]any{
+				"capabilities": map[string]any{"textDocumentSync": 1},
+				"serverInfo":   map[string]any{"name": "fake-tsgo", "version": "7.0.0-dev"},
+			}, nil)
+		case protocol.MethodTextDocumentDidOpen, protocol.MethodTextDocumentDidChange:
+			if crash {
+				_ = os.WriteFile(marker, nil, 0o644)
+				os.Exit(1)
+			}
+		}
+		return reply(ctx, nil, nil)
+	})
+	<-conn.Done()
+}
+
+// stdio is the fake tsgo's end of the connection.
+type stdio struct{}
+
+func (stdio) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdio) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdio) Close() error                { return os.Stdin.Close() }
+
+var _ io.ReadWriteCloser = stdio{}
@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+const (
+	// maxFixAllFiles bounds the files a project-wide ts_fix_all checks.
+	maxFixAllFiles = 500
+	// maxFixAllDiagnostics bounds the diagnostics one ts_fix_all call
+	// asks fixes for.
+	maxFixAllDiagnostics = 500
+
+	fixSkipConflict = "its edits overlap another fix"
+	fixSkipNone     = "no quick fix offered"
+	fixSkipFiles    = "it creates, moves or deletes files"
+)
+
+// fixAllSkip is a diagnostic ts_fix_all left alone.
+type fixAllSkip struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+type fixAllResult struct {
+	Code         int `json:"code"`
+	FilesChecked int `json:"filesChecked"`
+	// Diagnostics is the number of diagnostics with the code found.
+	Diagnostics int          `json:"diagnostics"`
+	Applied     int          `json:"applied"`
+	Skipped     []fixAllSkip `json:"skipped"`
+	// Remaining is the number of diagnostics with the code left after
+	// the fixes were written, counted again in the files checked.
+	Remaining  int        `json:"remaining"`
+	TotalEdits int        `json:"totalEdits"`
+	Changes    []editInfo `json:"changes"`
+}
+
+// fixMerger merges the edits of independent fixes into one edit, refusing
+// a fix whose edits overlap those of a fix merged before.
+type fixMerger struct {
+	edits map[string][]protocol.TextEdit
+}
+
+// positionBefore reports whether a comes before b.
+func positionBefore(a, b protocol.Position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
+
+// editsOverlap reports whether a and b touch the same text, or insert at
+// the same position, so the order they are applied in matters.
+func editsOverlap(a, b protocol.TextEdit) bool {
+	if a.Range.Start == b.Range.Start {
+		return true
+	}
+	return positionBefore(a.Range.Start, b.Range.End) && positionBefore(b.Range.Start, a.Range.End)
+}
+
+// add merges fix, a map from file path to its edits, and reports false,
+// merging nothing, when one of its edits overlaps a merged edit. An edit
+// equal to a merged one, such as the same import added by two fixes, is
+// merged once.
+func (m *fixMerger) add(fix map[string][]protocol.TextEdit) bool {
+	fresh := make(map[string][]protocol.TextEdit, len(fix))
+	for path, edits := range fix {
+		for _, e := range edits {
+			dup := false
+			for _, prev := range m.edits[path] {
+				if prev == e {
+					dup = true
+					break
+				}
+				if editsOverlap(prev, e) {
+					return false
+				}
+			}
+			if !dup {
+				fresh[path] = append(fresh[path], e)
+			}
+		}
+	}
+	if m.edits == nil {
+		m.edits = make(map[string][]protocol.TextEdit)
+	}
+	for path, edits := range fresh {
+		m.edits[path] = append(m.edits[path], edits...)
+	}
+	return true
+}
+
+// workspaceEdit returns the merged edits, or nil when there are none.
+func (m *fixMerger) workspaceEdit() *lsp.WorkspaceEdit {
+	if len(m.edits) == 0 {
+		return nil
+	}
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: make(map[protocol.DocumentURI][]protocol.TextEdit, len(m.edits))}}
+	for path, edits := range m.edits {
+		edit.Changes[protocol.DocumentURI(docsync.FileToURI(path))] = edits
+	}
+	return edit
+}
+
+// quickFixFor picks the fix to apply among the quick fixes of one
+// diagnostic: the preferred one, or else the first. With match, only
+// titles containing it are considered. Disabled fixes are passed over.
+func quickFixFor(actions []lsp.CodeAction, match string) (lsp.CodeAction, bool) {
+	var candidates []lsp.CodeAction
+	for _, a := range actions {
+		if a.Disabled == nil && strings.Contains(a.Title, match) {
+			candidates = append(candidates, a)
+		}
+	}
+	for _, a := range candidates {
+		if a.IsPreferred {
+			return a, true
+		}
+	}
+	if len(candidates) == 0 {
+		return lsp.CodeAction{}, false
+	}
+	return candidates[0], true
+}
+
+// codeDiagnostics returns the diagnostics of file with code, in order.
+func codeDiagnostics(ctx context.Context, client *lsp.Client, file string, code int) ([]protocol.Diagnostic, error) {
+	diags, err := client.Diagnostic(ctx, file)
+	if err != nil {
+		return nil, fmt.Errorf("diagnostic error: %w", err)
+	}
+	var out []protocol.Diagnostic
+	for _, d := range diags {
+		if n, ok := diagnosticCode(d.Code); ok && n == code {
+			out = append(out, d)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return positionBefore(out[i].Range.Start, out[j].Range.Start) })
+	return out, nil
+}
+
+// fixAllFiles returns the files ts_fix_all checks: file, or else the files
+// of the project's tsconfig.
+func fixAllFiles(request mcp.CallToolRequest, client *lsp.Client) ([]string, error) {
+	if file := request.GetString("file", ""); file != "" {
+		return []string{file}, nil
+	}
+	configPath := request.GetString("tsconfig", "")
+	if configPath == "" {
+		configPath = filepath.Join(client.RootDir(), "tsconfig.json")
+	}
+	cfg, err := tsconfig.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("tsconfig error: %v", err)
+	}
+	files := workspace.ProjectFiles(cfg)
+	if len(files) > maxFixAllFiles {
+		return nil, fmt.Errorf("the project has %d files, more than the %d ts_fix_all checks at once; pass file to fix one file at a time", len(files), maxFixAllFiles)
+	}
+	return files, nil
+}
+
+func makeFixAllHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, packages *workspace.PackageResolver, overlayCheck bool, journal *journalPolicy, recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		code, err := request.RequireInt("code")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		match := request.GetString("fix", "")
+		confirm := request.GetBool("confirm", false)
+		files, err := fixAllFiles(request, client)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result := fixAllResult{Code: code, FilesChecked: len(files), Skipped: []fixAllSkip{}, Changes: []editInfo{}}
+		var merger fixMerger
+		var fixed []string
+		for _, file := range files {
+			defer docs.Pin(file)()
+			if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+			}
+			diags, err := codeDiagnostics(ctx, client, file, code)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if len(diags) > 0 {
+				fixed = append(fixed, file)
+			}
+			for _, d := range diags {
+				result.Diagnostics++
+				if result.Diagnostics > maxFixAllDiagnostics {
+					return mcp.NewToolResultError(fmt.Sprintf("more than %d diagnostics with code %d; pass file to fix one file at a time", maxFixAllDiagnostics, code)), nil
+				}
+				skip := func(reason string) {
+					result.Skipped = append(result.Skipped, fixAllSkip{
+						File:    file,
+						Line:    int(d.Range.Start.Line) + 1,
+						Column:  int(d.Range.Start.Character) + 1,
+						Message: d.Message,
+						Reason:  reason,
+					})
+				}
+				start, end := d.Range.Start, d.Range.End
+				actions, err := client.CodeAction(ctx, file, int(start.Line)+1, int(start.Character)+1, int(end.Line)+1, int(end.Character)+1,
+					[]protocol.Diagnostic{d}, []protocol.CodeActionKind{protocol.QuickFix})
+				if err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("code action error: %v", err)), nil
+				}
+				var quickFixes []lsp.CodeAction
+				for _, a := range actions {
+					if kindMatches(a.Kind, string(protocol.QuickFix)) {
+						quickFixes = append(quickFixes, a)
+					}
+				}
+				action, ok := quickFixFor(quickFixes, match)
+				if !ok {
+					skip(fixSkipNone)
+					continue
+				}
+				edit, err := codeActionEdit(ctx, client, action)
+				if err != nil {
+					skip(err.Error())
+					continue
+				}
+				if len(edit.Operations) > 0 {
+					// Only text edits merge; apply such a fix on its own
+					// with ts_apply_code_action.
+					skip(fixSkipFiles)
+					continue
+				}
+				if !merger.add(mergeWorkspaceEdit(edit)) {
+					skip(fixSkipConflict)
+					continue
+				}
+				result.Applied++
+			}
+		}
+
+		edit := merger.workspaceEdit()
+		if edit == nil {
+			result.Remaining = result.Diagnostics
+			return fixAllResponse(result)
+		}
+		if path, pkg := installedPackageEdit(edit, packages); pkg != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("refusing to apply the fixes: they edit the installed package %s (%s)", pkg, pkg.DisplayPath(path))), nil
+		}
+
+		if confirm {
+			return previewEdit(pending, request.Params.Name, "", edit)
+		}
+
+		var gate editGate
+		if overlayCheck {
+			gate = overlayGate(ctx, client, docs)
+		}
+		changes, err := applyWorkspaceEdit(edit, docs, gate, journal, recorder.recording(editOrigin{tool: request.Params.Name}))
+		if err != nil {
+			resyncStale(ctx, client, docs, err)
+			return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
+		}
+		paths := sortedChangePaths(changes)
+		pending.InvalidateFiles(paths)
+
+		// Re-sync all modified files so the LSP server sees the new content.
+		for _, p := range paths {
+			if syncErr := docs.ResyncFile(ctx, client.Conn(), p); syncErr != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("re-sync error for %s: %v", p, syncErr)), nil
+			}
+		}
+
+		ClearFileCache()
+
+		for _, p := range paths {
+			result.TotalEdits += changes[p].Edits
+			result.Changes = append(result.Changes, changes[p])
+		}
+		// Fixes may leave diagnostics of the code behind, or add some to
+		// the files they changed.
+		recount := append([]string{}, fixed...)
+		for _, p := range paths {
+			if !slices.Contains(recount, p) {
+				recount = append(recount, p)
+			}
+		}
+		for _, f := range recount {
+			diags, err := codeDiagnostics(ctx, client, f, code)
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			result.Remaining += len(diags)
+		}
+		return fixAllResponse(result)
+	}
+}
+
+func fixAllResponse(result fixAllResult) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func textEdit(startLine, startChar, endLine, endChar uint32, text string) protocol.TextEdit {
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startChar},
+			End:   protocol.Position{Line: endLine, Character: endChar},
+		},
+		NewText: text,
+	}
+}
+
+func TestEditsOverlap(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b protocol.TextEdit
+		want bool
+	}{
+		{"disjoint lines", textEdit(1, 0, 2, 0, ""), textEdit(4, 0, 5, 0, ""), false},
+		{"adjacent", textEdit(1, 0, 1, 5, ""), textEdit(1, 5, 1, 9, ""), false},
+		{"nested", textEdit(1, 0, 3, 0, ""), textEdit(2, 4, 2, 8, "x"), true},
+		{"crossing", textEdit(1, 2, 1, 8, ""), textEdit(1, 6, 1, 12, ""), true},
+		{"same insertion point", textEdit(0, 0, 0, 0, "a"), textEdit(0, 0, 0, 0, "b"), true},
+		{"insertion inside a deletion", textEdit(1, 0, 1, 9, ""), textEdit(1, 4, 1, 4, "x"), true},
+		{"insertion at a deletion's end", textEdit(1, 0, 1, 9, ""), textEdit(1, 9, 1, 9, "x"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := editsOverlap(tt.a, tt.b); got != tt.want {
+				t.Errorf("editsOverlap(a, b) = %v, want %v", got, tt.want)
+			}
+			if got := editsOverlap(tt.b, tt.a); got != tt.want {
+				t.Errorf("editsOverlap(b, a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFixMerger(t *testing.T) {
+	var m fixMerger
+	if m.workspaceEdit() != nil {
+		t.Fatal("empty merger has an edit")
+	}
+	importEdit := textEdit(0, 0, 0, 0, "import { add } from \"./index\";\n")
+	if !m.add(map[string][]protocol.TextEdit{"/p/a.ts": {textEdit(2, 0, 3, 0, "")}}) {
+		t.Fatal("first fix refused")
+	}
+	if !m.add(map[string][]protocol.TextEdit{"/p/a.ts": {textEdit(5, 0, 6, 0, ""), importEdit}}) {
+		t.Fatal("disjoint fix refused")
+	}
+	// The same import added again is merged once.
+	if !m.add(map[string][]protocol.TextEdit{"/p/a.ts": {importEdit, textEdit(8, 2, 8, 4, "")}}) {
+		t.Fatal("fix repeating an edit refused")
+	}
+	// Overlapping the second fix refuses the whole fix, including its
+	// edit of another file.
+	if m.add(map[string][]protocol.TextEdit{"/p/b.ts": {textEdit(0, 0, 1, 0, "")}, "/p/a.ts": {textEdit(5, 3, 5, 6, "")}}) {
+		t.Fatal("overlapping fix merged")
+	}
+	if !m.add(map[string][]protocol.TextEdit{"/p/b.ts": {textEdit(0, 0, 1, 0, "")}}) {
+		t.Fatal("fix of another file refused")
+	}
+
+	edit := m.workspaceEdit()
+	if got := len(edit.Changes["file:///p/a.ts"]); got != 4 {
+		t.Errorf("a.ts edits = %d, want 4", got)
+	}
+	if got := len(edit.Changes["file:///p/b.ts"]); got != 1 {
+		t.Errorf("b.ts edits = %d, want 1", got)
+	}
+}
+
+func TestQuickFixFor(t *testing.T) {
+	actions := []lsp.CodeAction{
+		{CodeAction: protocol.CodeAction{Title: "Remove unused declaration for: 'x'"}},
+		{CodeAction: protocol.CodeAction{Title: "Prefix 'x' with an underscore", IsPreferred: true}},
+		{CodeAction: protocol.CodeAction{Title: "Remove import from './index'", Disabled: &protocol.CodeActionDisable{Reason: "stale"}}},
+	}
+	tests := []struct {
+		match  string
+		want   string
+		wantOK bool
+	}{
+		{"", "Prefix 'x' with an underscore", true},
+		{"Remove", "Remove unused declaration for: 'x'", true},
+		{"Remove import", "", false},
+		{"Ignore", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := quickFixFor(actions, tt.match)
+		if ok != tt.wantOK || got.Title != tt.want {
+			t.Errorf("quickFixFor(%q) = %q, %v; want %q, %v", tt.match, got.Title, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// foldingKindCode is the kind of folding ranges tsgo gives no kind, such
+// as function bodies and blocks.
+const foldingKindCode = "code"
+
+var foldingKinds = []string{
+	string(protocol.ImportsFoldingRange),
+	string(protocol.CommentFoldingRange),
+	string(protocol.RegionFoldingRange),
+	foldingKindCode,
+}
+
+type foldingRangeEntry struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind"`
+	// Preview is the trimmed text of the start line.
+	Preview string `json:"preview,omitempty"`
+}
+
+type foldingRangesResult struct {
+	File   string              `json:"file"`
+	Ranges []foldingRangeEntry `json:"ranges"`
+}
+
+// foldingRangeEntries converts ranges of kind, or of every kind when kind
+// is "", ordered by start line with enclosing ranges first.
+func foldingRangeEntries(file string, ranges []protocol.FoldingRange, kind string) []foldingRangeEntry {
+	entries := []foldingRangeEntry{}
+	for _, r := range ranges {
+		k := string(r.Kind)
+		if k == "" {
+			k = foldingKindCode
+		}
+		if kind != "" && k != kind {
+			continue
+		}
+		e := foldingRangeEntry{StartLine: int(r.StartLine) + 1, EndLine: int(r.EndLine) + 1, Kind: k}
+		if text, err := readLine(file, e.StartLine); err == nil {
+			e.Preview = strings.TrimSpace(text)
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].StartLine != entries[j].StartLine {
+			return entries[i].StartLine < entries[j].StartLine
+		}
+		return entries[i].EndLine > entries[j].EndLine
+	})
+	return entries
+}
+
+func makeFoldingRangesHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		kind := request.GetString("kind", "")
+		if kind != "" && !slices.Contains(foldingKinds, kind) {
+			return mcp.NewToolResultError(fmt.Sprintf("kind must be one of %s, got %q", strings.Join(foldingKinds, ", "), kind)), nil
+		}
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		ranges, err := client.FoldingRange(ctx, file)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("folding ranges are unavailable: %v; use ts_document_symbols for an outline instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("folding range error: %v", err)), nil
+		}
+
+		result := foldingRangesResult{File: file, Ranges: foldingRangeEntries(file, ranges, kind)}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
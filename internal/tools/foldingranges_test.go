@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestFoldingRangeEntries(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.ts")
+	src := "import { a } from \"./a\";\nimport { b } from \"./b\";\n\n/**\n * Doc.\n */\nexport function f() {\n  if (a) {\n    b();\n  }\n}\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ClearFileCache)
+	ranges := []protocol.FoldingRange{
+		{StartLine: 7, EndLine: 9},
+		{StartLine: 3, EndLine: 5, Kind: protocol.CommentFoldingRange},
+		{StartLine: 6, EndLine: 10},
+		{StartLine: 0, EndLine: 1, Kind: protocol.ImportsFoldingRange},
+	}
+
+	got := foldingRangeEntries(file, ranges, "")
+	want := []foldingRangeEntry{
+		{StartLine: 1, EndLine: 2, Kind: "imports", Preview: "import { a } from \"./a\";"},
+		{StartLine: 4, EndLine: 6, Kind: "comment", Preview: "/**"},
+		{StartLine: 7, EndLine: 11, Kind: "code", Preview: "export function f() {"},
+		{StartLine: 8, EndLine: 10, Kind: "code", Preview: "if (a) {"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if got := foldingRangeEntries(file, ranges, "code"); len(got) != 2 || got[0].StartLine != 7 {
+		t.Errorf("code entries = %+v", got)
+	}
+	if got := foldingRangeEntries(file, ranges, "region"); got == nil || len(got) != 0 {
+		t.Errorf("region entries = %#v, want an empty list", got)
+	}
+}
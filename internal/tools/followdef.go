@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/sourcemap"
+)
+
+// maxDefinitionHops bounds the re-exports and declaration maps followed
+// from one definition.
+const maxDefinitionHops = 4
+
+// Kinds of definitionHop.
+const (
+	hopReexport       = "re-export"
+	hopDeclarationMap = "declaration map"
+)
+
+// definitionHop is a location ts_definition passed through on its way to
+// the original source.
+type definitionHop struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Kind is hopReexport or hopDeclarationMap.
+	Kind string `json:"kind"`
+}
+
+// definitionBackend is the subset of *lsp.Client following definitions
+// needs.
+type definitionBackend interface {
+	Definition(ctx context.Context, file string, line, col int) ([]protocol.Location, error)
+}
+
+// reexportPattern matches a line re-exporting names from another module,
+// such as `export { createRepo } from "./repo";`.
+var reexportPattern = regexp.MustCompile(`^\s*export\s+(type\s+)?\{[^}]*\}\s*from\s*['"]`)
+
+// followDefinitions chases every location through re-export lines and
+// declaration maps to the original source, at most maxDefinitionHops
+// times each. sync opens a file with the server before a definition is
+// asked in it. Locations that lead to the same place are merged. vias are
+// the hops of each location, aligned with the returned locations.
+func followDefinitions(ctx context.Context, backend definitionBackend, sync func(file string) error, locs []protocol.Location) (out []protocol.Location, vias [][]definitionHop) {
+	seen := make(map[string]bool)
+	for _, loc := range locs {
+		for _, f := range followDefinition(ctx, backend, sync, loc) {
+			key := locationKey(f.loc)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, f.loc)
+			vias = append(vias, f.via)
+		}
+	}
+	return out, vias
+}
+
+type followedLocation struct {
+	loc protocol.Location
+	via []definitionHop
+}
+
+// followDefinition follows one location. A hop that fails, or leads back
+// to a location already passed, ends the chase where it is.
+func followDefinition(ctx context.Context, backend definitionBackend, sync func(file string) error, loc protocol.Location) []followedLocation {
+	visited := map[string]bool{locationKey(loc): true}
+	queue := []followedLocation{{loc: loc}}
+	var done []followedLocation
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		var next []protocol.Location
+		var kind string
+		if len(f.via) < maxDefinitionHops {
+			next, kind = nextDefinitions(ctx, backend, sync, f.loc)
+		}
+		hop := definitionHop{
+			File:   docsync.URIToFile(string(f.loc.URI)),
+			Line:   int(f.loc.Range.Start.Line) + 1,
+			Column: int(f.loc.Range.Start.Character) + 1,
+			Kind:   kind,
+		}
+		followed := false
+		for _, n := range next {
+			if key := locationKey(n); !visited[key] {
+				visited[key] = true
+				followed = true
+				via := append(append([]definitionHop(nil), f.via...), hop)
+				queue = append(queue, followedLocation{loc: n, via: via})
+			}
+		}
+		if !followed {
+			done = append(done, f)
+		}
+	}
+	return done
+}
+
+// nextDefinitions returns where a location leads: through the declaration
+// map of a declaration file, or, on a re-export line, to the definition of
+// the re-exported name. It returns nothing when the location is final.
+func nextDefinitions(ctx context.Context, backend definitionBackend, sync func(file string) error, loc protocol.Location) ([]protocol.Location, string) {
+	file := docsync.URIToFile(string(loc.URI))
+	if isDeclarationFile(file) {
+		if original, ok := declarationMapOriginal(file, loc.Range.Start); ok {
+			return []protocol.Location{original}, hopDeclarationMap
+		}
+		return nil, ""
+	}
+	line := int(loc.Range.Start.Line) + 1
+	text, err := readLine(file, line)
+	if err != nil || !reexportPattern.MatchString(text) {
+		return nil, ""
+	}
+	if err := sync(file); err != nil {
+		return nil, ""
+	}
+	next, err := backend.Definition(ctx, file, line, int(loc.Range.Start.Character)+1)
+	if err != nil {
+		return nil, ""
+	}
+	return next, hopReexport
+}
+
+// declarationMapOriginal maps a position in a declaration file through the
+// adjacent declaration map (file + ".map") to the original source, when
+// both exist.
+func declarationMapOriginal(file string, pos protocol.Position) (protocol.Location, bool) {
+	data, err := os.ReadFile(file + ".map")
+	if err != nil {
+		return protocol.Location{}, false
+	}
+	m, err := sourcemap.Parse(data)
+	if err != nil {
+		return protocol.Location{}, false
+	}
+	source, line, col, ok := m.Original(int(pos.Line), int(pos.Character))
+	if !ok {
+		return protocol.Location{}, false
+	}
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(filepath.Dir(file), filepath.FromSlash(source))
+	}
+	if _, err := os.Stat(source); err != nil {
+		return protocol.Location{}, false
+	}
+	start := protocol.Position{Line: uint32(line), Character: uint32(col)}
+	return protocol.Location{
+		URI:   protocol.DocumentURI(docsync.FileToURI(source)),
+		Range: protocol.Range{Start: start, End: start},
+	}, true
+}
+
+func locationKey(loc protocol.Location) string {
+	return fmt.Sprintf("%s:%d:%d", loc.URI, loc.Range.Start.Line, loc.Range.Start.Character)
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+)
+
+// fakeDefinitionBackend answers definitions from a map keyed by
+// "file:line:col", 1-based.
+type fakeDefinitionBackend map[string][]protocol.Location
+
+func (f fakeDefinitionBackend) Definition(_ context.Context, file string, line, col int) ([]protocol.Location, error) {
+	return f[fmt.Sprintf("%s:%d:%d", file, line, col)], nil
+}
+
+// fileLocation is the location of line and col, 1-based, in file.
+func fileLocation(file string, line, col int) protocol.Location {
+	pos := protocol.Position{Line: uint32(line - 1), Character: uint32(col - 1)}
+	return protocol.Location{URI: protocol.DocumentURI(docsync.FileToURI(file)), Range: protocol.Range{Start: pos, End: pos}}
+}
+
+func TestFollowDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) string {
+		p := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	repo := write("src/repo.ts", "export function createRepo() {}\n")
+	barrel := write("src/index.ts", "export { createRepo } from \"./repo\";\n")
+	decl := write("dist/index.d.ts", "export declare function greet(name: string): string;\n")
+	write("dist/index.d.ts.map", `{"version":3,"file":"index.d.ts","sourceRoot":"","sources":["../src/greet.ts"],"names":[],"mappings":"AAAA,wBAAgB,KAAK,CAAC,IAAI,EAAE,MAAM,GAAG,MAAM,CAE1C"}`)
+	greet := write("src/greet.ts", "export function greet(name: string): string {\n  return name;\n}\n")
+	unmapped := write("types/lib.d.ts", "export declare const x: number;\n")
+	cycleA := write("src/a.ts", "export { loop } from \"./b\";\n")
+	cycleB := write("src/b.ts", "export { loop } from \"./a\";\n")
+	var chain []string
+	for i := range 6 {
+		chain = append(chain, write(fmt.Sprintf("src/chain%d.ts", i), fmt.Sprintf("export { deep } from \"./chain%d\";\n", i+1)))
+	}
+	t.Cleanup(func() { forgetCachedLines(append([]string{barrel, cycleA, cycleB, repo, greet}, chain...)...) })
+
+	backend := fakeDefinitionBackend{
+		barrel + ":1:10": {fileLocation(repo, 1, 17)},
+		cycleA + ":1:10": {fileLocation(cycleB, 1, 10)},
+		cycleB + ":1:10": {fileLocation(cycleA, 1, 10)},
+	}
+	for i := range 5 {
+		backend[chain[i]+":1:10"] = []protocol.Location{fileLocation(chain[i+1], 1, 10)}
+	}
+	var synced []string
+	syncFile := func(f string) error {
+		synced = append(synced, f)
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		locs     []protocol.Location
+		want     []protocol.Location
+		wantVias [][]string // "file:line kind" of each hop
+	}{
+		{
+			name:     "re-export",
+			locs:     []protocol.Location{fileLocation(barrel, 1, 10)},
+			want:     []protocol.Location{fileLocation(repo, 1, 17)},
+			wantVias: [][]string{{barrel + ":1 re-export"}},
+		},
+		{
+			name:     "declaration map",
+			locs:     []protocol.Location{fileLocation(decl, 1, 25)},
+			want:     []protocol.Location{fileLocation(greet, 1, 17)},
+			wantVias: [][]string{{decl + ":1 declaration map"}},
+		},
+		{
+			name:     "declaration file without a map",
+			locs:     []protocol.Location{fileLocation(unmapped, 1, 22)},
+			want:     []protocol.Location{fileLocation(unmapped, 1, 22)},
+			wantVias: [][]string{nil},
+		},
+		{
+			name:     "cycle",
+			locs:     []protocol.Location{fileLocation(cycleA, 1, 10)},
+			want:     []protocol.Location{fileLocation(cycleB, 1, 10)},
+			wantVias: [][]string{{cycleA + ":1 re-export"}},
+		},
+		{
+			name: "hop limit",
+			locs: []protocol.Location{fileLocation(chain[0], 1, 10)},
+			want: []protocol.Location{fileLocation(chain[maxDefinitionHops], 1, 10)},
+			wantVias: [][]string{{
+				chain[0] + ":1 re-export", chain[1] + ":1 re-export", chain[2] + ":1 re-export", chain[3] + ":1 re-export",
+			}},
+		},
+		{
+			name:     "merged",
+			locs:     []protocol.Location{fileLocation(barrel, 1, 10), fileLocation(repo, 1, 17)},
+			want:     []protocol.Location{fileLocation(repo, 1, 17)},
+			wantVias: [][]string{{barrel + ":1 re-export"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, vias := followDefinitions(context.Background(), backend, syncFile, tt.locs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d locations %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if locationKey(got[i]) != locationKey(tt.want[i]) {
+					t.Errorf("location %d = %s, want %s", i, locationKey(got[i]), locationKey(tt.want[i]))
+				}
+				var hops []string
+				for _, h := range vias[i] {
+					hops = append(hops, fmt.Sprintf("%s:%d %s", h.File, h.Line, h.Kind))
+				}
+				if fmt.Sprint(hops) != fmt.Sprint(tt.wantVias[i]) {
+					t.Errorf("via %d = %v, want %v", i, hops, tt.wantVias[i])
+				}
+			}
+		})
+	}
+	if len(synced) == 0 || synced[0] != barrel {
+		t.Errorf("synced %v before asking for definitions", synced)
+	}
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+type formatResult struct {
+	File string `json:"file"`
+	// Edits is the number of edits tsgo returned; they may leave the file
+	// as it was.
+	Edits   int  `json:"edits"`
+	Changed bool `json:"changed"`
+}
+
+// detectIndent guesses the indentation of content from its first indented
+// line: a tab, or that line's run of spaces. Without one it is 4 spaces,
+// TypeScript's default.
+func detectIndent(content []byte) (tabSize int, insertSpaces bool) {
+	for _, line := range bytes.Split(content, []byte{'\n'}) {
+		switch {
+		case len(line) == 0:
+		case line[0] == '\t':
+			return 4, false
+		case line[0] == ' ':
+			n := len(line) - len(bytes.TrimLeft(line, " "))
+			if n < len(line) && n <= 8 && line[n] != '*' {
+				return n, true
+			}
+		}
+	}
+	return 4, true
+}
+
+func makeFormatHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, overlayCheck bool, journal *journalPolicy, recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		startLine := request.GetInt("startLine", 0)
+		endLine := request.GetInt("endLine", startLine)
+		if startLine == 0 && endLine != 0 {
+			return mcp.NewToolResultError("endLine needs startLine"), nil
+		}
+
+		original, err := os.ReadFile(file)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("read error: %v", err)), nil
+		}
+		tabSize, insertSpaces := detectIndent(original)
+		tabSize = request.GetInt("tabSize", tabSize)
+		if tabSize < 1 {
+			return mcp.NewToolResultError("tabSize must be at least 1"), nil
+		}
+		opts := protocol.FormattingOptions{
+			TabSize:      uint32(tabSize),
+			InsertSpaces: request.GetBool("insertSpaces", insertSpaces),
+		}
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		var edits []protocol.TextEdit
+		if startLine != 0 {
+			edits, err = client.RangeFormatting(ctx, file, startLine, endLine, opts)
+		} else {
+			edits, err = client.Formatting(ctx, file, opts)
+		}
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("formatting is unavailable: %v", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("format error: %v", err)), nil
+		}
+
+		result := formatResult{File: file, Edits: len(edits)}
+		updated, err := edit.ApplyTextEdits(original, edits)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
+		}
+		if !bytes.Equal(updated, original) {
+			var gate editGate
+			if overlayCheck {
+				gate = overlayGate(ctx, client, docs)
+			}
+			edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				protocol.DocumentURI(docsync.FileToURI(file)): edits,
+			}}}
+			if _, err := applyWorkspaceEdit(edit, docs, gate, journal, recorder.recording(editOrigin{tool: request.Params.Name})); err != nil {
+				resyncStale(ctx, client, docs, err)
+				return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
+			}
+			result.Changed = true
+			pending.InvalidateFiles([]string{file})
+			if err := docs.ResyncFile(ctx, client.Conn(), file); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("re-sync error for %s: %v", file, err)), nil
+			}
+			ClearFileCache()
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,25 @@
+package tools
+
+import "testing"
+
+func TestDetectIndent(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		tabSize      int
+		insertSpaces bool
+	}{
+		{"two spaces", "function f() {\n  return 1;\n}\n", 2, true},
+		{"tabs", "function f() {\n\treturn 1;\n}\n", 4, false},
+		{"doc comment continuation", "/**\n * Doc.\n */\nfunction f() {\n    return 1;\n}\n", 4, true},
+		{"flat", "export const x = 1;\n", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tabSize, insertSpaces := detectIndent([]byte(tt.content))
+			if tabSize != tt.tabSize || insertSpaces != tt.insertSpaces {
+				t.Errorf("detectIndent = %d, %v; want %d, %v", tabSize, insertSpaces, tt.tabSize, tt.insertSpaces)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const noHighlights = "No occurrences found"
+
+type highlightEntry struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Kind is "read", "write" or "text" for a textual match tsgo could
+	// not classify.
+	Kind    string `json:"kind"`
+	Preview string `json:"preview,omitempty"`
+	// VisualColumn is Column with tabs expanded, in columnMode "visual".
+	VisualColumn int `json:"visualColumn,omitempty"`
+}
+
+type documentHighlightsResult struct {
+	File       string           `json:"file"`
+	Highlights []highlightEntry `json:"highlights"`
+}
+
+// highlightKindName returns the name of a highlight kind; a missing kind
+// means a textual match.
+func highlightKindName(k protocol.DocumentHighlightKind) string {
+	switch k {
+	case protocol.DocumentHighlightKindRead:
+		return "read"
+	case protocol.DocumentHighlightKindWrite:
+		return "write"
+	}
+	return "text"
+}
+
+// highlightEntries converts highlights of file in position order.
+func highlightEntries(file string, highlights []protocol.DocumentHighlight, cols columnMode) []highlightEntry {
+	entries := make([]highlightEntry, 0, len(highlights))
+	for _, h := range highlights {
+		e := highlightEntry{
+			Line:   int(h.Range.Start.Line) + 1,
+			Column: int(h.Range.Start.Character) + 1,
+			Kind:   highlightKindName(h.Kind),
+		}
+		if text, err := readLine(file, e.Line); err == nil {
+			e.Preview = strings.TrimSpace(text)
+		}
+		e.VisualColumn = cols.visualColumn(file, e.Line, e.Column)
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Line != entries[j].Line {
+			return entries[i].Line < entries[j].Line
+		}
+		return entries[i].Column < entries[j].Column
+	})
+	return entries
+}
+
+func makeDocumentHighlightsHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		highlights, err := client.DocumentHighlight(ctx, file, line, col)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("document highlights are unavailable: %v; use ts_references instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("document highlight error: %v", err)), nil
+		}
+		if len(highlights) == 0 {
+			return mcp.NewToolResultText(noHighlights), nil
+		}
+
+		result := documentHighlightsResult{File: file, Highlights: highlightEntries(file, highlights, cols)}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestHighlightEntries(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.ts")
+	src := "let count = 0;\ncount += 1;\nconsole.log(count);\n"
+	if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ClearFileCache)
+	highlights := []protocol.DocumentHighlight{
+		{Range: protocol.Range{Start: protocol.Position{Line: 2, Character: 12}}, Kind: protocol.DocumentHighlightKindRead},
+		{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 4}}, Kind: protocol.DocumentHighlightKindWrite},
+		{Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 0}}, Kind: protocol.DocumentHighlightKindWrite},
+		{Range: protocol.Range{Start: protocol.Position{Line: 0, Character: 0}}},
+	}
+
+	got := highlightEntries(file, highlights, columnMode{})
+	want := []highlightEntry{
+		{Line: 1, Column: 1, Kind: "text", Preview: "let count = 0;"},
+		{Line: 1, Column: 5, Kind: "write", Preview: "let count = 0;"},
+		{Line: 2, Column: 1, Kind: "write", Preview: "count += 1;"},
+		{Line: 3, Column: 13, Kind: "read", Preview: "console.log(count);"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -2,11 +2,15 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
 	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
 	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
 )
@@ -25,29 +29,167 @@ func makeHoverHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHand
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+		format := request.GetString("format", "concise")
+		if format != "concise" && format != "full" {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid format %q (valid: concise, full)", format)), nil
+		}
 
+		defer docs.Pin(file)()
 		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
 		}
 
-		hover, err := client.Hover(ctx, file, line, col)
+		if format == "full" {
+			hover, note, err := hoverAt(ctx, client, file, line, col)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("hover error: %v", err)), nil
+			}
+			if hover == nil || strings.TrimSpace(hover.Contents.Value) == "" {
+				return mcp.NewToolResultText("No type information available"), nil
+			}
+			doc := parseFullHover(hover.Contents)
+			doc.Note = strings.TrimSpace(note)
+			data, err := json.MarshalIndent(doc, "", "  ")
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(data)), nil
+		}
+
+		content, note, err := hoverText(ctx, client, file, line, col)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("hover error: %v", err)), nil
 		}
-
-		if hover == nil {
+		if content == "" {
 			return mcp.NewToolResultText("No type information available"), nil
 		}
+		return mcp.NewToolResultText(note + content), nil
+	}
+}
+
+// hoverBackend is the subset of *lsp.Client the hover tools need.
+type hoverBackend interface {
+	Hover(ctx context.Context, file string, line, col int) (*protocol.Hover, error)
+}
+
+// hoverAt returns the hover of a position, or nil. Inside a JSDoc
+// annotation it retries at the type name; note then explains the retry.
+func hoverAt(ctx context.Context, backend hoverBackend, file string, line, col int) (hover *protocol.Hover, note string, err error) {
+	hover, err = backend.Hover(ctx, file, line, col)
+	if err != nil {
+		return nil, "", err
+	}
 
-		// Extract the content, keeping it concise
-		content := hover.Contents.Value
-		// If markdown, trim to just the type signature (first code block or first paragraph)
-		if hover.Contents.Kind == "markdown" {
-			content = extractConciseHover(content)
+	// Inside a JSDoc annotation only the type name itself has type
+	// information; retry there when the exact position has none.
+	if hover == nil || strings.TrimSpace(hover.Contents.Value) == "" {
+		if typeCol, ok := jsdocRetryColumn(file, line, col); ok {
+			retry, err := backend.Hover(ctx, file, line, typeCol)
+			if err == nil && retry != nil && strings.TrimSpace(retry.Contents.Value) != "" {
+				hover = retry
+				note = fmt.Sprintf("(no type information at column %d; showing the JSDoc type name at column %d)\n", col, typeCol)
+			}
 		}
+	}
+	return hover, note, nil
+}
 
-		return mcp.NewToolResultText(content), nil
+// hoverText returns the concise hover of a position, or "" when there is
+// no type information. note explains a retry at another column.
+func hoverText(ctx context.Context, backend hoverBackend, file string, line, col int) (content, note string, err error) {
+	hover, note, err := hoverAt(ctx, backend, file, line, col)
+	if err != nil {
+		return "", "", err
+	}
+	if hover == nil {
+		return "", "", nil
+	}
+
+	// Extract the content, keeping it concise
+	content = hover.Contents.Value
+	// If markdown, trim to just the type signature (first code block or first paragraph)
+	if hover.Contents.Kind == "markdown" {
+		content = extractConciseHover(content)
+	}
+	return content, note, nil
+}
+
+// hoverTag is a JSDoc tag of a full hover, such as @param or @deprecated.
+type hoverTag struct {
+	Tag string `json:"tag"`
+	// Name is the parameter a @param or @template tag describes.
+	Name string `json:"name,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// fullHover is the result of ts_hover in format "full".
+type fullHover struct {
+	// Signature is the first code block of the hover, "" when it has none.
+	Signature string `json:"signature"`
+	// Documentation is the rest of the hover's markdown, without the tags.
+	Documentation string     `json:"documentation"`
+	Tags          []hoverTag `json:"tags"`
+	// Note explains a retry at the JSDoc type name.
+	Note string `json:"note,omitempty"`
+}
+
+// hoverTagPattern matches a JSDoc tag line of a hover, "*@param* `name` —
+// text", with the name and text optional.
+var hoverTagPattern = regexp.MustCompile("^\\*@([\\w-]+)\\*(?:\\s+`([^`]*)`)?(?:\\s*(?:—|-|–)?\\s*(.*))?$")
+
+// parseFullHover splits hover contents into the signature, the
+// documentation and the JSDoc tags. Plain text hovers are documentation;
+// code blocks after the first one, such as other overloads, stay in it.
+// A tag's text runs to the next blank line.
+func parseFullHover(contents protocol.MarkupContent) fullHover {
+	doc := fullHover{Tags: []hoverTag{}}
+	if contents.Kind != protocol.Markdown {
+		doc.Documentation = strings.TrimSpace(contents.Value)
+		return doc
+	}
+	var signature, rest []string
+	inSignature, signatureDone, inBlock := false, false, false
+	var tag *hoverTag
+	for _, line := range strings.Split(contents.Value, "\n") {
+		trimmed := strings.TrimSpace(line)
+		fence := strings.HasPrefix(trimmed, "```")
+		if !signatureDone && (fence || inSignature) {
+			switch {
+			case !fence:
+				signature = append(signature, line)
+			case inSignature:
+				inSignature, signatureDone = false, true
+			default:
+				inSignature = true
+			}
+			continue
+		}
+		if fence {
+			inBlock = !inBlock
+		}
+		if !inBlock && !fence {
+			if m := hoverTagPattern.FindStringSubmatch(trimmed); m != nil {
+				doc.Tags = append(doc.Tags, hoverTag{Tag: m[1], Name: m[2], Text: strings.TrimSpace(m[3])})
+				tag = &doc.Tags[len(doc.Tags)-1]
+				continue
+			}
+			if tag != nil && trimmed != "" {
+				tag.Text = strings.TrimSpace(tag.Text + "\n" + trimmed)
+				continue
+			}
+		}
+		tag = nil
+		rest = append(rest, line)
 	}
+	// An unclosed first block is the signature all the same.
+	doc.Signature = strings.Join(signature, "\n")
+	doc.Documentation = strings.TrimSpace(strings.Join(rest, "\n"))
+	return doc
 }
 
 // extractConciseHover extracts the type signature from markdown hover content.
@@ -1,6 +1,11 @@
 package tools
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
 
 func TestExtractConciseHover(t *testing.T) {
 	tests := []struct {
@@ -39,3 +44,74 @@ func TestExtractConciseHover(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFullHover(t *testing.T) {
+	md := func(s string) protocol.MarkupContent {
+		return protocol.MarkupContent{Kind: protocol.Markdown, Value: s}
+	}
+	tests := []struct {
+		name     string
+		contents protocol.MarkupContent
+		want     fullHover
+	}{
+		{
+			name: "signature, documentation and tags",
+			contents: md("```typescript\nfunction greet(name: string, loud?: boolean): string\n```\nGreets someone by name.\n\n" +
+				"Falls back to \"friend\".\n\n*@param* `name` — who to greet\n\n*@param* `loud` — shout it,\nin capitals\n\n*@returns* — the greeting\n\n*@deprecated*"),
+			want: fullHover{
+				Signature:     "function greet(name: string, loud?: boolean): string",
+				Documentation: "Greets someone by name.\n\nFalls back to \"friend\".",
+				Tags: []hoverTag{
+					{Tag: "param", Name: "name", Text: "who to greet"},
+					{Tag: "param", Name: "loud", Text: "shout it,\nin capitals"},
+					{Tag: "returns", Text: "the greeting"},
+					{Tag: "deprecated"},
+				},
+			},
+		},
+		{
+			name:     "no code block",
+			contents: md("A plain description.\n\n*@see* https://example.com"),
+			want: fullHover{
+				Documentation: "A plain description.",
+				Tags:          []hoverTag{{Tag: "see", Text: "https://example.com"}},
+			},
+		},
+		{
+			name: "overloads",
+			contents: md("```typescript\nfunction parse(s: string): number (+1 overload)\n```\nParses a number.\n\n" +
+				"```typescript\nfunction parse(n: number): number\n```"),
+			want: fullHover{
+				Signature:     "function parse(s: string): number (+1 overload)",
+				Documentation: "Parses a number.\n\n```typescript\nfunction parse(n: number): number\n```",
+				Tags:          []hoverTag{},
+			},
+		},
+		{
+			name:     "tag-like line in a later code block",
+			contents: md("```ts\nconst x: number\n```\n```\n*@param* `y` — not a tag\n```"),
+			want: fullHover{
+				Signature:     "const x: number",
+				Documentation: "```\n*@param* `y` — not a tag\n```",
+				Tags:          []hoverTag{},
+			},
+		},
+		{
+			name:     "unclosed code block",
+			contents: md("```ts\ninterface Foo {\n  bar: string;"),
+			want:     fullHover{Signature: "interface Foo {\n  bar: string;", Tags: []hoverTag{}},
+		},
+		{
+			name:     "plain text",
+			contents: protocol.MarkupContent{Kind: protocol.PlainText, Value: "const x: number\n"},
+			want:     fullHover{Documentation: "const x: number", Tags: []hoverTag{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseFullHover(tt.contents); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFullHover =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const (
+	// maxHoverBatch is the most positions one ts_hover_batch call hovers.
+	maxHoverBatch = 50
+	// hoverBatchWorkers bounds the hovers in flight for one call.
+	hoverBatchWorkers = 8
+	// maxAnnotationLen bounds each hover in a rendered annotation.
+	maxAnnotationLen = 120
+)
+
+// hoverPosition is a position to hover, with the identifier found there
+// when the position came from an identifier scan.
+type hoverPosition struct {
+	Line       int
+	Column     int
+	Identifier string
+}
+
+// hoverBatchEntry is the hover of one position. Line and Column are as
+// the caller gave them, or where the identifier scan found Identifier.
+type hoverBatchEntry struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// VisualColumn is the column with tabs expanded, in columnMode
+	// "visual".
+	VisualColumn int    `json:"visualColumn,omitempty"`
+	Identifier   string `json:"identifier,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Note         string `json:"note,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type hoverBatchResult struct {
+	File    string            `json:"file"`
+	Results []hoverBatchEntry `json:"results"`
+}
+
+// hoverPositions hovers every position concurrently, at most
+// hoverBatchWorkers at a time. The entries are in the order of positions;
+// a failed hover only sets the Error of its own entry. chars are the
+// character columns to query, aligned with positions.
+func hoverPositions(ctx context.Context, backend hoverBackend, file string, positions []hoverPosition, chars []int) []hoverBatchEntry {
+	entries := make([]hoverBatchEntry, len(positions))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, hoverBatchWorkers)
+	for i, p := range positions {
+		entries[i] = hoverBatchEntry{Line: p.Line, Column: p.Column, Identifier: p.Identifier}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			entries[i].Type, entries[i].Note, entries[i].Error = hoverOne(ctx, backend, file, p.Line, chars[i])
+		}()
+	}
+	wg.Wait()
+	return entries
+}
+
+// hoverOne returns the concise type at a position and its note, or the
+// message of why there is none.
+func hoverOne(ctx context.Context, backend hoverBackend, file string, line, char int) (typ, note, errMsg string) {
+	content, note, err := hoverText(ctx, backend, file, line, char)
+	switch {
+	case err != nil:
+		return "", "", fmt.Sprintf("hover error: %v", err)
+	case content == "":
+		return "", "", "no type information available"
+	}
+	return content, strings.TrimSuffix(note, "\n"), ""
+}
+
+// scanIdentifiers returns the positions of whole-word occurrences of
+// identifiers in lines first to last (1-based) of lines, in source order.
+func scanIdentifiers(lines []string, first, last int, identifiers []string) []hoverPosition {
+	var out []hoverPosition
+	for n := first; n <= last && n <= len(lines); n++ {
+		line := lines[n-1]
+		var found []hoverPosition
+		for _, id := range identifiers {
+			for _, off := range findWordOccurrences(line, id) {
+				found = append(found, hoverPosition{Line: n, Column: int(byteOffsetToUTF16Col(line, off)) + 1, Identifier: id})
+			}
+		}
+		slices.SortStableFunc(found, func(a, b hoverPosition) int { return a.Column - b.Column })
+		out = append(out, found...)
+	}
+	return out
+}
+
+// renderHoverAnnotations returns the given lines of the file, 1-based and
+// ascending, each followed by the hovers on it as a trailing comment.
+// Non-adjacent lines are separated by a "// ..." line. Failed hovers are
+// left out.
+func renderHoverAnnotations(lines []string, lineNums []int, entries []hoverBatchEntry) string {
+	byLine := make(map[int][]string)
+	for _, e := range entries {
+		if e.Type == "" {
+			continue
+		}
+		text := strings.Join(strings.Fields(e.Type), " ")
+		if r := []rune(text); len(r) > maxAnnotationLen {
+			text = string(r[:maxAnnotationLen]) + "…"
+		}
+		if !slices.Contains(byLine[e.Line], text) {
+			byLine[e.Line] = append(byLine[e.Line], text)
+		}
+	}
+	var b strings.Builder
+	for i, n := range lineNums {
+		if n < 1 || n > len(lines) {
+			continue
+		}
+		if i > 0 && n > lineNums[i-1]+1 {
+			b.WriteString("// ...\n")
+		}
+		b.WriteString(lines[n-1])
+		if notes := byLine[n]; len(notes) > 0 {
+			b.WriteString("  // ")
+			b.WriteString(strings.Join(notes, "; "))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// parseHoverPositions reads the positions argument, an array of
+// {line, column} objects.
+func parseHoverPositions(raw any) ([]hoverPosition, error) {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("positions must be an array of {line, column} objects")
+	}
+	out := make([]hoverPosition, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("positions[%d] must be a {line, column} object", i)
+		}
+		line, lok := m["line"].(float64)
+		col, cok := m["column"].(float64)
+		if !lok || !cok || line < 1 || col < 1 {
+			return nil, fmt.Errorf("positions[%d] needs a line and column of at least 1", i)
+		}
+		out = append(out, hoverPosition{Line: int(line), Column: int(col)})
+	}
+	return out, nil
+}
+
+func makeHoverBatchHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		rawPositions, hasPositions := request.GetArguments()["positions"]
+		startLine := request.GetInt("startLine", 0)
+		identifiers := request.GetStringSlice("identifiers", nil)
+		if hasPositions == (startLine != 0) {
+			return mcp.NewToolResultError("pass either positions, or startLine with identifiers"), nil
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("read error: %v", err)), nil
+		}
+		lines := strings.Split(string(content), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimSuffix(l, "\r")
+		}
+
+		var positions []hoverPosition
+		var rendered []int
+		if hasPositions {
+			if positions, err = parseHoverPositions(rawPositions); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			seen := make(map[int]bool)
+			for _, p := range positions {
+				if !seen[p.Line] {
+					seen[p.Line] = true
+					rendered = append(rendered, p.Line)
+				}
+			}
+			slices.Sort(rendered)
+		} else {
+			endLine := request.GetInt("endLine", startLine)
+			if startLine < 1 || endLine < startLine || endLine > len(lines) {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid line range %d-%d (the file has %d lines)", startLine, endLine, len(lines))), nil
+			}
+			if len(identifiers) == 0 {
+				return mcp.NewToolResultError("identifiers is required with startLine"), nil
+			}
+			positions = scanIdentifiers(lines, startLine, endLine, identifiers)
+			for n := startLine; n <= endLine; n++ {
+				rendered = append(rendered, n)
+			}
+		}
+		if len(positions) > maxHoverBatch {
+			return mcp.NewToolResultError(fmt.Sprintf("%d positions; at most %d per call: narrow the range or the identifiers", len(positions), maxHoverBatch)), nil
+		}
+
+		// Scanned columns are character columns already.
+		chars := make([]int, len(positions))
+		for i, p := range positions {
+			chars[i] = p.Column
+			if cols.visual && p.Identifier == "" && p.Line <= len(lines) {
+				chars[i] = visualToCharColumn(lines[p.Line-1], p.Column, cols.tabWidth)
+			}
+		}
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+		entries := hoverPositions(ctx, client, file, positions, chars)
+		for i := range entries {
+			entries[i].VisualColumn = cols.visualColumn(file, entries[i].Line, chars[i])
+		}
+
+		if request.GetBool("render", false) {
+			return mcp.NewToolResultText(renderHoverAnnotations(lines, rendered, entries)), nil
+		}
+		data, err := json.MarshalIndent(hoverBatchResult{File: file, Results: entries}, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
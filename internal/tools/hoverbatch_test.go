@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// fakeHoverBackend answers hovers from a script keyed by "line:col",
+// earlier lines more slowly, and records the peak concurrency.
+type fakeHoverBackend struct {
+	script map[string]string // "" means no hover
+	errs   map[string]error
+
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (f *fakeHoverBackend) Hover(_ context.Context, _ string, line, col int) (*protocol.Hover, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+	time.Sleep(time.Duration(20-line%20) * time.Millisecond)
+
+	key := fmt.Sprintf("%d:%d", line, col)
+	if err := f.errs[key]; err != nil {
+		return nil, err
+	}
+	text, ok := f.script[key]
+	if !ok || text == "" {
+		return nil, nil
+	}
+	return &protocol.Hover{Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: "```ts\n" + text + "\n```"}}, nil
+}
+
+func TestHoverPositions(t *testing.T) {
+	backend := &fakeHoverBackend{
+		script: map[string]string{},
+		errs:   map[string]error{"3:1": errors.New("request timed out")},
+	}
+	var positions []hoverPosition
+	var chars []int
+	for line := 1; line <= 30; line++ {
+		backend.script[fmt.Sprintf("%d:1", line)] = fmt.Sprintf("const v%d: number", line)
+		positions = append(positions, hoverPosition{Line: line, Column: 1})
+		chars = append(chars, 1)
+	}
+	backend.script["5:1"] = ""
+
+	entries := hoverPositions(context.Background(), backend, "/nonexistent/a.ts", positions, chars)
+	if len(entries) != len(positions) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(positions))
+	}
+	for i, e := range entries {
+		if e.Line != i+1 {
+			t.Fatalf("entry %d is for line %d; results are out of input order", i, e.Line)
+		}
+		switch e.Line {
+		case 3:
+			if e.Error != "hover error: request timed out" || e.Type != "" {
+				t.Errorf("failed hover = %+v", e)
+			}
+		case 5:
+			if e.Error != "no type information available" {
+				t.Errorf("empty hover = %+v", e)
+			}
+		default:
+			if want := fmt.Sprintf("const v%d: number", e.Line); e.Type != want || e.Error != "" {
+				t.Errorf("entry = %+v, want type %q", e, want)
+			}
+		}
+	}
+	if backend.peak > hoverBatchWorkers || backend.peak < 2 {
+		t.Errorf("peak concurrency %d, want 2..%d", backend.peak, hoverBatchWorkers)
+	}
+}
+
+func TestScanIdentifiers(t *testing.T) {
+	lines := []string{
+		"function add(a: number, b: number) {",
+		"\tconst sum = a + b; // a, b",
+		"\treturn sumOf(sum, \"€\", b);",
+		"}",
+	}
+	got := scanIdentifiers(lines, 2, 3, []string{"sum", "b"})
+	want := []hoverPosition{
+		{Line: 2, Column: 8, Identifier: "sum"},
+		{Line: 2, Column: 18, Identifier: "b"},
+		{Line: 2, Column: 27, Identifier: "b"},
+		{Line: 3, Column: 15, Identifier: "sum"},
+		{Line: 3, Column: 25, Identifier: "b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("positions = %+v\nwant %+v", got, want)
+	}
+	if got := scanIdentifiers(lines, 4, 9, []string{"sum"}); got != nil {
+		t.Errorf("positions past the end = %+v", got)
+	}
+}
+
+func TestRenderHoverAnnotations(t *testing.T) {
+	lines := []string{"const a = 1;", "const b = a;", "", "let c = b;", "}"}
+	entries := []hoverBatchEntry{
+		{Line: 2, Column: 7, Type: "const b: 1"},
+		{Line: 2, Column: 11, Type: "const a: 1"},
+		{Line: 4, Column: 5, Type: "let c:\n  number"},
+		{Line: 4, Column: 5, Type: "let c:\n  number"},
+		{Line: 4, Column: 9, Error: "hover error: boom"},
+	}
+	got := renderHoverAnnotations(lines, []int{1, 2, 4}, entries)
+	want := "const a = 1;\n" +
+		"const b = a;  // const b: 1; const a: 1\n" +
+		"// ...\n" +
+		"let c = b;  // let c: number\n"
+	if got != want {
+		t.Errorf("rendered:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestParseHoverPositions(t *testing.T) {
+	got, err := parseHoverPositions([]any{
+		map[string]any{"line": float64(2), "column": float64(5)},
+		map[string]any{"line": float64(1), "column": float64(1)},
+	})
+	if err != nil || !reflect.DeepEqual(got, []hoverPosition{{Line: 2, Column: 5}, {Line: 1, Column: 1}}) {
+		t.Errorf("positions = %+v, %v", got, err)
+	}
+	for _, bad := range []any{
+		"2:5",
+		[]any{map[string]any{"line": float64(2)}},
+		[]any{map[string]any{"line": float64(0), "column": float64(1)}},
+		[]any{float64(3)},
+	} {
+		if _, err := parseHoverPositions(bad); err == nil {
+			t.Errorf("parseHoverPositions(%v) accepted", bad)
+		}
+	}
+}
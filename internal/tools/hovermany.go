@@ -0,0 +1,147 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// maxHoverMany is the most positions one ts_hover_many call hovers.
+const maxHoverMany = 25
+
+// filePosition is a position to hover in any file.
+type filePosition struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// hoverManyEntry is the hover of one position of a ts_hover_many call.
+type hoverManyEntry struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// VisualColumn is the column with tabs expanded, in columnMode
+	// "visual".
+	VisualColumn int    `json:"visualColumn,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Note         string `json:"note,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type hoverManyResult struct {
+	Results []hoverManyEntry `json:"results"`
+}
+
+// parseFilePositions reads the positions argument of ts_hover_many, an
+// array of {file, line, column} objects.
+func parseFilePositions(raw any) ([]filePosition, error) {
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("positions must be an array of {file, line, column} objects")
+	}
+	out := make([]filePosition, 0, len(items))
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("positions[%d] must be a {file, line, column} object", i)
+		}
+		file, _ := m["file"].(string)
+		if file == "" {
+			return nil, fmt.Errorf("positions[%d] needs a file", i)
+		}
+		line, lok := m["line"].(float64)
+		col, cok := m["column"].(float64)
+		if !lok || !cok || line < 1 || col < 1 {
+			return nil, fmt.Errorf("positions[%d] needs a line and column of at least 1", i)
+		}
+		out = append(out, filePosition{File: file, Line: int(line), Column: int(col)})
+	}
+	return out, nil
+}
+
+// hoverFilePositions hovers every position concurrently, at most
+// hoverBatchWorkers at a time, like hoverPositions. Positions in a file
+// of failed, which maps files to why they could not be synced, are not
+// hovered and get that error. chars are the character columns to query.
+func hoverFilePositions(ctx context.Context, backend hoverBackend, positions []filePosition, chars []int, failed map[string]string) []hoverManyEntry {
+	entries := make([]hoverManyEntry, len(positions))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, hoverBatchWorkers)
+	for i, p := range positions {
+		entries[i] = hoverManyEntry{File: p.File, Line: p.Line, Column: p.Column}
+		if msg, ok := failed[p.File]; ok {
+			entries[i].Error = msg
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			entries[i].Type, entries[i].Note, entries[i].Error = hoverOne(ctx, backend, p.File, p.Line, chars[i])
+		}()
+	}
+	wg.Wait()
+	return entries
+}
+
+func makeHoverManyHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		rawPositions, ok := request.GetArguments()["positions"]
+		if !ok {
+			return mcp.NewToolResultError("required argument \"positions\" not found"), nil
+		}
+		positions, err := parseFilePositions(rawPositions)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if len(positions) == 0 {
+			return mcp.NewToolResultError("positions is empty"), nil
+		}
+		if len(positions) > maxHoverMany {
+			return mcp.NewToolResultError(fmt.Sprintf("%d positions; at most %d per call", len(positions), maxHoverMany)), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Each file is synced once, however many of its positions there
+		// are; one that fails only fails its own positions.
+		failed := make(map[string]string)
+		synced := make(map[string]bool)
+		for _, p := range positions {
+			if synced[p.File] {
+				continue
+			}
+			synced[p.File] = true
+			defer docs.Pin(p.File)()
+			if err := docs.SyncFile(ctx, client.Conn(), p.File); err != nil {
+				failed[p.File] = fmt.Sprintf("sync error: %v", err)
+			}
+		}
+
+		chars := make([]int, len(positions))
+		for i, p := range positions {
+			chars[i] = cols.charColumn(p.File, p.Line, p.Column)
+		}
+		entries := hoverFilePositions(ctx, client, positions, chars, failed)
+		for i := range entries {
+			entries[i].VisualColumn = cols.visualColumn(entries[i].File, entries[i].Line, chars[i])
+		}
+
+		data, err := json.MarshalIndent(hoverManyResult{Results: entries}, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestHoverFilePositions(t *testing.T) {
+	backend := &fakeHoverBackend{script: map[string]string{}}
+	var positions []filePosition
+	var chars []int
+	for line := 1; line <= 20; line++ {
+		backend.script[fmt.Sprintf("%d:1", line)] = fmt.Sprintf("const v%d: number", line)
+		file := "/nonexistent/a.ts"
+		if line%2 == 0 {
+			file = "/nonexistent/b.ts"
+		}
+		positions = append(positions, filePosition{File: file, Line: line, Column: 1})
+		chars = append(chars, 1)
+	}
+	positions = append(positions, filePosition{File: "/nonexistent/c.ts", Line: 1, Column: 1})
+	chars = append(chars, 1)
+	failed := map[string]string{"/nonexistent/c.ts": "sync error: no such file"}
+
+	entries := hoverFilePositions(context.Background(), backend, positions, chars, failed)
+	if len(entries) != len(positions) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(positions))
+	}
+	for i, e := range entries[:20] {
+		if e.File != positions[i].File || e.Line != i+1 {
+			t.Fatalf("entry %d is for %s:%d; results are out of input order", i, e.File, e.Line)
+		}
+		if want := fmt.Sprintf("const v%d: number", e.Line); e.Type != want || e.Error != "" {
+			t.Errorf("entry = %+v, want type %q", e, want)
+		}
+	}
+	if last := entries[20]; last.Error != "sync error: no such file" || last.Type != "" {
+		t.Errorf("position in an unsynced file = %+v", last)
+	}
+	if backend.peak > hoverBatchWorkers || backend.peak < 2 {
+		t.Errorf("peak concurrency %d, want 2..%d", backend.peak, hoverBatchWorkers)
+	}
+}
+
+func TestParseFilePositions(t *testing.T) {
+	got, err := parseFilePositions([]any{
+		map[string]any{"file": "/p/b.ts", "line": float64(2), "column": float64(5)},
+		map[string]any{"file": "/p/a.ts", "line": float64(1), "column": float64(1)},
+	})
+	want := []filePosition{{File: "/p/b.ts", Line: 2, Column: 5}, {File: "/p/a.ts", Line: 1, Column: 1}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("positions = %+v, %v", got, err)
+	}
+
+	for _, raw := range []any{
+		"nope",
+		[]any{"nope"},
+		[]any{map[string]any{"line": float64(1), "column": float64(1)}},
+		[]any{map[string]any{"file": "/p/a.ts", "line": float64(0), "column": float64(1)}},
+	} {
+		if _, err := parseFilePositions(raw); err == nil {
+			t.Errorf("parseFilePositions(%#v) succeeded", raw)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/modgraph"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+type cycleEdge struct {
+	// File imports the next file of the cycle at Line with Import.
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Import   string `json:"import"`
+	TypeOnly bool   `json:"typeOnly,omitempty"`
+}
+
+type importCycle struct {
+	Files []string    `json:"files"`
+	Edges []cycleEdge `json:"edges"`
+	// TypeOnly is set when an edge is a type-only import, so the cycle is
+	// erased at runtime.
+	TypeOnly bool `json:"typeOnly,omitempty"`
+}
+
+type importCyclesResult struct {
+	Target       string        `json:"target"`
+	Cycles       []importCycle `json:"cycles"`
+	FilesScanned int           `json:"filesScanned"`
+	Truncated    bool          `json:"truncated"`
+}
+
+// findImportCycles builds the import graph of cfg's project and returns
+// the cycles through target, a file or directory. A target file outside
+// the project is scanned too. Paths in the result are relative to the
+// config's directory.
+func findImportCycles(cfg *tsconfig.Config, target string, opts modgraph.CycleOptions) (importCyclesResult, error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return importCyclesResult{}, err
+	}
+	paths, err := cfg.PathMapper()
+	if err != nil {
+		return importCyclesResult{}, err
+	}
+
+	files := workspace.ProjectFiles(cfg)
+	if !info.IsDir() && !cfg.Includes(target) {
+		files = append(files, target)
+	}
+	g := modgraph.Build(files, modgraph.NewResolver(paths, files), nil)
+
+	var cycles []modgraph.Cycle
+	var truncated bool
+	if info.IsDir() {
+		prefix := filepath.Clean(target) + string(filepath.Separator)
+		cycles, truncated = g.CyclesWithin(func(f string) bool { return strings.HasPrefix(f, prefix) }, opts)
+	} else {
+		cycles, truncated = g.CyclesThrough(target, opts)
+	}
+
+	rel := func(f string) string {
+		if r, err := filepath.Rel(cfg.Dir, f); err == nil {
+			return filepath.ToSlash(r)
+		}
+		return f
+	}
+	result := importCyclesResult{
+		Target:       rel(target),
+		Cycles:       make([]importCycle, len(cycles)),
+		FilesScanned: len(files),
+		Truncated:    truncated,
+	}
+	for i, c := range cycles {
+		ic := importCycle{TypeOnly: c.TypeOnly}
+		for _, e := range c.Edges {
+			ic.Files = append(ic.Files, rel(e.From))
+			ic.Edges = append(ic.Edges, cycleEdge{File: rel(e.From), Line: e.Line, Import: e.Text, TypeOnly: e.TypeOnly})
+		}
+		result.Cycles[i] = ic
+	}
+	return result, nil
+}
+
+func makeImportCyclesHandler(client *lsp.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		target, err := request.RequireString("path")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		opts := modgraph.CycleOptions{
+			MaxLength:       request.GetInt("maxLength", 10),
+			MaxCycles:       request.GetInt("maxCycles", 20),
+			IncludeTypeOnly: request.GetBool("includeTypeOnly", false),
+		}
+		if opts.MaxLength < 1 || opts.MaxCycles < 1 {
+			return mcp.NewToolResultError("maxLength and maxCycles must be at least 1"), nil
+		}
+		configPath := request.GetString("tsconfig", "")
+		if configPath == "" {
+			configPath = filepath.Join(client.RootDir(), "tsconfig.json")
+		}
+
+		cfg, err := tsconfig.Load(configPath)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("tsconfig error: %v", err)), nil
+		}
+		result, err := findImportCycles(cfg, target, opts)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
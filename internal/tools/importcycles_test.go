@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/modgraph"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+func TestFindImportCycles(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", "..", "testdata", "cycles"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := tsconfig.Load(filepath.Join(root, "tsconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := modgraph.CycleOptions{MaxLength: 10, MaxCycles: 20}
+
+	t.Run("file", func(t *testing.T) {
+		res, err := findImportCycles(cfg, filepath.Join(root, "src", "order.ts"), defaults)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Target != "src/order.ts" || res.FilesScanned != 4 || res.Truncated {
+			t.Errorf("result = %+v", res)
+		}
+		want := []importCycle{{
+			Files: []string{"src/order.ts", "src/customer.ts", "src/pricing.ts"},
+			Edges: []cycleEdge{
+				{File: "src/order.ts", Line: 1, Import: `import { Customer } from "./customer";`},
+				{File: "src/customer.ts", Line: 1, Import: `import { loyaltyDiscount } from "./pricing";`},
+				{File: "src/pricing.ts", Line: 2, Import: `import { Order } from "./order";`},
+			},
+		}}
+		if !reflect.DeepEqual(res.Cycles, want) {
+			t.Errorf("cycles = %+v\nwant %+v", res.Cycles, want)
+		}
+	})
+
+	t.Run("directory with type-only imports", func(t *testing.T) {
+		opts := defaults
+		opts.IncludeTypeOnly = true
+		res, err := findImportCycles(cfg, filepath.Join(root, "src"), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][]string
+		for _, c := range res.Cycles {
+			got = append(got, append(c.Files, map[bool]string{true: "type", false: "runtime"}[c.TypeOnly]))
+		}
+		want := [][]string{
+			{"src/customer.ts", "src/pricing.ts", "type"},
+			{"src/customer.ts", "src/pricing.ts", "src/order.ts", "runtime"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("cycles = %v, want %v", got, want)
+		}
+		if e := res.Cycles[0].Edges[1]; !e.TypeOnly || e.Line != 1 {
+			t.Errorf("type-only edge = %+v", e)
+		}
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		if _, err := findImportCycles(cfg, filepath.Join(root, "src", "nope.ts"), defaults); err == nil {
+			t.Error("expected an error for a missing target")
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+type inlayHintEntry struct {
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Label  string `json:"label"`
+	// Kind is "type" for inferred types and "parameter" for parameter
+	// names, or "" when tsgo does not say.
+	Kind string `json:"kind,omitempty"`
+}
+
+type inlayHintsResult struct {
+	File       string           `json:"file"`
+	Hints      []inlayHintEntry `json:"hints"`
+	TotalCount int              `json:"totalCount"`
+	// Truncated is set when there were more than maxResults hints.
+	Truncated bool `json:"truncated"`
+}
+
+func inlayHintKindName(k lsp.InlayHintKind) string {
+	switch k {
+	case lsp.InlayHintKindType:
+		return "type"
+	case lsp.InlayHintKindParameter:
+		return "parameter"
+	}
+	return ""
+}
+
+// inlayHintEntries converts hints in position order, keeping at most max.
+// Labels are trimmed of the padding editors draw around them.
+func inlayHintEntries(file string, hints []lsp.InlayHint, max int) inlayHintsResult {
+	sort.SliceStable(hints, func(i, j int) bool {
+		a, b := hints[i].Position, hints[j].Position
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Character < b.Character
+	})
+	result := inlayHintsResult{File: file, Hints: []inlayHintEntry{}, TotalCount: len(hints)}
+	if len(hints) > max {
+		hints = hints[:max]
+		result.Truncated = true
+	}
+	for _, h := range hints {
+		result.Hints = append(result.Hints, inlayHintEntry{
+			Line:   int(h.Position.Line) + 1,
+			Column: int(h.Position.Character) + 1,
+			Label:  strings.TrimSpace(h.Label),
+			Kind:   inlayHintKindName(h.Kind),
+		})
+	}
+	return result
+}
+
+func makeInlayHintsHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		maxResults := request.GetInt("maxResults", 50)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+		lines, err := cachedReadLines(file)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("read error: %v", err)), nil
+		}
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		startLine := request.GetInt("startLine", 1)
+		endLine := request.GetInt("endLine", len(lines))
+		if startLine < 1 || endLine < startLine {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid line range %d-%d", startLine, endLine)), nil
+		}
+		endLine = min(endLine, len(lines))
+		if startLine > endLine {
+			return mcp.NewToolResultError(fmt.Sprintf("startLine %d is past the end of the file (%d lines)", startLine, len(lines))), nil
+		}
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		hints, err := client.InlayHint(ctx, file, startLine, 1, endLine, utf16Len(lines[endLine-1])+1)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("inlay hints are unavailable: %v; use ts_hover_batch for the types of positions instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("inlay hint error: %v", err)), nil
+		}
+
+		data, err := json.MarshalIndent(inlayHintEntries(file, hints, maxResults), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
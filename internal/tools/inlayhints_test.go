@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestInlayHintEntries(t *testing.T) {
+	hints := []lsp.InlayHint{
+		{Position: protocol.Position{Line: 2, Character: 21}, Label: "name:", Kind: lsp.InlayHintKindParameter, PaddingRight: true},
+		{Position: protocol.Position{Line: 2, Character: 12}, Label: " : string", Kind: lsp.InlayHintKindType},
+		{Position: protocol.Position{Line: 0, Character: 4}, Label: "= 1"},
+	}
+	got := inlayHintEntries("/p/src/a.ts", hints, 2)
+	if got.TotalCount != 3 || !got.Truncated || len(got.Hints) != 2 {
+		t.Fatalf("result = %+v", got)
+	}
+	if h := got.Hints[0]; h != (inlayHintEntry{Line: 1, Column: 5, Label: "= 1"}) {
+		t.Errorf("first hint = %+v", h)
+	}
+	if h := got.Hints[1]; h != (inlayHintEntry{Line: 3, Column: 13, Label: ": string", Kind: "type"}) {
+		t.Errorf("second hint = %+v", h)
+	}
+}
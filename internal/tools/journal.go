@@ -0,0 +1,412 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const (
+	// journalVersion is the manifest layout written by this server. Bump it
+	// when the layout changes incompatibly.
+	journalVersion = 1
+	// defaultJournalThreshold is the number of files an edit must exceed
+	// to be journaled when the config does not say otherwise.
+	defaultJournalThreshold = 50
+	// journalChunkSize is the number of files written between manifest
+	// updates.
+	journalChunkSize = 10
+)
+
+// Recovery actions of ts_recover_pending_edit.
+const (
+	recoverComplete = "complete"
+	recoverRollback = "rollback"
+	recoverDiscard  = "discard"
+)
+
+// journalManifest is the on-disk record of a journaled edit, stored as
+// manifest.json in the edit's directory next to originals/<n> and
+// updated/<n>, the full content of Files[n] before and after the edit.
+// Readers ignore unknown fields and refuse versions newer than their own.
+type journalManifest struct {
+	Version int           `json:"version"`
+	ID      string        `json:"id"`
+	Created time.Time     `json:"created"`
+	Files   []journalFile `json:"files"`
+}
+
+// journalFile is one file of a journaled edit. Written is set once the
+// chunk holding the file has been written.
+type journalFile struct {
+	Path         string      `json:"path"`
+	Mode         os.FileMode `json:"mode"`
+	OriginalHash string      `json:"originalHash"`
+	UpdatedHash  string      `json:"updatedHash"`
+	Written      bool        `json:"written"`
+	// Created is set for a file the edit creates; its original state is
+	// absent, and rollback removes it with NewDirs, the directories made
+	// for it. Deleted is set for a file the edit removes, whose updated
+	// state is absent.
+	Created bool     `json:"created,omitempty"`
+	NewDirs []string `json:"newDirs,omitempty"`
+	Deleted bool     `json:"deleted,omitempty"`
+}
+
+// journalPolicy decides which edits are journaled and where their
+// manifests live.
+type journalPolicy struct {
+	dir       string // <root>/.typescript-mcp/pending
+	threshold int    // edits touching more files than this are journaled
+	always    bool
+	chunkSize int
+	// afterChunk, when set, runs after each chunk's manifest update with
+	// the number of files written so far. An error stops the apply as a
+	// crash would, leaving the journal behind; tests use it.
+	afterChunk func(written int) error
+}
+
+// newJournalPolicy returns the journal policy for the workspace root.
+func newJournalPolicy(root string, cfg *configFile) *journalPolicy {
+	p := &journalPolicy{
+		dir:       filepath.Join(root, ".typescript-mcp", "pending"),
+		threshold: defaultJournalThreshold,
+		always:    cfg.JournalEdits,
+		chunkSize: journalChunkSize,
+	}
+	if cfg.JournalThreshold > 0 {
+		p.threshold = cfg.JournalThreshold
+	}
+	return p
+}
+
+// applies reports whether an edit of files files is journaled.
+func (p *journalPolicy) applies(files int) bool {
+	return p != nil && (p.always || files > p.threshold)
+}
+
+// writeJournaled writes work in chunks, recording progress in a manifest
+// so that an apply cut short by a crash can be completed or rolled back by
+// recoverJournal. A write error or concurrent modification rolls back in
+// place as applyWorkspaceEdit does; on success the journal is removed.
+func writeJournaled(p *journalPolicy, work []edit.File) error {
+	id, err := newEditID(time.Now())
+	if err != nil {
+		return err
+	}
+	m := &journalManifest{
+		Version: journalVersion,
+		ID:      id,
+		Created: time.Now().UTC(),
+	}
+	dir := filepath.Join(p.dir, m.ID)
+	for _, sub := range []string{"originals", "updated"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return fmt.Errorf("creating edit journal: %w", err)
+		}
+	}
+	for i, w := range work {
+		n := strconv.Itoa(i)
+		if err := os.WriteFile(filepath.Join(dir, "originals", n), w.Original, 0o600); err != nil {
+			_ = os.RemoveAll(dir)
+			return fmt.Errorf("journaling %s: %w", w.Path, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "updated", n), w.Updated, 0o600); err != nil {
+			_ = os.RemoveAll(dir)
+			return fmt.Errorf("journaling %s: %w", w.Path, err)
+		}
+		m.Files = append(m.Files, journalFile{
+			Path:         w.Path,
+			Mode:         w.Mode,
+			OriginalHash: hashContent(w.Original),
+			UpdatedHash:  hashContent(w.Updated),
+			Created:      w.Created,
+			NewDirs:      w.NewDirs,
+			Deleted:      w.Deleted,
+		})
+	}
+	if err := saveManifest(dir, m); err != nil {
+		_ = os.RemoveAll(dir)
+		return err
+	}
+
+	chunk := p.chunkSize
+	if chunk <= 0 {
+		chunk = journalChunkSize
+	}
+	for start := 0; start < len(work); start += chunk {
+		end := min(start+chunk, len(work))
+		for i := start; i < end; i++ {
+			if err := edit.WriteFile(work[i], writeOptions()); err != nil {
+				err = edit.Rollback(err, work[:i], writeOptions())
+				_ = os.RemoveAll(dir)
+				return err
+			}
+			m.Files[i].Written = true
+		}
+		if err := saveManifest(dir, m); err != nil {
+			return fmt.Errorf("journaled edit %s: %w", m.ID, err)
+		}
+		if p.afterChunk != nil {
+			if err := p.afterChunk(end); err != nil {
+				return fmt.Errorf("journaled edit %s interrupted after %d of %d files: %w", m.ID, end, len(work), err)
+			}
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// newEditID returns an id for an edit made at t: its UTC time to the
+// nanosecond, so ids sort chronologically, and a random suffix.
+func newEditID(t time.Time) (string, error) {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("generating edit id: %w", err)
+	}
+	return t.UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(b[:]), nil
+}
+
+// saveManifest replaces dir's manifest.json atomically.
+func saveManifest(dir string, m *journalManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding edit manifest: %w", err)
+	}
+	tmp := filepath.Join(dir, "manifest.json.tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing edit manifest: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "manifest.json")); err != nil {
+		return fmt.Errorf("writing edit manifest: %w", err)
+	}
+	return nil
+}
+
+// loadManifest reads the manifest of journal id under dir.
+func loadManifest(dir, id string) (*journalManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, id, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading edit manifest %s: %w", id, err)
+	}
+	var m journalManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing edit manifest %s: %w", id, err)
+	}
+	if m.Version < 1 || m.Version > journalVersion {
+		return nil, fmt.Errorf("edit manifest %s has version %d; this server reads versions up to %d", id, m.Version, journalVersion)
+	}
+	return &m, nil
+}
+
+// pendingJournal summarizes an incomplete journaled edit.
+type pendingJournal struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Files   int       `json:"files"`
+	Written int       `json:"written"`
+	// Error is set when the manifest cannot be read.
+	Error string `json:"error,omitempty"`
+}
+
+// listPendingJournals returns the journaled edits left under dir by an
+// apply that did not finish, oldest first.
+func listPendingJournals(dir string) ([]pendingJournal, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing pending edits: %w", err)
+	}
+	var out []pendingJournal
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m, err := loadManifest(dir, e.Name())
+		if err != nil {
+			out = append(out, pendingJournal{ID: e.Name(), Error: err.Error()})
+			continue
+		}
+		pj := pendingJournal{ID: m.ID, Created: m.Created, Files: len(m.Files)}
+		for _, f := range m.Files {
+			if f.Written {
+				pj.Written++
+			}
+		}
+		out = append(out, pj)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// recoveryResult is the result of ts_recover_pending_edit.
+type recoveryResult struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	// Written lists the files recovery rewrote; the others already held
+	// the content the action asked for.
+	Written   []string `json:"written"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// recoverJournal completes (writes the intended content of every file) or
+// rolls back (restores every file's original content) the journaled edit
+// id under dir, or with recoverDiscard drops it untouched, and then
+// removes the journal. Files whose content matches neither the original
+// nor the intended content were changed by someone else; recovery then
+// writes nothing and keeps the journal.
+func recoverJournal(dir, id, action string) (*recoveryResult, error) {
+	if !validJournalID(id) {
+		return nil, fmt.Errorf("invalid edit id %q", id)
+	}
+	if action == recoverDiscard {
+		// A journal whose manifest was never written can only be dropped.
+		return &recoveryResult{ID: id, Action: action, Written: []string{}}, os.RemoveAll(filepath.Join(dir, id))
+	}
+	m, err := loadManifest(dir, id)
+	if err != nil {
+		return nil, err
+	}
+	journalDir := filepath.Join(dir, m.ID)
+	result := &recoveryResult{ID: m.ID, Action: action, Written: []string{}}
+	if action != recoverComplete && action != recoverRollback {
+		return nil, fmt.Errorf("unknown action %q (want %s, %s or %s)", action, recoverComplete, recoverRollback, recoverDiscard)
+	}
+
+	type write struct {
+		file    journalFile
+		content []byte
+		remove  bool
+	}
+	var writes []write
+	var conflicts []string
+	for i, f := range m.Files {
+		current, err := os.ReadFile(f.Path)
+		absent := (f.Created || f.Deleted) && errors.Is(err, os.ErrNotExist)
+		if err != nil && !absent {
+			return nil, fmt.Errorf("reading %s: %w", f.Path, err)
+		}
+		// A created file is in its original state while absent, a deleted
+		// one in its updated state.
+		original := (f.Created && absent) || (!f.Created && !absent && hashContent(current) == f.OriginalHash)
+		updated := (f.Deleted && absent) || (!f.Deleted && !absent && hashContent(current) == f.UpdatedHash)
+		switch {
+		case !original && !updated:
+			conflicts = append(conflicts, f.Path)
+			continue
+		case action == recoverRollback && original, action == recoverComplete && updated:
+			result.Unchanged++
+			continue
+		case action == recoverRollback && f.Created, action == recoverComplete && f.Deleted:
+			writes = append(writes, write{file: f, remove: true})
+			continue
+		}
+		wantHash, source := f.UpdatedHash, "updated"
+		if action == recoverRollback {
+			wantHash, source = f.OriginalHash, "originals"
+		}
+		content, err := os.ReadFile(filepath.Join(journalDir, source, strconv.Itoa(i)))
+		if err != nil {
+			return nil, fmt.Errorf("journaled content of %s is missing: %w", f.Path, err)
+		}
+		if hashContent(content) != wantHash {
+			return nil, fmt.Errorf("journaled content of %s is corrupt", f.Path)
+		}
+		writes = append(writes, write{file: f, content: content})
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("files changed since the edit was interrupted; resolve them or use %s: %v", recoverDiscard, conflicts)
+	}
+	for _, w := range writes {
+		switch {
+		case w.remove:
+			if err := os.Remove(w.file.Path); err != nil {
+				return nil, fmt.Errorf("removing %s: %w", w.file.Path, err)
+			}
+			if w.file.Created {
+				edit.RemoveEmptyDirs(w.file.NewDirs)
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(w.file.Path), 0o755); err != nil {
+				return nil, fmt.Errorf("writing %s: %w", w.file.Path, err)
+			}
+			if err := edit.WriteFileAtomic(w.file.Path, w.content, w.file.Mode); err != nil {
+				return nil, fmt.Errorf("writing %s: %w", w.file.Path, err)
+			}
+		}
+		result.Written = append(result.Written, w.file.Path)
+	}
+	return result, os.RemoveAll(journalDir)
+}
+
+// validJournalID rejects ids that would resolve outside the pending
+// directory.
+func validJournalID(id string) bool {
+	return id != "" && id == filepath.Base(id) && id[0] != '.'
+}
+
+func makeRecoverPendingEditHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, journal *journalPolicy) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id := request.GetString("id", "")
+		action := request.GetString("action", "")
+
+		journals, err := listPendingJournals(journal.dir)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if action == "" {
+			data, err := json.MarshalIndent(struct {
+				Pending []pendingJournal `json:"pending"`
+			}{Pending: append([]pendingJournal{}, journals...)}, "", "  ")
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+			}
+			return mcp.NewToolResultText(string(data)), nil
+		}
+		if id == "" {
+			if len(journals) != 1 {
+				return mcp.NewToolResultError(fmt.Sprintf("id is required: %d edits are pending", len(journals))), nil
+			}
+			id = journals[0].ID
+		}
+		if !validJournalID(id) {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid id %q", id)), nil
+		}
+
+		result, err := recoverJournal(journal.dir, id, action)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("recovery error: %v", err)), nil
+		}
+		pending.InvalidateFiles(result.Written)
+		for _, p := range result.Written {
+			if isDocFile(p) {
+				continue
+			}
+			if syncErr := docs.ResyncFile(ctx, client.Conn(), p); syncErr != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("re-sync error for %s: %v", p, syncErr)), nil
+			}
+		}
+		ClearFileCache()
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// journalFixture writes n files declaring "old" and returns them with an
+// edit renaming old to renamed in each.
+func journalFixture(t *testing.T, n int) ([]string, *lsp.WorkspaceEdit) {
+	t.Helper()
+	dir := t.TempDir()
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+	var files []string
+	for i := range n {
+		p := filepath.Join(dir, fmt.Sprintf("f%02d.ts", i))
+		if err := os.WriteFile(p, []byte("export const old = 1;\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, p)
+		edit.Changes[protocol.DocumentURI("file://"+p)] = []protocol.TextEdit{{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 0, Character: 13},
+				End:   protocol.Position{Line: 0, Character: 16},
+			},
+			NewText: "renamed",
+		}}
+	}
+	return files, edit
+}
+
+func fileContents(t *testing.T, files []string) []string {
+	t.Helper()
+	out := make([]string, len(files))
+	for i, p := range files {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = string(data)
+	}
+	return out
+}
+
+// interruptedEdit applies a 5-file edit in chunks of 2 and simulates a
+// crash after the first chunk.
+func interruptedEdit(t *testing.T) (*journalPolicy, []string, pendingJournal) {
+	t.Helper()
+	files, edit := journalFixture(t, 5)
+	p := &journalPolicy{dir: t.TempDir(), always: true, chunkSize: 2}
+	p.afterChunk = func(written int) error {
+		if written == 2 {
+			return errors.New("simulated crash")
+		}
+		return nil
+	}
+	if _, err := applyWorkspaceEdit(edit, nil, nil, p, nil); err == nil || !strings.Contains(err.Error(), "interrupted after 2 of 5 files") {
+		t.Fatalf("error = %v, want an interruption", err)
+	}
+	got := fileContents(t, files)
+	if got[1] != "export const renamed = 1;\n" || got[2] != "export const old = 1;\n" {
+		t.Fatalf("files after the crash = %q", got)
+	}
+	journals, err := listPendingJournals(p.dir)
+	if err != nil || len(journals) != 1 {
+		t.Fatalf("pending journals = %+v, %v; want one", journals, err)
+	}
+	if j := journals[0]; j.Files != 5 || j.Written != 2 {
+		t.Fatalf("pending journal = %+v, want 2 of 5 written", j)
+	}
+	return p, files, journals[0]
+}
+
+func TestJournaledApply(t *testing.T) {
+	files, edit := journalFixture(t, 5)
+	p := &journalPolicy{dir: t.TempDir(), always: true, chunkSize: 2}
+	chunks := 0
+	p.afterChunk = func(int) error { chunks++; return nil }
+	if _, err := applyWorkspaceEdit(edit, nil, nil, p, nil); err != nil {
+		t.Fatal(err)
+	}
+	if chunks != 3 {
+		t.Errorf("chunks = %d, want 3", chunks)
+	}
+	for i, c := range fileContents(t, files) {
+		if c != "export const renamed = 1;\n" {
+			t.Errorf("file %d = %q", i, c)
+		}
+	}
+	if journals, _ := listPendingJournals(p.dir); len(journals) != 0 {
+		t.Errorf("journal left behind: %+v", journals)
+	}
+}
+
+func TestRecoverJournal(t *testing.T) {
+	for _, tt := range []struct {
+		action string
+		want   string
+	}{
+		{recoverComplete, "export const renamed = 1;\n"},
+		{recoverRollback, "export const old = 1;\n"},
+	} {
+		t.Run(tt.action, func(t *testing.T) {
+			p, files, pending := interruptedEdit(t)
+			res, err := recoverJournal(p.dir, pending.ID, tt.action)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Written)+res.Unchanged != 5 {
+				t.Errorf("result = %+v, want 5 files accounted for", res)
+			}
+			for i, c := range fileContents(t, files) {
+				if c != tt.want {
+					t.Errorf("file %d = %q, want %q", i, c, tt.want)
+				}
+			}
+			if journals, _ := listPendingJournals(p.dir); len(journals) != 0 {
+				t.Errorf("journal not cleaned up: %+v", journals)
+			}
+		})
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		p, files, pending := interruptedEdit(t)
+		if err := os.WriteFile(files[3], []byte("export const other = 2;\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := recoverJournal(p.dir, pending.ID, recoverRollback); err == nil || !strings.Contains(err.Error(), files[3]) {
+			t.Fatalf("error = %v, want a conflict on %s", err, files[3])
+		}
+		if got := fileContents(t, files)[0]; got != "export const renamed = 1;\n" {
+			t.Errorf("recovery wrote despite the conflict: %q", got)
+		}
+		if _, err := recoverJournal(p.dir, pending.ID, recoverDiscard); err != nil {
+			t.Fatal(err)
+		}
+		if journals, _ := listPendingJournals(p.dir); len(journals) != 0 {
+			t.Errorf("journal not discarded: %+v", journals)
+		}
+	})
+
+	t.Run("newer manifest version", func(t *testing.T) {
+		p, _, pending := interruptedEdit(t)
+		manifest := filepath.Join(p.dir, pending.ID, "manifest.json")
+		data, err := os.ReadFile(manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = []byte(strings.Replace(string(data), `"version": 1`, `"version": 2`, 1))
+		if err := os.WriteFile(manifest, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := recoverJournal(p.dir, pending.ID, recoverComplete); err == nil || !strings.Contains(err.Error(), "version 2") {
+			t.Fatalf("error = %v, want a version error", err)
+		}
+	})
+
+	t.Run("id outside the pending directory", func(t *testing.T) {
+		if _, err := recoverJournal(t.TempDir(), "../x", recoverDiscard); err == nil {
+			t.Fatal("expected an invalid id error")
+		}
+	})
+}
+
+func TestJournalPolicyApplies(t *testing.T) {
+	p := newJournalPolicy("/repo", &configFile{})
+	if p.applies(50) || !p.applies(51) {
+		t.Errorf("default threshold: applies(50)=%v applies(51)=%v", p.applies(50), p.applies(51))
+	}
+	if p := newJournalPolicy("/repo", &configFile{JournalThreshold: 3}); !p.applies(4) || p.applies(3) {
+		t.Error("configured threshold not honored")
+	}
+	if p := newJournalPolicy("/repo", &configFile{JournalEdits: true}); !p.applies(1) {
+		t.Error("journalEdits should journal every edit")
+	}
+	var none *journalPolicy
+	if none.applies(1000) {
+		t.Error("nil policy should never journal")
+	}
+}
@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/jslex"
+)
+
+// jsdocTypeColumn finds the type name of the JSDoc type expression
+// ("{...}") at or around the 1-based UTF-16 column col of line number
+// lineNum (1-based) in lines. The type name is the last member of an
+// import type, as User in {import('./models').User}, and otherwise the
+// first name in the braces. It returns ok false when the position is not
+// inside a JSDoc comment, the line has no type expression there, or the
+// type name already covers col.
+func jsdocTypeColumn(lines []string, lineNum, col int) (typeCol int, ok bool) {
+	if lineNum < 1 || lineNum > len(lines) || !inJSDoc(lines, lineNum) {
+		return 0, false
+	}
+	line := strings.TrimSuffix(lines[lineNum-1], "\r")
+	off := edit.ByteOffset(line, uint32(col-1))
+	if end := strings.Index(line, "*/"); end >= 0 {
+		if off >= end {
+			return 0, false // code after the comment
+		}
+		line = line[:end]
+	}
+
+	// Prefer the braces around the column, else the line's only pair,
+	// as for a column on the tag name.
+	spans := braceSpans(line)
+	var span [2]int
+	switch {
+	case len(spans) == 0:
+		return 0, false
+	case len(spans) == 1:
+		span = spans[0]
+	default:
+		found := false
+		for _, s := range spans {
+			if off > s[0] && off < s[1] {
+				span, found = s, true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+
+	start := typeNameOffset(line[span[0]+1 : span[1]])
+	if start < 0 {
+		return 0, false
+	}
+	start += span[0] + 1
+	// Any column within the type name already queried it.
+	if off >= start && off < start+identLen(line[start:]) {
+		return 0, false
+	}
+	return utf16Len(line[:start]) + 1, true
+}
+
+// inJSDoc reports whether line lineNum (1-based) lies inside a /** */
+// comment: it opens the comment, or every line up to the one that does
+// continues it with a leading "*".
+func inJSDoc(lines []string, lineNum int) bool {
+	for i := lineNum - 1; i >= 0; i-- {
+		l := lines[i]
+		if open := strings.LastIndex(l, "/**"); open >= 0 {
+			return i == lineNum-1 || !strings.Contains(l[open:], "*/")
+		}
+		if i < lineNum-1 && (strings.Contains(l, "*/") || !strings.HasPrefix(strings.TrimSpace(l), "*")) {
+			return false
+		}
+	}
+	return false
+}
+
+// braceSpans returns the byte offsets of the outermost {...} pairs of
+// line.
+func braceSpans(line string) [][2]int {
+	var spans [][2]int
+	depth, open := 0, 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				spans = append(spans, [2]int{open, i})
+			}
+		}
+	}
+	return spans
+}
+
+// typeNameOffset returns the offset in a type expression of its type
+// name, or -1 when it has none.
+func typeNameOffset(expr string) int {
+	if i := strings.Index(expr, "import("); i >= 0 {
+		closeParen := strings.Index(expr[i:], ")")
+		if closeParen < 0 {
+			return -1
+		}
+		// Follow ".Member" accesses to the last one.
+		last := -1
+		for j := i + closeParen + 1; j < len(expr) && expr[j] == '.'; {
+			n := identLen(expr[j+1:])
+			if n == 0 {
+				break
+			}
+			last = j + 1
+			j += 1 + n
+		}
+		return last
+	}
+	// Names inside string literal types and the text of template literal
+	// types are not type names; those in ${...} substitutions are.
+	var lex jslex.Scanner
+	for i := 0; i < len(expr); {
+		kind, next := lex.Next(expr, i)
+		if kind != jslex.Code {
+			i = next
+			continue
+		}
+		n := identLen(expr[i:])
+		if n == 0 {
+			i = next
+			continue
+		}
+		switch expr[i : i+n] {
+		case "typeof", "keyof", "readonly", "new":
+			i += n
+			continue
+		}
+		if i > 0 && isIdentByte(expr[i-1]) {
+			i += n
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// identLen returns the length of the identifier at the start of s.
+func identLen(s string) int {
+	n := 0
+	for n < len(s) && isIdentByte(s[n]) {
+		n++
+	}
+	if n > 0 && s[0] >= '0' && s[0] <= '9' {
+		return 0
+	}
+	return n
+}
+
+// utf16Len returns the length of s in UTF-16 code units.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// jsdocRetryColumn returns the column to retry a query at when the server
+// found nothing at line and col of file, a position inside a JSDoc type
+// annotation.
+func jsdocRetryColumn(file string, line, col int) (int, bool) {
+	lines, err := cachedReadLines(file)
+	if err != nil {
+		return 0, false
+	}
+	return jsdocTypeColumn(lines, line, col)
+}
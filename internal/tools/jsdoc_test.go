@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSDocTypeColumn(t *testing.T) {
+	src := strings.Join([]string{
+		"/** @type {import('./models').User} */",        // 1
+		"export const admin = load();",                  // 2
+		"/**",                                           // 3
+		" * Greets a user.",                             // 4
+		" * @param {Array<Role>} roles",                 // 5
+		" * @param {typeof defaults} opts",              // 6
+		" * @returns {import(\"./models\").Api.Client}", // 7
+		" */",                                   // 8
+		"function greet(roles, opts) {}",        // 9
+		"// @type {User} is not JSDoc",          // 10
+		"/** @type {User} */ const u = {x: 1};", // 11
+		"/** @type {`user:${number}`} */",       // 12
+		"/** @type {'a' | Mode} */",             // 13
+	}, "\n")
+	lines := strings.Split(src, "\n")
+	tests := []struct {
+		name      string
+		line, col int
+		want      int // 0 means no adjustment
+	}{
+		{"on the tag", 1, 6, 31},
+		{"on import", 1, 13, 31},
+		{"on the module string", 1, 22, 31},
+		{"already on the type name", 1, 32, 0},
+		{"generic outer name", 5, 4, 12},
+		{"inside generic arguments", 5, 17, 12},
+		{"typeof is skipped", 6, 13, 19},
+		{"nested member of an import type", 7, 8, 37},
+		{"prose line in the comment", 4, 5, 0},
+		{"code after the comment", 2, 10, 0},
+		{"function body", 9, 5, 0},
+		{"line comment", 10, 12, 0},
+		{"after the closing */", 11, 30, 0},
+		{"before the closing */", 11, 6, 12},
+		{"template literal type", 12, 6, 20},
+		{"template literal text", 12, 14, 20},
+		{"string literal member", 13, 6, 18},
+	}
+	for _, tt := range tests {
+		got, ok := jsdocTypeColumn(lines, tt.line, tt.col)
+		if tt.want == 0 {
+			if ok {
+				t.Errorf("%s: adjusted to column %d, want no adjustment", tt.name, got)
+			}
+			continue
+		}
+		if !ok || got != tt.want {
+			t.Errorf("%s: jsdocTypeColumn(%d, %d) = %d, %v; want %d", tt.name, tt.line, tt.col, got, ok, tt.want)
+		}
+	}
+}
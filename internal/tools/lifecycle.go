@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/jsonrpc2"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// maxOpenDocsFromEnv reads TYPESCRIPT_MCP_MAX_OPEN_DOCS. It returns zero
+// (no cap) when the variable is unset or invalid.
+func maxOpenDocsFromEnv() int {
+	n, err := strconv.Atoi(os.Getenv("TYPESCRIPT_MCP_MAX_OPEN_DOCS"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// syncFreshnessFromEnv reads TYPESCRIPT_MCP_SYNC_FRESHNESS as a Go
+// duration; ok is false when the variable is unset or invalid.
+func syncFreshnessFromEnv() (d time.Duration, ok bool) {
+	d, err := time.ParseDuration(os.Getenv("TYPESCRIPT_MCP_SYNC_FRESHNESS"))
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+type openFileEntry struct {
+	File       string `json:"file"`
+	OK         bool   `json:"ok"`
+	LanguageID string `json:"languageId,omitempty"`
+	Version    int32  `json:"version,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type openFilesResult struct {
+	Files     []openFileEntry `json:"files"`
+	OpenCount int             `json:"openCount"`
+	MaxOpen   int             `json:"maxOpen,omitempty"`
+}
+
+type closeFilesResult struct {
+	Closed    []string               `json:"closed"`
+	Skipped   []docsync.SkippedClose `json:"skipped,omitempty"`
+	OpenCount int                    `json:"openCount"`
+}
+
+type openDocumentEntry struct {
+	File       string `json:"file"`
+	LanguageID string `json:"languageId"`
+	Version    int32  `json:"version"`
+	// Age is the time since didOpen; SinceSync the time since the content
+	// last changed.
+	Age       string `json:"age"`
+	SinceSync string `json:"sinceSync"`
+	Pinned    bool   `json:"pinned,omitempty"`
+}
+
+type serverStatusResult struct {
+	RootDir     string `json:"rootDir"`
+	TsgoVersion string `json:"tsgoVersion,omitempty"`
+	// VersionWarning is set when tsgo misses TYPESCRIPT_MCP_TSGO_VERSION
+	// and the server was started in warn-only mode.
+	VersionWarning string              `json:"versionWarning,omitempty"`
+	OpenDocuments  []openDocumentEntry `json:"openDocuments"`
+	OpenCount      int                 `json:"openCount"`
+	MaxOpen        int                 `json:"maxOpen,omitempty"`
+	SymbolCache    symbolCacheStats    `json:"symbolCache"`
+	// PendingEdits lists journaled edits an interrupted apply left behind;
+	// recover them with ts_recover_pending_edit.
+	PendingEdits []pendingJournal `json:"pendingEdits,omitempty"`
+	// Startup is the progress of the tsgo spawn and initialize handshake,
+	// with the error when it failed.
+	Startup lsp.StartupStatus `json:"startup"`
+	// Health is the state of the monitor that detects an unresponsive
+	// tsgo, once tsgo has started.
+	Health *lsp.HealthStatus `json:"health,omitempty"`
+}
+
+// serverInfoResult is the result of ts_server_info.
+type serverInfoResult struct {
+	// Name and Version are the serverInfo of tsgo's initialize result.
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	// TsgoVersion is the version tsgo --version printed.
+	TsgoVersion  string           `json:"tsgoVersion,omitempty"`
+	Capabilities []lsp.Capability `json:"capabilities"`
+	// UnavailableTools are the tools that fail because tsgo lacks the
+	// request they need.
+	UnavailableTools []string `json:"unavailableTools,omitempty"`
+}
+
+// serverInfo describes the running tsgo. names gives the tool names
+// reported in UnavailableTools.
+func serverInfo(client *lsp.Client, names toolNames) serverInfoResult {
+	result := serverInfoResult{TsgoVersion: client.TsgoVersion(), Capabilities: client.CapabilitySummary()}
+	if info := client.ServerInfo(); info != nil {
+		result.Name, result.Version = info.Name, info.Version
+	}
+	for tool, method := range toolCapabilities {
+		if !client.Supports(method) {
+			result.UnavailableTools = append(result.UnavailableTools, names.of(tool))
+		}
+	}
+	sort.Strings(result.UnavailableTools)
+	return result
+}
+
+// openFiles syncs each path with the server and reports the outcome per
+// file; one failure does not stop the rest. closeTool is the name of
+// ts_close_files, suggested when the open-document limit is reached.
+func openFiles(ctx context.Context, conn jsonrpc2.Conn, docs *docsync.Manager, paths []string, closeTool string) openFilesResult {
+	result := openFilesResult{Files: make([]openFileEntry, 0, len(paths))}
+	for _, p := range paths {
+		entry := openFileEntry{File: p}
+		if !filepath.IsAbs(p) {
+			entry.Error = "path must be absolute"
+			result.Files = append(result.Files, entry)
+			continue
+		}
+		switch err := docs.SyncFile(ctx, conn, p); {
+		case errors.Is(err, docsync.ErrOpenLimit):
+			entry.Error = fmt.Sprintf("open document limit (%d) reached; close files with %s or raise TYPESCRIPT_MCP_MAX_OPEN_DOCS", docs.MaxOpen(), closeTool)
+		case err != nil:
+			entry.Error = err.Error()
+		default:
+			d, _ := docs.Document(p)
+			entry.OK = true
+			entry.LanguageID = string(d.LanguageID)
+			entry.Version = d.Version
+		}
+		result.Files = append(result.Files, entry)
+	}
+	result.OpenCount = len(docs.OpenFiles())
+	result.MaxOpen = docs.MaxOpen()
+	return result
+}
+
+// serverStatus summarizes the open documents as of now.
+func serverStatus(docs *docsync.Manager, cache *symbolCache, now time.Time) serverStatusResult {
+	open := docs.Documents()
+	result := serverStatusResult{
+		OpenDocuments: make([]openDocumentEntry, len(open)),
+		OpenCount:     len(open),
+		MaxOpen:       docs.MaxOpen(),
+		SymbolCache:   cache.Stats(),
+	}
+	for i, d := range open {
+		result.OpenDocuments[i] = openDocumentEntry{
+			File:       d.Path,
+			LanguageID: string(d.LanguageID),
+			Version:    d.Version,
+			Age:        now.Sub(d.OpenedAt).Round(time.Second).String(),
+			SinceSync:  now.Sub(d.SyncedAt).Round(time.Second).String(),
+			Pinned:     d.Pinned,
+		}
+	}
+	return result
+}
+
+func makeOpenFilesHandler(client *lsp.Client, docs *docsync.Manager, closeTool string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		paths, err := request.RequireStringSlice("files")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		data, err := json.MarshalIndent(openFiles(ctx, client.Conn(), docs, paths, closeTool), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func makeCloseFilesHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		paths, err := request.RequireStringSlice("files")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		closed, skipped, err := docs.CloseFiles(ctx, client.Conn(), paths)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("close error after closing %d files: %v", len(closed), err)), nil
+		}
+
+		result := closeFilesResult{
+			Closed:    closed,
+			Skipped:   skipped,
+			OpenCount: len(docs.OpenFiles()),
+		}
+		if result.Closed == nil {
+			result.Closed = []string{}
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func makeServerStatusHandler(client *lsp.Client, docs *docsync.Manager, symbolCache *symbolCache, journal *journalPolicy) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result := serverStatus(docs, symbolCache, time.Now())
+		result.RootDir = client.RootDir()
+		result.TsgoVersion = client.TsgoVersion()
+		result.VersionWarning = client.VersionWarning()
+		result.PendingEdits, _ = listPendingJournals(journal.dir)
+		result.Startup = client.Startup()
+		if result.Startup.State == lsp.StartupReady {
+			health := client.Health()
+			result.Health = &health
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+func makeServerInfoHandler(client *lsp.Client, names toolNames) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		data, err := json.MarshalIndent(serverInfo(client, names), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
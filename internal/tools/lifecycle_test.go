@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lsp.dev/jsonrpc2"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+)
+
+// nopConn accepts and drops every notification.
+type nopConn struct {
+	jsonrpc2.Conn
+	methods []string
+}
+
+func (c *nopConn) Notify(_ context.Context, method string, _ interface{}) error {
+	c.methods = append(c.methods, method)
+	return nil
+}
+
+func TestOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("export {};\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	a, b, c := write("a.ts"), write("b.jsx"), write("c.ts")
+
+	docs := docsync.NewManager()
+	docs.SetMaxOpen(2)
+	conn := &nopConn{}
+	res := openFiles(context.Background(), conn, docs, []string{a, "rel.ts", filepath.Join(dir, "missing.ts"), b, c}, "ts_close_files")
+
+	if len(res.Files) != 5 {
+		t.Fatalf("got %d entries, want 5", len(res.Files))
+	}
+	if e := res.Files[0]; !e.OK || e.LanguageID != "typescript" || e.Version != 1 {
+		t.Errorf("a.ts = %+v", e)
+	}
+	if e := res.Files[1]; e.OK || e.Error != "path must be absolute" {
+		t.Errorf("relative path = %+v", e)
+	}
+	if e := res.Files[2]; e.OK || !strings.Contains(e.Error, "missing.ts") {
+		t.Errorf("missing file = %+v", e)
+	}
+	if e := res.Files[3]; !e.OK || e.LanguageID != "javascriptreact" {
+		t.Errorf("b.jsx = %+v", e)
+	}
+	if e := res.Files[4]; e.OK || !strings.Contains(e.Error, "open document limit (2)") || !strings.Contains(e.Error, "ts_close_files") {
+		t.Errorf("c.ts over the cap = %+v", e)
+	}
+	if res.OpenCount != 2 || res.MaxOpen != 2 {
+		t.Errorf("openCount = %d, maxOpen = %d", res.OpenCount, res.MaxOpen)
+	}
+	if len(conn.methods) != 2 {
+		t.Errorf("notifications = %v, want two didOpen", conn.methods)
+	}
+}
+
+func TestServerStatus(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "a.ts")
+	if err := os.WriteFile(p, []byte("export {};\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	docs := docsync.NewManager()
+	if err := docs.SyncFile(context.Background(), &nopConn{}, p); err != nil {
+		t.Fatal(err)
+	}
+	release := docs.Pin(p)
+	defer release()
+
+	res := serverStatus(docs, newSymbolCache(4), time.Now().Add(90*time.Second))
+	if res.OpenCount != 1 || len(res.OpenDocuments) != 1 {
+		t.Fatalf("status = %+v", res)
+	}
+	d := res.OpenDocuments[0]
+	if d.File != p || d.Version != 1 || !d.Pinned || d.Age != "1m30s" || d.SinceSync != "1m30s" {
+		t.Errorf("document = %+v", d)
+	}
+}
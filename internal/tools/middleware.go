@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+// withWorkspaceWarning prefixes every response of h with a warning content
+// item when the workspace root does not look like a TypeScript project.
+func withWorkspaceWarning(probe *workspace.Prober, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := h(ctx, request)
+		if err != nil || result == nil {
+			return result, err
+		}
+		if warning := probe.Status().Warning(); warning != "" {
+			result.Content = append([]mcp.Content{mcp.NewTextContent("warning: " + warning)}, result.Content...)
+		}
+		return result, nil
+	}
+}
+
+// withVersionWarning prefixes every response of h with the tsgo version
+// mismatch tolerated at startup, as warning reports it, when it is set.
+func withVersionWarning(warning func() string, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := h(ctx, request)
+		if err != nil || result == nil {
+			return result, err
+		}
+		if w := warning(); w != "" {
+			result.Content = append([]mcp.Content{mcp.NewTextContent("warning: " + w)}, result.Content...)
+		}
+		return result, nil
+	}
+}
+
+// lspUnavailableError is the error result of a tool that needs tsgo when
+// tsgo failed to start or was still starting when the call gave up.
+type lspUnavailableError struct {
+	Error   string            `json:"error"`
+	Startup lsp.StartupStatus `json:"startup"`
+}
+
+// withLSPReady makes h wait for the tsgo startup, bounded by the call's
+// context, and answers with an lspUnavailableError instead of calling h
+// when the startup failed or did not finish in time. A call after a
+// failed startup first starts tsgo again, so installing it needs no
+// restart of the MCP server. With retry, a call that fails because tsgo
+// exited while it ran is run once more after tsgo was restarted; only
+// read-only tools set it, as a write tool may have written its files before
+// tsgo exited, and running it again would write again or hide the write.
+func withLSPReady(client *lsp.Client, retry bool, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		err := client.Wait(ctx)
+		if err != nil && ctx.Err() == nil && client.RetryStartup() {
+			err = client.Wait(ctx)
+		}
+		if err == nil {
+			restarts := client.Restarts()
+			result, err := h(ctx, request)
+			if retry && (err != nil || result != nil && result.IsError) && (client.Exited() || client.Restarts() != restarts) && client.Wait(ctx) == nil {
+				slog.Info("retrying the call after tsgo was restarted", "tool", request.Params.Name)
+				return h(ctx, request)
+			}
+			return result, err
+		}
+		st := client.Startup()
+		msg := fmt.Sprintf("tsgo failed to start: %v; fix the cause and call again to retry", err)
+		if st.State == lsp.StartupStarting {
+			msg = fmt.Sprintf("tsgo is still starting (%s, %s so far); retry shortly", st.Phase, st.Elapsed)
+		}
+		data, mErr := json.MarshalIndent(lspUnavailableError{Error: msg, Startup: st}, "", "  ")
+		if mErr != nil {
+			return mcp.NewToolResultError(msg), nil
+		}
+		return mcp.NewToolResultError(string(data)), nil
+	}
+}
+
+// withCapability answers with an error naming method instead of calling h
+// when tsgo did not announce the capability of method, which tool cannot
+// do without. infoTool is the name of ts_server_info. It must run inside
+// withLSPReady, as the capabilities are only known once tsgo started.
+func withCapability(client *lsp.Client, method, tool, infoTool string, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if client.Supports(method) {
+			return h(ctx, request)
+		}
+		version := "this tsgo version"
+		if v := client.TsgoVersion(); v != "" {
+			version = "tsgo " + v
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("%s does not support %s, which %s needs; %s lists what it supports", version, method, tool, infoTool)), nil
+	}
+}
@@ -0,0 +1,196 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// callWithin calls tool name on s with args, giving it d to answer, and
+// returns the result's text content items.
+func callWithin(t *testing.T, s *server.MCPServer, d time.Duration, name string, args map[string]any) (*mcp.CallToolResult, []string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	var req mcp.CallToolRequest
+	req.Params.Name = name
+	req.Params.Arguments = args
+	result, err := s.GetTool(name).Handler(ctx, req)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	var texts []string
+	for _, c := range result.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			texts = append(texts, tc.Text)
+		}
+	}
+	return result, texts
+}
+
+func TestStartupGate(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     lsp.ProcessStarter
+		wantState string
+		wantError string
+	}{
+		{
+			name: "slow spawn",
+			start: func(ctx context.Context) (*lsp.TsgoProcess, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			wantState: lsp.StartupStarting,
+			wantError: "tsgo is still starting (spawn,",
+		},
+		{
+			name: "failed spawn",
+			start: func(context.Context) (*lsp.TsgoProcess, error) {
+				return nil, errors.New("tsgo not found")
+			},
+			wantState: lsp.StartupFailed,
+			wantError: "tsgo failed to start: start tsgo: tsgo not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TYPESCRIPT_MCP_CONFIG", "")
+			root := t.TempDir()
+			client := lsp.StartClient(context.Background(), docsync.FileToURI(root), tt.start)
+			t.Cleanup(func() { _ = client.Close() })
+			s := server.NewMCPServer("test", "test")
+			if err := Register(s, client, docsync.NewManager()); err != nil {
+				t.Fatal(err)
+			}
+
+			// Tools that do not need tsgo answer before it has started.
+			for name, args := range map[string]map[string]any{
+				"ts_project_info":  {"cwd": root},
+				"ts_server_status": nil,
+				"ts_list_edits":    nil,
+			} {
+				if result, texts := callWithin(t, s, time.Second, name, args); result.IsError {
+					t.Errorf("%s failed: %v", name, texts)
+				}
+			}
+
+			result, texts := callWithin(t, s, 50*time.Millisecond, "ts_hover", map[string]any{"file": root + "/a.ts", "line": 1, "column": 1})
+			if !result.IsError {
+				t.Fatalf("ts_hover succeeded without tsgo: %v", texts)
+			}
+			var unavailable lspUnavailableError
+			if err := json.Unmarshal([]byte(texts[len(texts)-1]), &unavailable); err != nil {
+				t.Fatalf("error is not structured: %v", texts)
+			}
+			if !strings.HasPrefix(unavailable.Error, tt.wantError) || unavailable.Startup.State != tt.wantState {
+				t.Errorf("error = %+v, want %q in state %s", unavailable, tt.wantError, tt.wantState)
+			}
+
+			_, texts = callWithin(t, s, time.Second, "ts_server_status", nil)
+			var status serverStatusResult
+			if err := json.Unmarshal([]byte(texts[len(texts)-1]), &status); err != nil {
+				t.Fatal(err)
+			}
+			if status.Startup.State != tt.wantState || status.Health != nil {
+				t.Errorf("status startup = %+v, health %+v", status.Startup, status.Health)
+			}
+		})
+	}
+}
+
+func TestCapabilityGate(t *testing.T) {
+	client := lsp.StartClient(context.Background(), docsync.FileToURI(t.TempDir()), func(context.Context) (*lsp.TsgoProcess, error) {
+		return nil, errors.New("tsgo not found")
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	called := false
+	h := withCapability(client, "textDocument/hover", "ts_hover", "ts_server_info", func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		return mcp.NewToolResultText("hover"), nil
+	})
+	result, err := h(context.Background(), mcp.CallToolRequest{})
+	if err != nil || !result.IsError || called {
+		t.Fatalf("result = %+v, %v; called %v; want an error without calling the tool", result, err, called)
+	}
+	const want = "this tsgo version does not support textDocument/hover, which ts_hover needs; ts_server_info lists what it supports"
+	if got := result.Content[0].(mcp.TextContent).Text; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+func TestWriteToolNotRetriedAfterRestart(t *testing.T) {
+	root := t.TempDir()
+	useFakeTsgo(t, filepath.Join(t.TempDir(), "crashed"))
+	config := filepath.Join(t.TempDir(), "config.json")
+	writeString(t, config, `{"recordEdits": true}`)
+	t.Setenv("TYPESCRIPT_MCP_CONFIG", config)
+
+	// Each file is larger than a pipe buffer, so that syncing the second
+	// fails once the fake tsgo crashed on the first.
+	padding := strings.Repeat("// padding\n", 200_000)
+	recorder := testRecorder(root, 0, recordStart)
+	var older, newer []string
+	for _, names := range [][]string{{"older.ts"}, {"newer1.ts", "newer2.ts"}} {
+		we := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+		var files []string
+		for _, name := range names {
+			p := filepath.Join(root, name)
+			writeString(t, p, oldContent+padding)
+			files = append(files, p)
+			we.Changes[protocol.DocumentURI(docsync.FileToURI(p))] = []protocol.TextEdit{{
+				Range:   protocol.Range{Start: protocol.Position{Character: 13}, End: protocol.Position{Character: 16}},
+				NewText: "renamed",
+			}}
+		}
+		if _, err := applyWorkspaceEdit(we, nil, nil, nil, recorder.recording(editOrigin{tool: "ts_rename"})); err != nil {
+			t.Fatal(err)
+		}
+		older, newer = newer, files
+	}
+
+	client := lsp.StartClient(context.Background(), docsync.FileToURI(root), lsp.StartTsgo)
+	t.Cleanup(func() { _ = client.Close() })
+	s := server.NewMCPServer("test", "test")
+	if err := Register(s, client, docsync.NewManager()); err != nil {
+		t.Fatal(err)
+	}
+
+	// tsgo crashes while the undo re-syncs the files it restored.
+	result, texts := callWithin(t, s, 10*time.Second, "ts_undo_last_edit", nil)
+	if !result.IsError || !strings.Contains(strings.Join(texts, "\n"), "re-sync error") {
+		t.Fatalf("undo = %v, want the re-sync error", texts)
+	}
+	for _, p := range newer {
+		if got := fileContents(t, []string{p})[0]; got != oldContent+padding {
+			t.Errorf("%s was not undone", p)
+		}
+	}
+	if got := fileContents(t, older)[0]; got != renamedContent+padding {
+		t.Errorf("the older edit was undone too")
+	}
+	records, err := recorder.records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	undone := 0
+	for _, r := range records {
+		if r.UndoneAt != nil {
+			undone++
+		}
+	}
+	if undone != 1 {
+		t.Errorf("%d edits undone, want 1", undone)
+	}
+}
@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// defaultToolPrefix starts every tool name unless RegisterOptions.Prefix
+// replaces it.
+const defaultToolPrefix = "ts_"
+
+// RegisterOptions adjusts how the tools are exposed, for servers that
+// carry other tool sets too.
+type RegisterOptions struct {
+	// Prefix replaces "ts_" in every tool name, e.g. "tsmcp_" exposes
+	// ts_rename as tsmcp_rename. Empty keeps "ts_".
+	Prefix string
+	// DisabledTools are left out. Either the default name ("ts_rename")
+	// or the prefixed one may be given.
+	DisabledTools []string
+	// Context bounds the tsgo processes started for projects outside the
+	// client's root; they are stopped when it is done. Nil means
+	// context.Background().
+	Context context.Context
+	// Hooks are the hooks s was created with (server.WithHooks). With
+	// them, a tool call the client cancels with notifications/cancelled
+	// has its context cancelled, ending its tsgo requests; without them
+	// a cancelled call runs to the end.
+	Hooks *server.Hooks
+}
+
+var (
+	toolPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	// toolNamePattern matches the tool names mentioned in descriptions and
+	// the server instructions.
+	toolNamePattern = regexp.MustCompile(`\bts_[a-z_]+\b`)
+)
+
+// toolNames maps the default tool names to the names a deployment uses.
+type toolNames struct {
+	prefix string
+}
+
+func newToolNames(prefix string) (toolNames, error) {
+	if prefix == "" {
+		return toolNames{prefix: defaultToolPrefix}, nil
+	}
+	if !toolPrefixPattern.MatchString(prefix) {
+		return toolNames{}, fmt.Errorf("tool prefix %q: must be 1-32 letters, digits, '_' or '-'", prefix)
+	}
+	return toolNames{prefix: prefix}, nil
+}
+
+// of returns the effective name of the tool named name by default.
+func (n toolNames) of(name string) string {
+	return n.prefix + strings.TrimPrefix(name, defaultToolPrefix)
+}
+
+// base returns the default name of a tool given by either name.
+func (n toolNames) base(name string) string {
+	if rest, ok := strings.CutPrefix(name, n.prefix); ok && !strings.HasPrefix(name, defaultToolPrefix) {
+		return defaultToolPrefix + rest
+	}
+	return name
+}
+
+// text rewrites the tool names mentioned in s.
+func (n toolNames) text(s string) string {
+	if n.prefix == defaultToolPrefix {
+		return s
+	}
+	return toolNamePattern.ReplaceAllStringFunc(s, n.of)
+}
+
+// tool renames t and the tool names in its descriptions.
+func (n toolNames) tool(t mcp.Tool) mcp.Tool {
+	t.Name = n.of(t.Name)
+	t.Description = n.text(t.Description)
+	props := make(map[string]any, len(t.InputSchema.Properties))
+	for name, p := range t.InputSchema.Properties {
+		if m, ok := p.(map[string]any); ok {
+			if d, ok := m["description"].(string); ok {
+				m = maps.Clone(m)
+				m["description"] = n.text(d)
+				p = m
+			}
+		}
+		props[name] = p
+	}
+	t.InputSchema.Properties = props
+	return t
+}
+
+// disabledSet resolves opts.DisabledTools to default names. It fails on a
+// name that is not one of tools.
+func (n toolNames) disabledSet(disabled []string, tools []registeredTool) (map[string]bool, error) {
+	known := make(map[string]bool, len(tools))
+	for _, t := range tools {
+		known[t.tool.Name] = true
+	}
+	set := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		base := n.base(name)
+		if !known[base] {
+			return nil, fmt.Errorf("disabled tool %q: unknown tool", name)
+		}
+		set[base] = true
+	}
+	return set, nil
+}
+
+// registerToolSet adds tools, named by default, to s under their effective
+// names, followed by aliases. Nothing is added when a name is taken
+// already: the error lists every conflict.
+func registerToolSet(s *server.MCPServer, tools []registeredTool, aliases map[string]toolAlias, opts RegisterOptions) error {
+	names, err := newToolNames(opts.Prefix)
+	if err != nil {
+		return err
+	}
+	disabled, err := names.disabledSet(opts.DisabledTools, tools)
+	if err != nil {
+		return err
+	}
+
+	builtin := make(map[string]registeredTool, len(tools))
+	var set []server.ServerTool
+	for _, t := range tools {
+		if disabled[t.tool.Name] {
+			continue
+		}
+		t.tool = names.tool(t.tool)
+		builtin[t.tool.Name] = t
+		set = append(set, server.ServerTool{Tool: t.tool, Handler: t.handler})
+	}
+
+	// Aliases may name their tool by its default name.
+	resolved := make(map[string]toolAlias, len(aliases))
+	for name, a := range aliases {
+		if _, ok := builtin[a.Tool]; !ok {
+			if _, ok := builtin[names.of(a.Tool)]; ok {
+				a.Tool = names.of(a.Tool)
+			}
+		}
+		resolved[name] = a
+	}
+	if err := validateAliases(resolved, builtin); err != nil {
+		return err
+	}
+
+	existing := s.ListTools()
+	var conflicts []string
+	for _, name := range slices.Concat(slices.Collect(maps.Keys(builtin)), slices.Collect(maps.Keys(resolved))) {
+		if _, ok := existing[name]; ok {
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		slices.Sort(conflicts)
+		return fmt.Errorf("tool names already registered: %s (set RegisterOptions.Prefix to register under other names)", strings.Join(conflicts, ", "))
+	}
+
+	s.AddTools(set...)
+	return registerAliases(s, resolved, builtin)
+}
+
+// Instructions returns the server instructions describing the tools as
+// registered with opts: under their effective names, without the disabled
+// ones.
+func Instructions(opts RegisterOptions) string {
+	names := toolNames{prefix: defaultToolPrefix}
+	if opts.Prefix != "" {
+		names.prefix = opts.Prefix
+	}
+	disabled := make(map[string]bool, len(opts.DisabledTools))
+	for _, name := range opts.DisabledTools {
+		disabled[names.base(name)] = true
+	}
+	var lines []string
+	for _, line := range strings.Split(serverInstructions, "\n") {
+		if head, _, ok := strings.Cut(line, ":"); ok && strings.HasPrefix(line, "- ") && allDisabled(toolNamePattern.FindAllString(head, -1), disabled) {
+			continue
+		}
+		lines = append(lines, names.text(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// allDisabled reports whether tools is non-empty and all of them are
+// disabled.
+func allDisabled(tools []string, disabled map[string]bool) bool {
+	for _, t := range tools {
+		if !disabled[t] {
+			return false
+		}
+	}
+	return len(tools) > 0
+}
+
+const serverInstructions = `TypeScript type-checking and code navigation tools powered by tsgo.
+
+Available tools:
+- ts_diagnostics: Get TypeScript errors and warnings for a file or several files
+- ts_project_diagnostics: Check every file of the project and count errors and warnings per file
+- ts_definition: Go to the definition of a symbol, optionally with its full source
+- ts_type_definition: Go to the declaration of the type of a symbol or expression
+- ts_implementations: Find the classes and members implementing an interface or abstract member
+- ts_call_hierarchy: Show the callers or callees of a function as a tree
+- ts_type_hierarchy: Show the supertypes or subtypes of a class or interface as a tree
+- ts_hover: Get type information and documentation for a symbol
+- ts_hover_batch: Get the types of many positions in a file at once, optionally as annotated source
+- ts_hover_many: Get the types of positions across several files at once
+- ts_signature_help: Get the signatures and active parameter of the call at a position
+- ts_inlay_hints: Get the inferred types and parameter names an editor shows inline
+- ts_semantic_tokens: Classify the identifiers of a file (type, enum member, variable, ...)
+- ts_references: Find all references to a symbol across the project
+- ts_document_highlights: Find the occurrences of a symbol in its own file, tagged read or write
+- ts_completion: Get the code completions available at a position
+- ts_symbol_card: Get signature, docs, export status and reference counts for a symbol in one call
+- ts_code_actions: List the quick fixes and refactorings available for a range
+- ts_apply_code_action: Apply a listed code action (writes changes to disk)
+- ts_extract_refactor: List or apply extract function/constant refactorings for a selection (writes changes to disk)
+- ts_fix_all: Apply the quick fix of every diagnostic with a code, in a file or the project (writes changes to disk)
+- ts_format: Format a file or a range of lines (writes changes to disk)
+- ts_prepare_rename: Check that a position can be renamed and get the symbol's range
+- ts_rename: Rename a symbol across the project (writes changes to disk)
+- ts_rename_file: Move a file and update the imports of it (writes changes to disk)
+- ts_apply_edit: Apply an edit previewed with confirm=true
+- ts_list_edits / ts_undo_last_edit: List recorded edits or revert the most recent one (when recordEdits is on)
+- ts_document_symbols: Get the symbol outline of a file
+- ts_workspace_symbols: Search the whole project for symbols by name
+- ts_folding_ranges: Get the line spans of the imports, comments, regions and code blocks of a file
+- ts_selection_range: Get the enclosing expressions, statements and blocks of a position
+- ts_symbol_at_position: Tell which function or class a position is inside, as a breadcrumb
+- ts_project_info: Get TypeScript project configuration info
+- ts_list_projects: Find every tsconfig of a monorepo in dependency order, or the project owning a file
+- ts_project_coverage: Find files tsconfig includes that tsgo never analyzed, and vice versa
+- ts_import_cycles: Find circular imports through a file or directory
+- ts_ambient_declarations: List globals, declare module statements and triple-slash references by file
+- ts_open_files / ts_close_files: Explicitly open or close documents in tsgo (optional; tools open files on demand)
+- ts_server_status: List open documents with versions and ages
+- ts_server_info: Show the tsgo version and which LSP requests it supports
+
+Every response starts with a one-line summary such as "diagnostics: 2 errors, 1 warning in src/a.ts (truncated: no)";
+pass summaryOnly=true to any tool to get just that line.
+
+Workflow:
+1. After editing TypeScript files, use ts_diagnostics to check for type errors
+2. Use ts_hover to understand types and ts_definition to navigate code
+3. Use ts_references before renaming or refactoring to find all usages
+4. Use ts_rename to rename symbols — it applies all changes across the project
+   (pass confirm=true to review the diff first, then ts_apply_edit with the editToken,
+   or dryRun=true to only look at the diff); symbol="MyClass.method" addresses it by name
+5. Use ts_document_symbols to get a file overview without reading the full source
+6. Use ts_code_actions to find tsgo's fixes for an error, then ts_apply_code_action to apply one`
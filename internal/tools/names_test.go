@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// namedTools returns a tool set whose handlers record the set and the
+// tool name they were called as.
+func namedTools(set string, calls *[]string) []registeredTool {
+	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		*calls = append(*calls, set+":"+request.Params.Name)
+		return mcp.NewToolResultText("ok"), nil
+	}
+	tools := []mcp.Tool{
+		mcp.NewTool("ts_rename",
+			mcp.WithDescription("Rename a symbol across the project."),
+			mcp.WithString("newName", mcp.Required()),
+			mcp.WithBoolean("confirm", mcp.Description("Return an editToken for ts_apply_edit instead of writing")),
+		),
+		mcp.NewTool("ts_apply_edit", mcp.WithDescription("Apply an edit previewed by ts_rename.")),
+		mcp.NewTool("ts_hover", mcp.WithDescription("Get type information.")),
+	}
+	out := make([]registeredTool, len(tools))
+	for i, t := range tools {
+		out[i] = registeredTool{tool: t, handler: handler}
+	}
+	return out
+}
+
+func callTool(t *testing.T, s *server.MCPServer, name string) {
+	t.Helper()
+	tool := s.GetTool(name)
+	if tool == nil {
+		t.Fatalf("%s is not registered", name)
+	}
+	var req mcp.CallToolRequest
+	req.Params.Name = name
+	if _, err := tool.Handler(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterToolSet(t *testing.T) {
+	var calls []string
+	s := server.NewMCPServer("test", "test")
+	if err := registerToolSet(s, namedTools("default", &calls), nil, RegisterOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	aliases := map[string]toolAlias{"tsmcp_quick_rename": {Tool: "ts_rename", Arguments: map[string]any{"newName": "x"}}}
+	opts := RegisterOptions{Prefix: "tsmcp_", DisabledTools: []string{"tsmcp_hover"}}
+	if err := registerToolSet(s, namedTools("tsmcp", &calls), aliases, opts); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.ListTools()); n != 6 {
+		t.Fatalf("registered %d tools, want 6", n)
+	}
+	if s.GetTool("tsmcp_hover") != nil {
+		t.Error("disabled tool tsmcp_hover was registered")
+	}
+
+	// Descriptions name the tools of their own set.
+	rename := s.GetTool("tsmcp_rename").Tool
+	confirm := rename.InputSchema.Properties["confirm"].(map[string]any)["description"]
+	if confirm != "Return an editToken for tsmcp_apply_edit instead of writing" {
+		t.Errorf("confirm description = %q", confirm)
+	}
+	if d := s.GetTool("tsmcp_apply_edit").Tool.Description; d != "Apply an edit previewed by tsmcp_rename." {
+		t.Errorf("apply description = %q", d)
+	}
+	if d := s.GetTool("ts_apply_edit").Tool.Description; d != "Apply an edit previewed by ts_rename." {
+		t.Errorf("default set was renamed: %q", d)
+	}
+
+	// Each name routes to its own set.
+	for _, name := range []string{"ts_rename", "tsmcp_rename", "ts_hover", "tsmcp_quick_rename"} {
+		callTool(t, s, name)
+	}
+	want := []string{"default:ts_rename", "tsmcp:tsmcp_rename", "default:ts_hover", "tsmcp:tsmcp_rename"}
+	if strings.Join(calls, " ") != strings.Join(want, " ") {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+
+	// A second set under a taken prefix adds nothing.
+	err := registerToolSet(s, namedTools("again", &calls), nil, RegisterOptions{Prefix: "tsmcp_"})
+	if err == nil || !strings.Contains(err.Error(), "tsmcp_apply_edit, tsmcp_rename") || strings.Contains(err.Error(), "tsmcp_hover") {
+		t.Errorf("conflict error = %v", err)
+	}
+	if s.GetTool("tsmcp_hover") != nil {
+		t.Error("tsmcp_hover was registered despite the conflicts")
+	}
+}
+
+func TestRegisterToolSetErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    RegisterOptions
+		aliases map[string]toolAlias
+		wantErr string
+	}{
+		{name: "bad prefix", opts: RegisterOptions{Prefix: "ts mcp"}, wantErr: "tool prefix"},
+		{name: "unknown disabled tool", opts: RegisterOptions{DisabledTools: []string{"ts_batch"}}, wantErr: `disabled tool "ts_batch"`},
+		{name: "alias of a disabled tool", opts: RegisterOptions{DisabledTools: []string{"ts_hover"}}, aliases: map[string]toolAlias{"h": {Tool: "ts_hover"}}, wantErr: "unknown tool"},
+		{name: "alias taken", aliases: map[string]toolAlias{"other": {Tool: "ts_hover"}}, wantErr: "already registered: other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			s := server.NewMCPServer("test", "test")
+			s.AddTool(mcp.NewTool("other"), nil)
+			err := registerToolSet(s, namedTools("default", &calls), tt.aliases, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if n := len(s.ListTools()); n != 1 {
+				t.Errorf("%d tools registered after the error", n)
+			}
+		})
+	}
+}
+
+func TestInstructions(t *testing.T) {
+	if got := Instructions(RegisterOptions{}); got != serverInstructions {
+		t.Errorf("default instructions changed:\n%s", got)
+	}
+	got := Instructions(RegisterOptions{Prefix: "tsmcp_", DisabledTools: []string{"ts_symbol_card", "tsmcp_open_files"}})
+	if m := toolNamePattern.FindString(got); m != "" {
+		t.Errorf("instructions still mention %s", m)
+	}
+	if strings.Contains(got, "tsmcp_symbol_card") {
+		t.Error("disabled tsmcp_symbol_card is listed")
+	}
+	for _, want := range []string{"- tsmcp_rename:", "- tsmcp_open_files / tsmcp_close_files:", "then tsmcp_apply_edit with the editToken"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("instructions lack %q", want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+type prepareRenameResult struct {
+	File      string `json:"file"`
+	CanRename bool   `json:"canRename"`
+	// Reason is tsgo's explanation when the position cannot be renamed.
+	Reason string `json:"reason,omitempty"`
+	// The range of the symbol ts_rename would rename, 1-based.
+	Line      int `json:"line,omitempty"`
+	Column    int `json:"column,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	EndColumn int `json:"endColumn,omitempty"`
+	// Text is the symbol's current text, read from the file.
+	Text        string `json:"text,omitempty"`
+	Placeholder string `json:"placeholder,omitempty"`
+	// VisualColumn is Column with tabs expanded, in columnMode "visual".
+	VisualColumn int `json:"visualColumn,omitempty"`
+}
+
+// rangeText returns the text of a single-line range of file as it is on
+// disk, or "" for a range spanning lines.
+func rangeText(file string, r protocol.Range) string {
+	if r.Start.Line != r.End.Line {
+		return ""
+	}
+	text, ok := currentLine(file, int(r.Start.Line)+1)
+	if !ok {
+		return ""
+	}
+	start := edit.ByteOffset(text, r.Start.Character)
+	end := edit.ByteOffset(text, r.End.Character)
+	if end < start {
+		return ""
+	}
+	return text[start:end]
+}
+
+// prepareRenameEntry converts the answer for the position line:col of
+// file. When tsgo leaves finding the symbol to the client, the
+// identifier at the position stands in for it.
+func prepareRenameEntry(file string, line, col int, prep *lsp.PrepareRenameResult, cols columnMode) prepareRenameResult {
+	result := prepareRenameResult{File: file, CanRename: prep.Reason == "", Reason: prep.Reason}
+	if !result.CanRename {
+		return result
+	}
+	if prep.DefaultBehavior {
+		text, _ := currentLine(file, line)
+		result.Line, result.Column, result.Text = line, col, identifierAt(text, col)
+	} else {
+		result.Line = int(prep.Range.Start.Line) + 1
+		result.Column = int(prep.Range.Start.Character) + 1
+		result.EndLine = int(prep.Range.End.Line) + 1
+		result.EndColumn = int(prep.Range.End.Character) + 1
+		result.Text = rangeText(file, prep.Range)
+		result.Placeholder = prep.Placeholder
+	}
+	result.VisualColumn = cols.visualColumn(file, result.Line, result.Column)
+	return result
+}
+
+func makePrepareRenameHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+
+		defer docs.Pin(file)()
+		// The range must match the file as ts_rename will see it.
+		if err := docs.ResyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		prep, err := client.PrepareRename(ctx, file, line, col)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("rename validation is unavailable: %v; use ts_rename with confirm to preview instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("prepare rename error: %v", err)), nil
+		}
+
+		data, err := json.MarshalIndent(prepareRenameEntry(file, line, col, prep, cols), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestPrepareRenameEntry(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.ts")
+	if err := os.WriteFile(file, []byte("const café = 1;\nconst ünï = café;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(ClearFileCache)
+
+	// ünï spans UTF-16 columns 6-9 of line 2.
+	prep := &lsp.PrepareRenameResult{Range: protocol.Range{
+		Start: protocol.Position{Line: 1, Character: 6},
+		End:   protocol.Position{Line: 1, Character: 9},
+	}}
+	got := prepareRenameEntry(file, 2, 8, prep, columnMode{})
+	want := prepareRenameResult{File: file, CanRename: true, Line: 2, Column: 7, EndLine: 2, EndColumn: 10, Text: "ünï"}
+	if got != want {
+		t.Errorf("entry = %+v, want %+v", got, want)
+	}
+
+	got = prepareRenameEntry(file, 2, 14, &lsp.PrepareRenameResult{DefaultBehavior: true}, columnMode{})
+	if !got.CanRename || got.Text != "café" || got.Line != 2 || got.Column != 14 {
+		t.Errorf("default behavior entry = %+v", got)
+	}
+
+	got = prepareRenameEntry(file, 1, 1, &lsp.PrepareRenameResult{Reason: "You cannot rename this element."}, columnMode{})
+	if got.CanRename || got.Reason != "You cannot rename this element." || got.Line != 0 || got.Text != "" {
+		t.Errorf("refused entry = %+v", got)
+	}
+}
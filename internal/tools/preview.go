@@ -0,0 +1,176 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultPreviewBudget is how many reference previews are read per call
+	// unless the caller asks for a different number.
+	defaultPreviewBudget = 100
+	// previewWorkers bounds concurrent file reads for previews.
+	previewWorkers = 8
+)
+
+// readLinesPartial returns the requested 1-based lines of file. Files
+// already in the line cache are served from it; otherwise the file is read
+// only up to the last requested line. Lines past the end of the file are
+// absent from the result.
+func readLinesPartial(file string, want []int) (map[int]string, error) {
+	out := make(map[int]string, len(want))
+	if len(want) == 0 {
+		return out, nil
+	}
+
+	fileLineCacheMu.Lock()
+	lines, cached := fileLineCache[file]
+	fileLineCacheMu.Unlock()
+	if cached {
+		for _, n := range want {
+			if n >= 1 && n <= len(lines) {
+				out[n] = lines[n-1]
+			}
+		}
+		return out, nil
+	}
+
+	wanted := make(map[int]bool, len(want))
+	last := 0
+	for _, n := range want {
+		wanted[n] = true
+		last = max(last, n)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for n := 1; n <= last; n++ {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		if wanted[n] {
+			out[n] = strings.TrimRight(line, "\r\n")
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return out, nil
+}
+
+// previewTarget is a location that wants a one-line preview.
+type previewTarget struct {
+	File string
+	Line int
+}
+
+// planPreviews picks which targets get a preview when there are more than
+// budget. Files with the most hits go first so the densest context survives;
+// ties keep the order in which files first appear. A negative budget means
+// unlimited. The result holds target indexes in input order.
+func planPreviews(targets []previewTarget, budget int) []int {
+	if budget < 0 || len(targets) <= budget {
+		all := make([]int, len(targets))
+		for i := range targets {
+			all[i] = i
+		}
+		return all
+	}
+
+	byFile := make(map[string][]int)
+	var order []string
+	for i, t := range targets {
+		if _, ok := byFile[t.File]; !ok {
+			order = append(order, t.File)
+		}
+		byFile[t.File] = append(byFile[t.File], i)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return len(byFile[order[i]]) > len(byFile[order[j]])
+	})
+
+	var picked []int
+	for _, f := range order {
+		for _, i := range byFile[f] {
+			if len(picked) == budget {
+				break
+			}
+			picked = append(picked, i)
+		}
+	}
+	sort.Ints(picked)
+	return picked
+}
+
+// loadPreviews reads the planned previews with a bounded pool of readers,
+// one read per file. It returns the trimmed preview line per target index
+// and whether each target was left out by the budget.
+func loadPreviews(ctx context.Context, targets []previewTarget, budget int) (previews map[int]string, omitted []bool) {
+	picked := planPreviews(targets, budget)
+	omitted = make([]bool, len(targets))
+	for i := range omitted {
+		omitted[i] = true
+	}
+
+	byFile := make(map[string][]int)
+	var files []string
+	for _, i := range picked {
+		omitted[i] = false
+		f := targets[i].File
+		if _, ok := byFile[f]; !ok {
+			files = append(files, f)
+		}
+		byFile[f] = append(byFile[f], i)
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, previewWorkers)
+	)
+	previews = make(map[int]string, len(picked))
+	for _, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		idx := byFile[f]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			lines := make([]int, len(idx))
+			for k, i := range idx {
+				lines[k] = targets[i].Line
+			}
+			got, err := readLinesPartial(f, lines)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			for _, i := range idx {
+				if l, ok := got[targets[i].Line]; ok {
+					previews[i] = strings.TrimSpace(l)
+				}
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return previews, omitted
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writePreviewFile(t testing.TB, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return p
+}
+
+func TestReadLinesPartial(t *testing.T) {
+	dir := t.TempDir()
+	ClearFileCache()
+	t.Cleanup(ClearFileCache)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []int
+		expect  map[int]string
+	}{
+		{"picks requested lines", "a\nb\nc\nd\n", []int{2, 4}, map[int]string{2: "b", 4: "d"}},
+		{"crlf", "a\r\nb\r\n", []int{1, 2}, map[int]string{1: "a", 2: "b"}},
+		{"no trailing newline", "a\nlast", []int{2}, map[int]string{2: "last"}},
+		{"past end of file", "a\nb\n", []int{1, 3, 9}, map[int]string{1: "a"}},
+		{"empty line", "a\n\nc\n", []int{2}, map[int]string{2: ""}},
+		{"nothing requested", "a\n", nil, map[int]string{}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := writePreviewFile(t, dir, fmt.Sprintf("f%d.ts", i), tt.content)
+			got, err := readLinesPartial(p, tt.want)
+			if err != nil {
+				t.Fatalf("readLinesPartial: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("got %q, want %q", got, tt.expect)
+			}
+		})
+	}
+
+	t.Run("served from cache", func(t *testing.T) {
+		p := writePreviewFile(t, dir, "cached.ts", "old\n")
+		if _, err := readLine(p, 1); err != nil {
+			t.Fatal(err)
+		}
+		writePreviewFile(t, dir, "cached.ts", "new\n")
+		got, err := readLinesPartial(p, []int{1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[1] != "old" {
+			t.Errorf("expected cached line, got %q", got[1])
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := readLinesPartial(filepath.Join(dir, "gone.ts"), []int{1}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestPlanPreviews(t *testing.T) {
+	targets := []previewTarget{
+		{"a.ts", 1}, // 0
+		{"b.ts", 1}, // 1
+		{"b.ts", 2}, // 2
+		{"c.ts", 1}, // 3
+		{"b.ts", 3}, // 4
+		{"c.ts", 2}, // 5
+	}
+	tests := []struct {
+		name   string
+		budget int
+		want   []int
+	}{
+		{"unlimited", -1, []int{0, 1, 2, 3, 4, 5}},
+		{"within budget", 6, []int{0, 1, 2, 3, 4, 5}},
+		{"densest file first", 3, []int{1, 2, 4}},
+		{"ties keep first appearance", 5, []int{1, 2, 3, 4, 5}},
+		{"zero", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planPreviews(targets, tt.budget); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planPreviews(budget=%d) = %v, want %v", tt.budget, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPreviews(t *testing.T) {
+	dir := t.TempDir()
+	ClearFileCache()
+	t.Cleanup(ClearFileCache)
+	a := writePreviewFile(t, dir, "a.ts", "  const a = 1;\n")
+	b := writePreviewFile(t, dir, "b.ts", "use(a);\n\nuse(a, a);\n")
+
+	targets := []previewTarget{{a, 1}, {b, 1}, {b, 3}, {filepath.Join(dir, "gone.ts"), 1}}
+	previews, omitted := loadPreviews(context.Background(), targets, 2)
+	if want := map[int]string{1: "use(a);", 2: "use(a, a);"}; !reflect.DeepEqual(previews, want) {
+		t.Errorf("previews = %q, want %q", previews, want)
+	}
+	if want := []bool{true, false, false, true}; !reflect.DeepEqual(omitted, want) {
+		t.Errorf("omitted = %v, want %v", omitted, want)
+	}
+
+	previews, omitted = loadPreviews(context.Background(), targets, -1)
+	if previews[0] != "const a = 1;" || omitted[3] {
+		t.Errorf("unlimited: previews = %q, omitted = %v", previews, omitted)
+	}
+	if _, ok := previews[3]; ok {
+		t.Error("unreadable file must have no preview")
+	}
+}
+
+// BenchmarkReferencePreviews compares reading every file in full for each
+// reference (the previous behavior) with the bounded, partial reader on a
+// synthetic tree of 500 files with hits near the top.
+func BenchmarkReferencePreviews(b *testing.B) {
+	dir := b.TempDir()
+	body := strings.Repeat("export const filler = 'xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx';\n", 2000)
+	var targets []previewTarget
+	for i := 0; i < 500; i++ {
+		p := writePreviewFile(b, dir, fmt.Sprintf("m%03d.ts", i), "import { x } from './x';\nx();\n"+body)
+		targets = append(targets, previewTarget{p, 1}, previewTarget{p, 2})
+	}
+	b.Cleanup(ClearFileCache)
+
+	b.Run("full-read", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			ClearFileCache()
+			for _, t := range targets {
+				if l, err := readLine(t.File, t.Line); err == nil {
+					_ = strings.TrimSpace(l)
+				}
+			}
+		}
+	})
+	b.Run("partial-unbounded", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			ClearFileCache()
+			loadPreviews(context.Background(), targets, -1)
+		}
+	})
+	b.Run("partial-default-budget", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			ClearFileCache()
+			loadPreviews(context.Background(), targets, defaultPreviewBudget)
+		}
+	})
+}
@@ -3,7 +3,9 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -11,42 +13,139 @@ import (
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
 	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
 )
 
+// defaultProjectFileResults caps the files ts_project_info lists.
+const defaultProjectFileResults = 200
+
 type projectInfoResult struct {
 	TsconfigPath string `json:"tsconfigPath,omitempty"`
 	ProjectRoot  string `json:"projectRoot,omitempty"`
+	// TsgoVersion is the version of the running tsgo.
+	TsgoVersion string `json:"tsgoVersion,omitempty"`
+	// ModuleResolution is the effective moduleResolution of the tsconfig,
+	// derived from "module" when not set explicitly.
+	ModuleResolution tsconfig.ResolutionMode `json:"moduleResolution,omitempty"`
+	// Config is the tsconfig with its extends chain applied.
+	Config *tsconfig.Effective `json:"config,omitempty"`
+	// ConfigError is set when the tsconfig or a config it extends cannot
+	// be read or parsed.
+	ConfigError *configError `json:"configError,omitempty"`
+	// SourceFiles are the files the project compiles, with listFiles.
+	SourceFiles *projectFiles `json:"sourceFiles,omitempty"`
+	// Misconfiguration is set when the server's workspace root contains no
+	// TypeScript files.
+	Misconfiguration *workspace.Status `json:"misconfiguration,omitempty"`
+	// SymbolCache reports document symbol cache effectiveness.
+	SymbolCache symbolCacheStats `json:"symbolCache"`
+	// Environment describes the tooling around the project.
+	Environment projectEnvironment `json:"environment"`
+}
+
+// projectFiles are the files the tsconfig's files/include/exclude rules
+// select, at most maxResults of them.
+type projectFiles struct {
+	Count     int      `json:"count"`
+	Files     []string `json:"files"`
+	Truncated bool     `json:"truncated"`
+}
+
+// newProjectFiles caps files at max.
+func newProjectFiles(files []string, max int) *projectFiles {
+	pf := &projectFiles{Count: len(files), Files: files}
+	if len(files) > max {
+		pf.Files = files[:max]
+		pf.Truncated = true
+	}
+	return pf
+}
+
+// configError is a tsconfig that failed to load, and the file at fault.
+type configError struct {
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// newConfigError describes err loading configPath, naming the config of
+// the extends chain that failed when there is one.
+func newConfigError(configPath string, err error) *configError {
+	var parseErr *tsconfig.ParseError
+	if errors.As(err, &parseErr) {
+		return &configError{File: parseErr.File, Message: parseErr.Err.Error()}
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &configError{File: pathErr.Path, Message: pathErr.Err.Error()}
+	}
+	return &configError{File: configPath, Message: err.Error()}
 }
 
-func makeProjectInfoHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+// projectEnvironment is the environment of the project directory with the
+// effective strictness flags of its tsconfig.
+type projectEnvironment struct {
+	workspace.Environment
+	Strictness map[string]bool `json:"strictness,omitempty"`
+}
+
+func makeProjectInfoHandler(client *lsp.Client, docs *docsync.Manager, probe *workspace.Prober, symbolCache *symbolCache, environments *workspace.EnvironmentCache) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		tsconfig := request.GetString("tsconfig", "")
+		configPath := request.GetString("tsconfig", "")
 		cwd := request.GetString("cwd", "")
+		listFiles := request.GetBool("listFiles", false)
+		maxResults := request.GetInt("maxResults", defaultProjectFileResults)
+		if maxResults < 1 {
+			return mcp.NewToolResultError(fmt.Sprintf("maxResults must be >= 1, got %d", maxResults)), nil
+		}
 
-		_ = client // client will be used once LSP provides project info capabilities
 		_ = docs
 
 		// If tsconfig is not specified, try to discover it
-		if tsconfig == "" {
+		if configPath == "" {
 			if cwd == "" {
-				var err error
-				cwd, err = os.Getwd()
-				if err != nil {
-					return mcp.NewToolResultError(fmt.Sprintf("cannot determine working directory: %v", err)), nil
-				}
+				cwd = client.RootDir()
 			}
 			candidate := filepath.Join(cwd, "tsconfig.json")
 			if _, err := os.Stat(candidate); err == nil {
-				tsconfig = candidate
+				configPath = candidate
 			}
 		}
 
 		result := projectInfoResult{
-			TsconfigPath: tsconfig,
+			TsconfigPath: configPath,
+			TsgoVersion:  client.TsgoVersion(),
+			SymbolCache:  symbolCache.Stats(),
+		}
+
+		envDir := cwd
+		if configPath != "" {
+			result.ProjectRoot = filepath.Dir(configPath)
+			envDir = result.ProjectRoot
+			if cfg, err := tsconfig.Load(configPath); err == nil {
+				result.ModuleResolution = cfg.ModuleResolution()
+			}
+			if eff, err := tsconfig.LoadEffective(configPath); err == nil {
+				result.Config = eff
+			} else {
+				result.ConfigError = newConfigError(configPath, err)
+			}
+			if flags, err := tsconfig.Strictness(configPath); err == nil {
+				result.Environment.Strictness = flags
+			}
+		}
+		if abs, err := filepath.Abs(envDir); err == nil {
+			result.Environment.Environment = environments.Get(abs)
+			if listFiles && configPath == "" {
+				result.SourceFiles = newProjectFiles(workspace.SourceFiles(abs), maxResults)
+			}
+		}
+		if listFiles && result.Config != nil {
+			result.SourceFiles = newProjectFiles(workspace.ProjectFiles(result.Config.Config()), maxResults)
 		}
 
-		if tsconfig != "" {
-			result.ProjectRoot = filepath.Dir(tsconfig)
+		if st := probe.Status(); !st.HasTypeScript {
+			result.Misconfiguration = &st
 		}
 
 		data, err := json.MarshalIndent(result, "", "  ")
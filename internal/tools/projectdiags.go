@@ -0,0 +1,260 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+const (
+	// projectDiagnosticsBatch is how many files ts_project_diagnostics
+	// opens at a time. Each batch is closed again once checked, so a large
+	// project never has all its files open with tsgo.
+	projectDiagnosticsBatch = 50
+	// maxFileDiagnostics bounds the diagnostics listed for each file.
+	maxFileDiagnostics = 20
+	// defaultProjectDiagnosticsTimeout is how long ts_project_diagnostics
+	// checks files before returning what it has.
+	defaultProjectDiagnosticsTimeout = 120
+)
+
+// severityLevels maps the values of the severity filter to LSP
+// severities, which number the most severe lowest.
+var severityLevels = map[string]protocol.DiagnosticSeverity{
+	"error":       protocol.DiagnosticSeverityError,
+	"warning":     protocol.DiagnosticSeverityWarning,
+	"information": protocol.DiagnosticSeverityInformation,
+	"hint":        protocol.DiagnosticSeverityHint,
+}
+
+type fileDiagnostics struct {
+	File        string            `json:"file"`
+	Errors      int               `json:"errors"`
+	Warnings    int               `json:"warnings"`
+	Information int               `json:"information,omitempty"`
+	Hints       int               `json:"hints,omitempty"`
+	Diagnostics []diagnosticEntry `json:"diagnostics"`
+	// More is the number of the file's diagnostics past
+	// maxFileDiagnostics.
+	More int `json:"more,omitempty"`
+}
+
+type projectDiagnosticsResult struct {
+	// Tsconfig is the config the files were selected by; empty when the
+	// project root was walked instead.
+	Tsconfig     string `json:"tsconfig,omitempty"`
+	FilesTotal   int    `json:"filesTotal"`
+	FilesChecked int    `json:"filesChecked"`
+	Errors       int    `json:"errors"`
+	Warnings     int    `json:"warnings"`
+	Information  int    `json:"information"`
+	Hints        int    `json:"hints"`
+	// FilesWithDiagnostics counts the files listed in Files and those
+	// maxResults left out.
+	FilesWithDiagnostics int `json:"filesWithDiagnostics"`
+	// Files are the files with diagnostics, the most errors first.
+	Files []fileDiagnostics `json:"files"`
+	// Truncated is set when more files had diagnostics than maxResults.
+	Truncated bool `json:"truncated"`
+	// TimedOut is set when the deadline passed before every file was
+	// checked; the counts cover the FilesChecked files.
+	TimedOut bool   `json:"timedOut"`
+	Elapsed  string `json:"elapsed"`
+}
+
+// tallyDiagnostics counts the diagnostics of file at least as severe as
+// minimum and lists the first of them in position order. It returns false
+// when none are left.
+func tallyDiagnostics(file string, diags []protocol.Diagnostic, minimum protocol.DiagnosticSeverity) (fileDiagnostics, bool) {
+	fd := fileDiagnostics{File: file}
+	var entries []diagnosticEntry
+	for _, d := range diags {
+		sev := d.Severity
+		if sev == 0 {
+			sev = protocol.DiagnosticSeverityError
+		}
+		if sev > minimum {
+			continue
+		}
+		switch sev {
+		case protocol.DiagnosticSeverityError:
+			fd.Errors++
+		case protocol.DiagnosticSeverityWarning:
+			fd.Warnings++
+		case protocol.DiagnosticSeverityInformation:
+			fd.Information++
+		default:
+			fd.Hints++
+		}
+		entries = append(entries, newDiagnosticEntry(file, d))
+	}
+	if len(entries) == 0 {
+		return fd, false
+	}
+	sortDiagnosticEntries(entries)
+	if len(entries) > maxFileDiagnostics {
+		fd.More = len(entries) - maxFileDiagnostics
+		entries = entries[:maxFileDiagnostics]
+	}
+	fd.Diagnostics = entries
+	return fd, true
+}
+
+// rankFileDiagnostics sorts files by errors, then warnings, then path,
+// and keeps at most max. It reports whether any were dropped.
+func rankFileDiagnostics(files []fileDiagnostics, max int) ([]fileDiagnostics, bool) {
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if a.Errors != b.Errors {
+			return a.Errors > b.Errors
+		}
+		if a.Warnings != b.Warnings {
+			return a.Warnings > b.Warnings
+		}
+		return a.File < b.File
+	})
+	if len(files) > max {
+		return files[:max], true
+	}
+	return files, false
+}
+
+// projectDiagnosticFiles returns the files of the project: those the
+// tsconfig selects, or without a tsconfig at the root, the TypeScript
+// files under it. It also returns the config used.
+func projectDiagnosticFiles(request mcp.CallToolRequest, root string) ([]string, string, error) {
+	configPath := request.GetString("tsconfig", "")
+	explicit := configPath != ""
+	if !explicit {
+		configPath = filepath.Join(root, "tsconfig.json")
+	}
+	cfg, err := tsconfig.Load(configPath)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return workspace.SourceFiles(root), "", nil
+		}
+		return nil, "", fmt.Errorf("tsconfig error: %v", err)
+	}
+	return workspace.ProjectFiles(cfg), configPath, nil
+}
+
+// projectDiagnosticsBatchSize returns how many of files to open at a time
+// within the open document limit of docs. It fails when the limit is
+// reached and some of files are not open yet; closeTool is the name of
+// ts_close_files to suggest.
+func projectDiagnosticsBatchSize(docs *docsync.Manager, files []string, closeTool string) (int, error) {
+	limit := docs.MaxOpen()
+	if limit <= 0 {
+		return projectDiagnosticsBatch, nil
+	}
+	room := limit - len(docs.OpenFiles())
+	closed := slices.ContainsFunc(files, func(f string) bool {
+		_, open := docs.Version(f)
+		return !open
+	})
+	if room < 1 && closed {
+		return 0, fmt.Errorf("open document limit (%d) reached; close files with %s or raise TYPESCRIPT_MCP_MAX_OPEN_DOCS", limit, closeTool)
+	}
+	return max(1, min(projectDiagnosticsBatch, room)), nil
+}
+
+func makeProjectDiagnosticsHandler(client *lsp.Client, docs *docsync.Manager, closeTool string) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		start := time.Now()
+		maxResults := request.GetInt("maxResults", 50)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+		severity := request.GetString("severity", "hint")
+		minimum, ok := severityLevels[severity]
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid severity %q (valid: error, warning, information, hint)", severity)), nil
+		}
+		timeout := request.GetInt("timeoutSeconds", defaultProjectDiagnosticsTimeout)
+		if timeout < 1 {
+			return mcp.NewToolResultError("timeoutSeconds must be at least 1"), nil
+		}
+		files, configPath, err := projectDiagnosticFiles(request, client.RootDir())
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		batch, err := projectDiagnosticsBatchSize(docs, files, closeTool)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+
+		result := projectDiagnosticsResult{Tsconfig: configPath, FilesTotal: len(files)}
+		var withDiags []fileDiagnostics
+		for i := 0; i < len(files); i += batch {
+			chunk := files[i:min(i+batch, len(files))]
+			var opened []string
+			for _, f := range chunk {
+				if _, open := docs.Version(f); !open {
+					opened = append(opened, f)
+				}
+			}
+			err := docs.SyncFiles(checkCtx, client.Conn(), chunk)
+			for _, f := range chunk {
+				if err != nil {
+					break
+				}
+				var diags []protocol.Diagnostic
+				if diags, err = client.Diagnostic(checkCtx, f); err != nil {
+					break
+				}
+				result.FilesChecked++
+				if fd, ok := tallyDiagnostics(f, diags, minimum); ok {
+					result.Errors += fd.Errors
+					result.Warnings += fd.Warnings
+					result.Information += fd.Information
+					result.Hints += fd.Hints
+					withDiags = append(withDiags, fd)
+				}
+			}
+			// Close what this batch opened, with ctx since checkCtx may be
+			// done. Files another call pinned meanwhile stay open.
+			if _, _, closeErr := docs.CloseFiles(ctx, client.Conn(), opened); closeErr != nil && err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				if checkCtx.Err() != nil && ctx.Err() == nil {
+					result.TimedOut = true
+					break
+				}
+				return mcp.NewToolResultError(fmt.Sprintf("diagnostic error: %v", err)), nil
+			}
+		}
+
+		result.FilesWithDiagnostics = len(withDiags)
+		result.Files, result.Truncated = rankFileDiagnostics(withDiags, maxResults)
+		if result.Files == nil {
+			result.Files = []fileDiagnostics{}
+		}
+		result.Elapsed = time.Since(start).Round(time.Millisecond).String()
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+)
+
+func diagAt(line uint32, sev protocol.DiagnosticSeverity, message string) protocol.Diagnostic {
+	return protocol.Diagnostic{
+		Range:    protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line, Character: 1}},
+		Severity: sev,
+		Code:     float64(2322),
+		Message:  message,
+	}
+}
+
+func TestTallyDiagnostics(t *testing.T) {
+	diags := []protocol.Diagnostic{
+		diagAt(9, protocol.DiagnosticSeverityWarning, "warning"),
+		diagAt(4, 0, "no severity"),
+		diagAt(2, protocol.DiagnosticSeverityError, "error"),
+		diagAt(7, protocol.DiagnosticSeverityHint, "hint"),
+		diagAt(8, protocol.DiagnosticSeverityInformation, "information"),
+	}
+
+	fd, ok := tallyDiagnostics("/p/a.ts", diags, protocol.DiagnosticSeverityHint)
+	if !ok || fd.Errors != 2 || fd.Warnings != 1 || fd.Information != 1 || fd.Hints != 1 || fd.More != 0 {
+		t.Fatalf("all severities = %+v", fd)
+	}
+	var lines []int
+	for _, d := range fd.Diagnostics {
+		lines = append(lines, d.Line)
+	}
+	if fmt.Sprint(lines) != "[3 5 8 9 10]" {
+		t.Errorf("lines = %v, want position order", lines)
+	}
+
+	fd, ok = tallyDiagnostics("/p/a.ts", diags, protocol.DiagnosticSeverityWarning)
+	if !ok || fd.Errors != 2 || fd.Warnings != 1 || fd.Information+fd.Hints != 0 || len(fd.Diagnostics) != 3 {
+		t.Errorf("warnings and up = %+v", fd)
+	}
+
+	if _, ok := tallyDiagnostics("/p/a.ts", diags[3:], protocol.DiagnosticSeverityError); ok {
+		t.Error("file without errors reported with the error filter")
+	}
+
+	var many []protocol.Diagnostic
+	for i := range maxFileDiagnostics + 3 {
+		many = append(many, diagAt(uint32(i), protocol.DiagnosticSeverityError, "error"))
+	}
+	fd, _ = tallyDiagnostics("/p/a.ts", many, protocol.DiagnosticSeverityHint)
+	if fd.Errors != maxFileDiagnostics+3 || len(fd.Diagnostics) != maxFileDiagnostics || fd.More != 3 {
+		t.Errorf("capped = %d errors, %d listed, %d more", fd.Errors, len(fd.Diagnostics), fd.More)
+	}
+}
+
+func TestRankFileDiagnostics(t *testing.T) {
+	files := []fileDiagnostics{
+		{File: "/p/c.ts", Errors: 1},
+		{File: "/p/a.ts", Warnings: 4},
+		{File: "/p/b.ts", Errors: 1, Warnings: 2},
+		{File: "/p/d.ts", Errors: 5},
+		{File: "/p/0.ts", Errors: 1},
+	}
+	got, truncated := rankFileDiagnostics(files, 4)
+	var order []string
+	for _, f := range got {
+		order = append(order, f.File)
+	}
+	if fmt.Sprint(order) != "[/p/d.ts /p/b.ts /p/0.ts /p/c.ts]" || !truncated {
+		t.Errorf("ranked = %v (truncated %v)", order, truncated)
+	}
+	if _, truncated := rankFileDiagnostics(files, 5); truncated {
+		t.Error("truncated with room for every file")
+	}
+}
+
+func TestProjectDiagnosticsBatchSize(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.ts", "b.ts", "c.ts"} {
+		p := filepath.Join(dir, name)
+		writeString(t, p, "export {};\n")
+		files = append(files, p)
+	}
+	tests := []struct {
+		name    string
+		limit   int
+		open    []string
+		want    int
+		wantErr bool
+	}{
+		{name: "no limit", want: projectDiagnosticsBatch},
+		{name: "room left", limit: 3, open: files[:1], want: 2},
+		{name: "limit reached with files to open", limit: 2, open: files[:2], wantErr: true},
+		{name: "limit reached with every file open", limit: 3, open: files, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs := docsync.NewManager()
+			docs.SetMaxOpen(tt.limit)
+			if err := docs.SyncFiles(context.Background(), &didChangeConn{}, tt.open); err != nil {
+				t.Fatal(err)
+			}
+			got, err := projectDiagnosticsBatchSize(docs, files, "ts_close_files")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "close files with ts_close_files") {
+					t.Errorf("error = %v, want one naming ts_close_files", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("batch = %d, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+}
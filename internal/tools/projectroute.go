@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// defaultProjectIdleTTL is how long the tsgo of a project outside the
+// workspace root outlives its last tool call.
+const defaultProjectIdleTTL = 10 * time.Minute
+
+// projectIdleTTLFromEnv reads TYPESCRIPT_MCP_PROJECT_IDLE_TTL, a Go
+// duration, falling back to defaultProjectIdleTTL.
+func projectIdleTTLFromEnv() time.Duration {
+	d, err := time.ParseDuration(os.Getenv("TYPESCRIPT_MCP_PROJECT_IDLE_TTL"))
+	if err != nil || d <= 0 {
+		return defaultProjectIdleTTL
+	}
+	return d
+}
+
+// projectStarter starts a tsgo rooted at root and returns it with the
+// tools bound to it.
+type projectStarter func(root string) (*lsp.Client, []registeredTool)
+
+// projectRouter sends the tool calls about a project outside the
+// workspace root to a tsgo started for that project. Projects inside the
+// root stay with the workspace's tsgo, which already picks the nearest
+// tsconfig of every file. A project's tsgo is stopped once it has been
+// idle for ttl.
+type projectRouter struct {
+	root  string
+	ttl   time.Duration
+	start projectStarter
+
+	mu       sync.Mutex
+	projects map[string]*routedProject
+	reaping  bool
+}
+
+// routedProject is a tsgo started for one project.
+type routedProject struct {
+	root     string
+	client   *lsp.Client
+	handlers map[string]server.ToolHandlerFunc
+	active   int
+	lastUsed time.Time
+}
+
+func newProjectRouter(root string, ttl time.Duration, start projectStarter) *projectRouter {
+	return &projectRouter{root: root, ttl: ttl, start: start, projects: make(map[string]*routedProject)}
+}
+
+// route wraps the workspace's handler of the tool name: a call whose
+// tsconfig, or file, belongs to a project outside the workspace root
+// runs the project's own handler instead.
+func (r *projectRouter) route(name string, workspaceHandler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		root := r.projectRoot(request)
+		if root == "" {
+			return workspaceHandler(ctx, request)
+		}
+		p := r.acquire(root)
+		defer r.release(p)
+		if h := p.handlers[name]; h != nil {
+			return h(ctx, request)
+		}
+		return workspaceHandler(ctx, request)
+	}
+}
+
+// projectRoot returns the directory of the project a call is about when
+// it lies outside the workspace root: that of the tsconfig argument, or
+// else of the tsconfig nearest to the file or path argument. It returns
+// "" for the workspace's own projects.
+func (r *projectRouter) projectRoot(request mcp.CallToolRequest) string {
+	var dir string
+	if config := request.GetString("tsconfig", ""); config != "" {
+		dir = filepath.Dir(config)
+	} else {
+		target := request.GetString("file", "")
+		if target == "" {
+			target = request.GetString("path", "")
+		}
+		if target == "" || !filepath.IsAbs(target) || pathWithin(r.root, target) {
+			return ""
+		}
+		dir = nearestConfigDir(target)
+	}
+	if dir == "" || !filepath.IsAbs(dir) || pathWithin(r.root, dir) {
+		return ""
+	}
+	return filepath.Clean(dir)
+}
+
+// nearestConfigDir returns the closest directory at or above path holding
+// a tsconfig.json, or "" when there is none.
+func nearestConfigDir(path string) string {
+	dir := filepath.Clean(path)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "tsconfig.json")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// pathWithin reports whether path is root or below it.
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// acquire returns the project serving root, starting it unless a running
+// project's root contains root, and marks it in use.
+func (r *projectRouter) acquire(root string) *routedProject {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var p *routedProject
+	for _, candidate := range r.projects {
+		if pathWithin(candidate.root, root) {
+			p = candidate
+			break
+		}
+	}
+	if p == nil {
+		client, tools := r.start(root)
+		p = &routedProject{root: root, client: client, handlers: make(map[string]server.ToolHandlerFunc, len(tools))}
+		for _, t := range tools {
+			p.handlers[t.tool.Name] = t.handler
+		}
+		r.projects[root] = p
+		slog.Info("started tsgo for a project outside the workspace root", "root", root)
+		if !r.reaping {
+			r.reaping = true
+			go r.reapLoop()
+		}
+	}
+	p.active++
+	return p
+}
+
+func (r *projectRouter) release(p *routedProject) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p.active--
+	p.lastUsed = time.Now()
+}
+
+// reapLoop stops idle projects for the life of the process.
+func (r *projectRouter) reapLoop() {
+	interval := max(r.ttl/2, time.Second)
+	for now := range time.Tick(interval) {
+		r.reap(now)
+	}
+}
+
+// reap stops the projects that have had no call running for ttl, and
+// returns their roots.
+func (r *projectRouter) reap(now time.Time) []string {
+	r.mu.Lock()
+	var idle []*routedProject
+	for root, p := range r.projects {
+		if p.active == 0 && now.Sub(p.lastUsed) >= r.ttl {
+			idle = append(idle, p)
+			delete(r.projects, root)
+		}
+	}
+	r.mu.Unlock()
+
+	roots := make([]string, 0, len(idle))
+	for _, p := range idle {
+		slog.Info("stopping idle project tsgo", "root", p.root)
+		if err := p.client.Close(); err != nil {
+			slog.Warn("stopping idle project tsgo", "root", p.root, "err", err)
+		}
+		roots = append(roots, p.root)
+	}
+	return roots
+}
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestProjectRouter(t *testing.T) {
+	base := t.TempDir()
+	workspace := filepath.Join(base, "workspace")
+	for _, dir := range []string{"workspace/pkg", "other/packages/core/src", "other/packages/app", "loose"} {
+		if err := os.MkdirAll(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, config := range []string{"workspace/tsconfig.json", "other/tsconfig.json", "other/packages/core/tsconfig.json"} {
+		if err := os.WriteFile(filepath.Join(base, config), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var started []string
+	answer := func(text string) registeredTool {
+		return registeredTool{
+			tool: mcp.NewTool("ts_hover"),
+			handler: func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultText(text), nil
+			},
+		}
+	}
+	router := newProjectRouter(workspace, time.Minute, func(root string) (*lsp.Client, []registeredTool) {
+		started = append(started, root)
+		client := lsp.StartClient(context.Background(), docsync.FileToURI(root), func(context.Context) (*lsp.TsgoProcess, error) {
+			return nil, errors.New("no tsgo in tests")
+		})
+		return client, []registeredTool{answer(root)}
+	})
+	t.Cleanup(func() { router.reap(time.Now().Add(time.Hour)) })
+	handler := router.route("ts_hover", answer("workspace").handler)
+
+	call := func(args map[string]any) string {
+		var req mcp.CallToolRequest
+		req.Params.Arguments = args
+		res, err := handler(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Content[0].(mcp.TextContent).Text
+	}
+	other := filepath.Join(base, "other")
+	tests := []struct {
+		name string
+		args map[string]any
+		want string
+	}{
+		{"no target", nil, "workspace"},
+		{"file in the workspace", map[string]any{"file": filepath.Join(workspace, "pkg", "a.ts")}, "workspace"},
+		{"tsconfig in the workspace", map[string]any{"tsconfig": filepath.Join(workspace, "tsconfig.json")}, "workspace"},
+		{"relative file", map[string]any{"file": "a.ts"}, "workspace"},
+		{"tsconfig outside", map[string]any{"tsconfig": filepath.Join(other, "tsconfig.json")}, other},
+		{"file under a running project", map[string]any{"file": filepath.Join(other, "packages", "core", "src", "a.ts")}, other},
+		{"file without a tsconfig", map[string]any{"file": filepath.Join(base, "loose", "a.ts")}, "workspace"},
+		{"path argument", map[string]any{"path": filepath.Join(other, "packages", "app")}, other},
+	}
+	for _, tt := range tests {
+		if got := call(tt.args); got != tt.want {
+			t.Errorf("%s: served by %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if !slices.Equal(started, []string{other}) {
+		t.Errorf("started %v, want only %s", started, other)
+	}
+
+	if reaped := router.reap(time.Now()); len(reaped) != 0 {
+		t.Errorf("reaped %v before the TTL", reaped)
+	}
+	if reaped := router.reap(time.Now().Add(time.Minute)); !slices.Equal(reaped, []string{other}) {
+		t.Errorf("reaped %v after the TTL, want %s", reaped, other)
+	}
+	core := filepath.Join(other, "packages", "core")
+	if got := call(map[string]any{"file": filepath.Join(core, "src", "a.ts")}); got != core {
+		t.Errorf("after reaping, served by %s, want a new tsgo for %s", got, core)
+	}
+}
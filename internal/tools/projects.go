@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+type listProjectsResult struct {
+	Root string `json:"root"`
+	// Projects are ordered so that each comes after the projects it
+	// references.
+	Projects []workspace.Project `json:"projects"`
+	// Owner answers the file parameter.
+	Owner *projectOwner `json:"owner,omitempty"`
+}
+
+// projectOwner is the project compiling a file. Included is false when
+// Config is only the nearest project and does not select the file.
+type projectOwner struct {
+	File     string `json:"file"`
+	Config   string `json:"config,omitempty"`
+	Included bool   `json:"included"`
+}
+
+func makeListProjectsHandler(client *lsp.Client) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		root := request.GetString("root", "")
+		if root == "" {
+			root = client.RootDir()
+		}
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid root: %v", err)), nil
+		}
+		result := listProjectsResult{Root: root, Projects: workspace.DiscoverProjects(root)}
+		if file := request.GetString("file", ""); file != "" {
+			abs, err := filepath.Abs(file)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid file: %v", err)), nil
+			}
+			result.Owner = &projectOwner{File: abs}
+			if p, included := workspace.OwningProject(result.Projects, abs); p != nil {
+				result.Owner.Config = p.Config
+				result.Owner.Included = included
+			}
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,563 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const (
+	// editRecordVersion is the record layout written by this server. Bump
+	// it when the layout changes incompatibly.
+	editRecordVersion = 1
+	// defaultEditRecordLimit is the number of records kept when the config
+	// does not say otherwise; older ones are pruned.
+	defaultEditRecordLimit = 200
+)
+
+// editRecord is the provenance record of one applied edit, stored as
+// <id>.json under .typescript-mcp/edits. The hunks hold the changed lines
+// of each file, so the record alone can revert the edit. Readers ignore
+// unknown fields and refuse versions newer than their own.
+type editRecord struct {
+	Version int       `json:"version"`
+	ID      string    `json:"id"`
+	Tool    string    `json:"tool"`
+	Time    time.Time `json:"time"`
+	// Symbol is the symbol the edit was made for, e.g. the old name of a
+	// rename.
+	Symbol string         `json:"symbol,omitempty"`
+	Files  []recordedFile `json:"files"`
+	// Undoes is the id of the record an undo reverted.
+	Undoes string `json:"undoes,omitempty"`
+	// UndoneAt is set once the edit has been undone.
+	UndoneAt *time.Time `json:"undoneAt,omitempty"`
+}
+
+// recordedFile is one file of a recorded edit.
+type recordedFile struct {
+	Path       string         `json:"path"`
+	Mode       os.FileMode    `json:"mode"`
+	BeforeHash string         `json:"beforeHash"`
+	AfterHash  string         `json:"afterHash"`
+	Hunks      []recordedHunk `json:"hunks"`
+	// Created is set when the edit created the file, along with the
+	// directories it created for it, deepest first. Deleted is set when
+	// the edit removed the file, as a delete or rename operation or the
+	// undo of a creation does.
+	Created bool     `json:"created,omitempty"`
+	NewDirs []string `json:"newDirs,omitempty"`
+	Deleted bool     `json:"deleted,omitempty"`
+}
+
+// recordedHunk is a run of changed lines. Starts are 1-based; a hunk that
+// only inserts or only deletes has the position of the line following it
+// on the empty side. Lines keep their line endings.
+type recordedHunk struct {
+	OldStart int      `json:"oldStart"`
+	OldLines int      `json:"oldLines"`
+	NewStart int      `json:"newStart"`
+	NewLines int      `json:"newLines"`
+	Removed  []string `json:"removed,omitempty"`
+	Added    []string `json:"added,omitempty"`
+}
+
+// editOrigin is the tool call an edit comes from.
+type editOrigin struct {
+	tool   string
+	symbol string
+	undoes string
+}
+
+// editRecorder keeps the provenance records of a workspace. Records are
+// read even when recording is off, so edits recorded earlier can still be
+// listed and undone.
+type editRecorder struct {
+	dir     string // <root>/.typescript-mcp/edits
+	enabled bool
+	limit   int
+	now     func() time.Time
+}
+
+// newEditRecorder returns the recorder for the workspace root.
+func newEditRecorder(root string, cfg *configFile) *editRecorder {
+	r := &editRecorder{
+		dir:     filepath.Join(root, ".typescript-mcp", "edits"),
+		enabled: cfg.RecordEdits,
+		limit:   defaultEditRecordLimit,
+		now:     time.Now,
+	}
+	if cfg.RecordLimit > 0 {
+		r.limit = cfg.RecordLimit
+	}
+	return r
+}
+
+// editRecording is an edit to be recorded once its files are written.
+type editRecording struct {
+	recorder *editRecorder
+	origin   editOrigin
+}
+
+// recording returns the recording of an edit from origin, or nil when
+// recording is off.
+func (r *editRecorder) recording(origin editOrigin) *editRecording {
+	if r == nil || !r.enabled {
+		return nil
+	}
+	return &editRecording{recorder: r, origin: origin}
+}
+
+// pendingRecord is a record written to a temporary file before the edit,
+// published by commit once every file is written and removed by discard
+// when the edit is rolled back. A nil pendingRecord does nothing.
+type pendingRecord struct {
+	recorder *editRecorder
+	id       string
+	tmp      string
+}
+
+// prepare writes the record of work to a temporary file.
+func (rec *editRecording) prepare(work []edit.File) (*pendingRecord, error) {
+	if rec == nil {
+		return nil, nil
+	}
+	r := rec.recorder
+	now := r.now()
+	id, err := newEditID(now)
+	if err != nil {
+		return nil, err
+	}
+	record := &editRecord{
+		Version: editRecordVersion,
+		ID:      id,
+		Tool:    rec.origin.tool,
+		Time:    now.UTC(),
+		Symbol:  rec.origin.symbol,
+		Undoes:  rec.origin.undoes,
+	}
+	for _, w := range work {
+		record.Files = append(record.Files, recordedFile{
+			Path:       w.Path,
+			Mode:       w.Mode,
+			BeforeHash: hashContent(w.Original),
+			AfterHash:  hashContent(w.Updated),
+			Hunks:      lineHunks(w.Original, w.Updated),
+			Created:    w.Created,
+			NewDirs:    w.NewDirs,
+			Deleted:    w.Deleted,
+		})
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating edit records: %w", err)
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding edit record: %w", err)
+	}
+	p := &pendingRecord{recorder: r, id: id, tmp: filepath.Join(r.dir, id+".json.tmp")}
+	if err := os.WriteFile(p.tmp, data, 0o600); err != nil {
+		_ = os.Remove(p.tmp)
+		return nil, fmt.Errorf("writing edit record: %w", err)
+	}
+	return p, nil
+}
+
+// commit publishes the record and prunes the oldest records beyond the
+// limit.
+func (p *pendingRecord) commit() error {
+	if p == nil {
+		return nil
+	}
+	if err := os.Rename(p.tmp, filepath.Join(p.recorder.dir, p.id+".json")); err != nil {
+		_ = os.Remove(p.tmp)
+		return fmt.Errorf("writing edit record: %w", err)
+	}
+	p.recorder.prune()
+	return nil
+}
+
+// discard drops the record of an edit that was not applied.
+func (p *pendingRecord) discard() {
+	if p != nil {
+		_ = os.Remove(p.tmp)
+	}
+}
+
+// recordIDs returns the ids of the published records, oldest first. Ids
+// start with their UTC creation time, so they sort chronologically.
+func (r *editRecorder) recordIDs() ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing edit records: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() && validJournalID(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// prune removes the oldest records beyond the limit.
+func (r *editRecorder) prune() {
+	ids, err := r.recordIDs()
+	if err != nil {
+		return
+	}
+	for len(ids) > r.limit {
+		_ = os.Remove(filepath.Join(r.dir, ids[0]+".json"))
+		ids = ids[1:]
+	}
+}
+
+// load reads record id.
+func (r *editRecorder) load(id string) (*editRecord, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading edit record %s: %w", id, err)
+	}
+	var rec editRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("parsing edit record %s: %w", id, err)
+	}
+	if rec.Version < 1 || rec.Version > editRecordVersion {
+		return nil, fmt.Errorf("edit record %s has version %d; this server reads versions up to %d", id, rec.Version, editRecordVersion)
+	}
+	return &rec, nil
+}
+
+// save replaces record rec atomically.
+func (r *editRecorder) save(rec *editRecord) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding edit record: %w", err)
+	}
+	tmp := filepath.Join(r.dir, rec.ID+".json.tmp")
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing edit record: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(r.dir, rec.ID+".json")); err != nil {
+		return fmt.Errorf("writing edit record: %w", err)
+	}
+	return nil
+}
+
+// records returns every readable record, newest first. Unreadable ones
+// are skipped.
+func (r *editRecorder) records() ([]*editRecord, error) {
+	ids, err := r.recordIDs()
+	if err != nil {
+		return nil, err
+	}
+	var out []*editRecord
+	for _, id := range slices.Backward(ids) {
+		if rec, err := r.load(id); err == nil {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+// splitLinesKeepEnds splits content into lines that keep their line
+// endings, so joining them restores content exactly.
+func splitLinesKeepEnds(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineHunks returns the runs of lines that differ between original and
+// updated.
+func lineHunks(original, updated []byte) []recordedHunk {
+	var hunks []recordedHunk
+	var cur *recordedHunk
+	oldLine, newLine := 1, 1
+	for _, op := range edit.DiffLines(splitLinesKeepEnds(string(original)), splitLinesKeepEnds(string(updated))) {
+		if op.Kind == ' ' {
+			cur = nil
+			oldLine++
+			newLine++
+			continue
+		}
+		if cur == nil {
+			hunks = append(hunks, recordedHunk{OldStart: oldLine, NewStart: newLine})
+			cur = &hunks[len(hunks)-1]
+		}
+		if op.Kind == '-' {
+			cur.OldLines++
+			cur.Removed = append(cur.Removed, op.Text)
+			oldLine++
+		} else {
+			cur.NewLines++
+			cur.Added = append(cur.Added, op.Text)
+			newLine++
+		}
+	}
+	return hunks
+}
+
+// revertHunks restores the content hunks were computed from, given the
+// content they produced.
+func revertHunks(updated []byte, hunks []recordedHunk) ([]byte, error) {
+	lines := splitLinesKeepEnds(string(updated))
+	for _, h := range slices.Backward(hunks) {
+		start := h.NewStart - 1
+		if start < 0 || start+h.NewLines > len(lines) || !slices.Equal(lines[start:start+h.NewLines], h.Added) {
+			return nil, fmt.Errorf("hunk at line %d does not match the file", h.NewStart)
+		}
+		lines = slices.Replace(lines, start, start+h.NewLines, h.Removed...)
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// undoWork computes the writes reverting rec. Every file must still hold
+// the content the edit wrote; files the edit created are removed again,
+// and files it deleted must still be absent and are recreated.
+func undoWork(rec *editRecord) ([]edit.File, error) {
+	var work []edit.File
+	var changed []string
+	for _, f := range rec.Files {
+		if f.Deleted {
+			if _, err := os.Lstat(f.Path); !errors.Is(err, os.ErrNotExist) {
+				changed = append(changed, f.Path)
+				continue
+			}
+			restored, err := revertHunks(nil, f.Hunks)
+			if err != nil || hashContent(restored) != f.BeforeHash {
+				return nil, fmt.Errorf("edit record %s cannot restore %s", rec.ID, f.Path)
+			}
+			work = append(work, edit.File{Path: f.Path, Mode: f.Mode, Updated: restored, Created: true, NewDirs: edit.MissingDirs(filepath.Dir(f.Path))})
+			continue
+		}
+		fi, err := os.Stat(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("stat %s: %w", f.Path, err)
+		}
+		current, err := os.ReadFile(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", f.Path, err)
+		}
+		if hashContent(current) != f.AfterHash {
+			changed = append(changed, f.Path)
+			continue
+		}
+		w := edit.File{Path: f.Path, Mode: fi.Mode().Perm(), ModTime: fi.ModTime(), Original: current}
+		if f.Created {
+			w.Deleted, w.NewDirs = true, f.NewDirs
+		} else {
+			restored, err := revertHunks(current, f.Hunks)
+			if err != nil || hashContent(restored) != f.BeforeHash {
+				return nil, fmt.Errorf("edit record %s cannot restore %s", rec.ID, f.Path)
+			}
+			w.Updated = restored
+		}
+		work = append(work, w)
+	}
+	if len(changed) > 0 {
+		return nil, fmt.Errorf("files changed since edit %s; undoing it would overwrite: %s", rec.ID, strings.Join(changed, ", "))
+	}
+	return work, nil
+}
+
+// lastUndoable returns the newest record that is neither undone nor an
+// undo itself.
+func (r *editRecorder) lastUndoable() (*editRecord, error) {
+	records, err := r.records()
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		if rec.UndoneAt == nil && rec.Undoes == "" {
+			return rec, nil
+		}
+	}
+	return nil, errors.New("no recorded edit to undo")
+}
+
+// undoLast reverts the newest undoable edit, records the undo when
+// recording is on, and marks the edit undone.
+func (r *editRecorder) undoLast(tool string) (*editRecord, []string, error) {
+	rec, err := r.lastUndoable()
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := make([]string, len(rec.Files))
+	for i, f := range rec.Files {
+		paths[i] = f.Path
+	}
+	defer edit.Lock(paths)()
+	work, err := undoWork(rec)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeEdit(work, nil, r.recording(editOrigin{tool: tool, symbol: rec.Symbol, undoes: rec.ID})); err != nil {
+		return nil, nil, err
+	}
+	now := r.now().UTC()
+	rec.UndoneAt = &now
+	if err := r.save(rec); err != nil {
+		return nil, nil, err
+	}
+	return rec, paths, nil
+}
+
+// editSummary is one entry of ts_list_edits.
+type editSummary struct {
+	ID       string     `json:"id"`
+	Tool     string     `json:"tool"`
+	Time     time.Time  `json:"time"`
+	Symbol   string     `json:"symbol,omitempty"`
+	Files    []string   `json:"files"`
+	Undoes   string     `json:"undoes,omitempty"`
+	UndoneAt *time.Time `json:"undoneAt,omitempty"`
+}
+
+type listEditsResult struct {
+	Edits      []editSummary `json:"edits"`
+	TotalCount int           `json:"totalCount"`
+	Truncated  bool          `json:"truncated"`
+	// Recording reports whether new edits are recorded.
+	Recording bool `json:"recording"`
+}
+
+// editFilter selects records by file and time range; zero fields match
+// everything.
+type editFilter struct {
+	file         string
+	since, until time.Time
+}
+
+func (f editFilter) matches(rec *editRecord) bool {
+	if !f.since.IsZero() && rec.Time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && rec.Time.After(f.until) {
+		return false
+	}
+	return f.file == "" || slices.ContainsFunc(rec.Files, func(rf recordedFile) bool { return rf.Path == f.file })
+}
+
+// listEdits returns the records matching f, newest first, at most max.
+func listEdits(r *editRecorder, f editFilter, max int) (listEditsResult, error) {
+	records, err := r.records()
+	if err != nil {
+		return listEditsResult{}, err
+	}
+	result := listEditsResult{Edits: []editSummary{}, Recording: r.enabled}
+	for _, rec := range records {
+		if !f.matches(rec) {
+			continue
+		}
+		result.TotalCount++
+		if len(result.Edits) == max {
+			result.Truncated = true
+			continue
+		}
+		s := editSummary{ID: rec.ID, Tool: rec.Tool, Time: rec.Time, Symbol: rec.Symbol, Undoes: rec.Undoes, UndoneAt: rec.UndoneAt}
+		for _, rf := range rec.Files {
+			s.Files = append(s.Files, rf.Path)
+		}
+		result.Edits = append(result.Edits, s)
+	}
+	return result, nil
+}
+
+// parseTimeArg reads an optional RFC 3339 time argument.
+func parseTimeArg(request mcp.CallToolRequest, name string) (time.Time, error) {
+	v := request.GetString(name, "")
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time such as 2024-05-01T12:00:00Z", name)
+	}
+	return t, nil
+}
+
+func makeListEditsHandler(recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		maxResults := request.GetInt("maxResults", 50)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+		var f editFilter
+		var err error
+		f.file = request.GetString("file", "")
+		if f.since, err = parseTimeArg(request, "since"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if f.until, err = parseTimeArg(request, "until"); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result, err := listEdits(recorder, f, maxResults)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
+// undoEditResult is the result of ts_undo_last_edit.
+type undoEditResult struct {
+	Undone editSummary `json:"undone"`
+}
+
+func makeUndoLastEditHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		rec, paths, err := recorder.undoLast(request.Params.Name)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("undo error: %v", err)), nil
+		}
+		pending.InvalidateFiles(paths)
+		for _, p := range paths {
+			if isDocFile(p) {
+				continue
+			}
+			if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+				// The undo removed a file the edit created.
+				if _, _, err := docs.CloseFiles(ctx, client.Conn(), []string{p}); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("close error for %s: %v", p, err)), nil
+				}
+				continue
+			}
+			if syncErr := docs.ResyncFile(ctx, client.Conn(), p); syncErr != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("re-sync error for %s: %v", p, syncErr)), nil
+			}
+		}
+		ClearFileCache()
+
+		result := undoEditResult{Undone: editSummary{ID: rec.ID, Tool: rec.Tool, Time: rec.Time, Symbol: rec.Symbol, Files: paths, UndoneAt: rec.UndoneAt}}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRecorder returns a recording recorder for root whose clock advances
+// one minute per edit, starting at start.
+func testRecorder(root string, limit int, start time.Time) *editRecorder {
+	r := newEditRecorder(root, &configFile{RecordEdits: true, RecordLimit: limit})
+	clock := start
+	r.now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	return r
+}
+
+var recordStart = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestLineHunks(t *testing.T) {
+	tests := []struct {
+		name              string
+		original, updated string
+		want              []recordedHunk
+	}{
+		{name: "identical", original: "a\nb\n", updated: "a\nb\n"},
+		{
+			name: "replaced line", original: "a\nb\nc\n", updated: "a\nB\nc\n",
+			want: []recordedHunk{{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1, Removed: []string{"b\n"}, Added: []string{"B\n"}}},
+		},
+		{
+			name: "insertion and deletion", original: "a\nb\nc\n", updated: "x\na\nc\n",
+			want: []recordedHunk{
+				{OldStart: 1, NewStart: 1, NewLines: 1, Added: []string{"x\n"}},
+				{OldStart: 2, OldLines: 1, NewStart: 3, Removed: []string{"b\n"}},
+			},
+		},
+		{
+			name: "missing final newline", original: "a\nb", updated: "a\nb\n",
+			want: []recordedHunk{{OldStart: 2, OldLines: 1, NewStart: 2, NewLines: 1, Removed: []string{"b"}, Added: []string{"b\n"}}},
+		},
+		{name: "from empty", original: "", updated: "a\r\n", want: []recordedHunk{{OldStart: 1, NewStart: 1, NewLines: 1, Added: []string{"a\r\n"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lineHunks([]byte(tt.original), []byte(tt.updated))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("hunks = %+v, want %+v", got, tt.want)
+			}
+			restored, err := revertHunks([]byte(tt.updated), got)
+			if err != nil || string(restored) != tt.original {
+				t.Errorf("revertHunks = %q, %v; want %q", restored, err, tt.original)
+			}
+		})
+	}
+	if _, err := revertHunks([]byte("a\nz\nc\n"), lineHunks([]byte("a\nb\nc\n"), []byte("a\nB\nc\n"))); err == nil {
+		t.Error("revertHunks accepted content the hunks did not produce")
+	}
+}
+
+func TestEditRecording(t *testing.T) {
+	files, edit := journalFixture(t, 2)
+	root := t.TempDir()
+	r := testRecorder(root, 0, recordStart)
+
+	if _, err := applyWorkspaceEdit(edit, nil, nil, nil, r.recording(editOrigin{tool: "ts_rename", symbol: "old"})); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(r.dir)
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".json") || strings.HasSuffix(entries[0].Name(), ".tmp") {
+		t.Fatalf("records = %v, want one published record", entries)
+	}
+	rec, err := r.lastUndoable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Tool != "ts_rename" || rec.Symbol != "old" || len(rec.Files) != 2 {
+		t.Fatalf("record = %+v", rec)
+	}
+	f := rec.Files[0]
+	if f.Path != files[0] || f.BeforeHash != hashContent([]byte(oldContent)) || f.AfterHash != hashContent([]byte(renamedContent)) {
+		t.Errorf("file record = %+v", f)
+	}
+	wantHunk := recordedHunk{OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1, Removed: []string{oldContent}, Added: []string{renamedContent}}
+	if len(f.Hunks) != 1 || !reflect.DeepEqual(f.Hunks[0], wantHunk) {
+		t.Errorf("hunks = %+v", f.Hunks)
+	}
+
+	// Recording off writes nothing.
+	_, edit = journalFixture(t, 1)
+	off := newEditRecorder(t.TempDir(), &configFile{})
+	if _, err := applyWorkspaceEdit(edit, nil, nil, nil, off.recording(editOrigin{tool: "ts_rename"})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(off.dir); !os.IsNotExist(err) {
+		t.Errorf("records written with recording off: %v", err)
+	}
+}
+
+func TestEditRecordRollback(t *testing.T) {
+	files, edit := journalFixture(t, 3)
+	r := testRecorder(t.TempDir(), 0, recordStart)
+	beforeEditWrite = func(path string) error {
+		if path == files[2] {
+			writeString(t, path, "// changed by the agent\n")
+		}
+		return nil
+	}
+	defer func() { beforeEditWrite = nil }()
+
+	if _, err := applyWorkspaceEdit(edit, nil, nil, nil, r.recording(editOrigin{tool: "ts_rename"})); err == nil {
+		t.Fatal("edit succeeded despite the concurrent modification")
+	}
+	if entries, _ := os.ReadDir(r.dir); len(entries) != 0 {
+		t.Errorf("rolled-back edit left records: %v", entries)
+	}
+}
+
+func TestUndoAfterRestart(t *testing.T) {
+	files, edit := journalFixture(t, 2)
+	root := t.TempDir()
+	first := testRecorder(root, 0, recordStart)
+	if _, err := applyWorkspaceEdit(edit, nil, nil, nil, first.recording(editOrigin{tool: "ts_rename", symbol: "old"})); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new server reads the records from disk.
+	second := testRecorder(root, 0, recordStart.Add(time.Hour))
+	rec, paths, err := second.undoLast("ts_undo_last_edit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Symbol != "old" || len(paths) != 2 || rec.UndoneAt == nil {
+		t.Errorf("undone = %+v, paths %v", rec, paths)
+	}
+	for i, c := range fileContents(t, files) {
+		if c != oldContent {
+			t.Errorf("file %d after undo = %q", i, c)
+		}
+	}
+	records, _ := second.records()
+	if len(records) != 2 || records[0].Undoes != rec.ID || records[1].UndoneAt == nil {
+		t.Errorf("records after undo = %+v", records)
+	}
+	if _, _, err := second.undoLast("ts_undo_last_edit"); err == nil || !strings.Contains(err.Error(), "no recorded edit") {
+		t.Errorf("second undo: %v", err)
+	}
+}
+
+func TestUndoRefusesChangedFiles(t *testing.T) {
+	files, edit := journalFixture(t, 2)
+	r := testRecorder(t.TempDir(), 0, recordStart)
+	if _, err := applyWorkspaceEdit(edit, nil, nil, nil, r.recording(editOrigin{tool: "ts_rename"})); err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, files[1], "export const mine = 2;\n")
+
+	if _, _, err := r.undoLast("ts_undo_last_edit"); err == nil || !strings.Contains(err.Error(), files[1]) {
+		t.Fatalf("undo error = %v, want the changed file named", err)
+	}
+	if got := fileContents(t, files); got[0] != renamedContent {
+		t.Errorf("undo wrote %q despite refusing", got[0])
+	}
+}
+
+func TestEditRecordPruning(t *testing.T) {
+	r := testRecorder(t.TempDir(), 2, recordStart)
+	var ids []string
+	for range 3 {
+		_, edit := journalFixture(t, 1)
+		if _, err := applyWorkspaceEdit(edit, nil, nil, nil, r.recording(editOrigin{tool: "ts_rename"})); err != nil {
+			t.Fatal(err)
+		}
+		rec, err := r.lastUndoable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rec.ID)
+	}
+	got, err := r.recordIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, ids[1:]) {
+		t.Errorf("records = %v, want the newest two of %v", got, ids)
+	}
+}
+
+func TestListEdits(t *testing.T) {
+	r := testRecorder(t.TempDir(), 0, recordStart)
+	var files []string
+	for _, tool := range []string{"ts_rename", "ts_rename", "ts_apply_edit"} {
+		f, edit := journalFixture(t, 1)
+		files = append(files, f[0])
+		if _, err := applyWorkspaceEdit(edit, nil, nil, nil, r.recording(editOrigin{tool: tool})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		filter    editFilter
+		max       int
+		wantFiles []string
+		truncated bool
+	}{
+		{name: "all, newest first", max: 50, wantFiles: []string{files[2], files[1], files[0]}},
+		{name: "by file", filter: editFilter{file: files[1]}, max: 50, wantFiles: []string{files[1]}},
+		{name: "since", filter: editFilter{since: recordStart.Add(2 * time.Minute)}, max: 50, wantFiles: []string{files[2], files[1]}},
+		{name: "until", filter: editFilter{until: recordStart.Add(time.Minute)}, max: 50, wantFiles: []string{files[0]}},
+		{name: "limited", max: 1, wantFiles: []string{files[2]}, truncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := listEdits(r, tt.filter, tt.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range res.Edits {
+				got = append(got, e.Files...)
+			}
+			if !reflect.DeepEqual(got, tt.wantFiles) || res.Truncated != tt.truncated || !res.Recording {
+				t.Errorf("edits = %v (truncated %v), want %v", got, res.Truncated, tt.wantFiles)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(r.dir, "x.json.tmp")); !os.IsNotExist(err) {
+		t.Error("temporary record left behind")
+	}
+}
@@ -0,0 +1,316 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// redactedText replaces suppressed content and masked matches.
+const redactedText = "[redacted]"
+
+// Placeholders for redacted path prefixes.
+const (
+	workspacePlaceholder = "$WORKSPACE"
+	homePlaceholder      = "~"
+)
+
+// redactionConfig is the "redaction" section of the config file.
+type redactionConfig struct {
+	// Paths replaces the workspace root and home directory in every
+	// string of a response with placeholders.
+	Paths bool `json:"paths"`
+	// SuppressPreviews lists globs, relative to the workspace root and
+	// matched as tsconfig exclude entries, of files whose source text
+	// never appears in a response.
+	SuppressPreviews []string `json:"suppressPreviews"`
+	// MaskPatterns are regular expressions masked wherever they match
+	// source text.
+	MaskPatterns []string `json:"maskPatterns"`
+}
+
+// contentKeys are the result fields that carry source text: previews,
+// diffs and the declaration text of symbol cards and outlines. Every tool
+// result follows this naming, so redaction covers new tools that do.
+var contentKeys = map[string]bool{
+	"preview":   true,
+	"diff":      true,
+	"signature": true,
+	"summary":   true,
+	"detail":    true,
+}
+
+// fileKeys name the field holding the file an object's content comes
+// from. Objects without one inherit the file of the enclosing object, or
+// the call's "file" argument.
+var fileKeys = []string{"file", "path"}
+
+// redactor applies the redaction rules to tool results.
+type redactor struct {
+	root, home string
+	paths      bool
+	suppress   []*regexp.Regexp
+	masks      []*regexp.Regexp
+}
+
+// newRedactor compiles cfg for the workspace root. It returns nil when cfg
+// sets no rule.
+func newRedactor(cfg redactionConfig, root, home string) (*redactor, error) {
+	if !cfg.Paths && len(cfg.SuppressPreviews) == 0 && len(cfg.MaskPatterns) == 0 {
+		return nil, nil
+	}
+	r := &redactor{root: filepath.Clean(root), paths: cfg.Paths}
+	if home != "" {
+		r.home = filepath.Clean(home)
+	}
+	for _, glob := range cfg.SuppressPreviews {
+		if re := tsconfig.ExcludePattern(r.root, glob); re != nil {
+			r.suppress = append(r.suppress, re)
+		}
+	}
+	for _, p := range cfg.MaskPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("redaction mask pattern %q: %w", p, err)
+		}
+		r.masks = append(r.masks, re)
+	}
+	return r, nil
+}
+
+// suppressed reports whether file's source text is withheld.
+func (r *redactor) suppressed(file string) bool {
+	if file == "" {
+		return false
+	}
+	p := filepath.ToSlash(filepath.Clean(file))
+	for _, re := range r.suppress {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// text redacts the paths in s.
+func (r *redactor) text(s string) string {
+	if !r.paths {
+		return s
+	}
+	s = replacePathPrefix(s, r.root, workspacePlaceholder)
+	if r.home != "" && r.home != "/" {
+		s = replacePathPrefix(s, r.home, homePlaceholder)
+	}
+	return s
+}
+
+// content redacts source text taken from file.
+func (r *redactor) content(file, s string) string {
+	if s == "" {
+		return s
+	}
+	if r.suppressed(file) {
+		return redactedText
+	}
+	for _, re := range r.masks {
+		s = re.ReplaceAllString(s, redactedText)
+	}
+	return r.text(s)
+}
+
+// replacePathPrefix replaces prefix with placeholder wherever it occurs as
+// a whole path: followed by a separator, the end of s, or a character that
+// cannot continue a file name. "/home/al" is thus left alone in
+// "/home/alice".
+func replacePathPrefix(s, prefix, placeholder string) string {
+	if prefix == "" || !strings.Contains(s, prefix) {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, prefix)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(prefix)
+		b.WriteString(s[:i])
+		if end == len(s) || s[end] == '/' || s[end] == '\\' || !isFileNameByte(s[end]) {
+			b.WriteString(placeholder)
+		} else {
+			b.WriteString(prefix)
+		}
+		s = s[end:]
+	}
+}
+
+func isFileNameByte(c byte) bool {
+	return isIdentByte(c) || c == '.' || c == '-' || c >= 0x80
+}
+
+// value redacts a decoded JSON value whose content fields come from file.
+func (r *redactor) value(v any, file string) any {
+	switch v := v.(type) {
+	case jsonObject:
+		for _, m := range v {
+			if s, ok := m.Value.(string); ok && slices.Contains(fileKeys, m.Key) {
+				file = s
+				break
+			}
+		}
+		for i, m := range v {
+			if s, ok := m.Value.(string); ok && contentKeys[m.Key] {
+				v[i].Value = r.content(file, s)
+				continue
+			}
+			v[i].Value = r.value(m.Value, file)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = r.value(v[i], file)
+		}
+		return v
+	case string:
+		return r.text(v)
+	}
+	return v
+}
+
+// result redacts every text item of result in place. JSON items are
+// redacted field by field; other text is the source text of file (hover
+// content) unless the result is an error.
+func (r *redactor) result(result *mcp.CallToolResult, file string) {
+	for i, c := range result.Content {
+		tc, ok := c.(mcp.TextContent)
+		if !ok {
+			continue
+		}
+		if v, ok := decodeOrderedJSON(tc.Text); ok {
+			if data, err := json.MarshalIndent(r.value(v, file), "", "  "); err == nil {
+				tc.Text = string(data)
+				result.Content[i] = tc
+				continue
+			}
+		}
+		if result.IsError {
+			tc.Text = r.text(tc.Text)
+		} else {
+			tc.Text = r.content(file, tc.Text)
+		}
+		result.Content[i] = tc
+	}
+}
+
+// withRedaction applies r to every response of h. It wraps the other
+// middleware so that warnings are redacted too.
+func withRedaction(r *redactor, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	if r == nil {
+		return h
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := h(ctx, request)
+		if err != nil || result == nil {
+			return result, err
+		}
+		r.result(result, request.GetString("file", ""))
+		return result, nil
+	}
+}
+
+// jsonObject is a decoded JSON object that keeps its key order, so a
+// redacted result reads like the original.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key   string
+	Value any
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// decodeOrderedJSON decodes text when it is a single JSON object or array.
+func decodeOrderedJSON(text string) (any, bool) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil, false
+	}
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	v, err := decodeOrdered(dec)
+	if err != nil || dec.More() {
+		return nil, false
+	}
+	return v, true
+}
+
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := jsonObject{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string)
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonMember{Key: key, Value: v})
+		}
+		_, err := dec.Token()
+		return obj, err
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			v, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("unexpected %v", delim)
+}
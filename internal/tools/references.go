@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
 	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
 )
 
 type referenceEntry struct {
@@ -17,68 +22,278 @@ type referenceEntry struct {
 	Line    int    `json:"line"`
 	Column  int    `json:"column"`
 	Preview string `json:"preview,omitempty"`
+	// PreviewOmitted is set when the preview budget ran out before this
+	// reference's file was read.
+	PreviewOmitted bool `json:"previewOmitted,omitempty"`
+	// Package and DisplayPath are set for locations inside node_modules.
+	Package     *workspace.Package `json:"package,omitempty"`
+	DisplayPath string             `json:"displayPath,omitempty"`
+	// VisualColumn is Column with tabs expanded, in columnMode "visual".
+	VisualColumn int `json:"visualColumn,omitempty"`
 }
 
 type referencesResult struct {
 	References []referenceEntry `json:"references"`
-	TotalCount int              `json:"totalCount"`
-	Truncated  bool             `json:"truncated"`
+	// TotalCount counts the references the filters kept; UnfilteredCount
+	// all references tsgo found.
+	TotalCount      int `json:"totalCount"`
+	UnfilteredCount int `json:"unfilteredCount"`
+	// Truncated is set when more pages follow; pass NextCursor to get the
+	// next one.
+	Truncated  bool   `json:"truncated"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
-func makeReferencesHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
-	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		file, err := request.RequireString("file")
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+// defaultPreviewsPerFile is the number of sample references of each file
+// ts_references lists with groupByFile.
+const defaultPreviewsPerFile = 3
+
+// referenceFileGroup is the references in one file.
+type referenceFileGroup struct {
+	File  string `json:"file"`
+	Count int    `json:"count"`
+	// References are the first previewsPerFile references, with previews.
+	References []referenceEntry `json:"references"`
+	// Package and DisplayPath are set for files inside node_modules.
+	Package     *workspace.Package `json:"package,omitempty"`
+	DisplayPath string             `json:"displayPath,omitempty"`
+}
+
+type groupedReferencesResult struct {
+	// Files are ordered by count, the most references first.
+	Files []referenceFileGroup `json:"files"`
+	// FileCount and TotalCount count all files and references the filters
+	// kept, including those truncation left out.
+	FileCount       int `json:"fileCount"`
+	TotalCount      int `json:"totalCount"`
+	UnfilteredCount int `json:"unfilteredCount"`
+	// Truncated is set when there were more than maxResults files.
+	Truncated bool `json:"truncated"`
+}
+
+// groupReferences groups sorted references by file, keeping at most
+// maxFiles files and perFile references of each.
+func groupReferences(all []referenceEntry, maxFiles, perFile int) groupedReferencesResult {
+	result := groupedReferencesResult{Files: []referenceFileGroup{}, TotalCount: len(all)}
+	for _, e := range all {
+		n := len(result.Files)
+		if n == 0 || result.Files[n-1].File != e.File {
+			result.Files = append(result.Files, referenceFileGroup{File: e.File, References: []referenceEntry{}, Package: e.Package, DisplayPath: e.DisplayPath})
+			n++
 		}
-		line, err := request.RequireInt("line")
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+		g := &result.Files[n-1]
+		g.Count++
+		if len(g.References) < perFile {
+			e.Package, e.DisplayPath = nil, ""
+			g.References = append(g.References, e)
 		}
-		col, err := request.RequireInt("column")
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+	}
+	sort.SliceStable(result.Files, func(i, j int) bool { return result.Files[i].Count > result.Files[j].Count })
+	result.FileCount = len(result.Files)
+	if len(result.Files) > maxFiles {
+		result.Files, result.Truncated = result.Files[:maxFiles], true
+	}
+	return result
+}
+
+// sortReferences orders references by file, line and column so pages of
+// a snapshot are stable.
+func sortReferences(entries []referenceEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.File != b.File {
+			return a.File < b.File
 		}
-		maxResults := request.GetInt("maxResults", 50)
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
 
-		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+// declarationFileExtensions are the extensions of declaration files.
+var declarationFileExtensions = []string{".d.ts", ".d.mts", ".d.cts"}
+
+// isDeclarationFile reports whether file is a .d.ts, .d.mts or .d.cts.
+func isDeclarationFile(file string) bool {
+	for _, ext := range declarationFileExtensions {
+		if strings.HasSuffix(file, ext) {
+			return true
 		}
+	}
+	return false
+}
 
-		locs, err := client.References(ctx, file, line, col)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("references error: %v", err)), nil
+// inDeclarationFile reports whether file is a declaration file or lies
+// under node_modules, where excludeDeclarationFiles drops references.
+func inDeclarationFile(file string) bool {
+	return isDeclarationFile(file) || inNodeModules(file)
+}
+
+// pathFilter is the include and exclude filters of ts_references. Each
+// pattern is a tsconfig-style glob or a path, relative to the project
+// root, and covers what it matches and everything below.
+type pathFilter struct {
+	include, exclude []*regexp.Regexp
+}
+
+// newPathFilter compiles the include and exclude patterns for root.
+func newPathFilter(root string, include, exclude []string) pathFilter {
+	compile := func(specs []string) []*regexp.Regexp {
+		var out []*regexp.Regexp
+		for _, spec := range specs {
+			if re := tsconfig.ExcludePattern(filepath.Clean(root), spec); re != nil {
+				out = append(out, re)
+			}
 		}
+		return out
+	}
+	return pathFilter{include: compile(include), exclude: compile(exclude)}
+}
 
-		totalCount := len(locs)
-		truncated := totalCount > maxResults
-		if truncated {
-			locs = locs[:maxResults]
+// keep reports whether file matches an include pattern, when there are
+// any, and no exclude pattern.
+func (f pathFilter) keep(file string) bool {
+	p := filepath.ToSlash(filepath.Clean(file))
+	matches := func(res []*regexp.Regexp) bool {
+		for _, re := range res {
+			if re.MatchString(p) {
+				return true
+			}
 		}
+		return false
+	}
+	return (len(f.include) == 0 || matches(f.include)) && !matches(f.exclude)
+}
 
-		entries := make([]referenceEntry, len(locs))
-		for i, loc := range locs {
-			refFile := docsync.URIToFile(string(loc.URI))
-			refLine := int(loc.Range.Start.Line) + 1
-			refCol := int(loc.Range.Start.Character) + 1
+// fileVersions records the docsync version of every file in files, 0 for
+// files that are not open.
+func fileVersions(docs *docsync.Manager, files []string) map[string]int32 {
+	versions := make(map[string]int32, len(files))
+	for _, f := range files {
+		versions[f], _ = docs.Version(f)
+	}
+	return versions
+}
+
+func makeReferencesHandler(client *lsp.Client, docs *docsync.Manager, packages *workspace.PackageResolver, cursors *cursorStore[referenceEntry]) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		maxResults := request.GetInt("maxResults", 50)
+		maxPreviews := request.GetInt("maxPreviews", defaultPreviewBudget)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
 
-			entry := referenceEntry{
-				File:   refFile,
-				Line:   refLine,
-				Column: refCol,
+		var (
+			all        []referenceEntry
+			unfiltered int
+			versions   map[string]int32
+			id         string
+			offset     int
+		)
+		groupByFile := request.GetBool("groupByFile", false)
+		if cursor := request.GetString("cursor", ""); cursor != "" {
+			if groupByFile {
+				return mcp.NewToolResultError("groupByFile results are not paged; pass a larger maxResults instead of cursor"), nil
+			}
+			var err error
+			if id, offset, err = parseCursor(cursor); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			var info any
+			if all, info, err = cursors.Get("ts_references", id); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			unfiltered = info.(int)
+		} else {
+			file, err := request.RequireString("file")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			line, err := request.RequireInt("line")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			col, err := request.RequireInt("column")
+			if err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			col = cols.charColumn(file, line, col)
+
+			defer docs.Pin(file)()
+			if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
 			}
 
-			if preview, err := readLine(refFile, refLine); err == nil {
-				entry.Preview = strings.TrimSpace(preview)
+			locs, err := client.References(ctx, file, line, col, request.GetBool("includeDeclaration", true))
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("references error: %v", err)), nil
 			}
 
-			entries[i] = entry
+			excludeDeclarationFiles := request.GetBool("excludeDeclarationFiles", false)
+			paths := newPathFilter(client.RootDir(), request.GetStringSlice("include", nil), request.GetStringSlice("exclude", nil))
+			unfiltered = len(locs)
+			all = make([]referenceEntry, 0, len(locs))
+			var files []string
+			for _, loc := range locs {
+				e := referenceEntry{
+					File:   docsync.URIToFile(string(loc.URI)),
+					Line:   int(loc.Range.Start.Line) + 1,
+					Column: int(loc.Range.Start.Character) + 1,
+				}
+				if (excludeDeclarationFiles && inDeclarationFile(e.File)) || !paths.keep(e.File) {
+					continue
+				}
+				if pkg := packages.Resolve(e.File); pkg != nil {
+					e.Package = pkg
+					e.DisplayPath = pkg.DisplayPath(e.File)
+				}
+				all = append(all, e)
+				files = append(files, e.File)
+			}
+			sortReferences(all)
+			versions = fileVersions(docs, files)
+			if groupByFile {
+				perFile := request.GetInt("previewsPerFile", defaultPreviewsPerFile)
+				if perFile < 0 {
+					return mcp.NewToolResultError("previewsPerFile must not be negative"), nil
+				}
+				grouped := groupReferences(all, maxResults, perFile)
+				grouped.UnfilteredCount = unfiltered
+				return groupedReferences(ctx, grouped, cols, maxPreviews)
+			}
+		}
+
+		pg, err := paginate(cursors, "ts_references", all, versions, unfiltered, id, offset, maxResults)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// Previews are read per page; the snapshot itself stays untouched.
+		entries := append([]referenceEntry(nil), pg.Items...)
+		targets := make([]previewTarget, len(entries))
+		for i, e := range entries {
+			targets[i] = previewTarget{File: e.File, Line: e.Line}
+		}
+		previews, omitted := loadPreviews(ctx, targets, maxPreviews)
+		for i := range entries {
+			entries[i].Preview = previews[i]
+			entries[i].PreviewOmitted = omitted[i]
+			entries[i].VisualColumn = cols.visualColumn(entries[i].File, entries[i].Line, entries[i].Column)
 		}
 
 		result := referencesResult{
-			References: entries,
-			TotalCount: totalCount,
-			Truncated:  truncated,
+			References:      entries,
+			TotalCount:      len(all),
+			UnfilteredCount: unfiltered,
+			Truncated:       pg.NextCursor != "",
+			Offset:          pg.Offset,
+			NextCursor:      pg.NextCursor,
 		}
 
 		data, err := json.MarshalIndent(result, "", "  ")
@@ -88,3 +303,31 @@ func makeReferencesHandler(client *lsp.Client, docs *docsync.Manager) server.Too
 		return mcp.NewToolResultText(string(data)), nil
 	}
 }
+
+// groupedReferences renders result, reading the previews of its sample
+// references.
+func groupedReferences(ctx context.Context, result groupedReferencesResult, cols columnMode, maxPreviews int) (*mcp.CallToolResult, error) {
+	var targets []previewTarget
+	for _, g := range result.Files {
+		for _, e := range g.References {
+			targets = append(targets, previewTarget{File: e.File, Line: e.Line})
+		}
+	}
+	previews, omitted := loadPreviews(ctx, targets, maxPreviews)
+	i := 0
+	for _, g := range result.Files {
+		for j := range g.References {
+			e := &g.References[j]
+			e.Preview = previews[i]
+			e.PreviewOmitted = omitted[i]
+			e.VisualColumn = cols.visualColumn(e.File, e.Line, e.Column)
+			i++
+		}
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestInDeclarationFile(t *testing.T) {
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"/p/src/index.ts", false},
+		{"/p/src/types.d.ts", true},
+		{"/p/src/esm.d.mts", true},
+		{"/p/src/cjs.d.cts", true},
+		{"/p/src/d.ts", false},
+		{"/p/node_modules/lodash/index.js", true},
+		{"/p/src/node_modules.ts", false},
+	}
+	for _, tt := range tests {
+		if got := inDeclarationFile(tt.file); got != tt.want {
+			t.Errorf("inDeclarationFile(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestGroupReferences(t *testing.T) {
+	var all []referenceEntry
+	for _, r := range []struct {
+		file  string
+		lines []int
+	}{
+		{"/p/a.ts", []int{1, 5}},
+		{"/p/b.ts", []int{2, 3, 4, 9}},
+		{"/p/c.ts", []int{7}},
+		{"/p/d.ts", []int{1, 2}},
+	} {
+		for _, l := range r.lines {
+			all = append(all, referenceEntry{File: r.file, Line: l, Column: 1})
+		}
+	}
+
+	got := groupReferences(all, 10, 3)
+	if got.TotalCount != 9 || got.FileCount != 4 || got.Truncated {
+		t.Errorf("counts = %d total, %d files, truncated %v", got.TotalCount, got.FileCount, got.Truncated)
+	}
+	var order []string
+	for _, g := range got.Files {
+		order = append(order, fmt.Sprintf("%s:%d", g.File, g.Count))
+	}
+	// Files with as many references keep their path order.
+	if want := []string{"/p/b.ts:4", "/p/a.ts:2", "/p/d.ts:2", "/p/c.ts:1"}; !slices.Equal(order, want) {
+		t.Errorf("files = %v, want %v", order, want)
+	}
+	if refs := got.Files[0].References; len(refs) != 3 || refs[0].Line != 2 || refs[2].Line != 4 {
+		t.Errorf("b.ts references = %+v", refs)
+	}
+
+	top := groupReferences(all, 2, 0)
+	if len(top.Files) != 2 || !top.Truncated || top.FileCount != 4 || top.TotalCount != 9 {
+		t.Errorf("maxFiles 2 = %+v", top)
+	}
+	if refs := top.Files[0].References; refs == nil || len(refs) != 0 {
+		t.Errorf("perFile 0 listed %v", refs)
+	}
+}
+
+func TestPathFilter(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             map[string]bool
+	}{
+		{
+			name:    "node_modules excluded",
+			exclude: []string{"**/node_modules"},
+			want: map[string]bool{
+				"/repo/src/index.ts":                          true,
+				"/repo/node_modules/lodash/index.d.ts":        false,
+				"/repo/packages/api/node_modules/zod/lib.ts":  false,
+				"/repo/packages/api/src/node_modules_util.ts": true,
+			},
+		},
+		{
+			name:    "nested directory prefix",
+			include: []string{"packages/api"},
+			want: map[string]bool{
+				"/repo/packages/api/src/server.ts":        true,
+				"/repo/packages/api/src/routes/users.ts":  true,
+				"/repo/packages/api-client/src/client.ts": false,
+				"/repo/packages/web/src/app.ts":           false,
+			},
+		},
+		{
+			name:    "include with exclude below it",
+			include: []string{"packages/api"},
+			exclude: []string{"packages/api/src/routes", "**/*.test.ts"},
+			want: map[string]bool{
+				"/repo/packages/api/src/server.ts":       true,
+				"/repo/packages/api/src/routes/users.ts": false,
+				"/repo/packages/api/src/server.test.ts":  false,
+			},
+		},
+		{
+			name:    "glob and absolute path",
+			include: []string{"packages/*/src/*.ts", "/repo/scripts"},
+			want: map[string]bool{
+				"/repo/packages/web/src/app.ts":        true,
+				"/repo/packages/web/src/lib/format.ts": false,
+				"/repo/scripts/build.ts":               true,
+			},
+		},
+		{
+			name: "no patterns",
+			want: map[string]bool{"/elsewhere/a.ts": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPathFilter("/repo", tt.include, tt.exclude)
+			for file, want := range tt.want {
+				if got := f.keep(file); got != want {
+					t.Errorf("keep(%q) = %v, want %v", file, got, want)
+				}
+			}
+		})
+	}
+}
@@ -3,42 +3,126 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
-	"sort"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
 	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
-	"go.lsp.dev/protocol"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
 )
 
 type editInfo struct {
 	File    string `json:"file"`
 	Edits   int    `json:"edits"`
 	Preview string `json:"preview,omitempty"`
+	// Diff is the written change as a unified diff with one line of
+	// context, cut at maxCompactDiffLines; DiffTruncated is then set.
+	Diff          string `json:"diff,omitempty"`
+	DiffTruncated bool   `json:"diffTruncated,omitempty"`
+	// ChangedLines are the 1-based lines of the written file that are new
+	// or changed.
+	ChangedLines []int `json:"changedLines,omitempty"`
+	// Created is set when the edit created the file, Deleted when it
+	// removed it, and RenamedFrom is the path it moved the file from.
+	Created     bool   `json:"created,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+	RenamedFrom string `json:"renamedFrom,omitempty"`
+	// sync is set for a file edited in place, to send tsgo the edits
+	// rather than the whole file.
+	sync *editSync
 }
 
 type renameResult struct {
 	NewName    string     `json:"newName"`
 	TotalEdits int        `json:"totalEdits"`
 	Changes    []editInfo `json:"changes"`
+	fileOps
+	// DocsCandidates lists whole-word mentions of the old name in
+	// documentation files when updateDocs is set.
+	DocsCandidates []docCandidate `json:"docsCandidates,omitempty"`
+	// DocsApplied is true when the candidates were rewritten along with
+	// the code (updateDocs "apply").
+	DocsApplied bool `json:"docsApplied,omitempty"`
+	// DocEdits lists the JSDoc @param tags rewritten with a renamed
+	// parameter.
+	DocEdits []jsdocTagEdit `json:"docEdits,omitempty"`
+	// ColumnReadings is set when the requested column points elsewhere
+	// in the other column mode, the one used first.
+	ColumnReadings []columnReading `json:"columnReadings,omitempty"`
+	// SkippedFiles lists the files left out of the edit by onlyUnder and
+	// excludeGlobs.
+	SkippedFiles []edit.Skipped `json:"skippedFiles,omitempty"`
 }
 
-func makeRenameHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+// columnReading is the position a requested column points at in one
+// column mode.
+type columnReading struct {
+	ColumnMode string `json:"columnMode"`
+	// Column is the character column.
+	Column     int    `json:"column"`
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// columnReadings returns the readings of col on line in m's mode and in
+// the other one, or nil when both point at the same character: a tab
+// before col makes character and visual columns differ, so a caller
+// using the wrong mode renames something else.
+func columnReadings(line string, col int, m columnMode) []columnReading {
+	visual := visualToCharColumn(line, col, m.tabWidth)
+	if visual == col {
+		return nil
+	}
+	readings := []columnReading{
+		{ColumnMode: columnModeCharacter, Column: col, Identifier: identifierAt(line, col)},
+		{ColumnMode: columnModeVisual, Column: visual, Identifier: identifierAt(line, visual)},
+	}
+	if m.visual {
+		readings[0], readings[1] = readings[1], readings[0]
+	}
+	return readings
+}
+
+// readingsWarning describes readings for a warning.
+func readingsWarning(readings []columnReading) string {
+	describe := func(r columnReading) string {
+		if r.Identifier == "" {
+			return fmt.Sprintf("column %d (no identifier) in %s mode", r.Column, r.ColumnMode)
+		}
+		return fmt.Sprintf("%q at column %d in %s mode", r.Identifier, r.Column, r.ColumnMode)
+	}
+	return fmt.Sprintf("the column points at %s but at %s; check columnMode", describe(readings[0]), describe(readings[1]))
+}
+
+// makeRenameHandler returns the ts_rename handler. excludeGlobs are the
+// default of its excludeGlobs parameter; nil means
+// defaultRenameExcludeGlobs.
+func makeRenameHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, packages *workspace.PackageResolver, symbolCache *symbolCache, excludeGlobs []string, overlayCheck bool, journal *journalPolicy, recorder *editRecorder) server.ToolHandlerFunc {
+	if excludeGlobs == nil {
+		excludeGlobs = defaultRenameExcludeGlobs
+	}
+	symbols := cachedSymbols{cache: symbolCache, src: client, docs: docs}
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		file, err := request.RequireString("file")
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
-		line, err := request.RequireInt("line")
-		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+		symbolName := request.GetString("symbol", "")
+		occurrence := request.GetInt("occurrence", 0)
+		line := request.GetInt("line", 0)
+		col := request.GetInt("column", 0)
+		if symbolName == "" && (line < 1 || col < 1) {
+			return mcp.NewToolResultError("either line and column, or symbol, is required"), nil
+		}
+		if occurrence < 0 || (occurrence > 0 && symbolName == "") {
+			return mcp.NewToolResultError("occurrence must be a positive number and needs symbol"), nil
 		}
-		col, err := request.RequireInt("column")
+		cols, err := parseColumnMode(request)
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
@@ -49,52 +133,175 @@ func makeRenameHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHan
 		if newName == "" {
 			return mcp.NewToolResultError("newName must not be empty"), nil
 		}
+		confirm := request.GetBool("confirm", false)
+		dryRun := request.GetBool("dryRun", false)
+		includeDiff := request.GetBool("includeDiff", true)
+		force := request.GetBool("force", false)
+		scope := newEditScope(client.RootDir(), request.GetString("onlyUnder", ""), request.GetStringSlice("excludeGlobs", excludeGlobs))
+		if confirm && dryRun {
+			return mcp.NewToolResultError("confirm and dryRun are exclusive: dryRun only previews, confirm also returns an editToken to apply"), nil
+		}
+		docsMode, err := updateDocsMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		if pkg := packages.Resolve(file); pkg != nil && !pkg.Linked {
+			return mcp.NewToolResultError(fmt.Sprintf("cannot rename in node_modules: %s belongs to the installed package %s", file, pkg)), nil
+		}
 
-		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+		defer docs.Pin(file)()
+		// A write tool must compute edits against the file as it is now.
+		if err := docs.ResyncFile(ctx, client.Conn(), file); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
 		}
+		// A symbol is addressed at the start of its name, a character
+		// column that needs no column mode checks.
+		if symbolName != "" {
+			if line, col, err = symbolPosition(ctx, symbols, file, symbolName, occurrence); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			cols = columnMode{}
+		}
 
-		edit, err := client.Rename(ctx, file, line, col, newName)
+		// The old name is read before the rename rewrites the file.
+		oldName := ""
+		var readings []columnReading
+		content, err := os.ReadFile(file)
+		if err == nil {
+			if lines := strings.Split(string(content), "\n"); line >= 1 && line <= len(lines) {
+				text := strings.TrimSuffix(lines[line-1], "\r")
+				if symbolName == "" {
+					if readings = columnReadings(text, col, cols); readings != nil {
+						slog.Warn("rename column reads differently per column mode", "file", file, "line", line, "column", col, "readings", readings)
+					}
+				}
+				if cols.visual {
+					col = visualToCharColumn(text, col, cols.tabWidth)
+				}
+				oldName = identifierAt(text, col)
+			}
+		}
+
+		// Fail early on a position that cannot be renamed, where rename
+		// itself answers with a confusing error or no edits.
+		prep, err := client.PrepareRename(ctx, file, line, col)
+		if err != nil && !errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("prepare rename error: %v", err)), nil
+		}
+		if prep != nil && prep.Reason != "" {
+			return mcp.NewToolResultError(fmt.Sprintf("cannot rename here: %s", prep.Reason)), nil
+		}
+
+		we, err := client.Rename(ctx, file, line, col, newName)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("rename error: %v", err)), nil
 		}
 
-		if edit == nil || (len(edit.Changes) == 0 && len(edit.DocumentChanges) == 0) {
+		if we == nil || (len(we.Changes) == 0 && len(we.DocumentChanges) == 0) {
 			return mcp.NewToolResultError("rename produced no changes"), nil
 		}
+		if path, pkg := installedPackageEdit(we, packages); pkg != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("refusing to rename: the symbol is also declared in the installed package %s (%s); rename a local alias instead", pkg, pkg.DisplayPath(path))), nil
+		}
+		// Files out of scope, such as build output, are left as they are
+		// rather than failing the whole rename.
+		skipped := scope.drop(we)
+		if len(we.Changes) == 0 && len(we.DocumentChanges) == 0 {
+			return mcp.NewToolResultError("rename produced no changes in scope; " + skippedWarning(skipped) + "\npass excludeGlobs or onlyUnder to include them"), nil
+		}
+
+		var tagEdits []jsdocTagEdit
+		var docCandidates []docCandidate
+		if oldName != "" && oldName != newName {
+			if scope.skipReason(file) == "" {
+				tagEdits = addParamTagEdits(ctx, symbols, &we.WorkspaceEdit, file, content, oldName, newName)
+			}
+			if docsMode != docsModeOff {
+				docCandidates = findDocCandidates(client.RootDir(), oldName, newName)
+			}
+			if docsMode == docsModeApply {
+				addDocEdits(&we.WorkspaceEdit, docCandidates)
+			}
+		}
+
+		// A new name already declared in scope leaves duplicate
+		// declarations that only show on the next diagnostics call; tsgo
+		// is asked about the renamed content before anything is written.
+		var conflicts []renameConflict
+		if !force {
+			files, err := edit.Plan(we)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
+			}
+			if conflicts, err = renameConflicts(ctx, client, docs, files); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("conflict check error: %v", err)), nil
+			}
+		}
+
+		if confirm || dryRun {
+			var result *mcp.CallToolResult
+			if dryRun {
+				result, err = dryRunEdit(we)
+			} else {
+				result, err = previewEdit(pending, request.Params.Name, oldName, we)
+			}
+			if err == nil && !result.IsError && len(skipped) > 0 {
+				result.Content = append([]mcp.Content{mcp.NewTextContent("warning: " + skippedWarning(skipped))}, result.Content...)
+			}
+			if err == nil && !result.IsError && len(conflicts) > 0 {
+				result.Content = append([]mcp.Content{mcp.NewTextContent("warning: " + (&renameConflictsError{conflicts}).Error())}, result.Content...)
+			}
+			if err == nil && !result.IsError && readings != nil {
+				result.Content = append([]mcp.Content{mcp.NewTextContent("warning: " + readingsWarning(readings))}, result.Content...)
+			}
+			return result, err
+		}
+		if len(conflicts) > 0 {
+			return mcp.NewToolResultError((&renameConflictsError{conflicts}).Error()), nil
+		}
 
-		changes, err := ApplyWorkspaceEdit(edit)
+		var gate editGate
+		if overlayCheck {
+			gate = overlayGate(ctx, client, docs)
+		}
+		changes, err := applyWorkspaceEdit(we, docs, gate, journal, recorder.recording(editOrigin{tool: request.Params.Name, symbol: oldName}))
 		if err != nil {
+			resyncStale(ctx, client, docs, err)
 			return mcp.NewToolResultError(fmt.Sprintf("apply error: %v", err)), nil
 		}
+		pending.InvalidateFiles(sortedChangePaths(changes))
 
 		// Re-sync all modified files so the LSP server sees the new content.
-		for filePath := range changes {
-			if syncErr := docs.SyncFile(ctx, client.Conn(), filePath); syncErr != nil {
-				return mcp.NewToolResultError(fmt.Sprintf("re-sync error for %s: %v", filePath, syncErr)), nil
-			}
+		if err := resyncChanged(ctx, client, docs, changes); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
 		}
 
 		ClearFileCache()
 
 		// Build change list in sorted path order for deterministic output.
 		totalEdits := 0
-		sortedPaths := make([]string, 0, len(changes))
-		for p := range changes {
-			sortedPaths = append(sortedPaths, p)
-		}
-		sort.Strings(sortedPaths)
+		sortedPaths := sortedChangePaths(changes)
 		changeList := make([]editInfo, 0, len(changes))
 		for _, p := range sortedPaths {
 			info := changes[p]
+			if !includeDiff {
+				info.Diff, info.DiffTruncated = "", false
+			}
 			totalEdits += info.Edits
 			changeList = append(changeList, info)
 		}
 
 		result := renameResult{
-			NewName:    newName,
-			TotalEdits: totalEdits,
-			Changes:    changeList,
+			NewName:        newName,
+			TotalEdits:     totalEdits,
+			Changes:        changeList,
+			fileOps:        fileOpsOf(changes),
+			DocsCandidates: docCandidates,
+			DocsApplied:    docsMode == docsModeApply && len(docCandidates) > 0,
+			DocEdits:       tagEdits,
+			ColumnReadings: readings,
+			SkippedFiles:   skipped,
 		}
 
 		data, err := json.MarshalIndent(result, "", "  ")
@@ -105,204 +312,156 @@ func makeRenameHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHan
 	}
 }
 
-// ApplyWorkspaceEdit applies a WorkspaceEdit to disk. It returns a map from
-// file path to the edit info for that file. On any write failure, previously
-// written files are rolled back to their original content. Files are processed
-// in sorted path order for deterministic behavior.
-func ApplyWorkspaceEdit(edit *protocol.WorkspaceEdit) (map[string]editInfo, error) {
-	// We request the simpler Changes format via DocumentChanges:false in
-	// capabilities, but defensively handle DocumentChanges too in case a
-	// server ignores the capability.
-	merged := make(map[protocol.DocumentURI][]protocol.TextEdit)
-	for docURI, edits := range edit.Changes {
-		merged[docURI] = append(merged[docURI], edits...)
-	}
-	for _, dc := range edit.DocumentChanges {
-		merged[dc.TextDocument.URI] = append(merged[dc.TextDocument.URI], dc.Edits...)
-	}
-
-	// Collect file paths in sorted order for deterministic processing.
-	paths := make([]string, 0, len(merged))
-	pathToURI := make(map[string]protocol.DocumentURI, len(merged))
-	for docURI := range merged {
-		p := docsync.URIToFile(string(docURI))
-		paths = append(paths, p)
-		pathToURI[p] = docURI
-	}
-	sort.Strings(paths)
-
-	// Read originals, compute new contents.
-	type fileWork struct {
-		path     string
-		mode     os.FileMode
-		original []byte
-		updated  []byte
-		edits    []protocol.TextEdit
+// installedPackageEdit returns the first file we touches inside an
+// installed (not workspace-linked) node_modules package, and that package.
+func installedPackageEdit(we *lsp.WorkspaceEdit, packages *workspace.PackageResolver) (string, *workspace.Package) {
+	for _, path := range edit.Paths(we) {
+		if pkg := packages.Resolve(path); pkg != nil && !pkg.Linked {
+			return path, pkg
+		}
 	}
-	work := make([]fileWork, 0, len(paths))
-
-	for _, filePath := range paths {
-		docURI := pathToURI[filePath]
-		edits := merged[docURI]
+	return "", nil
+}
 
-		fi, err := os.Stat(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("stat %s: %w", filePath, err)
+// updateDocsMode reads ts_rename's updateDocs argument. true is accepted
+// as "list", the safe mode: prose often uses the old name as an ordinary
+// word.
+func updateDocsMode(request mcp.CallToolRequest) (string, error) {
+	switch v := request.GetArguments()["updateDocs"].(type) {
+	case nil:
+		return docsModeOff, nil
+	case bool:
+		if v {
+			return docsModeList, nil
 		}
-		original, err := os.ReadFile(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("reading %s: %w", filePath, err)
+		return docsModeOff, nil
+	case string:
+		switch v {
+		case "", "false":
+			return docsModeOff, nil
+		case "true", docsModeList:
+			return docsModeList, nil
+		case docsModeApply:
+			return docsModeApply, nil
 		}
-		updated, err := applyFileEdits(original, edits)
-		if err != nil {
-			return nil, fmt.Errorf("applying edits to %s: %w", filePath, err)
-		}
-		work = append(work, fileWork{
-			path:     filePath,
-			mode:     fi.Mode().Perm(),
-			original: original,
-			updated:  updated,
-			edits:    edits,
-		})
+		return "", fmt.Errorf("invalid updateDocs %q (valid: list, apply)", v)
 	}
+	return "", fmt.Errorf("updateDocs must be \"list\" or \"apply\"")
+}
+
+// compactDiffContextLines and maxCompactDiffLines shape the diffs reported
+// with a written edit, which only need to show what changed.
+const (
+	compactDiffContextLines = 1
+	maxCompactDiffLines     = 100
+)
 
-	// Write all files; rollback on failure.
-	var written []fileWork
-	for _, w := range work {
-		if err := os.WriteFile(w.path, w.updated, w.mode); err != nil {
-			// Rollback previously written files.
-			for _, prev := range written {
-				_ = os.WriteFile(prev.path, prev.original, prev.mode)
+// beforeEditWrite, when set, runs just before each file of an edit is
+// revalidated and written; an error fails that write. Tests use it to
+// interleave outside writes.
+var beforeEditWrite func(path string) error
+
+// writeOptions are the options tools write edits with: atomically, each
+// file keeping its mode.
+func writeOptions() edit.Options {
+	return edit.Options{PreserveMode: true, Atomic: true, BeforeWrite: beforeEditWrite}
+}
+
+// applyWorkspaceEdit applies we to disk with edit.Apply and returns a map
+// from file path to the edit info for that file. Updated content failing
+// checkEditSanity is rejected before anything is written. It takes
+// optional document state the edit is checked against (see staleFiles),
+// an optional gate run on every file's updated content after the sanity
+// checks and aborted when the edit fails, an optional journal policy under which large edits are written
+// by writeJournaled, and an optional provenance recording.
+func applyWorkspaceEdit(we *lsp.WorkspaceEdit, docs editDocs, gate editGate, journal *journalPolicy, rec *editRecording) (map[string]editInfo, error) {
+	opts := writeOptions()
+	opts.Diff, opts.DiffContext, opts.MaxDiffLines = true, compactDiffContextLines, maxCompactDiffLines
+	opts.Check = func(files []edit.File) error {
+		if docs != nil {
+			if stale := staleFiles(we, files, docs); len(stale) > 0 {
+				return &staleEditError{Files: stale}
 			}
-			return nil, fmt.Errorf("writing %s: %w", w.path, err)
 		}
-		written = append(written, w)
+		return checkEdit(files, gate)
 	}
-
-	// Build result info.
-	result := make(map[string]editInfo, len(work))
-	for _, w := range work {
-		preview := ""
-		fl := int(firstEditLine(w.edits))
-		if lines := strings.SplitN(string(w.updated), "\n", fl+2); len(lines) > fl {
-			preview = strings.TrimSpace(lines[fl])
-		}
-		result[w.path] = editInfo{
-			File:    w.path,
-			Edits:   len(w.edits),
-			Preview: preview,
+	opts.Write = func(files []edit.File) error {
+		return writeEdit(files, journal, rec)
+	}
+	applied, err := edit.Apply(we, opts)
+	if err != nil {
+		if gate != nil {
+			gate.abort()
 		}
+		return nil, err
 	}
-	return result, nil
-}
 
-// firstEditLine returns the smallest line number from a set of edits.
-func firstEditLine(edits []protocol.TextEdit) uint32 {
-	if len(edits) == 0 {
-		return 0
-	}
-	min := edits[0].Range.Start.Line
-	for _, e := range edits[1:] {
-		if e.Range.Start.Line < min {
-			min = e.Range.Start.Line
+	result := make(map[string]editInfo, len(applied.Files))
+	for _, f := range applied.Files {
+		info := editInfo{
+			File:          f.Path,
+			Edits:         len(f.Edits),
+			Diff:          f.Diff,
+			DiffTruncated: f.DiffTruncated,
+			ChangedLines:  f.ChangedLines,
+			Created:       f.Created && f.RenamedFrom == "",
+			Deleted:       f.Deleted,
+			RenamedFrom:   f.RenamedFrom,
+		}
+		if !f.Deleted {
+			fl := int(edit.FirstLine(f.Edits))
+			if lines := strings.SplitN(string(f.Updated), "\n", fl+2); len(lines) > fl {
+				info.Preview = strings.TrimSpace(lines[fl])
+			}
 		}
+		if !f.Created && !f.Deleted && !f.Rebased {
+			info.sync = newEditSync(f.File)
+		}
+		result[f.Path] = info
 	}
-	return min
+	return result, nil
 }
 
-// applyFileEdits applies a set of TextEdits to file content. Edits are applied
-// in reverse order (bottom-up) so that earlier byte offsets remain valid.
-func applyFileEdits(content []byte, edits []protocol.TextEdit) ([]byte, error) {
-	// Sort edits in reverse order: by line descending, then character descending.
-	sorted := make([]protocol.TextEdit, len(edits))
-	copy(sorted, edits)
-	sort.Slice(sorted, func(i, j int) bool {
-		if sorted[i].Range.Start.Line != sorted[j].Range.Start.Line {
-			return sorted[i].Range.Start.Line > sorted[j].Range.Start.Line
+// checkEdit runs checkEditSanity and then gate, when set, on every file
+// of an edit. A moved file is checked against its content before the
+// move.
+func checkEdit(files []edit.File, gate editGate) error {
+	for _, f := range files {
+		if f.Deleted {
+			continue
 		}
-		return sorted[i].Range.Start.Character > sorted[j].Range.Start.Character
-	})
-
-	lines := splitLines(content)
-
-	for _, edit := range sorted {
-		startLine := int(edit.Range.Start.Line)
-		endLine := int(edit.Range.End.Line)
-
-		if startLine >= len(lines) || endLine >= len(lines) {
-			return nil, fmt.Errorf("edit range out of bounds: start line %d, end line %d, file has %d lines", startLine, endLine, len(lines))
+		err := checkEditSanity(f.Path, f.Base(), f.Updated, f.Edits)
+		if err == nil && gate != nil {
+			err = gate.check(f.Path, f.Base(), f.Updated)
 		}
-
-		startByte := utf16ColToByteOffset(lines[startLine], edit.Range.Start.Character)
-		endByte := utf16ColToByteOffset(lines[endLine], edit.Range.End.Character)
-
-		// Calculate absolute byte offsets within content.
-		absStart := lineOffset(lines, startLine) + startByte
-		absEnd := lineOffset(lines, endLine) + endByte
-
-		if absStart > len(content) || absEnd > len(content) || absStart > absEnd {
-			return nil, fmt.Errorf("computed byte offsets out of range: start=%d end=%d len=%d", absStart, absEnd, len(content))
+		if err != nil {
+			if sanityErr, ok := err.(*editSanityError); ok {
+				logRejectedEdit(sanityErr, f.Updated)
+			}
+			return err
 		}
-
-		var buf []byte
-		buf = append(buf, content[:absStart]...)
-		buf = append(buf, []byte(edit.NewText)...)
-		buf = append(buf, content[absEnd:]...)
-		content = buf
-
-		// Recompute lines after mutation since offsets shift.
-		lines = splitLines(content)
 	}
-
-	return content, nil
+	return nil
 }
 
-// splitLines splits content into lines, preserving line endings.
-// Each element includes its trailing \n (or \r\n) except possibly the last.
-func splitLines(content []byte) []string {
-	if len(content) == 0 {
-		return []string{""}
+// writeEdit writes the checked files of an edit, journaled when the policy
+// applies, and publishes its provenance record once every file is
+// written. Any failure rolls back both the written files and the record.
+func writeEdit(files []edit.File, journal *journalPolicy, rec *editRecording) error {
+	record, err := rec.prepare(files)
+	if err != nil {
+		return err
 	}
-	s := string(content)
-	var lines []string
-	for {
-		idx := strings.IndexByte(s, '\n')
-		if idx < 0 {
-			lines = append(lines, s)
-			break
-		}
-		lines = append(lines, s[:idx+1])
-		s = s[idx+1:]
+	write := func() error { return edit.Write(files, writeOptions()) }
+	if journal.applies(len(files)) {
+		write = func() error { return writeJournaled(journal, files) }
 	}
-	return lines
-}
-
-// lineOffset returns the byte offset of the start of a given line.
-func lineOffset(lines []string, line int) int {
-	off := 0
-	for i := 0; i < line && i < len(lines); i++ {
-		off += len(lines[i])
+	if err := write(); err != nil {
+		record.discard()
+		return err
 	}
-	return off
-}
-
-// utf16ColToByteOffset converts a UTF-16 column offset to a byte offset within
-// a line string. LSP positions use UTF-16 code units.
-func utf16ColToByteOffset(line string, utf16Col uint32) int {
-	utf16Count := uint32(0)
-	byteOff := 0
-	for byteOff < len(line) {
-		if utf16Count >= utf16Col {
-			break
-		}
-		r, size := utf8.DecodeRuneInString(line[byteOff:])
-		if r <= 0xFFFF {
-			utf16Count++
-		} else {
-			// Supplementary character: 2 UTF-16 code units.
-			utf16Count += 2
-		}
-		byteOff += size
+	if err := record.commit(); err != nil {
+		return edit.Rollback(err, files, writeOptions())
 	}
-	return byteOff
+	return nil
 }
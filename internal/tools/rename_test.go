@@ -3,184 +3,15 @@ package tools
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"go.lsp.dev/protocol"
-)
-
-func TestUTF16ColToByteOffset(t *testing.T) {
-	tests := []struct {
-		name     string
-		line     string
-		utf16Col uint32
-		want     int
-	}{
-		// ASCII: each character is 1 byte and 1 UTF-16 unit.
-		{name: "ascii col=0", line: "hello", utf16Col: 0, want: 0},
-		{name: "ascii col=5", line: "hello", utf16Col: 5, want: 5},
-
-		// 2-byte UTF-8 character (e-acute U+00E9): 1 UTF-16 code unit.
-		{name: "2byte after h", line: "h\u00e9llo", utf16Col: 1, want: 1},
-		{name: "2byte after e-acute", line: "h\u00e9llo", utf16Col: 2, want: 3},
-
-		// 3-byte UTF-8 character (CJK U+4E2D): 1 UTF-16 code unit.
-		{name: "cjk col=1", line: "\u4e2d\u6587", utf16Col: 1, want: 3},
-
-		// 4-byte UTF-8 character (emoji U+1F600): 2 UTF-16 code units (surrogate pair).
-		{name: "emoji after a", line: "a\U0001F600b", utf16Col: 1, want: 1},
-		{name: "emoji after emoji", line: "a\U0001F600b", utf16Col: 3, want: 5},
-		{name: "emoji at b", line: "a\U0001F600b", utf16Col: 4, want: 6},
-
-		// col=0 for any string.
-		{name: "col=0 empty", line: "", utf16Col: 0, want: 0},
-		{name: "col=0 nonempty", line: "abc", utf16Col: 0, want: 0},
-
-		// col beyond end returns len(line).
-		{name: "beyond end ascii", line: "abc", utf16Col: 100, want: 3},
-		{name: "beyond end unicode", line: "\u4e2d", utf16Col: 100, want: 3},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := utf16ColToByteOffset(tt.line, tt.utf16Col)
-			if got != tt.want {
-				t.Errorf("utf16ColToByteOffset(%q, %d) = %d, want %d", tt.line, tt.utf16Col, got, tt.want)
-			}
-		})
-	}
-}
-
-func TestApplyFileEdits(t *testing.T) {
-	t.Run("single edit replacing greet with sayHello", func(t *testing.T) {
-		content := []byte("export function greet(name: string): string {\n  return \"Hello\";\n}\n")
-		edits := []protocol.TextEdit{
-			{
-				Range: protocol.Range{
-					Start: protocol.Position{Line: 0, Character: 16},
-					End:   protocol.Position{Line: 0, Character: 21},
-				},
-				NewText: "sayHello",
-			},
-		}
-		got, err := applyFileEdits(content, edits)
-		if err != nil {
-			t.Fatalf("applyFileEdits: %v", err)
-		}
-		want := "export function sayHello(name: string): string {\n  return \"Hello\";\n}\n"
-		if string(got) != want {
-			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
-		}
-	})
-
-	t.Run("multiple edits on different lines", func(t *testing.T) {
-		content := []byte("const a = greet;\nconst b = greet;\n")
-		edits := []protocol.TextEdit{
-			{
-				Range: protocol.Range{
-					Start: protocol.Position{Line: 0, Character: 10},
-					End:   protocol.Position{Line: 0, Character: 15},
-				},
-				NewText: "sayHello",
-			},
-			{
-				Range: protocol.Range{
-					Start: protocol.Position{Line: 1, Character: 10},
-					End:   protocol.Position{Line: 1, Character: 15},
-				},
-				NewText: "sayHello",
-			},
-		}
-		got, err := applyFileEdits(content, edits)
-		if err != nil {
-			t.Fatalf("applyFileEdits: %v", err)
-		}
-		want := "const a = sayHello;\nconst b = sayHello;\n"
-		if string(got) != want {
-			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
-		}
-	})
-
-	t.Run("multiple edits on the same line", func(t *testing.T) {
-		// "import { greet, greet2 } from './index';\n"
-		content := []byte("import { greet, greet2 } from './index';\n")
-		edits := []protocol.TextEdit{
-			{
-				Range: protocol.Range{
-					Start: protocol.Position{Line: 0, Character: 9},
-					End:   protocol.Position{Line: 0, Character: 14},
-				},
-				NewText: "sayHello",
-			},
-			{
-				Range: protocol.Range{
-					Start: protocol.Position{Line: 0, Character: 16},
-					End:   protocol.Position{Line: 0, Character: 22},
-				},
-				NewText: "sayHello2",
-			},
-		}
-		got, err := applyFileEdits(content, edits)
-		if err != nil {
-			t.Fatalf("applyFileEdits: %v", err)
-		}
-		want := "import { sayHello, sayHello2 } from './index';\n"
-		if string(got) != want {
-			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
-		}
-	})
-
-	t.Run("edit replacing multiple characters on same line", func(t *testing.T) {
-		content := []byte("function longFunctionName() {}\n")
-		edits := []protocol.TextEdit{
-			{
-				Range: protocol.Range{
-					Start: protocol.Position{Line: 0, Character: 9},
-					End:   protocol.Position{Line: 0, Character: 25},
-				},
-				NewText: "fn",
-			},
-		}
-		got, err := applyFileEdits(content, edits)
-		if err != nil {
-			t.Fatalf("applyFileEdits: %v", err)
-		}
-		want := "function fn() {}\n"
-		if string(got) != want {
-			t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
-		}
-	})
-}
 
-func TestApplyFileEditsReverseOrder(t *testing.T) {
-	// Create edits in FORWARD order (line 0 before line 2).
-	// The function must internally sort to reverse order.
-	content := []byte("const a = greet;\nconst b = other;\nconst c = greet;\n")
-	edits := []protocol.TextEdit{
-		{
-			Range: protocol.Range{
-				Start: protocol.Position{Line: 0, Character: 10},
-				End:   protocol.Position{Line: 0, Character: 15},
-			},
-			NewText: "sayHello",
-		},
-		{
-			Range: protocol.Range{
-				Start: protocol.Position{Line: 2, Character: 10},
-				End:   protocol.Position{Line: 2, Character: 15},
-			},
-			NewText: "sayHello",
-		},
-	}
-
-	got, err := applyFileEdits(content, edits)
-	if err != nil {
-		t.Fatalf("applyFileEdits: %v", err)
-	}
-	want := "const a = sayHello;\nconst b = other;\nconst c = sayHello;\n"
-	if string(got) != want {
-		t.Errorf("got:\n%s\nwant:\n%s", string(got), want)
-	}
-}
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
 
 func TestApplyWorkspaceEdit(t *testing.T) {
 	t.Run("multi-file edit", func(t *testing.T) {
@@ -202,7 +33,7 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 		uri1 := protocol.DocumentURI("file://" + file1)
 		uri2 := protocol.DocumentURI("file://" + file2)
 
-		edit := &protocol.WorkspaceEdit{
+		edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{
 			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
 				uri1: {
 					{
@@ -230,11 +61,11 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 					},
 				},
 			},
-		}
+		}}
 
-		result, err := ApplyWorkspaceEdit(edit)
+		result, err := applyWorkspaceEdit(edit, nil, nil, nil, nil)
 		if err != nil {
-			t.Fatalf("ApplyWorkspaceEdit: %v", err)
+			t.Fatalf("applyWorkspaceEdit: %v", err)
 		}
 
 		if len(result) != 2 {
@@ -260,66 +91,166 @@ func TestApplyWorkspaceEdit(t *testing.T) {
 		if string(got2) != want2 {
 			t.Errorf("file2:\ngot:  %s\nwant: %s", string(got2), want2)
 		}
-	})
 
-	t.Run("rollback on write failure", func(t *testing.T) {
-		tmpDir := t.TempDir()
+		// Each file reports what was written.
+		info := result[file2]
+		wantDiff := "--- " + file2 + "\n+++ " + file2 + "\n@@ -1,2 +1,2 @@\n" +
+			"-import { greet } from './index';\n-const result = greet('world');\n" +
+			"+import { sayHello } from './index';\n+const result = sayHello('world');\n"
+		if info.Diff != wantDiff || info.DiffTruncated {
+			t.Errorf("file2 diff = %q, want %q", info.Diff, wantDiff)
+		}
+		if !reflect.DeepEqual(info.ChangedLines, []int{1, 2}) || !reflect.DeepEqual(result[file1].ChangedLines, []int{1}) {
+			t.Errorf("changed lines = %v and %v, want [1 2] and [1]", info.ChangedLines, result[file1].ChangedLines)
+		}
+	})
+}
 
-		// Names chosen so "aaa_writable.ts" sorts before "zzz_readonly.ts",
-		// guaranteeing the writable file is written first and must be rolled back.
-		writableFile := filepath.Join(tmpDir, "aaa_writable.ts")
-		readonlyFile := filepath.Join(tmpDir, "zzz_readonly.ts")
+func TestApplyWorkspaceEditKeepsModes(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		journal *journalPolicy
+	}{
+		{"direct", nil},
+		{"journaled", &journalPolicy{dir: t.TempDir(), always: true, chunkSize: 1}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			modes := map[string]os.FileMode{
+				filepath.Join(dir, "script.ts"): 0o755,
+				filepath.Join(dir, "secret.ts"): 0o600,
+				filepath.Join(dir, "shared.ts"): 0o664,
+			}
+			edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+			for path, mode := range modes {
+				if err := os.WriteFile(path, []byte("const greet = 1;\n"), mode); err != nil {
+					t.Fatal(err)
+				}
+				// WriteFile applies the umask; the test needs the exact mode.
+				if err := os.Chmod(path, mode); err != nil {
+					t.Fatal(err)
+				}
+				edit.Changes[protocol.DocumentURI("file://"+path)] = []protocol.TextEdit{{
+					Range:   protocol.Range{Start: protocol.Position{Character: 6}, End: protocol.Position{Character: 11}},
+					NewText: "sayHello",
+				}}
+			}
 
-		writableContent := "const a = greet;\n"
-		readonlyContent := "const b = greet;\n"
+			if _, err := applyWorkspaceEdit(edit, nil, nil, tt.journal, nil); err != nil {
+				t.Fatal(err)
+			}
+			for path, mode := range modes {
+				fi, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fi.Mode().Perm() != mode {
+					t.Errorf("%s: mode %v after the edit, want %v", filepath.Base(path), fi.Mode().Perm(), mode)
+				}
+				if data, _ := os.ReadFile(path); string(data) != "const sayHello = 1;\n" {
+					t.Errorf("%s = %q, want the edit written", filepath.Base(path), data)
+				}
+			}
+		})
+	}
+}
 
-		if err := os.WriteFile(writableFile, []byte(writableContent), 0644); err != nil {
-			t.Fatalf("WriteFile: %v", err)
+func TestInstalledPackageEdit(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/app.ts":                      "",
+		"node_modules/zod/package.json":   `{"name": "zod", "version": "3.22.4"}`,
+		"node_modules/zod/lib/types.d.ts": "",
+		"packages/ui/package.json":        `{"name": "@acme/ui"}`,
+		"packages/ui/src/button.ts":       "",
+		"node_modules/@acme/.keep":        "",
+		"node_modules/.pnpm/react@18.2.0/node_modules/react/package.json": `{"name": "react", "version": "18.2.0"}`,
+		"node_modules/.pnpm/react@18.2.0/node_modules/react/index.d.ts":   "",
+	}
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
 		}
-		if err := os.WriteFile(readonlyFile, []byte(readonlyContent), 0444); err != nil {
-			t.Fatalf("WriteFile: %v", err)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
 		}
-		// Ensure cleanup can remove the read-only file.
-		t.Cleanup(func() { _ = os.Chmod(readonlyFile, 0644) })
+	}
+	if err := os.Symlink(filepath.Join(root, "packages", "ui"), filepath.Join(root, "node_modules", "@acme", "ui")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(".pnpm", "react@18.2.0", "node_modules", "react"), filepath.Join(root, "node_modules", "react")); err != nil {
+		t.Fatal(err)
+	}
+	uri := func(rel string) protocol.DocumentURI {
+		return protocol.DocumentURI(docsync.FileToURI(filepath.Join(root, filepath.FromSlash(rel))))
+	}
 
-		writableURI := protocol.DocumentURI("file://" + writableFile)
-		readonlyURI := protocol.DocumentURI("file://" + readonlyFile)
+	tests := []struct {
+		name    string
+		files   []string
+		wantPkg string
+	}{
+		{name: "project only", files: []string{"src/app.ts"}},
+		{name: "linked workspace package", files: []string{"src/app.ts", "node_modules/@acme/ui/src/button.ts"}},
+		{name: "installed package", files: []string{"src/app.ts", "node_modules/zod/lib/types.d.ts"}, wantPkg: "zod@3.22.4"},
+		{name: "pnpm-linked installed package", files: []string{"src/app.ts", "node_modules/react/index.d.ts"}, wantPkg: "react@18.2.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+			for _, f := range tt.files {
+				edit.Changes[uri(f)] = []protocol.TextEdit{{NewText: "x"}}
+			}
+			_, pkg := installedPackageEdit(edit, workspace.NewPackageResolver())
+			got := ""
+			if pkg != nil {
+				got = pkg.String()
+			}
+			if got != tt.wantPkg {
+				t.Errorf("package = %q, want %q", got, tt.wantPkg)
+			}
+		})
+	}
+}
 
-		edit := &protocol.WorkspaceEdit{
-			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
-				writableURI: {
-					{
-						Range: protocol.Range{
-							Start: protocol.Position{Line: 0, Character: 10},
-							End:   protocol.Position{Line: 0, Character: 15},
-						},
-						NewText: "sayHello",
-					},
-				},
-				readonlyURI: {
-					{
-						Range: protocol.Range{
-							Start: protocol.Position{Line: 0, Character: 10},
-							End:   protocol.Position{Line: 0, Character: 15},
-						},
-						NewText: "sayHello",
-					},
-				},
+func TestColumnReadings(t *testing.T) {
+	line := "\tconst total = count + 1;"
+	tests := []struct {
+		name string
+		col  int
+		mode columnMode
+		want []columnReading
+	}{
+		{
+			name: "character column read visually",
+			col:  8,
+			mode: columnMode{tabWidth: 4},
+			want: []columnReading{
+				{ColumnMode: columnModeCharacter, Column: 8, Identifier: "total"},
+				{ColumnMode: columnModeVisual, Column: 5, Identifier: "const"},
 			},
-		}
-
-		_, err := ApplyWorkspaceEdit(edit)
-		if err == nil {
-			t.Fatal("expected error due to read-only file, got nil")
-		}
-
-		// Verify the writable file was rolled back to original.
-		got, err := os.ReadFile(writableFile)
-		if err != nil {
-			t.Fatalf("ReadFile: %v", err)
-		}
-		if string(got) != writableContent {
-			t.Errorf("writable file not rolled back:\ngot:  %s\nwant: %s", string(got), writableContent)
-		}
-	})
+		},
+		{
+			name: "visual mode first",
+			col:  11,
+			mode: columnMode{visual: true, tabWidth: 4},
+			want: []columnReading{
+				{ColumnMode: columnModeVisual, Column: 8, Identifier: "total"},
+				{ColumnMode: columnModeCharacter, Column: 11, Identifier: "total"},
+			},
+		},
+		{name: "before the tab", col: 1, mode: columnMode{tabWidth: 8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := columnReadings(line, tt.col, tt.mode)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if no := columnReadings("const total = 1;", 7, columnMode{tabWidth: 8}); no != nil {
+		t.Errorf("readings without tabs = %+v", no)
+	}
 }
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+)
+
+// conflictCodes are the diagnostics of two declarations of one name in one
+// scope, as a rename to a name already taken causes.
+var conflictCodes = map[int]bool{
+	1117: true, // An object literal cannot have multiple properties with the same name.
+	2300: true, // Duplicate identifier '{0}'.
+	2308: true, // Module {0} has already exported a member named '{1}'.
+	2393: true, // Duplicate function implementation.
+	2395: true, // Individual declarations in merged declaration '{0}' must be all exported or all local.
+	2397: true, // Declaration name conflicts with built-in global identifier '{0}'.
+	2440: true, // Import declaration conflicts with local declaration of '{0}'.
+	2451: true, // Cannot redeclare block-scoped variable '{0}'.
+	2567: true, // Enum declarations can only merge with namespace or other enum declarations.
+}
+
+// renameConflict is a conflict diagnostic a rename would introduce.
+type renameConflict struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (c renameConflict) String() string {
+	return fmt.Sprintf("%s:%d:%d TS%d %s", c.File, c.Line, c.Column, c.Code, c.Message)
+}
+
+// renameConflictsError refuses a rename that would introduce conflicts.
+type renameConflictsError struct {
+	Conflicts []renameConflict
+}
+
+func (e *renameConflictsError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		lines[i] = "  " + c.String()
+	}
+	return fmt.Sprintf("the new name conflicts with existing declarations; nothing was written (pass force to rename anyway):\n%s", strings.Join(lines, "\n"))
+}
+
+// renameConflicts returns the conflict diagnostics the updated content of
+// work would introduce. The updated files are sent to tsgo together as
+// unsaved overlays, so that a conflict between two of them shows, and
+// their diagnostics are compared with those of their content on disk: a
+// conflict counts as new when the file has more of its code and message
+// than before. The overlays are replaced by the content on disk again
+// before returning. Deleted and documentation files are not checked.
+func renameConflicts(ctx context.Context, client overlayBackend, docs *docsync.Manager, work []edit.File) (conflicts []renameConflict, err error) {
+	type checked struct {
+		edit.File
+		// before counts the conflict diagnostics of the file on disk;
+		// a created file has none.
+		before map[string]int
+	}
+	var files []checked
+	for _, w := range work {
+		if !w.Deleted && !isDocFile(w.Path) {
+			files = append(files, checked{File: w, before: map[string]int{}})
+		}
+	}
+	defer func() {
+		for _, f := range files {
+			var restoreErr error
+			if f.Created {
+				_, _, restoreErr = docs.CloseFiles(ctx, client.Conn(), []string{f.Path})
+			} else {
+				restoreErr = docs.ResyncFile(ctx, client.Conn(), f.Path)
+			}
+			if restoreErr != nil && err == nil {
+				err = fmt.Errorf("restoring %s after the conflict check: %w", f.Path, restoreErr)
+			}
+		}
+	}()
+
+	for _, f := range files {
+		if f.Created {
+			continue
+		}
+		if err := docs.ResyncFile(ctx, client.Conn(), f.Path); err != nil {
+			return nil, fmt.Errorf("conflict check sync of %s: %w", f.Path, err)
+		}
+		diags, err := syncedDiagnostics(ctx, client, docs, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range diags {
+			if code, _ := diagnosticCode(d.Code); conflictCodes[code] {
+				f.before[conflictKey(code, d.Message)]++
+			}
+		}
+	}
+	for _, f := range files {
+		if err := docs.SyncContent(ctx, client.Conn(), f.Path, f.Updated); err != nil {
+			return nil, fmt.Errorf("overlay sync of %s: %w", f.Path, err)
+		}
+	}
+	for _, f := range files {
+		diags, err := syncedDiagnostics(ctx, client, docs, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range diags {
+			code, _ := diagnosticCode(d.Code)
+			if !conflictCodes[code] {
+				continue
+			}
+			if key := conflictKey(code, d.Message); f.before[key] > 0 {
+				f.before[key]--
+				continue
+			}
+			conflicts = append(conflicts, renameConflict{
+				File:    f.Path,
+				Line:    int(d.Range.Start.Line) + 1,
+				Column:  int(d.Range.Start.Character) + 1,
+				Code:    code,
+				Message: d.Message,
+			})
+		}
+	}
+	return conflicts, nil
+}
+
+// syncedDiagnostics returns tsgo's diagnostics of the content last synced
+// for path.
+func syncedDiagnostics(ctx context.Context, client overlayBackend, docs *docsync.Manager, path string) ([]protocol.Diagnostic, error) {
+	version, _ := docs.Version(path)
+	report, err := client.DiagnosticReport(ctx, path, version)
+	if err != nil {
+		return nil, fmt.Errorf("conflict check diagnostics of %s: %w", path, err)
+	}
+	return report.Items, nil
+}
+
+// conflictKey identifies the diagnostics that count as the same conflict.
+func conflictKey(code int, message string) string {
+	return fmt.Sprintf("%d %s", code, message)
+}
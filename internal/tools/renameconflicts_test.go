@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// documentsConn records the text of every open document by URI.
+type documentsConn struct {
+	jsonrpc2.Conn
+	mu   sync.Mutex
+	text map[protocol.DocumentURI]string
+}
+
+func (c *documentsConn) Notify(_ context.Context, _ string, params interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch p := params.(type) {
+	case *protocol.DidOpenTextDocumentParams:
+		c.text[p.TextDocument.URI] = p.TextDocument.Text
+	case *protocol.DidChangeTextDocumentParams:
+		c.text[p.TextDocument.URI] = p.ContentChanges[0].Text
+	case *protocol.DidCloseTextDocumentParams:
+		delete(c.text, p.TextDocument.URI)
+	}
+	return nil
+}
+
+var constDecl = regexp.MustCompile(`(?m)^(?:export )?const (\w+)`)
+
+// fakeConflictBackend reports TS2451 at every declaration of a const
+// declared more than once in the synced text of a file.
+type fakeConflictBackend struct{ conn *documentsConn }
+
+func (b fakeConflictBackend) Conn() jsonrpc2.Conn { return b.conn }
+
+func (b fakeConflictBackend) DiagnosticReport(_ context.Context, file string, _ int32) (lsp.FileDiagnostics, error) {
+	b.conn.mu.Lock()
+	defer b.conn.mu.Unlock()
+	text, ok := b.conn.text[fileURI(file)]
+	if !ok {
+		return lsp.FileDiagnostics{}, fmt.Errorf("%s is not open", file)
+	}
+	lines := strings.Split(text, "\n")
+	declared := make(map[string][]int)
+	for i, l := range lines {
+		if m := constDecl.FindStringSubmatch(l); m != nil {
+			declared[m[1]] = append(declared[m[1]], i)
+		}
+	}
+	var diags []protocol.Diagnostic
+	for i, l := range lines {
+		m := constDecl.FindStringSubmatchIndex(l)
+		if m == nil || len(declared[l[m[2]:m[3]]]) < 2 {
+			continue
+		}
+		diags = append(diags, protocol.Diagnostic{
+			Range:   protocol.Range{Start: protocol.Position{Line: uint32(i), Character: uint32(m[2])}},
+			Code:    float64(2451),
+			Message: fmt.Sprintf("Cannot redeclare block-scoped variable '%s'.", l[m[2]:m[3]]),
+		})
+	}
+	return lsp.FileDiagnostics{Items: diags, Source: lsp.DiagnosticSourcePull}, nil
+}
+
+func TestRenameConflicts(t *testing.T) {
+	const (
+		aContent = "export const foo = 1;\nconst bar = 2;\n"
+		// b.ts declares dup twice already.
+		bContent = "const dup = 1;\nconst dup = 2;\nconst x = 3;\n"
+	)
+	tests := []struct {
+		name string
+		// edit gets the paths of a.ts and b.ts and of the missing n.ts.
+		edit func(a, b, n string) *lsp.WorkspaceEdit
+		want []string
+	}{
+		{
+			name: "new name declared in scope",
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					fileURI(a): {textEdit(0, 13, 0, 16, "bar")},
+				}}}
+			},
+			want: []string{
+				"a.ts:1:14 TS2451 Cannot redeclare block-scoped variable 'bar'.",
+				"a.ts:2:7 TS2451 Cannot redeclare block-scoped variable 'bar'.",
+			},
+		},
+		{
+			name: "free new name",
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					fileURI(a): {textEdit(0, 13, 0, 16, "baz")},
+				}}}
+			},
+		},
+		{
+			name: "conflict there before",
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					fileURI(b): {textEdit(2, 6, 2, 7, "y")},
+				}}}
+			},
+		},
+		{
+			name: "conflict added to one there before",
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					fileURI(b): {textEdit(2, 6, 2, 7, "dup")},
+				}}}
+			},
+			want: []string{"b.ts:3:7 TS2451 Cannot redeclare block-scoped variable 'dup'."},
+		},
+		{
+			name: "created file",
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{
+					docEdit(n, insertAtStart("const n = 1;\nconst n = 2;\n")),
+				}}}
+			},
+			want: []string{
+				"n.ts:1:7 TS2451 Cannot redeclare block-scoped variable 'n'.",
+				"n.ts:2:7 TS2451 Cannot redeclare block-scoped variable 'n'.",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			a, b, n := filepath.Join(dir, "a.ts"), filepath.Join(dir, "b.ts"), filepath.Join(dir, "n.ts")
+			writeString(t, a, aContent)
+			writeString(t, b, bContent)
+			docs := docsync.NewManager()
+			backend := fakeConflictBackend{conn: &documentsConn{text: make(map[protocol.DocumentURI]string)}}
+
+			work, err := edit.Plan(tt.edit(a, b, n))
+			if err != nil {
+				t.Fatal(err)
+			}
+			conflicts, err := renameConflicts(ctx, backend, docs, work)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range conflicts {
+				got = append(got, strings.TrimPrefix(c.String(), dir+string(filepath.Separator)))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("conflicts =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+
+			// tsgo is left with the content on disk, which is unchanged.
+			checkFiles(t, dir, map[string]string{"a.ts": aContent, "b.ts": bContent})
+			for _, w := range work {
+				text, open := backend.conn.text[fileURI(w.Path)]
+				switch {
+				case w.Created && open:
+					t.Errorf("created file %s left open in the server", filepath.Base(w.Path))
+				case !w.Created && text != string(w.Original):
+					t.Errorf("server left with %q for %s", text, filepath.Base(w.Path))
+				}
+			}
+		})
+	}
+}
+
+func TestRenameConflictsError(t *testing.T) {
+	err := &renameConflictsError{Conflicts: []renameConflict{
+		{File: "/p/a.ts", Line: 1, Column: 14, Code: 2451, Message: "Cannot redeclare block-scoped variable 'bar'."},
+		{File: "/p/a.ts", Line: 2, Column: 7, Code: 2451, Message: "Cannot redeclare block-scoped variable 'bar'."},
+	}}
+	want := "the new name conflicts with existing declarations; nothing was written (pass force to rename anyway):\n" +
+		"  /p/a.ts:1:14 TS2451 Cannot redeclare block-scoped variable 'bar'.\n" +
+		"  /p/a.ts:2:7 TS2451 Cannot redeclare block-scoped variable 'bar'."
+	if err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+// Modes of ts_rename's updateDocs parameter.
+const (
+	docsModeOff   = ""
+	docsModeList  = "list"
+	docsModeApply = "apply"
+)
+
+const (
+	// docsMaxVisits caps the walk for documentation files.
+	docsMaxVisits = 20000
+	// docsMaxFileSize skips documentation files too large to be prose.
+	docsMaxFileSize = 1 << 20
+)
+
+// docExtensions are the non-code files searched for stale mentions of a
+// renamed symbol.
+var docExtensions = map[string]bool{
+	".md":   true,
+	".mdx":  true,
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+}
+
+// docSkipFiles are generated files that mention every dependency name and
+// must never be rewritten.
+var docSkipFiles = map[string]bool{
+	"package-lock.json": true,
+	"pnpm-lock.yaml":    true,
+	"CHANGELOG.md":      true,
+}
+
+// docCandidate is a whole-word mention of the old name in a non-code file.
+type docCandidate struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Preview string `json:"preview"`
+
+	// edit replaces the mention with the new name.
+	edit protocol.TextEdit
+}
+
+// urlPattern matches URL-like tokens; mentions inside them are skipped so
+// links and anchors keep working.
+var urlPattern = regexp.MustCompile(`(?:[A-Za-z][A-Za-z0-9+.-]*://|www\.)[^\s)>\]"'` + "`" + `]+`)
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b == '$' ||
+		('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9') ||
+		b >= utf8.RuneSelf
+}
+
+// findWordOccurrences returns the byte offsets of whole-word occurrences
+// of name in line that are not part of a URL. Word boundaries follow
+// JavaScript identifier characters, so "$store" and "user_id" are single
+// words.
+func findWordOccurrences(line, name string) []int {
+	if name == "" {
+		return nil
+	}
+	urls := urlPattern.FindAllStringIndex(line, -1)
+	var out []int
+	for start := 0; ; {
+		i := strings.Index(line[start:], name)
+		if i < 0 {
+			return out
+		}
+		i += start
+		end := i + len(name)
+		start = i + 1
+		if i > 0 && isIdentByte(line[i-1]) {
+			continue
+		}
+		if end < len(line) && isIdentByte(line[end]) {
+			continue
+		}
+		inURL := false
+		for _, u := range urls {
+			if i >= u[0] && i < u[1] {
+				inURL = true
+				break
+			}
+		}
+		if !inURL {
+			out = append(out, i)
+		}
+	}
+}
+
+// byteOffsetToUTF16Col converts a byte offset within line to an LSP
+// (UTF-16) column.
+func byteOffsetToUTF16Col(line string, off int) uint32 {
+	var col uint32
+	for _, r := range line[:off] {
+		if r > 0xFFFF {
+			col += 2
+		} else {
+			col++
+		}
+	}
+	return col
+}
+
+// findDocCandidates scans the documentation files under root for whole-word
+// mentions of oldName and prepares edits replacing them with newName.
+func findDocCandidates(root, oldName, newName string) []docCandidate {
+	var out []docCandidate
+	_ = workspace.Walk(root, workspace.WalkOptions{MaxDepth: -1, MaxVisits: docsMaxVisits}, func(p string, d fs.DirEntry, _ int) error {
+		if d.IsDir() || !isDocFile(p) || docSkipFiles[d.Name()] {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > docsMaxFileSize {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		out = append(out, docCandidatesInContent(p, string(content), oldName, newName)...)
+		return nil
+	})
+	return out
+}
+
+// docCandidatesInContent finds mentions of oldName in one file's content.
+func docCandidatesInContent(file, content, oldName, newName string) []docCandidate {
+	var out []docCandidate
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		for _, off := range findWordOccurrences(line, oldName) {
+			col := byteOffsetToUTF16Col(line, off)
+			out = append(out, docCandidate{
+				File:    file,
+				Line:    i + 1,
+				Column:  int(col) + 1,
+				Preview: strings.TrimSpace(line),
+				edit: protocol.TextEdit{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: uint32(i), Character: col},
+						End:   protocol.Position{Line: uint32(i), Character: byteOffsetToUTF16Col(line, off+len(oldName))},
+					},
+					NewText: newName,
+				},
+			})
+		}
+	}
+	return out
+}
+
+// addDocEdits merges the candidates' edits into edit so they are written,
+// and rolled back, together with the code changes.
+func addDocEdits(edit *protocol.WorkspaceEdit, candidates []docCandidate) {
+	if len(candidates) == 0 {
+		return
+	}
+	if edit.Changes == nil {
+		edit.Changes = make(map[protocol.DocumentURI][]protocol.TextEdit)
+	}
+	for _, c := range candidates {
+		u := protocol.DocumentURI(docsync.FileToURI(c.File))
+		edit.Changes[u] = append(edit.Changes[u], c.edit)
+	}
+}
+
+// isDocFile reports whether path is one of the documentation files
+// updateDocs may edit; those are never synced with the language server.
+func isDocFile(path string) bool {
+	return docExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// identifierAt returns the identifier covering the 1-based (UTF-16)
+// column of line, or "" if there is none.
+func identifierAt(line string, col int) string {
+	off := edit.ByteOffset(line, uint32(col-1))
+	if off >= len(line) || !isIdentByte(line[off]) {
+		return ""
+	}
+	start, end := off, off
+	for start > 0 && isIdentByte(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isIdentByte(line[end]) {
+		end++
+	}
+	return line[start:end]
+}
@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestFindWordOccurrences(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		word string
+		want []int
+	}{
+		{"plain", "call formatDate here", "formatDate", []int{5}},
+		{"code span", "Use `formatDate()` to format.", "formatDate", []int{5}},
+		{"prefix of longer word", "formatDateTime", "formatDate", nil},
+		{"suffix of longer word", "reformatDate", "formatDate", nil},
+		{"underscore and dollar are word chars", "_formatDate $formatDate formatDate_", "formatDate", nil},
+		{"dotted access", "utils.formatDate(x)", "formatDate", []int{6}},
+		{"json key and value", `{"formatDate": "formatDate"}`, "formatDate", []int{2, 16}},
+		{"multiple", "formatDate, formatDate", "formatDate", []int{0, 12}},
+		{"inside url", "see https://example.com/docs/formatDate#formatDate", "formatDate", nil},
+		{"inside www url", "www.example.com/formatDate", "formatDate", nil},
+		{"after url", "[formatDate](https://example.com/formatDate) formatDate", "formatDate", []int{1, 45}},
+		{"empty name", "anything", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findWordOccurrences(tt.line, tt.word); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findWordOccurrences(%q, %q) = %v, want %v", tt.line, tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocCandidatesUTF16Columns(t *testing.T) {
+	got := docCandidatesInContent("/p/README.md", "# Title\r\n😀 — formatDate\n", "formatDate", "fmtDate")
+	if len(got) != 1 {
+		t.Fatalf("got %d candidates, want 1", len(got))
+	}
+	c := got[0]
+	// 😀 is two UTF-16 units, the dash and spaces one each.
+	if c.Line != 2 || c.Column != 6 || c.Preview != "😀 — formatDate" {
+		t.Errorf("candidate = %+v", c)
+	}
+	want := protocol.Range{
+		Start: protocol.Position{Line: 1, Character: 5},
+		End:   protocol.Position{Line: 1, Character: 15},
+	}
+	if c.edit.Range != want || c.edit.NewText != "fmtDate" {
+		t.Errorf("edit = %+v", c.edit)
+	}
+}
+
+func TestIdentifierAt(t *testing.T) {
+	line := "export function formatDate(d: Date) {}"
+	for col, want := range map[int]string{17: "formatDate", 22: "formatDate", 26: "formatDate", 16: "", 27: "", 1: "export", 100: ""} {
+		if got := identifierAt(line, col); got != want {
+			t.Errorf("identifierAt(col %d) = %q, want %q", col, got, want)
+		}
+	}
+}
+
+func TestUpdateDocsMode(t *testing.T) {
+	tests := []struct {
+		arg     any
+		want    string
+		wantErr bool
+	}{
+		{nil, docsModeOff, false},
+		{false, docsModeOff, false},
+		{true, docsModeList, false},
+		{"list", docsModeList, false},
+		{"true", docsModeList, false},
+		{"apply", docsModeApply, false},
+		{"everything", "", true},
+		{3, "", true},
+	}
+	for _, tt := range tests {
+		var req mcp.CallToolRequest
+		req.Params.Arguments = map[string]any{}
+		if tt.arg != nil {
+			req.Params.Arguments = map[string]any{"updateDocs": tt.arg}
+		}
+		got, err := updateDocsMode(req)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("updateDocsMode(%v) = %q, %v; want %q, err=%v", tt.arg, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// docsFixture lays out a project where the renamed symbol is the common
+// English word "format".
+func docsFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"src/format.ts":            "export function format(d: Date) {}\n",
+		"README.md":                "Call `format(date)` to render.\n\nThe output format is ISO 8601.\n",
+		"docs/api.mdx":             "## format\nSee https://example.com/api/format for details.\n",
+		"schema.json":              `{"$ref": "#/definitions/format"}` + "\n",
+		"config.yml":               "formatter: format\n",
+		"package-lock.json":        `{"format": "1.0.0"}`,
+		"node_modules/x/README.md": "format\n",
+		"src/notes.txt":            "format\n",
+		".git/COMMIT_EDITMSG":      "format\n",
+		"docs/formatting-guide.md": "Formatting and reformat are different words.\n",
+	}
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestFindDocCandidates(t *testing.T) {
+	root := docsFixture(t)
+	got := findDocCandidates(root, "format", "formatDate")
+
+	type hit struct {
+		file string
+		line int
+	}
+	var hits []hit
+	for _, c := range got {
+		rel, _ := filepath.Rel(root, c.File)
+		hits = append(hits, hit{filepath.ToSlash(rel), c.Line})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].file != hits[j].file {
+			return hits[i].file < hits[j].file
+		}
+		return hits[i].line < hits[j].line
+	})
+	want := []hit{
+		{"README.md", 1},
+		// Prose use of the common word: a false positive, which is why
+		// updateDocs lists by default instead of applying.
+		{"README.md", 3},
+		{"config.yml", 1},
+		{"docs/api.mdx", 1},
+		{"schema.json", 1},
+	}
+	if !reflect.DeepEqual(hits, want) {
+		t.Errorf("hits = %v, want %v", hits, want)
+	}
+}
+
+func TestRenameDocsListVersusApply(t *testing.T) {
+	root := docsFixture(t)
+	readme := filepath.Join(root, "README.md")
+	code := filepath.Join(root, "src", "format.ts")
+	codeEdit := func() *lsp.WorkspaceEdit {
+		return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			protocol.DocumentURI(docsync.FileToURI(code)): {{
+				Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 16}, End: protocol.Position{Line: 0, Character: 22}},
+				NewText: "formatDate",
+			}},
+		}}}
+	}
+	original, _ := os.ReadFile(readme)
+
+	// list: candidates are reported, docs are untouched.
+	candidates := findDocCandidates(root, "format", "formatDate")
+	changes, err := applyWorkspaceEdit(codeEdit(), nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Errorf("list mode changed %d files, want only the code file", len(changes))
+	}
+	if now, _ := os.ReadFile(readme); string(now) != string(original) {
+		t.Error("list mode must not modify docs")
+	}
+
+	// apply: doc edits are written in the same workspace edit as the code.
+	if err := os.WriteFile(code, []byte("export function format(d: Date) {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	edit := codeEdit()
+	addDocEdits(&edit.WorkspaceEdit, candidates)
+	changes, err = applyWorkspaceEdit(edit, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 5 {
+		t.Errorf("apply mode changed %d files, want 5", len(changes))
+	}
+	want := "Call `formatDate(date)` to render.\n\nThe output formatDate is ISO 8601.\n"
+	if now, _ := os.ReadFile(readme); string(now) != want {
+		t.Errorf("README = %q, want %q", now, want)
+	}
+	mdx, _ := os.ReadFile(filepath.Join(root, "docs", "api.mdx"))
+	if string(mdx) != "## formatDate\nSee https://example.com/api/format for details.\n" {
+		t.Errorf("api.mdx = %q (URL must be preserved)", mdx)
+	}
+}
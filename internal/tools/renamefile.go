@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+type renameFileResult struct {
+	OldPath    string `json:"oldPath"`
+	NewPath    string `json:"newPath"`
+	TotalEdits int    `json:"totalEdits"`
+	// Changes are the files whose imports were rewritten, the moved file
+	// under its new path.
+	Changes []editInfo `json:"changes"`
+}
+
+// withMove returns we with the move of oldPath to newPath added as a
+// rename operation after all its text edits, which tsgo computes against
+// the files before the move. we is left as it is.
+func withMove(we *lsp.WorkspaceEdit, oldPath, newPath string) *lsp.WorkspaceEdit {
+	out := *we
+	out.Operations = append(slices.Clone(we.Operations), lsp.ResourceOperation{
+		Kind:   protocol.RenameResourceOperation,
+		URI:    protocol.DocumentURI(docsync.FileToURI(oldPath)),
+		NewURI: protocol.DocumentURI(docsync.FileToURI(newPath)),
+		Index:  len(we.DocumentChanges),
+	})
+	return &out
+}
+
+// checkRenameFile validates the paths of a file move.
+func checkRenameFile(oldPath, newPath string) error {
+	if oldPath == newPath {
+		return fmt.Errorf("oldPath and newPath are the same file")
+	}
+	fi, err := os.Stat(oldPath)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%s is a directory; only files can be moved", oldPath)
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("%s already exists", newPath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func makeRenameFileHandler(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, packages *workspace.PackageResolver, overlayCheck bool, journal *journalPolicy, recorder *editRecorder) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		oldPath, err := request.RequireString("oldPath")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		newPath, err := request.RequireString("newPath")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		oldPath, newPath = filepath.Clean(oldPath), filepath.Clean(newPath)
+		if err := checkRenameFile(oldPath, newPath); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		// The moved file must be open so tsgo has loaded its project.
+		release := docs.Pin(oldPath)
+		defer release()
+		if err := docs.SyncFile(ctx, client.Conn(), oldPath); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		edit, err := client.WillRenameFiles(ctx, oldPath, newPath)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("import updates for moved files are unavailable: %v", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("will rename files error: %v", err)), nil
+		}
+		if edit == nil {
+			edit = &lsp.WorkspaceEdit{}
+		}
+		if path, pkg := installedPackageEdit(edit, packages); pkg != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("refusing to move %s: updating its imports edits the installed package %s (%s)", oldPath, pkg, pkg.DisplayPath(path))), nil
+		}
+		// The move goes through the edit, so that it is journaled,
+		// recorded and undone with the imports.
+		var gate editGate
+		if overlayCheck {
+			gate = overlayGate(ctx, client, docs)
+		}
+		changes, err := applyWorkspaceEdit(withMove(edit, oldPath, newPath), docs, gate, journal, recorder.recording(editOrigin{tool: request.Params.Name}))
+		if err != nil {
+			resyncStale(ctx, client, docs, err)
+			return mcp.NewToolResultError(fmt.Sprintf("apply error: %v; the file was not moved", err)), nil
+		}
+
+		if err := client.DidRenameFiles(ctx, oldPath, newPath); err != nil {
+			slog.Warn("notifying tsgo of a moved file", "oldPath", oldPath, "newPath", newPath, "error", err)
+		}
+		// Re-syncing closes the moved file's old path.
+		release()
+
+		paths := sortedChangePaths(changes)
+		pending.InvalidateFiles(append([]string{oldPath}, paths...))
+
+		// Re-sync all modified files so the LSP server sees the new content.
+		if err := resyncChanged(ctx, client, docs, changes); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		ClearFileCache()
+
+		result := renameFileResult{OldPath: oldPath, NewPath: newPath, Changes: []editInfo{}}
+		for _, p := range paths {
+			if changes[p].Edits == 0 {
+				// The move itself, without import updates.
+				continue
+			}
+			result.TotalEdits += changes[p].Edits
+			result.Changes = append(result.Changes, changes[p])
+		}
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestWithMove(t *testing.T) {
+	uriOf := func(p string) protocol.DocumentURI { return protocol.DocumentURI(docsync.FileToURI(p)) }
+	we := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{
+		DocumentChanges: []protocol.TextDocumentEdit{
+			{TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uriOf("/p/src/index.ts")}}},
+		},
+	}}
+	got := withMove(we, "/p/src/index.ts", "/p/src/lib/index.ts")
+	want := lsp.ResourceOperation{Kind: protocol.RenameResourceOperation, URI: uriOf("/p/src/index.ts"), NewURI: uriOf("/p/src/lib/index.ts"), Index: 1}
+	if len(got.Operations) != 1 || got.Operations[0] != want {
+		t.Errorf("operations = %+v, want the move after the text edits", got.Operations)
+	}
+	if len(we.Operations) != 0 {
+		t.Error("withMove modified its argument")
+	}
+}
+
+// TestRenameFileUndo moves a file with the import update tsgo computes and
+// undoes it: the file goes back with the import.
+func TestRenameFileUndo(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "util.ts")
+	newPath := filepath.Join(dir, "lib", "deep", "util.ts")
+	importer := filepath.Join(dir, "main.ts")
+	writeString(t, oldPath, "export const a = 1;\n")
+	writeString(t, importer, "import { a } from \"./util\";\n")
+	we := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+		protocol.DocumentURI(docsync.FileToURI(importer)): {{
+			Range:   protocol.Range{Start: protocol.Position{Character: 19}, End: protocol.Position{Character: 25}},
+			NewText: "./lib/deep/util",
+		}},
+	}}}
+	r := testRecorder(t.TempDir(), 0, recordStart)
+
+	changes, err := applyWorkspaceEdit(withMove(we, oldPath, newPath), nil, nil, nil, r.recording(editOrigin{tool: "ts_rename_file"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := changes[newPath]; info.RenamedFrom != oldPath {
+		t.Errorf("change of %s = %+v, want it moved from %s", newPath, info, oldPath)
+	}
+	if got := fileContents(t, []string{newPath, importer}); got[0] != "export const a = 1;\n" || got[1] != "import { a } from \"./lib/deep/util\";\n" {
+		t.Errorf("after the move = %q", got)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("old path still exists: %v", err)
+	}
+
+	if _, _, err := r.undoLast("ts_undo_last_edit"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fileContents(t, []string{oldPath, importer}); got[0] != "export const a = 1;\n" || got[1] != "import { a } from \"./util\";\n" {
+		t.Errorf("after the undo = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lib")); !os.IsNotExist(err) {
+		t.Errorf("the move's directories were not removed: %v", err)
+	}
+}
+
+func TestCheckRenameFile(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.ts"), filepath.Join(dir, "b.ts")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, paths := range map[string][2]string{
+		"same file":   {a, a},
+		"existing":    {a, b},
+		"directory":   {dir, filepath.Join(t.TempDir(), "moved")},
+		"missing old": {filepath.Join(dir, "c.ts"), filepath.Join(dir, "d.ts")},
+	} {
+		if err := checkRenameFile(paths[0], paths[1]); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
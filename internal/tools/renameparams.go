@@ -0,0 +1,400 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/jslex"
+)
+
+// jsdocTagEdit is a JSDoc tag naming a renamed parameter, rewritten along
+// with the code.
+type jsdocTagEdit struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Tag    string `json:"tag"`
+
+	edit protocol.TextEdit
+}
+
+// paramTags are the JSDoc tags whose name can be a parameter or, for
+// @property, a property of a destructured parameter documented as
+// "@property name.key" or on its own.
+var paramTags = map[string]bool{
+	"param":    true,
+	"arg":      true,
+	"argument": true,
+	"property": true,
+	"prop":     true,
+}
+
+// functionKinds are the symbol kinds that take parameters directly;
+// variables and properties count only when initialized with a function.
+var functionKinds = map[protocol.SymbolKind]bool{
+	protocol.SymbolKindFunction:    true,
+	protocol.SymbolKindMethod:      true,
+	protocol.SymbolKindConstructor: true,
+}
+
+var functionValuedKinds = map[protocol.SymbolKind]bool{
+	protocol.SymbolKindVariable: true,
+	protocol.SymbolKindConstant: true,
+	protocol.SymbolKindProperty: true,
+	protocol.SymbolKindField:    true,
+}
+
+// symbolBackend is the subset of *lsp.Client used to find the function
+// enclosing a rename.
+type symbolBackend interface {
+	DocumentSymbol(ctx context.Context, file string) ([]protocol.DocumentSymbol, error)
+}
+
+// addParamTagEdits adds to edit the JSDoc tag edits that go with renaming
+// a parameter of a function in file, whose content before the rename is
+// content, and returns them. Renames of anything but a parameter are left
+// alone.
+func addParamTagEdits(ctx context.Context, backend symbolBackend, edit *protocol.WorkspaceEdit, file string, content []byte, oldName, newName string) []jsdocTagEdit {
+	fileURI := protocol.DocumentURI(docsync.FileToURI(file))
+	var edits []protocol.TextEdit
+	for u, es := range edit.Changes {
+		if u != fileURI {
+			return nil // a parameter is only referenced in its own file
+		}
+		edits = append(edits, es...)
+	}
+	for _, dc := range edit.DocumentChanges {
+		if dc.TextDocument.URI != fileURI {
+			return nil
+		}
+		edits = append(edits, dc.Edits...)
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	symbols, err := backend.DocumentSymbol(ctx, file)
+	if err != nil {
+		return nil
+	}
+	tagEdits := paramTagEdits(string(content), symbols, edits, oldName, newName)
+	if len(tagEdits) == 0 {
+		return nil
+	}
+	if edit.Changes == nil {
+		edit.Changes = make(map[protocol.DocumentURI][]protocol.TextEdit)
+	}
+	for i := range tagEdits {
+		tagEdits[i].File = file
+		edit.Changes[fileURI] = append(edit.Changes[fileURI], tagEdits[i].edit)
+	}
+	return tagEdits
+}
+
+// paramTagEdits returns edits renaming oldName in the @param, @arg and
+// @property tags of the JSDoc block of the function whose parameter a
+// rename's edits rename. That is the case when the innermost symbol
+// containing every edit, counting its JSDoc block, is a function and the
+// first edit in its code, the declaration, lies in its parameter list.
+// Tags the rename already covers are skipped.
+func paramTagEdits(content string, symbols []protocol.DocumentSymbol, edits []protocol.TextEdit, oldName, newName string) []jsdocTagEdit {
+	lines := edit.SplitLines([]byte(content))
+	offset := func(p protocol.Position) int {
+		if int(p.Line) >= len(lines) {
+			return len(content)
+		}
+		return edit.LineOffset(lines, int(p.Line)) + edit.ByteOffset(lines[p.Line], p.Character)
+	}
+	// extent is the span of a symbol with its JSDoc block.
+	extent := func(s protocol.DocumentSymbol) (int, int) {
+		start := offset(s.Range.Start)
+		if doc, _, ok := jsdocBefore(content, start); ok {
+			start = doc
+		}
+		return start, offset(s.Range.End)
+	}
+	contains := func(s protocol.DocumentSymbol) bool {
+		start, end := extent(s)
+		for _, e := range edits {
+			if offset(e.Range.Start) < start || offset(e.Range.End) > end {
+				return false
+			}
+		}
+		return true
+	}
+
+	fn, ok := innermostSymbol(symbols, contains)
+	if !ok || (!functionKinds[fn.Kind] && !functionValuedKinds[fn.Kind]) {
+		return nil
+	}
+	open, close, ok := paramListSpan(content, offset(fn.SelectionRange.End), fn.Kind)
+	if !ok {
+		return nil
+	}
+	decl := -1
+	for _, e := range edits {
+		if d := offset(e.Range.Start); d > open && (decl < 0 || d < decl) {
+			decl = d
+		}
+	}
+	if decl < 0 || decl >= close {
+		return nil
+	}
+
+	start, end, ok := jsdocBefore(content, offset(fn.Range.Start))
+	if !ok {
+		return nil
+	}
+	var out []jsdocTagEdit
+	for _, t := range jsdocParamTags(content[start:end], oldName) {
+		pos := offsetPosition(lines, start+t.offset)
+		if editStartsAt(edits, pos) {
+			continue
+		}
+		out = append(out, jsdocTagEdit{
+			Line:   int(pos.Line) + 1,
+			Column: int(pos.Character) + 1,
+			Tag:    "@" + t.tag,
+			edit: protocol.TextEdit{
+				Range: protocol.Range{
+					Start: pos,
+					End:   protocol.Position{Line: pos.Line, Character: pos.Character + uint32(utf16Len(oldName))},
+				},
+				NewText: newName,
+			},
+		})
+	}
+	return out
+}
+
+// innermostSymbol returns the most deeply nested symbol for which contains
+// holds, given that it holds for every ancestor.
+func innermostSymbol(symbols []protocol.DocumentSymbol, contains func(protocol.DocumentSymbol) bool) (protocol.DocumentSymbol, bool) {
+	for _, s := range symbols {
+		if contains(s) {
+			if inner, ok := innermostSymbol(s.Children, contains); ok {
+				return inner, true
+			}
+			return s, true
+		}
+	}
+	return protocol.DocumentSymbol{}, false
+}
+
+func editStartsAt(edits []protocol.TextEdit, pos protocol.Position) bool {
+	for _, e := range edits {
+		if e.Range.Start == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// offsetPosition converts a byte offset of the content split into lines
+// to an LSP position.
+func offsetPosition(lines []string, off int) protocol.Position {
+	line := 0
+	for line < len(lines)-1 && off >= len(lines[line]) {
+		off -= len(lines[line])
+		line++
+	}
+	return protocol.Position{Line: uint32(line), Character: uint32(utf16Len(lines[line][:off]))}
+}
+
+// paramListSpan returns the offsets of the parentheses around the
+// parameter list of a function declared at after, the end of its name. For
+// variables and properties, the initializer must be a function or arrow
+// function; a lone arrow parameter ("x => ...") spans from just before the
+// identifier to the arrow.
+func paramListSpan(content string, after int, kind protocol.SymbolKind) (open, close int, ok bool) {
+	i := skipSpace(content, after)
+	if functionValuedKinds[kind] {
+		// Skip a type annotation up to the "=" of the initializer.
+		for ; i < len(content); i++ {
+			c := content[i]
+			if c == '\n' || c == ';' || c == '{' {
+				return 0, 0, false
+			}
+			if c == '=' && !strings.HasPrefix(content[i:], "=>") && !strings.HasPrefix(content[i:], "==") {
+				break
+			}
+			if c == '=' {
+				i++ // the arrow of a function type
+			}
+		}
+		if i >= len(content) {
+			return 0, 0, false
+		}
+		i = skipSpace(content, i+1)
+		if rest, ok := cutKeyword(content[i:], "async"); ok {
+			i = skipSpace(content, len(content)-len(rest))
+		}
+		if rest, ok := cutKeyword(content[i:], "function"); ok {
+			i = skipSpace(content, len(content)-len(rest))
+			i = skipSpace(content, i+identLen(content[i:]))
+		} else if n := identLen(content[i:]); n > 0 {
+			if j := skipSpace(content, i+n); strings.HasPrefix(content[j:], "=>") {
+				return i - 1, j, true
+			}
+			return 0, 0, false
+		}
+	}
+	if i < len(content) && content[i] == '<' {
+		i = skipAngles(content, i)
+		i = skipSpace(content, i)
+	}
+	if i >= len(content) || content[i] != '(' {
+		return 0, 0, false
+	}
+	close = matchParen(content, i)
+	if close < 0 {
+		return 0, 0, false
+	}
+	return i, close, true
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// skipAngles returns the index after the ">" closing the type parameters
+// opened at i.
+func skipAngles(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "=>"):
+			i++
+		case s[i] == '<':
+			depth++
+		case s[i] == '>':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// matchParen returns the index of the ")" closing the "(" at i, skipping
+// strings, template literal text and comments, or -1.
+func matchParen(s string, i int) int {
+	var lex jslex.Scanner
+	depth := 0
+	for i < len(s) {
+		kind, next := lex.Next(s, i)
+		if kind == jslex.Code {
+			switch s[i] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return i
+				}
+			}
+		}
+		i = next
+	}
+	return -1
+}
+
+// jsdocBefore returns the span of the JSDoc block ("/** ... */") attached
+// to the declaration starting at decl: only keywords may precede decl on
+// its line, and only blank lines and decorators separate the block from
+// that line.
+func jsdocBefore(content string, decl int) (start, end int, ok bool) {
+	lineStart := strings.LastIndexByte(content[:decl], '\n') + 1
+	for _, c := range []byte(content[lineStart:decl]) {
+		if !isIdentByte(c) && c != ' ' && c != '\t' {
+			return 0, 0, false
+		}
+	}
+	before := content[:lineStart]
+	for {
+		before = strings.TrimRight(before, " \t\r\n")
+		prev := before[strings.LastIndexByte(before, '\n')+1:]
+		if !strings.HasPrefix(strings.TrimSpace(prev), "@") {
+			break
+		}
+		before = before[:len(before)-len(prev)]
+	}
+	if !strings.HasSuffix(before, "*/") {
+		return 0, 0, false
+	}
+	start = strings.LastIndex(before, "/*")
+	if start < 0 || !strings.HasPrefix(before[start:], "/**") {
+		return 0, 0, false
+	}
+	return start, len(before), true
+}
+
+// jsdocTagName is a tag whose name is the renamed identifier, at offset in
+// the comment.
+type jsdocTagName struct {
+	tag    string
+	offset int
+}
+
+// jsdocParamTags finds the parameter tags of comment naming name, as in
+// "@param {T} name", "@param [name=1]" or "@property name.key". Only the
+// name itself is reported, so the type, description and hyphens stay as
+// they are.
+func jsdocParamTags(comment, name string) []jsdocTagName {
+	var out []jsdocTagName
+	for i := 0; i < len(comment); i++ {
+		if comment[i] != '@' || (i > 0 && isIdentByte(comment[i-1])) {
+			continue
+		}
+		n := identLen(comment[i+1:])
+		tag := comment[i+1 : i+1+n]
+		if !paramTags[tag] {
+			continue
+		}
+		j := skipInlineSpace(comment, i+1+n)
+		if j < len(comment) && comment[j] == '{' {
+			j = skipBraces(comment, j)
+			j = skipInlineSpace(comment, j)
+		}
+		if j < len(comment) && comment[j] == '[' {
+			j = skipInlineSpace(comment, j+1)
+		}
+		if identLen(comment[j:]) == len(name) && strings.HasPrefix(comment[j:], name) {
+			out = append(out, jsdocTagName{tag: tag, offset: j})
+		}
+		i = j
+	}
+	return out
+}
+
+func skipInlineSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// skipBraces returns the index after the "}" closing the "{" at i, or the
+// end of the line when it is not closed there.
+func skipBraces(s string, i int) int {
+	depth := 0
+	for ; i < len(s) && s[i] != '\n'; i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
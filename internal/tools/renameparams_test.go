@@ -0,0 +1,263 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+)
+
+// markedEdits strips the «» markers from src and returns the rename edits
+// they mark, each replacing the marked old name with newName.
+func markedEdits(src, newName string) (string, []protocol.TextEdit) {
+	var b strings.Builder
+	var offsets [][2]int
+	for {
+		i := strings.Index(src, "«")
+		if i < 0 {
+			b.WriteString(src)
+			break
+		}
+		j := strings.Index(src, "»")
+		b.WriteString(src[:i])
+		start := b.Len()
+		b.WriteString(src[i+len("«") : j])
+		offsets = append(offsets, [2]int{start, b.Len()})
+		src = src[j+len("»"):]
+	}
+	content := b.String()
+	lines := edit.SplitLines([]byte(content))
+	edits := make([]protocol.TextEdit, len(offsets))
+	for i, o := range offsets {
+		edits[i] = protocol.TextEdit{
+			Range:   protocol.Range{Start: offsetPosition(lines, o[0]), End: offsetPosition(lines, o[1])},
+			NewText: newName,
+		}
+	}
+	return content, edits
+}
+
+// declSymbol returns the symbol declared from the first occurrence of decl
+// in content up to the end of the following end, named name.
+func declSymbol(content, decl, end, name string, kind protocol.SymbolKind, children ...protocol.DocumentSymbol) protocol.DocumentSymbol {
+	lines := edit.SplitLines([]byte(content))
+	start := strings.Index(content, decl)
+	stop := start + strings.Index(content[start:], end) + len(end)
+	nameAt := start + strings.Index(content[start:], name)
+	return protocol.DocumentSymbol{
+		Name:  name,
+		Kind:  kind,
+		Range: protocol.Range{Start: offsetPosition(lines, start), End: offsetPosition(lines, stop)},
+		SelectionRange: protocol.Range{
+			Start: offsetPosition(lines, nameAt),
+			End:   offsetPosition(lines, nameAt+len(name)),
+		},
+		Children: children,
+	}
+}
+
+func TestParamTagEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		symbols func(content string) []protocol.DocumentSymbol
+		old     string
+		want    string // content after the rename, tags included
+		tags    []string
+	}{
+		{
+			name: "documented function parameter",
+			src: `/**
+ * Greets someone.
+ * @param {string} name - the name to greet
+ * @param {number} [times=1] how often
+ */
+export function greet(«name»: string, times = 1): string {
+  return «name».repeat(times);
+}
+`,
+			symbols: func(c string) []protocol.DocumentSymbol {
+				return []protocol.DocumentSymbol{declSymbol(c, "export function", "\n}", "greet", protocol.SymbolKindFunction)}
+			},
+			old: "name",
+			want: `/**
+ * Greets someone.
+ * @param {string} who - the name to greet
+ * @param {number} [times=1] how often
+ */
+export function greet(who: string, times = 1): string {
+  return who.repeat(times);
+}
+`,
+			tags: []string{"@param"},
+		},
+		{
+			name: "arrow function with property tags",
+			src: `/**
+ * @param {{verbose: boolean}} opts options
+ * @property {boolean} opts.verbose - log more
+ * @arg opts again
+ */
+export const run = async («opts») => «opts».verbose;
+`,
+			symbols: func(c string) []protocol.DocumentSymbol {
+				return []protocol.DocumentSymbol{declSymbol(c, "run =", ";", "run", protocol.SymbolKindVariable)}
+			},
+			old: "opts",
+			want: `/**
+ * @param {{verbose: boolean}} who options
+ * @property {boolean} who.verbose - log more
+ * @arg who again
+ */
+export const run = async (who) => who.verbose;
+`,
+			tags: []string{"@param", "@property", "@arg"},
+		},
+		{
+			name: "single arrow parameter",
+			src: `/** @param {number} x the value */
+const double = «x» => «x» * 2;
+`,
+			symbols: func(c string) []protocol.DocumentSymbol {
+				return []protocol.DocumentSymbol{declSymbol(c, "double", ";", "double", protocol.SymbolKindConstant)}
+			},
+			old: "x",
+			want: `/** @param {number} who the value */
+const double = who => who * 2;
+`,
+			tags: []string{"@param"},
+		},
+		{
+			name: "method with decorator, tag already renamed",
+			src: `class Greeter {
+  /**
+   * @param «name» the name
+   * @param [names] others
+   */
+  @logged
+  hello(«name»: string, names?: string[]) {
+    return «name»;
+  }
+}
+`,
+			symbols: func(c string) []protocol.DocumentSymbol {
+				return []protocol.DocumentSymbol{declSymbol(c, "class", "\n}", "Greeter", protocol.SymbolKindClass,
+					declSymbol(c, "hello", "\n  }", "hello", protocol.SymbolKindMethod))}
+			},
+			old: "name",
+			want: `class Greeter {
+  /**
+   * @param who the name
+   * @param [names] others
+   */
+  @logged
+  hello(who: string, names?: string[]) {
+    return who;
+  }
+}
+`,
+		},
+		{
+			name: "local variable",
+			src: `/**
+ * @param value the input
+ */
+function f(value: number) {
+  const «total» = value + 1;
+  return «total»;
+}
+`,
+			symbols: func(c string) []protocol.DocumentSymbol {
+				return []protocol.DocumentSymbol{declSymbol(c, "function", "\n}", "f", protocol.SymbolKindFunction)}
+			},
+			old: "total",
+			want: `/**
+ * @param value the input
+ */
+function f(value: number) {
+  const who = value + 1;
+  return who;
+}
+`,
+		},
+		{
+			name: "class member",
+			src: `/**
+ * @property count the count
+ */
+class Counter {
+  «count» = 0;
+  /** @param count unused */
+  bump(count: number) {
+    this.«count»++;
+  }
+}
+`,
+			symbols: func(c string) []protocol.DocumentSymbol {
+				return []protocol.DocumentSymbol{declSymbol(c, "class", "\n}", "Counter", protocol.SymbolKindClass,
+					declSymbol(c, "count =", ";", "count", protocol.SymbolKindProperty),
+					declSymbol(c, "bump", "\n  }", "bump", protocol.SymbolKindMethod))}
+			},
+			old: "count",
+			want: `/**
+ * @property count the count
+ */
+class Counter {
+  who = 0;
+  /** @param count unused */
+  bump(count: number) {
+    this.who++;
+  }
+}
+`,
+		},
+		{
+			name: "variable initialized with a call",
+			src: `/** @param x the value */
+const y = compute((«x») => «x»);
+`,
+			symbols: func(c string) []protocol.DocumentSymbol {
+				return []protocol.DocumentSymbol{declSymbol(c, "y =", ";", "y", protocol.SymbolKindConstant)}
+			},
+			old: "x",
+			want: `/** @param x the value */
+const y = compute((who) => who);
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, edits := markedEdits(tt.src, "who")
+			got := paramTagEdits(content, tt.symbols(content), edits, tt.old, "who")
+			var tags []string
+			for _, e := range got {
+				tags = append(tags, e.Tag)
+				edits = append(edits, e.edit)
+			}
+			if strings.Join(tags, " ") != strings.Join(tt.tags, " ") {
+				t.Errorf("tags = %v, want %v", tags, tt.tags)
+			}
+			updated, err := edit.ApplyTextEdits([]byte(content), edits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(updated) != tt.want {
+				t.Errorf("updated content:\n%s\nwant:\n%s", updated, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSDocParamTags(t *testing.T) {
+	comment := "/**\n * @param {Map<string, {a: number}>} name - x\n * @param names other\n * @param [name = 'n'] again\n * @returns {string} name\n * @see @param name\n * email@param name\n */"
+	var got []string
+	for _, tag := range jsdocParamTags(comment, "name") {
+		got = append(got, tag.tag+":"+comment[tag.offset:tag.offset+4])
+	}
+	want := []string{"param:name", "param:name", "param:name"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("tags = %v, want %v", got, want)
+	}
+}
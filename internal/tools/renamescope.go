@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// defaultRenameExcludeGlobs are the files ts_rename leaves out of its edit
+// unless excludeGlobs or the config file's renameExcludeGlobs replace
+// them: vendored packages, build output and generated declarations.
+var defaultRenameExcludeGlobs = []string{
+	"**/node_modules/**",
+	"**/dist/**",
+	"**/*.d.ts",
+}
+
+// editScope is the files an edit may write: those under onlyUnder, when
+// set, that match no exclude glob. Globs and onlyUnder are relative to the
+// project root, in tsconfig exclude syntax, and cover what they match and
+// everything below.
+type editScope struct {
+	onlyUnder   string
+	onlyUnderRE *regexp.Regexp
+	exclude     []string
+	excludeRE   []*regexp.Regexp
+}
+
+// newEditScope compiles onlyUnder and the exclude globs for root.
+func newEditScope(root, onlyUnder string, exclude []string) editScope {
+	root = filepath.Clean(root)
+	s := editScope{onlyUnder: onlyUnder, onlyUnderRE: tsconfig.ExcludePattern(root, onlyUnder)}
+	for _, glob := range exclude {
+		if re := tsconfig.ExcludePattern(root, glob); re != nil {
+			s.exclude = append(s.exclude, glob)
+			s.excludeRE = append(s.excludeRE, re)
+		}
+	}
+	return s
+}
+
+// skipReason returns why file is out of scope, or "" when it is in scope.
+func (s editScope) skipReason(file string) string {
+	p := filepath.ToSlash(filepath.Clean(file))
+	if s.onlyUnderRE != nil && !s.onlyUnderRE.MatchString(p) {
+		return "outside onlyUnder " + s.onlyUnder
+	}
+	for i, re := range s.excludeRE {
+		if re.MatchString(p) {
+			return "matches excludeGlobs " + s.exclude[i]
+		}
+	}
+	return ""
+}
+
+// drop removes the files out of scope from edit (see edit.Drop) and
+// returns them in sorted path order.
+func (s editScope) drop(we *lsp.WorkspaceEdit) []edit.Skipped {
+	return edit.Drop(we, s.skipReason)
+}
+
+// skippedWarning describes skipped files for a warning.
+func skippedWarning(skipped []edit.Skipped) string {
+	lines := make([]string, len(skipped))
+	for i, f := range skipped {
+		lines[i] = fmt.Sprintf("  %s (%s, %s)", f.File, f.Reason, plural(f.Edits, "edit"))
+	}
+	return fmt.Sprintf("%s left out of the edit:\n%s", plural(len(skipped), "file"), strings.Join(lines, "\n"))
+}
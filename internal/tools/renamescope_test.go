@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestEditScopeSkipReason(t *testing.T) {
+	root := filepath.FromSlash("/p")
+	tests := []struct {
+		name      string
+		onlyUnder string
+		exclude   []string
+		file      string
+		want      string
+	}{
+		{name: "source file", exclude: defaultRenameExcludeGlobs, file: "/p/src/a.ts"},
+		{name: "node_modules", exclude: defaultRenameExcludeGlobs, file: "/p/node_modules/lib/index.ts", want: "matches excludeGlobs **/node_modules/**"},
+		{name: "nested dist", exclude: defaultRenameExcludeGlobs, file: "/p/packages/core/dist/a.js", want: "matches excludeGlobs **/dist/**"},
+		{name: "declaration file", exclude: defaultRenameExcludeGlobs, file: "/p/src/types.d.ts", want: "matches excludeGlobs **/*.d.ts"},
+		{name: "distinct name", exclude: defaultRenameExcludeGlobs, file: "/p/src/distance.ts"},
+		{name: "no exclusions", file: "/p/dist/a.d.ts"},
+		{name: "under onlyUnder", onlyUnder: "packages/api", file: "/p/packages/api/src/a.ts"},
+		{name: "outside onlyUnder", onlyUnder: "packages/api", file: "/p/packages/web/a.ts", want: "outside onlyUnder packages/api"},
+		{name: "absolute onlyUnder", onlyUnder: "/p/packages/web", file: "/p/packages/web/a.ts"},
+		{name: "excluded under onlyUnder", onlyUnder: "packages", exclude: []string{"**/*.d.ts"}, file: "/p/packages/a.d.ts", want: "matches excludeGlobs **/*.d.ts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newEditScope(root, tt.onlyUnder, tt.exclude)
+			if got := s.skipReason(filepath.FromSlash(tt.file)); got != tt.want {
+				t.Errorf("skipReason(%s) = %q, want %q", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditScopeDrop(t *testing.T) {
+	dir := t.TempDir()
+	src, dist, decl, moved := filepath.Join(dir, "src", "a.ts"), filepath.Join(dir, "dist", "a.js"), filepath.Join(dir, "src", "a.d.ts"), filepath.Join(dir, "dist", "b.js")
+	rename := textEdit(0, 13, 0, 16, "bar")
+	we := &lsp.WorkspaceEdit{
+		WorkspaceEdit: protocol.WorkspaceEdit{
+			Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				fileURI(src):  {rename},
+				fileURI(dist): {rename, rename},
+			},
+			DocumentChanges: []protocol.TextDocumentEdit{
+				docEdit(decl, rename),
+				docEdit(src, rename),
+			},
+		},
+		Operations: []lsp.ResourceOperation{
+			{Kind: protocol.RenameResourceOperation, URI: fileURI(dist), NewURI: fileURI(moved), Index: 1},
+			{Kind: protocol.CreateResourceOperation, URI: fileURI(filepath.Join(dir, "src", "n.ts")), Index: 2},
+		},
+	}
+
+	skipped := newEditScope(dir, "", defaultRenameExcludeGlobs).drop(we)
+	want := []edit.Skipped{
+		{File: dist, Edits: 2, Reason: "matches excludeGlobs **/dist/**"},
+		{File: moved, Reason: "matches excludeGlobs **/dist/**"},
+		{File: decl, Edits: 1, Reason: "matches excludeGlobs **/*.d.ts"},
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %+v, want %+v", skipped, want)
+	}
+	if len(we.Changes) != 1 || we.Changes[fileURI(src)] == nil {
+		t.Errorf("changes = %v, want only src/a.ts", we.Changes)
+	}
+	if len(we.DocumentChanges) != 1 || we.DocumentChanges[0].TextDocument.URI != fileURI(src) {
+		t.Errorf("document changes = %+v, want only src/a.ts", we.DocumentChanges)
+	}
+	// The create followed both document changes and still does.
+	if len(we.Operations) != 1 || we.Operations[0].Kind != protocol.CreateResourceOperation || we.Operations[0].Index != 1 {
+		t.Errorf("operations = %+v, want the create at index 1", we.Operations)
+	}
+}
+
+func TestEditScopeDropNothing(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src", "a.ts")
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(src, renameOld)}}}
+	if skipped := newEditScope(dir, "src", defaultRenameExcludeGlobs).drop(edit); skipped != nil {
+		t.Errorf("skipped = %+v, want none", skipped)
+	}
+	if len(edit.DocumentChanges) != 1 {
+		t.Errorf("document changes = %+v, want them kept", edit.DocumentChanges)
+	}
+}
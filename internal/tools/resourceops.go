@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// fileRename is a file an edit moved.
+type fileRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// fileOps lists the files an edit created, moved and deleted, beside the
+// changes of the write tools' results.
+type fileOps struct {
+	Created []string     `json:"created,omitempty"`
+	Renamed []fileRename `json:"renamed,omitempty"`
+	Deleted []string     `json:"deleted,omitempty"`
+}
+
+// fileOpsOf collects the created, moved and deleted files of changes, in
+// sorted path order. The old path of a moved file is not listed as
+// deleted.
+func fileOpsOf(changes map[string]editInfo) fileOps {
+	var ops fileOps
+	moved := make(map[string]bool)
+	for _, p := range sortedChangePaths(changes) {
+		switch info := changes[p]; {
+		case info.RenamedFrom != "":
+			ops.Renamed = append(ops.Renamed, fileRename{From: info.RenamedFrom, To: p})
+			moved[info.RenamedFrom] = true
+		case info.Created:
+			ops.Created = append(ops.Created, p)
+		}
+	}
+	for _, p := range sortedChangePaths(changes) {
+		if changes[p].Deleted && !moved[p] {
+			ops.Deleted = append(ops.Deleted, p)
+		}
+	}
+	return ops
+}
+
+// editSync is an in-place edit of a file as written, for bringing tsgo up
+// to date without sending the whole file.
+type editSync struct {
+	// edits are the edits of the file with their line breaks converted
+	// as edit.ApplyTextEdits writes them.
+	edits []protocol.TextEdit
+	// before and after are the hashes of the content the edits apply to
+	// and of the content written.
+	before, after string
+}
+
+// newEditSync returns the editSync of f, a file edited in place.
+func newEditSync(f edit.File) *editSync {
+	return &editSync{edits: edit.NormalizeEdits(f.Original, f.Edits), before: hashContent(f.Original), after: hashContent(f.Updated)}
+}
+
+// resyncChanged brings tsgo up to date with the files an applied edit
+// changed: files it deleted, or moved away, are closed and the others
+// re-synced. A file edited in place whose content before the edit tsgo
+// has gets just the edits (see docsync.Manager.ApplyEdits); should tsgo's
+// copy then differ from what was written, the file is read again.
+// Documentation files are not opened in tsgo and are skipped.
+func resyncChanged(ctx context.Context, client *lsp.Client, docs *docsync.Manager, changes map[string]editInfo) error {
+	var gone []string
+	for _, p := range sortedChangePaths(changes) {
+		switch info := changes[p]; {
+		case isDocFile(p):
+		case info.Deleted:
+			gone = append(gone, p)
+		default:
+			if err := resyncEdited(ctx, client.Conn(), docs, p, info.sync); err != nil {
+				return fmt.Errorf("re-sync error for %s: %w", p, err)
+			}
+		}
+	}
+	if len(gone) > 0 {
+		if _, _, err := docs.CloseFiles(ctx, client.Conn(), gone); err != nil {
+			return fmt.Errorf("close error for %s: %w", strings.Join(gone, ", "), err)
+		}
+	}
+	return nil
+}
+
+// resyncEdited re-syncs a changed file, by its edits when sync is set and
+// tsgo has the content they apply to. A file that is not open is left
+// closed when the open document limit is reached: tsgo reads it from disk.
+func resyncEdited(ctx context.Context, conn jsonrpc2.Conn, docs *docsync.Manager, path string, sync *editSync) error {
+	if hash, open := docs.ContentHash(path); sync != nil && open && hash == sync.before {
+		if err := docs.ApplyEdits(ctx, conn, path, sync.edits); err != nil {
+			return err
+		}
+		if hash, _ := docs.ContentHash(path); hash == sync.after {
+			return nil
+		}
+	}
+	if err := docs.ResyncFile(ctx, conn, path); err != nil && !errors.Is(err, docsync.ErrOpenLimit) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,459 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// absent marks a file that must not exist in a test's expected contents.
+const absent = "<absent>"
+
+func fileURI(p string) protocol.DocumentURI {
+	return protocol.DocumentURI("file://" + p)
+}
+
+// docEdit is a DocumentChanges entry editing p.
+func docEdit(p string, edits ...protocol.TextEdit) protocol.TextDocumentEdit {
+	return protocol.TextDocumentEdit{
+		TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: fileURI(p)}},
+		Edits:        edits,
+	}
+}
+
+// renameOld renames old to renamed in oldContent.
+var renameOld = textEdit(0, 13, 0, 16, "renamed")
+
+// checkFiles compares the files under dir with want, keyed by name.
+func checkFiles(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		switch {
+		case content == absent && !errors.Is(err, os.ErrNotExist):
+			t.Errorf("%s exists: %q, %v", name, data, err)
+		case content != absent && (err != nil || string(data) != content):
+			t.Errorf("%s = %q, %v; want %q", name, data, err, content)
+		}
+	}
+}
+
+func TestApplyWorkspaceEditResourceOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		// edit builds the edit from the paths of a.ts and m.ts, which hold
+		// oldContent, and of the missing n.ts.
+		edit    func(a, m, n string) *lsp.WorkspaceEdit
+		want    map[string]string
+		wantOps fileOps
+		wantErr string
+	}{
+		{
+			name: "rename then edit the new path",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{
+					WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(n, renameOld)}},
+					Operations:    []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(n)}},
+				}
+			},
+			want:    map[string]string{"a.ts": absent, "n.ts": renamedContent, "m.ts": oldContent},
+			wantOps: fileOps{Renamed: []fileRename{{From: "a.ts", To: "n.ts"}}},
+		},
+		{
+			name: "edit then rename",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{
+					WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(a, renameOld)}},
+					Operations:    []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(n), Index: 1}},
+				}
+			},
+			want:    map[string]string{"a.ts": absent, "n.ts": renamedContent},
+			wantOps: fileOps{Renamed: []fileRename{{From: "a.ts", To: "n.ts"}}},
+		},
+		{
+			name: "rename onto an existing file",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{Operations: []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(m)}}}
+			},
+			wantErr: "the target already exists",
+		},
+		{
+			name: "rename onto an existing file, ignored",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{Operations: []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(m), IgnoreIfExists: true}}}
+			},
+			want: map[string]string{"a.ts": oldContent, "m.ts": oldContent},
+		},
+		{
+			name: "delete",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{
+					WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(m, renameOld)}},
+					Operations:    []lsp.ResourceOperation{{Kind: protocol.DeleteResourceOperation, URI: fileURI(a)}},
+				}
+			},
+			want:    map[string]string{"a.ts": absent, "m.ts": renamedContent},
+			wantOps: fileOps{Deleted: []string{"a.ts"}},
+		},
+		{
+			name: "delete a missing file",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{Operations: []lsp.ResourceOperation{{Kind: protocol.DeleteResourceOperation, URI: fileURI(n)}}}
+			},
+			wantErr: "it does not exist",
+		},
+		{
+			name: "delete a missing file, ignored",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{Operations: []lsp.ResourceOperation{{Kind: protocol.DeleteResourceOperation, URI: fileURI(n), IgnoreIfNotExists: true}}}
+			},
+			want: map[string]string{"n.ts": absent},
+		},
+		{
+			name: "delete a directory",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{Operations: []lsp.ResourceOperation{{Kind: protocol.DeleteResourceOperation, URI: fileURI(filepath.Dir(a)), Recursive: true}}}
+			},
+			wantErr: "deleting directories is not supported",
+		},
+		{
+			name: "create over an existing file",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{Operations: []lsp.ResourceOperation{{Kind: protocol.CreateResourceOperation, URI: fileURI(a)}}}
+			},
+			wantErr: "it already exists",
+		},
+		{
+			name: "create over an existing file, ignored",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{Operations: []lsp.ResourceOperation{{Kind: protocol.CreateResourceOperation, URI: fileURI(a), IgnoreIfExists: true}}}
+			},
+			want: map[string]string{"a.ts": oldContent},
+		},
+		{
+			name: "create over an existing file, overwritten",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{
+					WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(a, insertAtStart(createdContent))}},
+					Operations:    []lsp.ResourceOperation{{Kind: protocol.CreateResourceOperation, URI: fileURI(a), Overwrite: true}},
+				}
+			},
+			want: map[string]string{"a.ts": createdContent},
+		},
+		{
+			name: "unknown kind after other operations",
+			edit: func(a, m, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{
+					WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(m, renameOld)}},
+					Operations: []lsp.ResourceOperation{
+						{Kind: protocol.DeleteResourceOperation, URI: fileURI(a)},
+						{Kind: "chmod", URI: fileURI(m), Index: 1},
+					},
+				}
+			},
+			wantErr: "unsupported chmod operation",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			a, m, n := filepath.Join(dir, "a.ts"), filepath.Join(dir, "m.ts"), filepath.Join(dir, "n.ts")
+			writeString(t, a, oldContent)
+			writeString(t, m, oldContent)
+
+			changes, err := applyWorkspaceEdit(tt.edit(a, m, n), nil, nil, nil, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				checkFiles(t, dir, map[string]string{"a.ts": oldContent, "m.ts": oldContent, "n.ts": absent})
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkFiles(t, dir, tt.want)
+			ops := fileOpsOf(changes)
+			rel := func(p string) string { return strings.TrimPrefix(p, dir+string(filepath.Separator)) }
+			for i := range ops.Created {
+				ops.Created[i] = rel(ops.Created[i])
+			}
+			for i := range ops.Deleted {
+				ops.Deleted[i] = rel(ops.Deleted[i])
+			}
+			for i, r := range ops.Renamed {
+				ops.Renamed[i] = fileRename{From: rel(r.From), To: rel(r.To)}
+			}
+			if !reflect.DeepEqual(ops, tt.wantOps) {
+				t.Errorf("ops = %+v, want %+v", ops, tt.wantOps)
+			}
+		})
+	}
+}
+
+func TestFileOpsOf(t *testing.T) {
+	got := fileOpsOf(map[string]editInfo{
+		"/p/a.ts": {File: "/p/a.ts", Deleted: true},
+		"/p/b.ts": {File: "/p/b.ts", RenamedFrom: "/p/a.ts", Created: true},
+		"/p/c.ts": {File: "/p/c.ts", Created: true},
+		"/p/d.ts": {File: "/p/d.ts", Edits: 2},
+		"/p/e.ts": {File: "/p/e.ts", Deleted: true},
+	})
+	want := fileOps{
+		Created: []string{"/p/c.ts"},
+		Renamed: []fileRename{{From: "/p/a.ts", To: "/p/b.ts"}},
+		Deleted: []string{"/p/e.ts"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fileOpsOf = %+v, want %+v", got, want)
+	}
+}
+
+func TestResourceOperationRollback(t *testing.T) {
+	t.Cleanup(func() { beforeEditWrite = nil })
+	for _, journaled := range []bool{false, true} {
+		name := "direct"
+		if journaled {
+			name = "journaled"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			a, m, z := filepath.Join(dir, "a.ts"), filepath.Join(dir, "m.ts"), filepath.Join(dir, "z.ts")
+			writeString(t, a, oldContent)
+			writeString(t, m, oldContent)
+			// Sorted by path, a.ts is deleted before m.ts fails to write
+			// and z.ts would be created after.
+			edit := &lsp.WorkspaceEdit{
+				WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(m, renameOld)}},
+				Operations:    []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(z)}},
+			}
+			beforeEditWrite = func(path string) error {
+				if path == m {
+					return errors.New("disk full")
+				}
+				return nil
+			}
+			var journal *journalPolicy
+			if journaled {
+				journal = &journalPolicy{dir: t.TempDir(), always: true, chunkSize: 1}
+			}
+
+			if _, err := applyWorkspaceEdit(edit, nil, nil, journal, nil); err == nil || !strings.Contains(err.Error(), "disk full") {
+				t.Fatalf("error = %v, want the injected failure", err)
+			}
+			checkFiles(t, dir, map[string]string{"a.ts": oldContent, "m.ts": oldContent, "z.ts": absent})
+		})
+	}
+}
+
+func TestRecoverJournalDeletedFile(t *testing.T) {
+	for _, tt := range []struct {
+		action string
+		want   map[string]string
+	}{
+		{recoverComplete, map[string]string{"a.ts": absent, "m.ts": renamedContent}},
+		{recoverRollback, map[string]string{"a.ts": oldContent, "m.ts": oldContent}},
+	} {
+		t.Run(tt.action, func(t *testing.T) {
+			dir := t.TempDir()
+			a, m := filepath.Join(dir, "a.ts"), filepath.Join(dir, "m.ts")
+			writeString(t, a, oldContent)
+			writeString(t, m, oldContent)
+			edit := &lsp.WorkspaceEdit{
+				WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(m, renameOld)}},
+				Operations:    []lsp.ResourceOperation{{Kind: protocol.DeleteResourceOperation, URI: fileURI(a)}},
+			}
+			p := &journalPolicy{dir: t.TempDir(), always: true, chunkSize: 1}
+			p.afterChunk = func(int) error { return errors.New("simulated crash") }
+			if _, err := applyWorkspaceEdit(edit, nil, nil, p, nil); err == nil {
+				t.Fatal("expected an interruption")
+			}
+			checkFiles(t, dir, map[string]string{"a.ts": absent, "m.ts": oldContent})
+
+			journals, err := listPendingJournals(p.dir)
+			if err != nil || len(journals) != 1 {
+				t.Fatalf("pending journals = %+v, %v; want one", journals, err)
+			}
+			if _, err := recoverJournal(p.dir, journals[0].ID, tt.action); err != nil {
+				t.Fatal(err)
+			}
+			checkFiles(t, dir, tt.want)
+		})
+	}
+}
+
+func TestUndoResourceOperations(t *testing.T) {
+	dir := t.TempDir()
+	a, m, n := filepath.Join(dir, "a.ts"), filepath.Join(dir, "m.ts"), filepath.Join(dir, "lib", "n.ts")
+	writeString(t, a, oldContent)
+	writeString(t, m, "export {};\r\n")
+	if err := os.Chmod(m, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	edit := &lsp.WorkspaceEdit{
+		WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(n, renameOld)}},
+		Operations: []lsp.ResourceOperation{
+			{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(n)},
+			{Kind: protocol.DeleteResourceOperation, URI: fileURI(m)},
+		},
+	}
+	r := testRecorder(t.TempDir(), 0, recordStart)
+	if _, err := applyWorkspaceEdit(edit, nil, nil, nil, r.recording(editOrigin{tool: "ts_apply_code_action"})); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{"a.ts": absent, "m.ts": absent, "lib/n.ts": renamedContent})
+
+	if _, _, err := r.undoLast("ts_undo_last_edit"); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{"a.ts": oldContent, "m.ts": "export {};\r\n", "lib/n.ts": absent})
+	if fi, err := os.Stat(m); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("restored m.ts mode = %v, %v; want 0600", fi.Mode().Perm(), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lib")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("undo left the directory made for the moved file: %v", err)
+	}
+}
+
+func TestPreviewResourceOperations(t *testing.T) {
+	dir := t.TempDir()
+	a, n := filepath.Join(dir, "a.ts"), filepath.Join(dir, "n.ts")
+	writeString(t, a, oldContent)
+	edit := &lsp.WorkspaceEdit{
+		WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(n, renameOld)}},
+		Operations:    []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(n)}},
+	}
+	previews, hashes, err := previewWorkspaceEdit(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 2 {
+		t.Fatalf("previews = %+v, want a.ts and n.ts", previews)
+	}
+	if p := previews[0]; p.File != a || !p.Deleted {
+		t.Errorf("preview of a.ts = %+v, want it deleted", p)
+	}
+	if p := previews[1]; p.File != n || p.RenamedFrom != a || p.Created || !strings.Contains(p.Diff, "+export const renamed = 1;") {
+		t.Errorf("preview of n.ts = %+v, want it moved from a.ts and edited", p)
+	}
+	if hashes[a] != hashContent([]byte(oldContent)) || hashes[n] != "" {
+		t.Errorf("hashes = %v", hashes)
+	}
+	checkFiles(t, dir, map[string]string{"a.ts": oldContent, "n.ts": absent})
+}
+
+// didChangeConn records the content changes of every didChange.
+type didChangeConn struct {
+	jsonrpc2.Conn
+	changes [][]protocol.TextDocumentContentChangeEvent
+}
+
+func (c *didChangeConn) Notify(_ context.Context, _ string, params interface{}) error {
+	if p, ok := params.(*protocol.DidChangeTextDocumentParams); ok {
+		c.changes = append(c.changes, p.ContentChanges)
+	}
+	return nil
+}
+
+func TestResyncEdited(t *testing.T) {
+	ctx := context.Background()
+	crlf := "export const old = 1;\r\nold;\r\n"
+	// The new line the edit inserts is written, and sent, as CRLF.
+	edits := []protocol.TextEdit{textEdit(0, 13, 0, 16, "renamed"), textEdit(1, 0, 1, 3, "renamed;\nrenamed")}
+	want := "export const renamed = 1;\r\nrenamed;\r\nrenamed;\r\n"
+
+	tests := []struct {
+		name string
+		// stale is what tsgo has of the file instead of what was on disk.
+		stale    string
+		noSync   bool
+		wantFull bool
+	}{
+		{name: "edits"},
+		{name: "tsgo has other content", stale: "export const other = 1;\n", wantFull: true},
+		{name: "no edits to send", noSync: true, wantFull: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "a.ts")
+			writeString(t, p, crlf)
+			if tt.stale != "" {
+				writeString(t, p, tt.stale)
+			}
+			conn := &didChangeConn{}
+			docs := docsync.NewManager()
+			docs.SetIncremental(func() bool { return true })
+			if err := docs.SyncFile(ctx, conn, p); err != nil {
+				t.Fatal(err)
+			}
+			writeString(t, p, crlf)
+
+			changes, err := applyWorkspaceEdit(&lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{docEdit(p, edits...)}}}, nil, nil, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sync := changes[p].sync
+			if tt.noSync {
+				sync = nil
+			}
+			if err := resyncEdited(ctx, conn, docs, p, sync); err != nil {
+				t.Fatal(err)
+			}
+			checkFiles(t, filepath.Dir(p), map[string]string{"a.ts": want})
+			if hash, _ := docs.ContentHash(p); hash != hashContent([]byte(want)) {
+				t.Error("tsgo's copy differs from the file written")
+			}
+			if len(conn.changes) != 1 {
+				t.Fatalf("didChange sent %d times, want once", len(conn.changes))
+			}
+			full := len(conn.changes[0]) == 1 && conn.changes[0][0].Text == want
+			if full != tt.wantFull {
+				t.Errorf("changes = %+v, want full text %v", conn.changes[0], tt.wantFull)
+			}
+		})
+	}
+}
+
+func TestResyncEditedAtOpenLimit(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	open, closed := filepath.Join(dir, "open.ts"), filepath.Join(dir, "closed.ts")
+	writeString(t, open, "export const a = 1;\n")
+	writeString(t, closed, "export const b = 1;\n")
+	conn := &didChangeConn{}
+	docs := docsync.NewManager()
+	docs.SetMaxOpen(1)
+	if err := docs.SyncFile(ctx, conn, open); err != nil {
+		t.Fatal(err)
+	}
+
+	we := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{
+		docEdit(closed, textEdit(0, 13, 0, 14, "c")),
+		docEdit(open, textEdit(0, 13, 0, 14, "c")),
+	}}}
+	changes, err := applyWorkspaceEdit(we, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{closed, open} {
+		if err := resyncEdited(ctx, conn, docs, p, changes[p].sync); err != nil {
+			t.Errorf("re-sync of %s: %v", filepath.Base(p), err)
+		}
+	}
+	if _, ok := docs.Version(closed); ok {
+		t.Error("closed.ts opened beyond the limit")
+	}
+	if hash, _ := docs.ContentHash(open); hash != hashContent([]byte("export const c = 1;\n")) {
+		t.Error("open.ts not re-synced")
+	}
+}
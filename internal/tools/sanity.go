@@ -0,0 +1,354 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/jslex"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// sanityLogLimit caps the rejected content included in debug logs.
+const sanityLogLimit = 4096
+
+// editSanityError reports computed file content that failed a check run
+// before any file is written.
+type editSanityError struct {
+	File   string
+	Check  string // "nul", "line-delta", "brackets" or "overlay-syntax"
+	Detail string
+}
+
+func (e *editSanityError) Error() string {
+	return fmt.Sprintf("ERR_EDIT_SANITY: %s: %s check failed: %s; nothing was written", e.File, e.Check, e.Detail)
+}
+
+// editGate is an extra check of the updated content of an edit's files
+// before any is written. An error from check aborts the whole edit. abort
+// is called when the edit fails after the checks began, whether a check or
+// the write failed, to undo what the checks left behind.
+type editGate interface {
+	check(path string, original, updated []byte) error
+	abort()
+}
+
+// checkEditSanity guards against edit-application bugs such as wrong
+// offsets or mangled line endings. It rejects updated content that
+// introduces a NUL byte, whose line count moved by other than the edits'
+// newline delta, or, for source files, whose brackets no longer balance
+// although the original's did.
+func checkEditSanity(path string, original, updated []byte, edits []protocol.TextEdit) error {
+	if bytes.Count(updated, []byte{0}) > bytes.Count(original, []byte{0}) {
+		return &editSanityError{File: path, Check: "nul", Detail: "the edit introduces a NUL byte"}
+	}
+	want := lineDelta(edits)
+	if got := lineBreaks(string(updated)) - lineBreaks(string(original)); got != want && !finalNewlineKept(original, updated, got-want) {
+		return &editSanityError{File: path, Check: "line-delta", Detail: fmt.Sprintf("line count changed by %d, edits account for %d", got, want)}
+	}
+	if !isDocFile(path) && checkBrackets(original) == nil {
+		if err := checkBrackets(updated); err != nil {
+			return &editSanityError{File: path, Check: "brackets", Detail: err.Error()}
+		}
+	}
+	return nil
+}
+
+// lineDelta returns how many lines edits add to a file: the line breaks
+// they insert minus the line breaks inside the ranges they replace.
+func lineDelta(edits []protocol.TextEdit) int {
+	delta := 0
+	for _, e := range edits {
+		delta += lineBreaks(e.NewText) - int(e.Range.End.Line-e.Range.Start.Line)
+	}
+	return delta
+}
+
+// lineBreaks counts the line breaks of s as edit.SplitLines splits lines:
+// "\r\n", "\n" and a lone "\r" each end one.
+func lineBreaks(s string) int {
+	return strings.Count(s, "\n") + strings.Count(s, "\r") - strings.Count(s, "\r\n")
+}
+
+// finalNewlineKept reports whether extra, the lines updated has beyond
+// what the edits account for, is the line ending edit.ApplyTextEdits
+// adds or removes at the end so that the content ends as original did:
+// one more when original ends with a line break, one less when it does
+// not.
+func finalNewlineKept(original, updated []byte, extra int) bool {
+	if len(original) == 0 || len(updated) == 0 {
+		return false
+	}
+	ends := endsWithBreak(original)
+	if endsWithBreak(updated) != ends {
+		return false
+	}
+	if ends {
+		return extra == 1
+	}
+	return extra == -1
+}
+
+// endsWithBreak reports whether content ends with a line break.
+func endsWithBreak(content []byte) bool {
+	return bytes.HasSuffix(content, []byte{'\n'}) || bytes.HasSuffix(content, []byte{'\r'})
+}
+
+// bracket is an open bracket on checkBrackets' stack.
+type bracket struct {
+	char byte
+	line int
+}
+
+// checkBrackets reports the first unmatched or unclosed bracket of
+// TypeScript or JavaScript source, skipping strings, template literal text,
+// comments and regular expression literals. It is a heuristic: a "/"
+// starts a regular expression after an operator, an opening bracket or a
+// keyword such as return. Quoted strings end with their line, as they must
+// in valid code, so an apostrophe in JSX text cannot swallow the rest of
+// the file.
+func checkBrackets(content []byte) error {
+	src := string(content)
+	var lex jslex.Scanner
+	var stack []bracket
+	line, commentLine := 1, 0
+	prev := byte(0) // last significant byte outside strings and comments
+	prevWord := ""  // the identifier ending at prev, if any
+	for i := 0; i < len(src); {
+		kind, next := lex.Next(src, i)
+		switch kind {
+		case jslex.Comment:
+			if strings.HasPrefix(src[i:], "/*") {
+				commentLine = line
+			}
+		case jslex.String:
+			prev, prevWord = src[i], ""
+		case jslex.Template:
+			prev, prevWord = '`', ""
+			if strings.HasSuffix(src[i:next], "${") {
+				prev = '{'
+			}
+		}
+		if kind != jslex.Code {
+			line += strings.Count(src[i:next], "\n")
+			i = next
+			continue
+		}
+		c := src[i]
+		i = next
+		switch {
+		case c == '\n':
+			line++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			continue
+		case c == '/' && startsRegex(prev, prevWord):
+			i = skipRegex(content, i-1) + 1
+			prev, prevWord = '/', ""
+			continue
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, bracket{c, line})
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) == 0 {
+				return fmt.Errorf("unmatched %q at line %d", c, line)
+			}
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if closing(top.char) != c {
+				return fmt.Errorf("%q at line %d closes %q opened at line %d", c, line, top.char, top.line)
+			}
+		}
+		if isIdentByte(c) {
+			if !isIdentByte(prev) {
+				prevWord = ""
+			}
+			prevWord += string(c)
+		} else {
+			prevWord = ""
+		}
+		prev = c
+	}
+	switch lex.Open() {
+	case jslex.Comment:
+		return fmt.Errorf("unterminated comment at line %d", commentLine)
+	case jslex.Template:
+		return fmt.Errorf("unterminated template literal")
+	}
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return fmt.Errorf("unclosed %q opened at line %d", top.char, top.line)
+	}
+	return nil
+}
+
+func closing(open byte) byte {
+	switch open {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	}
+	return '}'
+}
+
+// regexKeywords are the keywords after which "/" starts a regular
+// expression rather than a division.
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true,
+	"new": true, "delete": true, "void": true, "throw": true, "case": true,
+	"do": true, "else": true, "yield": true, "await": true,
+}
+
+// startsRegex reports whether a "/" following prev starts a regular
+// expression literal.
+func startsRegex(prev byte, prevWord string) bool {
+	if prevWord != "" {
+		return regexKeywords[prevWord]
+	}
+	switch prev {
+	case 0, '(', ',', '=', ':', '[', '!', '&', '|', '?', '{', '}', ';', '~', '+', '-', '*', '%', '^':
+		return true
+	}
+	return false
+}
+
+// skipRegex returns the index of the closing "/" of the regular expression
+// starting at i, or of the end of its line when it has none.
+func skipRegex(content []byte, i int) int {
+	inClass := false
+	for i++; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '\\':
+			i++
+		case c == '\n':
+			return i - 1
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			return i
+		}
+	}
+	return len(content) - 1
+}
+
+// overlayBackend is the subset of *lsp.Client used by overlayGate.
+type overlayBackend interface {
+	Conn() jsonrpc2.Conn
+	DiagnosticReport(ctx context.Context, file string, version int32) (lsp.FileDiagnostics, error)
+}
+
+// overlayGate returns an editGate that sends the updated content of each
+// file to tsgo as an unsaved overlay and rejects it when it has more
+// syntax errors than the original. When the edit fails, every file
+// overlaid is synced from disk again, so that tsgo is not left with
+// content that was never written. Documentation files are not checked.
+func overlayGate(ctx context.Context, client overlayBackend, docs *docsync.Manager) editGate {
+	return &overlayChecker{ctx: ctx, client: client, docs: docs}
+}
+
+// overlayChecker is the editGate of overlayGate.
+type overlayChecker struct {
+	ctx    context.Context
+	client overlayBackend
+	docs   *docsync.Manager
+	// overlaid are the files sent as overlays since the last abort.
+	overlaid []string
+}
+
+func (g *overlayChecker) check(path string, original, updated []byte) error {
+	if isDocFile(path) {
+		return nil
+	}
+	g.overlaid = append(g.overlaid, path)
+	before, err := syntaxErrorCount(g.ctx, g.client, g.docs, path, original)
+	if err != nil {
+		return err
+	}
+	after, err := syntaxErrorCount(g.ctx, g.client, g.docs, path, updated)
+	if err != nil {
+		return err
+	}
+	if after > before {
+		return &editSanityError{File: path, Check: "overlay-syntax", Detail: fmt.Sprintf("syntax errors rose from %d to %d", before, after)}
+	}
+	return nil
+}
+
+// abort replaces the overlays with the files on disk, closing those that
+// do not exist, such as the files a failed edit would have created.
+func (g *overlayChecker) abort() {
+	for _, p := range g.overlaid {
+		var err error
+		if _, statErr := os.Stat(p); errors.Is(statErr, os.ErrNotExist) {
+			_, _, err = g.docs.CloseFiles(g.ctx, g.client.Conn(), []string{p})
+		} else {
+			err = g.docs.ResyncFile(g.ctx, g.client.Conn(), p)
+		}
+		if err != nil {
+			slog.Warn("restoring a file after an overlay check", "file", p, "error", err)
+		}
+	}
+	g.overlaid = nil
+}
+
+// syntaxErrorCount syncs content as path's overlay and counts the
+// syntactic diagnostics (codes 1000-1999) tsgo reports for it.
+func syntaxErrorCount(ctx context.Context, client overlayBackend, docs *docsync.Manager, path string, content []byte) (int, error) {
+	if err := docs.SyncContent(ctx, client.Conn(), path, content); err != nil {
+		return 0, fmt.Errorf("overlay sync of %s: %w", path, err)
+	}
+	version, _ := docs.Version(path)
+	report, err := client.DiagnosticReport(ctx, path, version)
+	if err != nil {
+		return 0, fmt.Errorf("overlay diagnostics of %s: %w", path, err)
+	}
+	return countSyntaxErrors(report.Items), nil
+}
+
+// countSyntaxErrors counts the diagnostics whose code marks a syntax
+// error.
+func countSyntaxErrors(diags []protocol.Diagnostic) int {
+	n := 0
+	for _, d := range diags {
+		var code int
+		switch v := d.Code.(type) {
+		case float64:
+			code = int(v)
+		case int32:
+			code = int(v)
+		case int:
+			code = v
+		case string:
+			code, _ = strconv.Atoi(v)
+		}
+		if code >= 1000 && code < 2000 {
+			n++
+		}
+	}
+	return n
+}
+
+// logRejectedEdit records a rejected edit. With TYPESCRIPT_MCP_DEBUG set,
+// the start of the rejected content is included for diagnosis.
+func logRejectedEdit(err *editSanityError, updated []byte) {
+	attrs := []any{"file", err.File, "check", err.Check, "detail", err.Detail}
+	if os.Getenv("TYPESCRIPT_MCP_DEBUG") != "" {
+		content := updated
+		if len(content) > sanityLogLimit {
+			content = content[:sanityLogLimit]
+		}
+		attrs = append(attrs, "content", string(content), "contentBytes", len(updated))
+	}
+	slog.Warn("rejected edit", attrs...)
+}
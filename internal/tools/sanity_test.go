@@ -0,0 +1,327 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.lsp.dev/jsonrpc2"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestCheckBrackets(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"plain", "function f(a: number[]) {\n  return { a };\n}\n", ""},
+		{"strings", `const s = "(" + ')' + "\"{";`, ""},
+		{"comments", "// (\n/* { [\n */ const a = 1;\n", ""},
+		{"regex literal", "const re = /[(\\/]+\\)/g;\nif (re.test(s)) { return /}/; }\n", ""},
+		{"division is not a regex", "const x = (a) / 2 / (b);\n", ""},
+		{"template with braces", "const t = `{ ${obj.map((x) => `(${x}`)} }`;\n", ""},
+		{"template spanning lines", "const t = `\n  ) ] }\n  ${ { a: 1 }.a }\n`;\n", ""},
+		{"jsx", "const el = <div className=\"a\" onClick={() => go(1)}>\n  Don't {label} </div>;\nconst br = <br />;\n", ""},
+		{"unmatched close", "f(a));\n", `unmatched ')' at line 1`},
+		{"mismatched", "const a = [1, 2);\n", `')' at line 1 closes '['`},
+		{"unclosed", "function f() {\n  if (x) {\n}\n", `unclosed '{' opened at line 1`},
+		{"unclosed substitution", "const t = `${a`;\n", "unterminated template literal"},
+		{"unterminated comment", "/* x\n", "unterminated comment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBrackets([]byte(tt.src))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckEditSanity(t *testing.T) {
+	edit := func(line, from, to uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range:   protocol.Range{Start: protocol.Position{Line: line, Character: from}, End: protocol.Position{Line: line, Character: to}},
+			NewText: text,
+		}
+	}
+	original := "export function f(a: string) {\n  return a;\n}\n"
+	tests := []struct {
+		name    string
+		path    string
+		updated string
+		edits   []protocol.TextEdit
+		check   string
+	}{
+		{"clean rename", "/a.ts", strings.Replace(original, "f(", "g(", 1), []protocol.TextEdit{edit(0, 16, 17, "g")}, ""},
+		{"inserted lines", "/a.ts", "// x\n// y\n" + original, []protocol.TextEdit{edit(0, 0, 0, "// x\n// y\n")}, ""},
+		{"nul byte", "/a.ts", strings.Replace(original, "f(", "f\x00(", 1), []protocol.TextEdit{edit(0, 17, 17, "\x00")}, "nul"},
+		{"lost line", "/a.ts", strings.Replace(original, "\n  return", "  return", 1), []protocol.TextEdit{edit(0, 16, 17, "f")}, "line-delta"},
+		{"broken brackets", "/a.ts", strings.Replace(original, "f(", "f((", 1), []protocol.TextEdit{edit(0, 17, 17, "(")}, "brackets"},
+		{"doc files skip brackets", "/README.md", strings.Replace(original, "f(", "f((", 1), []protocol.TextEdit{edit(0, 17, 17, "(")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkEditSanity(tt.path, []byte(original), []byte(tt.updated), tt.edits)
+			if tt.check == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			sanityErr, ok := err.(*editSanityError)
+			if !ok || sanityErr.Check != tt.check {
+				t.Fatalf("error = %v, want the %s check", err, tt.check)
+			}
+			if !strings.HasPrefix(err.Error(), "ERR_EDIT_SANITY: "+tt.path+": "+tt.check) {
+				t.Errorf("message = %q", err.Error())
+			}
+		})
+	}
+
+	// Content that was unbalanced to begin with is not blamed on the edit.
+	broken := "const a = (;\n"
+	if err := checkEditSanity("/b.ts", []byte(broken), []byte("const b = (;\n"), []protocol.TextEdit{edit(0, 6, 7, "b")}); err != nil {
+		t.Errorf("pre-existing imbalance rejected: %v", err)
+	}
+}
+
+func TestApplyWorkspaceEditSanity(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "a.ts")
+	original := "export const a = f(1);\n"
+	if err := os.WriteFile(p, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A bad offset that cuts the closing parenthesis.
+	edit := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+		protocol.DocumentURI(docsync.FileToURI(p)): {{
+			Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 19}, End: protocol.Position{Line: 0, Character: 21}},
+			NewText: "2",
+		}},
+	}}}
+	_, err := applyWorkspaceEdit(edit, nil, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "ERR_EDIT_SANITY") || !strings.Contains(err.Error(), "brackets check failed") {
+		t.Fatalf("error = %v, want a brackets sanity error", err)
+	}
+	if got, _ := os.ReadFile(p); string(got) != original {
+		t.Errorf("file was written: %q", got)
+	}
+}
+
+func TestApplyWorkspaceEditLineEndings(t *testing.T) {
+	at := func(line, col uint32) protocol.Range {
+		pos := protocol.Position{Line: line, Character: col}
+		return protocol.Range{Start: pos, End: pos}
+	}
+	tests := []struct {
+		name     string
+		original string
+		edit     protocol.TextEdit
+		want     string
+	}{
+		{
+			// The insertion at the end gives the file a final newline.
+			name:     "append without final newline",
+			original: "const a = 1;",
+			edit:     protocol.TextEdit{Range: at(0, 12), NewText: "\nconst b = 2;\n"},
+			want:     "const a = 1;\nconst b = 2;\n",
+		},
+		{
+			// The deletion takes the final newline away.
+			name:     "delete last line break",
+			original: "const a = 1;\nconst b = 2;\n",
+			edit:     protocol.TextEdit{Range: protocol.Range{Start: protocol.Position{Line: 1, Character: 12}, End: protocol.Position{Line: 2}}},
+			want:     "const a = 1;\nconst b = 2;",
+		},
+		{
+			name:     "lone CR line endings",
+			original: "const a = 1;\rconst b = 2;\r",
+			edit:     protocol.TextEdit{Range: at(1, 0), NewText: "// b\n"},
+			want:     "const a = 1;\r// b\rconst b = 2;\r",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "a.ts")
+			if err := os.WriteFile(p, []byte(tt.original), 0644); err != nil {
+				t.Fatal(err)
+			}
+			we := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+				protocol.DocumentURI(docsync.FileToURI(p)): {tt.edit},
+			}}}
+			if _, err := applyWorkspaceEdit(we, nil, nil, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(p); string(got) != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// overlayConn records the text of the latest didOpen or didChange, and
+// of each open document.
+type overlayConn struct {
+	jsonrpc2.Conn
+	mu    sync.Mutex
+	text  string
+	texts map[protocol.DocumentURI]string
+}
+
+func (c *overlayConn) Notify(_ context.Context, _ string, params interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.texts == nil {
+		c.texts = make(map[protocol.DocumentURI]string)
+	}
+	switch p := params.(type) {
+	case *protocol.DidOpenTextDocumentParams:
+		c.text = p.TextDocument.Text
+		c.texts[p.TextDocument.URI] = c.text
+	case *protocol.DidChangeTextDocumentParams:
+		c.text = p.ContentChanges[0].Text
+		c.texts[p.TextDocument.URI] = c.text
+	case *protocol.DidCloseTextDocumentParams:
+		delete(c.texts, p.TextDocument.URI)
+	}
+	return nil
+}
+
+// fakeOverlayBackend reports a syntax error (TS1109, "Expression
+// expected") for every "= =" in the content last synced.
+type fakeOverlayBackend struct{ conn *overlayConn }
+
+func (b fakeOverlayBackend) Conn() jsonrpc2.Conn { return b.conn }
+
+func (b fakeOverlayBackend) DiagnosticReport(context.Context, string, int32) (lsp.FileDiagnostics, error) {
+	b.conn.mu.Lock()
+	defer b.conn.mu.Unlock()
+	var diags []protocol.Diagnostic
+	for range strings.Count(b.conn.text, "= =") {
+		diags = append(diags, protocol.Diagnostic{Code: float64(1109), Message: "Expression expected."})
+	}
+	diags = append(diags, protocol.Diagnostic{Code: float64(2304), Message: "Cannot find name 'x'."})
+	return lsp.FileDiagnostics{Items: diags, Source: lsp.DiagnosticSourcePull}, nil
+}
+
+func TestOverlayGate(t *testing.T) {
+	ctx := context.Background()
+	p := filepath.Join(t.TempDir(), "a.ts")
+	original := "export const a = 1;\n"
+	if err := os.WriteFile(p, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	docs := docsync.NewManager()
+	backend := fakeOverlayBackend{conn: &overlayConn{}}
+	gate := overlayGate(ctx, backend, docs)
+
+	rename := func(newText string) *lsp.WorkspaceEdit {
+		return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			protocol.DocumentURI(docsync.FileToURI(p)): {{
+				Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 13}, End: protocol.Position{Line: 0, Character: 14}},
+				NewText: newText,
+			}},
+		}}}
+	}
+
+	// Deliberately corrupted: the new text passes the bracket and line
+	// checks but is not valid syntax.
+	_, err := applyWorkspaceEdit(rename("b ="), nil, gate, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "overlay-syntax check failed: syntax errors rose from 0 to 1") {
+		t.Fatalf("error = %v, want an overlay-syntax rejection", err)
+	}
+	if got, _ := os.ReadFile(p); string(got) != original {
+		t.Errorf("file was written: %q", got)
+	}
+	if backend.conn.text != original {
+		t.Errorf("server left with the rejected overlay %q", backend.conn.text)
+	}
+
+	if _, err := applyWorkspaceEdit(rename("b"), nil, gate, nil, nil); err != nil {
+		t.Fatalf("clean edit rejected: %v", err)
+	}
+	if got, _ := os.ReadFile(p); string(got) != "export const b = 1;\n" {
+		t.Errorf("file = %q", got)
+	}
+}
+
+func TestOverlayGateAbort(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.ts"), filepath.Join(dir, "b.ts")
+	const original = "export const a = 1;\n"
+	for _, p := range []string{a, b} {
+		writeString(t, p, original)
+	}
+	rename := func(textA, textB string) *lsp.WorkspaceEdit {
+		we := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{}}}
+		for p, text := range map[string]string{a: textA, b: textB} {
+			we.Changes[protocol.DocumentURI(docsync.FileToURI(p))] = []protocol.TextEdit{{
+				Range:   protocol.Range{Start: protocol.Position{Line: 0, Character: 13}, End: protocol.Position{Line: 0, Character: 14}},
+				NewText: text,
+			}}
+		}
+		return we
+	}
+	tests := []struct {
+		name        string
+		edit        *lsp.WorkspaceEdit
+		beforeWrite func(path string) error
+		want        string
+	}{
+		{name: "later file rejected", edit: rename("b", "b ="), want: "overlay-syntax check failed"},
+		{
+			name: "write failure",
+			edit: rename("b", "b"),
+			beforeWrite: func(path string) error {
+				if path == b {
+					return errors.New("disk full")
+				}
+				return nil
+			},
+			want: "disk full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := fakeOverlayBackend{conn: &overlayConn{}}
+			docs := docsync.NewManager()
+			beforeEditWrite = tt.beforeWrite
+			defer func() { beforeEditWrite = nil }()
+
+			_, err := applyWorkspaceEdit(tt.edit, nil, overlayGate(ctx, backend, docs), nil, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+			for _, p := range []string{a, b} {
+				if got := backend.conn.texts[protocol.DocumentURI(docsync.FileToURI(p))]; got != original {
+					t.Errorf("server left with %q for %s, want the content on disk", got, filepath.Base(p))
+				}
+			}
+		})
+	}
+}
+
+func TestCountSyntaxErrors(t *testing.T) {
+	diags := []protocol.Diagnostic{
+		{Code: float64(1005)}, {Code: int32(1128)}, {Code: "1109"},
+		{Code: float64(2322)}, {Code: nil}, {Code: "TS1005"},
+	}
+	if got := countSyntaxErrors(diags); got != 3 {
+		t.Errorf("countSyntaxErrors = %d, want 3", got)
+	}
+}
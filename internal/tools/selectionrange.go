@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const (
+	// maxSelectionPositions is the most positions one ts_selection_range
+	// call takes.
+	maxSelectionPositions = 50
+	// maxSelectionPreview bounds the preview of each range, in runes.
+	maxSelectionPreview = 80
+)
+
+// selectionRangeEntry is one enclosing range, 1-based and end-exclusive.
+type selectionRangeEntry struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+	// Preview is the range's text with whitespace runs collapsed,
+	// shortened to maxSelectionPreview runes.
+	Preview string `json:"preview"`
+}
+
+type selectionChain struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Ranges enclose the position, from the innermost out.
+	Ranges []selectionRangeEntry `json:"ranges"`
+}
+
+type selectionRangeResult struct {
+	File       string           `json:"file"`
+	Selections []selectionChain `json:"selections"`
+}
+
+// rangePreview returns the text of r in lines, whitespace collapsed and
+// shortened to maxSelectionPreview runes.
+func rangePreview(lines []string, r protocol.Range) string {
+	start, end := int(r.Start.Line), int(r.End.Line)
+	if start >= len(lines) {
+		return ""
+	}
+	end = min(end, len(lines)-1)
+	var parts []string
+	for n := start; n <= end; n++ {
+		text := lines[n]
+		from, to := 0, len(text)
+		if n == start {
+			from = edit.ByteOffset(text, r.Start.Character)
+		}
+		if n == int(r.End.Line) {
+			to = edit.ByteOffset(text, r.End.Character)
+		}
+		if from < to {
+			parts = append(parts, text[from:to])
+		}
+	}
+	preview := strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+	if runes := []rune(preview); len(runes) > maxSelectionPreview {
+		preview = string(runes[:maxSelectionPreview]) + "…"
+	}
+	return preview
+}
+
+// selectionEntries flattens the chain of sel from the innermost range
+// out, dropping a parent that repeats its child's range.
+func selectionEntries(lines []string, sel protocol.SelectionRange) []selectionRangeEntry {
+	entries := []selectionRangeEntry{}
+	var last *protocol.Range
+	for s := &sel; s != nil; s = s.Parent {
+		if last != nil && *last == s.Range {
+			continue
+		}
+		last = &s.Range
+		entries = append(entries, selectionRangeEntry{
+			StartLine:   int(s.Range.Start.Line) + 1,
+			StartColumn: int(s.Range.Start.Character) + 1,
+			EndLine:     int(s.Range.End.Line) + 1,
+			EndColumn:   int(s.Range.End.Character) + 1,
+			Preview:     rangePreview(lines, s.Range),
+		})
+	}
+	return entries
+}
+
+func makeSelectionRangeHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		rawPositions, hasPositions := request.GetArguments()["positions"]
+		line, col := request.GetInt("line", 0), request.GetInt("column", 0)
+		var positions []hoverPosition
+		switch {
+		case hasPositions && (line != 0 || col != 0):
+			return mcp.NewToolResultError("pass either line and column, or positions"), nil
+		case hasPositions:
+			if positions, err = parseHoverPositions(rawPositions); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			if len(positions) == 0 {
+				return mcp.NewToolResultError("positions must not be empty"), nil
+			}
+		case line < 1 || col < 1:
+			return mcp.NewToolResultError("line and column (at least 1), or positions, are required"), nil
+		default:
+			positions = []hoverPosition{{Line: line, Column: col}}
+		}
+		if len(positions) > maxSelectionPositions {
+			return mcp.NewToolResultError(fmt.Sprintf("%d positions; at most %d per call", len(positions), maxSelectionPositions)), nil
+		}
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+		lines, err := cachedReadLines(file)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("read error: %v", err)), nil
+		}
+
+		lspPositions := make([]protocol.Position, len(positions))
+		for i, p := range positions {
+			char := cols.charColumn(file, p.Line, p.Column)
+			lspPositions[i] = protocol.Position{Line: uint32(p.Line - 1), Character: uint32(char - 1)}
+		}
+		sels, err := client.SelectionRange(ctx, file, lspPositions)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("selection ranges are unavailable: %v; use ts_folding_ranges for the enclosing blocks instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("selection range error: %v", err)), nil
+		}
+		if len(sels) != len(positions) {
+			return mcp.NewToolResultError(fmt.Sprintf("selection range error: %d results for %d positions", len(sels), len(positions))), nil
+		}
+
+		result := selectionRangeResult{File: file, Selections: make([]selectionChain, len(positions))}
+		for i, p := range positions {
+			result.Selections[i] = selectionChain{Line: p.Line, Column: p.Column, Ranges: selectionEntries(lines, sels[i])}
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func selRange(sl, sc, el, ec uint32) protocol.Range {
+	return protocol.Range{Start: protocol.Position{Line: sl, Character: sc}, End: protocol.Position{Line: el, Character: ec}}
+}
+
+func TestSelectionEntries(t *testing.T) {
+	lines := []string{
+		"function f() {",
+		"  const total = add(1,",
+		"    2);",
+		"}",
+	}
+	// add, the call, the declaration twice (tsgo repeats ranges), the body.
+	call := selRange(1, 16, 2, 6)
+	decl := selRange(1, 2, 2, 7)
+	sel := protocol.SelectionRange{Range: selRange(1, 16, 1, 19), Parent: &protocol.SelectionRange{
+		Range: call, Parent: &protocol.SelectionRange{
+			Range: decl, Parent: &protocol.SelectionRange{
+				Range: decl, Parent: &protocol.SelectionRange{Range: selRange(0, 0, 3, 1)},
+			},
+		},
+	}}
+
+	got := selectionEntries(lines, sel)
+	want := []selectionRangeEntry{
+		{StartLine: 2, StartColumn: 17, EndLine: 2, EndColumn: 20, Preview: "add"},
+		{StartLine: 2, StartColumn: 17, EndLine: 3, EndColumn: 7, Preview: "add(1, 2)"},
+		{StartLine: 2, StartColumn: 3, EndLine: 3, EndColumn: 8, Preview: "const total = add(1, 2);"},
+		{StartLine: 1, StartColumn: 1, EndLine: 4, EndColumn: 2, Preview: "function f() { const total = add(1, 2); }"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRangePreviewShortens(t *testing.T) {
+	line := "const s = \"" + strings.Repeat("é", 100) + "\";"
+	got := rangePreview([]string{line}, selRange(0, 0, 0, uint32(utf16Len(line))))
+	if r := []rune(got); len(r) != maxSelectionPreview+1 || !strings.HasSuffix(got, "…") {
+		t.Errorf("preview = %q, want %d runes and an ellipsis", got, maxSelectionPreview)
+	}
+	if got := rangePreview([]string{"x"}, selRange(4, 0, 4, 1)); got != "" {
+		t.Errorf("range past the end = %q", got)
+	}
+}
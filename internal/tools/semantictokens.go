@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+type semanticTokenEntry struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	// Length counts UTF-16 code units, as columns do.
+	Length    int      `json:"length"`
+	Type      string   `json:"type"`
+	Modifiers []string `json:"modifiers,omitempty"`
+	// Text is the token's text, read from the file.
+	Text string `json:"text,omitempty"`
+}
+
+type semanticTokensResult struct {
+	File       string               `json:"file"`
+	Tokens     []semanticTokenEntry `json:"tokens"`
+	TotalCount int                  `json:"totalCount"`
+	// Truncated is set when there were more than maxResults tokens.
+	Truncated bool `json:"truncated"`
+}
+
+// semanticTokenEntries converts the tokens on lines startLine to endLine
+// of lines, the content of file, keeping at most max.
+func semanticTokenEntries(file string, lines []string, tokens []lsp.SemanticToken, startLine, endLine, max int) semanticTokensResult {
+	result := semanticTokensResult{File: file, Tokens: []semanticTokenEntry{}}
+	for _, tok := range tokens {
+		line := int(tok.Line) + 1
+		if line < startLine || line > endLine {
+			continue
+		}
+		result.TotalCount++
+		if len(result.Tokens) == max {
+			result.Truncated = true
+			continue
+		}
+		e := semanticTokenEntry{
+			Line:      line,
+			Column:    int(tok.Character) + 1,
+			Length:    int(tok.Length),
+			Type:      tok.Type,
+			Modifiers: tok.Modifiers,
+		}
+		if line <= len(lines) {
+			text := lines[line-1]
+			start := edit.ByteOffset(text, tok.Character)
+			e.Text = text[start:edit.ByteOffset(text, tok.Character+tok.Length)]
+		}
+		result.Tokens = append(result.Tokens, e)
+	}
+	return result
+}
+
+func makeSemanticTokensHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		maxResults := request.GetInt("maxResults", 200)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+		lines, err := cachedReadLines(file)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("read error: %v", err)), nil
+		}
+		if len(lines) == 0 {
+			lines = []string{""}
+		}
+		startLine := request.GetInt("startLine", 1)
+		endLine := request.GetInt("endLine", len(lines))
+		if startLine < 1 || endLine < startLine {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid line range %d-%d", startLine, endLine)), nil
+		}
+		endLine = min(endLine, len(lines))
+		if startLine > endLine {
+			return mcp.NewToolResultError(fmt.Sprintf("startLine %d is past the end of the file (%d lines)", startLine, len(lines))), nil
+		}
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		tokens, err := client.SemanticTokens(ctx, file, startLine, endLine)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("semantic tokens are unavailable: %v; use ts_hover_batch to classify positions instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("semantic tokens error: %v", err)), nil
+		}
+
+		data, err := json.MarshalIndent(semanticTokenEntries(file, lines, tokens, startLine, endLine, maxResults), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func TestSemanticTokenEntries(t *testing.T) {
+	lines := []string{
+		"enum Colör { Red }",
+		"const c = Colör.Red;",
+		"export class Box {}",
+	}
+	tokens := []lsp.SemanticToken{
+		{Line: 0, Character: 5, Length: 5, Type: "enum", Modifiers: []string{"declaration"}},
+		{Line: 0, Character: 13, Length: 3, Type: "enumMember", Modifiers: []string{"declaration", "readonly"}},
+		{Line: 1, Character: 6, Length: 1, Type: "variable", Modifiers: []string{"declaration", "readonly"}},
+		{Line: 1, Character: 10, Length: 5, Type: "enum"},
+		{Line: 1, Character: 16, Length: 3, Type: "enumMember", Modifiers: []string{"readonly"}},
+		{Line: 2, Character: 13, Length: 3, Type: "class", Modifiers: []string{"declaration"}},
+	}
+
+	got := semanticTokenEntries("/p/a.ts", lines, tokens, 2, 2, 2)
+	if got.TotalCount != 3 || !got.Truncated || len(got.Tokens) != 2 {
+		t.Fatalf("result = %+v, want 2 of line 2's 3 tokens", got)
+	}
+	c, colör := got.Tokens[0], got.Tokens[1]
+	if c.Line != 2 || c.Column != 7 || c.Length != 1 || c.Text != "c" || c.Type != "variable" || !slices.Equal(c.Modifiers, []string{"declaration", "readonly"}) {
+		t.Errorf("c = %+v", c)
+	}
+	if colör.Column != 11 || colör.Text != "Colör" || colör.Type != "enum" {
+		t.Errorf("Colör = %+v", colör)
+	}
+
+	all := semanticTokenEntries("/p/a.ts", lines, tokens, 1, 3, 50)
+	if all.TotalCount != 6 || all.Truncated || all.Tokens[1].Text != "Red" || all.Tokens[5].Text != "Box" {
+		t.Errorf("whole file = %+v", all)
+	}
+}
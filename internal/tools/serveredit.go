@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+// serverEditOrigin is the tool recorded for edits tsgo sends itself.
+const serverEditOrigin = "workspace/applyEdit"
+
+// serverEditApplier returns the lsp.EditApplier writing the edits tsgo
+// sends with workspace/applyEdit, such as those of commands a code action
+// runs. They are applied like a tool's edit, journaled and recorded, and
+// the written files re-synced, but without the overlay check: tsgo waits
+// on the answer and cannot serve the check meanwhile.
+//
+// The edit may arrive while a tool call is in flight, so only the written
+// files are dropped from the file line cache; ClearFileCache would empty
+// it under the running call.
+func serverEditApplier(client *lsp.Client, docs *docsync.Manager, pending *editTokenStore, packages *workspace.PackageResolver, journal *journalPolicy, recorder *editRecorder) lsp.EditApplier {
+	return func(ctx context.Context, label string, we *lsp.WorkspaceEdit) error {
+		if path, pkg := installedPackageEdit(we, packages); pkg != nil {
+			return fmt.Errorf("it edits the installed package %s (%s)", pkg, pkg.DisplayPath(path))
+		}
+		changes, err := applyWorkspaceEdit(we, docs, nil, journal, recorder.recording(editOrigin{tool: serverEditOrigin, symbol: label}))
+		if err != nil {
+			resyncStale(ctx, client, docs, err)
+			return err
+		}
+		paths := sortedChangePaths(changes)
+		pending.InvalidateFiles(paths)
+		forgetCachedLines(paths...)
+
+		// The files are written; a failed re-sync is caught up by the next
+		// tool call that syncs them.
+		if err := resyncChanged(ctx, client, docs, changes); err != nil {
+			slog.Warn("re-syncing a workspace edit from tsgo", "label", label, "error", err)
+		}
+		slog.Info("applied a workspace edit from tsgo", "label", label, "files", len(paths))
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+func TestServerEditApplierRefusesInstalledPackages(t *testing.T) {
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"node_modules/zod/package.json":   `{"name": "zod", "version": "3.22.4"}`,
+		"node_modules/zod/lib/types.d.ts": "export type A = 1;\n",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(root, "node_modules", "zod", "lib", "types.d.ts")
+	we := &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+		protocol.DocumentURI(docsync.FileToURI(path)): {{NewText: "// edited\n"}},
+	}}}
+
+	apply := serverEditApplier(nil, nil, newEditTokenStore(0), workspace.NewPackageResolver(), nil, nil)
+	err := apply(context.Background(), "Fix all", we)
+	if err == nil || !strings.Contains(err.Error(), "installed package zod@3.22.4") {
+		t.Fatalf("error = %v, want a refusal naming the package", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "export type A = 1;\n" {
+		t.Errorf("package file = %q, want it unchanged", data)
+	}
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/jslex"
+)
+
+// maxSignatureLines caps a multi-line declaration preview.
+const maxSignatureLines = 12
+
+// sigScanner tracks the lexical state of a declaration across lines so
+// that parentheses, angle brackets and terminators inside strings,
+// template literals and comments are ignored.
+type sigScanner struct {
+	lex       jslex.Scanner
+	parens    int
+	angles    int // type argument brackets outside parentheses
+	sawParams bool
+	done      bool
+}
+
+// scanLine advances the scanner over one line. It sets done once the
+// parameter list has closed and the declaration body, an arrow or a
+// semicolon follows at the top level.
+func (s *sigScanner) scanLine(line string) {
+	for i := 0; i < len(line) && !s.done; {
+		kind, next := s.lex.Next(line, i)
+		if kind != jslex.Code {
+			i = next
+			continue
+		}
+		switch c := line[i]; {
+		case c == '(':
+			s.parens++
+			s.sawParams = true
+		case c == ')':
+			if s.parens > 0 {
+				s.parens--
+			}
+		case s.parens > 0:
+			// Default values may compare with < and >; only parentheses
+			// count inside a parameter list.
+		case c == '=' && i+1 < len(line) && line[i+1] == '>':
+			next++
+			if s.angles == 0 && s.sawParams {
+				s.done = true
+			}
+		case c == '<' && i > 0 && isIdentByte(line[i-1]):
+			// Only a bracket right after a name opens type arguments;
+			// "a < b" is a comparison.
+			s.angles++
+		case c == '>':
+			if s.angles > 0 {
+				s.angles--
+			}
+		case (c == '{' || c == ';') && s.angles == 0:
+			s.done = true
+		}
+		i = next
+	}
+}
+
+// signatureEnd returns the index of the last line of the declaration that
+// starts at lines[start]. Declarations without a parameter list on their
+// first line end there; otherwise lines are added until the parameters
+// close and the return type is complete. capped reports that the
+// declaration was cut at maxSignatureLines.
+func signatureEnd(lines []string, start int) (end int, capped bool) {
+	var s sigScanner
+	for i := start; i < len(lines); i++ {
+		if i-start == maxSignatureLines {
+			return i - 1, true
+		}
+		s.scanLine(lines[i])
+		switch {
+		case s.done:
+			return i, false
+		case s.lex.Open() != jslex.Code || s.parens > 0 || s.angles > 0:
+			continue
+		case !s.sawParams:
+			return i, false
+		}
+		next := ""
+		if i+1 < len(lines) {
+			next = lines[i+1]
+		}
+		if !continuesSignature(lines[i], next) {
+			return i, false
+		}
+	}
+	return len(lines) - 1, false
+}
+
+// continuesSignature reports whether a signature whose parameters closed
+// on line goes on with a return type or body on the next line, as
+// opposed to a bodiless signature like an interface method.
+func continuesSignature(line, next string) bool {
+	line = strings.TrimSpace(line)
+	if strings.HasSuffix(line, ")") {
+		next = strings.TrimSpace(next)
+		return strings.HasPrefix(next, ":") || strings.HasPrefix(next, "=>") || strings.HasPrefix(next, "{")
+	}
+	return strings.HasSuffix(line, ":") || strings.HasSuffix(line, "|") || strings.HasSuffix(line, "&") ||
+		strings.HasSuffix(line, ",") || strings.HasSuffix(line, "=")
+}
+
+// signaturePreview returns the declaration starting at line (1-based) of
+// file as a preview: one trimmed line for most declarations, and the
+// whole signature for functions whose parameters or return type span
+// several lines, with the first line's indentation removed.
+func signaturePreview(file string, line int) (preview string, capped bool, err error) {
+	lines, err := cachedReadLines(file)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := readLine(file, line); err != nil {
+		return "", false, err
+	}
+	end, capped := signatureEnd(lines, line-1)
+	first := strings.TrimRight(lines[line-1], "\r")
+	indent := first[:len(first)-len(strings.TrimLeft(first, " \t"))]
+	out := []string{strings.TrimSpace(first)}
+	for _, l := range lines[line : end+1] {
+		out = append(out, strings.TrimRight(strings.TrimPrefix(l, indent), " \t\r"))
+	}
+	return strings.Join(out, "\n"), capped, nil
+}
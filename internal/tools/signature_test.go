@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignatureEnd(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		start  int
+		want   int
+		capped bool
+	}{
+		{"single line function", "export function add(a: number, b: number): number {\n  return a + b;\n}", 0, 0, false},
+		{"const", "export const limit = 10;\nexport const other = 2;", 0, 0, false},
+		{"interface", "export interface User {\n  id: string;\n}", 0, 0, false},
+		{"comparison is not a type argument", "const small = a < b\nconst next = 1;", 0, 0, false},
+		{"multi-line parameters", `export async function createUser(
+  name: string,
+  email: string,
+  role: Role = "user",
+): Promise<User> {
+  return db.insert(name, email, role);
+}`, 0, 4, false},
+		{"default values with parens", `function connect(
+  retries = Math.max(1, envInt("RETRIES")),
+  label = ")",
+  // a stray ( in a comment
+  /* and ) in a block */ timeout = (5 * 1000),
+) {
+}`, 0, 5, false},
+		{"template literal", "function greet(\n  msg = `(${name}`,\n): string {\n}", 0, 2, false},
+		{"return type on its own line", "function load(path: string)\n  : Promise<Buffer> {\n}", 0, 1, false},
+		{"arrow function const", `export const createUser = async (
+  name: string,
+): Promise<User> => {
+  return save(name);
+};`, 0, 2, false},
+		{"generics", `export function pick<T extends Record<string, (x: number) => void>, K extends keyof T>(
+  obj: T,
+  keys: K[],
+): Pick<T, K> {
+}`, 0, 3, false},
+		{"generic return type with object", "function wrap(\n  v: string,\n): Promise<{ a: string }> {\n}", 0, 2, false},
+		{"bodiless method", "  save(\n    user: User,\n  ): void\n  load(): User", 0, 2, false},
+		{"starts mid-file", "// header\nfunction f(\n  a: string,\n) {}", 1, 3, false},
+		{"capped", "function many(\n" + strings.Repeat("  a: string,\n", 20) + ") {}", 0, 11, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, capped := signatureEnd(strings.Split(tt.src, "\n"), tt.start)
+			if end != tt.want || capped != tt.capped {
+				t.Errorf("signatureEnd = %d, %v, want %d, %v", end, capped, tt.want, tt.capped)
+			}
+		})
+	}
+}
+
+func TestSignaturePreview(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "user.ts")
+	src := "export class Users {\n    async create(\n        name: string,\n    ): Promise<User> {\n        return save(name);\n    }\n}\n"
+	if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, capped, err := signaturePreview(p, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "async create(\n    name: string,\n): Promise<User> {"
+	if got != want || capped {
+		t.Errorf("preview = %q, %v, want %q", got, capped, want)
+	}
+	if _, _, err := signaturePreview(p, 99); err == nil {
+		t.Error("expected an error for a line out of range")
+	}
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+type signatureParameter struct {
+	Label         string `json:"label"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+type signatureEntry struct {
+	Label         string               `json:"label"`
+	Documentation string               `json:"documentation,omitempty"`
+	Parameters    []signatureParameter `json:"parameters"`
+}
+
+type signatureHelpResult struct {
+	Signatures []signatureEntry `json:"signatures"`
+	// ActiveSignature and ActiveParameter are 0-based indexes into
+	// Signatures and the active signature's Parameters.
+	ActiveSignature int `json:"activeSignature"`
+	ActiveParameter int `json:"activeParameter"`
+}
+
+// signatureHelpResultOf converts a signature help. An active signature
+// outside the list falls back to the first, and the active signature's own
+// active parameter wins over the response's.
+func signatureHelpResultOf(help *protocol.SignatureHelp) signatureHelpResult {
+	result := signatureHelpResult{
+		Signatures:      make([]signatureEntry, len(help.Signatures)),
+		ActiveSignature: int(help.ActiveSignature),
+		ActiveParameter: int(help.ActiveParameter),
+	}
+	for i, sig := range help.Signatures {
+		entry := signatureEntry{
+			Label:         sig.Label,
+			Documentation: markupText(sig.Documentation),
+			Parameters:    make([]signatureParameter, len(sig.Parameters)),
+		}
+		for j, p := range sig.Parameters {
+			entry.Parameters[j] = signatureParameter{Label: p.Label, Documentation: markupText(p.Documentation)}
+		}
+		result.Signatures[i] = entry
+	}
+	if result.ActiveSignature >= len(result.Signatures) {
+		result.ActiveSignature = 0
+	}
+	if len(help.Signatures) > 0 && help.Signatures[result.ActiveSignature].ActiveParameter != 0 {
+		result.ActiveParameter = int(help.Signatures[result.ActiveSignature].ActiveParameter)
+	}
+	return result
+}
+
+func makeSignatureHelpHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		help, err := client.SignatureHelp(ctx, file, line, col)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("signature help error: %v", err)), nil
+		}
+		if help == nil || len(help.Signatures) == 0 {
+			return mcp.NewToolResultText("No signature help available"), nil
+		}
+
+		data, err := json.MarshalIndent(signatureHelpResultOf(help), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestSignatureHelpResultOf(t *testing.T) {
+	help := &protocol.SignatureHelp{
+		Signatures: []protocol.SignatureInformation{
+			{Label: "f(a: string): void", Parameters: []protocol.ParameterInformation{{Label: "a: string"}}},
+			{
+				Label:           "f(a: string, b: number): void",
+				Documentation:   map[string]any{"kind": "markdown", "value": "Two arguments."},
+				Parameters:      []protocol.ParameterInformation{{Label: "a: string"}, {Label: "b: number", Documentation: "the count"}},
+				ActiveParameter: 1,
+			},
+		},
+		ActiveSignature: 1,
+	}
+	got := signatureHelpResultOf(help)
+	if got.ActiveSignature != 1 || got.ActiveParameter != 1 {
+		t.Errorf("active = %d/%d, want 1/1 from the signature's own activeParameter", got.ActiveSignature, got.ActiveParameter)
+	}
+	if sig := got.Signatures[1]; sig.Documentation != "Two arguments." || sig.Parameters[1].Documentation != "the count" {
+		t.Errorf("signature = %+v", sig)
+	}
+
+	help.ActiveSignature = 5
+	if got := signatureHelpResultOf(help); got.ActiveSignature != 0 || got.ActiveParameter != 0 {
+		t.Errorf("active = %d/%d, want the first signature for an index out of range", got.ActiveSignature, got.ActiveParameter)
+	}
+}
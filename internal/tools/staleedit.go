@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// editDocs is what the language server last analyzed of the open
+// documents; *docsync.Manager implements it.
+type editDocs interface {
+	Version(filePath string) (int32, bool)
+	ContentHash(filePath string) (string, bool)
+}
+
+// staleEditError reports files an edit was computed for that changed
+// since: tsgo analyzed content that is no longer on disk, so the edit's
+// positions may point anywhere.
+type staleEditError struct {
+	Files []string
+}
+
+func (e *staleEditError) Error() string {
+	return fmt.Sprintf("ERR_STALE_EDIT: file modified since analysis — re-run the tool: %s; nothing was written", strings.Join(e.Files, ", "))
+}
+
+// staleFiles returns the files of work, in order, whose content read for
+// the edit is not what tsgo analyzed: the content last synced differs
+// from the file, or a versioned document change of we names another
+// version than the one last synced. Files tsgo does not have open, and
+// files whose edits do not apply to their content on disk (created or
+// moved ones), cannot be checked and are skipped. Right before each write,
+// edit.WriteFile verifies the file still holds the content checked here.
+func staleFiles(we *lsp.WorkspaceEdit, work []edit.File, docs editDocs) []string {
+	versions := make(map[string]int32)
+	for _, dc := range we.DocumentChanges {
+		if v := dc.TextDocument.Version; v != nil {
+			versions[docsync.URIToFile(string(dc.TextDocument.URI))] = *v
+		}
+	}
+	var stale []string
+	for _, w := range work {
+		if w.Created || w.Rebased {
+			continue
+		}
+		hash, open := docs.ContentHash(w.Path)
+		if !open {
+			continue
+		}
+		current, _ := docs.Version(w.Path)
+		if v, versioned := versions[w.Path]; (versioned && v != current) || hash != hashContent(w.Original) {
+			stale = append(stale, w.Path)
+		}
+	}
+	return stale
+}
+
+// resyncStale re-syncs the files a *staleEditError names, so that running
+// the tool again analyzes their current content. Other errors are
+// ignored.
+func resyncStale(ctx context.Context, client *lsp.Client, docs *docsync.Manager, err error) {
+	var stale *staleEditError
+	if !errors.As(err, &stale) {
+		return
+	}
+	for _, p := range stale.Files {
+		if err := docs.ResyncFile(ctx, client.Conn(), p); err != nil {
+			slog.Warn("re-syncing a stale file", "file", p, "error", err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// fakeEditDocs is the document state of a test: the version and the
+// content last synced of each open file.
+type fakeEditDocs map[string]struct {
+	version int32
+	content string
+}
+
+func (d fakeEditDocs) Version(filePath string) (int32, bool) {
+	doc, ok := d[filePath]
+	return doc.version, ok
+}
+
+func (d fakeEditDocs) ContentHash(filePath string) (string, bool) {
+	doc, ok := d[filePath]
+	if !ok {
+		return "", false
+	}
+	return hashContent([]byte(doc.content)), true
+}
+
+func TestApplyWorkspaceEditStaleFiles(t *testing.T) {
+	version := func(v int32) *int32 { return &v }
+	versioned := func(p string, v *int32) protocol.TextDocumentEdit {
+		dc := docEdit(p, renameOld)
+		dc.TextDocument.Version = v
+		return dc
+	}
+	type doc = struct {
+		version int32
+		content string
+	}
+	tests := []struct {
+		name string
+		// docs and edit get the paths of a.ts and b.ts, which hold
+		// oldContent, and of the missing n.ts.
+		docs      func(a, b string) fakeEditDocs
+		edit      func(a, b, n string) *lsp.WorkspaceEdit
+		wantStale []string
+	}{
+		{
+			name: "synced files",
+			docs: func(a, b string) fakeEditDocs { return fakeEditDocs{a: doc{1, oldContent}, b: doc{4, oldContent}} },
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{
+					versioned(a, version(1)), versioned(b, version(4)),
+				}}}
+			},
+		},
+		{
+			name: "files changed on disk since the sync",
+			docs: func(a, b string) fakeEditDocs { return fakeEditDocs{a: doc{1, agentContent}, b: doc{1, agentContent}} },
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+					fileURI(a): {renameOld}, fileURI(b): {renameOld},
+				}}}
+			},
+			wantStale: []string{"a.ts", "b.ts"},
+		},
+		{
+			name: "another version than synced",
+			docs: func(a, b string) fakeEditDocs { return fakeEditDocs{a: doc{3, oldContent}, b: doc{1, oldContent}} },
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{
+					versioned(a, version(2)), versioned(b, version(1)),
+				}}}
+			},
+			wantStale: []string{"a.ts"},
+		},
+		{
+			name: "unversioned change of an open file",
+			docs: func(a, b string) fakeEditDocs { return fakeEditDocs{a: doc{3, oldContent}} },
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{versioned(a, nil)}}}
+			},
+		},
+		{
+			name: "files not open",
+			docs: func(a, b string) fakeEditDocs { return fakeEditDocs{} },
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{
+					versioned(a, version(7)), docEdit(n, insertAtStart(createdContent)),
+				}}}
+			},
+		},
+		{
+			name: "moved file",
+			// The edits of n.ts apply to the content of a.ts moved there.
+			docs: func(a, b string) fakeEditDocs { return fakeEditDocs{a: doc{1, oldContent}} },
+			edit: func(a, b, n string) *lsp.WorkspaceEdit {
+				return &lsp.WorkspaceEdit{
+					WorkspaceEdit: protocol.WorkspaceEdit{DocumentChanges: []protocol.TextDocumentEdit{versioned(n, version(1))}},
+					Operations:    []lsp.ResourceOperation{{Kind: protocol.RenameResourceOperation, URI: fileURI(a), NewURI: fileURI(n)}},
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			a, b, n := filepath.Join(dir, "a.ts"), filepath.Join(dir, "b.ts"), filepath.Join(dir, "n.ts")
+			writeString(t, a, oldContent)
+			writeString(t, b, oldContent)
+
+			_, err := applyWorkspaceEdit(tt.edit(a, b, n), tt.docs(a, b), nil, nil, nil)
+			if tt.wantStale == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var stale *staleEditError
+			if !errors.As(err, &stale) {
+				t.Fatalf("error = %v, want ERR_STALE_EDIT", err)
+			}
+			var got []string
+			for _, f := range stale.Files {
+				got = append(got, filepath.Base(f))
+			}
+			if !reflect.DeepEqual(got, tt.wantStale) {
+				t.Errorf("stale files = %v, want %v", got, tt.wantStale)
+			}
+			if !strings.Contains(err.Error(), "file modified since analysis — re-run the tool") {
+				t.Errorf("error = %q", err)
+			}
+			checkFiles(t, dir, map[string]string{"a.ts": oldContent, "b.ts": oldContent})
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// A blocked notification channel is retried this many times, with the
+// delay doubling from streamBackoff, before the stream gives up and the
+// remaining chunks go into the final response.
+const (
+	streamRetries = 4
+	streamBackoff = 5 * time.Millisecond
+)
+
+// streamableTools are the tools whose partial results can be streamed.
+var streamableTools = map[string]bool{
+	"ts_ambient_declarations": true,
+}
+
+// resultChunk is the payload of a streamed progress notification. Key is
+// stable across calls, so clients can merge chunks into an earlier result.
+type resultChunk struct {
+	Key  string `json:"key"`
+	Data any    `json:"data"`
+}
+
+// streamSummary marks a response whose chunks were streamed. Complete is
+// false when the stream stopped early; the chunks not sent are then in
+// the response itself.
+type streamSummary struct {
+	Chunks   int  `json:"chunks"`
+	Complete bool `json:"complete"`
+}
+
+// chunkStream sends the chunks of one tool call as notifications/progress
+// messages for the call's progress token.
+type chunkStream struct {
+	ctx    context.Context
+	srv    *server.MCPServer
+	token  mcp.ProgressToken
+	tool   string
+	redact *redactor
+	file   string
+
+	sent    int
+	stopped bool
+}
+
+type streamKey struct{}
+
+// withStreaming lets h stream partial results when the caller asked for
+// progress with a progress token. The notifications bypass withRedaction,
+// so the stream applies r itself.
+func withStreaming(r *redactor, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		srv := server.ServerFromContext(ctx)
+		if srv == nil || request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+			return h(ctx, request)
+		}
+		s := &chunkStream{
+			ctx:    ctx,
+			srv:    srv,
+			token:  request.Params.Meta.ProgressToken,
+			tool:   request.Params.Name,
+			redact: r,
+			file:   request.GetString("file", ""),
+		}
+		return h(context.WithValue(ctx, streamKey{}, s), request)
+	}
+}
+
+// streamFromContext returns the call's stream, or nil when the result is
+// not streamed.
+func streamFromContext(ctx context.Context) *chunkStream {
+	s, _ := ctx.Value(streamKey{}).(*chunkStream)
+	return s
+}
+
+// send sends one chunk and reports whether it was delivered. Nothing is
+// queued: a client that stops reading blocks the channel, and after the
+// retries the stream stops for good, so the caller keeps this and every
+// later chunk for its final response.
+func (s *chunkStream) send(key string, data any) bool {
+	if s.stopped {
+		return false
+	}
+	payload, err := s.payload(data)
+	if err != nil {
+		s.stopped = true
+		return false
+	}
+	message := s.tool + ": " + key
+	if s.redact != nil {
+		key, message = s.redact.text(key), s.redact.text(message)
+	}
+	params := map[string]any{
+		"progressToken": s.token,
+		"progress":      s.sent + 1,
+		"message":       message,
+		"chunk":         resultChunk{Key: key, Data: payload},
+	}
+	delay := streamBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.srv.SendNotificationToClient(s.ctx, "notifications/progress", params)
+		if err == nil {
+			s.sent++
+			return true
+		}
+		if !errors.Is(err, server.ErrNotificationChannelBlocked) || attempt == streamRetries {
+			s.stopped = true
+			return false
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			s.stopped = true
+			return false
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// payload returns data as sent, redacted like the final response.
+func (s *chunkStream) payload(data any) (any, error) {
+	if s.redact == nil {
+		return data, nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := decodeOrderedJSON(string(raw))
+	if !ok {
+		return nil, errors.New("chunk is not a JSON object")
+	}
+	return s.redact.value(v, s.file), nil
+}
+
+// summary returns the completeness marker for the final response.
+func (s *chunkStream) summary() *streamSummary {
+	return &streamSummary{Chunks: s.sent, Complete: !s.stopped}
+}
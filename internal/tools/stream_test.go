@@ -0,0 +1,152 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// streamSession is a client session whose notifications stay in a
+// buffered channel nobody reads, like a client that has stopped reading
+// once the buffer is full.
+type streamSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *streamSession) SessionID() string { return "stream-test" }
+func (s *streamSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+func (s *streamSession) Initialize()       {}
+func (s *streamSession) Initialized() bool { return true }
+
+// callStreaming calls ts_ambient_declarations on a server with a session
+// buffering capacity notifications, with a progress token when token is
+// set, and returns the decoded result and chunks.
+func callStreaming(t *testing.T, configPath string, capacity int, token bool) (ambientDeclarationsResult, []resultChunk) {
+	t.Helper()
+	const name = "ts_ambient_declarations"
+	s := server.NewMCPServer("test", "test")
+	s.AddTool(mcp.NewTool(name, mcp.WithString("tsconfig")), withStreaming(nil, makeAmbientDeclarationsHandler(nil)))
+	session := &streamSession{notifications: make(chan mcp.JSONRPCNotification, capacity)}
+	ctx := context.Background()
+	if err := s.RegisterSession(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	params := map[string]any{"name": name, "arguments": map[string]any{"tsconfig": configPath}}
+	if token {
+		params["_meta"] = map[string]any{"progressToken": "scan-1"}
+	}
+	msg, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": params})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, ok := s.HandleMessage(s.WithContext(ctx, session), msg).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("call failed: %+v", resp)
+	}
+	result, ok := resp.Result.(mcp.CallToolResult)
+	if !ok || result.IsError || len(result.Content) != 1 {
+		t.Fatalf("result = %+v", resp.Result)
+	}
+	var res ambientDeclarationsResult
+	if err := json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	close(session.notifications)
+	var chunks []resultChunk
+	for n := range session.notifications {
+		fields := n.Params.AdditionalFields
+		if n.Method != "notifications/progress" || fields["progressToken"] != "scan-1" || fields["progress"] != len(chunks)+1 {
+			t.Fatalf("notification %d = %s %+v", len(chunks), n.Method, fields)
+		}
+		chunk := fields["chunk"].(resultChunk)
+		// Decode the data as a client would.
+		data, err := json.Marshal(chunk.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var file ambientFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, resultChunk{Key: chunk.Key, Data: file})
+	}
+	return res, chunks
+}
+
+func TestAmbientDeclarationsStreaming(t *testing.T) {
+	configPath := filepath.Join(ambientFixture(t), "tsconfig.json")
+	cfg, err := tsconfig.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := listAmbientDeclarations(cfg)
+	if len(full.Files) != 3 {
+		t.Fatalf("fixture has %d files with declarations, want 3", len(full.Files))
+	}
+
+	tests := []struct {
+		name     string
+		capacity int
+		token    bool
+		want     *streamSummary
+	}{
+		{name: "no progress token", capacity: 10},
+		{name: "streamed", capacity: 10, token: true, want: &streamSummary{Chunks: 3, Complete: true}},
+		{name: "slow client", capacity: 1, token: true, want: &streamSummary{Chunks: 1, Complete: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, chunks := callStreaming(t, configPath, tt.capacity, tt.token)
+			if !reflect.DeepEqual(res.Stream, tt.want) {
+				t.Errorf("stream = %+v, want %+v", res.Stream, tt.want)
+			}
+			if res.FilesScanned != full.FilesScanned {
+				t.Errorf("filesScanned = %d, want %d", res.FilesScanned, full.FilesScanned)
+			}
+			// The chunks followed by the files left in the response make up
+			// the non-streamed result.
+			var merged []ambientFile
+			for _, c := range chunks {
+				file := c.Data.(ambientFile)
+				if c.Key != file.File {
+					t.Errorf("chunk key %q for file %q", c.Key, file.File)
+				}
+				merged = append(merged, file)
+			}
+			merged = append(merged, res.Files...)
+			if !reflect.DeepEqual(merged, full.Files) {
+				t.Errorf("chunks and files =\n%+v\nwant\n%+v", merged, full.Files)
+			}
+		})
+	}
+}
+
+func TestChunkStreamPayloadRedacted(t *testing.T) {
+	s := &chunkStream{redact: testRedactor(t, redactionConfig{Paths: true})}
+	got, err := s.payload(definitionEntry{File: "/home/alice/repo/src/a.ts", Line: 1, Column: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry definitionEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.File != workspacePlaceholder+"/src/a.ts" {
+		t.Errorf("file = %q", entry.File)
+	}
+}
@@ -0,0 +1,1215 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxSummaryText bounds free text, such as a hover or an error message,
+// quoted in a summary line.
+const maxSummaryText = 160
+
+// toolSummary is how the results of one tool are summarized.
+type toolSummary struct {
+	// kind starts every summary line of the tool, followed by ": ".
+	kind string
+	// grammar is the rest of the line of a successful result, as the tool
+	// description documents it.
+	grammar string
+	// summarize renders a successful result. It reports false when the
+	// result is not in the shape it expects.
+	summarize func(in summaryInput) (string, bool)
+}
+
+// summaryInput is a successful tool result as the summarizers see it.
+type summaryInput struct {
+	// detail is the text the handler returned, without warnings.
+	detail string
+	// structured is the result's structured content, if any.
+	structured any
+	ctx        summaryContext
+}
+
+// summaryContext is what the summarizers know of the call besides its
+// result.
+type summaryContext struct {
+	// root is the workspace root paths are shown relative to.
+	root string
+	// file is the call's file argument.
+	file string
+}
+
+// rel returns p relative to the workspace root when it lies inside it.
+func (c summaryContext) rel(p string) string {
+	if c.root == "" || p == "" {
+		return p
+	}
+	if r, err := filepath.Rel(c.root, p); err == nil && !strings.HasPrefix(r, "..") {
+		return filepath.ToSlash(r)
+	}
+	return p
+}
+
+// toolSummaries are the summarizers of the built-in tools, by tool name.
+var toolSummaries = map[string]toolSummary{
+	"ts_diagnostics": {
+		kind:      "diagnostics",
+		grammar:   "<n> errors, <n> warnings[, <n> other][ in <file>| in <n> files[, <n> failed]] (truncated: yes|no[, <total> total][, <n> filtered out][, analysis incomplete]) | <n> errors, <n> warnings[, <n> other] in <n> codes[ shown][, most <code> x<n>][ (analysis incomplete)]",
+		summarize: summarizeDiagnosticsDetail,
+	},
+	"ts_project_diagnostics": {
+		kind:      "project diagnostics",
+		grammar:   "<n> errors, <n> warnings[, <n> other] in <n> files of <n> checked[ of <total>, timed out] (<elapsed>)",
+		summarize: jsonSummary(summarizeProjectDiagnostics),
+	},
+	"ts_definition": {
+		kind:      "definition",
+		grammar:   "<n> locations[, first <file>:<line>:<column>]",
+		summarize: summarizeDefinitionDetail,
+	},
+	"ts_type_definition": {
+		kind:      "type definition",
+		grammar:   "<n> locations[, first <file>:<line>:<column>]",
+		summarize: summarizeTypeDefinitionDetail,
+	},
+	"ts_implementations": {
+		kind:      "implementations",
+		grammar:   "<n> locations[, first <file>:<line>:<column>]",
+		summarize: summarizeImplementationsDetail,
+	},
+	"ts_call_hierarchy": {
+		kind:      "call hierarchy",
+		grammar:   "<direction> calls of <name>: <n> direct, <n> total[, <n> recursive] (truncated: yes|no) | none",
+		summarize: summarizeCallHierarchyDetail,
+	},
+	"ts_type_hierarchy": {
+		kind:      "type hierarchy",
+		grammar:   "<direction> of <name>: <n> direct, <n> total (truncated: yes|no) | none",
+		summarize: summarizeTypeHierarchyDetail,
+	},
+	"ts_hover": {
+		kind:      "hover",
+		grammar:   "<first line of the type>[, <n> tags] | none",
+		summarize: summarizeHoverDetail,
+	},
+	"ts_hover_batch": {
+		kind:      "hover batch",
+		grammar:   "<n> positions, <n> typed, <n> failed | <n> lines rendered",
+		summarize: summarizeHoverBatchDetail,
+	},
+	"ts_hover_many": {
+		kind:      "hover many",
+		grammar:   "<n> positions in <n> files, <n> typed, <n> failed",
+		summarize: jsonSummary(summarizeHoverMany),
+	},
+	"ts_signature_help": {
+		kind:      "signature help",
+		grammar:   "<n> signatures, active <label>[, parameter <label>] | none",
+		summarize: summarizeSignatureHelpDetail,
+	},
+	"ts_inlay_hints": {
+		kind:      "inlay hints",
+		grammar:   "<n> of <total>[, <n> type][, <n> parameter] (truncated: yes|no)",
+		summarize: jsonSummary(summarizeInlayHints),
+	},
+	"ts_semantic_tokens": {
+		kind:      "semantic tokens",
+		grammar:   "<n> of <total>[, first <type> <text>] (truncated: yes|no)",
+		summarize: jsonSummary(summarizeSemanticTokens),
+	},
+	"ts_references": {
+		kind:      "references",
+		grammar:   "<total> total, <n> shown in <n> files (truncated: yes|no) | <total> total in <n> files, <n> shown[, most <file> x<n>] (truncated: yes|no)",
+		summarize: summarizeReferencesDetail,
+	},
+	"ts_document_highlights": {
+		kind:      "document highlights",
+		grammar:   "<n> occurrences: <n> read, <n> write[, <n> text]",
+		summarize: summarizeDocumentHighlightsDetail,
+	},
+	"ts_completion": {
+		kind:      "completion",
+		grammar:   "<n> of <total>[, first <label>] (truncated: yes|no)",
+		summarize: jsonSummary(summarizeCompletion),
+	},
+	"ts_document_symbols": {
+		kind:      "symbols",
+		grammar:   "<n> total, <n> top-level, <n> exported",
+		summarize: summarizeSymbolsDetail,
+	},
+	"ts_folding_ranges": {
+		kind:      "folding ranges",
+		grammar:   "<n> ranges[ (<n> <kind>, ...)]",
+		summarize: jsonSummary(summarizeFoldingRanges),
+	},
+	"ts_selection_range": {
+		kind:      "selection range",
+		grammar:   "<n> positions, <n> ranges[, innermost <preview>]",
+		summarize: jsonSummary(summarizeSelectionRange),
+	},
+	"ts_symbol_at_position": {
+		kind:      "symbol at position",
+		grammar:   "<breadcrumb> | none[, after <name>]",
+		summarize: jsonSummary(summarizeSymbolAtPosition),
+	},
+	"ts_workspace_symbols": {
+		kind:      "workspace symbols",
+		grammar:   "<n> of <total>[, first <kind> <name> at <file>:<line>] (truncated: yes|no)",
+		summarize: summarizeWorkspaceSymbolsDetail,
+	},
+	"ts_symbol_card": {
+		kind:      "symbol card",
+		grammar:   "<kind> <qualified name>[, exported][, deprecated][, <n> references][, <n> failed sections]",
+		summarize: jsonSummary(summarizeSymbolCard),
+	},
+	"ts_code_actions": {
+		kind:      "code actions",
+		grammar:   "<n> actions[, <n> preferred][, first <title>], <n> diagnostics in range",
+		summarize: jsonSummary(summarizeCodeActions),
+	},
+	"ts_apply_code_action": {
+		kind:      "apply code action",
+		grammar:   "<title>: <n> edits in <n> files[, <n> created][, <n> moved][, <n> deleted] | preview: <n> edits in <n> files, editToken <token> (expires in <duration>)",
+		summarize: summarizeApplyCodeActionDetail,
+	},
+	"ts_extract_refactor": {
+		kind:      "extract refactor",
+		grammar:   "<n> refactorings[, <n> disabled][, first <title>] | <title>: <n> edits in <n> files[, <n> created][, <n> moved][, <n> deleted][, named <name>] | preview: <n> edits in <n> files, editToken <token> (expires in <duration>)",
+		summarize: summarizeExtractRefactorDetail,
+	},
+	"ts_fix_all": {
+		kind:      "fix all",
+		grammar:   "TS<code>: <n> applied, <n> skipped of <n> diagnostics, <n> remaining[, <n> edits in <n> files] | preview: <n> edits in <n> files, editToken <token> (expires in <duration>)",
+		summarize: summarizeFixAllDetail,
+	},
+	"ts_format": {
+		kind:      "format",
+		grammar:   "<n> edits, changed|unchanged",
+		summarize: jsonSummary(summarizeFormat),
+	},
+	"ts_prepare_rename": {
+		kind:      "prepare rename",
+		grammar:   "<text> at <line>:<column> | cannot rename: <reason>",
+		summarize: jsonSummary(summarizePrepareRename),
+	},
+	"ts_rename": {
+		kind:      "rename",
+		grammar:   "<newName>: <n> edits in <n> files[, <n> created][, <n> moved][, <n> deleted][, <n> files skipped] | preview: <n> edits in <n> files, editToken <token> (expires in <duration>) | dry run: <n> edits in <n> files[, <n> diffs truncated]",
+		summarize: summarizeRenameDetail,
+	},
+	"ts_rename_file": {
+		kind:      "rename file",
+		grammar:   "<old file> -> <new file>: <n> edits in <n> files",
+		summarize: jsonSummary(summarizeRenameFile),
+	},
+	"ts_apply_edit": {
+		kind:      "apply edit",
+		grammar:   "<n> edits in <n> files[, <n> created][, <n> moved][, <n> deleted]",
+		summarize: jsonSummary(summarizeApplyEdit),
+	},
+	"ts_recover_pending_edit": {
+		kind:      "recover edit",
+		grammar:   "<n> pending | <action> <id>: <n> written, <n> unchanged",
+		summarize: summarizeRecoveryDetail,
+	},
+	"ts_list_edits": {
+		kind:      "edits",
+		grammar:   "<n> of <total> (truncated: yes|no, recording: on|off)",
+		summarize: jsonSummary(summarizeListEdits),
+	},
+	"ts_undo_last_edit": {
+		kind:      "undo",
+		grammar:   "<id> (<tool>[ <symbol>]) in <n> files",
+		summarize: jsonSummary(summarizeUndo),
+	},
+	"ts_project_info": {
+		kind:      "project",
+		grammar:   "<tsconfig> | no tsconfig[, extends <n> configs][, config error in <file>][, <n> source files][, tsgo <version>][, misconfigured]",
+		summarize: jsonSummary(summarizeProjectInfo),
+	},
+	"ts_list_projects": {
+		kind:      "projects",
+		grammar:   "<n> projects under <root>[, <file> owned by <config> | <file> not included, nearest <config> | <file> in no project]",
+		summarize: jsonSummary(summarizeListProjects),
+	},
+	"ts_project_coverage": {
+		kind:      "coverage",
+		grammar:   "<analyzed>/<project files> analyzed, <n> never analyzed, <n> analyzed but excluded (truncated: yes|no)",
+		summarize: jsonSummary(summarizeCoverage),
+	},
+	"ts_import_cycles": {
+		kind:      "import cycles",
+		grammar:   "<n> through <target>, <n> files scanned (truncated: yes|no)",
+		summarize: jsonSummary(summarizeImportCycles),
+	},
+	"ts_ambient_declarations": {
+		kind:      "ambient declarations",
+		grammar:   "<n> globals, <n> modules, <n> references in <n> files, <n> scanned",
+		summarize: jsonSummary(summarizeAmbient),
+	},
+	"ts_open_files": {
+		kind:      "open files",
+		grammar:   "<n> opened, <n> failed, <n> open[ of <max>]",
+		summarize: jsonSummary(summarizeOpenFiles),
+	},
+	"ts_close_files": {
+		kind:      "close files",
+		grammar:   "<n> closed, <n> skipped, <n> open",
+		summarize: jsonSummary(summarizeCloseFiles),
+	},
+	"ts_server_status": {
+		kind:      "server",
+		grammar:   "<starting|ready|failed>, <n> documents open[, tsgo <version>][, restarted <n> times][, <n> pending edits]",
+		summarize: jsonSummary(summarizeServerStatus),
+	},
+	"ts_server_info": {
+		kind:      "server info",
+		grammar:   "<name>[ <version>], <n> of <n> requests supported[, <n> tools unavailable]",
+		summarize: jsonSummary(summarizeServerInfo),
+	},
+}
+
+// summaryDescription is the sentence appended to the description of a
+// tool documenting its summary line.
+func summaryDescription(s toolSummary) string {
+	return fmt.Sprintf(" The first content item is a summary line: %q, or %q on failure; summaryOnly returns just that line.", s.kind+": "+s.grammar, s.kind+": error: <message>")
+}
+
+// jsonSummary summarizes a result whose detail, or structured content, is
+// the JSON of a T.
+func jsonSummary[T any](f func(T, summaryContext) string) func(summaryInput) (string, bool) {
+	return func(in summaryInput) (string, bool) {
+		var v T
+		if !decodeSummaryInput(in, &v) {
+			return "", false
+		}
+		return f(v, in.ctx), true
+	}
+}
+
+func decodeSummaryInput(in summaryInput, v any) bool {
+	data := []byte(in.detail)
+	if in.structured != nil {
+		var err error
+		if data, err = json.Marshal(in.structured); err != nil {
+			return false
+		}
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// withSummary prefixes every response of h with its summary line, and
+// with summaryOnly drops everything but the summary and the warnings.
+func withSummary(root, tool string, h server.ToolHandlerFunc) server.ToolHandlerFunc {
+	s, ok := toolSummaries[tool]
+	if !ok {
+		s = toolSummary{kind: strings.TrimPrefix(tool, "ts_")}
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := h(ctx, request)
+		if err != nil || result == nil {
+			return result, err
+		}
+		var warnings []mcp.Content
+		detail := ""
+		found := false
+		for _, c := range result.Content {
+			tc, isText := c.(mcp.TextContent)
+			switch {
+			case isText && strings.HasPrefix(tc.Text, "warning: "):
+				warnings = append(warnings, c)
+			case isText && !found:
+				detail, found = tc.Text, true
+			}
+		}
+		line := summaryLine(s, result, detail, summaryContext{root: root, file: request.GetString("file", "")})
+		if request.GetBool("summaryOnly", false) {
+			result.Content = append([]mcp.Content{mcp.NewTextContent(line)}, warnings...)
+			result.StructuredContent = nil
+			return result, nil
+		}
+		result.Content = append([]mcp.Content{mcp.NewTextContent(line)}, result.Content...)
+		return result, nil
+	}
+}
+
+// summaryLine renders the summary line of result, whose first text item
+// other than warnings is detail.
+func summaryLine(s toolSummary, result *mcp.CallToolResult, detail string, sc summaryContext) string {
+	if result.IsError {
+		var structured struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal([]byte(detail), &structured) == nil && structured.Error != "" {
+			detail = structured.Error
+		}
+		return s.kind + ": error: " + summaryText(detail)
+	}
+	if s.summarize != nil {
+		if line, ok := s.summarize(summaryInput{detail: detail, structured: result.StructuredContent, ctx: sc}); ok {
+			return s.kind + ": " + line
+		}
+	}
+	return s.kind + ": ok"
+}
+
+// summaryText returns the first line of s, shortened to maxSummaryText.
+func summaryText(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > maxSummaryText {
+		s = string(r[:maxSummaryText]) + "…"
+	}
+	return s
+}
+
+// plural returns "<n> <noun>", adding an "s" unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func summarizeDiagnostics(r diagnosticsResult, sc summaryContext) string {
+	var errs, warns, other int
+	for _, d := range r.Diagnostics {
+		switch d.Severity {
+		case "error":
+			errs++
+		case "warning":
+			warns++
+		default:
+			other++
+		}
+	}
+	line := plural(errs, "error") + ", " + plural(warns, "warning")
+	if other > 0 {
+		line += fmt.Sprintf(", %d other", other)
+	}
+	if sc.file != "" {
+		line += " in " + sc.rel(sc.file)
+	}
+	return line + diagnosticsNote(r.Truncated, r.TotalCount, r.FilteredOut, r.AnalysisIncomplete)
+}
+
+// diagnosticsNote ends a diagnostics summary line.
+func diagnosticsNote(truncated bool, total, filteredOut int, incomplete bool) string {
+	note := " (truncated: no"
+	if truncated {
+		note = fmt.Sprintf(" (truncated: yes, %d total", total)
+	}
+	if filteredOut > 0 {
+		note += fmt.Sprintf(", %d filtered out", filteredOut)
+	}
+	if incomplete {
+		note += ", analysis incomplete"
+	}
+	return note + ")"
+}
+
+func summarizeMultiDiagnostics(r multiDiagnosticsResult, _ summaryContext) string {
+	line := plural(r.Errors, "error") + ", " + plural(r.Warnings, "warning")
+	if r.Other > 0 {
+		line += fmt.Sprintf(", %d other", r.Other)
+	}
+	line += " in " + plural(len(r.Files), "file")
+	failed, truncated, incomplete := 0, false, false
+	for _, f := range r.Files {
+		if f.Error != "" {
+			failed++
+		}
+		truncated = truncated || f.Truncated
+		incomplete = incomplete || f.AnalysisIncomplete
+	}
+	if failed > 0 {
+		line += fmt.Sprintf(", %d failed", failed)
+	}
+	return line + diagnosticsNote(truncated, r.TotalCount, r.FilteredOut, incomplete)
+}
+
+func summarizeGroupedDiagnostics(r groupedDiagnosticsResult, _ summaryContext) string {
+	line := plural(r.Errors, "error") + ", " + plural(r.Warnings, "warning")
+	if r.Other > 0 {
+		line += fmt.Sprintf(", %d other", r.Other)
+	}
+	line += " in " + plural(len(r.Groups), "code")
+	if r.Truncated {
+		line += " shown"
+	}
+	if len(r.Groups) > 0 {
+		line += fmt.Sprintf(", most %s x%d", r.Groups[0].Code, r.Groups[0].Count)
+	}
+	if r.AnalysisIncomplete {
+		line += " (analysis incomplete)"
+	}
+	return line
+}
+
+// summarizeDiagnosticsDetail tells grouped and multi-file results from a
+// page of one file's diagnostics.
+func summarizeDiagnosticsDetail(in summaryInput) (string, bool) {
+	var probe struct {
+		Files  json.RawMessage `json:"files"`
+		Groups json.RawMessage `json:"groups"`
+	}
+	if !decodeSummaryInput(in, &probe) {
+		return "", false
+	}
+	if probe.Groups != nil {
+		return jsonSummary(summarizeGroupedDiagnostics)(in)
+	}
+	if probe.Files != nil {
+		return jsonSummary(summarizeMultiDiagnostics)(in)
+	}
+	return jsonSummary(summarizeDiagnostics)(in)
+}
+
+func summarizeProjectDiagnostics(r projectDiagnosticsResult, _ summaryContext) string {
+	line := plural(r.Errors, "error") + ", " + plural(r.Warnings, "warning")
+	if other := r.Information + r.Hints; other > 0 {
+		line += fmt.Sprintf(", %d other", other)
+	}
+	line += " in " + plural(r.FilesWithDiagnostics, "file")
+	line += fmt.Sprintf(" of %d checked", r.FilesChecked)
+	if r.TimedOut {
+		line += fmt.Sprintf(" of %d, timed out", r.FilesTotal)
+	}
+	return line + " (" + r.Elapsed + ")"
+}
+
+func summarizeDefinition(entries []definitionEntry, sc summaryContext) string {
+	line := plural(len(entries), "location")
+	if len(entries) > 0 {
+		e := entries[0]
+		line += fmt.Sprintf(", first %s:%d:%d", sc.rel(e.File), e.Line, e.Column)
+	}
+	return line
+}
+
+// summarizeDefinitionDetail also covers the "No definition found" answer.
+func summarizeDefinitionDetail(in summaryInput) (string, bool) {
+	if in.detail == "No definition found" {
+		return summarizeDefinition(nil, in.ctx), true
+	}
+	return jsonSummary(summarizeDefinition)(in)
+}
+
+// summarizeTypeDefinitionDetail also covers the "No type definition
+// found" answer.
+func summarizeTypeDefinitionDetail(in summaryInput) (string, bool) {
+	if in.detail == noTypeDefinition {
+		return summarizeDefinition(nil, in.ctx), true
+	}
+	return jsonSummary(summarizeDefinition)(in)
+}
+
+// summarizeImplementationsDetail also covers the "No implementations
+// found" answer.
+func summarizeImplementationsDetail(in summaryInput) (string, bool) {
+	if in.detail == noImplementations {
+		return summarizeDefinition(nil, in.ctx), true
+	}
+	return jsonSummary(summarizeDefinition)(in)
+}
+
+func summarizeCallHierarchy(r callHierarchyResult, _ summaryContext) string {
+	if len(r.Roots) == 0 {
+		return "none"
+	}
+	direct, total, recursive := 0, 0, 0
+	var count func(n callHierarchyNode)
+	count = func(n callHierarchyNode) {
+		for _, c := range n.Calls {
+			total++
+			if c.Recursive {
+				recursive++
+			}
+			count(c)
+		}
+	}
+	for _, root := range r.Roots {
+		direct += len(root.Calls)
+		count(root)
+	}
+	line := fmt.Sprintf("%s calls of %s: %d direct, %d total", r.Direction, r.Roots[0].Name, direct, total)
+	if recursive > 0 {
+		line += fmt.Sprintf(", %d recursive", recursive)
+	}
+	return line + " (truncated: " + yesNo(r.Truncated) + ")"
+}
+
+// summarizeCallHierarchyDetail also covers the answer for a position
+// without a function.
+func summarizeCallHierarchyDetail(in summaryInput) (string, bool) {
+	if in.detail == noCallHierarchy {
+		return "none", true
+	}
+	return jsonSummary(summarizeCallHierarchy)(in)
+}
+
+func summarizeTypeHierarchy(r typeHierarchyResult, _ summaryContext) string {
+	if len(r.Roots) == 0 {
+		return "none"
+	}
+	direct, total := 0, 0
+	var count func(n typeHierarchyNode)
+	count = func(n typeHierarchyNode) {
+		for _, c := range n.Types {
+			total++
+			count(c)
+		}
+	}
+	for _, root := range r.Roots {
+		direct += len(root.Types)
+		count(root)
+	}
+	return fmt.Sprintf("%s of %s: %d direct, %d total (truncated: %s)", r.Direction, r.Roots[0].Name, direct, total, yesNo(r.Truncated))
+}
+
+// summarizeTypeHierarchyDetail also covers the answer for a position
+// without a class or interface.
+func summarizeTypeHierarchyDetail(in summaryInput) (string, bool) {
+	if in.detail == noTypeHierarchy {
+		return "none", true
+	}
+	return jsonSummary(summarizeTypeHierarchy)(in)
+}
+
+// summarizeHover returns the first line of the type in a hover, or "none".
+func summarizeHover(detail string) string {
+	if detail == "" || detail == "No type information available" {
+		return "none"
+	}
+	for _, l := range strings.Split(detail, "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "```") && !strings.HasPrefix(l, "(no type information at column") {
+			return summaryText(l)
+		}
+	}
+	return "none"
+}
+
+func summarizeFullHover(h fullHover, _ summaryContext) string {
+	line := summarizeHover(h.Signature)
+	if h.Signature == "" {
+		line = summarizeHover(h.Documentation)
+	}
+	if len(h.Tags) > 0 {
+		line += ", " + plural(len(h.Tags), "tag")
+	}
+	return line
+}
+
+// summarizeHoverDetail summarizes a full hover, or the text of a concise
+// one.
+func summarizeHoverDetail(in summaryInput) (string, bool) {
+	if line, ok := jsonSummary(summarizeFullHover)(in); ok {
+		return line, true
+	}
+	return summarizeHover(in.detail), true
+}
+
+func summarizeHoverBatch(r hoverBatchResult, _ summaryContext) string {
+	var typed, failed int
+	for _, e := range r.Results {
+		if e.Error != "" {
+			failed++
+		} else {
+			typed++
+		}
+	}
+	return fmt.Sprintf("%s, %d typed, %d failed", plural(len(r.Results), "position"), typed, failed)
+}
+
+// summarizeHoverBatchDetail summarizes the JSON result, or the lines of
+// a rendered one.
+func summarizeHoverBatchDetail(in summaryInput) (string, bool) {
+	if line, ok := jsonSummary(summarizeHoverBatch)(in); ok {
+		return line, true
+	}
+	return plural(strings.Count(in.detail, "\n"), "line") + " rendered", true
+}
+
+func summarizeHoverMany(r hoverManyResult, _ summaryContext) string {
+	var typed, failed int
+	files := make(map[string]bool)
+	for _, e := range r.Results {
+		files[e.File] = true
+		if e.Error != "" {
+			failed++
+		} else {
+			typed++
+		}
+	}
+	return fmt.Sprintf("%s in %s, %d typed, %d failed", plural(len(r.Results), "position"), plural(len(files), "file"), typed, failed)
+}
+
+func summarizeSignatureHelp(r signatureHelpResult, _ summaryContext) string {
+	if len(r.Signatures) == 0 || r.ActiveSignature >= len(r.Signatures) {
+		return "none"
+	}
+	sig := r.Signatures[r.ActiveSignature]
+	line := fmt.Sprintf("%s, active %s", plural(len(r.Signatures), "signature"), summaryText(sig.Label))
+	if r.ActiveParameter < len(sig.Parameters) {
+		line += ", parameter " + sig.Parameters[r.ActiveParameter].Label
+	}
+	return line
+}
+
+// summarizeSignatureHelpDetail also covers the "No signature help
+// available" answer.
+func summarizeSignatureHelpDetail(in summaryInput) (string, bool) {
+	if in.detail == "No signature help available" {
+		return "none", true
+	}
+	return jsonSummary(summarizeSignatureHelp)(in)
+}
+
+func summarizeReferences(r referencesResult, _ summaryContext) string {
+	files := make(map[string]bool)
+	for _, ref := range r.References {
+		files[ref.File] = true
+	}
+	return fmt.Sprintf("%d total, %d shown in %s (truncated: %s)", r.TotalCount, len(r.References), plural(len(files), "file"), yesNo(r.Truncated))
+}
+
+func summarizeGroupedReferences(r groupedReferencesResult, sc summaryContext) string {
+	line := fmt.Sprintf("%d total in %s, %d shown", r.TotalCount, plural(r.FileCount, "file"), len(r.Files))
+	if len(r.Files) > 0 {
+		line += fmt.Sprintf(", most %s x%d", sc.rel(r.Files[0].File), r.Files[0].Count)
+	}
+	return line + fmt.Sprintf(" (truncated: %s)", yesNo(r.Truncated))
+}
+
+// summarizeReferencesDetail tells a groupByFile result from a page of
+// references.
+func summarizeReferencesDetail(in summaryInput) (string, bool) {
+	var probe struct {
+		Files json.RawMessage `json:"files"`
+	}
+	if !decodeSummaryInput(in, &probe) {
+		return "", false
+	}
+	if probe.Files != nil {
+		return jsonSummary(summarizeGroupedReferences)(in)
+	}
+	return jsonSummary(summarizeReferences)(in)
+}
+
+func summarizeCompletion(r completionResult, _ summaryContext) string {
+	line := fmt.Sprintf("%d of %d", len(r.Completions), r.TotalCount)
+	if len(r.Completions) > 0 {
+		line += ", first " + r.Completions[0].Label
+	}
+	return line + fmt.Sprintf(" (truncated: %s)", yesNo(r.Truncated))
+}
+
+func summarizeSymbols(entries []symbolEntry, _ summaryContext) string {
+	var total, exported int
+	var walk func([]symbolEntry)
+	walk = func(es []symbolEntry) {
+		for _, e := range es {
+			total++
+			if e.Exported {
+				exported++
+			}
+			walk(e.Children)
+		}
+	}
+	walk(entries)
+	return fmt.Sprintf("%d total, %d top-level, %d exported", total, len(entries), exported)
+}
+
+// summarizeSymbolsDetail also covers the "No symbols found" answers.
+func summarizeSymbolsDetail(in summaryInput) (string, bool) {
+	if strings.HasPrefix(in.detail, "No ") && strings.HasSuffix(in.detail, "symbols found") {
+		return summarizeSymbols(nil, in.ctx), true
+	}
+	return jsonSummary(summarizeSymbols)(in)
+}
+
+func summarizeWorkspaceSymbols(r workspaceSymbolsResult, sc summaryContext) string {
+	line := fmt.Sprintf("%d of %d", len(r.Symbols), r.TotalCount)
+	if len(r.Symbols) > 0 {
+		s := r.Symbols[0]
+		line += fmt.Sprintf(", first %s %s at %s:%d", s.Kind, s.Name, sc.rel(s.File), s.Line)
+	}
+	return line + fmt.Sprintf(" (truncated: %s)", yesNo(r.Truncated))
+}
+
+// summarizeWorkspaceSymbolsDetail also covers the "No symbols found"
+// answer.
+func summarizeWorkspaceSymbolsDetail(in summaryInput) (string, bool) {
+	if strings.HasPrefix(in.detail, "No symbols found") {
+		return summarizeWorkspaceSymbols(workspaceSymbolsResult{}, in.ctx), true
+	}
+	return jsonSummary(summarizeWorkspaceSymbols)(in)
+}
+
+func summarizeSymbolCard(c symbolCard, _ summaryContext) string {
+	name := c.QualifiedName
+	if name == "" {
+		name = c.Name
+	}
+	line := strings.TrimSpace(c.Kind + " " + name)
+	if c.Exported != nil && *c.Exported {
+		line += ", exported"
+	}
+	if c.Deprecated {
+		line += ", deprecated"
+	}
+	if c.References != nil {
+		line += ", " + plural(c.References.Total, "reference")
+	}
+	if len(c.Errors) > 0 {
+		line += ", " + plural(len(c.Errors), "failed section")
+	}
+	return line
+}
+
+func summarizePrepareRename(r prepareRenameResult, _ summaryContext) string {
+	if !r.CanRename {
+		return "cannot rename: " + r.Reason
+	}
+	text := r.Text
+	if text == "" {
+		text = "symbol"
+	}
+	return fmt.Sprintf("%s at %d:%d", text, r.Line, r.Column)
+}
+
+func summarizeDocumentHighlights(r documentHighlightsResult, _ summaryContext) string {
+	counts := map[string]int{}
+	for _, h := range r.Highlights {
+		counts[h.Kind]++
+	}
+	line := fmt.Sprintf("%s: %d read, %d write", plural(len(r.Highlights), "occurrence"), counts["read"], counts["write"])
+	if counts["text"] > 0 {
+		line += fmt.Sprintf(", %d text", counts["text"])
+	}
+	return line
+}
+
+// summarizeDocumentHighlightsDetail also covers the "No occurrences
+// found" answer.
+func summarizeDocumentHighlightsDetail(in summaryInput) (string, bool) {
+	if in.detail == noHighlights {
+		return summarizeDocumentHighlights(documentHighlightsResult{}, in.ctx), true
+	}
+	return jsonSummary(summarizeDocumentHighlights)(in)
+}
+
+func summarizeInlayHints(r inlayHintsResult, _ summaryContext) string {
+	line := fmt.Sprintf("%d of %d", len(r.Hints), r.TotalCount)
+	types, params := 0, 0
+	for _, h := range r.Hints {
+		switch h.Kind {
+		case "type":
+			types++
+		case "parameter":
+			params++
+		}
+	}
+	if types > 0 {
+		line += fmt.Sprintf(", %d type", types)
+	}
+	if params > 0 {
+		line += fmt.Sprintf(", %d parameter", params)
+	}
+	return line + " (truncated: " + yesNo(r.Truncated) + ")"
+}
+
+func summarizeSemanticTokens(r semanticTokensResult, _ summaryContext) string {
+	line := fmt.Sprintf("%d of %d", len(r.Tokens), r.TotalCount)
+	if len(r.Tokens) > 0 {
+		line += fmt.Sprintf(", first %s %s", r.Tokens[0].Type, r.Tokens[0].Text)
+	}
+	return line + " (truncated: " + yesNo(r.Truncated) + ")"
+}
+
+func summarizeSelectionRange(r selectionRangeResult, _ summaryContext) string {
+	ranges := 0
+	for _, s := range r.Selections {
+		ranges += len(s.Ranges)
+	}
+	line := fmt.Sprintf("%s, %s", plural(len(r.Selections), "position"), plural(ranges, "range"))
+	if len(r.Selections) > 0 && len(r.Selections[0].Ranges) > 0 {
+		line += ", innermost " + summaryText(r.Selections[0].Ranges[0].Preview)
+	}
+	return line
+}
+
+func summarizeSymbolAtPosition(r symbolAtPositionResult, _ summaryContext) string {
+	if r.Breadcrumb != "" {
+		return summaryText(r.Breadcrumb)
+	}
+	if r.NearestPreceding != nil {
+		return "none, after " + r.NearestPreceding.Name
+	}
+	return "none"
+}
+
+func summarizeFoldingRanges(r foldingRangesResult, _ summaryContext) string {
+	line := plural(len(r.Ranges), "range")
+	counts := map[string]int{}
+	for _, e := range r.Ranges {
+		counts[e.Kind]++
+	}
+	var parts []string
+	for _, k := range foldingKinds {
+		if counts[k] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[k], k))
+		}
+	}
+	if len(parts) > 0 {
+		line += " (" + strings.Join(parts, ", ") + ")"
+	}
+	return line
+}
+
+func summarizeCodeActions(r codeActionsResult, _ summaryContext) string {
+	line := plural(len(r.Actions), "action")
+	preferred := 0
+	for _, a := range r.Actions {
+		if a.IsPreferred {
+			preferred++
+		}
+	}
+	if preferred > 0 {
+		line += fmt.Sprintf(", %d preferred", preferred)
+	}
+	if len(r.Actions) > 0 {
+		line += ", first " + summaryText(r.Actions[0].Title)
+	}
+	return line + ", " + plural(r.Diagnostics, "diagnostic") + " in range"
+}
+
+// editCounts renders the edits and files of an applied edit.
+func editCounts(total int, changes []editInfo) string {
+	line := fmt.Sprintf("%s in %s", plural(total, "edit"), plural(len(changes), "file"))
+	created, moved, deleted := 0, 0, 0
+	for _, c := range changes {
+		switch {
+		case c.Created:
+			created++
+		case c.RenamedFrom != "":
+			// The old path is listed too, as deleted.
+			moved++
+			deleted--
+		case c.Deleted:
+			deleted++
+		}
+	}
+	for _, n := range []struct {
+		count int
+		what  string
+	}{{created, "created"}, {moved, "moved"}, {deleted, "deleted"}} {
+		if n.count > 0 {
+			line += fmt.Sprintf(", %d %s", n.count, n.what)
+		}
+	}
+	return line
+}
+
+func summarizeRename(r renameResult, _ summaryContext) string {
+	line := r.NewName + ": " + editCounts(r.TotalEdits, r.Changes)
+	if len(r.SkippedFiles) > 0 {
+		line += ", " + plural(len(r.SkippedFiles), "file") + " skipped"
+	}
+	return line
+}
+
+func summarizeEditPreview(r editPreviewResult, _ summaryContext) string {
+	return fmt.Sprintf("preview: %s in %s, editToken %s (expires in %s)", plural(r.TotalEdits, "edit"), plural(len(r.Changes), "file"), r.EditToken, r.ExpiresIn)
+}
+
+func summarizeEditDryRun(r editDryRunResult, _ summaryContext) string {
+	line := fmt.Sprintf("dry run: %s in %s", plural(r.TotalEdits, "edit"), plural(len(r.Changes), "file"))
+	truncated := 0
+	for _, c := range r.Changes {
+		if c.DiffTruncated {
+			truncated++
+		}
+	}
+	if truncated > 0 {
+		line += ", " + plural(truncated, "diff") + " truncated"
+	}
+	return line
+}
+
+// summarizeRenameDetail tells a preview, which has an editToken, and a
+// dry run from an applied rename.
+func summarizeRenameDetail(in summaryInput) (string, bool) {
+	var probe struct {
+		EditToken string `json:"editToken"`
+		DryRun    bool   `json:"dryRun"`
+	}
+	if !decodeSummaryInput(in, &probe) {
+		return "", false
+	}
+	switch {
+	case probe.EditToken != "":
+		return jsonSummary(summarizeEditPreview)(in)
+	case probe.DryRun:
+		return jsonSummary(summarizeEditDryRun)(in)
+	}
+	return jsonSummary(summarizeRename)(in)
+}
+
+func summarizeApplyCodeAction(r applyCodeActionResult, _ summaryContext) string {
+	return summaryText(r.Title) + ": " + editCounts(r.TotalEdits, r.Changes)
+}
+
+// summarizeApplyCodeActionDetail tells a preview from an applied action.
+func summarizeApplyCodeActionDetail(in summaryInput) (string, bool) {
+	var probe struct {
+		EditToken string `json:"editToken"`
+	}
+	if !decodeSummaryInput(in, &probe) {
+		return "", false
+	}
+	if probe.EditToken != "" {
+		return jsonSummary(summarizeEditPreview)(in)
+	}
+	return jsonSummary(summarizeApplyCodeAction)(in)
+}
+
+func summarizeExtractRefactorings(r extractRefactoringsResult, _ summaryContext) string {
+	line := plural(len(r.Refactorings), "refactoring")
+	disabled := 0
+	for _, e := range r.Refactorings {
+		if e.Disabled != "" {
+			disabled++
+		}
+	}
+	if disabled > 0 {
+		line += fmt.Sprintf(", %d disabled", disabled)
+	}
+	if len(r.Refactorings) > 0 {
+		line += ", first " + summaryText(r.Refactorings[0].Title)
+	}
+	return line
+}
+
+func summarizeExtractRefactor(r extractRefactorResult, _ summaryContext) string {
+	line := summaryText(r.Title) + ": " + editCounts(r.TotalEdits, r.Changes)
+	if r.Name != "" {
+		line += ", named " + r.Name
+	}
+	return line
+}
+
+// summarizeExtractRefactorDetail tells a listing, a preview and an applied
+// refactoring apart.
+func summarizeExtractRefactorDetail(in summaryInput) (string, bool) {
+	var probe struct {
+		EditToken    string          `json:"editToken"`
+		Refactorings json.RawMessage `json:"refactorings"`
+	}
+	if !decodeSummaryInput(in, &probe) {
+		return "", false
+	}
+	switch {
+	case probe.EditToken != "":
+		return jsonSummary(summarizeEditPreview)(in)
+	case probe.Refactorings != nil:
+		return jsonSummary(summarizeExtractRefactorings)(in)
+	}
+	return jsonSummary(summarizeExtractRefactor)(in)
+}
+
+func summarizeFixAll(r fixAllResult, _ summaryContext) string {
+	line := fmt.Sprintf("TS%d: %d applied, %d skipped of %s, %d remaining", r.Code, r.Applied, len(r.Skipped), plural(r.Diagnostics, "diagnostic"), r.Remaining)
+	if len(r.Changes) > 0 {
+		line += ", " + editCounts(r.TotalEdits, r.Changes)
+	}
+	return line
+}
+
+// summarizeFixAllDetail tells a preview from applied fixes.
+func summarizeFixAllDetail(in summaryInput) (string, bool) {
+	var probe struct {
+		EditToken string `json:"editToken"`
+	}
+	if !decodeSummaryInput(in, &probe) {
+		return "", false
+	}
+	if probe.EditToken != "" {
+		return jsonSummary(summarizeEditPreview)(in)
+	}
+	return jsonSummary(summarizeFixAll)(in)
+}
+
+func summarizeFormat(r formatResult, _ summaryContext) string {
+	if r.Changed {
+		return plural(r.Edits, "edit") + ", changed"
+	}
+	return plural(r.Edits, "edit") + ", unchanged"
+}
+
+func summarizeRenameFile(r renameFileResult, sc summaryContext) string {
+	return sc.rel(r.OldPath) + " -> " + sc.rel(r.NewPath) + ": " + editCounts(r.TotalEdits, r.Changes)
+}
+
+func summarizeApplyEdit(r applyEditResult, _ summaryContext) string {
+	return editCounts(r.TotalEdits, r.Changes)
+}
+
+func summarizeRecovery(r recoveryResult, _ summaryContext) string {
+	return fmt.Sprintf("%s %s: %d written, %d unchanged", r.Action, r.ID, len(r.Written), r.Unchanged)
+}
+
+// summarizeRecoveryDetail tells the list of pending edits from the result
+// of a recovery action.
+func summarizeRecoveryDetail(in summaryInput) (string, bool) {
+	var list struct {
+		Pending *[]pendingJournal `json:"pending"`
+	}
+	if !decodeSummaryInput(in, &list) {
+		return "", false
+	}
+	if list.Pending != nil {
+		return fmt.Sprintf("%d pending", len(*list.Pending)), true
+	}
+	return jsonSummary(summarizeRecovery)(in)
+}
+
+func summarizeListEdits(r listEditsResult, _ summaryContext) string {
+	recording := "off"
+	if r.Recording {
+		recording = "on"
+	}
+	return fmt.Sprintf("%d of %d (truncated: %s, recording: %s)", len(r.Edits), r.TotalCount, yesNo(r.Truncated), recording)
+}
+
+func summarizeUndo(r undoEditResult, _ summaryContext) string {
+	origin := r.Undone.Tool
+	if r.Undone.Symbol != "" {
+		origin += " " + r.Undone.Symbol
+	}
+	return fmt.Sprintf("%s (%s) in %s", r.Undone.ID, origin, plural(len(r.Undone.Files), "file"))
+}
+
+func summarizeProjectInfo(r projectInfoResult, sc summaryContext) string {
+	line := "no tsconfig"
+	if r.TsconfigPath != "" {
+		line = sc.rel(r.TsconfigPath)
+	}
+	if r.Config != nil && len(r.Config.Bases) > 0 {
+		line += ", extends " + plural(len(r.Config.Bases), "config")
+	}
+	if r.ConfigError != nil {
+		line += ", config error in " + sc.rel(r.ConfigError.File)
+	}
+	if r.SourceFiles != nil {
+		line += ", " + plural(r.SourceFiles.Count, "source file")
+	}
+	if r.TsgoVersion != "" {
+		line += ", tsgo " + r.TsgoVersion
+	}
+	if r.Misconfiguration != nil {
+		line += ", misconfigured"
+	}
+	return line
+}
+
+func summarizeListProjects(r listProjectsResult, sc summaryContext) string {
+	line := fmt.Sprintf("%s under %s", plural(len(r.Projects), "project"), sc.rel(r.Root))
+	switch o := r.Owner; {
+	case o == nil:
+	case o.Config == "":
+		line += fmt.Sprintf(", %s in no project", sc.rel(o.File))
+	case o.Included:
+		line += fmt.Sprintf(", %s owned by %s", sc.rel(o.File), sc.rel(o.Config))
+	default:
+		line += fmt.Sprintf(", %s not included, nearest %s", sc.rel(o.File), sc.rel(o.Config))
+	}
+	return line
+}
+
+func summarizeCoverage(r projectCoverageResult, _ summaryContext) string {
+	return fmt.Sprintf("%d/%d analyzed, %d never analyzed, %d analyzed but excluded (truncated: %s)",
+		r.AnalyzedFiles, r.ProjectFiles, len(r.NeverAnalyzed), len(r.AnalyzedButExcluded), yesNo(r.Truncated))
+}
+
+func summarizeImportCycles(r importCyclesResult, sc summaryContext) string {
+	return fmt.Sprintf("%d through %s, %d files scanned (truncated: %s)", len(r.Cycles), sc.rel(r.Target), r.FilesScanned, yesNo(r.Truncated))
+}
+
+func summarizeAmbient(r ambientDeclarationsResult, _ summaryContext) string {
+	var globals, modules, refs int
+	for _, f := range r.Files {
+		globals += len(f.Globals)
+		modules += len(f.Modules)
+		refs += len(f.References)
+	}
+	return fmt.Sprintf("%s, %s, %s in %s, %d scanned", plural(globals, "global"), plural(modules, "module"), plural(refs, "reference"), plural(len(r.Files), "file"), r.FilesScanned)
+}
+
+func summarizeOpenFiles(r openFilesResult, _ summaryContext) string {
+	var opened, failed int
+	for _, f := range r.Files {
+		if f.OK {
+			opened++
+		} else {
+			failed++
+		}
+	}
+	line := fmt.Sprintf("%d opened, %d failed, %d open", opened, failed, r.OpenCount)
+	if r.MaxOpen > 0 {
+		line += fmt.Sprintf(" of %d", r.MaxOpen)
+	}
+	return line
+}
+
+func summarizeCloseFiles(r closeFilesResult, _ summaryContext) string {
+	return fmt.Sprintf("%d closed, %d skipped, %d open", len(r.Closed), len(r.Skipped), r.OpenCount)
+}
+
+func summarizeServerStatus(r serverStatusResult, _ summaryContext) string {
+	line := fmt.Sprintf("%s, %s open", r.Startup.State, plural(r.OpenCount, "document"))
+	if r.TsgoVersion != "" {
+		line += ", tsgo " + r.TsgoVersion
+	}
+	if r.Startup.Restarts > 0 {
+		line += ", restarted " + plural(r.Startup.Restarts, "time")
+	}
+	if len(r.PendingEdits) > 0 {
+		line += ", " + plural(len(r.PendingEdits), "pending edit")
+	}
+	return line
+}
+
+func summarizeServerInfo(r serverInfoResult, _ summaryContext) string {
+	line := r.Name
+	if line == "" {
+		line = "tsgo"
+	}
+	if r.Version != "" {
+		line += " " + r.Version
+	}
+	supported := 0
+	for _, c := range r.Capabilities {
+		if c.Supported {
+			supported++
+		}
+	}
+	line += fmt.Sprintf(", %d of %s supported", supported, plural(len(r.Capabilities), "request"))
+	if len(r.UnavailableTools) > 0 {
+		line += ", " + plural(len(r.UnavailableTools), "tool") + " unavailable"
+	}
+	return line
+}
@@ -0,0 +1,600 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+func TestSummarizers(t *testing.T) {
+	sc := summaryContext{root: "/p", file: "/p/src/errors.ts"}
+	exported := true
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "diagnostics",
+			got: summarizeDiagnostics(diagnosticsResult{Diagnostics: []diagnosticEntry{
+				{Severity: "error"}, {Severity: "warning"}, {Severity: "error"},
+			}, TotalCount: 3}, sc),
+			want: "2 errors, 1 warning in src/errors.ts (truncated: no)",
+		},
+		{
+			name: "diagnostics truncated, without a file",
+			got: summarizeDiagnostics(diagnosticsResult{Diagnostics: []diagnosticEntry{
+				{Severity: "error"}, {Severity: "hint"},
+			}, TotalCount: 9, Truncated: true}, summaryContext{root: "/p"}),
+			want: "1 error, 0 warnings, 1 other (truncated: yes, 9 total)",
+		},
+		{
+			name: "definition",
+			got:  summarizeDefinition([]definitionEntry{{File: "/p/src/index.ts", Line: 3, Column: 17}, {File: "/elsewhere/b.ts"}}, sc),
+			want: "2 locations, first src/index.ts:3:17",
+		},
+		{
+			name: "no definition",
+			got:  summarizeDefinition(nil, sc),
+			want: "0 locations",
+		},
+		{
+			name: "hover",
+			got:  summarizeHover("(no type information at column 4; showing the JSDoc type name at column 9)\n```typescript\nfunction greet(name: string): string\n```"),
+			want: "function greet(name: string): string",
+		},
+		{
+			name: "no hover",
+			got:  summarizeHover("No type information available"),
+			want: "none",
+		},
+		{
+			name: "full hover",
+			got: summarizeFullHover(fullHover{
+				Signature: "function greet(name: string): string",
+				Tags:      []hoverTag{{Tag: "param", Name: "name"}, {Tag: "returns"}},
+			}, sc),
+			want: "function greet(name: string): string, 2 tags",
+		},
+		{
+			name: "full hover without a signature",
+			got:  summarizeFullHover(fullHover{Documentation: "Some docs.\n\nMore.", Tags: []hoverTag{}}, sc),
+			want: "Some docs.",
+		},
+		{
+			name: "hover batch",
+			got:  summarizeHoverBatch(hoverBatchResult{Results: []hoverBatchEntry{{Type: "a"}, {Error: "boom"}, {Type: "b"}}}, sc),
+			want: "3 positions, 2 typed, 1 failed",
+		},
+		{
+			name: "hover many",
+			got: summarizeHoverMany(hoverManyResult{Results: []hoverManyEntry{
+				{File: "/p/a.ts", Type: "a"}, {File: "/p/b.ts", Error: "sync error: boom"}, {File: "/p/a.ts", Type: "b"},
+			}}, sc),
+			want: "3 positions in 2 files, 2 typed, 1 failed",
+		},
+		{
+			name: "signature help",
+			got: summarizeSignatureHelp(signatureHelpResult{Signatures: []signatureEntry{
+				{Label: "greet(name: string): string", Parameters: []signatureParameter{{Label: "name: string"}}},
+				{Label: "greet(): string"},
+			}}, sc),
+			want: "2 signatures, active greet(name: string): string, parameter name: string",
+		},
+		{
+			name: "references",
+			got: summarizeReferences(referencesResult{References: []referenceEntry{
+				{File: "/p/a.ts"}, {File: "/p/a.ts"}, {File: "/p/b.ts"},
+			}, TotalCount: 12, Truncated: true}, sc),
+			want: "12 total, 3 shown in 2 files (truncated: yes)",
+		},
+		{
+			name: "references by file",
+			got: summarizeGroupedReferences(groupedReferencesResult{Files: []referenceFileGroup{
+				{File: "/p/src/b.ts", Count: 180}, {File: "/p/src/a.ts", Count: 20},
+			}, FileCount: 40, TotalCount: 300, Truncated: true}, sc),
+			want: "300 total in 40 files, 2 shown, most src/b.ts x180 (truncated: yes)",
+		},
+		{
+			name: "completion",
+			got: summarizeCompletion(completionResult{Completions: []completionEntry{
+				{Label: "greet"}, {Label: "greeting"},
+			}, TotalCount: 7, Truncated: true}, sc),
+			want: "2 of 7, first greet (truncated: yes)",
+		},
+		{
+			name: "symbols",
+			got: summarizeSymbols([]symbolEntry{
+				{Exported: true, Children: []symbolEntry{{}, {}}},
+				{},
+			}, sc),
+			want: "4 total, 2 top-level, 1 exported",
+		},
+		{
+			name: "workspace symbols",
+			got: summarizeWorkspaceSymbols(workspaceSymbolsResult{Symbols: []workspaceSymbolEntry{
+				{Name: "Runner", Kind: "class", File: "/p/src/run.ts", Line: 9},
+			}, TotalCount: 1}, sc),
+			want: "1 of 1, first class Runner at src/run.ts:9 (truncated: no)",
+		},
+		{
+			name: "symbol card",
+			got: summarizeSymbolCard(symbolCard{
+				Name: "greet", QualifiedName: "Greeter.greet", Kind: "method", Exported: &exported,
+				References: &cardReferences{Total: 1}, Errors: map[string]string{"signature": "timeout"},
+			}, sc),
+			want: "method Greeter.greet, exported, 1 reference, 1 failed section",
+		},
+		{
+			name: "rename",
+			got: summarizeRename(renameResult{NewName: "repository", TotalEdits: 14, Changes: []editInfo{
+				{Edits: 8}, {Edits: 5}, {Edits: 1, Created: true},
+			}}, sc),
+			want: "repository: 14 edits in 3 files, 1 created",
+		},
+		{
+			name: "rename with skipped files",
+			got: summarizeRename(renameResult{NewName: "repository", TotalEdits: 2, Changes: []editInfo{{Edits: 2}}, SkippedFiles: []edit.Skipped{
+				{File: "/p/dist/store.d.ts", Edits: 1, Reason: "matches excludeGlobs **/dist/**"},
+			}}, sc),
+			want: "repository: 2 edits in 1 file, 1 file skipped",
+		},
+		{
+			name: "rename preview",
+			got:  summarizeEditPreview(editPreviewResult{EditToken: "9f2c", ExpiresIn: "5m0s", TotalEdits: 2, Changes: []editPreview{{}}}, sc),
+			want: "preview: 2 edits in 1 file, editToken 9f2c (expires in 5m0s)",
+		},
+		{
+			name: "apply edit",
+			got:  summarizeApplyEdit(applyEditResult{TotalEdits: 1, Changes: []editInfo{{Edits: 1}}}, sc),
+			want: "1 edit in 1 file",
+		},
+		{
+			name: "recovery",
+			got:  summarizeRecovery(recoveryResult{ID: "j1", Action: recoverRollback, Written: []string{"/p/a.ts"}, Unchanged: 2}, sc),
+			want: "rollback j1: 1 written, 2 unchanged",
+		},
+		{
+			name: "list edits",
+			got:  summarizeListEdits(listEditsResult{Edits: []editSummary{{}}, TotalCount: 4, Truncated: true, Recording: true}, sc),
+			want: "1 of 4 (truncated: yes, recording: on)",
+		},
+		{
+			name: "undo",
+			got:  summarizeUndo(undoEditResult{Undone: editSummary{ID: "e1", Tool: "ts_rename", Symbol: "old", Files: []string{"/p/a.ts", "/p/b.ts"}}}, sc),
+			want: "e1 (ts_rename old) in 2 files",
+		},
+		{
+			name: "project info",
+			got:  summarizeProjectInfo(projectInfoResult{TsconfigPath: "/p/tsconfig.json", TsgoVersion: "7.0.0-dev", Misconfiguration: &workspace.Status{}}, sc),
+			want: "tsconfig.json, tsgo 7.0.0-dev, misconfigured",
+		},
+		{
+			name: "project info without tsconfig",
+			got:  summarizeProjectInfo(projectInfoResult{}, sc),
+			want: "no tsconfig",
+		},
+		{
+			name: "project info with extends",
+			got: summarizeProjectInfo(projectInfoResult{
+				TsconfigPath: "/p/tsconfig.json",
+				Config:       &tsconfig.Effective{Bases: []string{"/p/tsconfig.base.json", "/p/node_modules/@tsconfig/node20/tsconfig.json"}},
+			}, sc),
+			want: "tsconfig.json, extends 2 configs",
+		},
+		{
+			name: "project info with a config error",
+			got:  summarizeProjectInfo(projectInfoResult{TsconfigPath: "/p/tsconfig.json", ConfigError: &configError{File: "/p/tsconfig.base.json"}}, sc),
+			want: "tsconfig.json, config error in tsconfig.base.json",
+		},
+		{
+			name: "list projects",
+			got: summarizeListProjects(listProjectsResult{
+				Root:     "/p",
+				Projects: []workspace.Project{{Config: "/p/packages/core/tsconfig.json"}, {Config: "/p/tsconfig.json"}},
+				Owner:    &projectOwner{File: "/p/packages/core/src/a.ts", Config: "/p/packages/core/tsconfig.json", Included: true},
+			}, sc),
+			want: "2 projects under ., packages/core/src/a.ts owned by packages/core/tsconfig.json",
+		},
+		{
+			name: "list projects with a file not included",
+			got: summarizeListProjects(listProjectsResult{
+				Root:     "/p/packages",
+				Projects: []workspace.Project{{Config: "/p/packages/core/tsconfig.json"}},
+				Owner:    &projectOwner{File: "/p/packages/core/scripts/a.ts", Config: "/p/packages/core/tsconfig.json"},
+			}, sc),
+			want: "1 project under packages, packages/core/scripts/a.ts not included, nearest packages/core/tsconfig.json",
+		},
+		{
+			name: "list projects with a file outside",
+			got:  summarizeListProjects(listProjectsResult{Root: "/p", Owner: &projectOwner{File: "/tmp/a.ts"}}, sc),
+			want: "0 projects under ., /tmp/a.ts in no project",
+		},
+		{
+			name: "project info with files",
+			got:  summarizeProjectInfo(projectInfoResult{TsconfigPath: "/p/tsconfig.json", SourceFiles: newProjectFiles([]string{"/p/a.ts", "/p/b.ts", "/p/c.ts"}, 2)}, sc),
+			want: "tsconfig.json, 3 source files",
+		},
+		{
+			name: "code actions",
+			got: summarizeCodeActions(codeActionsResult{Actions: []codeActionEntry{
+				{Title: "Add import from \"./index\"", IsPreferred: true}, {Title: "Remove unused declaration"},
+			}, Diagnostics: 1}, sc),
+			want: `2 actions, 1 preferred, first Add import from "./index", 1 diagnostic in range`,
+		},
+		{
+			name: "apply code action",
+			got: summarizeApplyCodeAction(applyCodeActionResult{Title: "Add import from \"./index\"", TotalEdits: 1, Changes: []editInfo{
+				{File: "/p/src/fixes.ts", Edits: 1},
+			}}, sc),
+			want: `Add import from "./index": 1 edit in 1 file`,
+		},
+		{
+			name: "apply code action with file operations",
+			got: summarizeApplyCodeAction(applyCodeActionResult{Title: "Move to a new file", TotalEdits: 3, Changes: []editInfo{
+				{File: "/p/src/a.ts", Deleted: true},
+				{File: "/p/src/b.ts", Edits: 1, RenamedFrom: "/p/src/a.ts"},
+				{File: "/p/src/c.ts", Edits: 2, Created: true},
+				{File: "/p/src/d.ts", Deleted: true},
+			}}, sc),
+			want: "Move to a new file: 3 edits in 4 files, 1 created, 1 moved, 1 deleted",
+		},
+		{
+			name: "extract refactorings",
+			got: summarizeExtractRefactorings(extractRefactoringsResult{Refactorings: []extractRefactoring{
+				{Title: "Extract to constant in enclosing scope"}, {Title: "Extract to function in module scope", Disabled: "Cannot extract empty range."},
+			}}, sc),
+			want: "2 refactorings, 1 disabled, first Extract to constant in enclosing scope",
+		},
+		{
+			name: "extract refactor",
+			got: summarizeExtractRefactor(extractRefactorResult{Title: "Extract to constant in enclosing scope", Name: "newLocal", TotalEdits: 2, Changes: []editInfo{
+				{File: "/p/src/consumer.ts", Edits: 2},
+			}}, sc),
+			want: "Extract to constant in enclosing scope: 2 edits in 1 file, named newLocal",
+		},
+		{
+			name: "fix all",
+			got: summarizeFixAll(fixAllResult{Code: 6133, Diagnostics: 3, Applied: 2, Skipped: []fixAllSkip{{Reason: fixSkipConflict}}, Remaining: 1, TotalEdits: 2, Changes: []editInfo{
+				{File: "/p/src/a.ts", Edits: 1}, {File: "/p/src/b.ts", Edits: 1},
+			}}, sc),
+			want: "TS6133: 2 applied, 1 skipped of 3 diagnostics, 1 remaining, 2 edits in 2 files",
+		},
+		{
+			name: "fix all without fixes",
+			got:  summarizeFixAll(fixAllResult{Code: 2304, Skipped: []fixAllSkip{}}, sc),
+			want: "TS2304: 0 applied, 0 skipped of 0 diagnostics, 0 remaining",
+		},
+		{
+			name: "multi-file diagnostics",
+			got: summarizeMultiDiagnostics(multiDiagnosticsResult{Files: map[string]diagnosticsFileReport{
+				"/p/src/a.ts": {TotalCount: 60, Truncated: true},
+				"/p/src/b.ts": {TotalCount: 1},
+				"/p/src/c.ts": {Error: "sync error: no such file"},
+			}, TotalCount: 61, Errors: 58, Warnings: 1, Other: 2}, sc),
+			want: "58 errors, 1 warning, 2 other in 3 files, 1 failed (truncated: yes, 61 total)",
+		},
+		{
+			name: "filtered diagnostics",
+			got: summarizeDiagnostics(diagnosticsResult{Diagnostics: []diagnosticEntry{
+				{Severity: "error"}, {Severity: "error"},
+			}, TotalCount: 2, FilteredOut: 14}, sc),
+			want: "2 errors, 0 warnings in src/errors.ts (truncated: no, 14 filtered out)",
+		},
+		{
+			name: "diagnostics with analysis incomplete",
+			got:  summarizeDiagnostics(diagnosticsResult{Diagnostics: []diagnosticEntry{}, Source: "push", AnalysisIncomplete: true}, sc),
+			want: "0 errors, 0 warnings in src/errors.ts (truncated: no, analysis incomplete)",
+		},
+		{
+			name: "grouped diagnostics",
+			got: summarizeGroupedDiagnostics(groupedDiagnosticsResult{Groups: []diagnosticCodeGroup{
+				{Code: "TS2345", Count: 180}, {Code: "TS2551", Count: 20},
+			}, TotalCount: 200, Errors: 200}, sc),
+			want: "200 errors, 0 warnings in 2 codes, most TS2345 x180",
+		},
+		{
+			name: "grouped diagnostics, none",
+			got:  summarizeGroupedDiagnostics(groupedDiagnosticsResult{Groups: []diagnosticCodeGroup{}}, sc),
+			want: "0 errors, 0 warnings in 0 codes",
+		},
+		{
+			name: "project diagnostics",
+			got: summarizeProjectDiagnostics(projectDiagnosticsResult{
+				FilesTotal: 120, FilesChecked: 120, Errors: 7, Warnings: 1, FilesWithDiagnostics: 3, Elapsed: "4.2s",
+			}, sc),
+			want: "7 errors, 1 warning in 3 files of 120 checked (4.2s)",
+		},
+		{
+			name: "project diagnostics timed out",
+			got: summarizeProjectDiagnostics(projectDiagnosticsResult{
+				FilesTotal: 900, FilesChecked: 350, Errors: 1, Hints: 2, FilesWithDiagnostics: 2, TimedOut: true, Elapsed: "2m0s",
+			}, sc),
+			want: "1 error, 0 warnings, 2 other in 2 files of 350 checked of 900, timed out (2m0s)",
+		},
+		{
+			name: "call hierarchy",
+			got: summarizeCallHierarchy(callHierarchyResult{Direction: "incoming", Depth: 2, Roots: []callHierarchyNode{{
+				Name: "isEven", Calls: []callHierarchyNode{{Name: "isOdd", Calls: []callHierarchyNode{{Name: "isEven", Recursive: true}}}, {Name: "main"}},
+			}}}, sc),
+			want: "incoming calls of isEven: 2 direct, 3 total, 1 recursive (truncated: no)",
+		},
+		{
+			name: "rename file",
+			got: summarizeRenameFile(renameFileResult{OldPath: "/p/src/index.ts", NewPath: "/p/src/lib/math.ts", TotalEdits: 2, Changes: []editInfo{
+				{File: "/p/src/consumer.ts", Edits: 1}, {File: "/p/src/fixes.ts", Edits: 1},
+			}}, sc),
+			want: "src/index.ts -> src/lib/math.ts: 2 edits in 2 files",
+		},
+		{
+			name: "folding ranges",
+			got: summarizeFoldingRanges(foldingRangesResult{Ranges: []foldingRangeEntry{
+				{StartLine: 1, EndLine: 3, Kind: "imports"}, {StartLine: 5, EndLine: 9, Kind: "code"}, {StartLine: 6, EndLine: 8, Kind: "code"},
+			}}, sc),
+			want: "3 ranges (1 imports, 2 code)",
+		},
+		{
+			name: "inlay hints",
+			got: summarizeInlayHints(inlayHintsResult{Hints: []inlayHintEntry{
+				{Line: 2, Column: 9, Label: ": number", Kind: "type"}, {Line: 3, Column: 17, Label: "a:", Kind: "parameter"},
+			}, TotalCount: 80, Truncated: true}, sc),
+			want: "2 of 80, 1 type, 1 parameter (truncated: yes)",
+		},
+		{
+			name: "selection range",
+			got: summarizeSelectionRange(selectionRangeResult{Selections: []selectionChain{
+				{Line: 3, Column: 16, Ranges: []selectionRangeEntry{{Preview: "greet"}, {Preview: "greet(\"world\")"}}},
+				{Line: 4, Column: 13, Ranges: []selectionRangeEntry{{Preview: "add"}}},
+			}}, sc),
+			want: "2 positions, 3 ranges, innermost greet",
+		},
+		{
+			name: "symbol at position",
+			got:  summarizeSymbolAtPosition(symbolAtPositionResult{Breadcrumb: "UserService > updateProfile > callback"}, sc),
+			want: "UserService > updateProfile > callback",
+		},
+		{
+			name: "symbol at a top-level statement",
+			got:  summarizeSymbolAtPosition(symbolAtPositionResult{NearestPreceding: &symbolLevel{Name: "main"}}, sc),
+			want: "none, after main",
+		},
+		{
+			name: "semantic tokens",
+			got: summarizeSemanticTokens(semanticTokensResult{Tokens: []semanticTokenEntry{
+				{Line: 1, Column: 14, Length: 6, Type: "class", Text: "Circle"},
+			}, TotalCount: 12, Truncated: true}, sc),
+			want: "1 of 12, first class Circle (truncated: yes)",
+		},
+		{
+			name: "type hierarchy",
+			got: summarizeTypeHierarchy(typeHierarchyResult{Direction: "subtypes", Roots: []typeHierarchyNode{
+				{Name: "Shape", Types: []typeHierarchyNode{{Name: "Circle", Types: []typeHierarchyNode{{Name: "Ring"}}}, {Name: "Square"}}},
+			}}, sc),
+			want: "subtypes of Shape: 2 direct, 3 total (truncated: no)",
+		},
+		{
+			name: "prepare rename",
+			got:  summarizePrepareRename(prepareRenameResult{CanRename: true, Line: 3, Column: 7, EndLine: 3, EndColumn: 13, Text: "result"}, sc),
+			want: "result at 3:7",
+		},
+		{
+			name: "prepare rename refused",
+			got:  summarizePrepareRename(prepareRenameResult{Reason: "You cannot rename this element."}, sc),
+			want: "cannot rename: You cannot rename this element.",
+		},
+		{
+			name: "document highlights",
+			got: summarizeDocumentHighlights(documentHighlightsResult{Highlights: []highlightEntry{
+				{Line: 1, Column: 5, Kind: "write"}, {Line: 2, Column: 1, Kind: "write"}, {Line: 3, Column: 9, Kind: "read"},
+			}}, sc),
+			want: "3 occurrences: 1 read, 2 write",
+		},
+		{
+			name: "format",
+			got:  summarizeFormat(formatResult{File: "/p/src/errors.ts", Edits: 3, Changed: true}, sc),
+			want: "3 edits, changed",
+		},
+		{
+			name: "coverage",
+			got: summarizeCoverage(projectCoverageResult{Coverage: workspace.Coverage{
+				ProjectFiles: 10, AnalyzedFiles: 8, NeverAnalyzed: []string{"a", "b"}, AnalyzedButExcluded: []string{"c"},
+			}}, sc),
+			want: "8/10 analyzed, 2 never analyzed, 1 analyzed but excluded (truncated: no)",
+		},
+		{
+			name: "import cycles",
+			got:  summarizeImportCycles(importCyclesResult{Target: "/p/src", Cycles: []importCycle{{}}, FilesScanned: 7}, sc),
+			want: "1 through src, 7 files scanned (truncated: no)",
+		},
+		{
+			name: "ambient declarations",
+			got: summarizeAmbient(ambientDeclarationsResult{Files: []ambientFile{
+				{Globals: []ambientGlobal{{}, {}}, References: []ambientReference{{}}},
+				{Modules: []ambientModule{{}}},
+			}, FilesScanned: 20}, sc),
+			want: "2 globals, 1 module, 1 reference in 2 files, 20 scanned",
+		},
+		{
+			name: "open files",
+			got:  summarizeOpenFiles(openFilesResult{Files: []openFileEntry{{OK: true}, {Error: "missing"}}, OpenCount: 5, MaxOpen: 50}, sc),
+			want: "1 opened, 1 failed, 5 open of 50",
+		},
+		{
+			name: "close files",
+			got:  summarizeCloseFiles(closeFilesResult{Closed: []string{"/p/a.ts"}, Skipped: []docsync.SkippedClose{{}}, OpenCount: 3}, sc),
+			want: "1 closed, 1 skipped, 3 open",
+		},
+		{
+			name: "server status",
+			got: summarizeServerStatus(serverStatusResult{
+				OpenCount: 1, TsgoVersion: "7.0.0-dev", PendingEdits: []pendingJournal{{}},
+				Startup: lsp.StartupStatus{State: lsp.StartupReady, Restarts: 2},
+			}, sc),
+			want: "ready, 1 document open, tsgo 7.0.0-dev, restarted 2 times, 1 pending edit",
+		},
+		{
+			name: "server info",
+			got: summarizeServerInfo(serverInfoResult{
+				Name: "typescript-go", Version: "7.0.0-dev",
+				Capabilities:     []lsp.Capability{{Method: "textDocument/hover", Supported: true}, {Method: "textDocument/rename"}},
+				UnavailableTools: []string{"ts_rename"},
+			}, sc),
+			want: "typescript-go 7.0.0-dev, 1 of 2 requests supported, 1 tool unavailable",
+		},
+		{
+			name: "server info without a name",
+			got:  summarizeServerInfo(serverInfoResult{Capabilities: []lsp.Capability{{Method: "textDocument/hover", Supported: true}}}, sc),
+			want: "tsgo, 1 of 1 request supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("summary = %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		tool   string
+		result *mcp.CallToolResult
+		want   string
+	}{
+		{
+			name:   "json detail",
+			tool:   "ts_references",
+			result: mcp.NewToolResultText(`{"references": [{"file": "/p/a.ts", "line": 1, "column": 1}], "totalCount": 1, "truncated": false}`),
+			want:   "references: 1 total, 1 shown in 1 file (truncated: no)",
+		},
+		{
+			name:   "rename preview",
+			tool:   "ts_rename",
+			result: mcp.NewToolResultText(`{"editToken": "9f2c", "expiresIn": "5m0s", "totalEdits": 1, "changes": [{"file": "/p/a.ts", "edits": 1, "diff": ""}]}`),
+			want:   "rename: preview: 1 edit in 1 file, editToken 9f2c (expires in 5m0s)",
+		},
+		{
+			name:   "rename dry run",
+			tool:   "ts_rename",
+			result: mcp.NewToolResultText(`{"dryRun": true, "totalEdits": 3, "changes": [{"file": "/p/a.ts", "edits": 2, "diff": ""}, {"file": "/p/b.ts", "edits": 1, "diff": "", "diffTruncated": true}]}`),
+			want:   "rename: dry run: 3 edits in 2 files, 1 diff truncated",
+		},
+		{
+			name:   "pending edits",
+			tool:   "ts_recover_pending_edit",
+			result: mcp.NewToolResultText(`{"pending": []}`),
+			want:   "recover edit: 0 pending",
+		},
+		{
+			name:   "structured content",
+			tool:   "ts_symbol_card",
+			result: mcp.NewToolResultStructured(symbolCard{Name: "greet", Kind: "function"}, "## greet"),
+			want:   "symbol card: function greet",
+		},
+		{
+			name:   "rendered hover batch",
+			tool:   "ts_hover_batch",
+			result: mcp.NewToolResultText("const a = 1;  // const a: 1\n// ...\nlet c = a;\n"),
+			want:   "hover batch: 3 lines rendered",
+		},
+		{
+			name:   "text answer",
+			tool:   "ts_document_symbols",
+			result: mcp.NewToolResultText("No exported symbols found"),
+			want:   "symbols: 0 total, 0 top-level, 0 exported",
+		},
+		{
+			name:   "no implementations",
+			tool:   "ts_implementations",
+			result: mcp.NewToolResultText("No implementations found"),
+			want:   "implementations: 0 locations",
+		},
+		{
+			name:   "error",
+			tool:   "ts_hover",
+			result: mcp.NewToolResultError("sync error: open /p/a.ts: no such file or directory\nmore"),
+			want:   "hover: error: sync error: open /p/a.ts: no such file or directory",
+		},
+		{
+			name:   "structured error",
+			tool:   "ts_hover",
+			result: mcp.NewToolResultError(`{"error": "tsgo is still starting (spawn, 1s so far); retry shortly", "startup": {"state": "starting"}}`),
+			want:   "hover: error: tsgo is still starting (spawn, 1s so far); retry shortly",
+		},
+		{
+			name:   "unexpected detail",
+			tool:   "ts_server_status",
+			result: mcp.NewToolResultText("not json"),
+			want:   "server: ok",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detail := tt.result.Content[0].(mcp.TextContent).Text
+			if got := summaryLine(toolSummaries[tt.tool], tt.result, detail, summaryContext{root: "/p"}); got != tt.want {
+				t.Errorf("summary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSummary(t *testing.T) {
+	h := func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result := mcp.NewToolResultText(`{"closed": ["/p/a.ts"], "openCount": 0}`)
+		result.Content = append([]mcp.Content{mcp.NewTextContent("warning: no tsconfig.json found")}, result.Content...)
+		return result, nil
+	}
+	texts := func(args map[string]any) []string {
+		var req mcp.CallToolRequest
+		req.Params.Arguments = args
+		result, err := withSummary("/p", "ts_close_files", h)(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, c := range result.Content {
+			out = append(out, c.(mcp.TextContent).Text)
+		}
+		return out
+	}
+
+	const summary = "close files: 1 closed, 0 skipped, 0 open"
+	if got := texts(nil); len(got) != 3 || got[0] != summary || got[1] != "warning: no tsconfig.json found" {
+		t.Errorf("content = %q", got)
+	}
+	if got := texts(map[string]any{"summaryOnly": true}); !slices.Equal(got, []string{summary, "warning: no tsconfig.json found"}) {
+		t.Errorf("summaryOnly content = %q", got)
+	}
+}
+
+func TestEveryToolHasSummary(t *testing.T) {
+	t.Setenv("TYPESCRIPT_MCP_CONFIG", "")
+	client := lsp.StartClient(context.Background(), docsync.FileToURI(t.TempDir()), func(context.Context) (*lsp.TsgoProcess, error) {
+		return nil, errors.New("tsgo not found")
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	s := server.NewMCPServer("test", "test")
+	if err := Register(s, client, docsync.NewManager()); err != nil {
+		t.Fatal(err)
+	}
+	for name, tool := range s.ListTools() {
+		if _, ok := toolSummaries[name]; !ok {
+			t.Errorf("%s has no summarizer", name)
+		}
+		if _, ok := tool.Tool.InputSchema.Properties["summaryOnly"]; !ok {
+			t.Errorf("%s does not accept summaryOnly", name)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+// symbolLevel is one symbol of a breadcrumb, its range 1-based and
+// end-exclusive.
+type symbolLevel struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	StartLine   int    `json:"startLine"`
+	StartColumn int    `json:"startColumn"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+}
+
+type symbolAtPositionResult struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Chain are the symbols enclosing the position, outermost first.
+	Chain []symbolLevel `json:"chain"`
+	// Breadcrumb is the names of Chain joined by " > ".
+	Breadcrumb string `json:"breadcrumb"`
+	// NearestPreceding is the last top-level symbol ending before the
+	// position, when no symbol encloses it.
+	NearestPreceding *symbolLevel `json:"nearestPreceding,omitempty"`
+}
+
+func newSymbolLevel(s protocol.DocumentSymbol) symbolLevel {
+	return symbolLevel{
+		Name:        s.Name,
+		Kind:        symbolKindName(s.Kind),
+		StartLine:   int(s.Range.Start.Line) + 1,
+		StartColumn: int(s.Range.Start.Character) + 1,
+		EndLine:     int(s.Range.End.Line) + 1,
+		EndColumn:   int(s.Range.End.Character) + 1,
+	}
+}
+
+// symbolAtPosition returns the breadcrumb of the symbols enclosing pos,
+// or, when there are none, the nearest top-level symbol before it.
+func symbolAtPosition(symbols []protocol.DocumentSymbol, pos protocol.Position) (chain []symbolLevel, nearest *symbolLevel) {
+	chain = []symbolLevel{}
+	for _, s := range symbolChainAt(symbols, pos) {
+		chain = append(chain, newSymbolLevel(s))
+	}
+	if len(chain) > 0 {
+		return chain, nil
+	}
+	var best *protocol.DocumentSymbol
+	for i, s := range symbols {
+		if !positionBefore(s.Range.End, pos) {
+			continue
+		}
+		if best == nil || positionBefore(best.Range.End, s.Range.End) {
+			best = &symbols[i]
+		}
+	}
+	if best == nil {
+		return chain, nil
+	}
+	level := newSymbolLevel(*best)
+	return chain, &level
+}
+
+func makeSymbolAtPositionHandler(client *lsp.Client, docs *docsync.Manager, symbolCache *symbolCache) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if line < 1 || col < 1 {
+			return mcp.NewToolResultError(fmt.Sprintf("line and column must be >= 1, got line=%d col=%d", line, col)), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		char := cols.charColumn(file, line, col)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+		symbols, err := symbolCache.GetSymbols(ctx, client, docs, file)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("document symbols error: %v", err)), nil
+		}
+
+		pos := protocol.Position{Line: uint32(line - 1), Character: uint32(char - 1)}
+		chain, nearest := symbolAtPosition(symbols, pos)
+		names := make([]string, len(chain))
+		for i, l := range chain {
+			names[i] = l.Name
+		}
+		result := symbolAtPositionResult{
+			File:             file,
+			Line:             line,
+			Column:           col,
+			Chain:            chain,
+			Breadcrumb:       strings.Join(names, " > "),
+			NearestPreceding: nearest,
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestSymbolAtPosition(t *testing.T) {
+	sym := func(name string, kind protocol.SymbolKind, startLine, startChar, endLine, endChar uint32, children ...protocol.DocumentSymbol) protocol.DocumentSymbol {
+		r := protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startChar},
+			End:   protocol.Position{Line: endLine, Character: endChar},
+		}
+		return protocol.DocumentSymbol{Name: name, Kind: kind, Range: r, SelectionRange: r, Children: children}
+	}
+	symbols := []protocol.DocumentSymbol{
+		sym("helper", protocol.SymbolKindFunction, 0, 0, 2, 1),
+		sym("UserService", protocol.SymbolKindClass, 4, 0, 20, 1,
+			sym("updateProfile", protocol.SymbolKindMethod, 6, 2, 12, 3,
+				sym("callback", protocol.SymbolKindFunction, 8, 20, 10, 5))),
+	}
+	at := func(line, char uint32) protocol.Position { return protocol.Position{Line: line, Character: char} }
+
+	chain, nearest := symbolAtPosition(symbols, at(9, 4))
+	if len(chain) != 3 || chain[2].Name != "callback" || nearest != nil {
+		t.Fatalf("chain = %+v, nearest %+v", chain, nearest)
+	}
+	if want := (symbolLevel{Name: "updateProfile", Kind: "method", StartLine: 7, StartColumn: 3, EndLine: 13, EndColumn: 4}); chain[1] != want {
+		t.Errorf("level = %+v, want %+v", chain[1], want)
+	}
+
+	if chain, _ := symbolAtPosition(symbols, at(5, 0)); len(chain) != 1 || chain[0].Kind != "class" {
+		t.Errorf("class body chain = %+v", chain)
+	}
+
+	chain, nearest = symbolAtPosition(symbols, at(22, 0))
+	if chain == nil || len(chain) != 0 || nearest == nil || nearest.Name != "UserService" {
+		t.Errorf("after every symbol = %+v, nearest %+v", chain, nearest)
+	}
+	chain, nearest = symbolAtPosition(symbols, at(3, 0))
+	if len(chain) != 0 || nearest == nil || nearest.Name != "helper" {
+		t.Errorf("between symbols = %+v, nearest %+v", chain, nearest)
+	}
+	if _, nearest := symbolAtPosition(nil, at(0, 0)); nearest != nil {
+		t.Errorf("no symbols, nearest %+v", nearest)
+	}
+}
@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"go.lsp.dev/protocol"
+)
+
+// defaultSymbolCacheSize bounds the number of documents whose symbols are
+// kept.
+const defaultSymbolCacheSize = 64
+
+// symbolSource fetches document symbols from the language server.
+type symbolSource interface {
+	DocumentSymbol(ctx context.Context, file string) ([]protocol.DocumentSymbol, error)
+}
+
+// docVersions reports the synced version of an open document.
+// *docsync.Manager implements it.
+type docVersions interface {
+	Version(file string) (int32, bool)
+}
+
+type symbolCacheKey struct {
+	file    string
+	version int32
+}
+
+type symbolCacheEntry struct {
+	key     symbolCacheKey
+	ready   chan struct{}
+	symbols []protocol.DocumentSymbol
+	err     error
+	elem    *list.Element
+}
+
+// symbolCacheStats is reported by ts_project_info.
+type symbolCacheStats struct {
+	Hits    int `json:"hits"`
+	Misses  int `json:"misses"`
+	Entries int `json:"entries"`
+}
+
+// symbolCache memoizes DocumentSymbol results per (file, synced version).
+// A SyncFile that changes the content bumps the version, so stale entries
+// are never served; they are dropped when the new version is stored or
+// when the LRU bound evicts them. Concurrent requests for the same key
+// share one server round trip. Files that are not open with the server
+// have no version and are always fetched.
+type symbolCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[symbolCacheKey]*symbolCacheEntry
+	lru     *list.List // front is most recently used
+	hits    int
+	misses  int
+}
+
+func newSymbolCache(size int) *symbolCache {
+	if size <= 0 {
+		size = defaultSymbolCacheSize
+	}
+	return &symbolCache{
+		size:    size,
+		entries: make(map[symbolCacheKey]*symbolCacheEntry),
+		lru:     list.New(),
+	}
+}
+
+// GetSymbols returns the document symbols of file, from the cache when the
+// synced version has not changed since they were fetched.
+func (c *symbolCache) GetSymbols(ctx context.Context, src symbolSource, docs docVersions, file string) ([]protocol.DocumentSymbol, error) {
+	version, open := docs.Version(file)
+	if !open {
+		c.mu.Lock()
+		c.misses++
+		c.mu.Unlock()
+		return src.DocumentSymbol(ctx, file)
+	}
+	key := symbolCacheKey{file: file, version: version}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.hits++
+		c.lru.MoveToFront(e.elem)
+		c.mu.Unlock()
+		select {
+		case <-e.ready:
+			return e.symbols, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	c.misses++
+	e := &symbolCacheEntry{key: key, ready: make(chan struct{})}
+	c.dropFileLocked(file)
+	c.entries[key] = e
+	e.elem = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.removeLocked(c.lru.Back().Value.(*symbolCacheEntry))
+	}
+	c.mu.Unlock()
+
+	e.symbols, e.err = src.DocumentSymbol(ctx, file)
+	close(e.ready)
+	if e.err != nil {
+		// Failures are shared with concurrent waiters but not kept.
+		c.mu.Lock()
+		if c.entries[key] == e {
+			c.removeLocked(e)
+		}
+		c.mu.Unlock()
+	}
+	return e.symbols, e.err
+}
+
+// Stats returns hit/miss counters and the current entry count.
+func (c *symbolCache) Stats() symbolCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return symbolCacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
+}
+
+// dropFileLocked removes entries for older versions of file.
+func (c *symbolCache) dropFileLocked(file string) {
+	for k, e := range c.entries {
+		if k.file == file {
+			c.removeLocked(e)
+		}
+	}
+}
+
+func (c *symbolCache) removeLocked(e *symbolCacheEntry) {
+	delete(c.entries, e.key)
+	c.lru.Remove(e.elem)
+}
+
+// cachedSymbols adapts a symbolCache to the symbolSource-shaped backends
+// the handlers take, so callers stay unaware of the cache.
+type cachedSymbols struct {
+	cache *symbolCache
+	src   symbolSource
+	docs  docVersions
+}
+
+func (s cachedSymbols) DocumentSymbol(ctx context.Context, file string) ([]protocol.DocumentSymbol, error) {
+	return s.cache.GetSymbols(ctx, s.src, s.docs, file)
+}
@@ -0,0 +1,142 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// fakeVersions is a docVersions with settable versions.
+type fakeVersions map[string]int32
+
+func (f fakeVersions) Version(file string) (int32, bool) {
+	v, ok := f[file]
+	return v, ok
+}
+
+// failingSymbols fails every DocumentSymbol call.
+type failingSymbols struct{ calls int }
+
+func (f *failingSymbols) DocumentSymbol(context.Context, string) ([]protocol.DocumentSymbol, error) {
+	f.calls++
+	return nil, errors.New("server busy")
+}
+
+func TestSymbolCacheSharedAcrossEnrichments(t *testing.T) {
+	root, backend := newCardFixture(t)
+	declFile := filepath.Join(root, "src", "users.ts")
+	versions := fakeVersions{declFile: 1}
+	cache := newSymbolCache(defaultSymbolCacheSize)
+	cached := cachedCardBackend{
+		cardBackend: backend,
+		symbols:     cachedSymbols{cache: cache, src: backend, docs: versions},
+	}
+
+	// Three enrichment paths over the same file and version: symbol lookup
+	// by name, the card's declaration section, and the outline tool.
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch i {
+			case 0:
+				symbols, err := cached.DocumentSymbol(context.Background(), declFile)
+				if _, ok := findSymbolByName(symbols, "UserService.find"); err != nil || !ok {
+					t.Errorf("lookup by name: ok=%v err=%v", ok, err)
+				}
+			case 1:
+				card := assembleSymbolCard(context.Background(), cached, cardRequest{
+					file: declFile, line: 1, col: 14, rootDir: root,
+					sections: map[string]bool{cardSectionDeclaration: true},
+				})
+				if card.QualifiedName != "UserService" {
+					t.Errorf("card QualifiedName = %q", card.QualifiedName)
+				}
+			case 2:
+				if _, err := cache.GetSymbols(context.Background(), backend, versions, declFile); err != nil {
+					t.Errorf("GetSymbols: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := backend.calls["documentSymbol"]; got != 1 {
+		t.Fatalf("DocumentSymbol calls = %d, want 1", got)
+	}
+	if st := cache.Stats(); st.Hits != 2 || st.Misses != 1 || st.Entries != 1 {
+		t.Errorf("Stats = %+v", st)
+	}
+
+	// A sync that changed content bumps the version: refetch, and the old
+	// version's entry is dropped.
+	versions[declFile] = 2
+	if _, err := cache.GetSymbols(context.Background(), backend, versions, declFile); err != nil {
+		t.Fatal(err)
+	}
+	if got := backend.calls["documentSymbol"]; got != 2 {
+		t.Errorf("after version bump DocumentSymbol calls = %d, want 2", got)
+	}
+	if st := cache.Stats(); st.Entries != 1 {
+		t.Errorf("old version still cached: %+v", st)
+	}
+}
+
+func TestSymbolCacheUnopenedFilesBypass(t *testing.T) {
+	_, backend := newCardFixture(t)
+	cache := newSymbolCache(defaultSymbolCacheSize)
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetSymbols(context.Background(), backend, fakeVersions{}, "/x/closed.ts"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := backend.calls["documentSymbol"]; got != 2 {
+		t.Errorf("DocumentSymbol calls = %d, want 2 (no version, no caching)", got)
+	}
+}
+
+func TestSymbolCacheErrorsNotKept(t *testing.T) {
+	src := &failingSymbols{}
+	cache := newSymbolCache(defaultSymbolCacheSize)
+	versions := fakeVersions{"/x/a.ts": 1}
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetSymbols(context.Background(), src, versions, "/x/a.ts"); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if src.calls != 2 {
+		t.Errorf("calls = %d, want 2", src.calls)
+	}
+	if st := cache.Stats(); st.Entries != 0 {
+		t.Errorf("failed result cached: %+v", st)
+	}
+}
+
+func TestSymbolCacheLRU(t *testing.T) {
+	_, backend := newCardFixture(t)
+	cache := newSymbolCache(2)
+	versions := fakeVersions{"/x/a.ts": 1, "/x/b.ts": 1, "/x/c.ts": 1}
+	get := func(f string) {
+		t.Helper()
+		if _, err := cache.GetSymbols(context.Background(), backend, versions, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get("/x/a.ts")
+	get("/x/b.ts")
+	get("/x/a.ts") // a is now most recent
+	get("/x/c.ts") // evicts b
+	get("/x/a.ts")
+	if got := backend.calls["documentSymbol"]; got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+	get("/x/b.ts")
+	if got := backend.calls["documentSymbol"]; got != 4 {
+		t.Errorf("evicted entry should refetch, calls = %d", got)
+	}
+}
@@ -0,0 +1,488 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+// Symbol card sections. Each is fetched independently so one failing LSP
+// request only blanks its own part of the card.
+const (
+	cardSectionSignature   = "signature"
+	cardSectionDeclaration = "declaration"
+	cardSectionReferences  = "references"
+)
+
+var allCardSections = []string{cardSectionSignature, cardSectionDeclaration, cardSectionReferences}
+
+// cardBackend is the subset of *lsp.Client the symbol card needs.
+type cardBackend interface {
+	Hover(ctx context.Context, file string, line, col int) (*protocol.Hover, error)
+	Definition(ctx context.Context, file string, line, col int) ([]protocol.Location, error)
+	References(ctx context.Context, file string, line, col int, includeDeclaration bool) ([]protocol.Location, error)
+	DocumentSymbol(ctx context.Context, file string) ([]protocol.DocumentSymbol, error)
+}
+
+// cachedCardBackend routes DocumentSymbol through the symbol cache.
+type cachedCardBackend struct {
+	cardBackend
+	symbols cachedSymbols
+}
+
+func (b cachedCardBackend) DocumentSymbol(ctx context.Context, file string) ([]protocol.DocumentSymbol, error) {
+	return b.symbols.DocumentSymbol(ctx, file)
+}
+
+type cardLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// VisualColumn is Column with tabs expanded, in columnMode "visual".
+	VisualColumn int `json:"visualColumn,omitempty"`
+}
+
+type cardReferences struct {
+	Total       int            `json:"total"`
+	ByDirectory map[string]int `json:"byDirectory"`
+}
+
+// symbolCard is the stable output schema of ts_symbol_card.
+type symbolCard struct {
+	Name            string            `json:"name,omitempty"`
+	QualifiedName   string            `json:"qualifiedName,omitempty"`
+	Kind            string            `json:"kind,omitempty"`
+	Declaration     *cardLocation     `json:"declaration,omitempty"`
+	Signature       string            `json:"signature,omitempty"`
+	Summary         string            `json:"summary,omitempty"`
+	Exported        *bool             `json:"exported,omitempty"`
+	ImportSpecifier string            `json:"importSpecifier,omitempty"`
+	Deprecated      bool              `json:"deprecated"`
+	EnclosingChain  []string          `json:"enclosingChain,omitempty"`
+	References      *cardReferences   `json:"references,omitempty"`
+	Errors          map[string]string `json:"errors,omitempty"`
+}
+
+type cardRequest struct {
+	file     string
+	line     int
+	col      int
+	forFile  string
+	rootDir  string
+	sections map[string]bool
+	// mode decides whether import specifiers carry extensions, and paths
+	// which aliases they may use; packages, when set, names the package
+	// of a declaration in node_modules.
+	mode     tsconfig.ResolutionMode
+	paths    *tsconfig.PathMapper
+	packages *workspace.PackageResolver
+}
+
+func makeSymbolCardHandler(client *lsp.Client, docs *docsync.Manager, packages *workspace.PackageResolver, symbolCache *symbolCache) server.ToolHandlerFunc {
+	backend := cachedCardBackend{
+		cardBackend: client,
+		symbols:     cachedSymbols{cache: symbolCache, src: client, docs: docs},
+	}
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		symbolName := request.GetString("symbol", "")
+		line := request.GetInt("line", 0)
+		col := request.GetInt("column", 0)
+		if symbolName == "" && (line < 1 || col < 1) {
+			return mcp.NewToolResultError("either line and column, or symbol, is required"), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if symbolName == "" {
+			col = cols.charColumn(file, line, col)
+		}
+
+		sections := make(map[string]bool)
+		for _, s := range request.GetStringSlice("sections", allCardSections) {
+			if !slices.Contains(allCardSections, s) {
+				return mcp.NewToolResultError(fmt.Sprintf("unknown section %q (valid: %s)", s, strings.Join(allCardSections, ", "))), nil
+			}
+			sections[s] = true
+		}
+
+		format := request.GetString("format", "json")
+		if format != "json" && format != "markdown" {
+			return mcp.NewToolResultError(fmt.Sprintf("unknown format %q (valid: json, markdown)", format)), nil
+		}
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		if symbolName != "" {
+			symbols, err := backend.DocumentSymbol(ctx, file)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("document symbols error: %v", err)), nil
+			}
+			sym, ok := findSymbolByName(symbols, symbolName)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("symbol %q not found in %s", symbolName, file)), nil
+			}
+			line = int(sym.SelectionRange.Start.Line) + 1
+			col = int(sym.SelectionRange.Start.Character) + 1
+		}
+
+		forFile := request.GetString("forFile", "")
+		mode, paths := importConfigFor(forFile, request.GetString("tsconfig", ""), client.RootDir())
+		card := assembleSymbolCard(ctx, backend, cardRequest{
+			file:     file,
+			line:     line,
+			col:      col,
+			forFile:  forFile,
+			rootDir:  client.RootDir(),
+			sections: sections,
+			mode:     mode,
+			paths:    paths,
+			packages: packages,
+		})
+		if d := card.Declaration; d != nil {
+			d.VisualColumn = cols.visualColumn(d.File, d.Line, d.Column)
+		}
+
+		if format == "markdown" {
+			return mcp.NewToolResultStructured(card, renderSymbolCardMarkdown(card)), nil
+		}
+		data, err := json.MarshalIndent(card, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultStructured(card, string(data)), nil
+	}
+}
+
+// assembleSymbolCard fetches the requested sections concurrently. A failed
+// section is recorded in card.Errors and leaves its fields empty.
+func assembleSymbolCard(ctx context.Context, backend cardBackend, req cardRequest) symbolCard {
+	var (
+		card symbolCard
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+	)
+	fail := func(section string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if card.Errors == nil {
+			card.Errors = make(map[string]string)
+		}
+		card.Errors[section] = err.Error()
+	}
+
+	if req.sections[cardSectionSignature] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hover, err := backend.Hover(ctx, req.file, req.line, req.col)
+			if err != nil {
+				fail(cardSectionSignature, err)
+				return
+			}
+			if hover == nil {
+				return
+			}
+			md := hover.Contents.Value
+			mu.Lock()
+			defer mu.Unlock()
+			card.Signature = extractConciseHover(md)
+			card.Summary = firstSentence(hoverDocumentation(md))
+			if strings.Contains(md, "@deprecated") {
+				card.Deprecated = true
+			}
+		}()
+	}
+
+	if req.sections[cardSectionReferences] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			locs, err := backend.References(ctx, req.file, req.line, req.col, true)
+			if err != nil {
+				fail(cardSectionReferences, err)
+				return
+			}
+			refs := &cardReferences{Total: len(locs), ByDirectory: make(map[string]int)}
+			for _, loc := range locs {
+				dir := filepath.Dir(docsync.URIToFile(string(loc.URI)))
+				if rel, err := filepath.Rel(req.rootDir, dir); err == nil && !strings.HasPrefix(rel, "..") {
+					dir = filepath.ToSlash(rel)
+				}
+				refs.ByDirectory[dir]++
+			}
+			mu.Lock()
+			card.References = refs
+			mu.Unlock()
+		}()
+	}
+
+	if req.sections[cardSectionDeclaration] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decl, err := cardDeclaration(ctx, backend, req)
+			if err != nil {
+				fail(cardSectionDeclaration, err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			card.Name = decl.Name
+			card.QualifiedName = decl.QualifiedName
+			card.Kind = decl.Kind
+			card.Declaration = decl.Declaration
+			card.Exported = decl.Exported
+			card.ImportSpecifier = decl.ImportSpecifier
+			card.EnclosingChain = decl.EnclosingChain
+			card.Deprecated = card.Deprecated || decl.Deprecated
+		}()
+	}
+
+	wg.Wait()
+	return card
+}
+
+// cardDeclaration resolves the declaration and describes it using the
+// declaring file's outline. The returned card only has declaration fields.
+func cardDeclaration(ctx context.Context, backend cardBackend, req cardRequest) (symbolCard, error) {
+	var card symbolCard
+	locs, err := backend.Definition(ctx, req.file, req.line, req.col)
+	if err != nil {
+		return card, err
+	}
+	if len(locs) == 0 {
+		return card, fmt.Errorf("no declaration found")
+	}
+	loc := locs[0]
+	declFile := docsync.URIToFile(string(loc.URI))
+	card.Declaration = &cardLocation{
+		File:   declFile,
+		Line:   int(loc.Range.Start.Line) + 1,
+		Column: int(loc.Range.Start.Character) + 1,
+	}
+
+	if text, err := readLine(declFile, card.Declaration.Line); err == nil {
+		exported := isExportedDeclaration(text)
+		card.Exported = &exported
+		if exported && req.forFile != "" {
+			card.ImportSpecifier = cardImportSpecifier(req, declFile)
+		}
+	}
+
+	symbols, err := backend.DocumentSymbol(ctx, declFile)
+	if err != nil {
+		return card, nil // the location alone is still useful
+	}
+	chain := symbolChainAt(symbols, loc.Range.Start)
+	if len(chain) == 0 {
+		return card, nil
+	}
+	self := chain[len(chain)-1]
+	names := make([]string, len(chain))
+	for i, s := range chain {
+		names[i] = s.Name
+	}
+	card.Name = self.Name
+	card.QualifiedName = strings.Join(names, ".")
+	card.Kind = symbolKindName(self.Kind)
+	card.Deprecated = self.Deprecated || hasDeprecatedTag(self.Tags)
+	card.EnclosingChain = names[:len(names)-1]
+	return card, nil
+}
+
+// symbolChainAt returns the path of nested symbols whose range contains pos,
+// outermost first.
+func symbolChainAt(symbols []protocol.DocumentSymbol, pos protocol.Position) []protocol.DocumentSymbol {
+	for _, s := range symbols {
+		if rangeContains(s.Range, pos) {
+			return append([]protocol.DocumentSymbol{s}, symbolChainAt(s.Children, pos)...)
+		}
+	}
+	return nil
+}
+
+// cardImportSpecifier returns the specifier req.forFile imports declFile
+// with: the package's name for a file of an installed package, a paths
+// alias or relative specifier otherwise.
+func cardImportSpecifier(req cardRequest, declFile string) string {
+	if req.packages != nil {
+		if pkg := req.packages.Resolve(declFile); pkg != nil {
+			return typesModule(pkg.Name)
+		}
+	}
+	return tsconfig.ImportSpecifier(req.forFile, declFile, req.paths, req.mode)
+}
+
+// importConfigFor returns the module resolution mode and paths aliases of
+// the tsconfig.json nearest to forFile, or else of configPath, or of
+// rootDir/tsconfig.json when configPath is empty. Unreadable configs yield
+// the zero mode (extensionless specifiers) and no aliases.
+func importConfigFor(forFile, configPath, rootDir string) (tsconfig.ResolutionMode, *tsconfig.PathMapper) {
+	if forFile != "" {
+		if dir := nearestConfigDir(forFile); dir != "" {
+			configPath = filepath.Join(dir, "tsconfig.json")
+		}
+	}
+	if configPath == "" {
+		configPath = filepath.Join(rootDir, "tsconfig.json")
+	}
+	cfg, err := tsconfig.Load(configPath)
+	if err != nil {
+		return "", nil
+	}
+	paths, _ := cfg.PathMapper()
+	return cfg.ModuleResolution(), paths
+}
+
+func rangeContains(r protocol.Range, pos protocol.Position) bool {
+	if pos.Line < r.Start.Line || pos.Line > r.End.Line {
+		return false
+	}
+	if pos.Line == r.Start.Line && pos.Character < r.Start.Character {
+		return false
+	}
+	if pos.Line == r.End.Line && pos.Character > r.End.Character {
+		return false
+	}
+	return true
+}
+
+// findSymbolByName looks up a symbol by plain or dotted qualified name
+// (e.g. "MyClass.method"). The first match in document order wins.
+func findSymbolByName(symbols []protocol.DocumentSymbol, name string) (protocol.DocumentSymbol, bool) {
+	if found := findSymbolsByName(symbols, name); len(found) > 0 {
+		return found[0].symbol, true
+	}
+	return protocol.DocumentSymbol{}, false
+}
+
+func hasDeprecatedTag(tags []protocol.SymbolTag) bool {
+	for _, t := range tags {
+		if t == protocol.SymbolTagDeprecated {
+			return true
+		}
+	}
+	return false
+}
+
+// isExportedDeclaration reports whether a declaration line carries an
+// export modifier.
+func isExportedDeclaration(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "export ")
+}
+
+// hoverDocumentation returns the hover markdown with code blocks removed.
+func hoverDocumentation(md string) string {
+	var out []string
+	inCode := false
+	for _, line := range strings.Split(md, "\n") {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode {
+			out = append(out, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// firstSentence returns the first sentence of a documentation paragraph.
+func firstSentence(doc string) string {
+	if i := strings.Index(doc, "\n\n"); i >= 0 {
+		doc = doc[:i]
+	}
+	doc = strings.Join(strings.Fields(doc), " ")
+	if i := strings.Index(doc, ". "); i >= 0 {
+		return doc[:i+1]
+	}
+	return doc
+}
+
+// renderSymbolCardMarkdown renders a compact human-readable card.
+func renderSymbolCardMarkdown(card symbolCard) string {
+	var sb strings.Builder
+	title := card.QualifiedName
+	if title == "" {
+		title = card.Name
+	}
+	if title == "" {
+		title = "(unknown symbol)"
+	}
+	fmt.Fprintf(&sb, "### %s", title)
+	if card.Kind != "" {
+		fmt.Fprintf(&sb, " (%s)", card.Kind)
+	}
+	if card.Deprecated {
+		sb.WriteString(" — deprecated")
+	}
+	sb.WriteString("\n")
+	if card.Signature != "" {
+		fmt.Fprintf(&sb, "\n```ts\n%s\n```\n", card.Signature)
+	}
+	if card.Summary != "" {
+		fmt.Fprintf(&sb, "\n%s\n", card.Summary)
+	}
+	sb.WriteString("\n")
+	if card.Declaration != nil {
+		fmt.Fprintf(&sb, "- declared: %s:%d:%d\n", card.Declaration.File, card.Declaration.Line, card.Declaration.Column)
+	}
+	if card.Exported != nil {
+		fmt.Fprintf(&sb, "- exported: %t\n", *card.Exported)
+	}
+	if card.ImportSpecifier != "" {
+		fmt.Fprintf(&sb, "- import from: %q\n", card.ImportSpecifier)
+	}
+	if len(card.EnclosingChain) > 0 {
+		fmt.Fprintf(&sb, "- inside: %s\n", strings.Join(card.EnclosingChain, " > "))
+	}
+	if card.References != nil {
+		dirs := make([]string, 0, len(card.References.ByDirectory))
+		for d := range card.References.ByDirectory {
+			dirs = append(dirs, d)
+		}
+		sort.Strings(dirs)
+		parts := make([]string, len(dirs))
+		for i, d := range dirs {
+			parts[i] = fmt.Sprintf("%s (%d)", d, card.References.ByDirectory[d])
+		}
+		fmt.Fprintf(&sb, "- references: %d", card.References.Total)
+		if len(parts) > 0 {
+			fmt.Fprintf(&sb, " — %s", strings.Join(parts, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	if len(card.Errors) > 0 {
+		sections := make([]string, 0, len(card.Errors))
+		for s := range card.Errors {
+			sections = append(sections, s)
+		}
+		sort.Strings(sections)
+		for _, s := range sections {
+			fmt.Fprintf(&sb, "- %s unavailable: %s\n", s, card.Errors[s])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+// fakeCardBackend returns scripted responses and counts calls.
+type fakeCardBackend struct {
+	mu    sync.Mutex
+	calls map[string]int
+
+	hover       *protocol.Hover
+	hoverErr    error
+	defs        []protocol.Location
+	defErr      error
+	refs        []protocol.Location
+	refErr      error
+	symbols     []protocol.DocumentSymbol
+	beforeHover func()
+}
+
+func (f *fakeCardBackend) count(method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[method]++
+}
+
+func (f *fakeCardBackend) Hover(_ context.Context, _ string, _, _ int) (*protocol.Hover, error) {
+	f.count("hover")
+	if f.beforeHover != nil {
+		f.beforeHover()
+	}
+	return f.hover, f.hoverErr
+}
+
+func (f *fakeCardBackend) Definition(_ context.Context, _ string, _, _ int) ([]protocol.Location, error) {
+	f.count("definition")
+	return f.defs, f.defErr
+}
+
+func (f *fakeCardBackend) References(_ context.Context, _ string, _, _ int, _ bool) ([]protocol.Location, error) {
+	f.count("references")
+	return f.refs, f.refErr
+}
+
+func (f *fakeCardBackend) DocumentSymbol(_ context.Context, _ string) ([]protocol.DocumentSymbol, error) {
+	f.count("documentSymbol")
+	return f.symbols, nil
+}
+
+func lineRange(line, startChar, endLine, endChar uint32) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: line, Character: startChar},
+		End:   protocol.Position{Line: endLine, Character: endChar},
+	}
+}
+
+func newCardFixture(t *testing.T) (string, *fakeCardBackend) {
+	t.Helper()
+	root := t.TempDir()
+	declFile := filepath.Join(root, "src", "users.ts")
+	if err := os.MkdirAll(filepath.Dir(declFile), 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	content := "export class UserService {\n  /** @deprecated */\n  find(id: string) {}\n}\n"
+	if err := os.WriteFile(declFile, []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	ClearFileCache()
+	t.Cleanup(ClearFileCache)
+
+	declURI := protocol.DocumentURI("file://" + declFile)
+	backend := &fakeCardBackend{
+		hover: &protocol.Hover{Contents: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: "```typescript\nclass UserService\n```\nLooks up users. Caches results.\n\nMore detail.",
+		}},
+		defs: []protocol.Location{{URI: declURI, Range: lineRange(0, 13, 0, 24)}},
+		refs: []protocol.Location{
+			{URI: declURI, Range: lineRange(0, 13, 0, 24)},
+			{URI: protocol.DocumentURI("file://" + filepath.Join(root, "src", "api", "a.ts"))},
+			{URI: protocol.DocumentURI("file://" + filepath.Join(root, "src", "api", "b.ts"))},
+		},
+		symbols: []protocol.DocumentSymbol{{
+			Name:           "UserService",
+			Kind:           protocol.SymbolKindClass,
+			Range:          lineRange(0, 0, 3, 1),
+			SelectionRange: lineRange(0, 13, 0, 24),
+			Children: []protocol.DocumentSymbol{{
+				Name:           "find",
+				Kind:           protocol.SymbolKindMethod,
+				Range:          lineRange(2, 2, 2, 22),
+				SelectionRange: lineRange(2, 2, 2, 6),
+				Tags:           []protocol.SymbolTag{protocol.SymbolTagDeprecated},
+			}},
+		}},
+	}
+	return root, backend
+}
+
+func allSections() map[string]bool {
+	m := make(map[string]bool)
+	for _, s := range allCardSections {
+		m[s] = true
+	}
+	return m
+}
+
+func TestAssembleSymbolCard(t *testing.T) {
+	root, backend := newCardFixture(t)
+
+	card := assembleSymbolCard(context.Background(), backend, cardRequest{
+		file:     filepath.Join(root, "src", "main.ts"),
+		line:     1,
+		col:      1,
+		forFile:  filepath.Join(root, "src", "main.ts"),
+		rootDir:  root,
+		sections: allSections(),
+	})
+
+	if len(card.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", card.Errors)
+	}
+	if card.Name != "UserService" || card.QualifiedName != "UserService" || card.Kind != "class" {
+		t.Errorf("identity = %q/%q/%q", card.Name, card.QualifiedName, card.Kind)
+	}
+	if card.Signature != "class UserService" {
+		t.Errorf("Signature = %q", card.Signature)
+	}
+	if card.Summary != "Looks up users." {
+		t.Errorf("Summary = %q", card.Summary)
+	}
+	if card.Exported == nil || !*card.Exported {
+		t.Errorf("Exported = %v, want true", card.Exported)
+	}
+	if card.ImportSpecifier != "./users" {
+		t.Errorf("ImportSpecifier = %q", card.ImportSpecifier)
+	}
+	if card.Deprecated {
+		t.Error("class itself is not deprecated")
+	}
+	if card.Declaration == nil || card.Declaration.Line != 1 || card.Declaration.Column != 14 {
+		t.Errorf("Declaration = %+v", card.Declaration)
+	}
+	wantDirs := map[string]int{"src": 1, "src/api": 2}
+	if card.References == nil || card.References.Total != 3 || !reflect.DeepEqual(card.References.ByDirectory, wantDirs) {
+		t.Errorf("References = %+v", card.References)
+	}
+}
+
+func TestAssembleSymbolCardNestedDeprecated(t *testing.T) {
+	root, backend := newCardFixture(t)
+	backend.defs[0].Range = lineRange(2, 2, 2, 6)
+	backend.hover = nil
+
+	card := assembleSymbolCard(context.Background(), backend, cardRequest{
+		file: filepath.Join(root, "src", "users.ts"), line: 3, col: 3,
+		rootDir: root, sections: allSections(),
+	})
+	if card.QualifiedName != "UserService.find" || card.Kind != "method" {
+		t.Errorf("QualifiedName/Kind = %q/%q", card.QualifiedName, card.Kind)
+	}
+	if !reflect.DeepEqual(card.EnclosingChain, []string{"UserService"}) {
+		t.Errorf("EnclosingChain = %v", card.EnclosingChain)
+	}
+	if !card.Deprecated {
+		t.Error("expected deprecated from symbol tag")
+	}
+	if card.Exported == nil || *card.Exported {
+		t.Errorf("method line is not an export, got %v", card.Exported)
+	}
+}
+
+func TestAssembleSymbolCardPartialFailure(t *testing.T) {
+	root, backend := newCardFixture(t)
+	backend.refErr = errors.New("references timed out")
+	backend.defErr = errors.New("no definition provider")
+
+	card := assembleSymbolCard(context.Background(), backend, cardRequest{
+		file: filepath.Join(root, "src", "main.ts"), line: 1, col: 1,
+		rootDir: root, sections: allSections(),
+	})
+
+	want := map[string]string{
+		cardSectionReferences:  "references timed out",
+		cardSectionDeclaration: "no definition provider",
+	}
+	if !reflect.DeepEqual(card.Errors, want) {
+		t.Errorf("Errors = %v, want %v", card.Errors, want)
+	}
+	if card.Signature != "class UserService" {
+		t.Errorf("signature section should survive other failures, got %q", card.Signature)
+	}
+	if card.References != nil || card.Declaration != nil {
+		t.Error("failed sections must leave their fields empty")
+	}
+	md := renderSymbolCardMarkdown(card)
+	if !strings.Contains(md, "- references unavailable: references timed out") {
+		t.Errorf("markdown should report the failed section:\n%s", md)
+	}
+}
+
+func TestAssembleSymbolCardSections(t *testing.T) {
+	root, backend := newCardFixture(t)
+	card := assembleSymbolCard(context.Background(), backend, cardRequest{
+		file: filepath.Join(root, "src", "main.ts"), line: 1, col: 1,
+		rootDir: root, sections: map[string]bool{cardSectionSignature: true},
+	})
+	if card.Signature == "" {
+		t.Error("expected signature")
+	}
+	if backend.calls["definition"] != 0 || backend.calls["references"] != 0 || backend.calls["documentSymbol"] != 0 {
+		t.Errorf("trimmed sections must not be fetched, calls = %v", backend.calls)
+	}
+}
+
+func TestAssembleSymbolCardConcurrent(t *testing.T) {
+	root, backend := newCardFixture(t)
+
+	// Hover blocks until references has been requested; a sequential
+	// implementation that fetched signature first would time out.
+	refsCalled := make(chan struct{})
+	var once sync.Once
+	inner := backend.refs
+	backend.refs = nil
+	wrapped := &refsSignalBackend{fakeCardBackend: backend, refs: inner, signal: func() { once.Do(func() { close(refsCalled) }) }}
+	backend.beforeHover = func() {
+		select {
+		case <-refsCalled:
+		case <-time.After(2 * time.Second):
+			t.Error("hover and references were not fetched concurrently")
+		}
+	}
+
+	card := assembleSymbolCard(context.Background(), wrapped, cardRequest{
+		file: filepath.Join(root, "src", "main.ts"), line: 1, col: 1,
+		rootDir: root, sections: allSections(),
+	})
+	if card.References == nil || card.References.Total != 3 {
+		t.Errorf("References = %+v", card.References)
+	}
+}
+
+type refsSignalBackend struct {
+	*fakeCardBackend
+	refs   []protocol.Location
+	signal func()
+}
+
+func (r *refsSignalBackend) References(ctx context.Context, file string, line, col int, includeDeclaration bool) ([]protocol.Location, error) {
+	r.signal()
+	return r.refs, nil
+}
+
+func TestFirstSentence(t *testing.T) {
+	tests := map[string]string{
+		"Adds two numbers. Returns the sum.": "Adds two numbers.",
+		"No period":                          "No period",
+		"Wraps\nlines. Then more.":           "Wraps lines.",
+		"First para\n\nSecond para.":         "First para",
+		"":                                   "",
+	}
+	for in, want := range tests {
+		if got := firstSentence(in); got != want {
+			t.Errorf("firstSentence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAssembleSymbolCardNode16Specifier(t *testing.T) {
+	root, backend := newCardFixture(t)
+	card := assembleSymbolCard(context.Background(), backend, cardRequest{
+		file: filepath.Join(root, "src", "main.ts"), line: 1, col: 1,
+		forFile: filepath.Join(root, "src", "main.mts"),
+		rootDir: root, sections: map[string]bool{cardSectionDeclaration: true},
+		mode: tsconfig.ResolutionNode16,
+	})
+	if card.ImportSpecifier != "./users.js" {
+		t.Errorf("ImportSpecifier = %q, want ./users.js", card.ImportSpecifier)
+	}
+}
+
+func TestAssembleSymbolCardPathAlias(t *testing.T) {
+	root, backend := newCardFixture(t)
+	writeString(t, filepath.Join(root, "tsconfig.json"), `{"compilerOptions": {"paths": {"@app/*": ["./src/*"]}}}`)
+	forFile := filepath.Join(root, "test", "users.test.ts")
+	mode, paths := importConfigFor(forFile, "", root)
+	card := assembleSymbolCard(context.Background(), backend, cardRequest{
+		file: filepath.Join(root, "src", "main.ts"), line: 1, col: 1,
+		forFile: forFile,
+		rootDir: root, sections: map[string]bool{cardSectionDeclaration: true},
+		mode: mode, paths: paths,
+	})
+	if card.ImportSpecifier != "@app/users" {
+		t.Errorf("ImportSpecifier = %q, want @app/users", card.ImportSpecifier)
+	}
+}
+
+func TestAssembleSymbolCardPackageSpecifier(t *testing.T) {
+	tests := []struct {
+		name, pkg, file, want string
+	}{
+		{"package", "zod", "lib/types.d.ts", "zod"},
+		{"types package", "@types/node", "fs.d.ts", "node"},
+		{"scoped types package", "@types/scope__pkg", "index.d.ts", "@scope/pkg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, backend := newCardFixture(t)
+			pkgDir := filepath.Join(root, "node_modules", filepath.FromSlash(tt.pkg))
+			declFile := filepath.Join(pkgDir, filepath.FromSlash(tt.file))
+			if err := os.MkdirAll(filepath.Dir(declFile), 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeString(t, filepath.Join(pkgDir, "package.json"), `{"name": "`+tt.pkg+`"}`)
+			writeString(t, declFile, "export declare const parse: () => void;\n")
+			backend.defs = []protocol.Location{{URI: protocol.DocumentURI("file://" + declFile), Range: lineRange(0, 22, 0, 27)}}
+
+			card := assembleSymbolCard(context.Background(), backend, cardRequest{
+				file: filepath.Join(root, "src", "main.ts"), line: 1, col: 1,
+				forFile: filepath.Join(root, "src", "main.ts"),
+				rootDir: root, sections: map[string]bool{cardSectionDeclaration: true},
+				packages: workspace.NewPackageResolver(),
+			})
+			if card.ImportSpecifier != tt.want {
+				t.Errorf("ImportSpecifier = %q, want %q", card.ImportSpecifier, tt.want)
+			}
+		})
+	}
+}
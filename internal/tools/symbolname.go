@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.lsp.dev/protocol"
+)
+
+// namedSymbol is a document symbol found by name, with its qualified
+// name: the names of it and its enclosing symbols joined with dots.
+type namedSymbol struct {
+	symbol        protocol.DocumentSymbol
+	qualifiedName string
+}
+
+// findSymbolsByName returns the symbols a plain or dotted qualified name
+// (e.g. "MyClass.method") matches, in document order. A qualified name
+// matches at any depth: "Repo.find" also finds a Repo nested in a
+// namespace.
+func findSymbolsByName(symbols []protocol.DocumentSymbol, name string) []namedSymbol {
+	parts := strings.Split(name, ".")
+	var found []namedSymbol
+	seen := make(map[protocol.Range]bool)
+	// match appends the symbols of syms, whose container path is prefix,
+	// that the rest of the name matches exactly below them.
+	var match func(syms []protocol.DocumentSymbol, rest []string, prefix string)
+	match = func(syms []protocol.DocumentSymbol, rest []string, prefix string) {
+		for _, s := range syms {
+			if s.Name != rest[0] {
+				continue
+			}
+			qualified := s.Name
+			if prefix != "" {
+				qualified = prefix + "." + s.Name
+			}
+			if len(rest) > 1 {
+				match(s.Children, rest[1:], qualified)
+			} else if !seen[s.SelectionRange] {
+				seen[s.SelectionRange] = true
+				found = append(found, namedSymbol{symbol: s, qualifiedName: qualified})
+			}
+		}
+	}
+	var walk func(syms []protocol.DocumentSymbol, prefix string)
+	walk = func(syms []protocol.DocumentSymbol, prefix string) {
+		for _, s := range syms {
+			match([]protocol.DocumentSymbol{s}, parts, prefix)
+			qualified := s.Name
+			if prefix != "" {
+				qualified = prefix + "." + s.Name
+			}
+			walk(s.Children, qualified)
+		}
+	}
+	walk(symbols, "")
+	return found
+}
+
+// symbolCandidate is one of the symbols an ambiguous name matches.
+type symbolCandidate struct {
+	QualifiedName string `json:"qualifiedName"`
+	Kind          string `json:"kind"`
+	Line          int    `json:"line"`
+	Column        int    `json:"column"`
+}
+
+// ambiguousSymbolError reports a name that matches several symbols when
+// no occurrence picks one.
+type ambiguousSymbolError struct {
+	Name       string
+	File       string
+	Candidates []symbolCandidate
+}
+
+func (e *ambiguousSymbolError) Error() string {
+	lines := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		lines[i] = fmt.Sprintf("  %d. %s %s at %d:%d", i+1, c.Kind, c.QualifiedName, c.Line, c.Column)
+	}
+	return fmt.Sprintf("symbol %q matches %d symbols in %s; pass occurrence (1-%d) or a qualified name to pick one:\n%s",
+		e.Name, len(e.Candidates), e.File, len(e.Candidates), strings.Join(lines, "\n"))
+}
+
+// symbolPosition resolves name, a plain or dotted qualified name, to the
+// 1-based position of the start of its symbol's selection range in file,
+// where a rename or a lookup by position would point. occurrence picks the
+// occurrence-th match in document order, counting from 1; 0 accepts only a
+// name that matches one symbol, and an *ambiguousSymbolError lists the
+// matches otherwise.
+func symbolPosition(ctx context.Context, src symbolSource, file, name string, occurrence int) (line, col int, err error) {
+	symbols, err := src.DocumentSymbol(ctx, file)
+	if err != nil {
+		return 0, 0, fmt.Errorf("document symbols error: %w", err)
+	}
+	found := findSymbolsByName(symbols, name)
+	switch {
+	case len(found) == 0:
+		return 0, 0, fmt.Errorf("symbol %q not found in %s", name, file)
+	case occurrence < 0 || occurrence > len(found):
+		return 0, 0, fmt.Errorf("occurrence %d out of range: symbol %q matches %s in %s", occurrence, name, plural(len(found), "symbol"), file)
+	case occurrence == 0 && len(found) > 1:
+		candidates := make([]symbolCandidate, len(found))
+		for i, f := range found {
+			candidates[i] = symbolCandidate{
+				QualifiedName: f.qualifiedName,
+				Kind:          symbolKindName(f.symbol.Kind),
+				Line:          int(f.symbol.SelectionRange.Start.Line) + 1,
+				Column:        int(f.symbol.SelectionRange.Start.Character) + 1,
+			}
+		}
+		return 0, 0, &ambiguousSymbolError{Name: name, File: file, Candidates: candidates}
+	case occurrence == 0:
+		occurrence = 1
+	}
+	start := found[occurrence-1].symbol.SelectionRange.Start
+	return int(start.Line) + 1, int(start.Character) + 1, nil
+}
@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+// staticSymbols answers every DocumentSymbol call with the same outline.
+type staticSymbols []protocol.DocumentSymbol
+
+func (s staticSymbols) DocumentSymbol(context.Context, string) ([]protocol.DocumentSymbol, error) {
+	return s, nil
+}
+
+// namedAt returns a symbol whose name starts at the 0-based line and
+// character.
+func namedAt(name string, kind protocol.SymbolKind, line, char uint32, children ...protocol.DocumentSymbol) protocol.DocumentSymbol {
+	start := protocol.Position{Line: line, Character: char}
+	return protocol.DocumentSymbol{
+		Name:           name,
+		Kind:           kind,
+		Range:          protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line + 1}},
+		SelectionRange: protocol.Range{Start: start, End: protocol.Position{Line: line, Character: char + uint32(len(name))}},
+		Children:       children,
+	}
+}
+
+// repoOutline is the outline of:
+//
+//	class UserRepo {
+//	  find() {}
+//	  save() {}
+//	}
+//	namespace legacy {
+//	  export class UserRepo {
+//	    find() {}
+//	  }
+//	}
+//	function save() {}
+var repoOutline = staticSymbols{
+	namedAt("UserRepo", protocol.SymbolKindClass, 0, 6,
+		namedAt("find", protocol.SymbolKindMethod, 1, 2),
+		namedAt("save", protocol.SymbolKindMethod, 2, 2),
+	),
+	namedAt("legacy", protocol.SymbolKindNamespace, 4, 10,
+		namedAt("UserRepo", protocol.SymbolKindClass, 5, 15,
+			namedAt("find", protocol.SymbolKindMethod, 6, 4),
+		),
+	),
+	namedAt("save", protocol.SymbolKindFunction, 10, 9),
+}
+
+func TestFindSymbolsByName(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "UserRepo", want: []string{"UserRepo", "legacy.UserRepo"}},
+		{name: "UserRepo.find", want: []string{"UserRepo.find", "legacy.UserRepo.find"}},
+		{name: "legacy.UserRepo.find", want: []string{"legacy.UserRepo.find"}},
+		{name: "save", want: []string{"UserRepo.save", "save"}},
+		{name: "legacy.find"},
+		{name: "Missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range findSymbolsByName(repoOutline, tt.name) {
+				got = append(got, f.qualifiedName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findSymbolsByName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSymbolPosition(t *testing.T) {
+	tests := []struct {
+		name       string
+		symbol     string
+		occurrence int
+		wantLine   int
+		wantCol    int
+		wantErr    string
+	}{
+		{name: "unique name", symbol: "legacy", wantLine: 5, wantCol: 11},
+		{name: "qualified name", symbol: "legacy.UserRepo", wantLine: 6, wantCol: 16},
+		{name: "occurrence", symbol: "UserRepo", occurrence: 2, wantLine: 6, wantCol: 16},
+		{name: "first occurrence", symbol: "UserRepo", occurrence: 1, wantLine: 1, wantCol: 7},
+		{name: "occurrence of a unique name", symbol: "legacy", occurrence: 1, wantLine: 5, wantCol: 11},
+		{name: "occurrence out of range", symbol: "UserRepo", occurrence: 3, wantErr: `occurrence 3 out of range: symbol "UserRepo" matches 2 symbols in /p/repo.ts`},
+		{name: "not found", symbol: "UserRepository", wantErr: `symbol "UserRepository" not found in /p/repo.ts`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, col, err := symbolPosition(context.Background(), repoOutline, "/p/repo.ts", tt.symbol, tt.occurrence)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line != tt.wantLine || col != tt.wantCol {
+				t.Errorf("position = %d:%d, want %d:%d", line, col, tt.wantLine, tt.wantCol)
+			}
+		})
+	}
+}
+
+func TestSymbolPositionAmbiguous(t *testing.T) {
+	_, _, err := symbolPosition(context.Background(), repoOutline, "/p/repo.ts", "save", 0)
+	var ambiguous *ambiguousSymbolError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("error = %v, want the candidates", err)
+	}
+	want := []symbolCandidate{
+		{QualifiedName: "UserRepo.save", Kind: "method", Line: 3, Column: 3},
+		{QualifiedName: "save", Kind: "function", Line: 11, Column: 10},
+	}
+	if !reflect.DeepEqual(ambiguous.Candidates, want) {
+		t.Errorf("candidates = %+v, want %+v", ambiguous.Candidates, want)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "pass occurrence (1-2)") || !strings.Contains(msg, "  2. function save at 11:10") {
+		t.Errorf("error = %q", msg)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -14,25 +16,44 @@ import (
 )
 
 type symbolEntry struct {
-	Name     string        `json:"name"`
-	Kind     string        `json:"kind"`
-	Line     int           `json:"line"`
-	Detail   string        `json:"detail,omitempty"`
-	Children []symbolEntry `json:"children,omitempty"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Line and EndLine are the first and last line of the symbol's range.
+	Line    int    `json:"line"`
+	EndLine int    `json:"endLine"`
+	Detail  string `json:"detail,omitempty"`
+	// Container is the dotted path of the enclosing symbols, such as
+	// "MyClass", in flat output.
+	Container string        `json:"container,omitempty"`
+	Exported  bool          `json:"exported"`
+	Children  []symbolEntry `json:"children,omitempty"`
 }
 
-func makeDocumentSymbolsHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+func makeDocumentSymbolsHandler(client *lsp.Client, docs *docsync.Manager, symbolCache *symbolCache) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		file, err := request.RequireString("file")
 		if err != nil {
 			return mcp.NewToolResultError(err.Error()), nil
 		}
+		visibility := request.GetString("visibility", visibilityAll)
+		if visibility != visibilityAll && visibility != visibilityExported && visibility != visibilityInternal {
+			return mcp.NewToolResultError(fmt.Sprintf("unknown visibility %q (valid: all, exported, internal)", visibility)), nil
+		}
+		kinds := make(map[string]bool)
+		for _, k := range request.GetStringSlice("kinds", nil) {
+			if !slices.Contains(symbolKindNames(), k) {
+				return mcp.NewToolResultError(fmt.Sprintf("unknown kind %q (valid: %s)", k, strings.Join(symbolKindNames(), ", "))), nil
+			}
+			kinds[k] = true
+		}
+		flat := request.GetBool("flat", false)
 
+		defer docs.Pin(file)()
 		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
 		}
 
-		symbols, err := client.DocumentSymbol(ctx, file)
+		symbols, err := symbolCache.GetSymbols(ctx, client, docs, file)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("document symbols error: %v", err)), nil
 		}
@@ -41,7 +62,25 @@ func makeDocumentSymbolsHandler(client *lsp.Client, docs *docsync.Manager) serve
 			return mcp.NewToolResultText("No symbols found"), nil
 		}
 
-		entries := convertSymbols(symbols)
+		lines, _ := cachedReadLines(file)
+		entries := convertSymbols(symbols, newExportInfo(lines), nil)
+		keep := func(e symbolEntry) bool {
+			if visibility != visibilityAll && e.Exported != (visibility == visibilityExported) {
+				return false
+			}
+			return len(kinds) == 0 || kinds[e.Kind]
+		}
+		if flat {
+			entries = flattenSymbols(entries, "", keep)
+		} else if visibility != visibilityAll || len(kinds) > 0 {
+			entries = filterSymbols(entries, keep)
+		}
+		if len(entries) == 0 {
+			if visibility != visibilityAll && len(kinds) == 0 {
+				return mcp.NewToolResultText(fmt.Sprintf("No %s symbols found", visibility)), nil
+			}
+			return mcp.NewToolResultText("No matching symbols found"), nil
+		}
 
 		data, err := json.MarshalIndent(entries, "", "  ")
 		if err != nil {
@@ -51,23 +90,56 @@ func makeDocumentSymbolsHandler(client *lsp.Client, docs *docsync.Manager) serve
 	}
 }
 
-func convertSymbols(symbols []protocol.DocumentSymbol) []symbolEntry {
+// convertSymbols converts the symbols nested in parent, nil at the top
+// level, marking the exported ones.
+func convertSymbols(symbols []protocol.DocumentSymbol, exports exportInfo, parent *symbolEntry) []symbolEntry {
 	entries := make([]symbolEntry, len(symbols))
 	for i, sym := range symbols {
 		entry := symbolEntry{
-			Name:   sym.Name,
-			Kind:   symbolKindName(sym.Kind),
-			Line:   int(sym.Range.Start.Line) + 1,
-			Detail: sym.Detail,
+			Name:    sym.Name,
+			Kind:    symbolKindName(sym.Kind),
+			Line:    int(sym.Range.Start.Line) + 1,
+			EndLine: int(sym.Range.End.Line) + 1,
+			Detail:  sym.Detail,
 		}
+		entry.Exported = exports.exported(sym, parent)
 		if len(sym.Children) > 0 {
-			entry.Children = convertSymbols(sym.Children)
+			entry.Children = convertSymbols(sym.Children, exports, &entry)
 		}
 		entries[i] = entry
 	}
 	return entries
 }
 
+// flattenSymbols lists the entries for which keep holds, and those of
+// their descendants, in document order without children. Each carries the
+// dotted path of its ancestors, below container, as its Container.
+func flattenSymbols(entries []symbolEntry, container string, keep func(symbolEntry) bool) []symbolEntry {
+	out := []symbolEntry{}
+	for _, e := range entries {
+		path := e.Name
+		if container != "" {
+			path = container + "." + e.Name
+		}
+		children := e.Children
+		e.Children, e.Container = nil, container
+		if keep(e) {
+			out = append(out, e)
+		}
+		out = append(out, flattenSymbols(children, path, keep)...)
+	}
+	return out
+}
+
+// symbolKindNames are the names symbolKindName gives the LSP symbol kinds.
+func symbolKindNames() []string {
+	names := make([]string, 0, int(protocol.SymbolKindTypeParameter))
+	for k := protocol.SymbolKindFile; k <= protocol.SymbolKindTypeParameter; k++ {
+		names = append(names, symbolKindName(k))
+	}
+	return names
+}
+
 func symbolKindName(k protocol.SymbolKind) string {
 	switch k {
 	case protocol.SymbolKindFile:
@@ -110,6 +182,8 @@ func symbolKindName(k protocol.SymbolKind) string {
 		return "object"
 	case protocol.SymbolKindKey:
 		return "key"
+	case protocol.SymbolKindNull:
+		return "null"
 	case protocol.SymbolKindEnumMember:
 		return "enum_member"
 	case protocol.SymbolKindStruct:
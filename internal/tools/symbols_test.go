@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func TestDocumentSymbolFilters(t *testing.T) {
+	sym := func(name string, kind protocol.SymbolKind, line, endLine uint32, children ...protocol.DocumentSymbol) protocol.DocumentSymbol {
+		r := protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: endLine}}
+		return protocol.DocumentSymbol{Name: name, Kind: kind, Range: r, SelectionRange: r, Children: children}
+	}
+	symbols := []protocol.DocumentSymbol{
+		sym("Repo", protocol.SymbolKindClass, 0, 9,
+			sym("db", protocol.SymbolKindProperty, 1, 1),
+			sym("find", protocol.SymbolKindMethod, 2, 4,
+				sym("key", protocol.SymbolKindConstant, 3, 3)),
+			sym("save", protocol.SymbolKindMethod, 5, 8)),
+		sym("createRepo", protocol.SymbolKindFunction, 11, 13),
+		sym("defaults", protocol.SymbolKindVariable, 15, 18,
+			sym("size", protocol.SymbolKindProperty, 16, 16)),
+	}
+	entries := convertSymbols(symbols, exportInfo{}, nil)
+	if e := entries[0]; e.Line != 1 || e.EndLine != 10 || e.Children[1].EndLine != 5 {
+		t.Errorf("spans = %d-%d, find ends at %d", e.Line, e.EndLine, e.Children[1].EndLine)
+	}
+
+	methods := func(e symbolEntry) bool { return e.Kind == "method" || e.Kind == "function" }
+	if got, want := outline(filterSymbols(entries, methods)), "Repo-(find- save-) createRepo-"; got != want {
+		t.Errorf("filtered tree = %s, want %s", got, want)
+	}
+
+	type flatEntry struct{ container, name string }
+	var got []flatEntry
+	for _, e := range flattenSymbols(entries, "", methods) {
+		if e.Children != nil {
+			t.Errorf("flat entry %s has children", e.Name)
+		}
+		got = append(got, flatEntry{e.Container, e.Name})
+	}
+	want := []flatEntry{{"Repo", "find"}, {"Repo", "save"}, {"", "createRepo"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("flat = %v, want %v", got, want)
+	}
+
+	all := flattenSymbols(entries, "", func(symbolEntry) bool { return true })
+	if len(all) != 8 || all[3].Name != "key" || all[3].Container != "Repo.find" || all[3].EndLine != 4 {
+		t.Errorf("flat without a filter = %+v", all)
+	}
+	if none := flattenSymbols(entries, "", func(symbolEntry) bool { return false }); none == nil || len(none) != 0 {
+		t.Errorf("flat with nothing kept = %+v", none)
+	}
+}
+
+func TestSymbolKindNames(t *testing.T) {
+	names := symbolKindNames()
+	if len(names) != 26 || names[0] != "file" || names[25] != "type_parameter" {
+		t.Errorf("names = %v", names)
+	}
+	if slices.ContainsFunc(names, func(n string) bool { return strings.HasPrefix(n, "kind(") }) {
+		t.Errorf("a kind has no name: %v", names)
+	}
+}
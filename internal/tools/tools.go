@@ -1,78 +1,683 @@
 package tools
 
 import (
+	"context"
+	"log/slog"
+	"os"
+
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
 	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
 	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
 )
 
-// Register adds all TypeScript tool handlers to the MCP server.
-func Register(s *server.MCPServer, client *lsp.Client, docs *docsync.Manager) {
-	s.AddTool(mcp.NewTool("ts_diagnostics",
-		mcp.WithDescription("Get TypeScript errors and warnings. Use after editing code to check for type errors."),
+// lspFreeTools are the tools that answer without tsgo, so they do not wait
+// for its startup.
+var lspFreeTools = map[string]bool{
+	"ts_list_edits":           true,
+	"ts_project_info":         true,
+	"ts_list_projects":        true,
+	"ts_import_cycles":        true,
+	"ts_ambient_declarations": true,
+	"ts_server_status":        true,
+}
+
+// toolCapabilities are the requests tools cannot work without, for the
+// tools that have no fallback of their own when tsgo lacks one. Such a
+// tool answers with an error naming the request instead of calling tsgo.
+var toolCapabilities = map[string]string{
+	"ts_definition":        protocol.MethodTextDocumentDefinition,
+	"ts_implementations":   protocol.MethodTextDocumentImplementation,
+	"ts_hover":             protocol.MethodTextDocumentHover,
+	"ts_hover_batch":       protocol.MethodTextDocumentHover,
+	"ts_hover_many":        protocol.MethodTextDocumentHover,
+	"ts_signature_help":    protocol.MethodTextDocumentSignatureHelp,
+	"ts_references":        protocol.MethodTextDocumentReferences,
+	"ts_completion":        protocol.MethodTextDocumentCompletion,
+	"ts_document_symbols":  protocol.MethodTextDocumentDocumentSymbol,
+	"ts_workspace_symbols": protocol.MethodWorkspaceSymbol,
+	"ts_code_actions":      protocol.MethodTextDocumentCodeAction,
+	"ts_apply_code_action": protocol.MethodTextDocumentCodeAction,
+	"ts_extract_refactor":  protocol.MethodTextDocumentCodeAction,
+	"ts_fix_all":           protocol.MethodTextDocumentCodeAction,
+	"ts_rename":            protocol.MethodTextDocumentRename,
+}
+
+// Register adds all TypeScript tool handlers to the MCP server, followed by
+// the aliases of the config file named by TYPESCRIPT_MCP_CONFIG. It fails
+// when that file cannot be read or an alias is invalid. client may still be
+// starting: tools that need tsgo wait for it, the others answer at once.
+func Register(s *server.MCPServer, client *lsp.Client, docs *docsync.Manager) error {
+	return RegisterWithOptions(s, client, docs, RegisterOptions{})
+}
+
+// RegisterWithOptions is Register with the tools renamed or left out as
+// opts says. It fails without adding anything when a tool or alias name
+// is already registered on s.
+func RegisterWithOptions(s *server.MCPServer, client *lsp.Client, docs *docsync.Manager, opts RegisterOptions) error {
+	names, err := newToolNames(opts.Prefix)
+	if err != nil {
+		return err
+	}
+	config, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+	home, _ := os.UserHomeDir()
+	redact, err := newRedactor(config.Redaction, client.RootDir(), home)
+	if err != nil {
+		return err
+	}
+	journal := newJournalPolicy(client.RootDir(), config)
+	recorder := newEditRecorder(client.RootDir(), config)
+	if journals, _ := listPendingJournals(journal.dir); len(journals) > 0 {
+		for _, j := range journals {
+			slog.Warn("found an interrupted edit; complete or roll it back with "+names.of("ts_recover_pending_edit"), "id", j.ID, "files", j.Files, "written", j.Written)
+		}
+	}
+	shared := &sharedToolState{
+		names:    names,
+		config:   config,
+		redact:   redact,
+		pending:  newEditTokenStore(editTokenTTLFromEnv()),
+		packages: workspace.NewPackageResolver(),
+		journal:  journal,
+		recorder: recorder,
+		debug:    os.Getenv("TYPESCRIPT_MCP_DEBUG") != "",
+	}
+
+	set := toolSet(client, docs, shared)
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	router := newProjectRouter(client.RootDir(), projectIdleTTLFromEnv(), func(root string) (*lsp.Client, []registeredTool) {
+		c := lsp.StartClient(ctx, docsync.FileToURI(root), lsp.StartTsgo)
+		return c, toolSet(c, docsync.NewManager(), shared)
+	})
+	cancels := newCallCancels()
+	if opts.Hooks != nil {
+		cancels.install(s, opts.Hooks)
+	}
+	for i := range set {
+		set[i].handler = cancels.wrap(router.route(set[i].tool.Name, set[i].handler))
+	}
+	return registerToolSet(s, set, config.Aliases, opts)
+}
+
+// sharedToolState is the state the tool sets of all projects share: edit
+// tokens, journals and the edit record follow an edit whichever tsgo
+// computed it.
+type sharedToolState struct {
+	names    toolNames
+	config   *configFile
+	redact   *redactor
+	pending  *editTokenStore
+	packages *workspace.PackageResolver
+	journal  *journalPolicy
+	recorder *editRecorder
+	debug    bool
+}
+
+// toolSet returns the built-in tools bound to client and docs.
+func toolSet(client *lsp.Client, docs *docsync.Manager, shared *sharedToolState) []registeredTool {
+	names, config, redact := shared.names, shared.config, shared.redact
+	pending, packages, journal, recorder := shared.pending, shared.packages, shared.journal, shared.recorder
+	symbolCache := newSymbolCache(defaultSymbolCacheSize)
+	refCursors := newCursorStore[referenceEntry](0, 0)
+	diagCursors := newCursorStore[diagnosticEntry](0, 0)
+	docs.OnChange(func(path string, version int32) {
+		refCursors.InvalidateFile(path, version)
+		diagCursors.InvalidateFile(path, version)
+	})
+	if n := maxOpenDocsFromEnv(); n > 0 {
+		docs.SetMaxOpen(n)
+	}
+	if d, ok := syncFreshnessFromEnv(); ok {
+		docs.SetFreshness(d)
+	}
+	// A restarted tsgo knows none of the documents open with the last one.
+	client.OnRestart(docs.Reopen)
+	client.SetEditApplier(serverEditApplier(client, docs, pending, packages, journal, recorder))
+	docs.SetIncremental(client.SupportsIncrementalSync)
+
+	// Probe the workspace in the background so the first tool call rarely
+	// waits on it.
+	probe := workspace.NewProber(client.RootDir())
+	go probe.Status()
+
+	debug := shared.debug
+	var set []registeredTool
+	add := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		mcp.WithBoolean("summaryOnly", mcp.Description("Return only the summary line, without the detail (default false)"))(&tool)
+		if summary, ok := toolSummaries[tool.Name]; ok {
+			tool.Description += summaryDescription(summary)
+		}
+		if streamableTools[tool.Name] {
+			handler = withStreaming(redact, handler)
+		}
+		if !config.StrictTypes {
+			handler = withArgumentCoercion(tool, debug, handler)
+		}
+		if method, ok := toolCapabilities[tool.Name]; ok {
+			handler = withCapability(client, method, names.of(tool.Name), names.of("ts_server_info"), handler)
+		}
+		if !lspFreeTools[tool.Name] {
+			readOnly := tool.Annotations.ReadOnlyHint
+			handler = withLSPReady(client, readOnly != nil && *readOnly, handler)
+		}
+		handler = withVersionWarning(client.VersionWarning, withWorkspaceWarning(probe, handler))
+		handler = withSummary(client.RootDir(), tool.Name, handler)
+		handler = withRedaction(redact, handler)
+		set = append(set, registeredTool{tool: tool, handler: handler})
+	}
+
+	add(mcp.NewTool("ts_diagnostics",
+		mcp.WithDescription("Get TypeScript errors and warnings. Use after editing code to check for type errors. Pass files to check several files at once; the result then maps each file to its diagnostics, each truncated to maxResults, with counts over all of them."),
 		mcp.WithString("file", mcp.Description("Absolute path to check a single file")),
+		mcp.WithArray("files", mcp.WithStringItems(), mcp.Description("Absolute paths of files to check together (at most 50), instead of file")),
 		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json (auto-detected if omitted)")),
-		mcp.WithNumber("maxResults", mcp.Description("Maximum errors to return (default 50)")),
+		mcp.WithNumber("maxResults", mcp.Description("Page size: maximum errors to return (default 50); with files, per file")),
+		mcp.WithString("severity", mcp.Description("Least severe diagnostics to return: error, warning, information or hint (default hint, i.e. all). Filters before maxResults; filteredOut counts the diagnostics dropped")),
+		mcp.WithArray("codes", mcp.WithNumberItems(), mcp.Description("Only return diagnostics with these TypeScript error codes, e.g. [2322, 2345]")),
+		mcp.WithString("groupBy", mcp.Description("\"code\" to return the diagnostics grouped by code instead of listed: per code, the count, the message of the first one and up to 3 example locations, the most frequent code first. maxResults then bounds the groups")),
+		mcp.WithNumber("contextLines", mcp.Description("Lines of source before and after each diagnostic to return in its context, numbered, the diagnostic's line marked by '>' (default 0, at most 10)")),
+		mcp.WithString("cursor", mcp.Description("nextCursor of a previous page; continues that result instead of re-checking the file")),
+		mcp.WithBoolean("waitForProjectLoad", mcp.Description("Wait until tsgo has finished loading the project before checking, so cross-file errors are not missed after startup or a branch switch (default false). projectStillLoading is set when the wait timed out")),
+		mcp.WithBoolean("classifyCauses", mcp.Description("Classify module-not-found errors by cause, from node_modules and generated-code globs: missingPackage, missingTypes, typesNotIncluded, generated or missingFile. Each classified entry gets a cause with a remedy (e.g. \"install @types/lodash\", \"run codegen\"), and causes counts them (default false)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeDiagnosticsHandler(client, docs, diagCursors, config.GeneratedPaths))
+
+	add(mcp.NewTool("ts_project_diagnostics",
+		mcp.WithDescription("Check every file of the project for TypeScript errors and warnings, e.g. after a refactor. The files are those the tsconfig's files/include/exclude select, or without a tsconfig, the TypeScript files under the project root outside node_modules. They are opened with tsgo in batches and closed again once checked. Returns the total errors and warnings and the files with diagnostics, the most errors first, each with its first diagnostics; use ts_diagnostics on a file for all of them."),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json (default: tsconfig.json at the project root)")),
+		mcp.WithString("severity", mcp.Description("Least severe diagnostics to count: error, warning, information or hint (default hint, i.e. all)")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum files to list (default 50); the totals count every file")),
+		mcp.WithNumber("timeoutSeconds", mcp.Description("Stop checking after this long and return the files checked so far, with timedOut set (default 120)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeProjectDiagnosticsHandler(client, docs, names.of("ts_close_files")))
+
+	add(mcp.NewTool("ts_definition",
+		mcp.WithDescription("Go to definition of a symbol. Returns file and position where the symbol is defined, with a preview of the source line, or with includeBody the full source of the definition. Re-exports and declaration files with a declaration map are followed to the original source. Definitions in node_modules also carry the owning package and a short displayPath."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
+		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
+		mcp.WithBoolean("includeBody", mcp.Description("Also return the full source of each definition, the lines of its enclosing symbol, to save a file read (default false)")),
+		mcp.WithNumber("maxBodyLines", mcp.Description("Most lines of each body (default 40)")),
+		mcp.WithBoolean("nodeModulesBodies", mcp.Description("Also return bodies of definitions under node_modules, which are skipped by default (default false)")),
+		mcp.WithBoolean("raw", mcp.Description("Return the language server's locations as they are, without following re-exports and declaration maps to the original source (default false)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeDefinitionHandler(client, docs, packages, symbolCache))
+
+	add(mcp.NewTool("ts_type_definition",
+		mcp.WithDescription("Go to the definition of the type of a symbol or expression, e.g. the Repo interface for `const repo = createRepo()` rather than the variable. Returns the same entries as ts_definition, or \"No type definition found\" for types without a declaration such as primitives."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
+		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeTypeDefinitionHandler(client, docs, packages))
+
+	add(mcp.NewTool("ts_implementations",
+		mcp.WithDescription("Find the implementations of an interface, an interface or abstract member, or a type at a position, e.g. the classes implementing an interface method that ts_definition stops at. Returns the same entries as ts_definition, or \"No implementations found\"."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
+		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeImplementationsHandler(client, docs, packages))
+
+	add(mcp.NewTool("ts_call_hierarchy",
+		mcp.WithDescription("Show who calls the function or method at a position (incoming), or what it calls (outgoing), as a tree. With depth > 1 the callers of callers (or callees of callees) are expanded too; a call back into a function already on the path is marked recursive and not expanded again. Each node has name, kind, file, line, column and the call-site ranges."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
+		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
+		mcp.WithString("direction", mcp.Enum(callsIncoming, callsOutgoing), mcp.Description("\"incoming\" for callers, \"outgoing\" for callees (default \"incoming\")")),
+		mcp.WithNumber("depth", mcp.Description("Levels of calls to expand, at most 5 (default 1)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithDestructiveHintAnnotation(false),
-	), makeDiagnosticsHandler(client, docs))
+	), makeCallHierarchyHandler(client, docs))
 
-	s.AddTool(mcp.NewTool("ts_definition",
-		mcp.WithDescription("Go to definition of a symbol. Returns file and position where the symbol is defined, with a preview of the source line."),
+	add(mcp.NewTool("ts_type_hierarchy",
+		mcp.WithDescription("Show what the class or interface at a position extends and implements (supertypes), or what extends or implements it (subtypes), as a tree. With depth > 1 the supertypes of supertypes (or subtypes of subtypes) are expanded too. Each node has name, kind, file, line and column."),
 		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
 		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
 		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
-		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json")),
+		mcp.WithString("direction", mcp.Enum(typesSuper, typesSub), mcp.Description("\"supertypes\" for base classes and interfaces, \"subtypes\" for derived ones (default \"supertypes\")")),
+		mcp.WithNumber("depth", mcp.Description("Levels of types to expand, at most 5 (default 1)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithDestructiveHintAnnotation(false),
-	), makeDefinitionHandler(client, docs))
+	), makeTypeHierarchyHandler(client, docs))
 
-	s.AddTool(mcp.NewTool("ts_hover",
-		mcp.WithDescription("Get type information and documentation for a symbol at a position. Returns the resolved type signature."),
+	add(mcp.NewTool("ts_hover",
+		mcp.WithDescription("Get type information and documentation for a symbol at a position. Returns the resolved type signature, or with format \"full\", JSON with the signature, the JSDoc documentation and its tags."),
 		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
 		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
 		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
-		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json")),
+		mcp.WithString("format", mcp.Description("\"concise\" for the type signature only (default), or \"full\" for {signature, documentation, tags}, e.g. to learn what an API does")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithDestructiveHintAnnotation(false),
 	), makeHoverHandler(client, docs))
 
-	s.AddTool(mcp.NewTool("ts_references",
-		mcp.WithDescription("Find all references to a symbol across the project. Returns every location where the symbol is used."),
+	add(mcp.NewTool("ts_hover_batch",
+		mcp.WithDescription("Get the types of many positions in one file at once, e.g. every identifier of interest in a function. Pass positions, or startLine/endLine with identifiers to hover each whole-word occurrence in those lines. Returns one result per position in input order, each with the concise type signature or its own error; at most 50 positions per call. With render, returns the source lines with the types as trailing comments instead."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithArray("positions", mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"line":   map[string]any{"type": "number", "description": "Line number (1-based)"},
+				"column": map[string]any{"type": "number", "description": "Column number (1-based)"},
+			},
+			"required": []string{"line", "column"},
+		}), mcp.Description("Positions to hover")),
+		mcp.WithNumber("startLine", mcp.Description("First line to scan for identifiers (1-based), instead of positions")),
+		mcp.WithNumber("endLine", mcp.Description("Last line to scan (default startLine)")),
+		mcp.WithArray("identifiers", mcp.WithStringItems(), mcp.Description("Identifiers whose occurrences in the scanned lines are hovered; required with startLine")),
+		mcp.WithBoolean("render", mcp.Description("Return the requested lines annotated with the types as trailing comments instead of JSON (default false)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeHoverBatchHandler(client, docs))
+
+	add(mcp.NewTool("ts_hover_many",
+		mcp.WithDescription("Get the types of positions across several files at once, e.g. the symbols a stack trace or a search turned up. Syncs each file once and hovers the positions concurrently. Returns one result per position in input order, each with the concise type signature or its own error; at most 25 positions per call."),
+		mcp.WithArray("positions", mcp.Required(), mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"file":   map[string]any{"type": "string", "description": "Absolute file path"},
+				"line":   map[string]any{"type": "number", "description": "Line number (1-based)"},
+				"column": map[string]any{"type": "number", "description": "Column number (1-based)"},
+			},
+			"required": []string{"file", "line", "column"},
+		}), mcp.Description("Positions to hover")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeHoverManyHandler(client, docs))
+
+	add(mcp.NewTool("ts_signature_help",
+		mcp.WithDescription("Get the signatures of the call being written at a position, e.g. inside the parentheses of foo(a, |). Returns every overload with its parameters and documentation, plus the 0-based indexes of the active signature and of the parameter at the position."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
+		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based), inside the call's parentheses")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeSignatureHelpHandler(client, docs))
+
+	add(mcp.NewTool("ts_inlay_hints",
+		mcp.WithDescription("Get the inlay hints of a file or a range of lines: the types TypeScript inferred for untyped variables, parameters and return values, and the parameter name each argument of a call maps to. Each hint has line, column, label and kind (\"type\" or \"parameter\")."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("startLine", mcp.Description("First line (1-based, default 1)")),
+		mcp.WithNumber("endLine", mcp.Description("Last line (default: the end of the file)")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum hints to return (default 50)")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeInlayHintsHandler(client, docs))
+
+	add(mcp.NewTool("ts_semantic_tokens",
+		mcp.WithDescription("Classify the identifiers of a file or a range of lines in one call: each token has line, column, length, text, type (\"class\", \"enumMember\", \"variable\", \"parameter\", ...) and modifiers (\"declaration\", \"readonly\", ...). Cheaper than hovering each identifier to tell types from values."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("startLine", mcp.Description("First line (1-based, default 1)")),
+		mcp.WithNumber("endLine", mcp.Description("Last line (default: the end of the file)")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum tokens to return (default 200)")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeSemanticTokensHandler(client, docs))
+
+	add(mcp.NewTool("ts_references",
+		mcp.WithDescription("Find all references to a symbol across the project. Returns every location where the symbol is used, sorted by file and position; locations in node_modules also carry the owning package and a short displayPath. Results beyond maxResults are paged: pass nextCursor as cursor to continue."),
+		mcp.WithString("file", mcp.Description("Absolute file path (required without cursor)")),
+		mcp.WithNumber("line", mcp.Description("Line number (1-based, required without cursor)")),
+		mcp.WithNumber("column", mcp.Description("Column number (1-based, required without cursor)")),
+		mcp.WithNumber("maxResults", mcp.Description("Page size: maximum references to return (default 50)")),
+		mcp.WithString("cursor", mcp.Description("nextCursor of a previous page; serves the next page of that snapshot instead of re-querying")),
+		mcp.WithNumber("maxPreviews", mcp.Description("Maximum source-line previews to read; files with the most hits are previewed first (default 100)")),
+		mcp.WithBoolean("includeDeclaration", mcp.Description("Include the symbol's declaration among the references (default true); false leaves only its usages, e.g. before a rename")),
+		mcp.WithArray("include", mcp.WithStringItems(), mcp.Description("Only keep references in files matching one of these tsconfig-style globs or paths, relative to the project root; a directory covers everything below it, e.g. [\"packages/api\"]")),
+		mcp.WithArray("exclude", mcp.WithStringItems(), mcp.Description("Leave out references in files matching one of these globs or paths, e.g. [\"**/node_modules\", \"**/*.test.ts\"]. totalCount counts the references kept, unfilteredCount all of them")),
+		mcp.WithBoolean("groupByFile", mcp.Description("Return the files with references instead, each with its count and first references, the most references first. maxResults then bounds the files; fileCount and totalCount count them all. Not paged (default false)")),
+		mcp.WithNumber("previewsPerFile", mcp.Description("With groupByFile, the references listed per file (default 3)")),
+		mcp.WithBoolean("excludeDeclarationFiles", mcp.Description("Leave out references in declaration files (.d.ts, .d.mts, .d.cts) and under node_modules (default false). totalCount and paging count only the references kept")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeReferencesHandler(client, docs, packages, refCursors))
+
+	add(mcp.NewTool("ts_document_highlights",
+		mcp.WithDescription("Find the occurrences of the symbol at a position within its own file, each tagged \"read\", \"write\" or \"text\". Faster than ts_references when only same-file occurrences matter, and tells which ones assign to the symbol."),
 		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
 		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
 		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
-		mcp.WithNumber("maxResults", mcp.Description("Maximum references to return (default 50)")),
-		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithDestructiveHintAnnotation(false),
-	), makeReferencesHandler(client, docs))
+	), makeDocumentHighlightsHandler(client, docs))
 
-	s.AddTool(mcp.NewTool("ts_document_symbols",
-		mcp.WithDescription("Get the symbol outline of a file. Returns a tree of all functions, classes, interfaces, and variables with their types."),
+	add(mcp.NewTool("ts_completion",
+		mcp.WithDescription("Get the code completions tsgo offers at a position, e.g. after \"obj.\" to see what a value provides. Returns each completion's label, kind, detail (usually its type) and the text to insert, in editor order."),
 		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
-		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json")),
+		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
+		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based), typically just after the \".\" or the partial identifier")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum completions to return (default 50)")),
+		mcp.WithNumber("resolveDocs", mcp.Description("Fetch the documentation of the first n completions, at most 20 (default 0)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithDestructiveHintAnnotation(false),
-	), makeDocumentSymbolsHandler(client, docs))
+	), makeCompletionHandler(client, docs))
 
-	s.AddTool(mcp.NewTool("ts_rename",
-		mcp.WithDescription("Rename a symbol across the project. Applies all changes to disk and returns a summary of modified files."),
+	add(mcp.NewTool("ts_document_symbols",
+		mcp.WithDescription("Get the symbol outline of a file. Returns a tree of all functions, classes, interfaces, and variables with their types and line spans, each marked exported or not."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithString("visibility", mcp.Enum(visibilityAll, visibilityExported, visibilityInternal), mcp.Description("Which symbols to list: \"exported\" for the module's public surface, \"internal\" for the rest (default all). Non-matching parents of matching symbols are kept for context")),
+		mcp.WithArray("kinds", mcp.WithStringItems(), mcp.Description("Only list symbols of these kinds, such as [\"function\", \"class\"]. Non-matching parents of matching symbols are kept for context")),
+		mcp.WithBoolean("flat", mcp.Description("Return a flat list in document order instead of a tree, each symbol with its container path such as \"MyClass\" (default false)")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeDocumentSymbolsHandler(client, docs, symbolCache))
+
+	add(mcp.NewTool("ts_folding_ranges",
+		mcp.WithDescription("Get the foldable regions of a file with their exact line spans: the import block, comments, #region markers, and code such as functions, classes and blocks. Use it to find the lines of a region in a large file and read only those. Each range has startLine, endLine (1-based), kind and a preview of its first line."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithString("kind", mcp.Enum(foldingKinds...), mcp.Description("Only return ranges of this kind")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeFoldingRangesHandler(client, docs))
+
+	add(mcp.NewTool("ts_selection_range",
+		mcp.WithDescription("Get the chain of syntax ranges enclosing a position, from the innermost (the identifier) out through its expressions, statements and blocks to the whole file, e.g. to find the smallest enclosing expression or statement to extract. Each range has 1-based start and end positions and a short preview. Pass positions to get the chains of several positions in one call."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Description("Line number (1-based); with column, instead of positions")),
+		mcp.WithNumber("column", mcp.Description("Column number (1-based)")),
+		mcp.WithArray("positions", mcp.Items(map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"line":   map[string]any{"type": "number", "description": "Line number (1-based)"},
+				"column": map[string]any{"type": "number", "description": "Column number (1-based)"},
+			},
+			"required": []string{"line", "column"},
+		}), mcp.Description("Positions to expand, at most 50, instead of line and column")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeSelectionRangeHandler(client, docs))
+
+	add(mcp.NewTool("ts_symbol_at_position",
+		mcp.WithDescription("Tell which function, class or other symbol a position is inside, e.g. for a stack trace line or a diagnostic. Returns the chain of enclosing symbols, outermost first, each with its kind and range, and a breadcrumb such as \"UserService > updateProfile > callback\". Outside every symbol, such as a top-level statement, the chain is empty and the nearest preceding symbol is given as a hint."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
+		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeSymbolAtPositionHandler(client, docs, symbolCache))
+
+	add(mcp.NewTool("ts_workspace_symbols",
+		mcp.WithDescription("Search the whole project for symbols by name, e.g. to find the file that declares a class or function. Matching is fuzzy, best matches first. Returns each symbol's name, kind, file, position and container; symbols in node_modules also carry the owning package and a short displayPath."),
+		mcp.WithString("query", mcp.Required(), mcp.Description("Name or part of a name to search for")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum symbols to return (default 50)")),
+		mcp.WithString("file", mcp.Description("Absolute path of a file in the project to search; opened first so tsgo has the project loaded (default: the projects of the open files)")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeWorkspaceSymbolsHandler(client, docs, packages))
+
+	add(mcp.NewTool("ts_symbol_card",
+		mcp.WithDescription("Get everything known about a symbol in one call: qualified name, kind, declaration, signature, JSDoc summary, export status and import specifier, reference counts by directory, deprecation, and enclosing symbols."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("line", mcp.Description("Line number (1-based); required unless symbol is given")),
+		mcp.WithNumber("column", mcp.Description("Column number (1-based); required unless symbol is given")),
+		mcp.WithString("symbol", mcp.Description("Symbol name to look up in file instead of a position (e.g. \"MyClass.method\")")),
+		mcp.WithString("forFile", mcp.Description("Absolute path of the file that would import the symbol; enables importSpecifier")),
+		mcp.WithArray("sections", mcp.WithStringEnumItems(allCardSections), mcp.Description("Sections to fetch (default all): signature, declaration, references")),
+		mcp.WithString("format", mcp.Enum("json", "markdown"), mcp.Description("Text rendering of the card (default json)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithOutputSchema[symbolCard](),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeSymbolCardHandler(client, docs, packages, symbolCache))
+
+	add(mcp.NewTool("ts_code_actions",
+		mcp.WithDescription("List the quick fixes and refactorings tsgo offers for a range, e.g. adding a missing import, removing an unused variable or marking a function async. The file's diagnostics in the range are sent along, so their fixes are included. Returns each action's index, title, kind, and whether it carries an edit or a command; apply one with ts_apply_code_action."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("startLine", mcp.Required(), mcp.Description("First line of the range (1-based)")),
+		mcp.WithNumber("startColumn", mcp.Required(), mcp.Description("Start column (1-based)")),
+		mcp.WithNumber("endLine", mcp.Description("Last line of the range (default startLine)")),
+		mcp.WithNumber("endColumn", mcp.Description("End column (default startColumn)")),
+		mcp.WithString("kind", mcp.Description("Only list actions of this kind or its sub-kinds, e.g. \"quickfix\", \"refactor\", \"refactor.extract\" or \"source\"")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeCodeActionsHandler(client, docs))
+
+	add(mcp.NewTool("ts_apply_code_action",
+		mcp.WithDescription("Apply one of the code actions ts_code_actions lists for a range, writing its edit to disk with the same checks and rollback as ts_rename. The actions are requested again for the range, and the one with the given title, or else at the given index, is applied. Actions that only run a command are not supported."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("startLine", mcp.Required(), mcp.Description("First line of the range (1-based)")),
+		mcp.WithNumber("startColumn", mcp.Required(), mcp.Description("Start column (1-based)")),
+		mcp.WithNumber("endLine", mcp.Description("Last line of the range (default startLine)")),
+		mcp.WithNumber("endColumn", mcp.Description("End column (default startColumn)")),
+		mcp.WithString("kind", mcp.Description("The kind filter the actions were listed with, if any")),
+		mcp.WithString("title", mcp.Description("Exact title of the action to apply")),
+		mcp.WithNumber("index", mcp.Description("Index of the action in the ts_code_actions listing; used when title is not given")),
+		mcp.WithBoolean("confirm", mcp.Description("Preview the action as diffs and return an editToken for ts_apply_edit instead of writing (default false)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeApplyCodeActionHandler(client, docs, pending, packages, config.EditOverlayCheck, journal, recorder))
+
+	add(mcp.NewTool("ts_extract_refactor",
+		mcp.WithDescription("Extract the selected code into a new function, method, constant or type. Without pick, lists the extract refactorings tsgo offers for the selection, with the reason any of them is disabled. With pick, applies the refactoring with that title, writing its edit to disk with the same checks and rollback as ts_rename, and returns the name of the generated declaration and the files touched; rename it afterwards with ts_rename."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("startLine", mcp.Required(), mcp.Description("First line of the selection (1-based)")),
+		mcp.WithNumber("startColumn", mcp.Required(), mcp.Description("Start column (1-based)")),
+		mcp.WithNumber("endLine", mcp.Description("Last line of the selection (default startLine)")),
+		mcp.WithNumber("endColumn", mcp.Description("End column, exclusive (default startColumn)")),
+		mcp.WithString("pick", mcp.Description("Exact title of the refactoring to apply, e.g. \"Extract to function in module scope\"")),
+		mcp.WithBoolean("confirm", mcp.Description("Preview the refactoring as diffs and return an editToken for ts_apply_edit instead of writing (default false)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeExtractRefactorHandler(client, docs, pending, packages, config.EditOverlayCheck, journal, recorder))
+
+	add(mcp.NewTool("ts_fix_all",
+		mcp.WithDescription("Apply tsgo's quick fix to every diagnostic with a given code, e.g. 6133 for unused declarations or 2551 for \"did you mean\" misspellings, in one file or, without file, in the whole project. The fixes are merged into one edit and written with the same checks and rollback as ts_rename; a fix whose edits overlap an earlier one is skipped. Returns the fixes applied, those skipped and why, and the diagnostics with the code that remain afterwards."),
+		mcp.WithNumber("code", mcp.Required(), mcp.Description("Diagnostic code to fix, e.g. 6133")),
+		mcp.WithString("file", mcp.Description("Absolute file path; the whole project when omitted")),
+		mcp.WithString("fix", mcp.Description("Only apply fixes whose title contains this text, e.g. \"Remove\" or \"Prefix\"; by default the preferred fix, or else the first, is applied")),
+		mcp.WithBoolean("confirm", mcp.Description("Preview the fixes as diffs and return an editToken for ts_apply_edit instead of writing (default false)")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; selects the project's files when file is omitted")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeFixAllHandler(client, docs, pending, packages, config.EditOverlayCheck, journal, recorder))
+
+	add(mcp.NewTool("ts_format",
+		mcp.WithDescription("Format a file with tsgo's formatter and write the result to disk. Pass startLine (and endLine) to format only those lines. Indentation defaults to what the file already uses. Returns the number of edits and whether the file changed."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path")),
+		mcp.WithNumber("tabSize", mcp.Description("Spaces per indentation level (default: detected from the file)")),
+		mcp.WithBoolean("insertSpaces", mcp.Description("Indent with spaces rather than tabs (default: detected from the file)")),
+		mcp.WithNumber("startLine", mcp.Description("First line to format (1-based); formats the whole file when omitted")),
+		mcp.WithNumber("endLine", mcp.Description("Last line to format (default startLine)")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeFormatHandler(client, docs, pending, config.EditOverlayCheck, journal, recorder))
+
+	add(mcp.NewTool("ts_prepare_rename",
+		mcp.WithDescription("Check whether the symbol at a position can be renamed, without renaming it. Returns canRename with tsgo's reason when it cannot (a keyword, a symbol declared in a library), or the exact range and current text of the symbol ts_rename would rename."),
 		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path containing the symbol")),
 		mcp.WithNumber("line", mcp.Required(), mcp.Description("Line number (1-based)")),
 		mcp.WithNumber("column", mcp.Required(), mcp.Description("Column number (1-based)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makePrepareRenameHandler(client, docs))
+
+	add(mcp.NewTool("ts_rename",
+		mcp.WithDescription("Rename a symbol across the project, addressed by position or by its name in file. Applies all changes to disk and returns the modified files with a diff of each."),
+		mcp.WithString("file", mcp.Required(), mcp.Description("Absolute file path containing the symbol")),
+		mcp.WithNumber("line", mcp.Description("Line number (1-based); required unless symbol is given")),
+		mcp.WithNumber("column", mcp.Description("Column number (1-based); required unless symbol is given")),
+		mcp.WithString("symbol", mcp.Description("Name of the symbol declared in file to rename instead of a position, e.g. \"UserRepo\" or \"UserRepo.find\". A name matching several symbols fails with the candidates unless occurrence picks one")),
+		mcp.WithNumber("occurrence", mcp.Description("Which of the symbols matching symbol to rename, counting from 1 in document order")),
 		mcp.WithString("newName", mcp.Required(), mcp.Description("New name for the symbol")),
-		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json")),
+		mcp.WithBoolean("confirm", mcp.Description("Preview the rename as diffs and return an editToken for ts_apply_edit instead of writing (default false)")),
+		mcp.WithBoolean("dryRun", mcp.Description("Only preview the rename: return the per-file diffs and the edit count, write nothing and return no editToken (default false)")),
+		mcp.WithBoolean("includeDiff", mcp.Description("Return each written file's change as a unified diff with one line of context, cut after 100 lines (default true). changedLines lists the new or changed lines either way")),
+		mcp.WithString("updateDocs", mcp.Enum(docsModeList, docsModeApply), mcp.Description("Also find whole-word mentions of the old name in .md/.mdx/.json/.yaml files: \"list\" returns them as docsCandidates, \"apply\" rewrites them with the code (default: off)")),
+		mcp.WithArray("excludeGlobs", mcp.WithStringItems(), mcp.Description("Leave files matching one of these globs, relative to the project root, out of the rename and list them as skippedFiles instead of editing them (default [\"**/node_modules/**\", \"**/dist/**\", \"**/*.d.ts\"], or the config file's renameExcludeGlobs); pass [] to edit every file")),
+		mcp.WithString("onlyUnder", mcp.Description("Only edit files under this directory, absolute or relative to the project root; edits elsewhere are listed as skippedFiles")),
+		mcp.WithBoolean("force", mcp.Description("Rename even when tsgo reports the new name conflicts with an existing declaration in scope; without it such a rename is refused and the conflicts listed (default false)")),
+		withColumnMode(),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
 		mcp.WithReadOnlyHintAnnotation(false),
 		mcp.WithDestructiveHintAnnotation(true),
-	), makeRenameHandler(client, docs))
+	), makeRenameHandler(client, docs, pending, packages, symbolCache, config.RenameExcludeGlobs, config.EditOverlayCheck, journal, recorder))
 
-	s.AddTool(mcp.NewTool("ts_project_info",
-		mcp.WithDescription("Get TypeScript project configuration info. Returns tsconfig path and project root directory."),
-		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json")),
-		mcp.WithString("cwd", mcp.Description("Working directory for tsconfig discovery")),
+	add(mcp.NewTool("ts_rename_file",
+		mcp.WithDescription("Move or rename a TypeScript file and update the imports of it across the project, and the relative imports inside it. The import edits are written with the same checks and rollback as ts_rename; if they fail, the file is moved back. Returns the files whose imports were rewritten."),
+		mcp.WithString("oldPath", mcp.Required(), mcp.Description("Absolute path of the file to move")),
+		mcp.WithString("newPath", mcp.Required(), mcp.Description("Absolute path to move it to; missing directories are created")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeRenameFileHandler(client, docs, pending, packages, config.EditOverlayCheck, journal, recorder))
+
+	add(mcp.NewTool("ts_apply_edit",
+		mcp.WithDescription("Apply an edit previously previewed by a tool in confirmation mode. Fails without writing if any affected file changed since the preview."),
+		mcp.WithString("editToken", mcp.Required(), mcp.Description("Token returned by the preview")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeApplyEditHandler(client, docs, pending, config.EditOverlayCheck, journal, recorder))
+
+	add(mcp.NewTool("ts_recover_pending_edit",
+		mcp.WithDescription("Recover an edit interrupted mid-write. Large edits are journaled under .typescript-mcp/pending; without an action this lists the interrupted ones. \"complete\" writes the remaining files, \"rollback\" restores every file's original content, \"discard\" drops the journal without touching files."),
+		mcp.WithString("action", mcp.Enum(recoverComplete, recoverRollback, recoverDiscard), mcp.Description("Recovery action (default: list pending edits)")),
+		mcp.WithString("id", mcp.Description("Id of the pending edit (default: the only one)")),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeRecoverPendingEditHandler(client, docs, pending, journal))
+
+	add(mcp.NewTool("ts_list_edits",
+		mcp.WithDescription("List the applied edits recorded under .typescript-mcp/edits, newest first: tool, time, symbol and files of each. Edits are recorded when the config file sets recordEdits."),
+		mcp.WithString("file", mcp.Description("Absolute path; only edits touching this file")),
+		mcp.WithString("since", mcp.Description("RFC 3339 time; only edits at or after it")),
+		mcp.WithString("until", mcp.Description("RFC 3339 time; only edits at or before it")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum edits to return (default 50)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeListEditsHandler(recorder))
+
+	add(mcp.NewTool("ts_undo_last_edit",
+		mcp.WithDescription("Revert the newest recorded edit that is not undone yet, from its record under .typescript-mcp/edits, so it works across server restarts. Refuses when a file changed since the edit."),
+		mcp.WithReadOnlyHintAnnotation(false),
+		mcp.WithDestructiveHintAnnotation(true),
+	), makeUndoLastEditHandler(client, docs, pending, recorder))
+
+	add(mcp.NewTool("ts_project_info",
+		mcp.WithDescription("Get TypeScript project configuration info. Returns tsconfig path and project root directory; the effective tsconfig with its extends chain applied (compilerOptions, include, exclude, references, and the base config each inherited setting came from), or the file at fault when a config cannot be parsed; plus the environment: ESLint (and whether its rules are type-aware), formatters, package manager, targeted Node version, installed TypeScript and effective strictness flags."),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json; a project outside the workspace root gets a tsgo of its own")),
+		mcp.WithString("cwd", mcp.Description("Working directory for tsconfig discovery (default: the workspace root)")),
+		mcp.WithBoolean("listFiles", mcp.Description("List the source files the project compiles: the tsconfig's files/include/exclude expanded against the file system; node_modules is always skipped, outDir unless exclude is set (default false)")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum files to list with listFiles; the count covers all of them (default 200)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeProjectInfoHandler(client, docs, probe, symbolCache, workspace.NewEnvironmentCache(client.RootDir())))
+
+	add(mcp.NewTool("ts_list_projects",
+		mcp.WithDescription("Find every tsconfig.json under the workspace root (node_modules and .git skipped) plus the configs their references name, in dependency order: each project after the projects it references. With file, also tell which project owns the file: the nearest project whose files/include/exclude select it."),
+		mcp.WithString("root", mcp.Description("Directory to search (default: the workspace root)")),
+		mcp.WithString("file", mcp.Description("Absolute path of a file to find the owning project of")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeListProjectsHandler(client))
+
+	add(mcp.NewTool("ts_project_coverage",
+		mcp.WithDescription("Compare the files tsconfig includes with the files tsgo has actually analyzed. Lists included files never analyzed and analyzed files the config seems to exclude; diagnostics for files outside the program are misleadingly clean."),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json (default: tsconfig.json in the workspace root)")),
+		mcp.WithNumber("maxResults", mcp.Description("Maximum files to list per category (default 50)")),
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithDestructiveHintAnnotation(false),
-	), makeProjectInfoHandler(client, docs))
+	), makeProjectCoverageHandler(client, docs))
+
+	add(mcp.NewTool("ts_import_cycles",
+		mcp.WithDescription("Find circular import chains through a file, or through any file in a directory. Each cycle lists its files in import order with the import line that creates each edge. Type-only imports are skipped unless includeTypeOnly is set, since they are erased at runtime."),
+		mcp.WithString("path", mcp.Required(), mcp.Description("Absolute path of a file, or a directory for all cycles touching it")),
+		mcp.WithNumber("maxLength", mcp.Description("Longest cycle to report, in files (default 10)")),
+		mcp.WithNumber("maxCycles", mcp.Description("Maximum cycles to return, shortest first (default 20)")),
+		mcp.WithBoolean("includeTypeOnly", mcp.Description("Also follow type-only imports; such cycles are flagged typeOnly (default false)")),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json (default: tsconfig.json in the workspace root)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeImportCyclesHandler(client))
+
+	add(mcp.NewTool("ts_ambient_declarations",
+		mcp.WithDescription("List the project's ambient declarations by file: globals declared in scripts and declare global blocks, declare module \"name\" statements, and triple-slash reference targets. Use it to find where a global or a wildcard module comes from. With a progress token, each file is streamed as a progress notification."),
+		mcp.WithString("tsconfig", mcp.Description("Path to tsconfig.json (default: tsconfig.json in the workspace root)")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeAmbientDeclarationsHandler(client))
+
+	add(mcp.NewTool("ts_open_files",
+		mcp.WithDescription("Open files in tsgo and keep them open, e.g. to load the project of a specific entry point. Other tools open files on demand; this is for clients managing tsgo's open set themselves. Reports language ID and document version per file."),
+		mcp.WithArray("files", mcp.Required(), mcp.WithStringItems(), mcp.Description("Absolute file paths")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeOpenFilesHandler(client, docs, names.of("ts_close_files")))
+
+	add(mcp.NewTool("ts_close_files",
+		mcp.WithDescription("Close files in tsgo to free its memory. Files in use by a running tool call are skipped and reported, as are files that are not open."),
+		mcp.WithArray("files", mcp.Required(), mcp.WithStringItems(), mcp.Description("Absolute file paths")),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeCloseFilesHandler(client, docs))
+
+	add(mcp.NewTool("ts_server_status",
+		mcp.WithDescription("List the documents open in tsgo with their versions, ages and pin state, plus the open-document limit and symbol cache statistics."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeServerStatusHandler(client, docs, symbolCache, journal))
+
+	add(mcp.NewTool("ts_server_info",
+		mcp.WithDescription("Report the name and version tsgo announced and which LSP requests it supports, with the tools unavailable as a result. Use it to find out why a tool says tsgo does not support something."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithDestructiveHintAnnotation(false),
+	), makeServerInfoHandler(client, names))
+
+	return set
 }
@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+const (
+	typesSuper = "supertypes"
+	typesSub   = "subtypes"
+
+	// maxTypeHierarchyDepth and maxTypeHierarchyNodes bound
+	// ts_type_hierarchy as their call hierarchy counterparts do.
+	maxTypeHierarchyDepth = 5
+	maxTypeHierarchyNodes = 200
+
+	noTypeHierarchy = "No class or interface found at this position"
+)
+
+type typeHierarchyNode struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// VisualColumn is set in the visual column mode.
+	VisualColumn int `json:"visualColumn,omitempty"`
+	// Types are the supertypes or subtypes of the node.
+	Types []typeHierarchyNode `json:"types,omitempty"`
+	// Recursive is set on a node that is also one of its ancestors; its
+	// types are not expanded again.
+	Recursive bool `json:"recursive,omitempty"`
+}
+
+type typeHierarchyResult struct {
+	Direction string              `json:"direction"`
+	Depth     int                 `json:"depth"`
+	Roots     []typeHierarchyNode `json:"roots"`
+	// Truncated is set when expansion stopped at maxTypeHierarchyNodes.
+	Truncated bool `json:"truncated"`
+}
+
+// typeExpander returns the supertypes or subtypes of item.
+type typeExpander func(ctx context.Context, item lsp.TypeHierarchyItem) ([]lsp.TypeHierarchyItem, error)
+
+func clientTypeExpander(client *lsp.Client, direction string) typeExpander {
+	if direction == typesSub {
+		return client.Subtypes
+	}
+	return client.Supertypes
+}
+
+// typeTree builds type hierarchy trees, counting the nodes it expands.
+type typeTree struct {
+	expand    typeExpander
+	cols      columnMode
+	expanded  int
+	truncated bool
+}
+
+// typeItemKey identifies an item by its file and the start of its name.
+func typeItemKey(item lsp.TypeHierarchyItem) string {
+	start := item.SelectionRange.Start
+	return fmt.Sprintf("%s:%d:%d", item.URI, start.Line, start.Character)
+}
+
+// build returns the tree of item down to depth levels. path holds the keys
+// of item's ancestors, as in callTree.build.
+func (t *typeTree) build(ctx context.Context, item lsp.TypeHierarchyItem, depth int, path map[string]bool) (typeHierarchyNode, error) {
+	node := typeHierarchyNode{
+		Name:   item.Name,
+		Kind:   symbolKindName(item.Kind),
+		Detail: item.Detail,
+		File:   docsync.URIToFile(string(item.URI)),
+		Line:   int(item.SelectionRange.Start.Line) + 1,
+		Column: int(item.SelectionRange.Start.Character) + 1,
+	}
+	node.VisualColumn = t.cols.visualColumn(node.File, node.Line, node.Column)
+	key := typeItemKey(item)
+	if path[key] {
+		node.Recursive = true
+		return node, nil
+	}
+	if depth == 0 {
+		return node, nil
+	}
+	if t.expanded >= maxTypeHierarchyNodes {
+		t.truncated = true
+		return node, nil
+	}
+	t.expanded++
+	items, err := t.expand(ctx, item)
+	if err != nil {
+		return node, err
+	}
+	path[key] = true
+	defer delete(path, key)
+	for _, it := range items {
+		child, err := t.build(ctx, it, depth-1, path)
+		if err != nil {
+			return node, err
+		}
+		node.Types = append(node.Types, child)
+	}
+	return node, nil
+}
+
+func makeTypeHierarchyHandler(client *lsp.Client, docs *docsync.Manager) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		file, err := request.RequireString("file")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		line, err := request.RequireInt("line")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col, err := request.RequireInt("column")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		direction := request.GetString("direction", typesSuper)
+		if direction != typesSuper && direction != typesSub {
+			return mcp.NewToolResultError(fmt.Sprintf("direction must be %q or %q, got %q", typesSuper, typesSub, direction)), nil
+		}
+		depth := request.GetInt("depth", 1)
+		if depth < 1 || depth > maxTypeHierarchyDepth {
+			return mcp.NewToolResultError(fmt.Sprintf("depth must be between 1 and %d", maxTypeHierarchyDepth)), nil
+		}
+		cols, err := parseColumnMode(request)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		col = cols.charColumn(file, line, col)
+
+		defer docs.Pin(file)()
+		if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+		}
+
+		items, err := client.PrepareTypeHierarchy(ctx, file, line, col)
+		if errors.Is(err, lsp.ErrUnsupported) {
+			return mcp.NewToolResultError(fmt.Sprintf("type hierarchy is unavailable: %v; use ts_implementations for subtypes or ts_definition on the heritage clause instead", err)), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("type hierarchy error: %v", err)), nil
+		}
+		if len(items) == 0 {
+			return mcp.NewToolResultText(noTypeHierarchy), nil
+		}
+
+		tree := &typeTree{expand: clientTypeExpander(client, direction), cols: cols}
+		result := typeHierarchyResult{Direction: direction, Depth: depth, Roots: []typeHierarchyNode{}}
+		for _, item := range items {
+			root, err := tree.build(ctx, item, depth, map[string]bool{})
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%s error: %v", direction, err)), nil
+			}
+			result.Roots = append(result.Roots, root)
+		}
+		result.Truncated = tree.truncated
+
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+)
+
+func typeItem(name string, kind protocol.SymbolKind, line uint32) lsp.TypeHierarchyItem {
+	pos := protocol.Position{Line: line, Character: 13}
+	return lsp.TypeHierarchyItem{
+		Name:           name,
+		Kind:           kind,
+		URI:            "file:///p/src/shapes.ts",
+		SelectionRange: protocol.Range{Start: pos, End: pos},
+	}
+}
+
+func TestTypeTreeBuild(t *testing.T) {
+	shape := typeItem("Shape", protocol.SymbolKindInterface, 0)
+	circle := typeItem("Circle", protocol.SymbolKindClass, 4)
+	ring := typeItem("Ring", protocol.SymbolKindClass, 10)
+	square := typeItem("Square", protocol.SymbolKindClass, 16)
+	subtypes := map[string][]lsp.TypeHierarchyItem{
+		"Shape":  {circle, square},
+		"Circle": {ring},
+	}
+	expand := func(_ context.Context, item lsp.TypeHierarchyItem) ([]lsp.TypeHierarchyItem, error) {
+		return subtypes[item.Name], nil
+	}
+
+	tree := &typeTree{expand: expand}
+	root, err := tree.build(context.Background(), shape, 5, map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Name != "Shape" || root.Kind != "interface" || root.File != "/p/src/shapes.ts" || root.Line != 1 || root.Column != 14 {
+		t.Fatalf("root = %+v", root)
+	}
+	if len(root.Types) != 2 || root.Types[0].Name != "Circle" || root.Types[0].Kind != "class" || root.Types[1].Name != "Square" {
+		t.Fatalf("subtypes = %+v", root.Types)
+	}
+	if ring := root.Types[0].Types; len(ring) != 1 || ring[0].Name != "Ring" || ring[0].Line != 11 {
+		t.Errorf("Circle's subtypes = %+v", ring)
+	}
+
+	// Depth 1 expands the root only.
+	tree = &typeTree{expand: expand}
+	root, _ = tree.build(context.Background(), shape, 1, map[string]bool{})
+	if len(root.Types) != 2 || root.Types[0].Types != nil || tree.expanded != 1 {
+		t.Errorf("depth 1 = %+v, %d expanded", root, tree.expanded)
+	}
+
+	failing := func(context.Context, lsp.TypeHierarchyItem) ([]lsp.TypeHierarchyItem, error) {
+		return nil, errors.New("boom")
+	}
+	if _, err := (&typeTree{expand: failing}).build(context.Background(), shape, 1, map[string]bool{}); err == nil {
+		t.Error("want the expansion error")
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strings"
 	"sync"
+	"unicode/utf16"
+
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // readLine reads a specific 1-based line number from a file.
@@ -57,6 +61,16 @@ func cachedReadLines(file string) ([]string, error) {
 	return lines, nil
 }
 
+// forgetCachedLines drops files from the file line cache, for files just
+// synced from disk, which the agent may have edited since they were read.
+func forgetCachedLines(files ...string) {
+	fileLineCacheMu.Lock()
+	for _, f := range files {
+		delete(fileLineCache, f)
+	}
+	fileLineCacheMu.Unlock()
+}
+
 // ClearFileCache clears the file line cache. Call between tool invocations
 // if freshness is needed, though typically files don't change mid-batch.
 func ClearFileCache() {
@@ -64,3 +78,127 @@ func ClearFileCache() {
 	fileLineCache = make(map[string][]string)
 	fileLineCacheMu.Unlock()
 }
+
+// Values of the columnMode argument of the position-based tools.
+const (
+	columnModeCharacter = "character"
+	columnModeVisual    = "visual"
+	defaultTabWidth     = 8
+)
+
+// columnMode is how a call counts columns: in characters (UTF-16 code
+// units, as LSP does) or visually, with tabs expanded to tabWidth as
+// editors display them.
+type columnMode struct {
+	visual   bool
+	tabWidth int
+}
+
+// withColumnMode adds the columnMode and tabWidth parameters of the
+// position-based tools.
+func withColumnMode() mcp.ToolOption {
+	return func(t *mcp.Tool) {
+		mcp.WithString("columnMode", mcp.Enum(columnModeCharacter, columnModeVisual), mcp.Description("How column is counted: \"character\" (default) or \"visual\", with tabs expanded to tabWidth as editors display them. Results then also carry visualColumn"))(t)
+		mcp.WithNumber("tabWidth", mcp.Description("Tab width for columnMode \"visual\" (default 8)"))(t)
+	}
+}
+
+// parseColumnMode reads the columnMode and tabWidth arguments.
+func parseColumnMode(request mcp.CallToolRequest) (columnMode, error) {
+	tabWidth := request.GetInt("tabWidth", defaultTabWidth)
+	if tabWidth < 1 {
+		return columnMode{}, fmt.Errorf("tabWidth must be at least 1")
+	}
+	switch mode := request.GetString("columnMode", columnModeCharacter); mode {
+	case columnModeCharacter:
+		return columnMode{tabWidth: tabWidth}, nil
+	case columnModeVisual:
+		return columnMode{visual: true, tabWidth: tabWidth}, nil
+	default:
+		return columnMode{}, fmt.Errorf("invalid columnMode %q (valid: %s, %s)", mode, columnModeCharacter, columnModeVisual)
+	}
+}
+
+func (m columnMode) String() string {
+	if m.visual {
+		return columnModeVisual
+	}
+	return columnModeCharacter
+}
+
+// charColumn converts col, a column of the line of file as the caller
+// counts it, to a character column. The line is read from disk, not the
+// line cache, as the position must match the file as it is now.
+func (m columnMode) charColumn(file string, line, col int) int {
+	if !m.visual {
+		return col
+	}
+	text, ok := currentLine(file, line)
+	if !ok {
+		return col
+	}
+	return visualToCharColumn(text, col, m.tabWidth)
+}
+
+// visualColumn returns the visual column of the character column col of
+// the line of file, for results, or 0 outside visual mode.
+func (m columnMode) visualColumn(file string, line, col int) int {
+	if !m.visual {
+		return 0
+	}
+	text, err := readLine(file, line)
+	if err != nil {
+		return 0
+	}
+	return charToVisualColumn(text, col, m.tabWidth)
+}
+
+// currentLine reads the 1-based line of file from disk.
+func currentLine(file string, line int) (string, bool) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+	return strings.TrimSuffix(lines[line-1], "\r"), true
+}
+
+// visualToCharColumn converts a 1-based visual column of line, where a tab
+// advances to the next multiple of tabWidth, to a 1-based UTF-16 column. A
+// column inside a tab's expansion maps to the tab; columns past the end of
+// the line count one character each.
+func visualToCharColumn(line string, visual, tabWidth int) int {
+	v, c := 1, 1 // the visual and character column of the next rune
+	for _, r := range line {
+		next := v + 1
+		if r == '\t' {
+			next = v + tabWidth - (v-1)%tabWidth
+		}
+		if visual < next {
+			return c
+		}
+		v = next
+		c += utf16.RuneLen(r)
+	}
+	return c + visual - v
+}
+
+// charToVisualColumn is the inverse of visualToCharColumn.
+func charToVisualColumn(line string, char, tabWidth int) int {
+	v, c := 1, 1
+	for _, r := range line {
+		if c >= char {
+			return v
+		}
+		if r == '\t' {
+			v += tabWidth - (v-1)%tabWidth
+		} else {
+			v++
+		}
+		c += utf16.RuneLen(r)
+	}
+	return v + char - c
+}
@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestVisualColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		tabWidth int
+		visual   int
+		char     int
+	}{
+		{"no tabs", "const x = 1;", 8, 7, 7},
+		{"after a tab", "\treturn x;", 8, 9, 2},
+		{"after a tab of width 4", "\treturn x;", 4, 5, 2},
+		{"inside a tab", "\treturn x;", 8, 4, 1},
+		{"two tabs", "\t\tfoo()", 4, 9, 3},
+		{"tab after text", "ab\tc", 4, 5, 4},
+		{"tab after text, width 8", "ab\tc", 8, 9, 4},
+		{"spaces then tab", "  \tx", 4, 5, 4},
+		{"multibyte before a tab", "é\tx", 4, 5, 3},
+		{"astral character", "😀\tx", 4, 5, 4},
+		{"past the end", "\tx", 8, 12, 5},
+		{"first column", "\tx", 8, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := visualToCharColumn(tt.line, tt.visual, tt.tabWidth); got != tt.char {
+				t.Errorf("visualToCharColumn(%q, %d) = %d, want %d", tt.line, tt.visual, got, tt.char)
+			}
+		})
+	}
+
+	// Character columns round-trip through visual ones.
+	for _, line := range []string{"\t\tfoo(bar)", "ab\tc\td", "é\t😀\tx", "  \t  y"} {
+		for _, width := range []int{1, 2, 4, 8} {
+			for char := 1; char <= len(line)+2; char++ {
+				v := charToVisualColumn(line, char, width)
+				if back := visualToCharColumn(line, v, width); back != char && !inSurrogate(line, char) {
+					t.Errorf("%q width %d: char %d -> visual %d -> char %d", line, width, char, v, back)
+				}
+			}
+		}
+	}
+}
+
+// inSurrogate reports whether the UTF-16 column char falls between the
+// two halves of a surrogate pair, which has no visual column of its own.
+func inSurrogate(line string, char int) bool {
+	c := 1
+	for _, r := range line {
+		if r > 0xFFFF && char == c+1 {
+			return true
+		}
+		if r > 0xFFFF {
+			c += 2
+		} else {
+			c++
+		}
+	}
+	return false
+}
+
+func TestParseColumnMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    columnMode
+		wantErr bool
+	}{
+		{name: "default", args: map[string]any{}, want: columnMode{tabWidth: 8}},
+		{name: "visual", args: map[string]any{"columnMode": "visual", "tabWidth": 4.0}, want: columnMode{visual: true, tabWidth: 4}},
+		{name: "unknown mode", args: map[string]any{"columnMode": "byte"}, wantErr: true},
+		{name: "zero tab width", args: map[string]any{"columnMode": "visual", "tabWidth": 0.0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req mcp.CallToolRequest
+			req.Params.Arguments = tt.args
+			got, err := parseColumnMode(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("mode = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+type workspaceSymbolEntry struct {
+	Name          string `json:"name"`
+	Kind          string `json:"kind"`
+	File          string `json:"file"`
+	Line          int    `json:"line"`
+	Column        int    `json:"column"`
+	ContainerName string `json:"containerName,omitempty"`
+	// Package and DisplayPath are set for symbols inside node_modules.
+	Package     *workspace.Package `json:"package,omitempty"`
+	DisplayPath string             `json:"displayPath,omitempty"`
+}
+
+type workspaceSymbolsResult struct {
+	Symbols    []workspaceSymbolEntry `json:"symbols"`
+	TotalCount int                    `json:"totalCount"`
+	// Truncated is set when more than maxResults symbols matched.
+	Truncated bool `json:"truncated"`
+}
+
+// workspaceSymbolEntries converts symbols in tsgo's order, best match
+// first, keeping at most max.
+func workspaceSymbolEntries(symbols []protocol.SymbolInformation, packages *workspace.PackageResolver, max int) workspaceSymbolsResult {
+	result := workspaceSymbolsResult{Symbols: []workspaceSymbolEntry{}, TotalCount: len(symbols)}
+	if len(symbols) > max {
+		symbols = symbols[:max]
+		result.Truncated = true
+	}
+	for _, sym := range symbols {
+		entry := workspaceSymbolEntry{
+			Name:          sym.Name,
+			Kind:          symbolKindName(sym.Kind),
+			File:          docsync.URIToFile(string(sym.Location.URI)),
+			Line:          int(sym.Location.Range.Start.Line) + 1,
+			Column:        int(sym.Location.Range.Start.Character) + 1,
+			ContainerName: sym.ContainerName,
+		}
+		if pkg := packages.Resolve(entry.File); pkg != nil {
+			entry.Package = pkg
+			entry.DisplayPath = pkg.DisplayPath(entry.File)
+		}
+		result.Symbols = append(result.Symbols, entry)
+	}
+	return result
+}
+
+func makeWorkspaceSymbolsHandler(client *lsp.Client, docs *docsync.Manager, packages *workspace.PackageResolver) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, err := request.RequireString("query")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if strings.TrimSpace(query) == "" {
+			return mcp.NewToolResultError("query must not be empty"), nil
+		}
+		maxResults := request.GetInt("maxResults", 50)
+		if maxResults < 1 {
+			return mcp.NewToolResultError("maxResults must be at least 1"), nil
+		}
+
+		// tsgo only searches the projects of open files, so open one of
+		// the project to search.
+		if file := request.GetString("file", ""); file != "" {
+			defer docs.Pin(file)()
+			if err := docs.SyncFile(ctx, client.Conn(), file); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("sync error: %v", err)), nil
+			}
+		}
+
+		symbols, err := client.WorkspaceSymbol(ctx, query)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("workspace symbols error: %v", err)), nil
+		}
+		if len(symbols) == 0 && len(docs.OpenFiles()) == 0 {
+			return mcp.NewToolResultText("No symbols found: no project is loaded yet; pass file to search the project of that file"), nil
+		}
+
+		data, err := json.MarshalIndent(workspaceSymbolEntries(symbols, packages, maxResults), "", "  ")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("marshal error: %v", err)), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
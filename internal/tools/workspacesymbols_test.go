@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"testing"
+
+	"go.lsp.dev/protocol"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/workspace"
+)
+
+func TestWorkspaceSymbolEntries(t *testing.T) {
+	symbols := []protocol.SymbolInformation{
+		{Name: "Runner", Kind: protocol.SymbolKindClass, Location: protocol.Location{
+			URI:   "file:///p/src/run.ts",
+			Range: protocol.Range{Start: protocol.Position{Line: 8, Character: 13}},
+		}},
+		{Name: "runAll", Kind: protocol.SymbolKindFunction, ContainerName: "tasks", Location: protocol.Location{URI: "file:///p/src/tasks.ts"}},
+	}
+
+	got := workspaceSymbolEntries(symbols, workspace.NewPackageResolver(), 1)
+	if got.TotalCount != 2 || !got.Truncated || len(got.Symbols) != 1 {
+		t.Fatalf("result = %+v, want the first of 2, truncated", got)
+	}
+	want := workspaceSymbolEntry{Name: "Runner", Kind: "class", File: "/p/src/run.ts", Line: 9, Column: 14}
+	if got.Symbols[0] != want {
+		t.Errorf("entry = %+v, want %+v", got.Symbols[0], want)
+	}
+
+	got = workspaceSymbolEntries(symbols, workspace.NewPackageResolver(), 50)
+	if got.Truncated || got.Symbols[1].ContainerName != "tasks" || got.Symbols[1].Line != 1 {
+		t.Errorf("result = %+v", got)
+	}
+}
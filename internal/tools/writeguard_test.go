@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/edit"
+)
+
+const (
+	oldContent     = "export const old = 1;\n"
+	renamedContent = "export const renamed = 1;\n"
+	agentContent   = "export const old = 2; // agent\n"
+)
+
+func TestApplyWorkspaceEditConcurrentModification(t *testing.T) {
+	t.Cleanup(func() { beforeEditWrite = nil })
+
+	tests := []struct {
+		name    string
+		journal bool
+		// interleave runs before files[i] is written.
+		interleave func(t *testing.T, files []string, i int)
+		conflict   int      // index of the file named by the error
+		want       []string // contents afterwards
+		kept       []int    // written files left alone by rollback
+	}{
+		{
+			name: "before the first write",
+			interleave: func(t *testing.T, files []string, i int) {
+				if i == 0 {
+					writeString(t, files[0], agentContent)
+				}
+			},
+			conflict: 0,
+			want:     []string{agentContent, oldContent, oldContent},
+		},
+		{
+			name: "between writes",
+			interleave: func(t *testing.T, files []string, i int) {
+				if i == 2 {
+					writeString(t, files[2], agentContent)
+				}
+			},
+			conflict: 2,
+			want:     []string{oldContent, oldContent, agentContent},
+		},
+		{
+			name: "written file changed again",
+			interleave: func(t *testing.T, files []string, i int) {
+				if i == 2 {
+					writeString(t, files[0], agentContent)
+					writeString(t, files[2], agentContent)
+				}
+			},
+			conflict: 2,
+			want:     []string{agentContent, oldContent, agentContent},
+			kept:     []int{0},
+		},
+		{
+			name: "modification time only",
+			interleave: func(t *testing.T, files []string, i int) {
+				if i == 1 {
+					later := time.Now().Add(time.Hour)
+					if err := os.Chtimes(files[1], later, later); err != nil {
+						t.Fatal(err)
+					}
+				}
+			},
+			conflict: 1,
+			want:     []string{oldContent, oldContent, oldContent},
+		},
+		{
+			name:    "journaled, written file changed again",
+			journal: true,
+			interleave: func(t *testing.T, files []string, i int) {
+				if i == 2 {
+					writeString(t, files[1], agentContent)
+					writeString(t, files[2], agentContent)
+				}
+			},
+			conflict: 2,
+			want:     []string{oldContent, agentContent, agentContent},
+			kept:     []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, we := journalFixture(t, 3)
+			beforeEditWrite = func(path string) error {
+				tt.interleave(t, files, slices.Index(files, path))
+				return nil
+			}
+			var journal *journalPolicy
+			if tt.journal {
+				journal = &journalPolicy{dir: t.TempDir(), always: true, chunkSize: 1}
+			}
+
+			_, err := applyWorkspaceEdit(we, nil, nil, journal, nil)
+			var cm *edit.ConcurrentModificationError
+			if !errors.As(err, &cm) {
+				t.Fatalf("error = %v, want ERR_CONCURRENT_MODIFICATION", err)
+			}
+			if cm.File != files[tt.conflict] {
+				t.Errorf("conflicting file = %s, want %s", cm.File, files[tt.conflict])
+			}
+			var wantKept []string
+			for _, i := range tt.kept {
+				wantKept = append(wantKept, files[i])
+			}
+			if !slices.Equal(cm.Kept, wantKept) {
+				t.Errorf("kept = %v, want %v", cm.Kept, wantKept)
+			}
+			for i, c := range fileContents(t, files) {
+				if c != tt.want[i] {
+					t.Errorf("file %d = %q, want %q", i, c, tt.want[i])
+				}
+			}
+			if journal != nil {
+				if pending, _ := listPendingJournals(journal.dir); len(pending) != 0 {
+					t.Errorf("journal left behind: %+v", pending)
+				}
+			}
+		})
+	}
+
+	t.Run("no interleaving", func(t *testing.T) {
+		files, we := journalFixture(t, 3)
+		beforeEditWrite = nil
+		if _, err := applyWorkspaceEdit(we, nil, nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		for i, c := range fileContents(t, files) {
+			if c != renamedContent {
+				t.Errorf("file %d = %q", i, c)
+			}
+		}
+	})
+}
+
+func writeString(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
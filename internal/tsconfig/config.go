@@ -0,0 +1,389 @@
+package tsconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Config is the subset of a tsconfig.json the tools interpret themselves.
+// "extends" is not followed.
+type Config struct {
+	// Path is the absolute path of the tsconfig file.
+	Path string `json:"-"`
+	// Dir is the directory containing the tsconfig file; relative specs
+	// resolve against it.
+	Dir string `json:"-"`
+
+	Files           []string        `json:"files"`
+	Include         []string        `json:"include"`
+	Exclude         []string        `json:"exclude"`
+	CompilerOptions CompilerOptions `json:"compilerOptions"`
+
+	includes []*regexp.Regexp
+	excludes []*regexp.Regexp
+	files    map[string]bool
+}
+
+// CompilerOptions holds the compiler options the tools consult.
+type CompilerOptions struct {
+	BaseURL          string          `json:"baseUrl,omitempty"`
+	Paths            json.RawMessage `json:"paths,omitempty"`
+	OutDir           string          `json:"outDir,omitempty"`
+	AllowJS          bool            `json:"allowJs,omitempty"`
+	CheckJS          bool            `json:"checkJs,omitempty"`
+	Module           string          `json:"module,omitempty"`
+	ModuleResolution string          `json:"moduleResolution,omitempty"`
+}
+
+// ResolutionMode is an effective moduleResolution setting, lower-cased.
+type ResolutionMode string
+
+const (
+	ResolutionClassic  ResolutionMode = "classic"
+	ResolutionNode10   ResolutionMode = "node10"
+	ResolutionNode16   ResolutionMode = "node16"
+	ResolutionNodeNext ResolutionMode = "nodenext"
+	ResolutionBundler  ResolutionMode = "bundler"
+)
+
+// RequiresExtensions reports whether relative imports must name the
+// emitted file's extension, as in Node16/NodeNext.
+func (m ResolutionMode) RequiresExtensions() bool {
+	return m == ResolutionNode16 || m == ResolutionNodeNext
+}
+
+// ModuleResolution returns the effective module resolution mode: the
+// explicit moduleResolution when set, otherwise tsc's default for the
+// module setting.
+func (c *Config) ModuleResolution() ResolutionMode {
+	switch strings.ToLower(c.CompilerOptions.ModuleResolution) {
+	case "node", "node10":
+		return ResolutionNode10
+	case "node16":
+		return ResolutionNode16
+	case "nodenext":
+		return ResolutionNodeNext
+	case "bundler":
+		return ResolutionBundler
+	case "classic":
+		return ResolutionClassic
+	}
+	switch strings.ToLower(c.CompilerOptions.Module) {
+	case "node16", "node18", "node20":
+		return ResolutionNode16
+	case "nodenext":
+		return ResolutionNodeNext
+	case "preserve":
+		return ResolutionBundler
+	case "", "commonjs":
+		return ResolutionNode10
+	}
+	return ResolutionClassic
+}
+
+// defaultExclude is what tsc excludes when the config has no "exclude".
+var defaultExclude = []string{"node_modules", "bower_components", "jspm_packages"}
+
+// Load reads and parses a tsconfig file. Comments and trailing commas are
+// accepted, as tsc does.
+func Load(configPath string) (*Config, error) {
+	abs, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(abs, data)
+}
+
+// Parse parses tsconfig content as if it were read from configPath.
+func Parse(configPath string, data []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := json.Unmarshal(StripJSONC(data), cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", configPath, err)
+	}
+	cfg.Path = configPath
+	cfg.Dir = filepath.Dir(configPath)
+	cfg.compile()
+	return cfg, nil
+}
+
+// compile prepares the files/include/exclude matchers.
+func (c *Config) compile() {
+	include := c.Include
+	if include == nil && c.Files == nil {
+		include = []string{"**/*"}
+	}
+	exclude := c.Exclude
+	if exclude == nil {
+		exclude = append([]string(nil), defaultExclude...)
+		if c.CompilerOptions.OutDir != "" {
+			exclude = append(exclude, c.CompilerOptions.OutDir)
+		}
+	}
+	for _, spec := range include {
+		if re := specRegexp(c.Dir, spec, false); re != nil {
+			c.includes = append(c.includes, re)
+		}
+	}
+	for _, spec := range exclude {
+		if re := specRegexp(c.Dir, spec, true); re != nil {
+			c.excludes = append(c.excludes, re)
+		}
+	}
+	c.files = make(map[string]bool, len(c.Files))
+	for _, f := range c.Files {
+		c.files[c.resolve(f)] = true
+	}
+}
+
+// IncludeRoots returns the directories a walk must cover to find every
+// file the include specs select: the part of each spec before its first
+// wildcard, or for a spec naming a file, its directory. Roots below
+// another root are dropped. The paths are slash-separated.
+func (c *Config) IncludeRoots() []string {
+	include := c.Include
+	if include == nil && c.Files == nil {
+		include = []string{"."}
+	}
+	var roots []string
+	for _, spec := range include {
+		if spec == "" {
+			continue
+		}
+		var literal []string
+		segments := strings.Split(c.resolve(spec), "/")
+		for i, seg := range segments {
+			if strings.ContainsAny(seg, "*?") || (i == len(segments)-1 && path.Ext(seg) != "") {
+				break
+			}
+			literal = append(literal, seg)
+		}
+		root := strings.Join(literal, "/")
+		if root == "" {
+			root = "/"
+		}
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+	var out []string
+	for _, r := range roots {
+		if n := len(out); n > 0 && (r == out[n-1] || strings.HasPrefix(r, strings.TrimSuffix(out[n-1], "/")+"/")) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// resolve makes a spec absolute (slash-separated) relative to the config
+// directory.
+func (c *Config) resolve(spec string) string {
+	spec = filepath.ToSlash(spec)
+	if path.IsAbs(spec) || filepath.IsAbs(spec) {
+		return path.Clean(spec)
+	}
+	return path.Join(filepath.ToSlash(c.Dir), spec)
+}
+
+// Includes reports whether file belongs to the project by the config's
+// files/include/exclude rules. Files listed in "files" are always
+// included; everything else must match an include spec, no exclude spec,
+// and have a source extension the config compiles.
+func (c *Config) Includes(file string) bool {
+	p := filepath.ToSlash(filepath.Clean(file))
+	if c.files[p] {
+		return true
+	}
+	if !c.SourceFile(p) {
+		return false
+	}
+	matched := false
+	for _, re := range c.includes {
+		if re.MatchString(p) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	for _, re := range c.excludes {
+		if re.MatchString(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// SourceFile reports whether file has an extension the compiler picks up
+// from include globs: TypeScript always, JavaScript with allowJs or
+// checkJs, which implies it.
+func (c *Config) SourceFile(file string) bool {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".ts", ".tsx", ".mts", ".cts":
+		return true
+	case ".js", ".jsx", ".mjs", ".cjs":
+		return c.CompilerOptions.AllowJS || c.CompilerOptions.CheckJS
+	}
+	return false
+}
+
+// PathMapper returns the mapper for compilerOptions.paths, or nil when the
+// config declares none.
+func (c *Config) PathMapper() (*PathMapper, error) {
+	if len(c.CompilerOptions.Paths) == 0 {
+		return nil, nil
+	}
+	baseURL := ""
+	if c.CompilerOptions.BaseURL != "" {
+		baseURL = filepath.FromSlash(c.resolve(c.CompilerOptions.BaseURL))
+	}
+	return PathMapperFromJSON(c.Dir, baseURL, c.CompilerOptions.Paths)
+}
+
+// ExcludePattern compiles spec, relative to dir, as tsconfig compiles an
+// exclude entry: a match covers the matched path and everything below it.
+// It returns nil for an empty spec.
+func ExcludePattern(dir, spec string) *regexp.Regexp {
+	return specRegexp(dir, spec, true)
+}
+
+// specRegexp compiles an include or exclude spec. '**' matches any number
+// of directories, '*' and '?' match within one path segment. A spec whose
+// last segment has no wildcard and no extension names a directory and
+// covers everything below it; exclude specs always cover descendants.
+func specRegexp(dir, spec string, exclude bool) *regexp.Regexp {
+	if spec == "" {
+		return nil
+	}
+	abs := path.Join(filepath.ToSlash(dir), filepath.ToSlash(spec))
+	if path.IsAbs(filepath.ToSlash(spec)) {
+		abs = path.Clean(filepath.ToSlash(spec))
+	}
+	last := path.Base(abs)
+	switch {
+	case last == "**":
+		abs += "/*"
+	case !exclude && !strings.ContainsAny(last, "*?") && path.Ext(last) == "":
+		abs += "/**/*"
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	segments := strings.Split(abs, "/")
+	for i, seg := range segments {
+		if seg == "**" {
+			// "a/**/b" matches "a/b" too, so the separator is optional.
+			b.WriteString("(?:[^/]+/)*")
+			continue
+		}
+		for _, r := range seg {
+			switch r {
+			case '*':
+				b.WriteString("[^/]*")
+			case '?':
+				b.WriteString("[^/]")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		if i < len(segments)-1 {
+			b.WriteString("/")
+		}
+	}
+	if exclude {
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// StripJSONC removes // and /* */ comments and trailing commas so JSONC
+// content can be decoded with encoding/json. String contents are left
+// untouched.
+func StripJSONC(data []byte) []byte {
+	return stripTrailingCommas(stripComments(data))
+}
+
+func stripComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// stripTrailingCommas drops commas whose next significant byte closes an
+// object or array. data must already be free of comments.
+func stripTrailingCommas(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+		}
+		if c == ',' {
+			j := i + 1
+			for j < len(data) && (data[j] == ' ' || data[j] == '\t' || data[j] == '\n' || data[j] == '\r') {
+				j++
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
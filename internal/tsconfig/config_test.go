@@ -0,0 +1,128 @@
+package tsconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONC(t *testing.T) {
+	cfg, err := Parse("/p/tsconfig.json", []byte(`{
+		// line comment
+		"compilerOptions": {
+			/* block */ "outDir": "dist", // trailing
+			"baseUrl": "./src",
+		},
+		"include": ["src", "types/**/*.d.ts",],
+		"exclude": ["src/**/*.test.ts", "http://x"],
+	}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Dir != "/p" || cfg.CompilerOptions.OutDir != "dist" || cfg.CompilerOptions.BaseURL != "./src" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if len(cfg.Include) != 2 || len(cfg.Exclude) != 2 || cfg.Exclude[1] != "http://x" {
+		t.Errorf("include/exclude = %v / %v", cfg.Include, cfg.Exclude)
+	}
+}
+
+func TestConfigIncludes(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		file   string
+		want   bool
+	}{
+		{"default include", `{}`, "/p/src/a.ts", true},
+		{"default include tsx", `{}`, "/p/a.tsx", true},
+		{"default excludes node_modules", `{}`, "/p/node_modules/x/index.ts", false},
+		{"default excludes outDir", `{"compilerOptions":{"outDir":"build"}}`, "/p/build/a.d.ts", false},
+		{"js without allowJs", `{}`, "/p/a.js", false},
+		{"js with allowJs", `{"compilerOptions":{"allowJs":true}}`, "/p/a.js", true},
+		{"js with checkJs only", `{"compilerOptions":{"checkJs":true}}`, "/p/a.js", true},
+		{"non-source extension", `{}`, "/p/README.md", false},
+		{"outside config dir", `{}`, "/other/a.ts", false},
+		{"include directory", `{"include":["src"]}`, "/p/src/deep/a.ts", true},
+		{"include directory miss", `{"include":["src"]}`, "/p/scripts/seed.ts", false},
+		{"include star", `{"include":["src/*"]}`, "/p/src/deep/a.ts", false},
+		{"include globstar zero dirs", `{"include":["src/**/*.ts"]}`, "/p/src/a.ts", true},
+		{"include trailing globstar", `{"include":["src/**"]}`, "/p/src/x/a.ts", true},
+		{"include question mark", `{"include":["src/?.ts"]}`, "/p/src/a.ts", true},
+		{"exclude directory", `{"include":["src"],"exclude":["src/legacy"]}`, "/p/src/legacy/old.ts", false},
+		{"exclude glob", `{"exclude":["**/*.test.ts"]}`, "/p/src/a.test.ts", false},
+		{"explicit exclude drops defaults", `{"exclude":["dist"]}`, "/p/node_modules/x/a.ts", true},
+		{"files only", `{"files":["main.ts"]}`, "/p/other.ts", false},
+		{"files wins over exclude", `{"files":["legacy/a.ts"],"exclude":["legacy"]}`, "/p/legacy/a.ts", true},
+		{"files plus include", `{"files":["main.ts"],"include":["lib"]}`, "/p/lib/a.ts", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse("/p/tsconfig.json", []byte(tt.config))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := cfg.Includes(tt.file); got != tt.want {
+				t.Errorf("Includes(%q) = %v, want %v", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModuleResolution(t *testing.T) {
+	tests := []struct {
+		options string
+		want    ResolutionMode
+	}{
+		{`{}`, ResolutionNode10},
+		{`{"module": "commonjs"}`, ResolutionNode10},
+		{`{"module": "Node16"}`, ResolutionNode16},
+		{`{"module": "NodeNext"}`, ResolutionNodeNext},
+		{`{"module": "preserve"}`, ResolutionBundler},
+		{`{"module": "esnext"}`, ResolutionClassic},
+		{`{"module": "esnext", "moduleResolution": "bundler"}`, ResolutionBundler},
+		{`{"module": "commonjs", "moduleResolution": "node"}`, ResolutionNode10},
+		{`{"module": "nodenext", "moduleResolution": "NodeNext"}`, ResolutionNodeNext},
+	}
+	for _, tt := range tests {
+		cfg, err := Parse("/p/tsconfig.json", []byte(`{"compilerOptions": `+tt.options+`}`))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if got := cfg.ModuleResolution(); got != tt.want {
+			t.Errorf("ModuleResolution(%s) = %q, want %q", tt.options, got, tt.want)
+		}
+	}
+}
+
+func TestSourceFileModuleExtensions(t *testing.T) {
+	cfg, err := Parse("/p/tsconfig.json", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]bool{"/p/a.mts": true, "/p/a.cts": true, "/p/a.d.mts": true, "/p/a.mjs": false} {
+		if got := cfg.Includes(file); got != want {
+			t.Errorf("Includes(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestIncludeRoots(t *testing.T) {
+	tests := []struct {
+		config string
+		want   []string
+	}{
+		{`{}`, []string{"/p"}},
+		{`{"files": ["main.ts"]}`, nil},
+		{`{"include": ["src/**/*.ts", "src/lib", "test/*.spec.ts"]}`, []string{"/p/src", "/p/test"}},
+		{`{"include": ["../shared/**/*", "src/index.ts", "**/*.d.ts"]}`, []string{"/p", "/shared"}},
+	}
+	for _, tt := range tests {
+		cfg, err := Parse("/p/tsconfig.json", []byte(tt.config))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.IncludeRoots(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: IncludeRoots = %v, want %v", tt.config, got, tt.want)
+		}
+	}
+}
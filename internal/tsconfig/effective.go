@@ -0,0 +1,177 @@
+package tsconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Effective is a tsconfig with its "extends" chain applied as tsc applies
+// it: compiler options merge option by option, the config overriding its
+// bases and later bases overriding earlier ones; "files", "include" and
+// "exclude" are taken whole from the nearest config that sets them;
+// "references" are never inherited.
+type Effective struct {
+	// Path is the absolute path of the config.
+	Path string `json:"-"`
+	// CompilerOptions are the merged compiler options, as written.
+	CompilerOptions map[string]json.RawMessage `json:"compilerOptions"`
+	Files           []string                   `json:"files,omitempty"`
+	Include         []string                   `json:"include,omitempty"`
+	Exclude         []string                   `json:"exclude,omitempty"`
+	// References are the paths of the config's project references.
+	References []string `json:"references,omitempty"`
+	// Bases are the absolute paths of the configs extended, directly or
+	// not, in the order they were applied.
+	Bases []string `json:"bases,omitempty"`
+	// Sources maps each setting that came from a base to that base's
+	// path. Compiler options are keyed "compilerOptions.<name>".
+	Sources map[string]string `json:"sources,omitempty"`
+	// Unresolved are the "extends" entries that name no readable config,
+	// or one already on the chain.
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// ParseError is a config of an extends chain that is not valid JSONC.
+type ParseError struct {
+	// File is the absolute path of the offending config.
+	File string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing %s: %v", e.File, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// rawConfig is a config file as written.
+type rawConfig struct {
+	Extends         json.RawMessage            `json:"extends"`
+	CompilerOptions map[string]json.RawMessage `json:"compilerOptions"`
+	Files           []string                   `json:"files"`
+	Include         []string                   `json:"include"`
+	Exclude         []string                   `json:"exclude"`
+	References      []struct {
+		Path string `json:"path"`
+	} `json:"references"`
+}
+
+// LoadEffective reads the config at configPath and the configs it
+// extends, relative paths and packages under node_modules, and merges
+// them. A config that is not valid JSONC fails the load with a
+// *ParseError naming it.
+func LoadEffective(configPath string) (*Effective, error) {
+	abs, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	e := &Effective{Path: abs, CompilerOptions: make(map[string]json.RawMessage), Sources: make(map[string]string)}
+	if err := e.merge(abs, map[string]bool{abs: true}, 0); err != nil {
+		return nil, err
+	}
+	// Settings of the config itself need no source.
+	for key, src := range e.Sources {
+		if src == abs {
+			delete(e.Sources, key)
+		}
+	}
+	return e, nil
+}
+
+// merge applies the config at path, after its bases. chain holds the
+// configs being merged, to skip cycles.
+func (e *Effective) merge(path string, chain map[string]bool, depth int) error {
+	if depth > maxExtendsDepth {
+		return fmt.Errorf("%s: extends chain too deep", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var raw rawConfig
+	if err := json.Unmarshal(StripJSONC(data), &raw); err != nil {
+		return &ParseError{File: path, Err: err}
+	}
+
+	var bases []string
+	if len(raw.Extends) > 0 {
+		var one string
+		if json.Unmarshal(raw.Extends, &one) == nil {
+			bases = []string{one}
+		} else {
+			_ = json.Unmarshal(raw.Extends, &bases)
+		}
+	}
+	for _, b := range bases {
+		p, ok := resolveExtends(filepath.Dir(path), b)
+		if ok {
+			p, _ = filepath.Abs(p)
+		}
+		if !ok || chain[p] {
+			e.Unresolved = append(e.Unresolved, b)
+			continue
+		}
+		chain[p] = true
+		if err := e.merge(p, chain, depth+1); err != nil {
+			return err
+		}
+		delete(chain, p)
+		e.Bases = append(e.Bases, p)
+	}
+
+	for key, value := range raw.CompilerOptions {
+		e.CompilerOptions[key] = value
+		e.Sources["compilerOptions."+key] = path
+	}
+	if raw.Files != nil {
+		e.Files, e.Sources["files"] = raw.Files, path
+	}
+	if raw.Include != nil {
+		e.Include, e.Sources["include"] = raw.Include, path
+	}
+	if raw.Exclude != nil {
+		e.Exclude, e.Sources["exclude"] = raw.Exclude, path
+	}
+	if depth == 0 {
+		for _, r := range raw.References {
+			e.References = append(e.References, r.Path)
+		}
+	}
+	return nil
+}
+
+// Config returns the effective settings as a Config. Specs and paths
+// inherited from a base are made absolute against the base's directory,
+// as tsc resolves them.
+func (e *Effective) Config() *Config {
+	cfg := &Config{Path: e.Path, Dir: filepath.Dir(e.Path)}
+	if data, err := json.Marshal(e.CompilerOptions); err == nil {
+		_ = json.Unmarshal(data, &cfg.CompilerOptions)
+	}
+	resolve := func(key, spec string) string {
+		src, ok := e.Sources[key]
+		if !ok || spec == "" || filepath.IsAbs(filepath.FromSlash(spec)) {
+			return spec
+		}
+		return filepath.ToSlash(filepath.Join(filepath.Dir(src), filepath.FromSlash(spec)))
+	}
+	specs := func(key string, list []string) []string {
+		if list == nil {
+			return nil
+		}
+		out := make([]string, len(list))
+		for i, spec := range list {
+			out[i] = resolve(key, spec)
+		}
+		return out
+	}
+	cfg.Files = specs("files", e.Files)
+	cfg.Include = specs("include", e.Include)
+	cfg.Exclude = specs("exclude", e.Exclude)
+	cfg.CompilerOptions.OutDir = resolve("compilerOptions.outDir", cfg.CompilerOptions.OutDir)
+	cfg.CompilerOptions.BaseURL = resolve("compilerOptions.baseUrl", cfg.CompilerOptions.BaseURL)
+	cfg.compile()
+	return cfg
+}
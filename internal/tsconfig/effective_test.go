@@ -0,0 +1,123 @@
+package tsconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeConfigs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for rel, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadEffective(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"tsconfig.json": `{
+			// the app config
+			"extends": ["@tsconfig/node20", "./config/base.json", "./missing.json"],
+			"compilerOptions": {"strict": true,},
+			"include": ["src"],
+			"references": [{"path": "./packages/core"}],
+		}`,
+		"node_modules/@tsconfig/node20/tsconfig.json": `{"compilerOptions": {"target": "es2022", "module": "node16", "strict": false}}`,
+		"config/base.json": `{
+			"extends": "../tsconfig.json",
+			"compilerOptions": {"module": "esnext", "baseUrl": "..", "paths": {"@/*": ["src/*"]}},
+			"exclude": ["dist"],
+			"references": [{"path": "../packages/ignored"}]
+		}`,
+	})
+	eff, err := LoadEffective(filepath.Join(dir, "tsconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	node20 := filepath.Join(dir, "node_modules", "@tsconfig", "node20", "tsconfig.json")
+	base := filepath.Join(dir, "config", "base.json")
+
+	options := make(map[string]string)
+	for k, v := range eff.CompilerOptions {
+		options[k] = string(v)
+	}
+	wantOptions := map[string]string{
+		"target": `"es2022"`, "module": `"esnext"`, "strict": "true",
+		"baseUrl": `".."`, "paths": `{"@/*": ["src/*"]}`,
+	}
+	if !reflect.DeepEqual(options, wantOptions) {
+		t.Errorf("compilerOptions = %v, want %v", options, wantOptions)
+	}
+	wantSources := map[string]string{
+		"compilerOptions.target": node20, "compilerOptions.module": base,
+		"compilerOptions.baseUrl": base, "compilerOptions.paths": base, "exclude": base,
+	}
+	if !reflect.DeepEqual(eff.Sources, wantSources) {
+		t.Errorf("sources = %v, want %v", eff.Sources, wantSources)
+	}
+	if !reflect.DeepEqual(eff.Include, []string{"src"}) || !reflect.DeepEqual(eff.Exclude, []string{"dist"}) || eff.Files != nil {
+		t.Errorf("include = %v, exclude = %v, files = %v", eff.Include, eff.Exclude, eff.Files)
+	}
+	if !reflect.DeepEqual(eff.References, []string{"./packages/core"}) {
+		t.Errorf("references = %v, want only the config's own", eff.References)
+	}
+	if !reflect.DeepEqual(eff.Bases, []string{node20, base}) {
+		t.Errorf("bases = %v", eff.Bases)
+	}
+	if !reflect.DeepEqual(eff.Unresolved, []string{"../tsconfig.json", "./missing.json"}) {
+		t.Errorf("unresolved = %v", eff.Unresolved)
+	}
+}
+
+func TestEffectiveConfig(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"app/tsconfig.json":   `{"extends": "../base/tsconfig.json", "compilerOptions": {"allowJs": true}}`,
+		"base/tsconfig.json":  `{"compilerOptions": {"outDir": "out", "module": "nodenext"}, "include": ["../app/src"], "exclude": ["../app/src/gen"]}`,
+		"app/src/index.ts":    "",
+		"app/src/legacy.js":   "",
+		"app/src/gen/api.ts":  "",
+		"base/out/index.d.ts": "",
+	})
+	eff, err := LoadEffective(filepath.Join(dir, "app", "tsconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := eff.Config()
+	app := filepath.ToSlash(filepath.Join(dir, "app"))
+	if want := []string{app + "/src"}; !reflect.DeepEqual(cfg.IncludeRoots(), want) {
+		t.Errorf("IncludeRoots = %v, want %v", cfg.IncludeRoots(), want)
+	}
+	if cfg.ModuleResolution() != ResolutionNodeNext || cfg.CompilerOptions.OutDir != filepath.ToSlash(filepath.Join(dir, "base", "out")) {
+		t.Errorf("moduleResolution = %s, outDir = %s", cfg.ModuleResolution(), cfg.CompilerOptions.OutDir)
+	}
+	for file, want := range map[string]bool{"src/index.ts": true, "src/legacy.js": true, "src/gen/api.ts": false} {
+		if got := cfg.Includes(filepath.Join(app, file)); got != want {
+			t.Errorf("Includes(%s) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestLoadEffectiveErrors(t *testing.T) {
+	dir := writeConfigs(t, map[string]string{
+		"tsconfig.json":      `{"extends": "./tsconfig.base.json"}`,
+		"tsconfig.base.json": `{"compilerOptions": {"strict": true`,
+	})
+	_, err := LoadEffective(filepath.Join(dir, "tsconfig.json"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.File != filepath.Join(dir, "tsconfig.base.json") {
+		t.Errorf("malformed base: err = %v, want a ParseError for the base", err)
+	}
+	if _, err := LoadEffective(filepath.Join(t.TempDir(), "tsconfig.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing config: err = %v", err)
+	}
+}
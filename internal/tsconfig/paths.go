@@ -0,0 +1,284 @@
+// Package tsconfig implements the parts of TypeScript's project
+// configuration semantics that the tools need outside of tsgo.
+package tsconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PathMapper resolves module specifiers through compilerOptions.paths using
+// the same rules as tsc's module resolver:
+//
+//   - an exact (star-free) key wins over any wildcard key;
+//   - among wildcard keys the one with the longest prefix before the '*'
+//     wins, ties going to the key declared first;
+//   - the text matched by '*' replaces the first '*' of each substitution;
+//   - substitutions are tried in the order they are declared;
+//   - substitutions are relative to baseUrl when set, otherwise to the
+//     directory containing the tsconfig that declared paths.
+type PathMapper struct {
+	base     string
+	patterns []pathPattern
+}
+
+type pathPattern struct {
+	key           string
+	prefix        string
+	suffix        string
+	wildcard      bool
+	substitutions []string
+}
+
+// NewPathMapper builds a mapper for a paths table. configDir is the
+// directory of the tsconfig that declared paths; baseURL is the resolved
+// (absolute) baseUrl or empty if none was set. keys holds the declaration
+// order of the paths object, since Go maps do not preserve it; keys missing
+// from it are appended in sorted order. Keys with more than one '*' are
+// ignored, matching tsc which reports them as errors.
+func NewPathMapper(configDir, baseURL string, paths map[string][]string, keys []string) *PathMapper {
+	base := baseURL
+	if base == "" {
+		base = configDir
+	}
+	m := &PathMapper{base: filepath.ToSlash(filepath.Clean(base))}
+
+	ordered := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, k := range keys {
+		if _, ok := paths[k]; ok && !seen[k] {
+			ordered = append(ordered, k)
+			seen[k] = true
+		}
+	}
+	var rest []string
+	for k := range paths {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	ordered = append(ordered, rest...)
+
+	for _, k := range ordered {
+		stars := strings.Count(k, "*")
+		if stars > 1 {
+			continue
+		}
+		p := pathPattern{key: k, substitutions: paths[k], wildcard: stars == 1}
+		if p.wildcard {
+			i := strings.IndexByte(k, '*')
+			p.prefix, p.suffix = k[:i], k[i+1:]
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m
+}
+
+// Match returns the paths key that applies to specifier and the text
+// captured by its '*' (empty for exact keys).
+func (m *PathMapper) Match(specifier string) (key, captured string, ok bool) {
+	if m == nil {
+		return "", "", false
+	}
+	for _, p := range m.patterns {
+		if !p.wildcard && p.key == specifier {
+			return p.key, "", true
+		}
+	}
+	best := -1
+	for i, p := range m.patterns {
+		if !p.wildcard || !p.matches(specifier) {
+			continue
+		}
+		if best < 0 || len(p.prefix) > len(m.patterns[best].prefix) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return "", "", false
+	}
+	p := m.patterns[best]
+	return p.key, specifier[len(p.prefix) : len(specifier)-len(p.suffix)], true
+}
+
+func (p pathPattern) matches(s string) bool {
+	return len(s) >= len(p.prefix)+len(p.suffix) &&
+		strings.HasPrefix(s, p.prefix) &&
+		strings.HasSuffix(s, p.suffix)
+}
+
+// Resolve returns the candidate paths (without extension probing) for
+// specifier in the order tsc would try them. It returns nil when no paths
+// key matches.
+func (m *PathMapper) Resolve(specifier string) []string {
+	key, captured, ok := m.Match(specifier)
+	if !ok {
+		return nil
+	}
+	var p pathPattern
+	for _, cand := range m.patterns {
+		if cand.key == key {
+			p = cand
+			break
+		}
+	}
+	out := make([]string, 0, len(p.substitutions))
+	for _, sub := range p.substitutions {
+		if p.wildcard {
+			sub = strings.Replace(sub, "*", captured, 1)
+		}
+		out = append(out, m.join(sub))
+	}
+	return out
+}
+
+// IsAlias reports whether specifier is mapped by paths. Module graph
+// classification treats aliased specifiers as internal even though they
+// look like bare package names.
+func (m *PathMapper) IsAlias(specifier string) bool {
+	_, _, ok := m.Match(specifier)
+	return ok
+}
+
+// Specifier returns the aliased import specifier that resolves to file, if
+// any paths entry maps to it. file is an absolute path; its TypeScript
+// extension is dropped, and a trailing /index is dropped when the alias
+// points at the directory. When several aliases apply, exact keys are
+// preferred, then the shortest specifier.
+func (m *PathMapper) Specifier(file string) (string, bool) {
+	spec, _, ok := m.specifier(file, false)
+	return spec, ok
+}
+
+// specifier implements Specifier. keepIndex disables the directory form,
+// for resolution modes without directory index lookup; exact reports
+// whether the result is an exact (star-free) key.
+func (m *PathMapper) specifier(file string, keepIndex bool) (spec string, exact, ok bool) {
+	if m == nil {
+		return "", false, false
+	}
+	target := filepath.ToSlash(file)
+	forms := []string{target, trimTSExtension(target)}
+	if dir, ok := strings.CutSuffix(forms[1], "/index"); ok && !keepIndex {
+		forms = append(forms, dir)
+	}
+
+	var best string
+	bestExact := false
+	found := false
+	consider := func(spec string, exact bool) {
+		switch {
+		case !found,
+			exact && !bestExact,
+			exact == bestExact && len(spec) < len(best):
+			best, bestExact, found = spec, exact, true
+		}
+	}
+
+	for _, p := range m.patterns {
+		for _, sub := range p.substitutions {
+			abs := m.join(sub)
+			for _, form := range forms {
+				if !p.wildcard || !strings.Contains(sub, "*") {
+					if form == abs || form == trimTSExtension(abs) {
+						if p.wildcard {
+							// A wildcard key with a star-free
+							// substitution maps every capture to
+							// one file; there is no canonical
+							// specifier to emit.
+							continue
+						}
+						consider(p.key, true)
+					}
+					continue
+				}
+				i := strings.IndexByte(abs, '*')
+				subPrefix, subSuffix := abs[:i], abs[i+1:]
+				for _, sfx := range []string{subSuffix, trimTSExtension(subSuffix)} {
+					if len(form) < len(subPrefix)+len(sfx) ||
+						!strings.HasPrefix(form, subPrefix) ||
+						!strings.HasSuffix(form, sfx) {
+						continue
+					}
+					captured := form[len(subPrefix) : len(form)-len(sfx)]
+					if captured == "" && sfx == "" {
+						continue
+					}
+					consider(p.prefix+captured+p.suffix, false)
+				}
+			}
+		}
+	}
+	return best, bestExact, found
+}
+
+// join resolves a substitution against the mapper's base directory.
+func (m *PathMapper) join(sub string) string {
+	if path.IsAbs(sub) {
+		return path.Clean(sub)
+	}
+	return path.Join(m.base, sub)
+}
+
+// tsExtensions lists the suffixes dropped from import specifiers, longest
+// first so declaration files lose the whole ".d.ts".
+var tsExtensions = []string{".d.mts", ".d.cts", ".d.ts", ".tsx", ".mts", ".cts", ".ts", ".jsx", ".mjs", ".cjs", ".js"}
+
+func trimTSExtension(p string) string {
+	for _, ext := range tsExtensions {
+		if strings.HasSuffix(p, ext) {
+			return strings.TrimSuffix(p, ext)
+		}
+	}
+	return p
+}
+
+// PathMapperFromJSON builds a mapper from the raw JSON value of
+// compilerOptions.paths, preserving key declaration order (which decides
+// ties between equally specific wildcard keys).
+func PathMapperFromJSON(configDir, baseURL string, raw json.RawMessage) (*PathMapper, error) {
+	var paths map[string][]string
+	if err := json.Unmarshal(raw, &paths); err != nil {
+		return nil, fmt.Errorf("parsing paths: %w", err)
+	}
+	keys, err := objectKeys(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing paths: %w", err)
+	}
+	return NewPathMapper(configDir, baseURL, paths, keys), nil
+}
+
+// objectKeys returns the top-level keys of a JSON object in source order.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, fmt.Errorf("expected object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected object key")
+		}
+		keys = append(keys, key)
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,293 @@
+package tsconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+// The resolution cases below are adapted from the paths tests in the
+// TypeScript compiler's moduleNameResolver suite (tryLoadModuleUsingPaths).
+func TestPathMapperResolve(t *testing.T) {
+	tests := []struct {
+		name      string
+		configDir string
+		baseURL   string
+		paths     string
+		specifier string
+		want      []string
+	}{
+		{
+			name:      "catch-all with fallback array in declaration order",
+			configDir: "/root",
+			baseURL:   "/root",
+			paths:     `{"*": ["*", "generated/*"]}`,
+			specifier: "folder1/file2",
+			want:      []string{"/root/folder1/file2", "/root/generated/folder1/file2"},
+		},
+		{
+			name:      "longest prefix wins over catch-all",
+			configDir: "/root",
+			baseURL:   "/root",
+			paths:     `{"*": ["*"], "components/*": ["shared/components/*"]}`,
+			specifier: "components/file3",
+			want:      []string{"/root/shared/components/file3"},
+		},
+		{
+			name:      "longest prefix wins regardless of declaration order",
+			configDir: "/root",
+			paths:     `{"@app/feature/*": ["features/*"], "@app/*": ["src/*"]}`,
+			specifier: "@app/feature/login",
+			want:      []string{"/root/features/login"},
+		},
+		{
+			name:      "exact key wins over matching wildcard",
+			configDir: "/root",
+			baseURL:   "/root",
+			paths:     `{"*": ["node_modules/*"], "jquery": ["vendor/jquery/dist/jquery"]}`,
+			specifier: "jquery",
+			want:      []string{"/root/vendor/jquery/dist/jquery"},
+		},
+		{
+			name:      "equal prefixes tie to the first declared key",
+			configDir: "/root",
+			paths:     `{"a*": ["first/*"], "a*z": ["second/*"]}`,
+			specifier: "abz",
+			want:      []string{"/root/first/bz"},
+		},
+		{
+			name:      "prefix and suffix around the star",
+			configDir: "/repo",
+			paths:     `{"@lib/*/testing": ["libs/*/src/testing"]}`,
+			specifier: "@lib/auth/testing",
+			want:      []string{"/repo/libs/auth/src/testing"},
+		},
+		{
+			name:      "star may capture multiple segments",
+			configDir: "/repo",
+			paths:     `{"~/*": ["src/*"]}`,
+			specifier: "~/a/b/c",
+			want:      []string{"/repo/src/a/b/c"},
+		},
+		{
+			name:      "star may capture the empty string",
+			configDir: "/repo",
+			paths:     `{"lib*": ["libs/main*"]}`,
+			specifier: "lib",
+			want:      []string{"/repo/libs/main"},
+		},
+		{
+			name:      "only the first star of a substitution is replaced",
+			configDir: "/repo",
+			paths:     `{"x/*": ["a/*/b/*"]}`,
+			specifier: "x/y",
+			want:      []string{"/repo/a/y/b/*"},
+		},
+		{
+			name:      "wildcard key with star-free substitution",
+			configDir: "/repo",
+			paths:     `{"shim/*": ["shims/index.ts"]}`,
+			specifier: "shim/anything",
+			want:      []string{"/repo/shims/index.ts"},
+		},
+		{
+			name:      "baseUrl is the base when set",
+			configDir: "/repo/packages/app",
+			baseURL:   "/repo/packages/app/src",
+			paths:     `{"@/*": ["./*"]}`,
+			specifier: "@/util",
+			want:      []string{"/repo/packages/app/src/util"},
+		},
+		{
+			name:      "paths without baseUrl are relative to the tsconfig directory",
+			configDir: "/repo/packages/app",
+			paths:     `{"@/*": ["./src/*"]}`,
+			specifier: "@/util",
+			want:      []string{"/repo/packages/app/src/util"},
+		},
+		{
+			name:      "substitution may climb out of the base",
+			configDir: "/repo/packages/app",
+			paths:     `{"@shared/*": ["../shared/src/*"]}`,
+			specifier: "@shared/x",
+			want:      []string{"/repo/packages/shared/src/x"},
+		},
+		{
+			name:      "trailing slash in key requires the slash",
+			configDir: "/repo",
+			paths:     `{"@app/*": ["src/*"]}`,
+			specifier: "@app",
+			want:      nil,
+		},
+		{
+			name:      "exact key with trailing slash matches only literally",
+			configDir: "/repo",
+			paths:     `{"@app/": ["src/index"]}`,
+			specifier: "@app/",
+			want:      []string{"/repo/src/index"},
+		},
+		{
+			name:      "keys with two stars are ignored",
+			configDir: "/repo",
+			paths:     `{"*/*": ["never/*"]}`,
+			specifier: "a/b",
+			want:      nil,
+		},
+		{
+			name:      "no match",
+			configDir: "/repo",
+			paths:     `{"@app/*": ["src/*"]}`,
+			specifier: "react",
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := PathMapperFromJSON(tt.configDir, tt.baseURL, []byte(tt.paths))
+			if err != nil {
+				t.Fatalf("PathMapperFromJSON: %v", err)
+			}
+			got := m.Resolve(tt.specifier)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve(%q) = %v, want %v", tt.specifier, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathMapperSpecifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		paths  string
+		file   string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "wildcard alias",
+			paths:  `{"@app/*": ["src/*"]}`,
+			file:   "/repo/src/util/date.ts",
+			want:   "@app/util/date",
+			wantOK: true,
+		},
+		{
+			name:   "alias with suffix around the star",
+			paths:  `{"@lib/*/testing": ["libs/*/src/testing"]}`,
+			file:   "/repo/libs/auth/src/testing/index.ts",
+			want:   "@lib/auth/testing",
+			wantOK: true,
+		},
+		{
+			name:   "exact key preferred over shorter wildcard",
+			paths:  `{"*": ["vendor/*"], "jq": ["vendor/jquery/dist/jquery"]}`,
+			file:   "/repo/vendor/jquery/dist/jquery.d.ts",
+			want:   "jq",
+			wantOK: true,
+		},
+		{
+			name:   "shortest wildcard specifier wins",
+			paths:  `{"@app/*": ["src/*"], "@feature/*": ["src/features/*"]}`,
+			file:   "/repo/src/features/login.tsx",
+			want:   "@feature/login",
+			wantOK: true,
+		},
+		{
+			name:   "fallback substitution also maps",
+			paths:  `{"@gen/*": ["src/gen/*", "generated/*"]}`,
+			file:   "/repo/generated/api.ts",
+			want:   "@gen/api",
+			wantOK: true,
+		},
+		{
+			name:   "unmapped file",
+			paths:  `{"@app/*": ["src/*"]}`,
+			file:   "/repo/test/util.ts",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := PathMapperFromJSON("/repo", "", []byte(tt.paths))
+			if err != nil {
+				t.Fatalf("PathMapperFromJSON: %v", err)
+			}
+			got, ok := m.Specifier(tt.file)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Specifier(%q) = %q, %v; want %q, %v", tt.file, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestImportSpecifier(t *testing.T) {
+	m, err := PathMapperFromJSON("/repo", "", []byte(`{"@lib/*": ["libs/*/src"], "@/*": ["src/*"], "config": ["src/config.ts"]}`))
+	if err != nil {
+		t.Fatalf("PathMapperFromJSON: %v", err)
+	}
+	tests := []struct {
+		from, target string
+		paths        *PathMapper
+		mode         ResolutionMode
+		want         string
+	}{
+		{"/repo/src/a.ts", "/repo/src/b.ts", nil, "", "./b"},
+		{"/repo/src/a.ts", "/repo/src/util/index.ts", nil, "", "./util"},
+		{"/repo/src/deep/a.ts", "/repo/src/b.tsx", nil, "", "../b"},
+		{"/repo/src/a.ts", "/repo/libs/auth/src/index.ts", m, "", "@lib/auth"},
+		{"/repo/other/a.ts", "/repo/src/b.ts", m, "", "@/b"},
+
+		// Bundler resolution behaves like the default.
+		{"/repo/src/a.ts", "/repo/src/b.mts", nil, ResolutionBundler, "./b"},
+		{"/repo/src/a.ts", "/repo/src/util/index.ts", nil, ResolutionBundler, "./util"},
+
+		// Node16/NodeNext: emitted extension, explicit index.
+		{"/repo/src/a.ts", "/repo/src/b.ts", nil, ResolutionNode16, "./b.js"},
+		{"/repo/src/a.mts", "/repo/src/b.mts", nil, ResolutionNode16, "./b.mjs"},
+		{"/repo/src/a.ts", "/repo/src/b.cts", nil, ResolutionNodeNext, "./b.cjs"},
+		{"/repo/src/a.ts", "/repo/src/b.tsx", nil, ResolutionNode16, "./b.js"},
+		{"/repo/src/a.ts", "/repo/types/g.d.ts", nil, ResolutionNode16, "../types/g.js"},
+		{"/repo/src/a.ts", "/repo/types/g.d.mts", nil, ResolutionNode16, "../types/g.mjs"},
+		{"/repo/src/a.ts", "/repo/src/util/index.ts", nil, ResolutionNode16, "./util/index.js"},
+		{"/repo/src/a.ts", "/repo/src/util/index.mts", nil, ResolutionNodeNext, "./util/index.mjs"},
+		{"/repo/other/a.ts", "/repo/src/b.ts", m, ResolutionNode16, "@/b.js"},
+		{"/repo/other/a.ts", "/repo/src/config.ts", m, ResolutionNode16, "config"},
+		// A directory alias cannot be used without index lookup.
+		{"/repo/src/a.ts", "/repo/libs/auth/src/index.ts", m, ResolutionNode16, "../libs/auth/src/index.js"},
+	}
+	for _, tt := range tests {
+		if got := ImportSpecifier(tt.from, tt.target, tt.paths, tt.mode); got != tt.want {
+			t.Errorf("ImportSpecifier(%q, %q, %q) = %q, want %q", tt.from, tt.target, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestClassifySpecifier(t *testing.T) {
+	m, err := PathMapperFromJSON("/repo", "", []byte(`{"@lib/*": ["libs/*/src"], "config": ["src/config"]}`))
+	if err != nil {
+		t.Fatalf("PathMapperFromJSON: %v", err)
+	}
+	tests := []struct {
+		spec string
+		want ModuleKind
+	}{
+		{"./a", ModuleRelative},
+		{"../a/b", ModuleRelative},
+		{"@lib/auth", ModuleAlias},
+		{"config", ModuleAlias},
+		{"@types/node", ModuleExternal},
+		{"react", ModuleExternal},
+		{"node:fs", ModuleExternal},
+	}
+	for _, tt := range tests {
+		if got := ClassifySpecifier(tt.spec, m); got != tt.want {
+			t.Errorf("ClassifySpecifier(%q) = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+	if ClassifySpecifier("@lib/auth", nil) != ModuleExternal {
+		t.Error("without paths, @lib/auth should be external")
+	}
+}
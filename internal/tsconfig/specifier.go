@@ -0,0 +1,92 @@
+package tsconfig
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ImportSpecifier returns the specifier fromFile should use to import
+// targetFile. A paths alias is preferred when one maps to the target;
+// otherwise a relative "./" or "../" specifier is produced.
+//
+// Under Node16/NodeNext resolution relative and wildcard-alias specifiers
+// carry the extension of the emitted file (.ts→.js, .mts→.mjs, .cts→.cjs)
+// and directory index files are spelled out, since ESM resolution neither
+// adds extensions nor looks up index files. In every other mode specifiers
+// are extensionless with a trailing /index dropped.
+func ImportSpecifier(fromFile, targetFile string, paths *PathMapper, mode ResolutionMode) string {
+	explicit := mode.RequiresExtensions()
+	if spec, exact, ok := paths.specifier(targetFile, explicit); ok {
+		if explicit && !exact {
+			spec += OutputExtension(targetFile)
+		}
+		return spec
+	}
+
+	fromDir := filepath.ToSlash(filepath.Dir(fromFile))
+	target := trimTSExtension(filepath.ToSlash(targetFile))
+	if explicit {
+		target += OutputExtension(targetFile)
+	} else {
+		target = strings.TrimSuffix(target, "/index")
+	}
+
+	rel, err := filepath.Rel(filepath.FromSlash(fromDir), filepath.FromSlash(target))
+	if err != nil {
+		return target
+	}
+	rel = path.Clean(filepath.ToSlash(rel))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+		return rel
+	}
+	return "./" + rel
+}
+
+// OutputExtension returns the extension of the JavaScript file tsc emits
+// for a source file, which is what Node16/NodeNext import specifiers must
+// name. Declaration files map to the JavaScript they describe.
+func OutputExtension(file string) string {
+	switch lower := strings.ToLower(file); {
+	case strings.HasSuffix(lower, ".mts"), strings.HasSuffix(lower, ".mjs"):
+		return ".mjs"
+	case strings.HasSuffix(lower, ".cts"), strings.HasSuffix(lower, ".cjs"):
+		return ".cjs"
+	case strings.HasSuffix(lower, ".jsx"):
+		return ".jsx"
+	}
+	return ".js"
+}
+
+// ModuleKind classifies an import specifier for module graph purposes.
+type ModuleKind string
+
+const (
+	// ModuleRelative is a "./" or "../" specifier.
+	ModuleRelative ModuleKind = "relative"
+	// ModuleAlias is a bare-looking specifier mapped by compilerOptions.paths.
+	ModuleAlias ModuleKind = "alias"
+	// ModuleExternal is a package or builtin resolved outside the project.
+	ModuleExternal ModuleKind = "external"
+)
+
+// ClassifySpecifier reports whether specifier refers to project code
+// (relative or paths alias) or an external package. Aliases are checked
+// before the bare-name fallback so "@lib/*" style keys are not mistaken
+// for scoped packages.
+func ClassifySpecifier(specifier string, paths *PathMapper) ModuleKind {
+	if specifier == "." || specifier == ".." ||
+		strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") ||
+		strings.HasPrefix(specifier, "/") {
+		return ModuleRelative
+	}
+	if paths.IsAlias(specifier) {
+		return ModuleAlias
+	}
+	return ModuleExternal
+}
+
+// Internal reports whether the kind refers to project code.
+func (k ModuleKind) Internal() bool {
+	return k == ModuleRelative || k == ModuleAlias
+}
@@ -0,0 +1,133 @@
+package tsconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// strictFamily are the checks "strict" turns on unless they are set
+// individually.
+var strictFamily = []string{
+	"noImplicitAny",
+	"noImplicitThis",
+	"strictNullChecks",
+	"strictFunctionTypes",
+	"strictBindCallApply",
+	"strictPropertyInitialization",
+	"strictBuiltinIteratorReturn",
+	"useUnknownInCatchVariables",
+	"alwaysStrict",
+}
+
+// extraStrictFlags are stricter checks "strict" does not include, off by
+// default.
+var extraStrictFlags = []string{
+	"noUncheckedIndexedAccess",
+	"exactOptionalPropertyTypes",
+	"noImplicitOverride",
+	"noImplicitReturns",
+	"noFallthroughCasesInSwitch",
+	"noPropertyAccessFromIndexSignature",
+}
+
+// maxExtendsDepth bounds the "extends" chain, which guards against cycles.
+const maxExtendsDepth = 16
+
+// Strictness returns the effective strictness flags of the tsconfig at
+// configPath: "strict", the checks it implies and the stricter checks
+// outside it. Unlike Load, it follows "extends", to relative paths and to
+// packages under node_modules; bases that cannot be read are skipped.
+func Strictness(configPath string) (map[string]bool, error) {
+	abs, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	if err := mergeStrictness(abs, set, 0); err != nil {
+		return nil, err
+	}
+	flags := map[string]bool{"strict": set["strict"]}
+	for _, f := range strictFamily {
+		v, ok := set[f]
+		if !ok {
+			v = flags["strict"]
+		}
+		flags[f] = v
+	}
+	for _, f := range extraStrictFlags {
+		flags[f] = set[f]
+	}
+	return flags, nil
+}
+
+// mergeStrictness adds the boolean strictness options of the config at
+// path to set, after those of its bases, so the config overrides them.
+func mergeStrictness(path string, set map[string]bool, depth int) error {
+	if depth > maxExtendsDepth {
+		return fmt.Errorf("%s: extends chain too deep", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var raw struct {
+		Extends         json.RawMessage            `json:"extends"`
+		CompilerOptions map[string]json.RawMessage `json:"compilerOptions"`
+	}
+	if err := json.Unmarshal(StripJSONC(data), &raw); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	var bases []string
+	if len(raw.Extends) > 0 {
+		var one string
+		if json.Unmarshal(raw.Extends, &one) == nil {
+			bases = []string{one}
+		} else {
+			_ = json.Unmarshal(raw.Extends, &bases)
+		}
+	}
+	// Later bases override earlier ones.
+	for _, b := range bases {
+		if p, ok := resolveExtends(filepath.Dir(path), b); ok {
+			_ = mergeStrictness(p, set, depth+1)
+		}
+	}
+
+	for key, value := range raw.CompilerOptions {
+		var v bool
+		if json.Unmarshal(value, &v) != nil {
+			continue
+		}
+		set[key] = v
+	}
+	return nil
+}
+
+// resolveExtends finds the config an "extends" entry of a config in dir
+// names: a path relative to dir, or a package config under node_modules,
+// with ".json" or "/tsconfig.json" added as tsc does.
+func resolveExtends(dir, spec string) (string, bool) {
+	var bases []string
+	if filepath.IsAbs(spec) || strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") {
+		bases = []string{filepath.Join(dir, filepath.FromSlash(spec))}
+	} else {
+		for d := dir; ; d = filepath.Dir(d) {
+			bases = append(bases, filepath.Join(d, "node_modules", filepath.FromSlash(spec)))
+			if filepath.Dir(d) == d {
+				break
+			}
+		}
+	}
+	for _, b := range bases {
+		for _, p := range []string{b, b + ".json", filepath.Join(b, "tsconfig.json")} {
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
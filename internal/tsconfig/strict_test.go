@@ -0,0 +1,81 @@
+package tsconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStrictness(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  map[string]bool // flags checked; others are not
+	}{
+		{
+			name:  "defaults",
+			files: map[string]string{"tsconfig.json": `{}`},
+			want:  map[string]bool{"strict": false, "strictNullChecks": false, "noUncheckedIndexedAccess": false},
+		},
+		{
+			name: "strict with an override",
+			files: map[string]string{"tsconfig.json": `{
+				// comments are fine
+				"compilerOptions": {"strict": true, "strictPropertyInitialization": false, "noUncheckedIndexedAccess": true,},
+			}`},
+			want: map[string]bool{"strict": true, "noImplicitAny": true, "strictPropertyInitialization": false, "noUncheckedIndexedAccess": true},
+		},
+		{
+			name: "relative extends chain",
+			files: map[string]string{
+				"tsconfig.json":      `{"extends": "./config/app", "compilerOptions": {"noImplicitAny": false}}`,
+				"config/app.json":    `{"extends": "../tsconfig.base.json", "compilerOptions": {"exactOptionalPropertyTypes": true}}`,
+				"tsconfig.base.json": `{"compilerOptions": {"strict": true, "exactOptionalPropertyTypes": false}}`,
+			},
+			want: map[string]bool{"strict": true, "noImplicitAny": false, "strictNullChecks": true, "exactOptionalPropertyTypes": true},
+		},
+		{
+			name: "package and array extends",
+			files: map[string]string{
+				"tsconfig.json": `{"extends": ["@tsconfig/strictest", "./missing.json", "./loose.json"]}`,
+				"node_modules/@tsconfig/strictest/tsconfig.json": `{"compilerOptions": {"strict": true, "noImplicitOverride": true, "useUnknownInCatchVariables": true}}`,
+				"loose.json": `{"compilerOptions": {"useUnknownInCatchVariables": false}}`,
+			},
+			want: map[string]bool{"strict": true, "noImplicitOverride": true, "useUnknownInCatchVariables": false},
+		},
+		{
+			name: "extends cycle",
+			files: map[string]string{
+				"tsconfig.json": `{"extends": "./a.json"}`,
+				"a.json":        `{"extends": "./tsconfig.json", "compilerOptions": {"strict": true}}`,
+			},
+			want: map[string]bool{"strict": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for rel, content := range tt.files {
+				p := filepath.Join(dir, filepath.FromSlash(rel))
+				if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := Strictness(filepath.Join(dir, "tsconfig.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for flag, want := range tt.want {
+				if got[flag] != want {
+					t.Errorf("%s = %v, want %v", flag, got[flag], want)
+				}
+			}
+		})
+	}
+	if _, err := Strictness(filepath.Join(t.TempDir(), "tsconfig.json")); err == nil {
+		t.Error("missing config: no error")
+	}
+}
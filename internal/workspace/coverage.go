@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// projectMaxVisits caps the project file walk.
+const projectMaxVisits = 100000
+
+// ProjectFiles walks the directories the tsconfig's include specs reach
+// and returns the files its files/include/exclude rules select, sorted.
+// Files listed explicitly in "files" are included even when the walk does
+// not reach them.
+func ProjectFiles(cfg *tsconfig.Config) []string {
+	seen := make(map[string]bool)
+	for _, root := range cfg.IncludeRoots() {
+		_ = Walk(filepath.FromSlash(root), WalkOptions{MaxDepth: -1, MaxVisits: projectMaxVisits}, func(p string, d fs.DirEntry, _ int) error {
+			if !d.IsDir() && cfg.Includes(p) {
+				seen[p] = true
+			}
+			return nil
+		})
+	}
+	for _, f := range cfg.Files {
+		p := f
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(cfg.Dir, p)
+		}
+		seen[filepath.Clean(p)] = true
+	}
+	files := make([]string, 0, len(seen))
+	for f := range seen {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Coverage compares the files a tsconfig selects with the files the
+// language server has actually analyzed.
+type Coverage struct {
+	Config string `json:"config"`
+	// ProjectFiles is the number of files the config selects.
+	ProjectFiles int `json:"projectFiles"`
+	// AnalyzedFiles is the number of files the server has reported on.
+	AnalyzedFiles int `json:"analyzedFiles"`
+	// NeverAnalyzed lists files the config includes that the server has
+	// never produced diagnostics for and that were never opened.
+	NeverAnalyzed []string `json:"neverAnalyzed"`
+	// AnalyzedButExcluded lists files the server analyzed that the config
+	// seemingly does not include: excluded files, or scripts outside every
+	// include spec that tsgo picked up in an inferred project.
+	AnalyzedButExcluded []string `json:"analyzedButExcluded"`
+}
+
+// SourceFiles walks root and returns its TypeScript files, sorted, for a
+// project without a tsconfig.
+func SourceFiles(root string) []string {
+	var files []string
+	_ = Walk(root, WalkOptions{MaxDepth: -1, MaxVisits: projectMaxVisits}, func(p string, d fs.DirEntry, _ int) error {
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".ts", ".tsx", ".mts", ".cts":
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// Reconcile builds a Coverage report from the config's project files and
+// the set of analyzed files (absolute paths, in any order, duplicates
+// allowed). Analyzed files under node_modules and files with extensions
+// the config never compiles are not reported as excluded.
+func Reconcile(cfg *tsconfig.Config, projectFiles, analyzed []string) Coverage {
+	inProject := make(map[string]bool, len(projectFiles))
+	for _, f := range projectFiles {
+		inProject[filepath.Clean(f)] = true
+	}
+	seen := make(map[string]bool, len(analyzed))
+	for _, f := range analyzed {
+		seen[filepath.Clean(f)] = true
+	}
+
+	cov := Coverage{
+		Config:              cfg.Path,
+		ProjectFiles:        len(inProject),
+		AnalyzedFiles:       len(seen),
+		NeverAnalyzed:       []string{},
+		AnalyzedButExcluded: []string{},
+	}
+	for f := range inProject {
+		if !seen[f] {
+			cov.NeverAnalyzed = append(cov.NeverAnalyzed, f)
+		}
+	}
+	for f := range seen {
+		if inProject[f] || cfg.Includes(f) {
+			continue
+		}
+		if strings.Contains(filepath.ToSlash(f), "/node_modules/") || !cfg.SourceFile(f) {
+			continue
+		}
+		cov.AnalyzedButExcluded = append(cov.AnalyzedButExcluded, f)
+	}
+	sort.Strings(cov.NeverAnalyzed)
+	sort.Strings(cov.AnalyzedButExcluded)
+	return cov
+}
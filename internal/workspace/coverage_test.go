@@ -0,0 +1,117 @@
+package workspace
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+func TestReconcile(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"tsconfig.json":             `{"include": ["src"], "exclude": ["src/legacy"]}`,
+		"src/index.ts":              "",
+		"src/util.ts":               "",
+		"src/legacy/old.ts":         "",
+		"scripts/seed.ts":           "",
+		"node_modules/lib/index.ts": "",
+	})
+	cfg, err := tsconfig.Load(filepath.Join(root, "tsconfig.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+
+	files := ProjectFiles(cfg)
+	if want := []string{abs("src/index.ts"), abs("src/util.ts")}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("ProjectFiles = %v, want %v", files, want)
+	}
+
+	t.Run("excluded file and orphaned script", func(t *testing.T) {
+		analyzed := []string{
+			abs("src/index.ts"),
+			abs("src/index.ts"),
+			abs("src/legacy/old.ts"),
+			abs("scripts/seed.ts"),
+			abs("node_modules/lib/index.ts"),
+			abs("README.md"),
+		}
+		cov := Reconcile(cfg, files, analyzed)
+		if cov.ProjectFiles != 2 || cov.AnalyzedFiles != 5 {
+			t.Errorf("counts = %d project, %d analyzed", cov.ProjectFiles, cov.AnalyzedFiles)
+		}
+		if want := []string{abs("src/util.ts")}; !reflect.DeepEqual(cov.NeverAnalyzed, want) {
+			t.Errorf("NeverAnalyzed = %v, want %v", cov.NeverAnalyzed, want)
+		}
+		if want := []string{abs("scripts/seed.ts"), abs("src/legacy/old.ts")}; !reflect.DeepEqual(cov.AnalyzedButExcluded, want) {
+			t.Errorf("AnalyzedButExcluded = %v, want %v", cov.AnalyzedButExcluded, want)
+		}
+	})
+
+	t.Run("fully covered", func(t *testing.T) {
+		cov := Reconcile(cfg, files, files)
+		if len(cov.NeverAnalyzed) != 0 || len(cov.AnalyzedButExcluded) != 0 {
+			t.Errorf("expected no discrepancies, got %+v", cov)
+		}
+	})
+}
+
+func TestProjectFilesGlobs(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"app/tsconfig.json": `{
+			"include": ["src/**/*.ts", "../shared/**/*"],
+			"exclude": ["**/*.test.ts", "src/legacy"],
+			"files": ["scripts/seed.ts"]
+		}`,
+		"app/src/index.ts":              "",
+		"app/src/deep/er/util.ts":       "",
+		"app/src/view.tsx":              "",
+		"app/src/index.test.ts":         "",
+		"app/src/legacy/old.ts":         "",
+		"app/src/node_modules/x/a.ts":   "",
+		"app/scripts/seed.ts":           "",
+		"app/scripts/other.ts":          "",
+		"shared/types.d.ts":             "",
+		"shared/nested/helpers.ts":      "",
+		"shared/nested/helpers.test.ts": "",
+	})
+	cfg, err := tsconfig.Load(filepath.Join(root, "app", "tsconfig.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+	want := []string{
+		abs("app/scripts/seed.ts"),
+		abs("app/src/deep/er/util.ts"),
+		abs("app/src/index.ts"),
+		// The exclude globs are relative to app/ and do not reach shared/.
+		abs("shared/nested/helpers.test.ts"),
+		abs("shared/nested/helpers.ts"),
+		abs("shared/types.d.ts"),
+	}
+	if got := ProjectFiles(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("ProjectFiles = %v, want %v", got, want)
+	}
+}
+
+func TestSourceFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"src/index.ts":              "",
+		"src/view.TSX":              "",
+		"src/types.d.ts":            "",
+		"src/legacy.js":             "",
+		"dist/index.ts":             "",
+		"node_modules/lib/index.ts": "",
+		".gitignore":                "dist/\n",
+	})
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+
+	want := []string{abs("src/index.ts"), abs("src/types.d.ts"), abs("src/view.TSX")}
+	if got := SourceFiles(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("SourceFiles = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,341 @@
+package workspace
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Environment is the tooling around a project that changes how its
+// diagnostics should be read: linting, formatting, the package manager,
+// the targeted Node version and the installed TypeScript. It is gathered
+// from the file system only; no project command is run.
+type Environment struct {
+	ESLint         *ToolConfig        `json:"eslint,omitempty"`
+	Formatters     []ToolConfig       `json:"formatters,omitempty"`
+	PackageManager *PackageManager    `json:"packageManager,omitempty"`
+	Node           *NodeTarget        `json:"node,omitempty"`
+	TypeScript     *TypeScriptInstall `json:"typescript,omitempty"`
+}
+
+// ToolConfig is a configured lint or format tool.
+type ToolConfig struct {
+	Name string `json:"name"`
+	// Config is the config file relative to the workspace root, or the
+	// package.json holding the configuration.
+	Config string `json:"config"`
+	// TypeAware is set for ESLint configs that enable type-aware rules,
+	// which report type errors of their own.
+	TypeAware bool `json:"typeAware,omitempty"`
+}
+
+// PackageManager is the package manager a project uses.
+type PackageManager struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	// Source is the lockfile or "package.json" when the packageManager
+	// field names it.
+	Source string `json:"source"`
+}
+
+// NodeTarget is the Node version a project targets.
+type NodeTarget struct {
+	// Version is the content of the version file.
+	Version     string `json:"version,omitempty"`
+	VersionFile string `json:"versionFile,omitempty"`
+	// Engines is the engines.node range of package.json.
+	Engines string `json:"engines,omitempty"`
+}
+
+// TypeScriptInstall is the TypeScript package a project depends on.
+type TypeScriptInstall struct {
+	// Declared is the version range in package.json.
+	Declared string `json:"declared,omitempty"`
+	// Installed is the version under node_modules.
+	Installed string `json:"installed,omitempty"`
+}
+
+// Config file names, in the order each tool looks for them.
+var (
+	eslintConfigs = []string{
+		"eslint.config.js", "eslint.config.mjs", "eslint.config.cjs",
+		"eslint.config.ts", "eslint.config.mts", "eslint.config.cts",
+		".eslintrc.js", ".eslintrc.cjs", ".eslintrc.yaml", ".eslintrc.yml", ".eslintrc.json", ".eslintrc",
+	}
+	prettierConfigs = []string{
+		".prettierrc", ".prettierrc.json", ".prettierrc.yaml", ".prettierrc.yml", ".prettierrc.json5",
+		".prettierrc.js", ".prettierrc.cjs", ".prettierrc.mjs", ".prettierrc.toml",
+		"prettier.config.js", "prettier.config.cjs", "prettier.config.mjs",
+	}
+	biomeConfigs     = []string{"biome.json", "biome.jsonc"}
+	nodeVersionFiles = []string{".nvmrc", ".node-version"}
+	// lockfiles map to their package manager, in order of precedence when
+	// several are present.
+	lockfiles = []struct{ file, manager string }{
+		{"pnpm-lock.yaml", "pnpm"},
+		{"yarn.lock", "yarn"},
+		{"bun.lock", "bun"},
+		{"bun.lockb", "bun"},
+		{"package-lock.json", "npm"},
+		{"npm-shrinkwrap.json", "npm"},
+	}
+)
+
+// typeAwareMarkers in an ESLint config mean typed linting: parser options
+// pointing at a project, or a type-checked preset.
+var typeAwareMarkers = []string{
+	"projectService", "parserOptions.project", "project:", `"project"`,
+	"TypeChecked", "type-checked", "requiring-type-checking",
+}
+
+// manifest is the part of package.json the probes read.
+type manifest struct {
+	PackageManager  string            `json:"packageManager"`
+	ESLintConfig    json.RawMessage   `json:"eslintConfig"`
+	Prettier        json.RawMessage   `json:"prettier"`
+	Engines         map[string]string `json:"engines"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+	Version         string            `json:"version"`
+}
+
+func readManifest(dir string) *manifest {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return &m
+}
+
+// ProbeEnvironment gathers the environment of the project in dir. Each
+// setting is taken from the nearest directory that has it, from dir up to
+// root; paths are reported relative to root.
+func ProbeEnvironment(dir, root string) Environment {
+	dirs := searchDirs(dir, root)
+	rel := func(p string) string {
+		if r, err := filepath.Rel(root, p); err == nil && !strings.HasPrefix(r, "..") {
+			return filepath.ToSlash(r)
+		}
+		return p
+	}
+	return Environment{
+		ESLint:         probeESLint(dirs, rel),
+		Formatters:     probeFormatters(dirs, rel),
+		PackageManager: probePackageManager(dirs, rel),
+		Node:           probeNode(dirs, rel),
+		TypeScript:     probeTypeScript(dirs),
+	}
+}
+
+// searchDirs returns dir and its parents up to root, or just dir when it
+// is not inside root.
+func searchDirs(dir, root string) []string {
+	dir, root = filepath.Clean(dir), filepath.Clean(root)
+	dirs := []string{dir}
+	if r, err := filepath.Rel(root, dir); err != nil || strings.HasPrefix(r, "..") {
+		return dirs
+	}
+	for dir != root {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// findFile returns the first of names present in the nearest of dirs.
+func findFile(dirs, names []string) (string, bool) {
+	for _, d := range dirs {
+		for _, n := range names {
+			p := filepath.Join(d, n)
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				return p, true
+			}
+		}
+	}
+	return "", false
+}
+
+func probeESLint(dirs []string, rel func(string) string) *ToolConfig {
+	for _, d := range dirs {
+		if p, ok := findFile([]string{d}, eslintConfigs); ok {
+			data, _ := os.ReadFile(p)
+			return &ToolConfig{Name: "eslint", Config: rel(p), TypeAware: typeAware(string(data))}
+		}
+		if m := readManifest(d); m != nil && len(m.ESLintConfig) > 0 {
+			return &ToolConfig{Name: "eslint", Config: rel(filepath.Join(d, "package.json")), TypeAware: typeAware(string(m.ESLintConfig))}
+		}
+	}
+	return nil
+}
+
+func typeAware(config string) bool {
+	for _, m := range typeAwareMarkers {
+		if strings.Contains(config, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeFormatters finds Prettier and Biome configs. Both can be present,
+// e.g. Biome for linting only.
+func probeFormatters(dirs []string, rel func(string) string) []ToolConfig {
+	var out []ToolConfig
+	for _, d := range dirs {
+		if p, ok := findFile([]string{d}, prettierConfigs); ok {
+			out = append(out, ToolConfig{Name: "prettier", Config: rel(p)})
+			break
+		}
+		if m := readManifest(d); m != nil && len(m.Prettier) > 0 {
+			out = append(out, ToolConfig{Name: "prettier", Config: rel(filepath.Join(d, "package.json"))})
+			break
+		}
+	}
+	if p, ok := findFile(dirs, biomeConfigs); ok {
+		out = append(out, ToolConfig{Name: "biome", Config: rel(p)})
+	}
+	return out
+}
+
+// probePackageManager prefers the packageManager field of package.json
+// ("pnpm@9.1.0") and falls back to the lockfile.
+func probePackageManager(dirs []string, rel func(string) string) *PackageManager {
+	for _, d := range dirs {
+		if m := readManifest(d); m != nil && m.PackageManager != "" {
+			name, version, _ := strings.Cut(m.PackageManager, "@")
+			version, _, _ = strings.Cut(version, "+") // drop the "+sha..." hash
+			return &PackageManager{Name: name, Version: version, Source: rel(filepath.Join(d, "package.json"))}
+		}
+		for _, l := range lockfiles {
+			p := filepath.Join(d, l.file)
+			if _, err := os.Stat(p); err == nil {
+				return &PackageManager{Name: l.manager, Source: rel(p)}
+			}
+		}
+	}
+	return nil
+}
+
+func probeNode(dirs []string, rel func(string) string) *NodeTarget {
+	var node NodeTarget
+	if p, ok := findFile(dirs, nodeVersionFiles); ok {
+		if data, err := os.ReadFile(p); err == nil {
+			node.Version = strings.TrimSpace(string(data))
+			node.VersionFile = rel(p)
+		}
+	}
+	for _, d := range dirs {
+		if m := readManifest(d); m != nil && m.Engines["node"] != "" {
+			node.Engines = m.Engines["node"]
+			break
+		}
+	}
+	if node == (NodeTarget{}) {
+		return nil
+	}
+	return &node
+}
+
+func probeTypeScript(dirs []string) *TypeScriptInstall {
+	var ts TypeScriptInstall
+	for _, d := range dirs {
+		m := readManifest(d)
+		if m == nil {
+			continue
+		}
+		if v := m.DevDependencies["typescript"]; v != "" {
+			ts.Declared = v
+		} else if v := m.Dependencies["typescript"]; v != "" {
+			ts.Declared = v
+		}
+		if ts.Declared != "" {
+			break
+		}
+	}
+	for _, d := range dirs {
+		if m := readManifest(filepath.Join(d, "node_modules", "typescript")); m != nil && m.Version != "" {
+			ts.Installed = m.Version
+			break
+		}
+	}
+	if ts == (TypeScriptInstall{}) {
+		return nil
+	}
+	return &ts
+}
+
+// environmentFiles are every file the probes read, per directory.
+func environmentFiles() []string {
+	files := []string{"package.json", filepath.Join("node_modules", "typescript", "package.json")}
+	files = append(files, eslintConfigs...)
+	files = append(files, prettierConfigs...)
+	files = append(files, biomeConfigs...)
+	files = append(files, nodeVersionFiles...)
+	for _, l := range lockfiles {
+		files = append(files, l.file)
+	}
+	return files
+}
+
+// EnvironmentCache caches ProbeEnvironment per project directory. There is
+// no file watcher, so an entry is checked on every use against the size
+// and modification time of the files the probes read, which is much
+// cheaper than reading them again.
+type EnvironmentCache struct {
+	root string
+
+	mu      sync.Mutex
+	entries map[string]environmentEntry
+}
+
+type environmentEntry struct {
+	stamp string
+	env   Environment
+}
+
+// NewEnvironmentCache creates a cache for projects in the workspace root.
+func NewEnvironmentCache(root string) *EnvironmentCache {
+	return &EnvironmentCache{root: root, entries: make(map[string]environmentEntry)}
+}
+
+// Get returns the environment of the project in dir, probing again when
+// one of its files was added, removed or changed.
+func (c *EnvironmentCache) Get(dir string) Environment {
+	stamp := environmentStamp(searchDirs(dir, c.root))
+	c.mu.Lock()
+	e, ok := c.entries[dir]
+	c.mu.Unlock()
+	if ok && e.stamp == stamp {
+		return e.env
+	}
+	env := ProbeEnvironment(dir, c.root)
+	c.mu.Lock()
+	c.entries[dir] = environmentEntry{stamp: stamp, env: env}
+	c.mu.Unlock()
+	return env
+}
+
+// environmentStamp summarizes which environment files exist in dirs and
+// their size and modification time.
+func environmentStamp(dirs []string) string {
+	var b strings.Builder
+	for _, d := range dirs {
+		for _, f := range environmentFiles() {
+			if info, err := os.Stat(filepath.Join(d, f)); err == nil {
+				fmt.Fprintf(&b, "%s/%s:%d:%d;", d, f, info.Size(), info.ModTime().UnixNano())
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,245 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProbeESLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  *ToolConfig
+	}{
+		{name: "none", files: map[string]string{"package.json": `{}`}},
+		{
+			name: "flat config with type-checked preset",
+			files: map[string]string{
+				"eslint.config.mjs": "export default tseslint.config(...tseslint.configs.recommendedTypeChecked);",
+			},
+			want: &ToolConfig{Name: "eslint", Config: "eslint.config.mjs", TypeAware: true},
+		},
+		{
+			name: "legacy config without type information",
+			files: map[string]string{
+				".eslintrc.json": `{"extends": ["eslint:recommended"]}`,
+			},
+			want: &ToolConfig{Name: "eslint", Config: ".eslintrc.json"},
+		},
+		{
+			name: "package.json with parser project",
+			files: map[string]string{
+				"package.json": `{"eslintConfig": {"parserOptions": {"project": "./tsconfig.json"}}}`,
+			},
+			want: &ToolConfig{Name: "eslint", Config: "package.json", TypeAware: true},
+		},
+		{
+			name: "nearest config wins",
+			files: map[string]string{
+				"eslint.config.js":          "export default [];",
+				"packages/app/.eslintrc.js": "module.exports = { parserOptions: { projectService: true } };",
+			},
+			want: &ToolConfig{Name: "eslint", Config: "packages/app/.eslintrc.js", TypeAware: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			got := ProbeEnvironment(filepath.Join(root, "packages", "app"), root).ESLint
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("eslint = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeFormatters(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []ToolConfig
+	}{
+		{name: "none", files: map[string]string{"package.json": `{}`}},
+		{
+			name:  "prettierrc",
+			files: map[string]string{".prettierrc": "{}"},
+			want:  []ToolConfig{{Name: "prettier", Config: ".prettierrc"}},
+		},
+		{
+			name:  "prettier key in package.json",
+			files: map[string]string{"package.json": `{"prettier": "@acme/prettier-config"}`},
+			want:  []ToolConfig{{Name: "prettier", Config: "package.json"}},
+		},
+		{
+			name:  "biome and prettier",
+			files: map[string]string{"biome.json": "{}", "prettier.config.mjs": "export default {};"},
+			want:  []ToolConfig{{Name: "prettier", Config: "prettier.config.mjs"}, {Name: "biome", Config: "biome.json"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			got := ProbeEnvironment(root, root).Formatters
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("formatters = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbePackageManager(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  *PackageManager
+	}{
+		{name: "none", files: map[string]string{"package.json": `{}`}},
+		{
+			name: "packageManager field",
+			files: map[string]string{
+				"package.json": `{"packageManager": "yarn@4.1.0+sha224.953c8233f7a92884eee2de69a1b92d1f2ec1655e66d08071ba9a02fa"}`,
+				"yarn.lock":    "",
+			},
+			want: &PackageManager{Name: "yarn", Version: "4.1.0", Source: "package.json"},
+		},
+		{
+			name:  "pnpm lockfile",
+			files: map[string]string{"package.json": `{}`, "pnpm-lock.yaml": ""},
+			want:  &PackageManager{Name: "pnpm", Source: "pnpm-lock.yaml"},
+		},
+		{
+			name:  "npm lockfile in a parent",
+			files: map[string]string{"package-lock.json": "{}", "packages/app/package.json": `{}`},
+			want:  &PackageManager{Name: "npm", Source: "package-lock.json"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			got := ProbeEnvironment(filepath.Join(root, "packages", "app"), root).PackageManager
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("package manager = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeNode(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  *NodeTarget
+	}{
+		{name: "none", files: map[string]string{"package.json": `{}`}},
+		{
+			name:  "nvmrc",
+			files: map[string]string{".nvmrc": "v20.11.0\n"},
+			want:  &NodeTarget{Version: "v20.11.0", VersionFile: ".nvmrc"},
+		},
+		{
+			name:  "node-version and engines",
+			files: map[string]string{".node-version": "22", "package.json": `{"engines": {"node": ">=20"}}`},
+			want:  &NodeTarget{Version: "22", VersionFile: ".node-version", Engines: ">=20"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			got := ProbeEnvironment(root, root).Node
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("node = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeTypeScript(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  *TypeScriptInstall
+	}{
+		{name: "none", files: map[string]string{"package.json": `{}`}},
+		{
+			name: "declared and installed",
+			files: map[string]string{
+				"package.json":                         `{"devDependencies": {"typescript": "^5.4.0"}}`,
+				"node_modules/typescript/package.json": `{"name": "typescript", "version": "5.4.5"}`,
+			},
+			want: &TypeScriptInstall{Declared: "^5.4.0", Installed: "5.4.5"},
+		},
+		{
+			name:  "declared, not installed",
+			files: map[string]string{"package.json": `{"dependencies": {"typescript": "~5.6.2"}}`},
+			want:  &TypeScriptInstall{Declared: "~5.6.2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, tt.files)
+			got := ProbeEnvironment(root, root).TypeScript
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("typescript = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchDirs(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	got := searchDirs(filepath.FromSlash("/repo/packages/app"), root)
+	want := []string{filepath.FromSlash("/repo/packages/app"), filepath.FromSlash("/repo/packages"), root}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("searchDirs = %v, want %v", got, want)
+	}
+	if got := searchDirs(filepath.FromSlash("/elsewhere/app"), root); len(got) != 1 {
+		t.Errorf("outside the root: %v", got)
+	}
+}
+
+func TestEnvironmentCache(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"package.json": `{}`, "yarn.lock": ""})
+	c := NewEnvironmentCache(root)
+	if pm := c.Get(root).PackageManager; pm == nil || pm.Name != "yarn" {
+		t.Fatalf("package manager = %+v", pm)
+	}
+
+	// An unrelated file does not invalidate the entry.
+	writeTree(t, root, map[string]string{"src/a.ts": ""})
+	c.mu.Lock()
+	entry := c.entries[root]
+	entry.env.PackageManager.Version = "cached"
+	c.mu.Unlock()
+	if pm := c.Get(root).PackageManager; pm.Version != "cached" {
+		t.Errorf("entry was probed again: %+v", pm)
+	}
+
+	// Adding, changing or removing an environment file does.
+	writeTree(t, root, map[string]string{".prettierrc": "{}"})
+	if f := c.Get(root).Formatters; len(f) != 1 {
+		t.Errorf("formatters after adding .prettierrc = %+v", f)
+	}
+	later := time.Now().Add(time.Minute)
+	writeTree(t, root, map[string]string{"package.json": `{"packageManager": "pnpm@9.1.0"}`})
+	if err := os.Chtimes(filepath.Join(root, "package.json"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if pm := c.Get(root).PackageManager; pm.Name != "pnpm" {
+		t.Errorf("package manager after editing package.json = %+v", pm)
+	}
+	if err := os.Remove(filepath.Join(root, ".prettierrc")); err != nil {
+		t.Fatal(err)
+	}
+	if f := c.Get(root).Formatters; len(f) != 0 {
+		t.Errorf("formatters after removing .prettierrc = %+v", f)
+	}
+}
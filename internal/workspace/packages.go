@@ -0,0 +1,185 @@
+package workspace
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Package identifies the installed npm package that owns a file under
+// node_modules.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	// Linked is true when the package directory is a symlink to a
+	// directory outside every node_modules, as with workspace packages
+	// linked by npm, yarn or pnpm; its files are project source rather
+	// than a third-party install. pnpm's links into its virtual store are
+	// not linked packages.
+	Linked bool `json:"linked,omitempty"`
+	// Dir is the package root.
+	Dir string `json:"-"`
+}
+
+// String returns "name@version", or just the name when the version is
+// unknown.
+func (p *Package) String() string {
+	if p.Version == "" {
+		return p.Name
+	}
+	return p.Name + "@" + p.Version
+}
+
+// DisplayPath shortens file, which must be inside the package, to
+// "name@version/relative/path".
+func (p *Package) DisplayPath(file string) string {
+	rel, err := filepath.Rel(p.Dir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return p.String() + "/" + filepath.ToSlash(rel)
+}
+
+// PackageResolver maps node_modules paths to their owning package. Results
+// are cached per directory, so resolving many locations in the same
+// package reads its package.json once.
+type PackageResolver struct {
+	mu    sync.Mutex
+	cache map[string]*Package
+}
+
+// NewPackageResolver creates an empty resolver.
+func NewPackageResolver() *PackageResolver {
+	return &PackageResolver{cache: make(map[string]*Package)}
+}
+
+// Resolve returns the package owning file, or nil when file is not inside
+// a node_modules directory. The nearest package.json with a name below the
+// innermost node_modules wins, so nested dependencies resolve to
+// themselves and package.json files in subdirectories that only set
+// "type" are skipped. pnpm's virtual store
+// (node_modules/.pnpm/name@version/node_modules/name) supplies the version
+// when package.json lacks one.
+func (r *PackageResolver) Resolve(file string) *Package {
+	dir := filepath.Dir(file)
+	r.mu.Lock()
+	p, ok := r.cache[dir]
+	r.mu.Unlock()
+	if ok {
+		return p
+	}
+
+	p = resolvePackage(file)
+	r.mu.Lock()
+	r.cache[dir] = p
+	r.mu.Unlock()
+	return p
+}
+
+func resolvePackage(file string) *Package {
+	slash := filepath.ToSlash(file)
+	i := strings.LastIndex(slash, "/node_modules/")
+	if i < 0 {
+		return nil
+	}
+	nmDir := filepath.FromSlash(slash[:i+len("/node_modules")])
+	rest := strings.Split(slash[i+len("/node_modules/"):], "/")
+	layoutName := rest[0]
+	if strings.HasPrefix(layoutName, "@") && len(rest) > 2 {
+		layoutName += "/" + rest[1]
+	}
+	if len(rest) < 2 || layoutName == ".bin" || strings.HasPrefix(layoutName, ".") {
+		return nil
+	}
+
+	var p *Package
+	for dir := filepath.Dir(file); len(dir) > len(nmDir); dir = filepath.Dir(dir) {
+		name, version, ok := readPackageJSON(filepath.Join(dir, "package.json"))
+		if ok && name != "" {
+			p = &Package{Name: name, Version: version, Dir: dir}
+			break
+		}
+	}
+	if p == nil {
+		p = &Package{Name: layoutName, Dir: filepath.Join(nmDir, filepath.FromSlash(layoutName))}
+	}
+
+	if p.Version == "" {
+		if name, version, ok := pnpmStoreEntry(slash[:i]); ok && name == p.Name {
+			p.Version = version
+		}
+	}
+	p.Linked = linkedDir(p.Dir)
+	return p
+}
+
+// linkedDir reports whether dir is a symlink whose target lies outside
+// every node_modules directory.
+func linkedDir(dir string) bool {
+	info, err := os.Lstat(dir)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		return false
+	}
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	return !strings.Contains(filepath.ToSlash(real)+"/", "/node_modules/")
+}
+
+// readPackageJSON returns the name and version fields of a package.json.
+func readPackageJSON(path string) (name, version string, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false
+	}
+	var pkg struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", "", false
+	}
+	return pkg.Name, pkg.Version, true
+}
+
+// pnpmStoreEntry parses the package name and version from a path ending in
+// a pnpm virtual store entry (".../node_modules/.pnpm/<entry>").
+func pnpmStoreEntry(slashPath string) (name, version string, ok bool) {
+	const marker = "/node_modules/.pnpm/"
+	i := strings.LastIndex(slashPath, marker)
+	if i < 0 {
+		return "", "", false
+	}
+	entry := slashPath[i+len(marker):]
+	if strings.Contains(entry, "/") {
+		return "", "", false
+	}
+	return parsePnpmEntry(entry)
+}
+
+// parsePnpmEntry splits a pnpm store directory name such as
+// "zod@3.22.4", "@types+node@20.11.5" or
+// "react-dom@18.2.0_react@18.2.0" / "react-dom@18.2.0(react@18.2.0)" (peer
+// suffixes of older and newer pnpm) into package name and version.
+func parsePnpmEntry(entry string) (name, version string, ok bool) {
+	if j := strings.IndexByte(entry, '('); j >= 0 {
+		entry = entry[:j]
+	}
+	at := strings.IndexByte(entry[min(1, len(entry)):], '@')
+	if at < 0 {
+		return "", "", false
+	}
+	at += min(1, len(entry))
+	name = strings.ReplaceAll(entry[:at], "+", "/")
+	version = entry[at+1:]
+	if j := strings.IndexByte(version, '_'); j >= 0 {
+		version = version[:j]
+	}
+	if name == "" || version == "" {
+		return "", "", false
+	}
+	return name, version, true
+}
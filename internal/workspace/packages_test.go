@@ -0,0 +1,158 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageResolver(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"node_modules/lodash/package.json":                                                `{"name": "lodash", "version": "4.17.21"}`,
+		"node_modules/lodash/fp/map.d.ts":                                                 "",
+		"node_modules/@types/node/package.json":                                           `{"name": "@types/node", "version": "20.11.5"}`,
+		"node_modules/@types/node/fs/promises.d.ts":                                       "",
+		"node_modules/outer/package.json":                                                 `{"name": "outer", "version": "1.0.0"}`,
+		"node_modules/outer/node_modules/inner/package.json":                              `{"name": "inner", "version": "2.3.4"}`,
+		"node_modules/outer/node_modules/inner/index.d.ts":                                "",
+		"node_modules/esm-only/package.json":                                              `{"name": "esm-only", "version": "0.5.0"}`,
+		"node_modules/esm-only/dist/esm/package.json":                                     `{"type": "module"}`,
+		"node_modules/esm-only/dist/esm/index.d.ts":                                       "",
+		"node_modules/.pnpm/zod@3.22.4/node_modules/zod/package.json":                     `{"name": "zod", "version": "3.22.4"}`,
+		"node_modules/.pnpm/zod@3.22.4/node_modules/zod/lib/types.d.ts":                   "",
+		"node_modules/.pnpm/@scope+nover@1.2.3/node_modules/@scope/nover/package.json":    `{"name": "@scope/nover"}`,
+		"node_modules/.pnpm/@scope+nover@1.2.3/node_modules/@scope/nover/index.d.ts":      "",
+		"node_modules/.pnpm/nojson@0.1.0_react@18.2.0/node_modules/nojson/lib/index.d.ts": "",
+		"packages/ui/package.json":                                                        `{"name": "@acme/ui", "private": true}`,
+		"packages/ui/src/button.ts":                                                       "",
+		"src/app.ts":                                                                      "",
+	})
+	if err := os.MkdirAll(filepath.Join(root, "node_modules", "@acme"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "packages", "ui"), filepath.Join(root, "node_modules", "@acme", "ui")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	// pnpm links every direct dependency into its virtual store.
+	if err := os.Symlink(filepath.Join(".pnpm", "zod@3.22.4", "node_modules", "zod"), filepath.Join(root, "node_modules", "zod")); err != nil {
+		t.Fatal(err)
+	}
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+
+	tests := []struct {
+		name        string
+		file        string
+		want        *Package
+		displayPath string
+	}{
+		{
+			name:        "plain package",
+			file:        "node_modules/lodash/fp/map.d.ts",
+			want:        &Package{Name: "lodash", Version: "4.17.21", Dir: abs("node_modules/lodash")},
+			displayPath: "lodash@4.17.21/fp/map.d.ts",
+		},
+		{
+			name:        "scoped package",
+			file:        "node_modules/@types/node/fs/promises.d.ts",
+			want:        &Package{Name: "@types/node", Version: "20.11.5", Dir: abs("node_modules/@types/node")},
+			displayPath: "@types/node@20.11.5/fs/promises.d.ts",
+		},
+		{
+			name:        "nested dependency",
+			file:        "node_modules/outer/node_modules/inner/index.d.ts",
+			want:        &Package{Name: "inner", Version: "2.3.4", Dir: abs("node_modules/outer/node_modules/inner")},
+			displayPath: "inner@2.3.4/index.d.ts",
+		},
+		{
+			name:        "nameless package.json in subdirectory",
+			file:        "node_modules/esm-only/dist/esm/index.d.ts",
+			want:        &Package{Name: "esm-only", Version: "0.5.0", Dir: abs("node_modules/esm-only")},
+			displayPath: "esm-only@0.5.0/dist/esm/index.d.ts",
+		},
+		{
+			name:        "pnpm virtual store",
+			file:        "node_modules/.pnpm/zod@3.22.4/node_modules/zod/lib/types.d.ts",
+			want:        &Package{Name: "zod", Version: "3.22.4", Dir: abs("node_modules/.pnpm/zod@3.22.4/node_modules/zod")},
+			displayPath: "zod@3.22.4/lib/types.d.ts",
+		},
+		{
+			name:        "pnpm link into the virtual store",
+			file:        "node_modules/zod/lib/types.d.ts",
+			want:        &Package{Name: "zod", Version: "3.22.4", Dir: abs("node_modules/zod")},
+			displayPath: "zod@3.22.4/lib/types.d.ts",
+		},
+		{
+			name:        "pnpm version fallback for scoped package",
+			file:        "node_modules/.pnpm/@scope+nover@1.2.3/node_modules/@scope/nover/index.d.ts",
+			want:        &Package{Name: "@scope/nover", Version: "1.2.3", Dir: abs("node_modules/.pnpm/@scope+nover@1.2.3/node_modules/@scope/nover")},
+			displayPath: "@scope/nover@1.2.3/index.d.ts",
+		},
+		{
+			name:        "pnpm layout without package.json",
+			file:        "node_modules/.pnpm/nojson@0.1.0_react@18.2.0/node_modules/nojson/lib/index.d.ts",
+			want:        &Package{Name: "nojson", Version: "0.1.0", Dir: abs("node_modules/.pnpm/nojson@0.1.0_react@18.2.0/node_modules/nojson")},
+			displayPath: "nojson@0.1.0/lib/index.d.ts",
+		},
+		{
+			name:        "symlinked workspace package",
+			file:        "node_modules/@acme/ui/src/button.ts",
+			want:        &Package{Name: "@acme/ui", Linked: true, Dir: abs("node_modules/@acme/ui")},
+			displayPath: "@acme/ui/src/button.ts",
+		},
+		{
+			name: "project source",
+			file: "src/app.ts",
+		},
+	}
+	r := NewPackageResolver()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.Resolve(abs(tt.file))
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("Resolve = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.want {
+				t.Fatalf("Resolve = %+v, want %+v", got, tt.want)
+			}
+			if dp := got.DisplayPath(abs(tt.file)); dp != tt.displayPath {
+				t.Errorf("DisplayPath = %q, want %q", dp, tt.displayPath)
+			}
+		})
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		file := abs("node_modules/lodash/fp/map.d.ts")
+		first := r.Resolve(file)
+		if err := os.Remove(abs("node_modules/lodash/package.json")); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Resolve(file); got != first {
+			t.Errorf("second Resolve = %+v, want cached %+v", got, first)
+		}
+	})
+}
+
+func TestParsePnpmEntry(t *testing.T) {
+	tests := []struct {
+		entry, name, version string
+		ok                   bool
+	}{
+		{"zod@3.22.4", "zod", "3.22.4", true},
+		{"@types+node@20.11.5", "@types/node", "20.11.5", true},
+		{"react-dom@18.2.0_react@18.2.0", "react-dom", "18.2.0", true},
+		{"react-dom@18.2.0(react@18.2.0)", "react-dom", "18.2.0", true},
+		{"@tanstack+query@5.0.0(react@18.2.0)(typescript@5.4.0)", "@tanstack/query", "5.0.0", true},
+		{"node_modules", "", "", false},
+		{"@scope+name", "", "", false},
+	}
+	for _, tt := range tests {
+		name, version, ok := parsePnpmEntry(tt.entry)
+		if name != tt.name || version != tt.version || ok != tt.ok {
+			t.Errorf("parsePnpmEntry(%q) = %q, %q, %v, want %q, %q, %v", tt.entry, name, version, ok, tt.name, tt.version, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// probeDepth is how far below the root the probe looks for TypeScript.
+	probeDepth = 2
+	// probeMaxVisits caps the probe so pointing the server at / or a huge
+	// tree stays cheap.
+	probeMaxVisits = 5000
+	// candidateDepth is how far the candidate scan looks for tsconfig.json.
+	candidateDepth = 4
+	// maxCandidates caps the number of suggested project directories.
+	maxCandidates = 5
+)
+
+// Status describes whether the workspace root looks like a TypeScript
+// project.
+type Status struct {
+	Root string `json:"root"`
+	// HasTypeScript is true when a tsconfig.json, jsconfig.json or
+	// TypeScript source file (.ts, .tsx, .mts, .cts) was found within
+	// probeDepth levels of Root.
+	HasTypeScript bool `json:"hasTypeScript"`
+	// Candidates lists directories (relative to Root, with a trailing
+	// slash) that contain a tsconfig.json, when HasTypeScript is false.
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// Warning returns the message prefixed to tool responses when the root is
+// misconfigured, or "" when it looks fine.
+func (s Status) Warning() string {
+	if s.HasTypeScript {
+		return ""
+	}
+	msg := fmt.Sprintf("no TypeScript files found under %s", s.Root)
+	if len(s.Candidates) > 0 {
+		msg += "; nearest candidates: " + strings.Join(s.Candidates, ", ")
+	}
+	return msg
+}
+
+// ProbeRoot checks root for TypeScript content. It is bounded by
+// probeMaxVisits entries and never reads file contents.
+func ProbeRoot(root string) Status {
+	st := Status{Root: root}
+	_ = Walk(root, WalkOptions{MaxDepth: probeDepth, MaxVisits: probeMaxVisits}, func(p string, d fs.DirEntry, _ int) error {
+		if !d.IsDir() && isTypeScriptMarker(d.Name()) {
+			st.HasTypeScript = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if !st.HasTypeScript {
+		st.Candidates = findProjectDirs(root)
+	}
+	return st
+}
+
+func isTypeScriptMarker(name string) bool {
+	if name == "tsconfig.json" || name == "jsconfig.json" {
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ts", ".tsx", ".mts", ".cts":
+		return true
+	}
+	return false
+}
+
+// findProjectDirs returns the shallowest directories under root containing
+// a tsconfig.json, relative to root with a trailing slash.
+func findProjectDirs(root string) []string {
+	type found struct {
+		rel   string
+		depth int
+	}
+	var dirs []found
+	_ = Walk(root, WalkOptions{MaxDepth: candidateDepth, MaxVisits: probeMaxVisits}, func(p string, d fs.DirEntry, depth int) error {
+		if d.IsDir() || d.Name() != "tsconfig.json" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return nil
+		}
+		dirs = append(dirs, found{rel: filepath.ToSlash(rel) + "/", depth: depth})
+		return nil
+	})
+	sort.SliceStable(dirs, func(i, j int) bool {
+		if dirs[i].depth != dirs[j].depth {
+			return dirs[i].depth < dirs[j].depth
+		}
+		return dirs[i].rel < dirs[j].rel
+	})
+	var out []string
+	for _, d := range dirs {
+		if len(out) == maxCandidates {
+			break
+		}
+		out = append(out, d.rel)
+	}
+	return out
+}
+
+// Prober runs ProbeRoot once and caches the result.
+type Prober struct {
+	root   string
+	once   sync.Once
+	status Status
+}
+
+// NewProber creates a Prober for root. The probe runs on the first call to
+// Status.
+func NewProber(root string) *Prober {
+	return &Prober{root: root}
+}
+
+// Status returns the cached probe result, running the probe if needed.
+func (p *Prober) Status() Status {
+	p.once.Do(func() {
+		p.status = ProbeRoot(p.root)
+	})
+	return p.status
+}
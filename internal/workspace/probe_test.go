@@ -0,0 +1,143 @@
+package workspace
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (relative path -> content) under root.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+}
+
+func TestProbeRoot(t *testing.T) {
+	t.Run("empty root", func(t *testing.T) {
+		root := t.TempDir()
+		st := ProbeRoot(root)
+		if st.HasTypeScript {
+			t.Fatal("expected HasTypeScript=false for empty root")
+		}
+		if len(st.Candidates) != 0 {
+			t.Errorf("expected no candidates, got %v", st.Candidates)
+		}
+		if got := st.Warning(); got != "no TypeScript files found under "+root {
+			t.Errorf("Warning() = %q", got)
+		}
+	})
+
+	t.Run("nested projects", func(t *testing.T) {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{
+			"README.md":                          "# repo",
+			"tools/a/b/c/tsconfig.json":          "{}",
+			"web/frontend/app/src/tsconfig.json": "{}",
+			"web/frontend/app/tsconfig.json":     "{}",
+		})
+		st := ProbeRoot(root)
+		if st.HasTypeScript {
+			t.Fatal("expected HasTypeScript=false: all TypeScript is deeper than two levels")
+		}
+		want := []string{"web/frontend/app/", "tools/a/b/c/", "web/frontend/app/src/"}
+		if !reflect.DeepEqual(st.Candidates, want) {
+			t.Errorf("Candidates = %v, want %v", st.Candidates, want)
+		}
+		if !strings.Contains(st.Warning(), "nearest candidates: web/frontend/app/, tools/a/b/c/") {
+			t.Errorf("Warning() = %q", st.Warning())
+		}
+	})
+
+	t.Run("configured root", func(t *testing.T) {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{
+			"tsconfig.json": "{}",
+			"src/index.ts":  "export {};",
+		})
+		st := ProbeRoot(root)
+		if !st.HasTypeScript {
+			t.Fatal("expected HasTypeScript=true")
+		}
+		if st.Warning() != "" {
+			t.Errorf("expected no warning, got %q", st.Warning())
+		}
+	})
+
+	t.Run("javascript project with jsconfig", func(t *testing.T) {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{
+			"jsconfig.json": `{"compilerOptions":{"checkJs":true}}`,
+			"src/index.js":  "export {};",
+		})
+		if !ProbeRoot(root).HasTypeScript {
+			t.Fatal("expected HasTypeScript=true")
+		}
+	})
+
+	t.Run("source two levels down without tsconfig", func(t *testing.T) {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{"packages/app/main.tsx": ""})
+		if !ProbeRoot(root).HasTypeScript {
+			t.Error("expected .tsx two levels down to count")
+		}
+	})
+
+	t.Run("module-format sources count", func(t *testing.T) {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{"src/server.mts": "", "src/legacy.cts": ""})
+		if !ProbeRoot(root).HasTypeScript {
+			t.Error("expected .mts/.cts sources to count")
+		}
+	})
+
+	t.Run("node_modules and gitignored dirs do not count", func(t *testing.T) {
+		root := t.TempDir()
+		writeTree(t, root, map[string]string{
+			".gitignore":                "# build\n/dist/\n*.log\n",
+			"node_modules/lib/index.ts": "",
+			"dist/index.ts":             "",
+		})
+		if ProbeRoot(root).HasTypeScript {
+			t.Error("files under node_modules or ignored dirs must not count")
+		}
+	})
+}
+
+func TestProberCaches(t *testing.T) {
+	root := t.TempDir()
+	p := NewProber(root)
+	if p.Status().HasTypeScript {
+		t.Fatal("expected empty root")
+	}
+	writeTree(t, root, map[string]string{"index.ts": ""})
+	if p.Status().HasTypeScript {
+		t.Error("expected cached result to be reused")
+	}
+}
+
+func TestWalkMaxVisits(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a.ts": "", "b.ts": "", "c.ts": "", "d.ts": ""})
+	var seen int
+	err := Walk(root, WalkOptions{MaxDepth: -1, MaxVisits: 2}, func(string, fs.DirEntry, int) error {
+		seen++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if seen != 2 {
+		t.Errorf("visited %d entries, want 2", seen)
+	}
+}
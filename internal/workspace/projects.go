@@ -0,0 +1,148 @@
+package workspace
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/tsconfig"
+)
+
+// Project is a tsconfig found in the workspace.
+type Project struct {
+	// Config is the absolute path of the tsconfig.
+	Config string `json:"config"`
+	// Root is the directory of the tsconfig.
+	Root string `json:"root"`
+	// References are the absolute paths of the configs the project
+	// references, in the order written.
+	References []string `json:"references,omitempty"`
+	// Error is set when the config, or a config it extends, cannot be
+	// loaded. Such a project owns no files.
+	Error string `json:"error,omitempty"`
+
+	cfg *tsconfig.Config
+}
+
+// Includes reports whether the project's files/include/exclude rules
+// select file.
+func (p *Project) Includes(file string) bool {
+	return p.cfg != nil && p.cfg.Includes(file)
+}
+
+// DiscoverProjects walks root for tsconfig.json files, skipping the
+// directories Walk skips, and returns them with the configs their
+// references name, ordered so that every project comes after the projects
+// it references. Projects in a reference cycle keep path order among
+// themselves.
+func DiscoverProjects(root string) []Project {
+	var found []string
+	_ = Walk(root, WalkOptions{MaxDepth: -1, MaxVisits: projectMaxVisits}, func(p string, d fs.DirEntry, _ int) error {
+		if !d.IsDir() && d.Name() == "tsconfig.json" {
+			found = append(found, p)
+		}
+		return nil
+	})
+	sort.Strings(found)
+
+	projects := make(map[string]*Project)
+	var load func(path string)
+	load = func(path string) {
+		if projects[path] != nil {
+			return
+		}
+		p := loadProject(path)
+		projects[path] = p
+		for _, ref := range p.References {
+			if _, err := os.Stat(ref); err == nil {
+				load(ref)
+			}
+		}
+	}
+	for _, path := range found {
+		load(path)
+	}
+
+	paths := make([]string, 0, len(projects))
+	for path := range projects {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	ordered := make([]Project, 0, len(paths))
+	state := make(map[string]int) // 1 visiting, 2 done
+	var visit func(path string)
+	visit = func(path string) {
+		p := projects[path]
+		if p == nil || state[path] != 0 {
+			return
+		}
+		state[path] = 1
+		for _, ref := range p.References {
+			visit(ref)
+		}
+		state[path] = 2
+		ordered = append(ordered, *p)
+	}
+	for _, path := range paths {
+		visit(path)
+	}
+	return ordered
+}
+
+// loadProject loads the tsconfig at path with its extends chain applied.
+func loadProject(path string) *Project {
+	p := &Project{Config: path, Root: filepath.Dir(path)}
+	eff, err := tsconfig.LoadEffective(path)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	p.cfg = eff.Config()
+	for _, ref := range eff.References {
+		p.References = append(p.References, referencedConfig(p.Root, ref))
+	}
+	return p
+}
+
+// referencedConfig resolves the path of a project reference: a config
+// file, or a directory holding a tsconfig.json.
+func referencedConfig(dir, ref string) string {
+	p := filepath.FromSlash(ref)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(dir, p)
+	}
+	if strings.HasSuffix(strings.ToLower(p), ".json") {
+		return filepath.Clean(p)
+	}
+	return filepath.Join(p, "tsconfig.json")
+}
+
+// OwningProject returns the project that compiles file: of the projects
+// whose root contains it, the nearest one that includes it. When none
+// does, it returns the nearest project with included false, as the
+// server then falls back to an inferred project. It returns nil when no
+// project's root contains file.
+func OwningProject(projects []Project, file string) (owner *Project, included bool) {
+	file = filepath.Clean(file)
+	var candidates []*Project
+	for i := range projects {
+		p := &projects[i]
+		if rel, err := filepath.Rel(p.Root, file); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return len(candidates[i].Root) > len(candidates[j].Root)
+	})
+	for _, p := range candidates {
+		if p.Includes(file) {
+			return p, true
+		}
+	}
+	return candidates[0], false
+}
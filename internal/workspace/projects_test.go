@@ -0,0 +1,68 @@
+package workspace
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverProjects(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"tsconfig.json":                     `{"files": [], "references": [{"path": "./packages/app"}, {"path": "./packages/core/tsconfig.build.json"}]}`,
+		"packages/app/tsconfig.json":        `{"extends": "../../tsconfig.base.json", "include": ["src"], "references": [{"path": "../core/tsconfig.build.json"}, {"path": "../missing"}]}`,
+		"packages/app/src/main.ts":          "",
+		"packages/app/scripts/build.ts":     "",
+		"packages/core/tsconfig.build.json": `{"compilerOptions": {"composite": true}, "include": ["src"]}`,
+		"packages/core/tsconfig.json":       `{"include": ["src", "test"]}`,
+		"packages/core/src/index.ts":        "",
+		"packages/broken/tsconfig.json":     `{"include": [`,
+		"tsconfig.base.json":                `{"compilerOptions": {"strict": true}}`,
+		"node_modules/lib/tsconfig.json":    `{}`,
+	})
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+
+	projects := DiscoverProjects(root)
+	var configs []string
+	for _, p := range projects {
+		configs = append(configs, p.Config)
+	}
+	want := []string{
+		abs("packages/core/tsconfig.build.json"),
+		abs("packages/app/tsconfig.json"),
+		abs("packages/broken/tsconfig.json"),
+		abs("packages/core/tsconfig.json"),
+		abs("tsconfig.json"),
+	}
+	if !reflect.DeepEqual(configs, want) {
+		t.Fatalf("projects = %v, want %v", configs, want)
+	}
+	app := projects[1]
+	if app.Root != abs("packages/app") || !reflect.DeepEqual(app.References, []string{abs("packages/core/tsconfig.build.json"), abs("packages/missing/tsconfig.json")}) {
+		t.Errorf("app = %+v", app)
+	}
+	if broken := projects[2]; broken.Error == "" || broken.Includes(abs("packages/broken/a.ts")) {
+		t.Errorf("broken = %+v, want an error and no files", broken)
+	}
+
+	tests := []struct {
+		file     string
+		owner    string
+		included bool
+	}{
+		{"packages/app/src/main.ts", "packages/app/tsconfig.json", true},
+		{"packages/app/scripts/build.ts", "packages/app/tsconfig.json", false},
+		{"packages/core/src/index.ts", "packages/core/tsconfig.build.json", true},
+		{"packages/core/test/index.test.ts", "packages/core/tsconfig.json", true},
+		{"tools/gen.ts", "tsconfig.json", false},
+	}
+	for _, tt := range tests {
+		p, included := OwningProject(projects, abs(tt.file))
+		if p == nil || p.Config != abs(tt.owner) || included != tt.included {
+			t.Errorf("OwningProject(%s) = %+v, %v; want %s, %v", tt.file, p, included, tt.owner, tt.included)
+		}
+	}
+	if p, _ := OwningProject(projects, filepath.Join(t.TempDir(), "a.ts")); p != nil {
+		t.Errorf("file outside the workspace owned by %s", p.Config)
+	}
+}
@@ -0,0 +1,108 @@
+// Package workspace inspects the project tree on disk independently of the
+// language server.
+package workspace
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultSkipDirs are directory names never descended into.
+var defaultSkipDirs = map[string]bool{
+	"node_modules":    true,
+	".git":            true,
+	".hg":             true,
+	".svn":            true,
+	".typescript-mcp": true,
+}
+
+// WalkOptions bounds a directory walk.
+type WalkOptions struct {
+	// MaxDepth limits descent below the root; 0 visits only the root's
+	// direct entries. A negative value means unlimited.
+	MaxDepth int
+	// MaxVisits stops the walk after this many entries; 0 means unlimited.
+	MaxVisits int
+}
+
+// errStopWalk ends a walk early without reporting an error.
+var errStopWalk = errors.New("stop walk")
+
+// Walk visits entries under root in lexical order, skipping dependency and
+// VCS directories plus the plain directory names listed in root's
+// .gitignore. fn receives each entry's absolute path and its depth (0 for
+// the root's direct children). Returning fs.SkipDir from fn on a directory
+// skips it; returning fs.SkipAll stops the walk.
+func Walk(root string, opts WalkOptions, fn func(path string, d fs.DirEntry, depth int) error) error {
+	skip := ignoredDirs(root)
+	visits := 0
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			// Unreadable subtrees are skipped, not fatal.
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if p == root {
+			return nil
+		}
+		if opts.MaxVisits > 0 && visits >= opts.MaxVisits {
+			return errStopWalk
+		}
+		visits++
+
+		rel, _ := filepath.Rel(root, p)
+		depth := strings.Count(filepath.ToSlash(rel), "/")
+		if d.IsDir() {
+			if defaultSkipDirs[d.Name()] || skip[d.Name()] {
+				return fs.SkipDir
+			}
+			if opts.MaxDepth >= 0 && depth >= opts.MaxDepth {
+				if err := fn(p, d, depth); err != nil {
+					return err
+				}
+				return fs.SkipDir
+			}
+		}
+		return fn(p, d, depth)
+	})
+	if errors.Is(err, errStopWalk) || errors.Is(err, fs.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+// ignoredDirs returns the plain directory names listed in root/.gitignore.
+// Glob patterns and negations are not interpreted; this is a cheap filter
+// for the common build-output entries (dist/, out/, coverage/).
+func ignoredDirs(root string) map[string]bool {
+	skip := make(map[string]bool)
+	f, err := os.Open(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		return skip
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "/")
+		line = strings.TrimSuffix(line, "/")
+		if line == "" || strings.ContainsAny(line, "*?[/") {
+			continue
+		}
+		skip[line] = true
+	}
+	return skip
+}
@@ -1,390 +1,1489 @@
 package test
 
 import (
-	"context"
-	"encoding/json"
+	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
-	"time"
 
-	"go.lsp.dev/protocol"
-
-	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
-	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
-	"github.com/paulvanbrenk/typescript-mcp/internal/tools"
-)
-
-var (
-	sharedClient *lsp.Client
-	sharedDocs   *docsync.Manager
-	fixtureDir   string
+	"github.com/paulvanbrenk/typescript-mcp/typescriptmcptest"
 )
 
-// testdataDir returns the absolute path to testdata/simple.
-func testdataDir() string {
+// testdataDir returns the absolute path to testdata/<name>.
+func testdataDir(name string) string {
 	_, file, _, ok := runtime.Caller(0)
 	if !ok {
 		panic("cannot determine test file path")
 	}
-	return filepath.Join(filepath.Dir(file), "..", "testdata", "simple")
+	return filepath.Join(filepath.Dir(file), "..", "testdata", name)
 }
 
-func TestMain(m *testing.M) {
-	fixtureDir = testdataDir()
+// simpleFiles loads testdata/simple as fixture files, so tests that edit
+// the project never mutate the checked-in copy.
+func simpleFiles(t *testing.T) map[string]string {
+	t.Helper()
+	return testdataFiles(t, "simple")
+}
 
-	// Skip integration tests if tsgo is not available.
-	if _, err := exec.LookPath("tsgo"); err != nil {
-		// Run tests anyway -- individual tests will skip
-		os.Exit(m.Run())
+// testdataFiles loads testdata/<name> as fixture files.
+func testdataFiles(t *testing.T, name string) map[string]string {
+	t.Helper()
+	root := testdataDir(name)
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("loading testdata: %v", err)
 	}
+	return files
+}
 
-	// Start a single shared client for all integration tests.
-	rootURI := docsync.FileToURI(fixtureDir)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// TestNavigation shares one server across the read-only tools.
+func TestNavigation(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
+	c := srv.Client
 
-	var err error
-	sharedClient, err = lsp.NewClient(ctx, rootURI)
-	if err != nil {
-		// Cannot start client; let tests skip gracefully.
-		os.Exit(m.Run())
-	}
+	indexFile := fx.Path("src/index.ts")
+	consumerFile := fx.Path("src/consumer.ts")
 
-	sharedDocs = docsync.NewManager()
+	t.Run("diagnostics", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"file": fx.Path("src/errors.ts")})
 
-	// Sync all fixture files up front.
-	files := []string{
-		filepath.Join(fixtureDir, "src", "index.ts"),
-		filepath.Join(fixtureDir, "src", "errors.ts"),
-		filepath.Join(fixtureDir, "src", "consumer.ts"),
-	}
-	for _, f := range files {
-		if err := sharedDocs.SyncFile(ctx, sharedClient.Conn(), f); err != nil {
-			panic("SyncFile: " + err.Error())
+		if res.Source != "pull" && res.Source != "push" {
+			t.Errorf("source = %q", res.Source)
+		}
+		if res.AnalysisIncomplete {
+			t.Error("analysis of errors.ts incomplete")
+		}
+		if len(res.Diagnostics) < 2 {
+			t.Errorf("expected at least 2 diagnostics in errors.ts, got %d", len(res.Diagnostics))
+			for i, d := range res.Diagnostics {
+				t.Logf("  diag[%d]: %s (line %d)", i, d.Message, d.Line)
+			}
 		}
-	}
 
-	// Give the server time to process all files.
-	time.Sleep(1 * time.Second)
+		// Verify at least one diagnostic mentions a type error.
+		hasTypeError := false
+		for _, d := range res.Diagnostics {
+			msg := strings.ToLower(d.Message)
+			if strings.Contains(msg, "type") && strings.Contains(msg, "not assignable") {
+				hasTypeError = true
+				break
+			}
+		}
+		if !hasTypeError && len(res.Diagnostics) > 0 {
+			t.Log("warning: no 'not assignable' type error found in diagnostics")
+			for i, d := range res.Diagnostics {
+				t.Logf("  diag[%d]: %s", i, d.Message)
+			}
+		}
+	})
 
-	code := m.Run()
+	t.Run("diagnostics filtered by code", func(t *testing.T) {
+		file := fx.Path("src/errors.ts")
+		all := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"file": file})
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"file": file, "codes": []int{2322}, "severity": "error"})
+		if res.TotalCount < 2 {
+			t.Errorf("expected the 2 TS2322 errors, got %d", res.TotalCount)
+		}
+		for _, d := range res.Diagnostics {
+			if code, _ := d.Code.(float64); code != 2322 || d.Severity != "error" {
+				t.Errorf("filter kept %s TS%v: %s", d.Severity, d.Code, d.Message)
+			}
+		}
+		if res.TotalCount+res.FilteredOut != all.TotalCount {
+			t.Errorf("%d kept + %d filtered out, want %d in all", res.TotalCount, res.FilteredOut, all.TotalCount)
+		}
+	})
 
-	_ = sharedClient.Close()
-	os.Exit(code)
-}
+	t.Run("diagnostics with context", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"file": fx.Path("src/errors.ts"), "contextLines": 1})
+		for _, d := range res.Diagnostics {
+			marked := fmt.Sprintf("> %d | ", d.Line)
+			if !strings.Contains(d.Context, marked) {
+				t.Errorf("context of line %d lacks %q:\n%s", d.Line, marked, d.Context)
+			}
+		}
+		if len(res.Diagnostics) == 0 {
+			t.Error("expected diagnostics in errors.ts")
+		}
+	})
 
-// requireClient skips the test if the shared client is not available.
-func requireClient(t *testing.T) {
-	t.Helper()
-	if sharedClient == nil {
-		t.Skip("requires tsgo in PATH; install with: npm install -g @typescript/native-preview")
-	}
-}
+	t.Run("diagnostics grouped by code", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.GroupedDiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"files": []string{fx.Path("src/errors.ts"), consumerFile}, "groupBy": "code"})
+		if len(res.Groups) == 0 || res.Groups[0].Code != "TS2322" || res.Groups[0].Count < 2 {
+			t.Fatalf("groups = %+v", res.Groups)
+		}
+		counted := 0
+		for _, g := range res.Groups {
+			counted += g.Count
+			if len(g.Examples) == 0 || len(g.Examples) > 3 {
+				t.Errorf("%s has %d examples", g.Code, len(g.Examples))
+			}
+		}
+		if counted != res.TotalCount || len(res.Failed) != 0 {
+			t.Errorf("groups count %d of %d, failed %v", counted, res.TotalCount, res.Failed)
+		}
+	})
 
-func TestDiagnostics(t *testing.T) {
-	requireClient(t)
-	errorsFile := filepath.Join(fixtureDir, "src", "errors.ts")
+	t.Run("diagnostics of several files", func(t *testing.T) {
+		errorsFile := fx.Path("src/errors.ts")
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.MultiDiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"files": []string{errorsFile, consumerFile}, "maxResults": 1})
+		if len(res.Files) != 2 {
+			t.Fatalf("files = %v", res.Files)
+		}
+		errs := res.Files[errorsFile]
+		if errs.TotalCount < 2 || len(errs.Diagnostics) != 1 || !errs.Truncated {
+			t.Errorf("errors.ts = %+v", errs)
+		}
+		if consumer := res.Files[consumerFile]; consumer.TotalCount != 0 || consumer.Error != "" {
+			t.Errorf("consumer.ts = %+v", consumer)
+		}
+		if res.TotalCount != errs.TotalCount || res.Errors < 2 {
+			t.Errorf("totals = %d diagnostics, %d errors", res.TotalCount, res.Errors)
+		}
+	})
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	t.Run("diagnostics after project load", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"file": consumerFile, "waitForProjectLoad": true})
+		if res.ProjectStillLoading {
+			t.Error("project still loading after the wait")
+		}
+		if !res.InProgram {
+			t.Error("consumer.ts not in the program after the wait")
+		}
+	})
 
-	diags, err := sharedClient.Diagnostic(ctx, errorsFile)
-	if err != nil {
-		t.Fatalf("Diagnostic: %v", err)
-	}
+	t.Run("project diagnostics", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ProjectDiagnosticsResult](t, c, "ts_project_diagnostics",
+			map[string]any{"severity": "error"})
+		if res.TimedOut || res.FilesChecked != res.FilesTotal || res.FilesTotal == 0 {
+			t.Fatalf("checked %d of %d files (timed out: %v)", res.FilesChecked, res.FilesTotal, res.TimedOut)
+		}
+		var errorsFile *typescriptmcptest.FileDiagnostics
+		for i, f := range res.Files {
+			if f.File == fx.Path("src/errors.ts") {
+				errorsFile = &res.Files[i]
+			}
+			if f.File == consumerFile {
+				t.Errorf("consumer.ts listed with %+v", f.Diagnostics)
+			}
+		}
+		if errorsFile == nil || errorsFile.Errors < 2 || res.Errors < errorsFile.Errors {
+			t.Errorf("errors.ts = %+v, total errors %d", errorsFile, res.Errors)
+		}
+	})
 
-	if len(diags) < 2 {
-		t.Errorf("expected at least 2 diagnostics in errors.ts, got %d", len(diags))
-		for i, d := range diags {
-			t.Logf("  diag[%d]: %s (line %d)", i, d.Message, d.Range.Start.Line+1)
+	t.Run("definition", func(t *testing.T) {
+		// "greet" is used on line 3, column 16 of consumer.ts: `const result = greet("world");`
+		locs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, c, "ts_definition",
+			map[string]any{"file": consumerFile, "line": 3, "column": 16})
+
+		if len(locs) == 0 {
+			t.Fatal("expected at least one definition location")
 		}
-	}
+		if !strings.HasSuffix(locs[0].File, "index.ts") {
+			t.Errorf("expected definition in index.ts, got %s", locs[0].File)
+		}
+	})
 
-	// Verify at least one diagnostic mentions a type error.
-	hasTypeError := false
-	for _, d := range diags {
-		msg := strings.ToLower(d.Message)
-		if strings.Contains(msg, "type") && strings.Contains(msg, "not assignable") {
-			hasTypeError = true
-			break
+	t.Run("definition with body", func(t *testing.T) {
+		locs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, c, "ts_definition",
+			map[string]any{"file": consumerFile, "line": 3, "column": 16, "includeBody": true})
+		if len(locs) == 0 {
+			t.Fatal("expected at least one definition location")
 		}
-	}
-	if !hasTypeError && len(diags) > 0 {
-		t.Log("warning: no 'not assignable' type error found in diagnostics")
-		for i, d := range diags {
-			t.Logf("  diag[%d]: %s", i, d.Message)
+		d := locs[0]
+		if !strings.Contains(d.Body, "return `Hello, ${name}!`;") || d.BodyStartLine != 1 || d.BodyEndLine != 3 {
+			t.Errorf("body of greet = %q, lines %d-%d", d.Body, d.BodyStartLine, d.BodyEndLine)
 		}
-	}
+	})
+
+	t.Run("type definition", func(t *testing.T) {
+		// "unit" is on line 19, column 14 of shapes.ts: `export const unit: Shape = new Circle(1);`
+		locs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, c, "ts_type_definition",
+			map[string]any{"file": fx.Path("src/shapes.ts"), "line": 19, "column": 14})
+
+		if len(locs) != 1 || !strings.HasSuffix(locs[0].File, "shapes.ts") || locs[0].Line != 1 {
+			t.Errorf("type definition = %+v, want the Shape interface on line 1", locs)
+		}
+	})
+
+	t.Run("implementations", func(t *testing.T) {
+		// "area" is on line 2, column 3 of shapes.ts: `  area(): number;`
+		locs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, c, "ts_implementations",
+			map[string]any{"file": fx.Path("src/shapes.ts"), "line": 2, "column": 3})
+
+		var lines []int
+		for _, loc := range locs {
+			if strings.HasSuffix(loc.File, "shapes.ts") {
+				lines = append(lines, loc.Line)
+			}
+		}
+		if len(lines) != 2 || !slices.Contains(lines, 7) || !slices.Contains(lines, 14) {
+			t.Errorf("implementations = %+v, want Circle.area (line 7) and Square.area (line 14)", locs)
+		}
+	})
+
+	t.Run("no implementations", func(t *testing.T) {
+		// Line 1, column 1 of consumer.ts is the import keyword.
+		content := typescriptmcptest.MustCallToolText(t, c, "ts_implementations",
+			map[string]any{"file": consumerFile, "line": 1, "column": 1})
+		if content != "No implementations found" {
+			t.Errorf("content = %q", content)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		// "greet" is on line 1, column 17 of index.ts: `export function greet(name: string): string {`
+		content := typescriptmcptest.MustCallToolText(t, c, "ts_hover",
+			map[string]any{"file": indexFile, "line": 1, "column": 17})
+
+		if content == "" {
+			t.Fatal("expected non-empty hover content")
+		}
+		// The hover should contain the function signature.
+		if !strings.Contains(content, "greet") {
+			t.Errorf("hover content should mention 'greet', got: %s", content)
+		}
+		if !strings.Contains(content, "string") {
+			t.Errorf("hover content should mention 'string', got: %s", content)
+		}
+	})
+
+	t.Run("full hover", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.FullHover](t, c, "ts_hover",
+			map[string]any{"file": indexFile, "line": 1, "column": 17, "format": "full"})
+		if !strings.Contains(res.Signature, "greet") || strings.Contains(res.Signature, "```") {
+			t.Errorf("signature = %q", res.Signature)
+		}
+		if res.Tags == nil {
+			t.Error("tags missing")
+		}
+	})
+
+	t.Run("hover many", func(t *testing.T) {
+		// Five positions across both files, four times over: 20 hovers in
+		// flight together on the one connection.
+		spots := []struct {
+			file      string
+			line, col int
+			want      string
+		}{
+			{indexFile, 1, 17, "greet"},
+			{indexFile, 5, 17, "add"},
+			{consumerFile, 1, 10, "greet"},
+			{consumerFile, 3, 7, "result"},
+			{consumerFile, 4, 7, "sum"},
+		}
+		var positions []any
+		for range 4 {
+			for _, s := range spots {
+				positions = append(positions, map[string]any{"file": s.file, "line": s.line, "column": s.col})
+			}
+		}
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.HoverManyResult](t, c, "ts_hover_many",
+			map[string]any{"positions": positions})
+		if len(res.Results) != len(positions) {
+			t.Fatalf("got %d results, want %d", len(res.Results), len(positions))
+		}
+		for i, r := range res.Results {
+			s := spots[i%len(spots)]
+			if r.File != s.file || r.Line != s.line || r.Column != s.col {
+				t.Errorf("result %d is for %s:%d:%d, want %s:%d:%d", i, r.File, r.Line, r.Column, s.file, s.line, s.col)
+				continue
+			}
+			if r.Error != "" || !strings.Contains(r.Type, s.want) {
+				t.Errorf("result %d = %+v, want a type mentioning %q", i, r, s.want)
+			}
+		}
+	})
+
+	t.Run("references", func(t *testing.T) {
+		// "greet" definition on line 1, column 17 of index.ts.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ReferencesResult](t, c, "ts_references",
+			map[string]any{"file": indexFile, "line": 1, "column": 17})
+
+		if len(res.References) < 2 {
+			t.Errorf("expected at least 2 references to greet (definition + usage), got %d", len(res.References))
+			for i, loc := range res.References {
+				t.Logf("  ref[%d]: %s:%d", i, loc.File, loc.Line)
+			}
+			return
+		}
+
+		// Check that at least one reference is in consumer.ts.
+		hasConsumerRef := false
+		for _, loc := range res.References {
+			if strings.HasSuffix(loc.File, "consumer.ts") {
+				hasConsumerRef = true
+				break
+			}
+		}
+		if !hasConsumerRef {
+			t.Error("expected at least one reference in consumer.ts")
+			for i, loc := range res.References {
+				t.Logf("  ref[%d]: %s:%d", i, loc.File, loc.Line)
+			}
+		}
+	})
+
+	t.Run("references filtered by path", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ReferencesResult](t, c, "ts_references",
+			map[string]any{"file": indexFile, "line": 1, "column": 17, "exclude": []string{"src/consumer.ts"}})
+		if res.TotalCount == 0 || res.TotalCount >= res.UnfilteredCount {
+			t.Errorf("%d of %d references kept", res.TotalCount, res.UnfilteredCount)
+		}
+		for _, loc := range res.References {
+			if loc.File == consumerFile {
+				t.Errorf("excluded reference listed: %s:%d", loc.File, loc.Line)
+			}
+		}
+	})
+
+	t.Run("references by file", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.GroupedReferencesResult](t, c, "ts_references",
+			map[string]any{"file": indexFile, "line": 1, "column": 17, "groupByFile": true, "previewsPerFile": 1})
+		counted := 0
+		for i, f := range res.Files {
+			counted += f.Count
+			if len(f.References) != 1 || f.References[0].File != f.File {
+				t.Errorf("%s references = %+v", f.File, f.References)
+			}
+			if i > 0 && f.Count > res.Files[i-1].Count {
+				t.Errorf("%s (%d) after %s (%d)", f.File, f.Count, res.Files[i-1].File, res.Files[i-1].Count)
+			}
+		}
+		if res.FileCount < 2 || len(res.Files) != res.FileCount || counted != res.TotalCount {
+			t.Errorf("%d files listed of %d, %d references of %d", len(res.Files), res.FileCount, counted, res.TotalCount)
+		}
+	})
+
+	t.Run("references without the declaration", func(t *testing.T) {
+		all := typescriptmcptest.MustCallTool[typescriptmcptest.ReferencesResult](t, c, "ts_references",
+			map[string]any{"file": indexFile, "line": 1, "column": 17})
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ReferencesResult](t, c, "ts_references",
+			map[string]any{"file": indexFile, "line": 1, "column": 17, "includeDeclaration": false, "excludeDeclarationFiles": true})
+		if res.TotalCount != len(res.References) || res.TotalCount >= all.TotalCount {
+			t.Errorf("%d of %d references kept, %d listed", res.TotalCount, all.TotalCount, len(res.References))
+		}
+		for _, loc := range res.References {
+			if loc.File == indexFile && loc.Line == 1 {
+				t.Errorf("declaration listed: %s:%d:%d", loc.File, loc.Line, loc.Column)
+			}
+		}
+	})
+
+	t.Run("signature help", func(t *testing.T) {
+		// Line 4 of consumer.ts is `const sum = add(1, 2);`; column 20 is the
+		// second argument.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.SignatureHelpResult](t, c, "ts_signature_help",
+			map[string]any{"file": consumerFile, "line": 4, "column": 20})
+
+		if len(res.Signatures) == 0 {
+			t.Fatal("expected a signature for add")
+		}
+		sig := res.Signatures[res.ActiveSignature]
+		if !strings.Contains(sig.Label, "add(") || len(sig.Parameters) != 2 {
+			t.Errorf("signature = %+v", sig)
+		}
+		if res.ActiveParameter != 1 {
+			t.Errorf("activeParameter = %d, want 1 (b)", res.ActiveParameter)
+		}
+	})
+
+	t.Run("no signature help", func(t *testing.T) {
+		content := typescriptmcptest.MustCallToolText(t, c, "ts_signature_help",
+			map[string]any{"file": consumerFile, "line": 1, "column": 1})
+		if content != "No signature help available" {
+			t.Errorf("content = %q", content)
+		}
+	})
+
+	t.Run("prepare rename", func(t *testing.T) {
+		// Line 3 of consumer.ts is `const result = greet("world");`.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.PrepareRenameResult](t, c, "ts_prepare_rename",
+			map[string]any{"file": consumerFile, "line": 3, "column": 9})
+		if !res.CanRename || res.Text != "result" || res.Line != 3 || res.Column != 7 || res.EndColumn != 13 {
+			t.Errorf("result = %+v, want result at 3:7-3:13", res)
+		}
+
+		keyword := typescriptmcptest.MustCallTool[typescriptmcptest.PrepareRenameResult](t, c, "ts_prepare_rename",
+			map[string]any{"file": consumerFile, "line": 3, "column": 1})
+		if keyword.CanRename || keyword.Reason == "" {
+			t.Errorf("const = %+v, want a refusal with a reason", keyword)
+		}
+
+		// ts_rename refuses the keyword before asking for any edits.
+		rename := typescriptmcptest.CallTool(t, c, "ts_rename",
+			map[string]any{"file": consumerFile, "line": 3, "column": 1, "newName": "let"})
+		if text := typescriptmcptest.Summary(rename); !rename.IsError || !strings.Contains(text, "cannot rename here: "+keyword.Reason) {
+			t.Errorf("rename of const = %+v, want a cannot rename here error", rename.Content)
+		}
+	})
+
+	t.Run("selection range", func(t *testing.T) {
+		// greet is on line 3, column 16 of consumer.ts and add on line 4,
+		// column 13.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.SelectionRangeResult](t, c, "ts_selection_range",
+			map[string]any{"file": consumerFile, "positions": []map[string]any{{"line": 3, "column": 16}, {"line": 4, "column": 13}}})
+		if len(res.Selections) != 2 {
+			t.Fatalf("selections = %+v, want one per position", res.Selections)
+		}
+
+		ranges := res.Selections[0].Ranges
+		if len(ranges) < 3 || ranges[0].Preview != "greet" || ranges[0].StartLine != 3 || ranges[0].StartColumn != 16 {
+			t.Fatalf("ranges of greet = %+v, want greet innermost", ranges)
+		}
+		var previews []string
+		for _, r := range ranges {
+			previews = append(previews, r.Preview)
+		}
+		if !slices.Contains(previews, `greet("world")`) {
+			t.Errorf("previews = %q, want the call among them", previews)
+		}
+		if last := ranges[len(ranges)-1]; last.StartLine != 1 || last.EndLine < 5 {
+			t.Errorf("outermost range = %+v, want the whole file", last)
+		}
+		if add := res.Selections[1].Ranges; len(add) == 0 || add[0].Preview != "add" {
+			t.Errorf("ranges of add = %+v", add)
+		}
+	})
+
+	t.Run("document highlights", func(t *testing.T) {
+		// "result" is declared on line 3, column 7 of consumer.ts and read
+		// on line 5, column 13: `console.log(result, sum);`
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.DocumentHighlightsResult](t, c, "ts_document_highlights",
+			map[string]any{"file": consumerFile, "line": 5, "column": 13})
+
+		var got []string
+		for _, h := range res.Highlights {
+			got = append(got, fmt.Sprintf("%d:%d %s", h.Line, h.Column, h.Kind))
+		}
+		if !slices.Contains(got, "3:7 write") || !slices.Contains(got, "5:13 read") {
+			t.Errorf("highlights = %+v, want the declaration of result as a write and its use as a read", res.Highlights)
+		}
+	})
+
+	t.Run("inlay hints", func(t *testing.T) {
+		// Line 3 of consumer.ts is `const result = greet("world");`: result
+		// is inferred as string and "world" is greet's name parameter.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.InlayHintsResult](t, c, "ts_inlay_hints",
+			map[string]any{"file": consumerFile, "startLine": 3, "endLine": 3})
+
+		var kinds []string
+		for _, h := range res.Hints {
+			if h.Line != 3 {
+				t.Errorf("hint %+v is outside line 3", h)
+			}
+			kinds = append(kinds, h.Kind+" "+h.Label)
+		}
+		if !slices.Contains(kinds, "type : string") || !slices.Contains(kinds, "parameter name:") {
+			t.Errorf("hints = %+v, want the string type of result and the name parameter", res.Hints)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		// Line 5 of consumer.ts is `console.log(result, sum);`; column 9 is
+		// just after "console.".
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.CompletionResult](t, c, "ts_completion",
+			map[string]any{"file": consumerFile, "line": 5, "column": 9, "maxResults": 200, "resolveDocs": 1})
+
+		var log *typescriptmcptest.Completion
+		for i := range res.Completions {
+			if res.Completions[i].Label == "log" {
+				log = &res.Completions[i]
+			}
+		}
+		if log == nil {
+			t.Fatalf("expected a log completion, got %+v", res.Completions)
+		}
+		if log.Kind != "method" || log.InsertText == "" {
+			t.Errorf("log completion = %+v", *log)
+		}
+		if res.TotalCount < len(res.Completions) {
+			t.Errorf("totalCount %d below the %d completions returned", res.TotalCount, len(res.Completions))
+		}
+	})
+
+	t.Run("workspace symbols", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.WorkspaceSymbolsResult](t, c, "ts_workspace_symbols",
+			map[string]any{"query": "Runner", "file": consumerFile})
+
+		var found *typescriptmcptest.WorkspaceSymbol
+		for i, s := range res.Symbols {
+			if s.Name == "Runner" {
+				found = &res.Symbols[i]
+				break
+			}
+		}
+		if found == nil {
+			t.Fatalf("expected Runner among %+v", res.Symbols)
+		}
+		if !strings.HasSuffix(found.File, "visibility.ts") || found.Kind != "class" || found.Line != 9 {
+			t.Errorf("Runner = %+v, want the class on line 9 of visibility.ts", *found)
+		}
+	})
+
+	t.Run("code actions", func(t *testing.T) {
+		// "add" is used without an import on line 2, column 22 of fixes.ts.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.CodeActionsResult](t, c, "ts_code_actions",
+			map[string]any{"file": fx.Path("src/fixes.ts"), "startLine": 2, "startColumn": 22, "endColumn": 25, "kind": "quickfix"})
+
+		if res.Diagnostics == 0 {
+			t.Error("expected the missing name diagnostic in the range")
+		}
+		found := false
+		for _, a := range res.Actions {
+			if strings.Contains(a.Title, "import") && a.HasEdit {
+				found = true
+			}
+			if !strings.HasPrefix(a.Kind, "quickfix") {
+				t.Errorf("action %+v is not a quick fix", a)
+			}
+		}
+		if !found {
+			t.Errorf("expected an import fix among %+v", res.Actions)
+		}
+	})
+
+	t.Run("semantic tokens", func(t *testing.T) {
+		// Line 5 of shapes.ts is `export class Circle implements Shape {`.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.SemanticTokensResult](t, c, "ts_semantic_tokens",
+			map[string]any{"file": fx.Path("src/shapes.ts"), "startLine": 5, "endLine": 5})
+
+		got := map[string]typescriptmcptest.SemanticToken{}
+		for _, tok := range res.Tokens {
+			if tok.Line != 5 {
+				t.Errorf("token %+v is outside line 5", tok)
+			}
+			got[tok.Text] = tok
+		}
+		if circle := got["Circle"]; circle.Type != "class" || circle.Column != 14 || !slices.Contains(circle.Modifiers, "declaration") {
+			t.Errorf("Circle = %+v, want a class declaration at column 14", circle)
+		}
+		if shape := got["Shape"]; shape.Type != "interface" || shape.Column != 32 {
+			t.Errorf("Shape = %+v, want an interface at column 32", shape)
+		}
+	})
+
+	t.Run("type hierarchy", func(t *testing.T) {
+		shapes := fx.Path("src/shapes.ts")
+		// Shape is declared on line 1, column 18 of shapes.ts and
+		// implemented by Circle and Square.
+		subtypes := typescriptmcptest.MustCallTool[typescriptmcptest.TypeHierarchyResult](t, c, "ts_type_hierarchy",
+			map[string]any{"file": shapes, "line": 1, "column": 18, "direction": "subtypes"})
+		if len(subtypes.Roots) != 1 || subtypes.Roots[0].Name != "Shape" {
+			t.Fatalf("roots = %+v, want Shape", subtypes.Roots)
+		}
+		var names []string
+		for _, n := range subtypes.Roots[0].Types {
+			names = append(names, n.Name)
+		}
+		if !slices.Contains(names, "Circle") || !slices.Contains(names, "Square") {
+			t.Errorf("subtypes of Shape = %+v, want Circle and Square", subtypes.Roots[0].Types)
+		}
+
+		// Circle is declared on line 5, column 14.
+		supertypes := typescriptmcptest.MustCallTool[typescriptmcptest.TypeHierarchyResult](t, c, "ts_type_hierarchy",
+			map[string]any{"file": shapes, "line": 5, "column": 14})
+		if len(supertypes.Roots) != 1 || len(supertypes.Roots[0].Types) != 1 {
+			t.Fatalf("supertypes of Circle = %+v", supertypes.Roots)
+		}
+		if shape := supertypes.Roots[0].Types[0]; shape.Name != "Shape" || shape.Kind != "interface" || shape.Line != 1 {
+			t.Errorf("supertype = %+v, want the Shape interface on line 1", shape)
+		}
+	})
+
+	t.Run("folding ranges", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.FoldingRangesResult](t, c, "ts_folding_ranges",
+			map[string]any{"file": fx.Path("src/shapes.ts"), "kind": "code"})
+
+		// Circle starts on line 5 and its area method on line 7. Where the
+		// ranges end depends on whether tsgo folds the closing brace.
+		var starts []int
+		for _, r := range res.Ranges {
+			if r.Kind != "code" || r.EndLine <= r.StartLine {
+				t.Errorf("range %+v", r)
+			}
+			starts = append(starts, r.StartLine)
+		}
+		if !slices.Contains(starts, 5) || !slices.Contains(starts, 7) {
+			t.Errorf("ranges = %+v, want Circle (line 5) and its area method (line 7)", res.Ranges)
+		}
+	})
+
+	t.Run("document symbols", func(t *testing.T) {
+		symbols := typescriptmcptest.MustCallTool[[]typescriptmcptest.Symbol](t, c, "ts_document_symbols",
+			map[string]any{"file": indexFile})
+
+		if len(symbols) == 0 {
+			t.Fatal("expected at least one document symbol")
+		}
+
+		expected := []struct {
+			name string
+			line int // 1-based
+		}{
+			{name: "greet", line: 1},
+			{name: "add", line: 5},
+		}
+
+		symByName := make(map[string]typescriptmcptest.Symbol)
+		for _, sym := range symbols {
+			symByName[sym.Name] = sym
+		}
+
+		for _, w := range expected {
+			sym, ok := symByName[w.name]
+			if !ok {
+				t.Errorf("expected symbol %q in document symbols", w.name)
+				continue
+			}
+			if sym.Line != w.line {
+				t.Errorf("symbol %q: line = %d, want %d", w.name, sym.Line, w.line)
+			}
+		}
+	})
+
+	t.Run("document symbols flat by kind", func(t *testing.T) {
+		symbols := typescriptmcptest.MustCallTool[[]typescriptmcptest.Symbol](t, c, "ts_document_symbols",
+			map[string]any{"file": fx.Path("src/shapes.ts"), "kinds": []string{"method"}, "flat": true})
+		if len(symbols) != 3 {
+			t.Fatalf("methods = %+v, want the three area methods", symbols)
+		}
+		for _, s := range symbols {
+			if s.Name != "area" || s.Kind != "method" || len(s.Children) != 0 {
+				t.Errorf("flat entry = %+v", s)
+			}
+		}
+		if s := symbols[1]; s.Container != "Circle" || s.Line != 7 || s.EndLine != 9 {
+			t.Errorf("Circle.area = %+v, want lines 7-9", s)
+		}
+	})
+
+	t.Run("symbol at position", func(t *testing.T) {
+		// Line 8 is `return Math.PI * this.radius ** 2;` in Circle.area.
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.SymbolAtPositionResult](t, c, "ts_symbol_at_position",
+			map[string]any{"file": fx.Path("src/shapes.ts"), "line": 8, "column": 5})
+		if res.Breadcrumb != "Circle > area" || len(res.Chain) != 2 || res.Chain[1].StartLine != 7 {
+			t.Errorf("symbol at position = %+v", res)
+		}
+
+		// Line 5 of consumer.ts is the top-level `console.log(result, sum);`.
+		top := typescriptmcptest.MustCallTool[typescriptmcptest.SymbolAtPositionResult](t, c, "ts_symbol_at_position",
+			map[string]any{"file": consumerFile, "line": 5, "column": 1})
+		if len(top.Chain) != 0 || top.NearestPreceding == nil || top.NearestPreceding.Name != "sum" {
+			t.Errorf("top-level statement = %+v, want no chain and sum before it", top)
+		}
+	})
+
+	t.Run("document symbols visibility", func(t *testing.T) {
+		file := fx.Path("src/visibility.ts")
+		names := func(symbols []typescriptmcptest.Symbol) map[string]bool {
+			m := make(map[string]bool)
+			for _, s := range symbols {
+				m[s.Name] = true
+			}
+			return m
+		}
+
+		all := typescriptmcptest.MustCallTool[[]typescriptmcptest.Symbol](t, c, "ts_document_symbols",
+			map[string]any{"file": file})
+		// Inline modifiers and the export list at the bottom of the file.
+		wantExported := map[string]bool{
+			"Options": true, "run": true, "Runner": true, "format": false,
+			"defaultOptions": true, "helper": true,
+		}
+		for _, s := range all {
+			if want, ok := wantExported[s.Name]; ok && s.Exported != want {
+				t.Errorf("%s: exported = %t, want %t", s.Name, s.Exported, want)
+			}
+		}
+
+		exported := typescriptmcptest.MustCallTool[[]typescriptmcptest.Symbol](t, c, "ts_document_symbols",
+			map[string]any{"file": file, "visibility": "exported"})
+		got := names(exported)
+		for _, name := range []string{"Options", "Runner", "defaultOptions", "helper"} {
+			if !got[name] {
+				t.Errorf("exported view lacks %s: %+v", name, exported)
+			}
+		}
+		if got["format"] {
+			t.Errorf("exported view lists format: %+v", exported)
+		}
+
+		internal := typescriptmcptest.MustCallTool[[]typescriptmcptest.Symbol](t, c, "ts_document_symbols",
+			map[string]any{"file": file, "visibility": "internal"})
+		got = names(internal)
+		if !got["format"] || got["Options"] || got["helper"] {
+			t.Errorf("internal view = %+v", internal)
+		}
+		// Runner stays as the context of its private member.
+		for _, s := range internal {
+			if s.Name == "Runner" && (len(s.Children) != 1 || s.Children[0].Name != "reset") {
+				t.Errorf("Runner children = %+v, want only reset", s.Children)
+			}
+		}
+	})
 }
 
-func TestDefinition(t *testing.T) {
-	requireClient(t)
-	consumerFile := filepath.Join(fixtureDir, "src", "consumer.ts")
+func TestRename(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	// Rename "greet" -> "sayHello" at line 1, col 17 of index.ts.
+	// index.ts line 1: `export function greet(name: string): string {`
+	//                                   ^ col 17 (1-based)
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.RenameResult](t, srv.Client, "ts_rename",
+		map[string]any{"file": fx.Path("src/index.ts"), "line": 1, "column": 17, "newName": "sayHello"})
+	if len(res.Changes) == 0 {
+		t.Fatal("no file changes applied")
+	}
+	for _, c := range res.Changes {
+		if !strings.Contains(c.Diff, "+") || !strings.Contains(c.Diff, "sayHello") || len(c.ChangedLines) == 0 {
+			t.Errorf("change of %s = %+v, want its diff and changed lines", c.File, c)
+		}
+	}
 
-	// "greet" is used on line 3, column 16 of consumer.ts: `const result = greet("world");`
-	locs, err := sharedClient.Definition(ctx, consumerFile, 3, 16)
-	if err != nil {
-		t.Fatalf("Definition: %v", err)
+	// Verify index.ts has "sayHello" and not "greet" (as function name).
+	indexContent := fx.ReadFile(t, "src/index.ts")
+	if !strings.Contains(indexContent, "sayHello") {
+		t.Errorf("index.ts should contain 'sayHello', got:\n%s", indexContent)
+	}
+	if strings.Contains(indexContent, "function greet") {
+		t.Errorf("index.ts should not contain 'function greet', got:\n%s", indexContent)
 	}
 
-	if len(locs) == 0 {
-		t.Fatal("expected at least one definition location")
+	// Verify consumer.ts has "sayHello" and not "greet".
+	consumerContent := fx.ReadFile(t, "src/consumer.ts")
+	if !strings.Contains(consumerContent, "sayHello") {
+		t.Errorf("consumer.ts should contain 'sayHello', got:\n%s", consumerContent)
+	}
+	if strings.Contains(consumerContent, "greet") {
+		t.Errorf("consumer.ts should not contain 'greet', got:\n%s", consumerContent)
 	}
+}
+
+func TestRenameDryRun(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
+	before := fx.ReadFile(t, "src/consumer.ts")
 
-	defFile := docsync.URIToFile(string(locs[0].URI))
-	if !strings.HasSuffix(defFile, "index.ts") {
-		t.Errorf("expected definition in index.ts, got %s", defFile)
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.DryRunResult](t, srv.Client, "ts_rename",
+		map[string]any{"file": fx.Path("src/index.ts"), "line": 1, "column": 17, "newName": "sayHello", "dryRun": true})
+	if !res.DryRun || res.TotalEdits == 0 || len(res.Changes) != 2 {
+		t.Fatalf("dry run = %+v, want edits in index.ts and consumer.ts", res)
+	}
+	for _, c := range res.Changes {
+		if !strings.Contains(c.Diff, "+") || !strings.Contains(c.Diff, "sayHello") {
+			t.Errorf("diff of %s = %q, want the renamed lines", c.File, c.Diff)
+		}
+	}
+	if got := fx.ReadFile(t, "src/consumer.ts"); got != before {
+		t.Errorf("the dry run wrote consumer.ts:\n%s", got)
 	}
 }
 
-func TestHover(t *testing.T) {
-	requireClient(t)
-	indexFile := filepath.Join(fixtureDir, "src", "index.ts")
+func TestApplyCodeAction(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
+	c := srv.Client
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	// "add" is used without an import on line 2, column 22 of fixes.ts.
+	args := map[string]any{"file": fx.Path("src/fixes.ts"), "startLine": 2, "startColumn": 22, "endColumn": 25, "kind": "quickfix"}
+	list := typescriptmcptest.MustCallTool[typescriptmcptest.CodeActionsResult](t, c, "ts_code_actions", args)
+	title := ""
+	for _, a := range list.Actions {
+		if strings.Contains(a.Title, "import") {
+			title = a.Title
+			break
+		}
+	}
+	if title == "" {
+		t.Fatalf("no import fix among %+v", list.Actions)
+	}
 
-	// "greet" is on line 1, column 17 of index.ts: `export function greet(name: string): string {`
-	hover, err := sharedClient.Hover(ctx, indexFile, 1, 17)
-	if err != nil {
-		t.Fatalf("Hover: %v", err)
+	args["title"] = title
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.ApplyCodeActionResult](t, c, "ts_apply_code_action", args)
+	if res.Title != title || res.TotalEdits == 0 || len(res.Changes) != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	if content := fx.ReadFile(t, "src/fixes.ts"); !strings.Contains(content, `import { add } from "./index"`) {
+		t.Errorf("fixes.ts lacks the import:\n%s", content)
+	}
+
+	// The file was re-synced, so tsgo no longer reports the missing name.
+	diags := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+		map[string]any{"file": fx.Path("src/fixes.ts")})
+	if len(diags.Diagnostics) != 0 {
+		t.Errorf("diagnostics after the fix = %+v", diags.Diagnostics)
 	}
+}
+
+func TestExtractRefactor(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
+	c := srv.Client
 
-	if hover == nil {
-		t.Fatal("expected hover result, got nil")
+	// "add(1, 2)" spans columns 13 to 22 of line 4 of consumer.ts.
+	args := map[string]any{"file": fx.Path("src/consumer.ts"), "startLine": 4, "startColumn": 13, "endColumn": 22}
+	list := typescriptmcptest.MustCallTool[typescriptmcptest.ExtractRefactoringsResult](t, c, "ts_extract_refactor", args)
+	pick := ""
+	for _, r := range list.Refactorings {
+		if strings.Contains(r.Title, "constant") && r.Disabled == "" {
+			pick = r.Title
+			break
+		}
+	}
+	if pick == "" {
+		t.Fatalf("no extract to constant among %+v", list.Refactorings)
 	}
 
-	content := hover.Contents.Value
-	if content == "" {
-		t.Fatal("expected non-empty hover content")
+	args["pick"] = pick
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.ExtractRefactorResult](t, c, "ts_extract_refactor", args)
+	if res.Title != pick || res.TotalEdits == 0 || len(res.Changes) != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	if res.Name == "" {
+		t.Fatalf("no generated name in %+v", res)
+	}
+	content := fx.ReadFile(t, "src/consumer.ts")
+	if !strings.Contains(content, "const "+res.Name) || !strings.Contains(content, "= "+res.Name) {
+		t.Errorf("consumer.ts does not declare and use %s:\n%s", res.Name, content)
 	}
+}
 
-	// The hover should contain the function signature.
-	if !strings.Contains(content, "greet") {
-		t.Errorf("hover content should mention 'greet', got: %s", content)
+func TestFixAll(t *testing.T) {
+	files := simpleFiles(t)
+	files["src/typos.ts"] = "const total = 1;\nexport const a = totl + 1;\nexport const b = totl + 2;\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+	file := fx.Path("src/typos.ts")
+
+	// Both uses of totl are "Cannot find name 'totl'. Did you mean
+	// 'total'?" (2552), fixed by changing the spelling.
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.FixAllResult](t, srv.Client, "ts_fix_all",
+		map[string]any{"file": file, "code": 2552})
+	if res.Diagnostics != 2 || res.Applied != 2 || len(res.Skipped) != 0 || res.Remaining != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if len(res.Changes) != 1 || res.TotalEdits != 2 {
+		t.Errorf("changes = %+v, totalEdits %d", res.Changes, res.TotalEdits)
 	}
-	if !strings.Contains(content, "string") {
-		t.Errorf("hover content should mention 'string', got: %s", content)
+	if content := fx.ReadFile(t, "src/typos.ts"); strings.Contains(content, "totl") {
+		t.Errorf("typos.ts still misspells total:\n%s", content)
 	}
 }
 
-func TestReferences(t *testing.T) {
-	requireClient(t)
-	indexFile := filepath.Join(fixtureDir, "src", "index.ts")
+func TestCallHierarchy(t *testing.T) {
+	files := simpleFiles(t)
+	files["src/parity.ts"] = "export function isEven(n: number): boolean {\n  return n === 0 ? true : isOdd(n - 1);\n}\n\nexport function isOdd(n: number): boolean {\n  return n === 0 ? false : isEven(n - 1);\n}\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+	file := fx.Path("src/parity.ts")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	// isEven is at line 1, column 17; its only caller is isOdd, whose
+	// caller is isEven again.
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.CallHierarchyResult](t, srv.Client, "ts_call_hierarchy",
+		map[string]any{"file": file, "line": 1, "column": 17, "depth": 3})
+	if len(res.Roots) != 1 || res.Roots[0].Name != "isEven" || len(res.Roots[0].Calls) != 1 {
+		t.Fatalf("incoming = %+v", res)
+	}
+	odd := res.Roots[0].Calls[0]
+	if odd.Name != "isOdd" || len(odd.CallSites) != 1 || odd.CallSites[0].Line != 6 {
+		t.Errorf("caller = %+v, want isOdd calling on line 6", odd)
+	}
+	if len(odd.Calls) != 1 || !odd.Calls[0].Recursive {
+		t.Errorf("isOdd's callers = %+v, want a recursive isEven", odd.Calls)
+	}
 
-	// "greet" definition on line 1, column 17 of index.ts.
-	locs, err := sharedClient.References(ctx, indexFile, 1, 17)
-	if err != nil {
-		t.Fatalf("References: %v", err)
+	res = typescriptmcptest.MustCallTool[typescriptmcptest.CallHierarchyResult](t, srv.Client, "ts_call_hierarchy",
+		map[string]any{"file": file, "line": 1, "column": 17, "direction": "outgoing"})
+	if len(res.Roots) != 1 || len(res.Roots[0].Calls) != 1 || res.Roots[0].Calls[0].Name != "isOdd" {
+		t.Errorf("outgoing = %+v", res)
 	}
+}
 
-	if len(locs) < 2 {
-		t.Errorf("expected at least 2 references to greet (definition + usage), got %d", len(locs))
-		for i, loc := range locs {
-			t.Logf("  ref[%d]: %s:%d", i, docsync.URIToFile(string(loc.URI)), loc.Range.Start.Line+1)
-		}
-		return
+func TestFormat(t *testing.T) {
+	files := simpleFiles(t)
+	files["src/messy.ts"] = "export function messy(a:number,b:number){\nreturn a+b\n}\n\nexport const kept   =   1;\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+	file := fx.Path("src/messy.ts")
+
+	// Only line 5 is formatted.
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.FormatResult](t, srv.Client, "ts_format",
+		map[string]any{"file": file, "startLine": 5, "tabSize": 2})
+	if !res.Changed {
+		t.Fatalf("range format result = %+v", res)
+	}
+	content := fx.ReadFile(t, "src/messy.ts")
+	if !strings.Contains(content, "export const kept = 1;") || !strings.Contains(content, "a:number,b:number") {
+		t.Errorf("messy.ts after formatting line 5:\n%s", content)
 	}
 
-	// Check that at least one reference is in consumer.ts.
-	hasConsumerRef := false
-	for _, loc := range locs {
-		f := docsync.URIToFile(string(loc.URI))
-		if strings.HasSuffix(f, "consumer.ts") {
-			hasConsumerRef = true
-			break
-		}
+	res = typescriptmcptest.MustCallTool[typescriptmcptest.FormatResult](t, srv.Client, "ts_format",
+		map[string]any{"file": file, "tabSize": 2})
+	if !res.Changed || res.Edits == 0 {
+		t.Fatalf("format result = %+v", res)
 	}
-	if !hasConsumerRef {
-		t.Error("expected at least one reference in consumer.ts")
-		for i, loc := range locs {
-			t.Logf("  ref[%d]: %s:%d", i, docsync.URIToFile(string(loc.URI)), loc.Range.Start.Line+1)
-		}
+	if content := fx.ReadFile(t, "src/messy.ts"); !strings.Contains(content, "export function messy(a: number, b: number) {\n  return a + b\n}") {
+		t.Errorf("messy.ts after formatting:\n%s", content)
+	}
+
+	// Formatting again is a no-op.
+	res = typescriptmcptest.MustCallTool[typescriptmcptest.FormatResult](t, srv.Client, "ts_format",
+		map[string]any{"file": file, "tabSize": 2})
+	if res.Changed {
+		t.Errorf("second format result = %+v, want unchanged", res)
 	}
 }
 
-func TestDocumentSymbols(t *testing.T) {
-	requireClient(t)
-	indexFile := filepath.Join(fixtureDir, "src", "index.ts")
+func TestRenameFile(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
+	c := srv.Client
 
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.RenameFileResult](t, c, "ts_rename_file",
+		map[string]any{"oldPath": fx.Path("src/index.ts"), "newPath": fx.Path("src/lib/math.ts")})
+	var changed []string
+	for _, ch := range res.Changes {
+		changed = append(changed, filepath.Base(ch.File))
+	}
+	if !slices.Contains(changed, "consumer.ts") {
+		t.Fatalf("changes = %+v, want consumer.ts", res.Changes)
+	}
+	if _, err := os.Stat(fx.Path("src/index.ts")); !os.IsNotExist(err) {
+		t.Errorf("src/index.ts still exists: %v", err)
+	}
+	if content := fx.ReadFile(t, "src/consumer.ts"); !strings.Contains(content, `from "./lib/math"`) {
+		t.Errorf("consumer.ts imports:\n%s", content)
+	}
 
-	symbols, err := sharedClient.DocumentSymbol(ctx, indexFile)
-	if err != nil {
-		t.Fatalf("DocumentSymbol: %v", err)
+	diags := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+		map[string]any{"file": fx.Path("src/consumer.ts")})
+	if len(diags.Diagnostics) != 0 {
+		t.Errorf("diagnostics after the move = %+v", diags.Diagnostics)
 	}
+}
+
+func TestRenameVisualColumn(t *testing.T) {
+	files := simpleFiles(t)
+	files["src/tabs.ts"] = "export function area(width: number, height: number): number {\n\tconst size = width * height;\n\treturn size;\n}\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+	file := fx.Path("src/tabs.ts")
 
-	if len(symbols) == 0 {
-		t.Fatal("expected at least one document symbol")
+	// With tabs 4 wide, "size" on line 2 is at visual column 11 but
+	// character column 8.
+	defs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, srv.Client, "ts_definition",
+		map[string]any{"file": file, "line": 3, "column": 12, "columnMode": "visual", "tabWidth": 4})
+	if len(defs) != 1 || defs[0].Line != 2 || defs[0].Column != 8 || defs[0].VisualColumn != 11 {
+		t.Errorf("definitions = %+v, want line 2, column 8, visualColumn 11", defs)
 	}
 
-	type want struct {
-		name string
-		line uint32 // 0-based LSP line
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.RenameResult](t, srv.Client, "ts_rename",
+		map[string]any{"file": file, "line": 2, "column": 11, "columnMode": "visual", "tabWidth": 4, "newName": "total"})
+	if len(res.ColumnReadings) != 2 || res.ColumnReadings[0].Identifier != "size" || res.ColumnReadings[1].ColumnMode != "character" {
+		t.Errorf("columnReadings = %+v", res.ColumnReadings)
 	}
-	expected := []want{
-		{name: "greet", line: 0}, // line 1 in index.ts
-		{name: "add", line: 4},   // line 5 in index.ts
+	if content := fx.ReadFile(t, "src/tabs.ts"); !strings.Contains(content, "\tconst total = width * height;\n\treturn total;") {
+		t.Errorf("tabs.ts after the rename:\n%s", content)
 	}
+}
 
-	symByName := make(map[string]protocol.DocumentSymbol)
-	for _, sym := range symbols {
-		symByName[sym.Name] = sym
+func TestRenameBySymbol(t *testing.T) {
+	files := simpleFiles(t)
+	files["src/repo.ts"] = "export class UserRepo {\n  save(): void {}\n}\n\nexport function save(): void {}\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+	file := fx.Path("src/repo.ts")
+
+	res := typescriptmcptest.CallTool(t, srv.Client, "ts_rename", map[string]any{"file": file, "symbol": "save", "newName": "persist"})
+	if text := typescriptmcptest.Summary(res); !res.IsError || !strings.Contains(text, "1. method UserRepo.save at 2:3") || !strings.Contains(text, "2. function save at 5:17") {
+		t.Fatalf("rename of an ambiguous name = %q, want the candidates", text)
 	}
 
-	for _, w := range expected {
-		sym, ok := symByName[w.name]
-		if !ok {
-			t.Errorf("expected symbol %q in document symbols", w.name)
-			continue
-		}
-		if sym.Range.Start.Line != w.line {
-			t.Errorf("symbol %q: Range.Start.Line = %d, want %d", w.name, sym.Range.Start.Line, w.line)
-		}
+	typescriptmcptest.MustCallTool[typescriptmcptest.RenameResult](t, srv.Client, "ts_rename",
+		map[string]any{"file": file, "symbol": "save", "occurrence": 2, "newName": "persist"})
+	typescriptmcptest.MustCallTool[typescriptmcptest.RenameResult](t, srv.Client, "ts_rename",
+		map[string]any{"file": file, "symbol": "UserRepo", "newName": "UserRepository"})
+	want := "export class UserRepository {\n  save(): void {}\n}\n\nexport function persist(): void {}\n"
+	if got := fx.ReadFile(t, "src/repo.ts"); got != want {
+		t.Errorf("repo.ts after the renames:\n%s\nwant:\n%s", got, want)
 	}
 }
 
-func TestRename(t *testing.T) {
-	if _, err := exec.LookPath("tsgo"); err != nil {
-		t.Skip("requires tsgo in PATH; install with: npm install -g @typescript/native-preview")
+func TestRenameSkipsExcludedFiles(t *testing.T) {
+	files := simpleFiles(t)
+	files["dist/consumer.ts"] = "import { greet } from \"../src/index\";\n\nexport const out = greet(\"dist\");\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+	before := fx.ReadFile(t, "dist/consumer.ts")
+
+	// Without exclusions, the file would be renamed with the rest.
+	preview := typescriptmcptest.MustCallTool[typescriptmcptest.DryRunResult](t, srv.Client, "ts_rename",
+		map[string]any{"file": fx.Path("src/index.ts"), "line": 1, "column": 17, "newName": "sayHello", "dryRun": true, "excludeGlobs": []string{}})
+	if len(preview.Changes) != 3 {
+		t.Errorf("dry run with excludeGlobs [] = %+v, want index.ts and both consumers", preview.Changes)
 	}
 
-	// Copy testdata/simple to a temp dir so we don't mutate the fixture.
-	tmpDir := t.TempDir()
-	srcDir := filepath.Join(tmpDir, "src")
-	if err := os.MkdirAll(srcDir, 0755); err != nil {
-		t.Fatalf("MkdirAll: %v", err)
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.RenameResult](t, srv.Client, "ts_rename",
+		map[string]any{"file": fx.Path("src/index.ts"), "line": 1, "column": 17, "newName": "sayHello"})
+	if len(res.SkippedFiles) != 1 || res.SkippedFiles[0].File != fx.Path("dist/consumer.ts") || res.SkippedFiles[0].Edits != 2 {
+		t.Errorf("skippedFiles = %+v, want dist/consumer.ts with 2 edits", res.SkippedFiles)
+	}
+	if got := fx.ReadFile(t, "dist/consumer.ts"); got != before {
+		t.Errorf("excluded dist/consumer.ts was edited:\n%s", got)
+	}
+	if got := fx.ReadFile(t, "src/consumer.ts"); !strings.Contains(got, "sayHello") {
+		t.Errorf("src/consumer.ts after the rename:\n%s", got)
 	}
+}
+
+func TestRenameConflict(t *testing.T) {
+	files := simpleFiles(t)
+	files["src/clash.ts"] = "export const foo = 1;\nexport const bar = 2;\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+	before := fx.ReadFile(t, "src/clash.ts")
 
-	filesToCopy := []string{
-		"tsconfig.json",
-		filepath.Join("src", "index.ts"),
-		filepath.Join("src", "consumer.ts"),
-		filepath.Join("src", "errors.ts"),
+	// foo is at line 1, column 14; bar is already declared.
+	args := map[string]any{"file": fx.Path("src/clash.ts"), "line": 1, "column": 14, "newName": "bar"}
+	res := typescriptmcptest.CallTool(t, srv.Client, "ts_rename", args)
+	if text := typescriptmcptest.Summary(res); !res.IsError || !strings.Contains(text, "conflicts with existing declarations") || !strings.Contains(text, "clash.ts:2:14 TS2451") {
+		t.Fatalf("rename to a declared name = %q, want the conflict refused", text)
 	}
-	for _, rel := range filesToCopy {
-		data, err := os.ReadFile(filepath.Join(fixtureDir, rel))
-		if err != nil {
-			t.Fatalf("ReadFile %s: %v", rel, err)
-		}
-		if err := os.WriteFile(filepath.Join(tmpDir, rel), data, 0644); err != nil {
-			t.Fatalf("WriteFile %s: %v", rel, err)
-		}
+	if got := fx.ReadFile(t, "src/clash.ts"); got != before {
+		t.Errorf("the refused rename wrote clash.ts:\n%s", got)
+	}
+	diags := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, srv.Client, "ts_diagnostics",
+		map[string]any{"file": fx.Path("src/clash.ts")})
+	if len(diags.Diagnostics) != 0 {
+		t.Errorf("diagnostics after the check = %+v, want those of the file on disk", diags.Diagnostics)
 	}
 
-	// Start a scoped LSP client rooted at tmpDir.
-	rootURI := docsync.FileToURI(tmpDir)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	args["force"] = true
+	typescriptmcptest.MustCallTool[typescriptmcptest.RenameResult](t, srv.Client, "ts_rename", args)
+	if got := fx.ReadFile(t, "src/clash.ts"); !strings.Contains(got, "export const bar = 1;") {
+		t.Errorf("clash.ts after the forced rename:\n%s", got)
+	}
+}
 
-	client, err := lsp.NewClient(ctx, rootURI)
-	if err != nil {
-		t.Fatalf("NewClient: %v", err)
+func TestDiagnosticCauses(t *testing.T) {
+	files := simpleFiles(t)
+	files["src/deps.ts"] = "import { graphql } from './__generated__/graphql.js';\nimport pad from 'left-pad';\nimport { missing } from './missing.js';\n\nexport const x = [graphql, pad, missing];\n"
+	files["node_modules/left-pad/package.json"] = `{"name": "left-pad", "version": "1.3.0", "main": "index.js"}`
+	files["node_modules/left-pad/index.js"] = "module.exports = function leftPad() {};\n"
+	fx := typescriptmcptest.NewFixtureProject(t, files)
+	srv := typescriptmcptest.StartServer(t, fx)
+
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, srv.Client, "ts_diagnostics",
+		map[string]any{"file": fx.Path("src/deps.ts"), "classifyCauses": true})
+	want := map[int]string{1: "generated", 2: "missingTypes", 3: "missingFile"}
+	for _, d := range res.Diagnostics {
+		if kind, ok := want[d.Line]; ok && d.Cause != nil && d.Cause.Kind == kind {
+			delete(want, d.Line)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("unclassified lines %v in %+v", want, res.Diagnostics)
+	}
+	if len(res.Causes) != 3 {
+		t.Errorf("causes = %+v, want three kinds", res.Causes)
 	}
-	defer client.Close()
+}
 
-	docs := docsync.NewManager()
+func TestProjectInfo(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
 
-	indexFile := filepath.Join(tmpDir, "src", "index.ts")
-	consumerFile := filepath.Join(tmpDir, "src", "consumer.ts")
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.ProjectInfoResult](t, srv.Client, "ts_project_info",
+		map[string]any{"cwd": fx.Dir})
 
-	if err := docs.SyncFile(ctx, client.Conn(), indexFile); err != nil {
-		t.Fatalf("SyncFile index.ts: %v", err)
+	if want := fx.Path("tsconfig.json"); res.TsconfigPath != want {
+		t.Errorf("tsconfigPath = %q, want %q", res.TsconfigPath, want)
 	}
-	if err := docs.SyncFile(ctx, client.Conn(), consumerFile); err != nil {
-		t.Fatalf("SyncFile consumer.ts: %v", err)
+	if res.ProjectRoot != fx.Dir {
+		t.Errorf("projectRoot = %q, want %q", res.ProjectRoot, fx.Dir)
+	}
+	if s := res.Environment.Strictness; !s["strict"] || !s["strictNullChecks"] || s["noUncheckedIndexedAccess"] {
+		t.Errorf("strictness = %v, want strict checks on and noUncheckedIndexedAccess off", s)
+	}
+	if res.Config == nil || res.Config.CompilerOptions["target"] != "ES2022" || res.Config.CompilerOptions["strict"] != true {
+		t.Errorf("config = %+v, want the fixture's compiler options", res.Config)
 	}
-	time.Sleep(1 * time.Second)
 
-	// Rename "greet" -> "sayHello" at line 1, col 17 of index.ts.
-	// index.ts line 1: `export function greet(name: string): string {`
-	//                                   ^ col 17 (1-based)
-	edit, err := client.Rename(ctx, indexFile, 1, 17, "sayHello")
-	if err != nil {
-		t.Fatalf("Rename: %v", err)
+	t.Run("extends", func(t *testing.T) {
+		fx.WriteFile(t, "config/tsconfig.base.json", `{
+			// shared settings
+			"compilerOptions": {"baseUrl": "..", "paths": {"@/*": ["*"]}, "noUncheckedIndexedAccess": true,},
+			"exclude": ["dist"],
+		}`)
+		fx.WriteFile(t, "tsconfig.app.json", `{"extends": "./config/tsconfig.base.json", "compilerOptions": {"strict": true}, "references": [{"path": "./lib"}]}`)
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ProjectInfoResult](t, srv.Client, "ts_project_info",
+			map[string]any{"tsconfig": fx.Path("tsconfig.app.json")})
+		cfg := res.Config
+		if cfg == nil {
+			t.Fatalf("no config, error %+v", res.ConfigError)
+		}
+		base := fx.Path("config/tsconfig.base.json")
+		if cfg.CompilerOptions["baseUrl"] != ".." || cfg.Sources["compilerOptions.baseUrl"] != base || cfg.Sources["exclude"] != base {
+			t.Errorf("baseUrl = %v from %q, sources %v", cfg.CompilerOptions["baseUrl"], cfg.Sources["compilerOptions.baseUrl"], cfg.Sources)
+		}
+		if _, ok := cfg.Sources["compilerOptions.strict"]; ok {
+			t.Errorf("strict is the config's own setting but has a source: %v", cfg.Sources)
+		}
+		if len(cfg.Bases) != 1 || cfg.Bases[0] != base || len(cfg.References) != 1 || cfg.References[0] != "./lib" {
+			t.Errorf("bases = %v, references = %v", cfg.Bases, cfg.References)
+		}
+	})
+
+	t.Run("list files", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ProjectInfoResult](t, srv.Client, "ts_project_info",
+			map[string]any{"cwd": fx.Dir, "listFiles": true, "maxResults": 2})
+		files := res.SourceFiles
+		if files == nil || files.Count != len(simpleFiles(t))-1 || len(files.Files) != 2 || !files.Truncated {
+			t.Fatalf("sourceFiles = %+v, want every fixture source file counted and two listed", files)
+		}
+		if files.Files[0] != fx.Path("src/consumer.ts") {
+			t.Errorf("first file = %q, want src/consumer.ts", files.Files[0])
+		}
+	})
+
+	t.Run("malformed base", func(t *testing.T) {
+		fx.WriteFile(t, "config/broken.json", `{"compilerOptions": {"strict": true`)
+		fx.WriteFile(t, "tsconfig.broken.json", `{"extends": "./config/broken.json"}`)
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ProjectInfoResult](t, srv.Client, "ts_project_info",
+			map[string]any{"tsconfig": fx.Path("tsconfig.broken.json")})
+		if res.Config != nil || res.ConfigError == nil || res.ConfigError.File != fx.Path("config/broken.json") {
+			t.Errorf("config = %+v, error = %+v, want an error naming config/broken.json", res.Config, res.ConfigError)
+		}
+	})
+}
+
+func TestListProjects(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, testdataFiles(t, "monorepo"))
+	srv := typescriptmcptest.StartServer(t, fx)
+
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.ListProjectsResult](t, srv.Client, "ts_list_projects",
+		map[string]any{"root": fx.Dir, "file": fx.Path("packages/app/src/main.ts")})
+
+	var configs []string
+	for _, p := range res.Projects {
+		configs = append(configs, p.Config)
 	}
-	if edit == nil {
-		t.Fatal("expected workspace edit, got nil")
+	want := []string{fx.Path("packages/core/tsconfig.json"), fx.Path("packages/app/tsconfig.json"), fx.Path("tsconfig.json")}
+	if strings.Join(configs, " ") != strings.Join(want, " ") {
+		t.Errorf("projects = %v, want %v", configs, want)
 	}
-	if len(edit.Changes) == 0 && len(edit.DocumentChanges) == 0 {
-		t.Fatal("expected non-empty workspace edit")
+	if o := res.Owner; o == nil || o.Config != fx.Path("packages/app/tsconfig.json") || !o.Included {
+		t.Errorf("owner = %+v, want packages/app", o)
 	}
 
-	// Apply the workspace edit using the production code path.
-	changes, err := tools.ApplyWorkspaceEdit(edit)
-	if err != nil {
-		t.Fatalf("ApplyWorkspaceEdit: %v", err)
+	t.Run("file outside include", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.ListProjectsResult](t, srv.Client, "ts_list_projects",
+			map[string]any{"root": fx.Dir, "file": fx.Path("packages/app/scripts/build.ts")})
+		if o := res.Owner; o == nil || o.Config != fx.Path("packages/app/tsconfig.json") || o.Included {
+			t.Errorf("owner = %+v, want packages/app, not included", o)
+		}
+	})
+}
+
+// TestProjectOutsideRoot covers calls about a project outside the server's
+// root, served by a tsgo started for it.
+func TestProjectOutsideRoot(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, testdataFiles(t, "monorepo"))
+	srv := typescriptmcptest.StartServer(t, fx)
+	other := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+
+	content := typescriptmcptest.MustCallToolText(t, srv.Client, "ts_hover",
+		map[string]any{"file": other.Path("src/index.ts"), "line": 1, "column": 17})
+	if !strings.Contains(content, "greet") {
+		t.Errorf("hover outside the root = %q, want greet's signature", content)
 	}
-	if len(changes) == 0 {
-		t.Fatal("no file changes applied")
+
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.ProjectInfoResult](t, srv.Client, "ts_project_info",
+		map[string]any{"tsconfig": other.Path("tsconfig.json")})
+	if res.ProjectRoot != other.Dir || res.Config == nil || res.Config.CompilerOptions["target"] != "ES2022" {
+		t.Errorf("project info = %+v, want the other project's", res)
 	}
+}
 
-	// Verify index.ts has "sayHello" and not "greet" (as function name).
-	indexContent, err := os.ReadFile(indexFile)
-	if err != nil {
-		t.Fatalf("ReadFile index.ts: %v", err)
+func TestServerInfo(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
+
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.ServerInfoResult](t, srv.Client, "ts_server_info", nil)
+	if res.Name == "" || len(res.Capabilities) == 0 {
+		t.Fatalf("server info = %+v, want tsgo's name and capabilities", res)
 	}
-	if !strings.Contains(string(indexContent), "sayHello") {
-		t.Errorf("index.ts should contain 'sayHello', got:\n%s", string(indexContent))
+	for _, c := range res.Capabilities {
+		if c.Method == "textDocument/hover" && !c.Supported {
+			t.Errorf("tsgo does not announce hover: %+v", res)
+		}
 	}
-	if strings.Contains(string(indexContent), "function greet") {
-		t.Errorf("index.ts should not contain 'function greet', got:\n%s", string(indexContent))
+	for _, tool := range res.UnavailableTools {
+		if tool == "ts_hover" {
+			t.Errorf("ts_hover is reported unavailable: %+v", res)
+		}
 	}
+}
 
-	// Verify consumer.ts has "sayHello" and not "greet".
-	consumerContent, err := os.ReadFile(consumerFile)
-	if err != nil {
-		t.Fatalf("ReadFile consumer.ts: %v", err)
+func TestImportCycles(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, testdataFiles(t, "cycles"))
+	srv := typescriptmcptest.StartServer(t, fx)
+
+	res := typescriptmcptest.MustCallTool[typescriptmcptest.ImportCyclesResult](t, srv.Client, "ts_import_cycles",
+		map[string]any{"path": fx.Path("src/order.ts")})
+
+	if len(res.Cycles) != 1 {
+		t.Fatalf("expected one runtime cycle through order.ts, got %+v", res.Cycles)
 	}
-	if !strings.Contains(string(consumerContent), "sayHello") {
-		t.Errorf("consumer.ts should contain 'sayHello', got:\n%s", string(consumerContent))
+	want := []string{"src/order.ts", "src/customer.ts", "src/pricing.ts"}
+	if got := res.Cycles[0].Files; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("cycle files = %v, want %v", got, want)
 	}
-	if strings.Contains(string(consumerContent), "greet") {
-		t.Errorf("consumer.ts should not contain 'greet', got:\n%s", string(consumerContent))
+	if e := res.Cycles[0].Edges[2]; e.File != "src/pricing.ts" || e.Line != 2 {
+		t.Errorf("closing edge = %+v, want pricing.ts line 2", e)
 	}
 }
 
-func TestProjectInfo(t *testing.T) {
-	tsconfigPath := filepath.Join(fixtureDir, "tsconfig.json")
+// TestDefinitionReexports covers ts_definition following barrels and
+// declaration maps to the original source.
+func TestDefinitionReexports(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, testdataFiles(t, "reexports"))
+	srv := typescriptmcptest.StartServer(t, fx)
+	c := srv.Client
+	appFile := fx.Path("src/app.ts")
 
-	// ts_project_info doesn't need the LSP client for its current
-	// implementation (it just checks the filesystem), so we test the
-	// result structure directly.
-	type projectInfoResult struct {
-		TsconfigPath string `json:"tsconfigPath,omitempty"`
-		ProjectRoot  string `json:"projectRoot,omitempty"`
-	}
+	t.Run("through a barrel", func(t *testing.T) {
+		// Column 20 is createRepo in `export const repo = createRepo("main");`.
+		locs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, c, "ts_definition",
+			map[string]any{"file": appFile, "line": 4, "column": 20})
+		if len(locs) != 1 || !strings.HasSuffix(locs[0].File, "src/repo.ts") || locs[0].Line != 1 {
+			t.Errorf("definition = %+v, want createRepo in repo.ts", locs)
+		}
+	})
 
-	result := projectInfoResult{
-		TsconfigPath: tsconfigPath,
-		ProjectRoot:  fixtureDir,
-	}
+	t.Run("through a declaration map", func(t *testing.T) {
+		// Column 23 is greet in `export const message = greet(repo.name);`.
+		locs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, c, "ts_definition",
+			map[string]any{"file": appFile, "line": 5, "column": 23})
+		if len(locs) != 1 || !strings.HasSuffix(locs[0].File, "src/greet.ts") || locs[0].Line != 1 {
+			t.Errorf("definition = %+v, want greet in src/greet.ts", locs)
+		}
+	})
 
-	data, err := json.Marshal(result)
-	if err != nil {
-		t.Fatalf("marshal: %v", err)
+	t.Run("raw", func(t *testing.T) {
+		type hopped struct {
+			typescriptmcptest.Location
+			Via []any `json:"via"`
+		}
+		locs := typescriptmcptest.MustCallTool[[]hopped](t, c, "ts_definition",
+			map[string]any{"file": appFile, "line": 5, "column": 23, "raw": true})
+		for _, l := range locs {
+			if len(l.Via) != 0 {
+				t.Errorf("raw definition followed hops: %+v", l)
+			}
+		}
+	})
+}
+
+// TestCheckJS covers JavaScript checked through JSDoc annotations.
+func TestCheckJS(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, testdataFiles(t, "checkjs"))
+	srv := typescriptmcptest.StartServer(t, fx)
+	c := srv.Client
+	appFile := fx.Path("src/app.js")
+
+	t.Run("hover on a JSDoc tag", func(t *testing.T) {
+		// Column 6 is the "type" of `/** @type {import('./models.js').User} */`.
+		content := typescriptmcptest.MustCallToolText(t, c, "ts_hover",
+			map[string]any{"file": appFile, "line": 3, "column": 6})
+		if !strings.Contains(content, "User") {
+			t.Errorf("hover should describe User, got: %s", content)
+		}
+	})
+
+	t.Run("definition into the imported module", func(t *testing.T) {
+		// Column 15 is inside import('./models.js').
+		locs := typescriptmcptest.MustCallTool[[]typescriptmcptest.Location](t, c, "ts_definition",
+			map[string]any{"file": appFile, "line": 3, "column": 15})
+		if len(locs) == 0 {
+			t.Fatal("expected a definition location")
+		}
+		if !strings.HasSuffix(locs[0].File, "models.js") {
+			t.Errorf("expected definition in models.js, got %s", locs[0].File)
+		}
+	})
+
+	t.Run("JSDoc type mismatch", func(t *testing.T) {
+		res := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+			map[string]any{"file": appFile})
+		found := false
+		for _, d := range res.Diagnostics {
+			if d.Line == 7 && strings.Contains(d.Message, "not assignable") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a type error on line 7, got %+v", res.Diagnostics)
+		}
+	})
+}
+
+// TestRenameParameterDocs covers JSDoc tags renamed with a parameter.
+func TestRenameParameterDocs(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, testdataFiles(t, "checkjs"))
+	srv := typescriptmcptest.StartServer(t, fx)
+	file := fx.Path("src/params.js")
+
+	tests := []struct {
+		name       string
+		line, col  int
+		newName    string
+		wantTags   int
+		wantText   []string
+		unwantText []string
+	}{
+		{
+			name: "documented function parameter",
+			line: 7, col: 29, newName: "cents",
+			wantTags:   1,
+			wantText:   []string{"@param {number} cents - the amount in cents", "return (cents / 100)"},
+			unwantText: []string{"} amount -"},
+		},
+		{
+			name: "arrow function",
+			line: 15, col: 23, newName: "person",
+			wantTags:   2,
+			wantText:   []string{"}} person the user to greet", "@property {string} person.name - shown", "person.name;"},
+			unwantText: []string{"user.name", "(user)"},
+		},
+		{
+			name: "local variable",
+			line: 18, col: 7, newName: "sum",
+			wantText: []string{"let sum = 0;", "return sum;"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := typescriptmcptest.MustCallTool[typescriptmcptest.RenameResult](t, srv.Client, "ts_rename",
+				map[string]any{"file": file, "line": tt.line, "column": tt.col, "newName": tt.newName})
+			// tsgo may rename @param tags itself; docEdits only lists
+			// the tags it left.
+			if len(res.DocEdits) > tt.wantTags {
+				t.Errorf("docEdits = %+v, want at most %d", res.DocEdits, tt.wantTags)
+			}
+			content := fx.ReadFile(t, "src/params.js")
+			for _, w := range tt.wantText {
+				if !strings.Contains(content, w) {
+					t.Errorf("params.js lacks %q:\n%s", w, content)
+				}
+			}
+			for _, w := range tt.unwantText {
+				if strings.Contains(content, w) {
+					t.Errorf("params.js still has %q:\n%s", w, content)
+				}
+			}
+		})
 	}
+}
+
+// TestSummaryLines checks the summary line leading every response against
+// the detail it summarizes, and that summaryOnly returns just that line.
+func TestSummaryLines(t *testing.T) {
+	fx := typescriptmcptest.NewFixtureProject(t, simpleFiles(t))
+	srv := typescriptmcptest.StartServer(t, fx)
+	c := srv.Client
+	indexFile := fx.Path("src/index.ts")
 
-	var decoded projectInfoResult
-	if err := json.Unmarshal(data, &decoded); err != nil {
-		t.Fatalf("unmarshal: %v", err)
+	diags := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, c, "ts_diagnostics",
+		map[string]any{"file": fx.Path("src/errors.ts")})
+	errCount := 0
+	for _, d := range diags.Diagnostics {
+		if d.Severity == "error" {
+			errCount++
+		}
+	}
+	refs := typescriptmcptest.MustCallTool[typescriptmcptest.ReferencesResult](t, c, "ts_references",
+		map[string]any{"file": indexFile, "line": 1, "column": 17})
+	refFiles := make(map[string]bool)
+	for _, r := range refs.References {
+		refFiles[r.File] = true
 	}
 
-	if decoded.TsconfigPath != tsconfigPath {
-		t.Errorf("tsconfigPath = %q, want %q", decoded.TsconfigPath, tsconfigPath)
+	// want is the whole summary line, or its start when it ends in "...".
+	tests := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{"ts_diagnostics", map[string]any{"file": fx.Path("src/errors.ts")},
+			fmt.Sprintf("diagnostics: %d errors, 0 warnings in src/errors.ts (truncated: no)", errCount)},
+		{"ts_definition", map[string]any{"file": fx.Path("src/consumer.ts"), "line": 3, "column": 16},
+			"definition: 1 location, first src/index.ts:1:..."},
+		{"ts_hover", map[string]any{"file": indexFile, "line": 1, "column": 17},
+			"hover: function greet(name: string)..."},
+		{"ts_references", map[string]any{"file": indexFile, "line": 1, "column": 17},
+			fmt.Sprintf("references: %d total, %d shown in %d files (truncated: no)", refs.TotalCount, len(refs.References), len(refFiles))},
+		{"ts_document_symbols", map[string]any{"file": indexFile},
+			"symbols: ..."},
+		{"ts_import_cycles", map[string]any{"path": fx.Path("src")},
+			"import cycles: 0 through src, ..."},
+		{"ts_project_info", map[string]any{"cwd": fx.Dir}, "project: tsconfig.json..."},
+		{"ts_server_status", nil, "server: ready, ..."},
+		{"ts_server_info", nil, "server info: ..."},
+		{"ts_list_edits", nil, "edits: 0 of 0 (truncated: no, recording: off)"},
+		{"ts_recover_pending_edit", nil, "recover edit: 0 pending"},
+		{"ts_apply_edit", map[string]any{"editToken": "unknown"}, "apply edit: error: ..."},
 	}
-	if decoded.ProjectRoot != fixtureDir {
-		t.Errorf("projectRoot = %q, want %q", decoded.ProjectRoot, fixtureDir)
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			full := typescriptmcptest.CallTool(t, c, tt.tool, tt.args)
+			summary := typescriptmcptest.Summary(full)
+			if len(full.Content) < 2 || strings.Contains(summary, "\n") {
+				t.Fatalf("response %+v does not lead with a summary line", full.Content)
+			}
+			if start, ok := strings.CutSuffix(tt.want, "..."); ok && !strings.HasPrefix(summary, start) || !ok && summary != tt.want {
+				t.Errorf("summary = %q, want %q", summary, tt.want)
+			}
+
+			args := map[string]any{"summaryOnly": true}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			only := typescriptmcptest.CallTool(t, c, tt.tool, args)
+			if len(only.Content) != 1 || typescriptmcptest.Summary(only) != summary {
+				t.Errorf("summaryOnly content = %+v, want just %q", only.Content, summary)
+			}
+		})
 	}
 }
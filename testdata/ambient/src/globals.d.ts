@@ -0,0 +1,9 @@
+/// <reference path="./vendor.d.ts" />
+/// <reference lib="es2022" />
+
+declare const __APP_VERSION__: string;
+
+declare module "*.svg" {
+  const url: string;
+  export default url;
+}
@@ -0,0 +1,6 @@
+import logo from "./logo.svg";
+
+export function boot(): string {
+  track("boot", { version: __APP_VERSION__ });
+  return window.currentUser?.id ?? logo;
+}
@@ -0,0 +1,9 @@
+export interface User {
+  id: string;
+}
+
+declare global {
+  interface Window {
+    currentUser?: User;
+  }
+}
@@ -0,0 +1,6 @@
+declare function track(event: string, props?: Record<string, unknown>): void;
+
+interface AnalyticsEvent {
+  name: string;
+  at: number;
+}
@@ -0,0 +1,7 @@
+import { makeUser } from "./models.js";
+
+/** @type {import('./models.js').User} */
+export const admin = makeUser(1);
+
+/** @type {import('./models.js').User} */
+export const broken = { id: "two", name: "b" };
@@ -0,0 +1,12 @@
+/** @typedef {{ id: number, name: string }} User */
+
+/** @type {`user:${number}`} */
+export const prefix = "user:1";
+
+/**
+ * @param {number} id
+ * @returns {User}
+ */
+export function makeUser(id) {
+  return { id, name: "guest" };
+}
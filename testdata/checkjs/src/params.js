@@ -0,0 +1,21 @@
+/**
+ * Formats a price.
+ * @param {number} amount - the amount in cents
+ * @param {string} [currency="EUR"] the currency code
+ * @returns {string}
+ */
+export function formatPrice(amount, currency = "EUR") {
+  return (amount / 100).toFixed(2) + " " + currency;
+}
+
+/**
+ * @param {{ name: string }} user the user to greet
+ * @property {string} user.name - shown in the greeting
+ */
+export const greet = (user) => "Hello, " + user.name;
+
+export function total(/** @type {number[]} */ items) {
+  let amount = 0;
+  for (const item of items) amount += item;
+  return amount;
+}
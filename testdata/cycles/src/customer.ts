@@ -0,0 +1,9 @@
+import { loyaltyDiscount } from "./pricing";
+
+export class Customer {
+  constructor(public name: string, public orders: number) {}
+
+  discount(): number {
+    return loyaltyDiscount(this);
+  }
+}
@@ -0,0 +1,10 @@
+import { Customer } from "./customer";
+import { formatMoney } from "./money";
+
+export class Order {
+  constructor(public customer: Customer, public total: number) {}
+
+  describe(): string {
+    return `${this.customer.name}: ${formatMoney(this.total)}`;
+  }
+}
@@ -0,0 +1,10 @@
+import type { Customer } from "./customer";
+import { Order } from "./order";
+
+export function loyaltyDiscount(customer: Customer): number {
+  return customer.orders > 10 ? 0.1 : 0;
+}
+
+export function reorder(previous: Order): Order {
+  return new Order(previous.customer, previous.total * (1 - loyaltyDiscount(previous.customer)));
+}
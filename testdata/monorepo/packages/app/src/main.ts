@@ -0,0 +1 @@
+export const message: string = "app";
@@ -0,0 +1,2 @@
+export declare function greet(name: string): string;
+//# sourceMappingURL=greet.d.ts.map
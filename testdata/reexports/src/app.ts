@@ -0,0 +1,5 @@
+import { createRepo } from "./index";
+import { greet } from "../dist/greet";
+
+export const repo = createRepo("main");
+export const message = greet(repo.name);
@@ -0,0 +1 @@
+export { createRepo } from "./repo";
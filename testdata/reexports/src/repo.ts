@@ -0,0 +1,3 @@
+export function createRepo(name: string) {
+  return { name };
+}
@@ -0,0 +1,2 @@
+// Intentional error with a quick fix for code action testing
+export const total = add(1, 2);
@@ -0,0 +1,25 @@
+export interface Options {
+  verbose: boolean;
+}
+
+export default function run(options: Options): string {
+  return format(options.verbose);
+}
+
+export class Runner {
+  start(): void {}
+  private reset(): void {}
+}
+
+function format(verbose: boolean): string {
+  return verbose ? "verbose" : "quiet";
+}
+
+const defaultOptions: Options = { verbose: false };
+
+function helper(): void {}
+
+export {
+  defaultOptions,
+  helper as publicHelper,
+};
@@ -0,0 +1,831 @@
+package typescriptmcptest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// callTimeout bounds a single tool call.
+const callTimeout = 30 * time.Second
+
+// The result types below mirror the JSON the tools return; they cover the
+// fields tests usually assert on and ignore the rest.
+
+// Diagnostic is one entry of DiagnosticsResult.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Code     any    `json:"code,omitempty"`
+	Message  string `json:"message"`
+	// Related are the locations tsgo relates the diagnostic to.
+	Related []DiagnosticRelated `json:"related,omitempty"`
+	// Context is set with contextLines.
+	Context string `json:"context,omitempty"`
+	// Cause is set with classifyCauses for a missing module.
+	Cause *DiagnosticCause `json:"cause,omitempty"`
+}
+
+// DiagnosticRelated is a related location of a Diagnostic.
+type DiagnosticRelated struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// DiagnosticCause is why a module could not be found and how to fix it.
+type DiagnosticCause struct {
+	Kind      string `json:"kind"`
+	Specifier string `json:"specifier"`
+	Remedy    string `json:"remedy"`
+}
+
+// CauseSummary counts the diagnostics of one cause kind.
+type CauseSummary struct {
+	Kind     string   `json:"kind"`
+	Count    int      `json:"count"`
+	Remedies []string `json:"remedies"`
+}
+
+// DiagnosticsResult is the result of ts_diagnostics.
+type DiagnosticsResult struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	TotalCount  int          `json:"totalCount"`
+	FilteredOut int          `json:"filteredOut"`
+	Truncated   bool         `json:"truncated"`
+	NextCursor  string       `json:"nextCursor,omitempty"`
+	// Source is "pull" or "push".
+	Source             string `json:"source"`
+	AnalysisIncomplete bool   `json:"analysisIncomplete,omitempty"`
+	InProgram          bool   `json:"inProgram"`
+	Project            string `json:"project,omitempty"`
+	// ProjectStillLoading is set when waitForProjectLoad timed out.
+	ProjectStillLoading bool `json:"projectStillLoading,omitempty"`
+	// Causes is set with classifyCauses.
+	Causes []CauseSummary `json:"causes,omitempty"`
+}
+
+// DiagnosticsFileReport is one file of a ts_diagnostics result for files.
+type DiagnosticsFileReport struct {
+	Diagnostics        []Diagnostic `json:"diagnostics"`
+	TotalCount         int          `json:"totalCount"`
+	FilteredOut        int          `json:"filteredOut"`
+	Truncated          bool         `json:"truncated"`
+	Source             string       `json:"source,omitempty"`
+	AnalysisIncomplete bool         `json:"analysisIncomplete,omitempty"`
+	InProgram          bool         `json:"inProgram"`
+	Project            string       `json:"project,omitempty"`
+	Error              string       `json:"error,omitempty"`
+}
+
+// MultiDiagnosticsResult is the result of ts_diagnostics for files.
+type MultiDiagnosticsResult struct {
+	Files               map[string]DiagnosticsFileReport `json:"files"`
+	TotalCount          int                              `json:"totalCount"`
+	FilteredOut         int                              `json:"filteredOut"`
+	Errors              int                              `json:"errors"`
+	Warnings            int                              `json:"warnings"`
+	Other               int                              `json:"other"`
+	ProjectStillLoading bool                             `json:"projectStillLoading,omitempty"`
+	Causes              []CauseSummary                   `json:"causes,omitempty"`
+}
+
+// DiagnosticLocation is an example location of a DiagnosticCodeGroup.
+type DiagnosticLocation struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// DiagnosticCodeGroup is the diagnostics of one code in a
+// GroupedDiagnosticsResult.
+type DiagnosticCodeGroup struct {
+	Code     string               `json:"code"`
+	Severity string               `json:"severity"`
+	Count    int                  `json:"count"`
+	Message  string               `json:"message"`
+	Examples []DiagnosticLocation `json:"examples"`
+}
+
+// GroupedDiagnosticsResult is the result of ts_diagnostics with groupBy.
+type GroupedDiagnosticsResult struct {
+	Groups      []DiagnosticCodeGroup `json:"groups"`
+	Truncated   bool                  `json:"truncated"`
+	TotalCount  int                   `json:"totalCount"`
+	FilteredOut int                   `json:"filteredOut"`
+	Errors      int                   `json:"errors"`
+	Warnings    int                   `json:"warnings"`
+	Other       int                   `json:"other"`
+	Failed      map[string]string     `json:"failed,omitempty"`
+}
+
+// FileDiagnostics is a file ts_project_diagnostics lists.
+type FileDiagnostics struct {
+	File        string       `json:"file"`
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+	Information int          `json:"information,omitempty"`
+	Hints       int          `json:"hints,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	More        int          `json:"more,omitempty"`
+}
+
+// ProjectDiagnosticsResult is the result of ts_project_diagnostics.
+type ProjectDiagnosticsResult struct {
+	Tsconfig             string            `json:"tsconfig,omitempty"`
+	FilesTotal           int               `json:"filesTotal"`
+	FilesChecked         int               `json:"filesChecked"`
+	Errors               int               `json:"errors"`
+	Warnings             int               `json:"warnings"`
+	Information          int               `json:"information"`
+	Hints                int               `json:"hints"`
+	FilesWithDiagnostics int               `json:"filesWithDiagnostics"`
+	Files                []FileDiagnostics `json:"files"`
+	Truncated            bool              `json:"truncated"`
+	TimedOut             bool              `json:"timedOut"`
+	Elapsed              string            `json:"elapsed"`
+}
+
+// Location is a 1-based source position. ts_definition returns a
+// []Location.
+type Location struct {
+	File           string `json:"file"`
+	Line           int    `json:"line"`
+	Column         int    `json:"column"`
+	Preview        string `json:"preview,omitempty"`
+	PreviewOmitted bool   `json:"previewOmitted,omitempty"`
+	// PreviewTruncated is set on a definition whose multi-line signature
+	// preview was cut short.
+	PreviewTruncated bool `json:"previewTruncated,omitempty"`
+	// Package and DisplayPath are set for locations inside node_modules.
+	Package     *Package `json:"package,omitempty"`
+	DisplayPath string   `json:"displayPath,omitempty"`
+	// AdjustedColumn is set when the lookup was retried at the type name
+	// of a JSDoc annotation.
+	AdjustedColumn int `json:"adjustedColumn,omitempty"`
+	// VisualColumn is Column with tabs expanded, in columnMode "visual".
+	VisualColumn int `json:"visualColumn,omitempty"`
+	// The body fields are set on definitions with includeBody.
+	Body          string `json:"body,omitempty"`
+	BodyStartLine int    `json:"bodyStartLine,omitempty"`
+	BodyEndLine   int    `json:"bodyEndLine,omitempty"`
+	BodyTruncated bool   `json:"bodyTruncated,omitempty"`
+	BodyFallback  bool   `json:"bodyFallback,omitempty"`
+	BodySkipped   bool   `json:"bodySkipped,omitempty"`
+}
+
+// Package is the npm package owning a node_modules location.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Linked  bool   `json:"linked,omitempty"`
+}
+
+// HoverTag is a JSDoc tag of a FullHover.
+type HoverTag struct {
+	Tag  string `json:"tag"`
+	Name string `json:"name,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// FullHover is the result of ts_hover with format "full".
+type FullHover struct {
+	Signature     string     `json:"signature"`
+	Documentation string     `json:"documentation"`
+	Tags          []HoverTag `json:"tags"`
+	Note          string     `json:"note,omitempty"`
+}
+
+// HoverManyEntry is the hover of one position of a HoverManyResult.
+type HoverManyEntry struct {
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+	Column       int    `json:"column"`
+	VisualColumn int    `json:"visualColumn,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Note         string `json:"note,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// HoverManyResult is the result of ts_hover_many.
+type HoverManyResult struct {
+	Results []HoverManyEntry `json:"results"`
+}
+
+// ReferencesResult is the result of ts_references.
+type ReferencesResult struct {
+	References      []Location `json:"references"`
+	TotalCount      int        `json:"totalCount"`
+	UnfilteredCount int        `json:"unfilteredCount"`
+	Truncated       bool       `json:"truncated"`
+	Offset          int        `json:"offset,omitempty"`
+	NextCursor      string     `json:"nextCursor,omitempty"`
+}
+
+// ReferenceFile is a file of a GroupedReferencesResult.
+type ReferenceFile struct {
+	File       string     `json:"file"`
+	Count      int        `json:"count"`
+	References []Location `json:"references"`
+}
+
+// GroupedReferencesResult is the result of ts_references with groupByFile.
+type GroupedReferencesResult struct {
+	Files           []ReferenceFile `json:"files"`
+	FileCount       int             `json:"fileCount"`
+	TotalCount      int             `json:"totalCount"`
+	UnfilteredCount int             `json:"unfilteredCount"`
+	Truncated       bool            `json:"truncated"`
+}
+
+// Completion is one entry of a CompletionResult.
+type Completion struct {
+	Label         string `json:"label"`
+	Kind          string `json:"kind,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+	InsertText    string `json:"insertText"`
+	Documentation string `json:"documentation,omitempty"`
+	Deprecated    bool   `json:"deprecated,omitempty"`
+}
+
+// CompletionResult is the result of ts_completion.
+type CompletionResult struct {
+	Completions  []Completion `json:"completions"`
+	TotalCount   int          `json:"totalCount"`
+	Truncated    bool         `json:"truncated"`
+	IsIncomplete bool         `json:"isIncomplete,omitempty"`
+}
+
+// SignatureParameter is one parameter of a Signature.
+type SignatureParameter struct {
+	Label         string `json:"label"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// Signature is one overload of a SignatureHelpResult.
+type Signature struct {
+	Label         string               `json:"label"`
+	Documentation string               `json:"documentation,omitempty"`
+	Parameters    []SignatureParameter `json:"parameters"`
+}
+
+// SignatureHelpResult is the result of ts_signature_help.
+type SignatureHelpResult struct {
+	Signatures      []Signature `json:"signatures"`
+	ActiveSignature int         `json:"activeSignature"`
+	ActiveParameter int         `json:"activeParameter"`
+}
+
+// WorkspaceSymbol is one match of a WorkspaceSymbolsResult.
+type WorkspaceSymbol struct {
+	Name          string   `json:"name"`
+	Kind          string   `json:"kind"`
+	File          string   `json:"file"`
+	Line          int      `json:"line"`
+	Column        int      `json:"column"`
+	ContainerName string   `json:"containerName,omitempty"`
+	Package       *Package `json:"package,omitempty"`
+	DisplayPath   string   `json:"displayPath,omitempty"`
+}
+
+// WorkspaceSymbolsResult is the result of ts_workspace_symbols.
+type WorkspaceSymbolsResult struct {
+	Symbols    []WorkspaceSymbol `json:"symbols"`
+	TotalCount int               `json:"totalCount"`
+	Truncated  bool              `json:"truncated"`
+}
+
+// InlayHint is one hint of an InlayHintsResult.
+type InlayHint struct {
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Label  string `json:"label"`
+	Kind   string `json:"kind,omitempty"`
+}
+
+// InlayHintsResult is the result of ts_inlay_hints.
+type InlayHintsResult struct {
+	File       string      `json:"file"`
+	Hints      []InlayHint `json:"hints"`
+	TotalCount int         `json:"totalCount"`
+	Truncated  bool        `json:"truncated"`
+}
+
+// DocumentHighlight is one occurrence of a DocumentHighlightsResult.
+type DocumentHighlight struct {
+	Line         int    `json:"line"`
+	Column       int    `json:"column"`
+	Kind         string `json:"kind"`
+	Preview      string `json:"preview,omitempty"`
+	VisualColumn int    `json:"visualColumn,omitempty"`
+}
+
+// DocumentHighlightsResult is the result of ts_document_highlights.
+type DocumentHighlightsResult struct {
+	File       string              `json:"file"`
+	Highlights []DocumentHighlight `json:"highlights"`
+}
+
+// SemanticToken is one token of a SemanticTokensResult.
+type SemanticToken struct {
+	Line      int      `json:"line"`
+	Column    int      `json:"column"`
+	Length    int      `json:"length"`
+	Type      string   `json:"type"`
+	Modifiers []string `json:"modifiers,omitempty"`
+	Text      string   `json:"text,omitempty"`
+}
+
+// SemanticTokensResult is the result of ts_semantic_tokens.
+type SemanticTokensResult struct {
+	File       string          `json:"file"`
+	Tokens     []SemanticToken `json:"tokens"`
+	TotalCount int             `json:"totalCount"`
+	Truncated  bool            `json:"truncated"`
+}
+
+// SelectionRange is one enclosing range of a SelectionChain.
+type SelectionRange struct {
+	StartLine   int    `json:"startLine"`
+	StartColumn int    `json:"startColumn"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+	Preview     string `json:"preview"`
+}
+
+// SelectionChain is the ranges enclosing one position of a
+// SelectionRangeResult, innermost first.
+type SelectionChain struct {
+	Line   int              `json:"line"`
+	Column int              `json:"column"`
+	Ranges []SelectionRange `json:"ranges"`
+}
+
+// SelectionRangeResult is the result of ts_selection_range.
+type SelectionRangeResult struct {
+	File       string           `json:"file"`
+	Selections []SelectionChain `json:"selections"`
+}
+
+// SymbolLevel is one symbol of a SymbolAtPositionResult, its range
+// end-exclusive.
+type SymbolLevel struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	StartLine   int    `json:"startLine"`
+	StartColumn int    `json:"startColumn"`
+	EndLine     int    `json:"endLine"`
+	EndColumn   int    `json:"endColumn"`
+}
+
+// SymbolAtPositionResult is the result of ts_symbol_at_position.
+type SymbolAtPositionResult struct {
+	File             string        `json:"file"`
+	Line             int           `json:"line"`
+	Column           int           `json:"column"`
+	Chain            []SymbolLevel `json:"chain"`
+	Breadcrumb       string        `json:"breadcrumb"`
+	NearestPreceding *SymbolLevel  `json:"nearestPreceding,omitempty"`
+}
+
+// FoldingRange is one range of a FoldingRangesResult.
+type FoldingRange struct {
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Kind      string `json:"kind"`
+	Preview   string `json:"preview,omitempty"`
+}
+
+// FoldingRangesResult is the result of ts_folding_ranges.
+type FoldingRangesResult struct {
+	File   string         `json:"file"`
+	Ranges []FoldingRange `json:"ranges"`
+}
+
+// CodeAction is one action of a CodeActionsResult.
+type CodeAction struct {
+	Index       int      `json:"index"`
+	Title       string   `json:"title"`
+	Kind        string   `json:"kind,omitempty"`
+	HasEdit     bool     `json:"hasEdit"`
+	HasCommand  bool     `json:"hasCommand"`
+	IsPreferred bool     `json:"isPreferred,omitempty"`
+	Disabled    string   `json:"disabled,omitempty"`
+	Fixes       []string `json:"fixes,omitempty"`
+}
+
+// CodeActionsResult is the result of ts_code_actions.
+type CodeActionsResult struct {
+	File        string       `json:"file"`
+	Actions     []CodeAction `json:"actions"`
+	Diagnostics int          `json:"diagnostics"`
+}
+
+// Symbol is one node of the tree ts_document_symbols returns as a
+// []Symbol.
+type Symbol struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Line    int    `json:"line"`
+	EndLine int    `json:"endLine"`
+	Detail  string `json:"detail,omitempty"`
+	// Container is the dotted path of the enclosing symbols, in flat
+	// output.
+	Container string   `json:"container,omitempty"`
+	Exported  bool     `json:"exported"`
+	Children  []Symbol `json:"children,omitempty"`
+}
+
+// FileChange is one file touched by ts_rename.
+type FileChange struct {
+	File          string `json:"file"`
+	Edits         int    `json:"edits"`
+	Preview       string `json:"preview,omitempty"`
+	Diff          string `json:"diff,omitempty"`
+	DiffTruncated bool   `json:"diffTruncated,omitempty"`
+	ChangedLines  []int  `json:"changedLines,omitempty"`
+	Created       bool   `json:"created,omitempty"`
+	Deleted       bool   `json:"deleted,omitempty"`
+	RenamedFrom   string `json:"renamedFrom,omitempty"`
+}
+
+// FileRename is a file an edit moved.
+type FileRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// FileOps lists the files an edit created, moved and deleted.
+type FileOps struct {
+	Created []string     `json:"created,omitempty"`
+	Renamed []FileRename `json:"renamed,omitempty"`
+	Deleted []string     `json:"deleted,omitempty"`
+}
+
+// PrepareRenameResult is the result of ts_prepare_rename.
+type PrepareRenameResult struct {
+	File         string `json:"file"`
+	CanRename    bool   `json:"canRename"`
+	Reason       string `json:"reason,omitempty"`
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	EndLine      int    `json:"endLine,omitempty"`
+	EndColumn    int    `json:"endColumn,omitempty"`
+	Text         string `json:"text,omitempty"`
+	Placeholder  string `json:"placeholder,omitempty"`
+	VisualColumn int    `json:"visualColumn,omitempty"`
+}
+
+// RenameResult is the result of ts_rename.
+type RenameResult struct {
+	NewName    string       `json:"newName"`
+	TotalEdits int          `json:"totalEdits"`
+	Changes    []FileChange `json:"changes"`
+	FileOps
+	DocEdits []DocEdit `json:"docEdits,omitempty"`
+	// ColumnReadings is set when the column points elsewhere in the other
+	// column mode.
+	ColumnReadings []ColumnReading `json:"columnReadings,omitempty"`
+	// SkippedFiles lists the files left out by onlyUnder and
+	// excludeGlobs.
+	SkippedFiles []SkippedFile `json:"skippedFiles,omitempty"`
+}
+
+// SkippedFile is a file ts_rename left out of its edit.
+type SkippedFile struct {
+	File   string `json:"file"`
+	Edits  int    `json:"edits"`
+	Reason string `json:"reason"`
+}
+
+// EditPreview is the would-be change to one file of a preview.
+type EditPreview struct {
+	File          string `json:"file"`
+	Edits         int    `json:"edits"`
+	Diff          string `json:"diff"`
+	DiffTruncated bool   `json:"diffTruncated,omitempty"`
+	Created       bool   `json:"created,omitempty"`
+	Deleted       bool   `json:"deleted,omitempty"`
+	RenamedFrom   string `json:"renamedFrom,omitempty"`
+}
+
+// DryRunResult is the result of ts_rename with dryRun.
+type DryRunResult struct {
+	DryRun     bool          `json:"dryRun"`
+	TotalEdits int           `json:"totalEdits"`
+	Changes    []EditPreview `json:"changes"`
+}
+
+// ApplyCodeActionResult is the result of ts_apply_code_action.
+type ApplyCodeActionResult struct {
+	Title      string       `json:"title"`
+	Kind       string       `json:"kind,omitempty"`
+	TotalEdits int          `json:"totalEdits"`
+	Changes    []FileChange `json:"changes"`
+	FileOps
+}
+
+// ExtractRefactoring is one refactoring ts_extract_refactor lists.
+type ExtractRefactoring struct {
+	Title    string `json:"title"`
+	Kind     string `json:"kind,omitempty"`
+	Disabled string `json:"disabled,omitempty"`
+}
+
+// ExtractRefactoringsResult is the result of ts_extract_refactor without
+// pick.
+type ExtractRefactoringsResult struct {
+	File         string               `json:"file"`
+	Refactorings []ExtractRefactoring `json:"refactorings"`
+}
+
+// ExtractRefactorResult is the result of ts_extract_refactor with pick.
+type ExtractRefactorResult struct {
+	Title      string       `json:"title"`
+	Kind       string       `json:"kind,omitempty"`
+	Name       string       `json:"name,omitempty"`
+	TotalEdits int          `json:"totalEdits"`
+	Changes    []FileChange `json:"changes"`
+	FileOps
+}
+
+// FixAllSkip is a diagnostic ts_fix_all left alone.
+type FixAllSkip struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+}
+
+// FixAllResult is the result of ts_fix_all.
+type FixAllResult struct {
+	Code         int          `json:"code"`
+	FilesChecked int          `json:"filesChecked"`
+	Diagnostics  int          `json:"diagnostics"`
+	Applied      int          `json:"applied"`
+	Skipped      []FixAllSkip `json:"skipped"`
+	Remaining    int          `json:"remaining"`
+	TotalEdits   int          `json:"totalEdits"`
+	Changes      []FileChange `json:"changes"`
+}
+
+// CallHierarchyResult is the result of ts_call_hierarchy.
+type CallHierarchyResult struct {
+	Direction string          `json:"direction"`
+	Depth     int             `json:"depth"`
+	Roots     []CallHierarchy `json:"roots"`
+	Truncated bool            `json:"truncated"`
+}
+
+// CallHierarchy is a node of a ts_call_hierarchy tree.
+type CallHierarchy struct {
+	Name      string          `json:"name"`
+	Kind      string          `json:"kind"`
+	File      string          `json:"file"`
+	Line      int             `json:"line"`
+	Column    int             `json:"column"`
+	CallSites []CallSite      `json:"callSites,omitempty"`
+	Calls     []CallHierarchy `json:"calls,omitempty"`
+	Recursive bool            `json:"recursive,omitempty"`
+}
+
+// CallSite is the range of a call in a ts_call_hierarchy node.
+type CallSite struct {
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+}
+
+// TypeHierarchyResult is the result of ts_type_hierarchy.
+type TypeHierarchyResult struct {
+	Direction string          `json:"direction"`
+	Depth     int             `json:"depth"`
+	Roots     []TypeHierarchy `json:"roots"`
+	Truncated bool            `json:"truncated"`
+}
+
+// TypeHierarchy is a node of a ts_type_hierarchy tree.
+type TypeHierarchy struct {
+	Name      string          `json:"name"`
+	Kind      string          `json:"kind"`
+	File      string          `json:"file"`
+	Line      int             `json:"line"`
+	Column    int             `json:"column"`
+	Types     []TypeHierarchy `json:"types,omitempty"`
+	Recursive bool            `json:"recursive,omitempty"`
+}
+
+// FormatResult is the result of ts_format.
+type FormatResult struct {
+	File    string `json:"file"`
+	Edits   int    `json:"edits"`
+	Changed bool   `json:"changed"`
+}
+
+// RenameFileResult is the result of ts_rename_file.
+type RenameFileResult struct {
+	OldPath    string       `json:"oldPath"`
+	NewPath    string       `json:"newPath"`
+	TotalEdits int          `json:"totalEdits"`
+	Changes    []FileChange `json:"changes"`
+}
+
+// ColumnReading is what a ts_rename column points at in one column mode.
+type ColumnReading struct {
+	ColumnMode string `json:"columnMode"`
+	Column     int    `json:"column"`
+	Identifier string `json:"identifier,omitempty"`
+}
+
+// DocEdit is a JSDoc tag ts_rename rewrote with a renamed parameter.
+type DocEdit struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Tag    string `json:"tag"`
+}
+
+// CycleEdge is one import of an ImportCycle.
+type CycleEdge struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Import   string `json:"import"`
+	TypeOnly bool   `json:"typeOnly,omitempty"`
+}
+
+// ImportCycle is one cycle reported by ts_import_cycles.
+type ImportCycle struct {
+	Files    []string    `json:"files"`
+	Edges    []CycleEdge `json:"edges"`
+	TypeOnly bool        `json:"typeOnly,omitempty"`
+}
+
+// ImportCyclesResult is the result of ts_import_cycles.
+type ImportCyclesResult struct {
+	Target       string        `json:"target"`
+	Cycles       []ImportCycle `json:"cycles"`
+	FilesScanned int           `json:"filesScanned"`
+	Truncated    bool          `json:"truncated"`
+}
+
+// ProjectInfoResult is the result of ts_project_info.
+type ProjectInfoResult struct {
+	TsconfigPath     string         `json:"tsconfigPath,omitempty"`
+	ProjectRoot      string         `json:"projectRoot,omitempty"`
+	ModuleResolution string         `json:"moduleResolution,omitempty"`
+	TsgoVersion      string         `json:"tsgoVersion,omitempty"`
+	Config           *ProjectConfig `json:"config,omitempty"`
+	ConfigError      *struct {
+		File    string `json:"file"`
+		Message string `json:"message"`
+	} `json:"configError,omitempty"`
+	SourceFiles *struct {
+		Count     int      `json:"count"`
+		Files     []string `json:"files"`
+		Truncated bool     `json:"truncated"`
+	} `json:"sourceFiles,omitempty"`
+	Environment ProjectEnvironment `json:"environment"`
+}
+
+// ProjectConfig is the tsconfig of ts_project_info with its extends chain
+// applied.
+type ProjectConfig struct {
+	CompilerOptions map[string]any    `json:"compilerOptions"`
+	Include         []string          `json:"include,omitempty"`
+	Exclude         []string          `json:"exclude,omitempty"`
+	References      []string          `json:"references,omitempty"`
+	Bases           []string          `json:"bases,omitempty"`
+	Sources         map[string]string `json:"sources,omitempty"`
+}
+
+// ListProjectsResult is the result of ts_list_projects.
+type ListProjectsResult struct {
+	Root     string `json:"root"`
+	Projects []struct {
+		Config     string   `json:"config"`
+		Root       string   `json:"root"`
+		References []string `json:"references,omitempty"`
+		Error      string   `json:"error,omitempty"`
+	} `json:"projects"`
+	Owner *struct {
+		File     string `json:"file"`
+		Config   string `json:"config,omitempty"`
+		Included bool   `json:"included"`
+	} `json:"owner,omitempty"`
+}
+
+// ProjectEnvironment is the environment section of ts_project_info.
+type ProjectEnvironment struct {
+	PackageManager *struct {
+		Name   string `json:"name"`
+		Source string `json:"source"`
+	} `json:"packageManager,omitempty"`
+	Strictness map[string]bool `json:"strictness,omitempty"`
+}
+
+// ServerInfoResult is the result of ts_server_info.
+type ServerInfoResult struct {
+	Name         string `json:"name,omitempty"`
+	Version      string `json:"version,omitempty"`
+	TsgoVersion  string `json:"tsgoVersion,omitempty"`
+	Capabilities []struct {
+		Method    string `json:"method"`
+		Supported bool   `json:"supported"`
+	} `json:"capabilities"`
+	UnavailableTools []string `json:"unavailableTools,omitempty"`
+}
+
+// CallTool calls a tool and fails the test on a transport error. Tool
+// errors are returned as a result with IsError set.
+func CallTool(t testing.TB, c *client.Client, name string, args map[string]any) *mcp.CallToolResult {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	var req mcp.CallToolRequest
+	req.Params.Name = name
+	req.Params.Arguments = args
+	res, err := c.CallTool(ctx, req)
+	if err != nil {
+		t.Fatalf("typescriptmcptest: calling %s: %v", name, err)
+	}
+	return res
+}
+
+// MustCallToolText calls a tool, fails the test if it reports an error,
+// and returns its text output without workspace warnings.
+func MustCallToolText(t testing.TB, c *client.Client, name string, args map[string]any) string {
+	t.Helper()
+	res := CallTool(t, c, name, args)
+	text := resultText(res)
+	if res.IsError {
+		t.Fatalf("typescriptmcptest: %s failed: %s", name, text)
+	}
+	return text
+}
+
+// MustCallTool calls a tool, fails the test if it reports an error, and
+// decodes its output into T. Structured content is preferred over text.
+func MustCallTool[T any](t testing.TB, c *client.Client, name string, args map[string]any) T {
+	t.Helper()
+	res := CallTool(t, c, name, args)
+	text := resultText(res)
+	if res.IsError {
+		t.Fatalf("typescriptmcptest: %s failed: %s", name, text)
+	}
+
+	var out T
+	data := []byte(text)
+	if res.StructuredContent != nil {
+		var err error
+		if data, err = json.Marshal(res.StructuredContent); err != nil {
+			t.Fatalf("typescriptmcptest: %s: %v", name, err)
+		}
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("typescriptmcptest: decoding %s result into %T: %v\n%s", name, out, err, text)
+	}
+	return out
+}
+
+// Summary returns the summary line the server puts first in every
+// response, or "" when res has no text content.
+func Summary(res *mcp.CallToolResult) string {
+	if len(res.Content) == 0 {
+		return ""
+	}
+	if tc, ok := mcp.AsTextContent(res.Content[0]); ok {
+		return tc.Text
+	}
+	return ""
+}
+
+// resultText joins the text contents of res, dropping the leading summary
+// line and the "warning: " items the server prepends for misconfigured
+// workspaces. The first item counts as the summary when it is a single
+// line followed by other text; a summaryOnly response keeps it.
+func resultText(res *mcp.CallToolResult) string {
+	var parts []string
+	summary := false
+	for i, c := range res.Content {
+		tc, ok := mcp.AsTextContent(c)
+		if !ok || strings.HasPrefix(tc.Text, "warning: ") {
+			continue
+		}
+		summary = summary || i == 0 && !strings.Contains(tc.Text, "\n")
+		parts = append(parts, tc.Text)
+	}
+	if summary && len(parts) > 1 {
+		parts = parts[1:]
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,89 @@
+// Package typescriptmcptest runs the typescript-mcp server in-process
+// against throwaway TypeScript projects, for Go tests of agents and tools
+// built on top of it.
+//
+//	fx := typescriptmcptest.NewFixtureProject(t, map[string]string{
+//		"src/index.ts": "export const x: number = 'nope';",
+//	})
+//	srv := typescriptmcptest.StartServer(t, fx)
+//	res := typescriptmcptest.MustCallTool[typescriptmcptest.DiagnosticsResult](t, srv.Client,
+//		"ts_diagnostics", map[string]any{"file": fx.Path("src/index.ts")})
+//
+// StartServer skips the test when tsgo is not installed.
+package typescriptmcptest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// DefaultTSConfig is written as tsconfig.json when a fixture does not
+// provide one.
+const DefaultTSConfig = `{
+  "compilerOptions": {
+    "strict": true,
+    "target": "ES2022",
+    "module": "ESNext",
+    "moduleResolution": "bundler",
+    "noEmit": true
+  }
+}
+`
+
+// Fixture is a TypeScript project materialized in a temporary directory.
+type Fixture struct {
+	// Dir is the absolute project root.
+	Dir string
+}
+
+// NewFixtureProject writes files (slash-separated paths relative to the
+// project root -> content) into a fresh temporary directory that is
+// removed when the test ends. DefaultTSConfig is added unless files
+// contains "tsconfig.json".
+func NewFixtureProject(t testing.TB, files map[string]string) *Fixture {
+	t.Helper()
+	fx := &Fixture{Dir: t.TempDir()}
+	if _, ok := files["tsconfig.json"]; !ok {
+		fx.WriteFile(t, "tsconfig.json", DefaultTSConfig)
+	}
+	for rel, content := range files {
+		fx.WriteFile(t, rel, content)
+	}
+	return fx
+}
+
+// Path returns the absolute path of a slash-separated project-relative
+// path.
+func (f *Fixture) Path(rel string) string {
+	return filepath.Join(f.Dir, filepath.FromSlash(rel))
+}
+
+// WriteFile creates or replaces a project file, creating parent
+// directories as needed. The server trusts a file it synced within
+// docsync.DefaultFreshness, so a test that rewrites a file it has already
+// queried should wait that long before querying it again.
+func (f *Fixture) WriteFile(t testing.TB, rel, content string) {
+	t.Helper()
+	if filepath.IsAbs(rel) || strings.HasPrefix(filepath.Clean(filepath.FromSlash(rel)), "..") {
+		t.Fatalf("typescriptmcptest: fixture path %q must be relative to the project root", rel)
+	}
+	p := f.Path(rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatalf("typescriptmcptest: %v", err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatalf("typescriptmcptest: %v", err)
+	}
+}
+
+// ReadFile returns the current content of a project file.
+func (f *Fixture) ReadFile(t testing.TB, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(f.Path(rel))
+	if err != nil {
+		t.Fatalf("typescriptmcptest: %v", err)
+	}
+	return string(data)
+}
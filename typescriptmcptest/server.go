@@ -0,0 +1,101 @@
+package typescriptmcptest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/paulvanbrenk/typescript-mcp/internal/docsync"
+	"github.com/paulvanbrenk/typescript-mcp/internal/lsp"
+	"github.com/paulvanbrenk/typescript-mcp/internal/tools"
+)
+
+// startTimeout bounds tsgo startup and the MCP handshake.
+const startTimeout = 30 * time.Second
+
+// lookupTsgo is replaced in tests to exercise the skip path.
+var lookupTsgo = lsp.ResolveTsgo
+
+// Server is a running typescript-mcp server connected to an in-process
+// MCP client.
+type Server struct {
+	// Client is initialized and ready for CallTool.
+	Client  *client.Client
+	Fixture *Fixture
+
+	closeOnce sync.Once
+	close     func()
+}
+
+// StartServer starts tsgo rooted at fx.Dir, registers every tool on a new
+// MCP server, and connects an in-process client to it. The server is shut
+// down, along with the tsgo of any project outside fx.Dir it served, when
+// the test ends; Close may be called earlier. The test is
+// skipped, not failed, when tsgo is not installed.
+func StartServer(t testing.TB, fx *Fixture) *Server {
+	t.Helper()
+	if _, err := lookupTsgo(); err != nil {
+		t.Skipf("typescriptmcptest: %v", err)
+	}
+
+	// tsgo is bound to this context for its whole life, so it must outlive
+	// startup.
+	procCtx, stopProc := context.WithCancel(context.Background())
+	lspClient, err := lsp.NewClient(procCtx, docsync.FileToURI(fx.Dir))
+	if err != nil {
+		stopProc()
+		t.Fatalf("typescriptmcptest: starting tsgo: %v", err)
+	}
+	docs := docsync.NewManager()
+
+	hooks := &server.Hooks{}
+	s := server.NewMCPServer("typescript-mcp", "test", server.WithHooks(hooks))
+	if err := tools.RegisterWithOptions(s, lspClient, docs, tools.RegisterOptions{Context: procCtx, Hooks: hooks}); err != nil {
+		_ = lspClient.Close()
+		stopProc()
+		t.Fatalf("typescriptmcptest: registering tools: %v", err)
+	}
+
+	srv := &Server{Fixture: fx}
+	c, err := client.NewInProcessClient(s)
+	if err == nil {
+		srv.Client = c
+		ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+		defer cancel()
+		if err = c.Start(ctx); err == nil {
+			_, err = c.Initialize(ctx, mcp.InitializeRequest{
+				Params: mcp.InitializeParams{
+					ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION,
+					ClientInfo:      mcp.Implementation{Name: "typescriptmcptest", Version: "test"},
+				},
+			})
+		}
+	}
+
+	srv.close = func() {
+		if srv.Client != nil {
+			_ = srv.Client.Close()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = docs.Close(ctx, lspClient.Conn())
+		_ = lspClient.Close()
+		stopProc()
+	}
+	t.Cleanup(srv.Close)
+	if err != nil {
+		t.Fatalf("typescriptmcptest: connecting client: %v", err)
+	}
+	return srv
+}
+
+// Close shuts down the client, the server and tsgo. It is safe to call
+// more than once.
+func (s *Server) Close() {
+	s.closeOnce.Do(s.close)
+}
@@ -0,0 +1,205 @@
+package typescriptmcptest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestNewFixtureProject(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantTSConfig string
+	}{
+		{
+			name:         "default tsconfig",
+			files:        map[string]string{"src/index.ts": "export const x = 1;\n"},
+			wantTSConfig: DefaultTSConfig,
+		},
+		{
+			name: "custom tsconfig",
+			files: map[string]string{
+				"tsconfig.json": `{"compilerOptions":{"strict":false}}`,
+				"src/index.ts":  "export const x = 1;\n",
+			},
+			wantTSConfig: `{"compilerOptions":{"strict":false}}`,
+		},
+		{
+			name:         "nested directories",
+			files:        map[string]string{"packages/a/src/deep/mod.ts": "export {};\n"},
+			wantTSConfig: DefaultTSConfig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fx := NewFixtureProject(t, tt.files)
+			if !filepath.IsAbs(fx.Dir) {
+				t.Errorf("Dir = %q, want absolute", fx.Dir)
+			}
+			if got := fx.ReadFile(t, "tsconfig.json"); got != tt.wantTSConfig {
+				t.Errorf("tsconfig.json = %q, want %q", got, tt.wantTSConfig)
+			}
+			for rel, want := range tt.files {
+				data, err := os.ReadFile(filepath.Join(fx.Dir, filepath.FromSlash(rel)))
+				if err != nil {
+					t.Fatalf("reading %s: %v", rel, err)
+				}
+				if string(data) != want {
+					t.Errorf("%s = %q, want %q", rel, data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFixturePath(t *testing.T) {
+	fx := &Fixture{Dir: filepath.FromSlash("/tmp/proj")}
+	want := filepath.FromSlash("/tmp/proj/src/a.ts")
+	if got := fx.Path("src/a.ts"); got != want {
+		t.Errorf("Path = %q, want %q", got, want)
+	}
+}
+
+func TestStartServerSkipsWithoutTsgo(t *testing.T) {
+	saved := lookupTsgo
+	lookupTsgo = func() (string, error) {
+		return "", errors.New("tsgo not found in PATH; install it with: npm install -g @typescript/native-preview")
+	}
+	defer func() { lookupTsgo = saved }()
+
+	fx := NewFixtureProject(t, nil)
+	var skipped, returned bool
+	t.Run("start", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		StartServer(t, fx)
+		returned = true
+	})
+	if !skipped {
+		t.Error("StartServer did not skip the test")
+	}
+	if returned {
+		t.Error("StartServer returned after skipping")
+	}
+}
+
+// connectFake serves a single echo-style tool over an in-process client.
+func connectFake(t *testing.T, handler server.ToolHandlerFunc) *client.Client {
+	t.Helper()
+	s := server.NewMCPServer("fake", "test")
+	s.AddTool(mcp.NewTool("fake"), handler)
+	c, err := client.NewInProcessClient(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Initialize(ctx, mcp.InitializeRequest{
+		Params: mcp.InitializeParams{ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestMustCallTool(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *mcp.CallToolResult
+	}{
+		{
+			name:   "text",
+			result: mcp.NewToolResultText(`{"references":[{"file":"/p/a.ts","line":3,"column":5}],"totalCount":1,"truncated":false}`),
+		},
+		{
+			name: "skips workspace warning",
+			result: &mcp.CallToolResult{Content: []mcp.Content{
+				mcp.NewTextContent("warning: no tsconfig.json found"),
+				mcp.NewTextContent(`{"references":[{"file":"/p/a.ts","line":3,"column":5}],"totalCount":1}`),
+			}},
+		},
+		{
+			name: "skips summary line",
+			result: &mcp.CallToolResult{Content: []mcp.Content{
+				mcp.NewTextContent("references: 1 total, 1 shown in 1 file (truncated: no)"),
+				mcp.NewTextContent("warning: no tsconfig.json found"),
+				mcp.NewTextContent(`{"references":[{"file":"/p/a.ts","line":3,"column":5}],"totalCount":1}`),
+			}},
+		},
+		{
+			name: "structured content",
+			result: mcp.NewToolResultStructured(
+				map[string]any{"references": []any{map[string]any{"file": "/p/a.ts", "line": 3, "column": 5}}, "totalCount": 1},
+				"fallback text",
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotArgs map[string]any
+			c := connectFake(t, func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				gotArgs = req.GetArguments()
+				return tt.result, nil
+			})
+			res := MustCallTool[ReferencesResult](t, c, "fake", map[string]any{"file": "/p/a.ts"})
+			if gotArgs["file"] != "/p/a.ts" {
+				t.Errorf("tool got args %v", gotArgs)
+			}
+			want := Location{File: "/p/a.ts", Line: 3, Column: 5}
+			if res.TotalCount != 1 || len(res.References) != 1 || res.References[0] != want {
+				t.Errorf("result = %+v, want one reference %+v", res, want)
+			}
+		})
+	}
+}
+
+func TestMustCallToolText(t *testing.T) {
+	c := connectFake(t, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{
+			mcp.NewTextContent("warning: not a TypeScript project"),
+			mcp.NewTextContent("(method) greet(name: string): string"),
+		}}, nil
+	})
+	if got := MustCallToolText(t, c, "fake", nil); got != "(method) greet(name: string): string" {
+		t.Errorf("text = %q", got)
+	}
+}
+
+func TestCallToolError(t *testing.T) {
+	c := connectFake(t, func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultError("file is required"), nil
+	})
+	res := CallTool(t, c, "fake", nil)
+	if !res.IsError || !strings.Contains(resultText(res), "file is required") {
+		t.Errorf("result = %+v, want tool error", res)
+	}
+}
+
+func TestStartServerLifecycle(t *testing.T) {
+	fx := NewFixtureProject(t, map[string]string{
+		"src/index.ts": "export function greet(name: string): string {\n  return name;\n}\n",
+	})
+	srv := StartServer(t, fx)
+
+	info := MustCallTool[ProjectInfoResult](t, srv.Client, "ts_project_info", map[string]any{"cwd": fx.Dir})
+	if info.TsconfigPath != fx.Path("tsconfig.json") {
+		t.Errorf("tsconfigPath = %q, want %q", info.TsconfigPath, fx.Path("tsconfig.json"))
+	}
+	syms := MustCallTool[[]Symbol](t, srv.Client, "ts_document_symbols", map[string]any{"file": fx.Path("src/index.ts")})
+	if len(syms) == 0 || syms[0].Name != "greet" {
+		t.Errorf("symbols = %+v, want greet", syms)
+	}
+
+	srv.Close()
+	srv.Close() // idempotent
+}